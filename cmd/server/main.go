@@ -5,20 +5,51 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/james-see/synthtribe2midi/pkg/api"
 )
 
+// Set by the release build
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
+	api.SetBuildInfo(version, commit, date)
 	port := flag.Int("port", 8080, "Server port")
+	origins := flag.String("cors-origins", "", "Comma-separated allowed CORS origins (\"*\" for any, \"none\" to disable; env "+api.EnvCORSOrigins+", default *)")
+	methods := flag.String("cors-methods", "", "Comma-separated allowed CORS methods (env "+api.EnvCORSMethods+")")
+	headers := flag.String("cors-headers", "", "Comma-separated allowed CORS request headers (env "+api.EnvCORSHeaders+")")
+	credentials := flag.Bool("cors-credentials", false, "Allow credentialed CORS requests (requires explicit -cors-origins)")
 	flag.Parse()
 
+	opts := api.Options{CORS: api.CORSConfig{
+		Origins:     splitFlag(*origins),
+		Methods:     splitFlag(*methods),
+		Headers:     splitFlag(*headers),
+		Credentials: *credentials,
+	}}
+
 	fmt.Printf("Starting synthtribe2midi API server on port %d...\n", *port)
 	fmt.Printf("Swagger docs available at http://localhost:%d/swagger/index.html\n", *port)
 	
-	if err := api.StartServer(*port); err != nil {
+	if err := api.StartServer(*port, opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+
+// splitFlag splits a comma-separated flag value, dropping empty items
+func splitFlag(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
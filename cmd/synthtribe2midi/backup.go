@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/backup"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/schedule"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupDir      string
+	backupSchedule string
+	backupKeep     int
+	backupMaxAge   time.Duration
+	backupTimeout  time.Duration
+	backupRetries  int
+	backupVerify   bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up every pattern slot of a connected device to a bank file",
+	Long: `Request every pattern slot from a connected device and save them as one
+timestamped bank file (e.g. backups/td3-20261015-030000.syx). With --schedule
+the backup repeats on a cron schedule until interrupted; --keep and --max-age
+prune old backups.
+
+Examples:
+  synthtribe2midi backup --port TD-3 --dir backups
+  synthtribe2midi backup --port TD-3 --schedule "0 3 * * *" --keep 30`,
+	Args:         cobra.NoArgs,
+	RunE:         runBackup,
+	SilenceUsage: true,
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI port name (or unique part of it)")
+	backupCmd.Flags().StringVar(&backupDir, "dir", "backups", "Directory for bank files")
+	backupCmd.Flags().StringVar(&backupSchedule, "schedule", "", `Cron schedule, e.g. "0 3 * * *" or @daily (default: back up once)`)
+	backupCmd.Flags().IntVar(&backupKeep, "keep", 0, "Keep only the newest N backups (0 = keep all)")
+	backupCmd.Flags().DurationVar(&backupMaxAge, "max-age", 0, "Delete backups older than this, e.g. 720h (0 = never)")
+	backupCmd.Flags().DurationVar(&backupTimeout, "timeout", backup.DefaultTimeout, "Time to wait for each pattern dump")
+	backupCmd.Flags().IntVar(&backupRetries, "retries", 2, "Re-request a pattern this many times after a timeout or damaged dump")
+	backupCmd.Flags().BoolVar(&backupVerify, "verify", false, "Read every slot twice and fail if the dumps differ")
+	_ = backupCmd.MarkFlagRequired("port")
+	rootCmd.AddCommand(backupCmd)
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	device, err := getDevice()
+	if err != nil {
+		return err
+	}
+	dev, ok := device.(converter.PatternRequester)
+	if !ok {
+		return fmt.Errorf("%s does not support pattern dump requests", device.Name())
+	}
+
+	job := &backup.Job{
+		Port:      midiPort,
+		Dir:       backupDir,
+		Prefix:    deviceName,
+		Device:    dev,
+		Retention: backup.Retention{Keep: backupKeep, MaxAge: backupMaxAge},
+		Options:   backup.Options{Timeout: backupTimeout, Retries: backupRetries, Verify: backupVerify},
+	}
+	if sysexID >= 0 {
+		job.Options.DeviceID = uint8(sysexID)
+	}
+
+	if backupSchedule == "" {
+		path, err := job.Run(time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Backed up %d patterns to %s\n", dev.Slots(), path)
+		return nil
+	}
+
+	sched, err := schedule.Parse(backupSchedule)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	next, err := sched.Next(time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backing up on schedule %q; next run %s\n", sched, next.Format(time.RFC1123))
+	return schedule.Run(ctx, sched, func(at time.Time) {
+		path, err := job.Run(at)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Backup failed: %v\n", err)
+			return
+		}
+		fmt.Printf("Backed up %d patterns to %s\n", dev.Slots(), path)
+	})
+}
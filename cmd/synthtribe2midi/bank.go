@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	patternIndex int
+	bankAll      bool
+	bankMerge    bool
+)
+
+// addBankFlags adds the flags choosing the patterns of bank input (a .syx
+// file with several dumps, such as a full backup) to a conversion command
+func addBankFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&patternIndex, "pattern-index", 0, "Convert only this pattern (1-based) of a bank .syx file")
+	cmd.Flags().BoolVar(&bankAll, "all", false, "Convert every pattern of a bank .syx file, each to outputs of its own named <output>-01, -02, ...")
+	cmd.Flags().BoolVar(&bankMerge, "merge", false, "With --all, write a single MIDI file with a track per pattern")
+	cmd.MarkFlagsMutuallyExclusive("pattern-index", "all")
+}
+
+// bankPattern returns the pattern of input data to convert: the whole file,
+// or for a bank the one chosen with --pattern-index
+func bankPattern(input string, data []byte) ([]byte, error) {
+	if bankMerge {
+		return nil, errors.New("--merge needs --all")
+	}
+	items, err := service.SplitBank(input, data, converter.Limits{})
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > 1 && patternIndex == 0 {
+		return nil, fmt.Errorf("%s holds %d patterns: choose one with --pattern-index, or convert them all with --all", input, len(items))
+	}
+	if patternIndex == 0 {
+		return data, nil
+	}
+	item, err := service.PickBankItem(items, patternIndex)
+	if err != nil {
+		return nil, err
+	}
+	return item.Data, nil
+}
+
+// bankOutput returns the output path of the index'th (0-based) pattern of a
+// bank, e.g. song-03.mid for song.mid
+func bankOutput(output string, index int) string {
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s-%02d%s", strings.TrimSuffix(output, ext), index+1, ext)
+}
+
+// convertBank converts every pattern of input, parsed as from (or detected
+// when empty): each to outputs of its own, named after its position, or
+// with --merge all to the MIDI outputs as one multi-track file
+func convertBank(cmd *cobra.Command, input string, from converter.Format, outputs []string, formats []converter.Format) error {
+	data, release, err := readInput(input)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+	defer release()
+
+	items, err := service.SplitBank(input, data, converter.Limits{})
+	if err != nil {
+		return err
+	}
+	if from != "" {
+		for i := range items {
+			items[i].Format = from
+		}
+	}
+	if bankMerge {
+		return mergeBank(cmd, input, items, outputs, formats)
+	}
+
+	paths := make([]string, 0, len(items)*len(outputs))
+	for i := range items {
+		for _, out := range outputs {
+			paths = append(paths, bankOutput(out, i))
+		}
+	}
+	rec := conversionRecord(cmd, input, paths...)
+	if skipUpToDate(rec) {
+		return nil
+	}
+
+	results := make([]*service.ConvertResponse, len(items))
+	var warnings []converter.Violation
+	for i, item := range items {
+		resp, err := service.Convert(context.Background(), service.ConvertRequest{
+			Data:     item.Data,
+			Filename: item.Name,
+			From:     item.Format,
+			To:       formats,
+			Device:   deviceName,
+			Options:  getOptions(),
+		})
+		if err != nil {
+			return fmt.Errorf("%s: conversion failed: %w", item.Name, err)
+		}
+		for _, w := range resp.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", item.Name, w)
+		}
+		results[i] = resp
+		warnings = append(warnings, resp.Warnings...)
+	}
+	if err := recordConversion(rec, warnings, func() error {
+		for i, resp := range results {
+			for j, out := range outputs {
+				if err := converter.WriteFileAtomic(bankOutput(out, i), resp.Outputs[j].Data, 0644); err != nil {
+					return fmt.Errorf("failed to write output file: %w", err)
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Converted %d pattern(s) of %s -> %s ... %s\n", len(items), input, paths[0], paths[len(paths)-1])
+	return nil
+}
+
+// mergeBank converts the patterns of a bank to the MIDI outputs as one
+// multi-track file, a track per pattern
+func mergeBank(cmd *cobra.Command, input string, items []service.BankItem, outputs []string, formats []converter.Format) error {
+	for _, f := range formats {
+		if f != converter.FormatMIDI {
+			return errors.New("--merge writes a single MIDI file: give only .mid outputs")
+		}
+	}
+	rec := conversionRecord(cmd, input, outputs...)
+	if skipUpToDate(rec) {
+		return nil
+	}
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(items))
+	patterns := make([]*converter.Pattern, len(items))
+	for i, item := range items {
+		if err := service.CheckFormat(item.Data, item.Format); err != nil {
+			return fmt.Errorf("%s: %w", item.Name, err)
+		}
+		if patterns[i], err = conv.ParsePattern(item.Data, item.Format); err != nil {
+			return fmt.Errorf("%s: %w", item.Name, err)
+		}
+		names[i] = item.Name
+	}
+	merged, warnings, err := converter.MergePatterns(names, patterns)
+	if err != nil {
+		return err
+	}
+	data, genWarnings, err := conv.Generate(merged, converter.FormatMIDI)
+	if err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+	warnings = append(warnings, genWarnings...)
+
+	if err := recordConversion(rec, warnings, func() error {
+		for _, out := range outputs {
+			if err := converter.WriteFileAtomic(out, data, 0644); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	printWarnings(warnings)
+	fmt.Printf("Merged %d pattern(s) of %s -> %s\n", len(items), input, strings.Join(outputs, ", "))
+	return nil
+}
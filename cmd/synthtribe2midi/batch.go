@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchTo        string
+	batchOutDir    string
+	batchCollision string
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <files or globs...>",
+	Short: "Convert many files in one run",
+	Long: `Convert every file matching the given globs (quote them so the shell
+leaves them alone) to one format. Each file is reported as it is converted;
+a file that fails does not stop the batch, and the command exits non-zero
+if any did. Outputs are named after their input, next to it or in
+--out-dir; when two inputs map to the same output (line.seq and line.syx to
+line.mid), --on-collision decides what happens to the second.
+
+Examples:
+  synthtribe2midi batch "*.seq" --to midi --out-dir ./converted
+  synthtribe2midi batch "backups/*.syx" "*.mid" --to seq --on-collision skip`,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         runBatch,
+	SilenceUsage: true,
+}
+
+func init() {
+	batchCmd.Flags().StringVar(&batchTo, "to", "", "Output format: seq, syx, or midi")
+	batchCmd.Flags().StringVar(&batchOutDir, "out-dir", "", "Write outputs to this directory (default: next to each input)")
+	batchCmd.Flags().StringVar(&batchCollision, "on-collision", service.CollisionRename, "When an output was already written from another input of the batch: rename, skip, overwrite")
+	_ = batchCmd.MarkFlagRequired("to")
+	addBankFlags(batchCmd)
+	rootCmd.AddCommand(batchCmd)
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	to := service.ParseFormat(batchTo)
+	if to != converter.FormatSeq && to != converter.FormatSyx && to != converter.FormatMIDI {
+		return fmt.Errorf("unknown --to format %q (use seq, syx, or midi)", batchTo)
+	}
+	if err := service.ValidateCollisionPolicy(batchCollision); err != nil || batchCollision == service.CollisionAsk {
+		return fmt.Errorf("invalid --on-collision %q (use rename, skip, or overwrite)", batchCollision)
+	}
+	inputs, err := service.ExpandInputs(args)
+	if err != nil {
+		return err
+	}
+	if batchOutDir != "" {
+		if err := os.MkdirAll(batchOutDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	written := service.NewOutputs()
+	var converted, skipped, failed int
+	for _, input := range inputs {
+		if converter.DetectFormat(input) == to {
+			fmt.Fprintf(os.Stderr, "%s: failed: already %s\n", input, service.DescribeFormat(to))
+			failed++
+			continue
+		}
+		output, c := written.Claim(service.BatchOutput(input, batchOutDir, to), input, batchCollision)
+		if c != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", c)
+		}
+		if output == "" {
+			skipped++
+			continue
+		}
+
+		done, err := convertBatchFile(cmd, input, output, to)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s: failed: %v\n", input, err)
+			failed++
+		case !done:
+			fmt.Printf("%s: up to date\n", input)
+			skipped++
+		default:
+			fmt.Printf("%s -> %s\n", input, output)
+			converted++
+		}
+	}
+
+	fmt.Printf("Converted %d of %d file(s), %d skipped, %d failed\n", converted, len(inputs), skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed", failed, len(inputs))
+	}
+	return nil
+}
+
+// convertBatchFile converts one input of a batch, each pattern of a bank
+// with --all
+func convertBatchFile(cmd *cobra.Command, input, output string, to converter.Format) (bool, error) {
+	if bankAll {
+		return true, convertBank(cmd, input, "", []string{output}, []converter.Format{to})
+	}
+	return convertFile(cmd, input, "", []string{output}, []converter.Format{to})
+}
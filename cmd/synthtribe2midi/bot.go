@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/james-see/synthtribe2midi/pkg/bot"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	botToken    string
+	botChannels []string
+	botMIDITo   string
+)
+
+var botCmd = &cobra.Command{
+	Use:   "bot telegram|discord",
+	Short: "Run a chat bot that converts shared pattern files",
+	Long: `Listen for .seq, .syx, and .mid files posted in Telegram or Discord chats and
+reply with the converted file and a short summary (key, notes, density,
+slides/accents). MIDI files are converted to --midi-to; .seq and .syx files
+are converted to MIDI.
+
+The bot token is read from --token or $SYNTHTRIBE2MIDI_BOT_TOKEN. Discord bots
+need the Message Content intent enabled in the developer portal.
+
+Examples:
+  SYNTHTRIBE2MIDI_BOT_TOKEN=123:abc synthtribe2midi bot telegram
+  synthtribe2midi bot discord --channel 112233445566778899 --midi-to syx`,
+	Args:         cobra.ExactArgs(1),
+	ValidArgs:    []string{"telegram", "discord"},
+	RunE:         runBot,
+	SilenceUsage: true,
+}
+
+func init() {
+	botCmd.Flags().StringVar(&botToken, "token", "", "Bot token (default: $SYNTHTRIBE2MIDI_BOT_TOKEN)")
+	botCmd.Flags().StringSliceVar(&botChannels, "channel", nil, "Only answer in these chat/channel IDs (repeatable)")
+	botCmd.Flags().StringVar(&botMIDITo, "midi-to", "seq", "Format to convert MIDI files to (seq or syx)")
+	rootCmd.AddCommand(botCmd)
+}
+
+func runBot(cmd *cobra.Command, args []string) error {
+	token := botToken
+	if token == "" {
+		token = os.Getenv("SYNTHTRIBE2MIDI_BOT_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("a bot token is required (--token or $SYNTHTRIBE2MIDI_BOT_TOKEN)")
+	}
+
+	dev, err := getDevice()
+	if err != nil {
+		return err
+	}
+	conv := bot.NewConverter(dev)
+	switch strings.ToLower(botMIDITo) {
+	case "seq":
+		conv.MIDITarget = converter.FormatSeq
+	case "syx":
+		conv.MIDITarget = converter.FormatSyx
+	default:
+		return fmt.Errorf("--midi-to must be seq or syx, got %q", botMIDITo)
+	}
+
+	var b bot.Bot
+	switch strings.ToLower(args[0]) {
+	case "telegram":
+		t := bot.NewTelegram(token, conv)
+		for _, c := range botChannels {
+			id, err := strconv.ParseInt(c, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid telegram chat ID %q", c)
+			}
+			t.Chats = append(t.Chats, id)
+		}
+		b = t
+	case "discord":
+		d := bot.NewDiscord(token, conv)
+		d.Channels = botChannels
+		b = d
+	default:
+		return fmt.Errorf("unknown platform %q (use telegram or discord)", args[0])
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Bot running on %s; press Ctrl+C to stop\n", args[0])
+	return b.Run(ctx)
+}
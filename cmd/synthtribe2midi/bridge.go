@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/james-see/synthtribe2midi/pkg/bridge"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bridgePort string
+	bridgeDir  string
+)
+
+var bridgeCmd = &cobra.Command{
+	Use:   "bridge",
+	Short: "Create a virtual MIDI port that links a DAW to pattern files",
+	Long: `Create a virtual MIDI port for a DAW to connect to. Pattern dumps the DAW
+sends to the port (e.g. a recorded SysEx clip) are converted and saved as .seq
+files in --dir; .seq, .syx, .303, and MIDI files dropped into --dir are sent
+to the DAW as pattern SysEx, ready to record or forward to the synth.
+
+Virtual ports need the rtmidi build and are not supported on Windows; there,
+create a loopback port (e.g. loopMIDI) and bridge through it with
+--midi-backend rtmidi --port loopMIDI.
+
+Examples:
+  synthtribe2midi bridge --dir ~/patterns/incoming
+  synthtribe2midi bridge --device ms1 --port "MS-1 Bridge"`,
+	Args:         cobra.NoArgs,
+	RunE:         runBridge,
+	SilenceUsage: true,
+}
+
+func init() {
+	bridgeCmd.Flags().StringVarP(&bridgePort, "port", "p", "synthtribe2midi", "Name of the virtual port (or the port to use with --midi-backend)")
+	bridgeCmd.Flags().StringVar(&bridgeDir, "dir", "bridge", "Folder for captured patterns and files to play back")
+	rootCmd.AddCommand(bridgeCmd)
+}
+
+func runBridge(cmd *cobra.Command, args []string) error {
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+
+	// Default to virtual ports rather than the usual hardware backend
+	var backend mididevice.Backend
+	if midiBackend == "" {
+		backend, err = mididevice.Lookup("virtual")
+	} else {
+		backend, err = mididevice.Default()
+	}
+	if err != nil {
+		return err
+	}
+
+	in, err := mididevice.OpenIn(backend, bridgePort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := mididevice.OpenOut(backend, bridgePort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	b := &bridge.Bridge{
+		Conv:   conv,
+		Dir:    bridgeDir,
+		Prefix: deviceName,
+		Out:    out,
+		Logf: func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Bridging %s (%s) <-> %s; press Ctrl+C to stop\n", bridgePort, backend.Name(), bridgeDir)
+	return b.Run(ctx, in)
+}
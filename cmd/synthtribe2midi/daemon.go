@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/james-see/synthtribe2midi/pkg/daemon"
+	"github.com/spf13/cobra"
+)
+
+var daemonConfig string
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the API server and watch folders in one long-running process",
+	Long: `Run the API server and any configured watch folders from a single YAML
+config, e.g. on a Raspberry Pi permanently attached to the synth. Status is
+served at ` + daemon.StatusPath + `.
+
+Example daemon.yaml:
+
+  server:
+    port: 8080
+  watch:
+    - dir: /home/pi/incoming
+      to: [midi, syx]
+      output: /home/pi/converted`,
+	Args:         cobra.NoArgs,
+	RunE:         runDaemon,
+	SilenceUsage: true,
+}
+
+func init() {
+	daemonCmd.Flags().StringVarP(&daemonConfig, "config", "c", "daemon.yaml", "Daemon configuration file")
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	cfg, err := daemon.LoadConfig(daemonConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return daemon.New(cfg).Run(ctx)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/generate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	genArp     string
+	genStyle   string
+	genOctave  int
+	genOctaves int
+	genSteps   int
+	genTempo   float64
+	genSeed    int64
+
+	genModel          string
+	genPreset         string
+	genRoot           string
+	genScale          string
+	genNotes          []string
+	genMaxJump        int
+	genRest           float64
+	genTie            float64
+	genSlide          float64
+	genAccent         float64
+	genDownbeatAccent float64
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a new pattern",
+	Long: `Create a pattern from scratch and write it in any format (.seq, .syx, or
+MIDI, following the output extension). The seed is printed so a result can
+be reproduced.
+
+By default a line is drawn at random following a style preset: the notes it
+may use, the largest jump between notes, weights that favour some notes, and
+the chance of rests, ties, slides, and accents. Pick a built-in preset or a
+YAML file with --preset, and override single settings with flags.
+
+With --model, a line is generated from a model learned from your own
+patterns (see "learn"), so it follows their notes and phrasing.
+
+With --arp, the notes of a chord are arpeggiated up, down, up and down, or
+in random order, and seasoned with accents and slides.
+
+Examples:
+  synthtribe2midi generate -o line.seq
+  synthtribe2midi generate --preset hypnotic --seed 7 -o line.mid
+  synthtribe2midi generate --preset mystyle.yaml --rest 0.3 --max-jump 5 -o line.syx
+  synthtribe2midi generate --notes A2,C3,E3,G3 --downbeat-accent 1 -o line.seq
+  synthtribe2midi generate --model mystyle.json -o line.seq
+  synthtribe2midi generate --arp Am7 --style updown -o arp.seq
+  synthtribe2midi generate --arp "F#m" --style random --octaves 2 --seed 42 -o arp.mid`,
+	Args:         cobra.NoArgs,
+	RunE:         runGenerate,
+	SilenceUsage: true,
+}
+
+func init() {
+	generateCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (required)")
+	generateCmd.Flags().StringVar(&genArp, "arp", "", "Arpeggiate this chord, e.g. Am7, C, F#m, Bbmaj7")
+	generateCmd.Flags().StringVar(&genStyle, "style", generate.ArpUp, "Arp style ("+strings.Join(generate.ArpStyles, ", ")+")")
+	generateCmd.Flags().IntVar(&genOctave, "octave", 2, "Octave of the chord or scale root (C2 = MIDI note 36)")
+	generateCmd.Flags().IntVar(&genOctaves, "octaves", 1, "Number of octaves the arpeggio or scale spans")
+	generateCmd.Flags().IntVar(&genSteps, "steps", generate.DefaultSteps, "Pattern length in steps")
+	generateCmd.Flags().Float64Var(&genTempo, "tempo", generate.DefaultTempo, "Tempo in BPM")
+	generateCmd.Flags().Int64Var(&genSeed, "seed", 0, "Random seed (default: random)")
+	generateCmd.Flags().StringVar(&genModel, "model", "", "Generate from a model written by learn")
+	generateCmd.Flags().StringVar(&genPreset, "preset", generate.DefaultPreset, "Style preset ("+strings.Join(generate.Presets(), ", ")+") or YAML file")
+	generateCmd.Flags().StringVar(&genRoot, "root", "", "Root note of the scale, e.g. A or F#")
+	generateCmd.Flags().StringVar(&genScale, "scale", "", "Scale, e.g. minor, phrygian, minor-pentatonic")
+	generateCmd.Flags().StringSliceVar(&genNotes, "notes", nil, "Allowed notes instead of a scale, e.g. A2,C3,E3")
+	generateCmd.Flags().IntVar(&genMaxJump, "max-jump", 0, "Largest interval between notes in semitones (0 = unlimited)")
+	generateCmd.Flags().Float64Var(&genRest, "rest", 0, "Rest probability per step (0-1)")
+	generateCmd.Flags().Float64Var(&genTie, "tie", 0, "Tie probability per step (0-1)")
+	generateCmd.Flags().Float64Var(&genSlide, "slide", 0, "Slide probability per step (0-1)")
+	generateCmd.Flags().Float64Var(&genAccent, "accent", 0, "Accent probability per step (0-1)")
+	generateCmd.Flags().Float64Var(&genDownbeatAccent, "downbeat-accent", 0, "Accent probability on steps 1, 5, 9, 13 (0-1)")
+	_ = generateCmd.MarkFlagRequired("output")
+	rootCmd.AddCommand(generateCmd)
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	format := converter.DetectFormat(outputFile)
+	if format == converter.FormatUnknown || format.InputOnly() {
+		return fmt.Errorf("cannot write %s: use a .seq, .syx, or .mid output", outputFile)
+	}
+	if genSeed == 0 {
+		genSeed = time.Now().UnixNano()
+	}
+	opts := generate.Options{Steps: genSteps, Tempo: genTempo, Seed: genSeed}
+
+	var pattern *converter.Pattern
+	if genArp != "" && genModel != "" {
+		return fmt.Errorf("--arp and --model cannot be combined")
+	}
+	switch {
+	case genModel != "":
+		model, err := generate.LoadModel(genModel)
+		if err != nil {
+			return err
+		}
+		// The model knows the corpus' usual length and tempo
+		if !cmd.Flags().Changed("steps") {
+			opts.Steps = 0
+		}
+		if !cmd.Flags().Changed("tempo") {
+			opts.Tempo = 0
+		}
+		if pattern, err = model.Generate(opts); err != nil {
+			return err
+		}
+	case genArp != "":
+		if genOctave < 0 || genOctave > 9 {
+			return fmt.Errorf("invalid --octave %d", genOctave)
+		}
+		chord, err := generate.ParseChord(genArp, uint8(12*(genOctave+1)))
+		if err != nil {
+			return err
+		}
+		if pattern, err = generate.Arp(chord, genStyle, genOctaves, opts); err != nil {
+			return err
+		}
+	default:
+		style, err := generateStyle(cmd)
+		if err != nil {
+			return err
+		}
+		if pattern, err = generate.Generate(style, opts); err != nil {
+			return err
+		}
+	}
+
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+	data, err := conv.GeneratePattern(pattern, format)
+	if err != nil {
+		return fmt.Errorf("generation failed: %w", err)
+	}
+	if err := converter.WriteFileAtomic(outputFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	printWarnings(conv.Warnings())
+	fmt.Printf("Generated %s (seed %d)\n", outputFile, genSeed)
+	return nil
+}
+
+// generateStyle loads the --preset style and applies the flags given on the
+// command line over it
+func generateStyle(cmd *cobra.Command) (*generate.Style, error) {
+	style, err := generate.LoadStyle(genPreset)
+	if err != nil {
+		return nil, err
+	}
+	flags := cmd.Flags()
+	if flags.Changed("octave") {
+		style.Octave = genOctave
+	}
+	if flags.Changed("octaves") {
+		style.Range = genOctaves
+	}
+	if flags.Changed("root") {
+		style.Root = genRoot
+		style.Notes = nil
+	}
+	if flags.Changed("scale") {
+		style.Scale = genScale
+		style.Notes = nil
+	}
+	if flags.Changed("notes") {
+		style.Notes = genNotes
+	}
+	if flags.Changed("max-jump") {
+		style.MaxJump = genMaxJump
+	}
+	for name, p := range map[string]*float64{
+		"rest":            &style.Rest,
+		"tie":             &style.Tie,
+		"slide":           &style.Slide,
+		"accent":          &style.Accent,
+		"downbeat-accent": &style.DownbeatAccent,
+	} {
+		if flags.Changed(name) {
+			*p, _ = flags.GetFloat64(name)
+		}
+	}
+	if err := style.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid style: %w", err)
+	}
+	return style, nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noHistory    bool
+	historyDir   string
+	historyLimit int
+	historyForce bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past conversions",
+	Long: `Every conversion is recorded with its input, outputs, device, options,
+warnings, and the content hash of each file, so you can trace how an output
+was produced. Files a conversion overwrote are kept, so it can be undone.
+Disable recording with --no-history.
+
+Examples:
+  synthtribe2midi history
+  synthtribe2midi history show 3fa2
+  synthtribe2midi history undo 3fa2`,
+	Args: cobra.NoArgs,
+	RunE: runHistoryList,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details of a conversion",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+}
+
+var historyUndoCmd = &cobra.Command{
+	Use:   "undo <id>",
+	Short: "Undo a conversion, restoring overwritten files",
+	Long: `Delete the files a conversion created and restore the ones it
+overwrote. Outputs changed since the conversion are left alone unless --force
+is given.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runHistoryUndo,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noHistory, "no-history", false, "Do not record conversions in the history")
+	historyCmd.PersistentFlags().StringVar(&historyDir, "history-dir", "", "History directory (default: user config dir)")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Show only the most recent N conversions (0 = all)")
+	historyUndoCmd.Flags().BoolVar(&historyForce, "force", false, "Undo even if outputs were modified since")
+
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyUndoCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func openHistory() (*history.Log, error) {
+	dir := historyDir
+	if dir == "" {
+		var err error
+		if dir, err = history.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	return history.Open(dir)
+}
+
+// conversionRecord describes a conversion by the running command with the
+// current --device and options
+func conversionRecord(cmd *cobra.Command, input string, outputs ...string) history.Record {
+	rec := history.Record{
+		Command: cmd.Name(),
+		Device:  deviceName,
+		Options: getOptions(),
+		Input:   history.File{Path: input, Format: string(converter.DetectFormat(input))},
+	}
+	if info, ok := devices.LookupInfo(deviceName); ok {
+		rec.Device = info.ID
+	}
+	for _, out := range outputs {
+		rec.Outputs = append(rec.Outputs, history.File{Path: out, Format: string(converter.DetectFormat(out))})
+	}
+	return rec
+}
+
+// recordConversion runs write, which creates the record's outputs, and adds
+// the conversion to the history unless --no-history is set. A history that
+// cannot be opened does not stop the conversion. With --preserve-mtime the
+// outputs get the modification time of the input.
+func recordConversion(rec history.Record, warnings []converter.Violation, write func() error) error {
+	if keepMTime {
+		write = preservingModTime(rec, write)
+	}
+	if noHistory {
+		return write()
+	}
+	log, err := openHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: conversion not recorded: %v\n", err)
+		return write()
+	}
+	return log.Record(&rec, func() error {
+		if err := write(); err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			rec.Warnings = append(rec.Warnings, w.String())
+		}
+		return nil
+	})
+}
+
+// skipUpToDate reports, with --skip-up-to-date, whether the record's
+// outputs are already newer than its input and, when the history has the
+// last conversion, still hold what it wrote with the same device and options
+func skipUpToDate(rec history.Record) bool {
+	if !skipCurrent {
+		return false
+	}
+	outputs := make([]string, len(rec.Outputs))
+	for i, out := range rec.Outputs {
+		outputs[i] = out.Path
+	}
+	if !converter.UpToDate(rec.Input.Path, outputs...) {
+		return false
+	}
+	if !noHistory {
+		if log, err := openHistory(); err == nil {
+			if ok, err := log.UpToDate(rec); err == nil && !ok {
+				return false
+			}
+		}
+	}
+	fmt.Printf("Skipped %s -> %s: up to date\n", rec.Input.Path, strings.Join(outputs, ", "))
+	return true
+}
+
+// preservingModTime wraps write to copy the input's modification time to
+// the record's outputs
+func preservingModTime(rec history.Record, write func() error) func() error {
+	input := rec.Input.Path
+	outputs := make([]string, len(rec.Outputs))
+	for i, out := range rec.Outputs {
+		outputs[i] = out.Path
+	}
+	return func() error {
+		if err := write(); err != nil {
+			return err
+		}
+		return converter.PreserveModTime(input, outputs...)
+	}
+}
+
+func runHistoryList(cmd *cobra.Command, args []string) error {
+	log, err := openHistory()
+	if err != nil {
+		return err
+	}
+	records, err := log.List()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("No conversions recorded")
+		return nil
+	}
+	if historyLimit > 0 && len(records) > historyLimit {
+		records = records[len(records)-historyLimit:]
+	}
+
+	for _, rec := range records {
+		outputs := make([]string, len(rec.Outputs))
+		for i, out := range rec.Outputs {
+			outputs[i] = filepath.Base(out.Path)
+		}
+		status := ""
+		if rec.Undone != nil {
+			status = "  (undone)"
+		}
+		fmt.Printf("%s  %s  %-9s %s -> %s%s\n", rec.ID, rec.Time.Local().Format("2006-01-02 15:04"),
+			rec.Command, filepath.Base(rec.Input.Path), strings.Join(outputs, ", "), status)
+	}
+	return nil
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	log, err := openHistory()
+	if err != nil {
+		return err
+	}
+	rec, err := log.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("ID:       %s\n", rec.ID)
+	fmt.Printf("Time:     %s\n", rec.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Command:  %s\n", rec.Command)
+	if rec.From != "" {
+		fmt.Printf("Device:   %s -> %s\n", rec.From, rec.Device)
+	} else {
+		fmt.Printf("Device:   %s\n", rec.Device)
+	}
+	fmt.Printf("Options:  %s\n", formatOptions(rec.Options))
+	fmt.Printf("Input:    %s (%s)\n          sha256 %s\n", rec.Input.Path, rec.Input.Format, rec.Input.Hash)
+	for _, out := range rec.Outputs {
+		note := ""
+		if out.Backup != "" {
+			note = ", overwrote an existing file"
+		}
+		fmt.Printf("Output:   %s (%s%s)\n          sha256 %s\n", out.Path, out.Format, note, out.Hash)
+	}
+	for _, w := range rec.Warnings {
+		fmt.Printf("Warning:  %s\n", w)
+	}
+	if rec.Undone != nil {
+		fmt.Printf("Undone:   %s\n", rec.Undone.Local().Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+func runHistoryUndo(cmd *cobra.Command, args []string) error {
+	log, err := openHistory()
+	if err != nil {
+		return err
+	}
+	rec, err := log.Undo(args[0], historyForce)
+	if err != nil {
+		return err
+	}
+	for _, out := range rec.Outputs {
+		if out.Backup != "" {
+			fmt.Printf("Restored %s\n", out.Path)
+		} else {
+			fmt.Printf("Removed  %s\n", out.Path)
+		}
+	}
+	return nil
+}
+
+// formatOptions summarizes the conversion options that differ from defaults
+func formatOptions(opts converter.ConvertOptions) string {
+	var parts []string
+	if opts.GateTrack {
+		parts = append(parts, fmt.Sprintf("gate track (note %d)", opts.GateNote))
+	}
+	if opts.AccentTrack {
+		parts = append(parts, fmt.Sprintf("accent track (note %d)", opts.AccentNote))
+	}
+	if opts.DeviceID != nil {
+		parts = append(parts, fmt.Sprintf("device ID %d", *opts.DeviceID))
+	}
+	if opts.Groove != nil {
+		parts = append(parts, "groove")
+	}
+	if opts.Transpose != 0 {
+		parts = append(parts, fmt.Sprintf("transpose %+d", opts.Transpose))
+	}
+	if opts.Channel != 0 {
+		parts = append(parts, fmt.Sprintf("channel %d", opts.Channel))
+	}
+	if opts.GateLength != 0 && opts.GateLength != converter.DefaultGateLength {
+		parts = append(parts, fmt.Sprintf("gate length %g", opts.GateLength))
+	}
+	if opts.AccentThreshold != 0 && opts.AccentThreshold != converter.DefaultAccentThreshold {
+		parts = append(parts, fmt.Sprintf("accent threshold %d", opts.AccentThreshold))
+	}
+	if opts.GhostThreshold != 0 && opts.GhostThreshold != converter.DefaultGhostThreshold {
+		parts = append(parts, fmt.Sprintf("ghost threshold %d", opts.GhostThreshold))
+	}
+	if opts.SlideMode != "" && opts.SlideMode != converter.SlideInterval {
+		parts = append(parts, "slides "+opts.SlideMode)
+	}
+	if opts.Bar != 0 {
+		parts = append(parts, fmt.Sprintf("bar %d", opts.Bar))
+	}
+	if opts.Downmix != "" {
+		parts = append(parts, "downmix "+opts.Downmix)
+	}
+	if opts.Strict {
+		parts = append(parts, "strict")
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, ", ")
+}
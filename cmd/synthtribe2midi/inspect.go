@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectSummary bool
+	inspectGrid    bool
+	inspectNoColor bool
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [input]",
+	Short: "Show a pattern's contents and features",
+	Long: `Detect the format of a pattern file (.seq, .syx, MIDI, or any other input)
+and print a summary (name, length, tempo, key, density) and its steps as a
+colored terminal grid: note names, accent, slide, and tie marks, and "·"
+for rests, with a row per track for drum and other multi-track patterns.
+Conversions can be checked this way without SynthTribe or a DAW, over SSH
+too.
+
+Examples:
+  synthtribe2midi inspect acid.seq
+  synthtribe2midi inspect groove.prm --device rd8
+  synthtribe2midi inspect acid.seq --summary`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runInspect,
+	SilenceUsage: true,
+}
+
+func init() {
+	inspectCmd.Flags().BoolVarP(&inspectSummary, "summary", "s", false, "Print only the one-line summary, without the grid")
+	inspectCmd.Flags().BoolVarP(&inspectGrid, "grid", "g", false, "Draw the steps as a terminal grid")
+	_ = inspectCmd.Flags().MarkDeprecated("grid", "the grid is shown unless --summary is given")
+	inspectCmd.Flags().BoolVar(&inspectNoColor, "no-color", false, "Disable colors (also honours $NO_COLOR)")
+	rootCmd.AddCommand(inspectCmd)
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+	pattern, err := conv.ReadPatternFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %s\n", args[0], describePattern(pattern))
+	if !inspectSummary {
+		fmt.Println()
+		fmt.Println(render.Grid(pattern, render.GridOptions{
+			NoColor: inspectNoColor || os.Getenv("NO_COLOR") != "",
+			Names:   noteNames,
+		}))
+	}
+	return nil
+}
+
+// describePattern summarizes a pattern for inspect: its name, length, and
+// tracks, then the hits of drum patterns or the analysis of melodic ones
+func describePattern(p *converter.Pattern) string {
+	length := p.StepCount()
+	if p.Length > 0 {
+		length = min(length, p.Length)
+	}
+	desc := fmt.Sprintf("%d steps", length)
+	if p.Name != "" {
+		desc = fmt.Sprintf("%q, %s", p.Name, desc)
+	}
+	if len(p.Tracks) > 0 {
+		desc += fmt.Sprintf(", %d tracks", len(p.Tracks))
+	}
+	if !p.IsDrum() {
+		// Multi-track lines are analyzed as the line a TD-3 would play
+		line := p.Clone()
+		line.SingleTrack()
+		return desc + ", " + analysis.Analyze(line).Describe(noteNames)
+	}
+	hits := 0
+	for _, t := range p.Tracks {
+		for _, s := range t.Steps[:min(len(t.Steps), length)] {
+			if s.Gate {
+				hits++
+			}
+		}
+	}
+	return fmt.Sprintf("%s, %d hits, %.0f BPM", desc, hits, p.Tempo)
+}
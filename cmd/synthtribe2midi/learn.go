@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/generate"
+	"github.com/spf13/cobra"
+)
+
+var learnOrder int
+
+var learnCmd = &cobra.Command{
+	Use:   "learn [files or directories...]",
+	Short: "Learn a generator model from existing patterns",
+	Long: `Build a Markov model of how the steps of your patterns follow each other:
+which notes, rests, accents, slides, and ties come after the previous few
+steps. Use the model with "generate --model" to create new patterns in the
+style of the corpus. Directories are searched recursively; files that are
+not patterns are skipped.
+
+A higher --order copies longer phrases from the corpus; a lower one mixes
+them more freely.
+
+Examples:
+  synthtribe2midi learn ~/patterns -o mystyle.json
+  synthtribe2midi generate --model mystyle.json -o new.seq`,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         runLearn,
+	SilenceUsage: true,
+}
+
+func init() {
+	learnCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Model file path (required)")
+	learnCmd.Flags().IntVar(&learnOrder, "order", generate.DefaultOrder, "Number of previous steps the model looks at")
+	_ = learnCmd.MarkFlagRequired("output")
+	rootCmd.AddCommand(learnCmd)
+}
+
+func runLearn(cmd *cobra.Command, args []string) error {
+	if learnOrder < 1 {
+		return fmt.Errorf("invalid --order %d", learnOrder)
+	}
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+
+	// Patterns the device cannot play would teach the model notes it cannot
+	// generate
+	dev, err := getDevice()
+	if err != nil {
+		return err
+	}
+	model := generate.NewModel(learnOrder)
+	skipped := 0
+	for _, root := range args {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || converter.DetectFormat(path) == converter.FormatUnknown {
+				return nil
+			}
+			pattern, err := conv.ReadPatternFile(path)
+			if err != nil || !validForDevice(pattern, dev) {
+				skipped++
+				return nil
+			}
+			model.Learn(pattern)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if model.Patterns == 0 {
+		return fmt.Errorf("no patterns found in %v", args)
+	}
+
+	if err := model.Save(outputFile); err != nil {
+		return fmt.Errorf("failed to write model: %w", err)
+	}
+	fmt.Printf("Learned %d pattern(s) into %s\n", model.Patterns, outputFile)
+	if skipped > 0 {
+		fmt.Printf("%d file(s) skipped: not patterns or not valid for the %s\n", skipped, dev.Name())
+	}
+	return nil
+}
+
+// validForDevice reports whether the pattern has no error-severity violations
+func validForDevice(p *converter.Pattern, dev converter.Device) bool {
+	for _, v := range p.Validate(dev) {
+		if v.Severity == converter.SeverityError {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,1042 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/transfer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	libraryDir    string
+	libName       string
+	libTags       []string
+	libFilterTag  string
+	libMinRating  int
+	libCollection string
+	libKind       string
+	libSort       string
+	midiPort      string
+	syncStart     string
+	syncDelay     time.Duration
+	syncDryRun    bool
+	syncVerify    bool
+	syncRetries   int
+	syncTimeout   time.Duration
+	syncChanged   bool
+	syncTwoWay    bool
+	syncConflict  string
+	libNote       string
+	libVersion    string
+	libText       bool
+
+	// stdin answers interactive questions, such as two-way sync conflicts
+	stdin = bufio.NewReader(os.Stdin)
+)
+
+var libCmd = &cobra.Command{
+	Use:   "lib",
+	Short: "Manage the pattern library",
+	Long: `Store patterns in a local library and curate them with tags, ratings,
+and named collections.
+
+Examples:
+  synthtribe2midi lib add *.seq --tag acid
+  synthtribe2midi lib list --tag acid --min-rating 4
+  synthtribe2midi lib rate 3f2a9c 5
+  synthtribe2midi lib collection add "live set A" 3f2a9c 81be04
+  synthtribe2midi lib log 3f2a9c
+  synthtribe2midi lib export backup.tar.zst`,
+}
+
+var libInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a library directory",
+	Long: `Create a library at --library (or the default location).
+
+With --text the library is kept as indented JSON text: one file per pattern,
+with its metadata, a line per step, and its data as lines of hex, plus an
+index of patterns and collections. Keep the directory in a git repository to
+get readable diffs of every change. Later lib commands on the directory use
+the text layout automatically; move an existing library over with lib export
+and lib import.
+
+Examples:
+  synthtribe2midi lib init --text --library ~/music/patterns
+  synthtribe2midi lib export old.tar.zst && synthtribe2midi lib import old.tar.zst --library ~/music/patterns`,
+	Args:         cobra.NoArgs,
+	RunE:         runLibInit,
+	SilenceUsage: true,
+}
+
+var libAddCmd = &cobra.Command{
+	Use:   "add <file>...",
+	Short: "Add pattern files to the library",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runLibAdd,
+}
+
+var libListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List patterns in the library",
+	Args:  cobra.NoArgs,
+	RunE:  runLibList,
+}
+
+var libRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a pattern from the library",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLibRemove,
+}
+
+var libGetCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Write a stored pattern to a file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLibGet,
+}
+
+var libUpdateCmd = &cobra.Command{
+	Use:   "update <id> <file>",
+	Short: "Replace a pattern's content, keeping the old content as a version",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runLibUpdate,
+}
+
+var libLogCmd = &cobra.Command{
+	Use:   "log <id>",
+	Short: "Show the versions of a pattern, newest first",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLibLog,
+}
+
+var libRevertCmd = &cobra.Command{
+	Use:   "revert <id> <version>",
+	Short: "Make an earlier version the current content (kept as a new version)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runLibRevert,
+}
+
+var libTagCmd = &cobra.Command{
+	Use:   "tag <id> <tag>...",
+	Short: "Add tags to a pattern",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runLibTag,
+}
+
+var libUntagCmd = &cobra.Command{
+	Use:   "untag <id> <tag>...",
+	Short: "Remove tags from a pattern",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runLibUntag,
+}
+
+var libRateCmd = &cobra.Command{
+	Use:   "rate <id> <1-5>",
+	Short: "Rate a pattern (0 clears the rating)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runLibRate,
+}
+
+var libCollectionCmd = &cobra.Command{
+	Use:   "collection",
+	Short: "Manage named collections of patterns",
+}
+
+var libCollectionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List collections",
+	Args:  cobra.NoArgs,
+	RunE:  runLibCollectionList,
+}
+
+var libCollectionAddCmd = &cobra.Command{
+	Use:   "add <name> <id>...",
+	Short: "Append patterns to a collection, creating it if needed",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runLibCollectionAdd,
+}
+
+var libCollectionRemoveCmd = &cobra.Command{
+	Use:   "remove <name> <id>...",
+	Short: "Remove patterns from a collection",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runLibCollectionRemove,
+}
+
+var libCollectionDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a collection (patterns stay in the library)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLibCollectionDelete,
+}
+
+var libAnalyzeCmd = &cobra.Command{
+	Use:   "analyze [id]...",
+	Short: "Recompute features and automatic tags (all patterns if no IDs given)",
+	RunE:  runLibAnalyze,
+}
+
+var libExportCmd = &cobra.Command{
+	Use:   "export <archive>",
+	Short: "Export the library to a portable archive (.tar.zst, .tar.gz, .tar)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLibExport,
+}
+
+var libImportCmd = &cobra.Command{
+	Use:   "import <archive>",
+	Short: "Import a library archive, merging tags and collections",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLibImport,
+}
+
+var libSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Push a collection into consecutive hardware pattern slots",
+	Long: `Write every pattern of a collection, in order, to consecutive pattern
+memory slots of a connected device and record which slot each one occupies.
+With --incremental each slot is read first and only patterns that differ are
+written, which is much faster than a full restore and leaves matching slots
+untouched; with --dry-run it lists what would change.
+
+With --two-way the device is also synced back: slots edited on the device
+since the last sync become the new version of their library pattern (see
+lib log).
+Slots changed on both sides (or never synced and different) are conflicts,
+resolved as --on-conflict says: keep the device's pattern, keep the
+library's, or fork (save the device's as a new pattern, then write the
+library's). By default each conflict is shown and asked about.
+
+Examples:
+  synthtribe2midi lib sync --collection "live set A" --port TD-3
+  synthtribe2midi lib sync --collection "live set A" --port TD-3 --verify
+  synthtribe2midi lib sync --collection "live set A" --port TD-3 --incremental
+  synthtribe2midi lib sync --collection "live set A" --port TD-3 --two-way --on-conflict fork
+  synthtribe2midi lib sync -c "live set A" --start 2A1 --dry-run`,
+	Args:         cobra.NoArgs,
+	RunE:         runLibSync,
+	SilenceUsage: true,
+}
+
+func init() {
+	libCmd.PersistentFlags().StringVar(&libraryDir, "library", "", "Library directory or synthtribe2midi server URL (default: $SYNTHTRIBE2MIDI_LIBRARY or user config dir)")
+
+	libInitCmd.Flags().BoolVar(&libText, "text", false, "Store the library as git-friendly JSON text files")
+
+	libAddCmd.Flags().StringVar(&libName, "name", "", "Pattern name (default: file name)")
+	libAddCmd.Flags().StringSliceVarP(&libTags, "tag", "t", nil, "Tags to apply")
+
+	libListCmd.Flags().StringVarP(&libFilterTag, "tag", "t", "", "Only show patterns with this tag")
+	libListCmd.Flags().IntVar(&libMinRating, "min-rating", 0, "Only show patterns rated at least this")
+	libListCmd.Flags().StringVarP(&libCollection, "collection", "c", "", "Only show patterns in this collection (in order)")
+	libListCmd.Flags().StringVar(&libKind, "kind", "", "Only show patterns or patches (pattern, patch)")
+	libListCmd.Flags().StringVar(&libSort, "sort", library.SortName, "Sort by name, created (when the pattern was made), or added")
+
+	libGetCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (default: pattern name)")
+	libGetCmd.Flags().StringVar(&libVersion, "version", "", "Write this earlier version (hash or prefix, see lib log)")
+
+	libUpdateCmd.Flags().StringVarP(&libNote, "note", "m", "", "Describe the change in the pattern's log")
+
+	libSyncCmd.Flags().StringVarP(&libCollection, "collection", "c", "", "Collection to push (required)")
+	libSyncCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI output port name (or unique part of it)")
+	libSyncCmd.Flags().StringVar(&syncStart, "start", "", "First slot to write, e.g. 1A1 (default: first slot)")
+	libSyncCmd.Flags().DurationVar(&syncDelay, "delay", 200*time.Millisecond, "Pause between patterns so the device can store each one")
+	libSyncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show the slot layout without sending anything")
+	libSyncCmd.Flags().BoolVar(&syncVerify, "verify", false, "Read every written slot back and compare it with the pattern")
+	libSyncCmd.Flags().IntVar(&syncRetries, "retries", 2, "Rewrite a slot this many times if verification fails")
+	libSyncCmd.Flags().DurationVar(&syncTimeout, "timeout", transfer.DefaultTimeout, "Time to wait for each read-back with --verify or --incremental")
+	libSyncCmd.Flags().BoolVar(&syncChanged, "incremental", false, "Read each slot first and write only the patterns that differ")
+	libSyncCmd.Flags().BoolVar(&syncTwoWay, "two-way", false, "Also keep patterns edited on the device since the last sync")
+	libSyncCmd.Flags().StringVar(&syncConflict, "on-conflict", library.ConflictAsk, "With --two-way, how to resolve slots changed on both sides: "+strings.Join(library.ConflictPolicies, ", "))
+	_ = libSyncCmd.MarkFlagRequired("collection")
+
+	libCollectionCmd.AddCommand(libCollectionListCmd)
+	libCollectionCmd.AddCommand(libCollectionAddCmd)
+	libCollectionCmd.AddCommand(libCollectionRemoveCmd)
+	libCollectionCmd.AddCommand(libCollectionDeleteCmd)
+
+	libCmd.AddCommand(libInitCmd)
+	libCmd.AddCommand(libAddCmd)
+	libCmd.AddCommand(libListCmd)
+	libCmd.AddCommand(libRemoveCmd)
+	libCmd.AddCommand(libGetCmd)
+	libCmd.AddCommand(libUpdateCmd)
+	libCmd.AddCommand(libLogCmd)
+	libCmd.AddCommand(libRevertCmd)
+	libCmd.AddCommand(libTagCmd)
+	libCmd.AddCommand(libUntagCmd)
+	libCmd.AddCommand(libRateCmd)
+	libCmd.AddCommand(libCollectionCmd)
+	libCmd.AddCommand(libSyncCmd)
+	libCmd.AddCommand(libAnalyzeCmd)
+	libCmd.AddCommand(libExportCmd)
+	libCmd.AddCommand(libImportCmd)
+
+	rootCmd.AddCommand(libCmd)
+}
+
+// libraryLocation returns the library directory or server URL to use
+func libraryLocation() (string, error) {
+	dir := libraryDir
+	if dir == "" {
+		dir = os.Getenv("SYNTHTRIBE2MIDI_LIBRARY")
+	}
+	if dir == "" {
+		return library.DefaultDir()
+	}
+	return dir, nil
+}
+
+func openLibrary() (*library.Library, error) {
+	dir, err := libraryLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	var lib *library.Library
+	if library.IsRemote(dir) {
+		lib, err = library.OpenRemote(dir)
+	} else {
+		lib, err = library.Open(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	lib.SetAnalyzer(analyzeLibraryData)
+	if text, ok := lib.Store().(*library.TextStore); ok {
+		text.SetDecoder(decodeLibraryData)
+	}
+	return lib, nil
+}
+
+func runLibInit(cmd *cobra.Command, args []string) error {
+	dir, err := libraryLocation()
+	if err != nil {
+		return err
+	}
+	if library.IsRemote(dir) {
+		return fmt.Errorf("%s is a server; lib init creates a library directory", dir)
+	}
+
+	if libText {
+		if !library.IsText(dir) {
+			if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+				return fmt.Errorf("%s is not empty; create the text library in a new directory and lib import an export of this one", dir)
+			}
+		}
+		if _, err := library.OpenText(dir); err != nil {
+			return err
+		}
+		fmt.Printf("Text library ready in %s\n", dir)
+		return nil
+	}
+	if _, err := library.Open(dir); err != nil {
+		return err
+	}
+	fmt.Printf("Library ready in %s\n", dir)
+	return nil
+}
+
+// analyzeLibraryData analyzes stored pattern data for automatic tagging
+func analyzeLibraryData(format, device string, data []byte) (*analysis.Result, error) {
+	dev, err := libraryDevice(device)
+	if err != nil {
+		return nil, err
+	}
+	return analysis.AnalyzeData(dev, converter.Format(format), data)
+}
+
+// decodeLibraryData parses pattern data for the steps of text library files
+func decodeLibraryData(format, device string, data []byte) (*converter.Pattern, error) {
+	dev, err := libraryDevice(device)
+	if err != nil {
+		return nil, err
+	}
+	return converter.New(dev).ParsePattern(data, converter.Format(format))
+}
+
+// libraryDevice returns the handler for a library entry's device, or for
+// --device when the entry does not name one
+func libraryDevice(device string) (converter.Device, error) {
+	if device == "" {
+		return getDevice()
+	}
+	dev, ok := devices.Lookup(device)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", devices.ErrUnknownDevice, device)
+	}
+	return dev, nil
+}
+
+func runLibAdd(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range args {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		data, release, err := readInput(path)
+		if err != nil {
+			return err
+		}
+
+		format := converter.DetectFormat(path)
+		if format == converter.FormatUnknown {
+			format = converter.DetectFormatFromContent(data)
+		}
+
+		name := libName
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		entry, err := lib.AddCreated(name, string(format), strings.ToLower(deviceName), data, info.ModTime())
+		release()
+		if err != nil {
+			return err
+		}
+		if len(libTags) > 0 {
+			if entry, err = lib.Tag(entry.ID, libTags...); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Added %s  %s\n", entry.ID, entry.Name)
+	}
+	return nil
+}
+
+func runLibList(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+
+	if libKind != "" && libKind != library.KindPattern && libKind != library.KindPatch {
+		return fmt.Errorf("invalid --kind %q (use pattern or patch)", libKind)
+	}
+	switch libSort {
+	case library.SortName, library.SortCreated, library.SortAdded:
+	default:
+		return fmt.Errorf("invalid --sort %q (use name, created, or added)", libSort)
+	}
+	entries, err := lib.List(library.Filter{
+		Tag:        libFilterTag,
+		MinRating:  libMinRating,
+		Collection: libCollection,
+		Kind:       libKind,
+		Sort:       libSort,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		format := e.Format
+		if e.IsPatch() {
+			format = "patch"
+		}
+		date := ""
+		switch libSort {
+		case library.SortCreated:
+			date = e.CreatedAt().Local().Format("2006-01-02") + "  "
+		case library.SortAdded:
+			date = e.Added.Local().Format("2006-01-02") + "  "
+		}
+		fmt.Printf("%s  %s%-24s %-5s %-5s %s\n", e.ID, date, e.Name, format, stars(e.Rating), strings.Join(e.Tags, ","))
+	}
+	fmt.Printf("%d pattern(s)\n", len(entries))
+	return nil
+}
+
+func runLibRemove(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	if err := lib.Remove(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", args[0])
+	return nil
+}
+
+func runLibGet(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+
+	entry, data, err := lib.Data(args[0])
+	if err != nil {
+		return err
+	}
+	format := entry.Format
+	if libVersion != "" {
+		var v *library.Version
+		if _, v, data, err = lib.VersionData(entry.ID, libVersion); err != nil {
+			return err
+		}
+		format = v.Format
+	}
+
+	output := outputFile
+	if output == "" {
+		output = entry.Name + "." + format
+		if format == string(converter.FormatMIDI) {
+			output = entry.Name + ".mid"
+		}
+	}
+	if err := converter.WriteFileAtomic(output, data, 0644); err != nil {
+		return err
+	}
+	if keepMTime && !entry.Created.IsZero() {
+		if err := converter.SetModTime(entry.Created, output); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Wrote %s -> %s\n", entry.ID, output)
+	return nil
+}
+
+func runLibUpdate(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	format := converter.DetectFormat(args[1])
+	if format == converter.FormatUnknown {
+		format = converter.DetectFormatFromContent(data)
+	}
+
+	entry, err := lib.Update(args[0], string(format), data, libNote)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s  %s (version %s)\n", entry.ID, entry.Name, entry.Hash[:12])
+	return nil
+}
+
+func runLibLog(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	entry, versions, err := lib.History(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s  %s\n", entry.ID, entry.Name)
+	for i, v := range versions {
+		current := " "
+		if i == 0 {
+			current = "*"
+		}
+		fmt.Printf("%s %s  %s  %-4s %s\n", current, v.ShortHash(), v.Saved.Local().Format("2006-01-02 15:04"), v.Format, v.Note)
+	}
+	return nil
+}
+
+func runLibRevert(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	entry, err := lib.Revert(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reverted %s  %s to version %s\n", entry.ID, entry.Name, entry.Hash[:12])
+	return nil
+}
+
+func runLibTag(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	entry, err := lib.Tag(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s tags: %s\n", entry.ID, strings.Join(entry.Tags, ", "))
+	return nil
+}
+
+func runLibUntag(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	entry, err := lib.Untag(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s tags: %s\n", entry.ID, strings.Join(entry.Tags, ", "))
+	return nil
+}
+
+func runLibRate(cmd *cobra.Command, args []string) error {
+	rating, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid rating %q", args[1])
+	}
+
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	entry, err := lib.Rate(args[0], rating)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s rated %s\n", entry.ID, stars(entry.Rating))
+	return nil
+}
+
+func runLibCollectionList(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	colls, err := lib.Collections()
+	if err != nil {
+		return err
+	}
+	for _, c := range colls {
+		fmt.Printf("%-24s %d pattern(s)\n", c.Name, len(c.Patterns))
+	}
+	return nil
+}
+
+func runLibCollectionAdd(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	coll, err := lib.AddToCollection(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d pattern(s)\n", coll.Name, len(coll.Patterns))
+	return nil
+}
+
+func runLibCollectionRemove(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	coll, err := lib.RemoveFromCollection(args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d pattern(s)\n", coll.Name, len(coll.Patterns))
+	return nil
+}
+
+func runLibCollectionDelete(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	if err := lib.DeleteCollection(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted collection %s\n", args[0])
+	return nil
+}
+
+func runLibAnalyze(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+
+	ids := args
+	if len(ids) == 0 {
+		entries, err := lib.List(library.Filter{})
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+	}
+
+	for _, id := range ids {
+		entry, err := lib.Reanalyze(id)
+		if err != nil {
+			return err
+		}
+		summary := "not analyzable"
+		if entry.Analysis != nil {
+			summary = entry.Analysis.Describe(noteNames)
+		}
+		fmt.Printf("%s  %-24s %s\n", entry.ID, entry.Name, summary)
+	}
+	return nil
+}
+
+func runLibExport(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	if err := lib.ExportFile(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Exported library to %s\n", args[0])
+	return nil
+}
+
+func runLibImport(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	importFile := lib.ImportFile
+	if mapInputs {
+		importFile = lib.ImportFileMapped
+	}
+	stats, err := importFile(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d new pattern(s), merged %d existing, %d collection(s)\n",
+		stats.Added, stats.Merged, stats.Collections)
+	return nil
+}
+
+func runLibSync(cmd *cobra.Command, args []string) error {
+	device, err := getDevice()
+	if err != nil {
+		return err
+	}
+	dev, ok := device.(converter.SlotDevice)
+	if !ok {
+		return fmt.Errorf("%s does not support writing pattern slots", device.Name())
+	}
+	if midiPort == "" && !syncDryRun {
+		return fmt.Errorf("--port is required (or use --dry-run)")
+	}
+	if syncTwoWay && midiPort == "" {
+		return fmt.Errorf("--port is required with --two-way")
+	}
+	if err := library.ValidateConflictPolicy(syncConflict); err != nil {
+		return fmt.Errorf("invalid --on-conflict: %w", err)
+	}
+
+	start := 0
+	if syncStart != "" {
+		var err error
+		if start, err = dev.ParseSlot(syncStart); err != nil {
+			return err
+		}
+	}
+
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	assignments, err := lib.AssignSlots(libCollection, start, dev.Slots())
+	if err != nil {
+		return err
+	}
+
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+
+	// Convert everything up front so a bad pattern aborts before any slot is overwritten
+	msgs := make([][]byte, len(assignments))
+	for i, a := range assignments {
+		if a.Entry.IsPatch() {
+			return fmt.Errorf("%s (%s) is a patch dump; send it with lib patch push", a.Entry.ID, a.Entry.Name)
+		}
+		_, data, err := lib.Data(a.Entry.ID)
+		if err != nil {
+			return err
+		}
+		pattern, err := conv.ParsePattern(data, converter.Format(a.Entry.Format))
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", a.Entry.ID, a.Entry.Name, err)
+		}
+		if msgs[i], err = conv.PatternToSlotSyx(pattern, a.Slot); err != nil {
+			return fmt.Errorf("%s (%s): %w", a.Entry.ID, a.Entry.Name, err)
+		}
+		printWarnings(conv.Warnings())
+	}
+
+	if syncTwoWay {
+		return syncBothWays(lib, conv, assignments, msgs)
+	}
+	if syncDryRun && !syncChanged {
+		for _, a := range assignments {
+			fmt.Printf("%-4s <- %s  %s\n", dev.SlotName(a.Slot), a.Entry.ID, a.Entry.Name)
+		}
+		return nil
+	}
+
+	out, err := mididevice.OpenOutput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	opts := transfer.Options{Timeout: syncTimeout, Retries: syncRetries, Verify: syncVerify}
+	if sysexID >= 0 {
+		opts.DeviceID = uint8(sysexID)
+	}
+	send := func(slot int, msg []byte) error { return out.Send(msg) }
+	unchanged := func(slot int, msg []byte) (bool, error) { return false, nil }
+	if syncVerify || syncChanged {
+		requester, ok := dev.(converter.PatternRequester)
+		if !ok {
+			return fmt.Errorf("%s does not support reading slots back for --verify or --incremental", device.Name())
+		}
+		in, err := mididevice.OpenInput(midiPort)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = in.Close() }()
+		if syncVerify {
+			send = func(slot int, msg []byte) error {
+				return transfer.Push(out, in, requester, slot, msg, opts)
+			}
+		}
+		if syncChanged {
+			unchanged = func(slot int, msg []byte) (bool, error) {
+				return transfer.Unchanged(out, in, requester, slot, msg, opts)
+			}
+		}
+	}
+
+	written, skipped := 0, 0
+	for i, a := range assignments {
+		slot := dev.SlotName(a.Slot)
+		same, err := unchanged(a.Slot, msgs[i])
+		if err != nil {
+			return fmt.Errorf("failed to read slot %s: %w", slot, err)
+		}
+		switch {
+		case same:
+			skipped++
+			fmt.Printf("%-4s == %s  %s\n", slot, a.Entry.ID, a.Entry.Name)
+		case syncDryRun:
+			written++
+			fmt.Printf("%-4s <- %s  %s\n", slot, a.Entry.ID, a.Entry.Name)
+		default:
+			if written > 0 {
+				time.Sleep(syncDelay)
+			}
+			if err := send(a.Slot, msgs[i]); err != nil {
+				return fmt.Errorf("failed to write %s to slot %s: %w", a.Entry.ID, slot, err)
+			}
+			written++
+			fmt.Printf("%-4s <- %s  %s\n", slot, a.Entry.ID, a.Entry.Name)
+		}
+		if !syncDryRun {
+			if _, err := lib.RecordSlot(a.Entry.ID, out.Name(), slot); err != nil {
+				return err
+			}
+		}
+	}
+	switch {
+	case syncDryRun:
+		fmt.Printf("%d of %d pattern(s) would be written to %s\n", written, len(assignments), out.Name())
+	case syncChanged:
+		fmt.Printf("Synced %d pattern(s) to %s, %d already in place\n", written, out.Name(), skipped)
+	default:
+		fmt.Printf("Synced %d pattern(s) to %s\n", written, out.Name())
+	}
+	return nil
+}
+
+// syncBothWays reads every slot of the layout and reconciles it with the
+// library: library changes are written, device edits are kept in the
+// library, and conflicts are resolved by --on-conflict
+func syncBothWays(lib *library.Library, conv *converter.Converter, assignments []library.SlotAssignment, msgs [][]byte) error {
+	dev := conv.GetDevice()
+	requester, ok := dev.(converter.PatternRequester)
+	if !ok {
+		return fmt.Errorf("%s does not support reading slots for --two-way", dev.Name())
+	}
+
+	out, err := mididevice.OpenOutput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+	in, err := mididevice.OpenInput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	opts := transfer.Options{Timeout: syncTimeout, Retries: syncRetries, Verify: syncVerify}
+	if sysexID >= 0 {
+		opts.DeviceID = uint8(sysexID)
+	}
+
+	written, kept := 0, 0
+	for i, a := range assignments {
+		slot := requester.SlotName(a.Slot)
+		want, err := dev.ParseSyx(msgs[i])
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", a.Entry.ID, a.Entry.Name, err)
+		}
+		dump, err := transfer.Pull(out, in, requester, a.Slot, opts)
+		if err != nil {
+			return err
+		}
+		have, err := dev.ParseSyx(dump)
+		if err != nil {
+			return fmt.Errorf("slot %s: %w", slot, err)
+		}
+		var synced *converter.Pattern
+		if e, r, err := lib.SlotEntry(out.Name(), slot); err != nil {
+			return err
+		} else if e != nil {
+			// The version written at the last sync; older records only
+			// know the pattern
+			version := r.Hash
+			if version == "" {
+				version = e.Hash
+			}
+			if synced, err = storedPattern(lib, conv, requester, e.ID, version, a.Slot); err != nil {
+				return err
+			}
+		}
+
+		state := library.CompareSlot(dev, have, want, synced)
+		resolution := ""
+		switch state {
+		case library.SlotLibraryChanged:
+			resolution = library.ConflictLibrary
+		case library.SlotDeviceChanged:
+			resolution = library.ConflictDevice
+		case library.SlotConflict:
+			resolution = syncConflict
+			if resolution == library.ConflictAsk && !syncDryRun {
+				if resolution, err = askConflict(slot, a.Entry, want, have); err != nil {
+					return err
+				}
+			}
+		}
+		if syncDryRun {
+			fmt.Printf("%-4s %-18s %s  %s\n", slot, state, a.Entry.ID, a.Entry.Name)
+			continue
+		}
+
+		entry := a.Entry
+		switch resolution {
+		case library.ConflictDevice:
+			e, err := lib.Update(a.Entry.ID, string(converter.FormatSyx), dump, fmt.Sprintf("edited on %s in slot %s", out.Name(), slot))
+			if err != nil {
+				return err
+			}
+			entry = *e
+			kept++
+			fmt.Printf("%-4s -> %s  %s (kept device edit)\n", slot, e.ID, e.Name)
+		case library.ConflictFork, library.ConflictLibrary:
+			if resolution == library.ConflictFork {
+				e, err := lib.Add(a.Entry.Name+" (device)", string(converter.FormatSyx), strings.ToLower(deviceName), dump)
+				if err != nil {
+					return err
+				}
+				kept++
+				fmt.Printf("%-4s -> %s  %s (forked device edit)\n", slot, e.ID, e.Name)
+			}
+			if written > 0 {
+				time.Sleep(syncDelay)
+			}
+			if err := transfer.Push(out, in, requester, a.Slot, msgs[i], opts); err != nil {
+				return fmt.Errorf("failed to write %s to slot %s: %w", a.Entry.ID, slot, err)
+			}
+			written++
+			fmt.Printf("%-4s <- %s  %s\n", slot, a.Entry.ID, a.Entry.Name)
+		default:
+			fmt.Printf("%-4s == %s  %s\n", slot, a.Entry.ID, a.Entry.Name)
+		}
+		if _, err := lib.RecordSlot(entry.ID, out.Name(), slot); err != nil {
+			return err
+		}
+	}
+	if !syncDryRun {
+		fmt.Printf("Synced %s: wrote %d pattern(s), kept %d device edit(s)\n", out.Name(), written, kept)
+	}
+	return nil
+}
+
+// storedPattern returns a version of a library pattern as the device stores
+// it in a slot
+func storedPattern(lib *library.Library, conv *converter.Converter, dev converter.SlotDevice, id, version string, slot int) (*converter.Pattern, error) {
+	e, v, data, err := lib.VersionData(id, version)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := conv.ParsePattern(data, converter.Format(v.Format))
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %w", e.ID, e.Name, err)
+	}
+	msg, err := dev.GenerateSyxSlot(pattern, slot)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %w", e.ID, e.Name, err)
+	}
+	return conv.GetDevice().ParseSyx(msg)
+}
+
+// askConflict shows how a slot's library and device patterns differ and
+// asks which to keep
+func askConflict(slot string, e library.Entry, lib, device *converter.Pattern) (string, error) {
+	fmt.Printf("%s: %s  %s changed both in the library and on the device (library -> device):\n", slot, e.ID, e.Name)
+	for _, d := range converter.Diff(lib, device) {
+		fmt.Printf("  %s\n", d)
+	}
+	for {
+		fmt.Print("Keep [d]evice, keep [l]ibrary, or [f]ork? ")
+		line, err := stdin.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "d", library.ConflictDevice:
+			return library.ConflictDevice, nil
+		case "l", library.ConflictLibrary:
+			return library.ConflictLibrary, nil
+		case "f", library.ConflictFork:
+			return library.ConflictFork, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("no answer for slot %s: %w", slot, err)
+		}
+	}
+}
+
+// stars renders a rating as filled and empty stars
+func stars(rating int) string {
+	if rating == 0 {
+		return "-"
+	}
+	return strings.Repeat("★", rating) + strings.Repeat("☆", library.MaxRating-rating)
+}
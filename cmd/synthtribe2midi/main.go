@@ -2,14 +2,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/james-see/synthtribe2midi/pkg/api"
+	"github.com/james-see/synthtribe2midi/pkg/config"
 	"github.com/james-see/synthtribe2midi/pkg/converter"
 	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/pack"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
 	"github.com/james-see/synthtribe2midi/pkg/tui"
 	"github.com/spf13/cobra"
 )
@@ -21,12 +29,43 @@ var (
 )
 
 var (
-	outputFile string
-	deviceName string
-	serverPort int
+	outputFile  string
+	deviceName  string
+	serverPort  int
+	gateTrack   bool
+	gateNote    uint8
+	accentTrack bool
+	accentNote  uint8
+	sysexID     int
+	midiBackend string
+	keepMTime   bool
+	skipCurrent bool
+	mapInputs   bool
+	grooveFile  string
+	groove      *converter.Groove
+	accentFile  string
+	accents     *converter.AccentMap
+
+	convertOutputs []string
+	convertFormats []string
+
+	serverCORS         api.CORSConfig
+	serverOptionsWrite bool
+
+	configFile string
+	userConfig *config.Config
+	presetName string
+
+	tuiInline    bool
+	tuiOutputDir string
+	tuiCollision string
+
+	convOpts  converter.ConvertOptions
+	noteNames converter.NoteNaming
 )
 
 func main() {
+	api.SetBuildInfo(version, commit, date)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -48,56 +87,134 @@ Examples:
   synthtribe2midi tui
   synthtribe2midi serve --port 8080`,
 	Version: fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if sysexID < -1 || sysexID > 127 {
+			return fmt.Errorf("--device-id must be between 0 and 127, or -1 to keep the source ID; got %d", sysexID)
+		}
+		if gateNote > 127 {
+			return fmt.Errorf("--gate-note must be between 0 and 127, got %d", gateNote)
+		}
+		if accentNote > 127 {
+			return fmt.Errorf("--accent-note must be between 0 and 127, got %d", accentNote)
+		}
+		if grooveFile != "" {
+			var err error
+			if groove, err = converter.LoadGroove(grooveFile); err != nil {
+				return err
+			}
+		}
+		if accentFile != "" {
+			if cmd.Flags().Changed("accent-threshold") {
+				return errors.New("--accent-from and --accent-threshold both set the accent threshold; use one")
+			}
+			var err error
+			if accents, err = converter.LoadAccents(accentFile); err != nil {
+				return err
+			}
+		}
+		if err := noteNames.Validate(); err != nil {
+			return fmt.Errorf("invalid --note-names or --middle-c: %w", err)
+		}
+		if err := devices.Validate(deviceName); err != nil {
+			return err
+		}
+		var err error
+		if userConfig, err = loadConfig(); err != nil {
+			return err
+		}
+		preset, err := userConfig.Preset(presetName)
+		if err != nil {
+			return err
+		}
+		dev, err := getDevice()
+		if err != nil {
+			return err
+		}
+		applyConvertConfig(cmd, preset, converter.DeviceCapabilities(dev).Defaults)
+		return mididevice.Use(midiBackend)
+	},
 }
 
 var convertCmd = &cobra.Command{
 	Use:   "convert <input>",
 	Short: "Auto-detect and convert between formats",
-	Long:  `Automatically detects input format and converts to the output format based on file extension.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runConvert,
+	Long: `Automatically detects input format and converts to the output format based on file extension.
+
+Give -o more than once, or --formats, to write several formats in one pass:
+the input is parsed once, so the outputs always hold the same pattern.
+
+Examples:
+  synthtribe2midi convert line.mid -o line.seq
+  synthtribe2midi convert line.mid -o line.seq -o line.syx
+  synthtribe2midi convert line.mid --formats seq,syx
+
+A bank .syx file with several pattern dumps, such as a full backup, needs
+--pattern-index to pick one pattern, or --all to convert every pattern
+(--merge writes them all to one MIDI file, a track per pattern):
+  synthtribe2midi convert backup.syx --pattern-index 3 -o 1A3.mid
+  synthtribe2midi convert backup.syx --all -o backup.mid
+  synthtribe2midi convert backup.syx --all --merge -o backup.mid`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConvert,
 }
 
 var midi2seqCmd = &cobra.Command{
 	Use:   "midi2seq <input.mid>",
 	Short: "Convert MIDI to .seq format",
 	Args:  cobra.ExactArgs(1),
-	RunE:  runMIDIToSeq,
+	RunE:  runConversion(converter.FormatMIDI, converter.FormatSeq),
 }
 
 var seq2midiCmd = &cobra.Command{
 	Use:   "seq2midi <input.seq>",
 	Short: "Convert .seq to MIDI format",
 	Args:  cobra.ExactArgs(1),
-	RunE:  runSeqToMIDI,
+	RunE:  runConversion(converter.FormatSeq, converter.FormatMIDI),
 }
 
 var midi2syxCmd = &cobra.Command{
 	Use:   "midi2syx <input.mid>",
 	Short: "Convert MIDI to .syx format",
 	Args:  cobra.ExactArgs(1),
-	RunE:  runMIDIToSyx,
+	RunE:  runConversion(converter.FormatMIDI, converter.FormatSyx),
 }
 
 var syx2midiCmd = &cobra.Command{
 	Use:   "syx2midi <input.syx>",
 	Short: "Convert .syx to MIDI format",
 	Args:  cobra.ExactArgs(1),
-	RunE:  runSyxToMIDI,
+	RunE:  runConversion(converter.FormatSyx, converter.FormatMIDI),
 }
 
 var seq2syxCmd = &cobra.Command{
 	Use:   "seq2syx <input.seq>",
 	Short: "Convert .seq to .syx format",
 	Args:  cobra.ExactArgs(1),
-	RunE:  runSeqToSyx,
+	RunE:  runConversion(converter.FormatSeq, converter.FormatSyx),
 }
 
 var syx2seqCmd = &cobra.Command{
 	Use:   "syx2seq <input.syx>",
 	Short: "Convert .syx to .seq format",
 	Args:  cobra.ExactArgs(1),
-	RunE:  runSyxToSeq,
+	RunE:  runConversion(converter.FormatSyx, converter.FormatSeq),
+}
+
+var identifyCmd = &cobra.Command{
+	Use:   "identify <input.syx>",
+	Short: "Identify the manufacturer and model of SysEx messages",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runIdentify,
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compare the patterns in two files step by step",
+	Long:  `Parses both files (any supported format) and lists every step and field that differs.`,
+	Args:  cobra.ExactArgs(2),
+	RunE:  runDiff,
+	// A difference is a result, not a usage mistake
+	SilenceUsage: true,
 }
 
 var tuiCmd = &cobra.Command{
@@ -114,11 +231,37 @@ var serveCmd = &cobra.Command{
 
 func init() {
 	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&deviceName, "device", "d", "td3", "Target device (td3)")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file (default: config.yaml in the user config directory)")
+	rootCmd.PersistentFlags().StringVar(&presetName, "preset", "", "Conversion options preset from the config file, used instead of its convert section")
+	rootCmd.PersistentFlags().StringVarP(&deviceName, "device", "d", devices.DefaultID, "Target device ("+strings.Join(devices.IDs(), ", ")+")")
+	rootCmd.PersistentFlags().BoolVar(&gateTrack, "gate-track", false, "Add a fixed-pitch gate track to MIDI output (accents as velocity)")
+	rootCmd.PersistentFlags().Uint8Var(&gateNote, "gate-note", converter.DefaultGateNote, "MIDI note used for the gate track")
+	rootCmd.PersistentFlags().BoolVar(&accentTrack, "accent-track", false, "Add a fixed-pitch accent trigger track to MIDI output")
+	rootCmd.PersistentFlags().Uint8Var(&accentNote, "accent-note", converter.DefaultAccentNote, "MIDI note used for the accent track")
+	rootCmd.PersistentFlags().IntVar(&sysexID, "device-id", -1, "SysEx device ID (0-127) for .syx output; default keeps the source ID")
+	rootCmd.PersistentFlags().BoolVar(&skipCurrent, "skip-up-to-date", false, "Skip conversions whose outputs are newer than the input and unchanged since")
+	rootCmd.PersistentFlags().StringVar(&grooveFile, "groove", "", "Apply the timing and dynamics of this MIDI file to MIDI output")
+	rootCmd.PersistentFlags().IntVar(&convOpts.Transpose, "transpose", 0, "Transpose the output by this many semitones")
+	rootCmd.PersistentFlags().IntVar(&convOpts.Channel, "channel", 0, "MIDI channel (1-16) to write, and the only one read from MIDI input (default: write 1, read all)")
+	rootCmd.PersistentFlags().Float64Var(&convOpts.GateLength, "gate-length", 0, fmt.Sprintf("Fraction of a step that plain notes sound in MIDI output, 0-1 (default %g)", converter.DefaultGateLength))
+	rootCmd.PersistentFlags().IntVar(&convOpts.AccentThreshold, "accent-threshold", 0, fmt.Sprintf("Lowest MIDI velocity read as an accent (default %d)", converter.DefaultAccentThreshold))
+	rootCmd.PersistentFlags().IntVar(&convOpts.GhostThreshold, "ghost-threshold", 0, fmt.Sprintf("MIDI velocity below which drum hits are read as ghost notes; 1 reads none (default %d)", converter.DefaultGhostThreshold))
+	rootCmd.PersistentFlags().StringVar(&accentFile, "accent-from", "", "Read accents and velocities from MIDI input the way this reference MIDI file plays them, instead of with --accent-threshold")
+	rootCmd.PersistentFlags().StringVar(&convOpts.SlideMode, "slide-mode", "", "How slides are read from MIDI: "+strings.Join(converter.SlideModes, ", ")+" (default "+converter.SlideInterval+")")
+	rootCmd.PersistentFlags().IntVar(&convOpts.Bar, "bar", 0, "Read only this bar (1-based) of multi-bar MIDI input (default: fold all bars)")
+	rootCmd.PersistentFlags().StringVar(&convOpts.Downmix, "downmix", "", "Read every bar of MIDI input and fit clips longer than the device's patterns: "+strings.Join(converter.DownmixModes, ", ")+" (default: fold all bars, without warnings)")
+	rootCmd.PersistentFlags().BoolVar(&convOpts.Strict, "strict", false, "Fail conversions that produce warnings instead of fixing the pattern up")
+	rootCmd.PersistentFlags().BoolVar(&convOpts.VerifyRoundtrip, "verify-roundtrip", false, "Parse every generated .seq and .syx back and fail the conversion if it reads differently")
+	rootCmd.PersistentFlags().BoolVar(&convOpts.Recover, "recover", false, "Salvage what can be read of damaged .seq and .syx input, reading lost steps as rests and warning which bytes were unusable")
+	rootCmd.PersistentFlags().BoolVar(&keepMTime, "preserve-mtime", false, "Give output files the modification time of their input")
+	rootCmd.PersistentFlags().BoolVar(&mapInputs, "mmap", false, "Memory-map input files and archives instead of reading them into RAM (falls back to reading)")
+	rootCmd.PersistentFlags().StringVar(&noteNames.Style, "note-names", converter.NoteNamesSharps, "How notes are shown: "+strings.Join(converter.NoteNamingStyles, ", "))
+	rootCmd.PersistentFlags().IntVar(&noteNames.MiddleC, "middle-c", 4, "Octave of middle C (MIDI note 60) in shown note names: 4 (C4) or 3 (C3, as in many DAWs)")
+	rootCmd.PersistentFlags().StringVar(&midiBackend, "midi-backend", "", "MIDI backend for hardware I/O (rtmidi, portmidi, alsa, virtual; default: first available)")
 
 	// Convert command
-	convertCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (required)")
-	_ = convertCmd.MarkFlagRequired("output")
+	convertCmd.Flags().StringArrayVarP(&convertOutputs, "output", "o", nil, "Output file path (repeat for several outputs)")
+	convertCmd.Flags().StringSliceVar(&convertFormats, "formats", nil, "Also write these formats (seq, syx, midi), named after the first output or the input")
 
 	// midi2seq command
 	midi2seqCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output .seq file path")
@@ -137,9 +280,23 @@ func init() {
 
 	// syx2seq command
 	syx2seqCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output .seq file path")
+	for _, cmd := range []*cobra.Command{convertCmd, syx2midiCmd, syx2seqCmd} {
+		addBankFlags(cmd)
+	}
 
 	// serve command
+	tuiCmd.Flags().BoolVar(&tuiInline, "inline", false, "Run without the alternate screen in a compact layout, leaving results in the scrollback")
+	tuiCmd.Flags().StringVar(&tuiOutputDir, "output-dir", "", "Write converted files to this directory (default: next to the input)")
+	tuiCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI port of the device, for pushing generated patterns")
+	tuiCmd.Flags().StringVar(&packRegistry, "registry", "", "Pack registry URL for the packs screen (default: $"+pack.RegistryEnv+" or pack.registry in the config file)")
+	tuiCmd.Flags().StringVar(&libraryDir, "library", "", "Library directory or server URL packs are installed into (default: $SYNTHTRIBE2MIDI_LIBRARY or user config dir)")
+	tuiCmd.Flags().StringVar(&tuiCollision, "on-collision", service.CollisionAsk, "When an output was already written from another input this session: "+strings.Join(service.CollisionPolicies, ", "))
 	serveCmd.Flags().IntVarP(&serverPort, "port", "p", 8080, "Server port")
+	serveCmd.Flags().StringSliceVar(&serverCORS.Origins, "cors-origin", nil, "Allowed CORS origin, e.g. https://app.example.com (repeatable; \"*\" for any, \"none\" to disable; env "+api.EnvCORSOrigins+", default *)")
+	serveCmd.Flags().StringSliceVar(&serverCORS.Methods, "cors-methods", nil, "Allowed CORS methods (env "+api.EnvCORSMethods+")")
+	serveCmd.Flags().StringSliceVar(&serverCORS.Headers, "cors-headers", nil, "Allowed CORS request headers (env "+api.EnvCORSHeaders+")")
+	serveCmd.Flags().BoolVar(&serverCORS.Credentials, "cors-credentials", false, "Allow credentialed CORS requests (requires explicit --cors-origin)")
+	serveCmd.Flags().BoolVar(&serverOptionsWrite, "allow-options-write", false, "Let clients change the default conversion options (PUT /api/v1/options), which are saved to the config file")
 
 	// Add commands
 	rootCmd.AddCommand(convertCmd)
@@ -149,16 +306,100 @@ func init() {
 	rootCmd.AddCommand(syx2midiCmd)
 	rootCmd.AddCommand(seq2syxCmd)
 	rootCmd.AddCommand(syx2seqCmd)
+	rootCmd.AddCommand(identifyCmd)
+	rootCmd.AddCommand(diffCmd)
 	rootCmd.AddCommand(tuiCmd)
 	rootCmd.AddCommand(serveCmd)
 }
 
-func getDevice() converter.Device {
-	switch strings.ToLower(deviceName) {
-	case "td3", "td-3":
-		return devices.NewTD3()
-	default:
-		return devices.NewTD3()
+// getDevice returns a handler for --device, which the root command has
+// validated
+func getDevice() (converter.Device, error) {
+	return devices.New(deviceName)
+}
+
+func getOptions() converter.ConvertOptions {
+	opts := converter.DefaultOptions()
+	opts.GateTrack = gateTrack
+	opts.GateNote = gateNote
+	opts.AccentTrack = accentTrack
+	opts.AccentNote = accentNote
+	if sysexID >= 0 {
+		id := uint8(sysexID)
+		opts.DeviceID = &id
+	}
+	opts.Groove = groove
+	opts.Transpose = convOpts.Transpose
+	opts.Channel = convOpts.Channel
+	opts.GateLength = convOpts.GateLength
+	opts.AccentThreshold = convOpts.AccentThreshold
+	opts.GhostThreshold = convOpts.GhostThreshold
+	opts.Accents = accents
+	opts.SlideMode = convOpts.SlideMode
+	opts.Bar = convOpts.Bar
+	opts.Downmix = convOpts.Downmix
+	opts.Strict = convOpts.Strict
+	opts.VerifyRoundtrip = convOpts.VerifyRoundtrip
+	opts.Recover = convOpts.Recover
+	return opts
+}
+
+// applyConvertConfig sets the conversion flags not given on the command line
+// to the defaults of the config file, or else those of the device
+func applyConvertConfig(cmd *cobra.Command, c config.ConvertConfig, device converter.Defaults) {
+	defaults := c.Options().WithDefaults(device)
+	flags := []struct {
+		name  string
+		apply func()
+	}{
+		{"transpose", func() { convOpts.Transpose = defaults.Transpose }},
+		{"channel", func() { convOpts.Channel = defaults.Channel }},
+		{"gate-length", func() { convOpts.GateLength = defaults.GateLength }},
+		{"accent-threshold", func() { convOpts.AccentThreshold = defaults.AccentThreshold }},
+		{"ghost-threshold", func() { convOpts.GhostThreshold = defaults.GhostThreshold }},
+		{"slide-mode", func() { convOpts.SlideMode = defaults.SlideMode }},
+		{"downmix", func() { convOpts.Downmix = defaults.Downmix }},
+		{"strict", func() { convOpts.Strict = defaults.Strict }},
+		{"verify-roundtrip", func() { convOpts.VerifyRoundtrip = defaults.VerifyRoundtrip }},
+		{"recover", func() { convOpts.Recover = defaults.Recover }},
+		{"gate-track", func() { gateTrack = defaults.GateTrack }},
+		{"gate-note", func() { gateNote = defaults.GateNote }},
+		{"accent-track", func() { accentTrack = defaults.AccentTrack }},
+		{"accent-note", func() { accentNote = defaults.AccentNote }},
+	}
+	for _, f := range flags {
+		if !cmd.Flags().Changed(f.name) {
+			f.apply()
+		}
+	}
+}
+
+// configPath returns the file that settings are saved to
+func configPath() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+	return config.DefaultPath()
+}
+
+func newConverter() (*converter.Converter, error) {
+	opts := getOptions()
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid conversion options: %w", err)
+	}
+	dev, err := getDevice()
+	if err != nil {
+		return nil, err
+	}
+	conv := converter.New(dev)
+	conv.SetOptions(opts)
+	return conv, nil
+}
+
+// printWarnings prints the warnings of a conversion
+func printWarnings(warnings []converter.Violation) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
 	}
 }
 
@@ -172,160 +413,260 @@ func getOutputPath(input, defaultExt string) string {
 
 func runConvert(cmd *cobra.Command, args []string) error {
 	input := args[0]
-	conv := converter.New(getDevice())
-	
-	fmt.Printf("Converting %s -> %s\n", input, outputFile)
-	if err := conv.ConvertFile(input, outputFile); err != nil {
+	outputs, err := convertTargets(input)
+	if err != nil {
+		return err
+	}
+	formats := make([]converter.Format, len(outputs))
+	for i, out := range outputs {
+		if formats[i] = converter.DetectFormat(out); formats[i] == converter.FormatUnknown {
+			return fmt.Errorf("cannot determine output format of %s", out)
+		}
+		if formats[i] == converter.DetectFormat(input) {
+			return fmt.Errorf("unsupported conversion: %s to %s", formats[i], formats[i])
+		}
+	}
+
+	if bankAll {
+		return convertBank(cmd, input, "", outputs, formats)
+	}
+	done, err := convertFile(cmd, input, "", outputs, formats)
+	if err != nil || !done {
 		return err
 	}
-	fmt.Println("Conversion complete!")
+	fmt.Printf("Converted %s -> %s\n", input, strings.Join(outputs, ", "))
 	return nil
 }
 
-func runMIDIToSeq(cmd *cobra.Command, args []string) error {
-	input := args[0]
-	output := getOutputPath(input, ".seq")
-	
-	conv := converter.New(getDevice())
-	data, err := os.ReadFile(input)
-	if err != nil {
-		return err
+// convertTargets returns the outputs of convert: the -o paths, then one per
+// --formats entry named after the first output (or the input)
+func convertTargets(input string) ([]string, error) {
+	if len(convertOutputs) == 0 && len(convertFormats) == 0 {
+		return nil, errors.New("give an output with -o or formats with --formats")
 	}
-	
-	result, err := conv.MIDIToSeq(data)
-	if err != nil {
-		return err
+	base := input
+	if len(convertOutputs) > 0 {
+		base = convertOutputs[0]
 	}
-	
-	if err := os.WriteFile(output, result, 0644); err != nil {
-		return err
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	outputs := append([]string(nil), convertOutputs...)
+	for _, name := range convertFormats {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "midi" {
+			name = "mid"
+		}
+		path := base + "." + name
+		if f := converter.DetectFormat(path); f != converter.FormatSeq && f != converter.FormatSyx && f != converter.FormatMIDI {
+			return nil, fmt.Errorf("unknown format %q (use seq, syx, or midi)", name)
+		}
+		if !slices.Contains(outputs, path) {
+			outputs = append(outputs, path)
+		}
 	}
-	
-	fmt.Printf("Converted %s -> %s\n", input, output)
-	return nil
+	for _, out := range outputs {
+		if filepath.Clean(out) == filepath.Clean(input) {
+			return nil, fmt.Errorf("output %s would overwrite the input", out)
+		}
+	}
+	return outputs, nil
 }
 
-func runSeqToMIDI(cmd *cobra.Command, args []string) error {
-	input := args[0]
-	output := getOutputPath(input, ".mid")
-	
-	conv := converter.New(getDevice())
-	data, err := os.ReadFile(input)
+// runConversion returns the RunE of a fixed conversion command such as
+// midi2seq, which writes next to the input unless -o is given
+func runConversion(from, to converter.Format) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		input := args[0]
+		output := getOutputPath(input, service.Ext(to))
+		if bankAll {
+			return convertBank(cmd, input, from, []string{output}, []converter.Format{to})
+		}
+		done, err := convertFile(cmd, input, from, []string{output}, []converter.Format{to})
+		if err != nil || !done {
+			return err
+		}
+		fmt.Printf("Converted %s -> %s\n", input, output)
+		return nil
+	}
+}
+
+// convertFile converts input, parsed as from (or detected when empty), to
+// each output in the matching format through the service layer, and records
+// the conversion in the history. It reports false when --skip-up-to-date
+// left the outputs alone.
+func convertFile(cmd *cobra.Command, input string, from converter.Format, outputs []string, formats []converter.Format) (bool, error) {
+	rec := conversionRecord(cmd, input, outputs...)
+	if skipUpToDate(rec) {
+		return false, nil
+	}
+	data, release, err := readInput(input)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("failed to read input file: %w", err)
+	}
+	defer release()
+	if data, err = bankPattern(input, data); err != nil {
+		return false, err
 	}
-	
-	result, err := conv.SeqToMIDI(data)
+
+	resp, err := service.Convert(context.Background(), service.ConvertRequest{
+		Data:     data,
+		Filename: input,
+		From:     from,
+		To:       formats,
+		Device:   deviceName,
+		Options:  getOptions(),
+	})
 	if err != nil {
-		return err
+		return false, fmt.Errorf("conversion failed: %w", err)
 	}
-	
-	if err := os.WriteFile(output, result, 0644); err != nil {
-		return err
+	if resp.InputDeviceID != nil {
+		fmt.Printf("SysEx device ID: %d\n", *resp.InputDeviceID)
 	}
-	
-	fmt.Printf("Converted %s -> %s\n", input, output)
-	return nil
+	if err := recordConversion(rec, resp.Warnings, func() error {
+		for i, out := range outputs {
+			if err := converter.WriteFileAtomic(out, resp.Outputs[i].Data, 0644); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	printWarnings(resp.Warnings)
+	return true, nil
 }
 
-func runMIDIToSyx(cmd *cobra.Command, args []string) error {
-	input := args[0]
-	output := getOutputPath(input, ".syx")
-	
-	conv := converter.New(getDevice())
-	data, err := os.ReadFile(input)
-	if err != nil {
-		return err
+// readInput reads an input file, memory-mapped with --mmap so large banks
+// stay on disk; call release once done with data
+func readInput(path string) (data []byte, release func(), err error) {
+	if !mapInputs {
+		data, err = os.ReadFile(path)
+		return data, func() {}, err
 	}
-	
-	result, err := conv.MIDIToSyx(data)
+	f, err := converter.MapFile(path)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	
-	if err := os.WriteFile(output, result, 0644); err != nil {
-		return err
-	}
-	
-	fmt.Printf("Converted %s -> %s\n", input, output)
-	return nil
+	return f.Bytes(), func() { _ = f.Close() }, nil
 }
 
-func runSyxToMIDI(cmd *cobra.Command, args []string) error {
-	input := args[0]
-	output := getOutputPath(input, ".mid")
-	
-	conv := converter.New(getDevice())
-	data, err := os.ReadFile(input)
+func runIdentify(cmd *cobra.Command, args []string) error {
+	data, release, err := readInput(args[0])
 	if err != nil {
 		return err
 	}
-	
-	result, err := conv.SyxToMIDI(data)
+	defer release()
+	if msg, ok := sysex.FromText(data); ok {
+		data = msg
+	}
+
+	messages, err := sysex.Split(data)
 	if err != nil {
 		return err
 	}
-	
-	if err := os.WriteFile(output, result, 0644); err != nil {
-		return err
+
+	for i, msg := range messages {
+		ident, err := sysex.Identify(msg)
+		if err != nil {
+			fmt.Printf("Message %d: %v\n", i+1, err)
+			continue
+		}
+		fmt.Printf("Message %d: %s, %d bytes\n", i+1, ident, len(msg))
+		if ident.Model != nil && ident.Model.Device != "" {
+			fmt.Printf("  Use --device %s\n", ident.Model.Device)
+		}
 	}
-	
-	fmt.Printf("Converted %s -> %s\n", input, output)
 	return nil
 }
 
-func runSeqToSyx(cmd *cobra.Command, args []string) error {
-	input := args[0]
-	output := getOutputPath(input, ".syx")
-	
-	conv := converter.New(getDevice())
-	data, err := os.ReadFile(input)
+func runDiff(cmd *cobra.Command, args []string) error {
+	conv, err := newConverter()
 	if err != nil {
 		return err
 	}
-	
-	result, err := conv.SeqToSyx(data)
+
+	a, err := conv.ReadPatternFile(args[0])
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %w", args[0], err)
 	}
-	
-	if err := os.WriteFile(output, result, 0644); err != nil {
-		return err
+	b, err := conv.ReadPatternFile(args[1])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[1], err)
 	}
-	
-	fmt.Printf("Converted %s -> %s\n", input, output)
-	return nil
+
+	diffs := converter.Diff(a, b)
+	if len(diffs) == 0 {
+		fmt.Println("Patterns are identical")
+		return nil
+	}
+
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	return fmt.Errorf("patterns differ in %d places", len(diffs))
 }
 
-func runSyxToSeq(cmd *cobra.Command, args []string) error {
-	input := args[0]
-	output := getOutputPath(input, ".seq")
-	
-	conv := converter.New(getDevice())
-	data, err := os.ReadFile(input)
+func runTUI(cmd *cobra.Command, args []string) error {
+	if err := service.ValidateCollisionPolicy(tuiCollision); err != nil {
+		return fmt.Errorf("invalid --on-collision: %w", err)
+	}
+	keys, err := tui.NewKeyMap(userConfig.TUI.Keys)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid tui.keys in config: %w", err)
 	}
-	
-	result, err := conv.SyxToSeq(data)
+	path, err := configPath()
 	if err != nil {
 		return err
 	}
-	
-	if err := os.WriteFile(output, result, 0644); err != nil {
-		return err
+	opts := getOptions()
+	// Without a registry, the packs screen says how to set one
+	var registry *pack.Registry
+	if location := registryLocation(); location != "" {
+		if registry, err = pack.NewRegistry(location); err != nil {
+			return err
+		}
 	}
-	
-	fmt.Printf("Converted %s -> %s\n", input, output)
-	return nil
+	return tui.Run(tui.Options{
+		Inline:     tuiInline,
+		Device:     deviceName,
+		Convert:    &opts,
+		OutputDir:  tuiOutputDir,
+		Port:       midiPort,
+		Keys:       &keys,
+		Collision:  tuiCollision,
+		NoteNames:  noteNames,
+		Config:     userConfig,
+		ConfigPath: path,
+		Preset:     presetName,
+		Registry:   registry,
+		Library:    openLibrary,
+	})
 }
 
-func runTUI(cmd *cobra.Command, args []string) error {
-	return tui.Run()
+// loadConfig reads the --config file, or the default one if it exists
+func loadConfig() (*config.Config, error) {
+	if configFile != "" {
+		return config.Load(configFile)
+	}
+	return config.LoadDefault()
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	defaults := getOptions()
 	fmt.Printf("Starting API server on port %d...\n", serverPort)
-	return api.StartServer(serverPort)
+	return api.StartServer(serverPort, api.Options{
+		CORS:              serverCORS,
+		Defaults:          &defaults,
+		AllowOptionsWrite: serverOptionsWrite,
+		// Defaults changed over the API are kept in the config file
+		SaveDefaults: func(opts converter.ConvertOptions) error {
+			userConfig.Convert.SetOptions(opts)
+			return config.Save(path, userConfig)
+		},
+	})
 }
 
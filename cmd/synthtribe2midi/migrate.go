@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFrom string
+	migrateTo   string
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [input]",
+	Short: "Convert a pattern from one device's format to another's",
+	Long: `Read a pattern with one device handler and write it with another, e.g. to
+move an x0xb0x pattern archive to the TD-3 or back. The output format
+(.seq, .syx, or MIDI) follows the output file extension.
+
+Examples:
+  synthtribe2midi migrate old-x0x.seq --from x0xb0x --to td3 -o pattern.seq
+  synthtribe2midi migrate pattern.seq --from td3 --to x0xb0x -o pattern.syx`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runMigrate,
+	SilenceUsage: true,
+}
+
+func init() {
+	migrateCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (required)")
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", "", "Source device (default: --device)")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", "td3", "Target device")
+	_ = migrateCmd.MarkFlagRequired("output")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	if migrateFrom == "" {
+		migrateFrom = deviceName
+	}
+	from, ok := devices.Lookup(migrateFrom)
+	if !ok {
+		return fmt.Errorf("unknown source device %q", migrateFrom)
+	}
+	to, ok := devices.Lookup(migrateTo)
+	if !ok {
+		return fmt.Errorf("unknown target device %q", migrateTo)
+	}
+	format := converter.DetectFormat(outputFile)
+	if format == converter.FormatUnknown {
+		return fmt.Errorf("cannot determine output format from %s", outputFile)
+	}
+
+	rec := conversionRecord(cmd, input, outputFile)
+	rec.Device, rec.From = migrateTo, migrateFrom
+	if skipUpToDate(rec) {
+		return nil
+	}
+
+	pattern, err := converter.New(from).ReadPatternFile(input)
+	if err != nil {
+		return err
+	}
+	conv := converter.New(to)
+	conv.SetOptions(getOptions())
+	data, err := conv.GeneratePattern(pattern, format)
+	if err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+	if err := recordConversion(rec, conv.Warnings(), func() error {
+		if err := converter.WriteFileAtomic(outputFile, data, 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	printWarnings(conv.Warnings())
+	fmt.Printf("Migrated %s (%s) -> %s (%s)\n", input, from.Name(), outputFile, to.Name())
+	return nil
+}
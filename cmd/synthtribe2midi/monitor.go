@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	monitorCapture  string
+	monitorRealtime bool
+	monitorGrid     bool
+	monitorNoColor  bool
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Log all incoming MIDI on a port, decoding SysEx and pattern dumps",
+	Long: `Print every message arriving on a MIDI input with a timestamp, its raw
+bytes, and a decoding: channel messages are named, SysEx is identified against
+the manufacturer database, and pattern dumps of supported devices are decoded.
+With --capture, all SysEx is also appended to a .syx file for later analysis,
+e.g. when working out the dump format of a new device.
+
+Examples:
+  synthtribe2midi monitor --port TD-3
+  synthtribe2midi monitor --port "Pro-800" --capture pro800.syx
+  synthtribe2midi monitor --port TD-3 --grid --realtime`,
+	Args:         cobra.NoArgs,
+	RunE:         runMonitor,
+	SilenceUsage: true,
+}
+
+func init() {
+	monitorCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI input port name (or unique part of it)")
+	monitorCmd.Flags().StringVar(&monitorCapture, "capture", "", "Append all received SysEx to this .syx file")
+	monitorCmd.Flags().BoolVar(&monitorRealtime, "realtime", false, "Also show clock, active sensing, and other real-time messages")
+	monitorCmd.Flags().BoolVarP(&monitorGrid, "grid", "g", false, "Draw decoded pattern dumps as a step grid")
+	monitorCmd.Flags().BoolVar(&monitorNoColor, "no-color", false, "Disable colors (also honours $NO_COLOR)")
+	_ = monitorCmd.MarkFlagRequired("port")
+	rootCmd.AddCommand(monitorCmd)
+}
+
+func runMonitor(cmd *cobra.Command, args []string) error {
+	m := &monitor.Monitor{
+		W:            os.Stdout,
+		HideRealtime: !monitorRealtime,
+		Patterns:     monitorGrid,
+		NoColor:      monitorNoColor || os.Getenv("NO_COLOR") != "",
+	}
+	if monitorCapture != "" {
+		f, err := os.OpenFile(monitorCapture, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open capture file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		m.Capture = f
+	}
+
+	in, err := mididevice.OpenInput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	stop, err := in.Listen(m.Handle)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", in.Name(), err)
+	}
+	defer stop()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Printf("Monitoring %s; press Ctrl+C to stop\n", in.Name())
+	<-ctx.Done()
+	fmt.Printf("\n%d message(s) received\n", m.Count())
+	return nil
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/pack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	packKey        string
+	packTrust      string
+	packMeta       pack.Metadata
+	packTags       []string
+	packCollection string
+	packDryRun     bool
+	packRegistry   string
+)
+
+var packCmd = &cobra.Command{
+	Use:   "pack",
+	Short: "Build, verify, and install signed pattern packs",
+	Long: `Pattern packs (` + pack.Extension + `) bundle a directory of pattern files with
+a manifest of their checksums and metadata, signed with the author's key, so
+packs shared online can be checked for integrity and installed into the
+library in one step. Packs shared through a registry, a static index that
+any web server can host, are found with "pack search" and installed by name.
+
+The signing key is created on the first pack build (in the user config dir,
+or --key); publish its public key, shown by "pack key", so users can pass it
+to --trust.
+
+Examples:
+  synthtribe2midi pack build acid-essentials/ -o acid-essentials.stpk --author "DJ Squelch"
+  synthtribe2midi pack verify acid-essentials.stpk --trust stpk:Mh3F...
+  synthtribe2midi pack install acid-essentials.stpk --trust squelch.pub
+  synthtribe2midi pack search acid --registry https://packs.example.com/
+  synthtribe2midi pack install acid-essentials@1.2`,
+}
+
+var packBuildCmd = &cobra.Command{
+	Use:          "build <dir>",
+	Short:        "Build a signed pack from the pattern files in a directory",
+	Args:         cobra.ExactArgs(1),
+	RunE:         runPackBuild,
+	SilenceUsage: true,
+}
+
+var packVerifyCmd = &cobra.Command{
+	Use:          "verify <pack>",
+	Short:        "Check a pack's signature and checksums and list its contents",
+	Args:         cobra.ExactArgs(1),
+	RunE:         runPackVerify,
+	SilenceUsage: true,
+}
+
+var packSearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "List the packs in the registry matching a query",
+	Long: `List the packs in the pack registry whose name, description, author,
+device, or tags contain every word of the query; without one, list them all.
+
+The registry is --registry, $` + pack.RegistryEnv + `, or pack.registry in
+the config file: the URL of its index.json, or of the directory holding it.`,
+	Args:         cobra.ArbitraryArgs,
+	RunE:         runPackSearch,
+	SilenceUsage: true,
+}
+
+var packInstallCmd = &cobra.Command{
+	Use:   "install <pack>",
+	Short: "Verify a pack and add its patterns to the library",
+	Long: `Verify a pack, then add its patterns to the library, tagged with the pack's
+tags and gathered in a collection named after the pack. Nothing is added if
+verification fails.
+
+The pack is a pack file, or the name of a pack in the registry (see "pack
+search"), optionally with @version; without one, the newest is installed.
+Registry packs are checked against the checksum and signing key the registry
+lists for them, and against --trust if given.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runPackInstall,
+	SilenceUsage: true,
+}
+
+var packKeyCmd = &cobra.Command{
+	Use:          "key",
+	Short:        "Print the public key of the pack signing key, creating it if needed",
+	Args:         cobra.NoArgs,
+	RunE:         runPackKey,
+	SilenceUsage: true,
+}
+
+func init() {
+	packCmd.PersistentFlags().StringVar(&packKey, "key", "", "Signing key file (default: pack.key in the user config dir)")
+
+	packBuildCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (default: <dir>"+pack.Extension+")")
+	packBuildCmd.Flags().StringVar(&packMeta.Name, "name", "", "Pack name (default: directory name)")
+	packBuildCmd.Flags().StringVar(&packMeta.Version, "version", "", "Pack version, e.g. 1.0")
+	packBuildCmd.Flags().StringVar(&packMeta.Author, "author", "", "Pack author")
+	packBuildCmd.Flags().StringVar(&packMeta.Description, "description", "", "What the pack holds")
+	packBuildCmd.Flags().StringVar(&packMeta.License, "license", "", "License of the patterns, e.g. CC-BY-4.0")
+	packBuildCmd.Flags().StringSliceVarP(&packTags, "tag", "t", nil, "Tags applied to the patterns on install")
+
+	for _, c := range []*cobra.Command{packVerifyCmd, packInstallCmd} {
+		c.Flags().StringVar(&packTrust, "trust", "", "Require this signer: a public key (stpk:...) or .pub file")
+	}
+	for _, c := range []*cobra.Command{packSearchCmd, packInstallCmd} {
+		c.Flags().StringVar(&packRegistry, "registry", "", "Pack registry URL (default: $"+pack.RegistryEnv+" or pack.registry in the config file)")
+	}
+	packInstallCmd.Flags().StringVarP(&packCollection, "collection", "c", "", "Collection for the patterns (default: pack name)")
+	packInstallCmd.Flags().StringVar(&libraryDir, "library", "", "Library directory or server URL (default: $SYNTHTRIBE2MIDI_LIBRARY or user config dir)")
+	packInstallCmd.Flags().BoolVar(&packDryRun, "dry-run", false, "Verify and list the patterns without installing them")
+
+	packCmd.AddCommand(packBuildCmd)
+	packCmd.AddCommand(packVerifyCmd)
+	packCmd.AddCommand(packSearchCmd)
+	packCmd.AddCommand(packInstallCmd)
+	packCmd.AddCommand(packKeyCmd)
+	rootCmd.AddCommand(packCmd)
+}
+
+// signingKey loads the pack signing key, creating it on first use
+func signingKey() (ed25519.PrivateKey, error) {
+	keyPath := packKey
+	if keyPath == "" {
+		var err error
+		if keyPath, err = pack.DefaultKeyPath(); err != nil {
+			return nil, err
+		}
+	}
+	key, created, err := pack.LoadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		fmt.Printf("Created signing key %s (public key in %s.pub)\n", keyPath, keyPath)
+	}
+	return key, nil
+}
+
+func runPackBuild(cmd *cobra.Command, args []string) error {
+	key, err := signingKey()
+	if err != nil {
+		return err
+	}
+	meta := packMeta
+	meta.Tags = packTags
+	meta.Device = strings.ToLower(deviceName)
+	p, err := pack.Build(args[0], meta)
+	if err != nil {
+		return err
+	}
+
+	output := outputFile
+	if output == "" {
+		output = filepath.Clean(args[0]) + pack.Extension
+	}
+	if err := p.WriteFile(output, key); err != nil {
+		return err
+	}
+	fmt.Printf("Packed %d pattern(s) -> %s\n", len(p.Manifest.Files), output)
+	fmt.Printf("Signed by %s\n", pack.FormatPublicKey(p.Signer))
+	return nil
+}
+
+// registryLocation returns the --registry, $SYNTHTRIBE2MIDI_REGISTRY, or
+// configured pack registry URL, or "" if none is set
+func registryLocation() string {
+	if packRegistry != "" {
+		return packRegistry
+	}
+	if location := os.Getenv(pack.RegistryEnv); location != "" {
+		return location
+	}
+	if userConfig != nil {
+		return userConfig.Pack.Registry
+	}
+	return ""
+}
+
+// openRegistry returns the client of the pack registry
+func openRegistry() (*pack.Registry, error) {
+	location := registryLocation()
+	if location == "" {
+		return nil, fmt.Errorf("no pack registry: pass --registry, set $%s, or set pack.registry in the config file", pack.RegistryEnv)
+	}
+	return pack.NewRegistry(location)
+}
+
+// isPackFile reports whether an install argument names a pack file rather
+// than a registry pack
+func isPackFile(arg string) bool {
+	if strings.HasSuffix(arg, pack.Extension) {
+		return true
+	}
+	_, err := os.Stat(arg)
+	return err == nil
+}
+
+// readPack reads and verifies a pack file, or downloads and verifies a
+// registry pack, checking its signer against --trust
+func readPack(arg string) (*pack.Pack, error) {
+	var p *pack.Pack
+	var err error
+	if isPackFile(arg) {
+		p, err = pack.ReadFile(arg)
+	} else {
+		p, err = downloadPack(arg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if packTrust != "" {
+		trusted, err := pack.ParsePublicKey(packTrust)
+		if err != nil {
+			return nil, err
+		}
+		if !p.Signer.Equal(trusted) {
+			return nil, fmt.Errorf("%w: signed by %s, not the trusted key %s", pack.ErrInvalid, pack.Fingerprint(p.Signer), pack.Fingerprint(trusted))
+		}
+	}
+	return p, nil
+}
+
+// downloadPack finds a pack by name in the registry and downloads it
+func downloadPack(name string) (*pack.Pack, error) {
+	registry, err := openRegistry()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	listing, err := registry.Find(ctx, name)
+	if errors.Is(err, pack.ErrNotFound) {
+		return nil, fmt.Errorf("%w in %s (see \"pack search\")", err, registry.URL())
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Downloading %s\n", strings.TrimSpace(listing.Name+" "+listing.Version))
+	return registry.Download(ctx, listing)
+}
+
+func runPackSearch(cmd *cobra.Command, args []string) error {
+	registry, err := openRegistry()
+	if err != nil {
+		return err
+	}
+	found, err := registry.Search(context.Background(), strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		fmt.Println("No matching packs")
+		return nil
+	}
+	for _, l := range found {
+		fmt.Printf("%-24s %-8s %-6s %s\n", l.Name, l.Version, l.Device, l.Description)
+		var about []string
+		if l.Author != "" {
+			about = append(about, "by "+l.Author)
+		}
+		if len(l.Tags) > 0 {
+			about = append(about, "tags: "+strings.Join(l.Tags, ", "))
+		}
+		if len(about) > 0 {
+			fmt.Printf("  %s\n", strings.Join(about, "; "))
+		}
+	}
+	return nil
+}
+
+func runPackVerify(cmd *cobra.Command, args []string) error {
+	p, err := readPack(args[0])
+	if err != nil {
+		return err
+	}
+	m := p.Manifest.Pack
+	fmt.Println(strings.TrimSpace(m.Name + " " + m.Version))
+	if m.Author != "" {
+		fmt.Printf("  by %s\n", m.Author)
+	}
+	if m.Description != "" {
+		fmt.Printf("  %s\n", m.Description)
+	}
+	if m.License != "" {
+		fmt.Printf("  license: %s\n", m.License)
+	}
+	for _, f := range p.Manifest.Files {
+		fmt.Printf("  %-40s %-4s %6d bytes  %s\n", f.Path, f.Format, f.Size, f.SHA256[:12])
+	}
+	fmt.Printf("OK: %d pattern(s), signed by %s (%s)\n", len(p.Manifest.Files), pack.Fingerprint(p.Signer), pack.FormatPublicKey(p.Signer))
+	if packTrust == "" {
+		fmt.Println("Signer not checked; pass --trust with the author's public key to require it")
+	}
+	return nil
+}
+
+func runPackInstall(cmd *cobra.Command, args []string) error {
+	p, err := readPack(args[0])
+	if err != nil {
+		return err
+	}
+	meta := p.Manifest.Pack
+	if packDryRun {
+		for _, f := range p.Manifest.Files {
+			fmt.Printf("Would add %s\n", f.Path)
+		}
+		return nil
+	}
+
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	entries, err := pack.Install(lib, p, packCollection, strings.ToLower(deviceName))
+	for _, entry := range entries {
+		fmt.Printf("Added %s  %s\n", entry.ID, entry.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	collection := packCollection
+	if collection == "" {
+		collection = meta.Name
+	}
+	fmt.Printf("Installed %d pattern(s) from %s into collection %q (signed by %s)\n", len(entries), meta.Name, collection, pack.Fingerprint(p.Signer))
+	return nil
+}
+
+func runPackKey(cmd *cobra.Command, args []string) error {
+	key, err := signingKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println(pack.FormatPublicKey(key.Public().(ed25519.PublicKey)))
+	return nil
+}
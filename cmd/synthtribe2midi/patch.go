@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+	"github.com/james-see/synthtribe2midi/pkg/transfer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	patchRequest string
+	patchTimeout time.Duration
+	patchIdle    time.Duration
+	patchDelay   time.Duration
+	patchRetries int
+)
+
+var libPatchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Store and transfer synth patch dumps (Pro-800, Model D, ...)",
+	Long: `Keep patch (sound) SysEx dumps of other synths in the library next to
+your patterns. Patches are stored byte for byte and sent back unchanged; the
+sending device is identified from the dump and recorded with it.
+
+Examples:
+  synthtribe2midi lib patch add pro800-bass.syx --tag bass
+  synthtribe2midi lib patch pull --port Pro-800 --name "Init pad"
+  synthtribe2midi lib patch pull --port Pro-800 --request "F0 00 20 32 ... F7"
+  synthtribe2midi lib patch push 81be04 --port Pro-800`,
+}
+
+var libPatchAddCmd = &cobra.Command{
+	Use:   "add <file.syx>...",
+	Short: "Add patch dump files to the library",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runLibPatchAdd,
+}
+
+var libPatchPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Receive a patch dump over MIDI and store it",
+	Long: `Wait for a patch dump on a MIDI port and store it in the library. With
+--request, the given SysEx message is sent first to ask the device for the
+dump; otherwise start the dump from the synth's front panel.`,
+	Args:         cobra.NoArgs,
+	RunE:         runLibPatchPull,
+	SilenceUsage: true,
+}
+
+var libPatchPushCmd = &cobra.Command{
+	Use:          "push <id>",
+	Short:        "Send a stored patch dump to a device over MIDI",
+	Args:         cobra.ExactArgs(1),
+	RunE:         runLibPatchPush,
+	SilenceUsage: true,
+}
+
+func init() {
+	libPatchAddCmd.Flags().StringVar(&libName, "name", "", "Patch name (default: file name)")
+	libPatchAddCmd.Flags().StringSliceVarP(&libTags, "tag", "t", nil, "Tags to apply")
+
+	libPatchPullCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI port name (or unique part of it)")
+	libPatchPullCmd.Flags().StringVar(&patchRequest, "request", "", "SysEx dump request to send first, as hex bytes")
+	libPatchPullCmd.Flags().StringVar(&libName, "name", "", "Patch name (default: sender and time)")
+	libPatchPullCmd.Flags().StringSliceVarP(&libTags, "tag", "t", nil, "Tags to apply")
+	libPatchPullCmd.Flags().DurationVar(&patchTimeout, "timeout", 30*time.Second, "Time to wait for the dump to start")
+	libPatchPullCmd.Flags().DurationVar(&patchIdle, "idle", time.Second, "Quiet time that marks the end of a multi-message dump")
+	libPatchPullCmd.Flags().IntVar(&patchRetries, "retries", 2, "Re-send --request this many times after a timeout or damaged dump")
+	_ = libPatchPullCmd.MarkFlagRequired("port")
+
+	libPatchPushCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI output port name (or unique part of it)")
+	libPatchPushCmd.Flags().DurationVar(&patchDelay, "delay", 50*time.Millisecond, "Pause between the messages of a multi-message dump")
+	_ = libPatchPushCmd.MarkFlagRequired("port")
+
+	libPatchCmd.AddCommand(libPatchAddCmd)
+	libPatchCmd.AddCommand(libPatchPullCmd)
+	libPatchCmd.AddCommand(libPatchPushCmd)
+	libCmd.AddCommand(libPatchCmd)
+}
+
+func runLibPatchAdd(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range args {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := libName
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		entry, err := addPatch(lib, name, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("Added %s  %s (%s)\n", entry.ID, entry.Name, entry.Source)
+	}
+	return nil
+}
+
+func runLibPatchPull(cmd *cobra.Command, args []string) error {
+	var req []byte
+	if patchRequest != "" {
+		var err error
+		if req, err = sysex.ParseHex(patchRequest); err != nil {
+			return fmt.Errorf("invalid --request: %w", err)
+		}
+		if err := sysex.Validate(req); err != nil {
+			return fmt.Errorf("invalid --request: %w", err)
+		}
+	}
+
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	out, err := mididevice.OpenOutput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+	in, err := mididevice.OpenInput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	// Only SysEx, and with a request only dumps from the same manufacturer
+	accept := func(msg []byte) bool {
+		if len(msg) == 0 || msg[0] != sysex.Start {
+			return false
+		}
+		if req == nil {
+			return true
+		}
+		id, err := sysex.ManufacturerID(req)
+		return err == nil && sysex.HasManufacturer(msg, id)
+	}
+
+	if req == nil {
+		fmt.Printf("Waiting for a patch dump on %s; start it from the synth...\n", in.Name())
+	}
+	// A dump started from the panel cannot be asked for again
+	retries := patchRetries
+	if req == nil {
+		retries = 0
+	}
+	var msgs [][]byte
+	err = transfer.Retry(transfer.Options{Retries: retries}, func() error {
+		var err error
+		if msgs, err = mididevice.RequestAll(out, in, req, accept, patchTimeout, patchIdle); err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if err := sysex.Validate(msg); err != nil {
+				return fmt.Errorf("%w: %w", transfer.ErrIncomplete, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	name := libName
+	if name == "" {
+		name = time.Now().Format("patch-20060102-150405")
+	}
+	entry, err := addPatch(lib, name, bytes.Join(msgs, nil))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pulled %d message(s) from %s: %s  %s (%s)\n", len(msgs), in.Name(), entry.ID, entry.Name, entry.Source)
+	return nil
+}
+
+func runLibPatchPush(cmd *cobra.Command, args []string) error {
+	lib, err := openLibrary()
+	if err != nil {
+		return err
+	}
+	entry, msgs, err := lib.PatchMessages(args[0])
+	if err != nil {
+		return err
+	}
+
+	out, err := mididevice.OpenOutput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	for i, msg := range msgs {
+		if i > 0 {
+			time.Sleep(patchDelay)
+		}
+		if err := out.Send(msg); err != nil {
+			return fmt.Errorf("failed to send %s: %w", entry.ID, err)
+		}
+	}
+	fmt.Printf("Sent %s  %s (%d message(s)) to %s\n", entry.ID, entry.Name, len(msgs), out.Name())
+	return nil
+}
+
+// addPatch stores a patch dump and applies the --tag flags
+func addPatch(lib *library.Library, name string, data []byte) (*library.Entry, error) {
+	entry, err := lib.AddPatch(name, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(libTags) > 0 {
+		return lib.Tag(entry.ID, libTags...)
+	}
+	return entry, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/spf13/cobra"
+)
+
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "List MIDI backends and their ports",
+	Long: `List the MIDI backends compiled into this build and the ports each one
+can see. The backend marked with * is used unless --midi-backend selects
+another; try a different one if a device is missing or transfers fail.
+
+Example:
+  synthtribe2midi ports
+  synthtribe2midi lib patch pull --midi-backend alsa --port TD-3`,
+	Args:         cobra.NoArgs,
+	RunE:         runPorts,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(portsCmd)
+}
+
+func runPorts(cmd *cobra.Command, args []string) error {
+	names := mididevice.Backends()
+	if len(names) == 0 {
+		return mididevice.ErrNoBackend
+	}
+	def, err := mididevice.Default()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		b, err := mididevice.Lookup(name)
+		if err != nil {
+			return err
+		}
+		mark := " "
+		if b == def {
+			mark = "*"
+		}
+		fmt.Printf("%s %s\n", mark, name)
+		if pc, ok := b.(mididevice.PortCreator); ok && pc.CreatesPorts() {
+			fmt.Println("    (creates virtual ports under the name given with --port)")
+			continue
+		}
+		printPorts("out", b.Outputs)
+		printPorts("in", b.Inputs)
+	}
+	return nil
+}
+
+// printPorts lists the ports of one direction, reporting errors inline so one
+// broken backend does not hide the others
+func printPorts(dir string, list func() ([]string, error)) {
+	ports, err := list()
+	if err != nil {
+		fmt.Printf("    %-3s  error: %v\n", dir, err)
+		return
+	}
+	if len(ports) == 0 {
+		fmt.Printf("    %-3s  (none)\n", dir)
+	}
+	for _, p := range ports {
+		fmt.Printf("    %-3s  %s\n", dir, p)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/spf13/cobra"
+)
+
+var (
+	printTitle    string
+	printPageSize string
+	printSynced   bool
+)
+
+var printCmd = &cobra.Command{
+	Use:   "print [files...]",
+	Short: "Print patterns to a PDF cheat sheet",
+	Long: `Render patterns to a printable PDF: one step grid per pattern with its
+name, tempo, and key, so you can keep a paper reference of what is stored in
+each hardware slot. SysEx banks print every pattern they hold.
+
+With --synced, the library patterns last synced to hardware are printed in
+slot order, labelled with their slots; --port limits them to one device.
+
+Examples:
+  synthtribe2midi print acid.seq bass.syx -o set.pdf --title "Friday set"
+  synthtribe2midi print --synced --port TD-3 -o slots.pdf --page-size Letter`,
+	RunE:         runPrint,
+	SilenceUsage: true,
+}
+
+func init() {
+	printCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output .pdf file path (default: patterns.pdf, or the input's name)")
+	printCmd.Flags().StringVar(&printTitle, "title", "", "Heading of the first page")
+	printCmd.Flags().StringVar(&printPageSize, "page-size", render.PageA4, "Page size (A4, Letter)")
+	printCmd.Flags().BoolVar(&printSynced, "synced", false, "Print the library patterns synced to hardware slots")
+	printCmd.Flags().StringVarP(&midiPort, "port", "p", "", "With --synced, only patterns synced to this port (or unique part of its name)")
+	printCmd.Flags().StringVar(&libraryDir, "library", "", "Library directory or server URL for --synced (default: $SYNTHTRIBE2MIDI_LIBRARY or user config dir)")
+	rootCmd.AddCommand(printCmd)
+}
+
+func runPrint(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !printSynced {
+		return fmt.Errorf("give pattern files to print, or --synced for the library's hardware slots")
+	}
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+
+	var entries []render.SheetEntry
+	for _, input := range args {
+		data, release, err := readInput(input)
+		if err != nil {
+			return fmt.Errorf("failed to read input file: %w", err)
+		}
+		defer release()
+		items, err := service.SplitBank(input, data, converter.Limits{})
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			pattern, err := conv.ParsePattern(item.Data, item.Format)
+			if err != nil {
+				return fmt.Errorf("%s: %w", item.Name, err)
+			}
+			entries = append(entries, render.SheetEntry{Title: item.Name, Pattern: pattern})
+		}
+		printWarnings(conv.Warnings())
+	}
+	if printSynced {
+		synced, err := syncedSheetEntries(conv)
+		if err != nil {
+			return err
+		}
+		if len(synced) == 0 {
+			return fmt.Errorf("no library patterns are synced to hardware slots")
+		}
+		entries = append(entries, synced...)
+	}
+
+	output := "patterns.pdf"
+	if len(args) == 1 && !printSynced {
+		output = getOutputPath(args[0], ".pdf")
+	} else if outputFile != "" {
+		output = outputFile
+	}
+
+	var buf bytes.Buffer
+	opts := render.PDFOptions{Title: printTitle, PageSize: printPageSize, Names: noteNames}
+	if err := render.PDF(&buf, entries, opts); err != nil {
+		return err
+	}
+	if err := converter.WriteFileAtomic(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	fmt.Printf("Printed %d pattern(s) -> %s\n", len(entries), output)
+	return nil
+}
+
+// syncedSheetEntries returns a cheat sheet entry for every slot a library
+// pattern was synced to, in slot order
+func syncedSheetEntries(conv *converter.Converter) ([]render.SheetEntry, error) {
+	lib, err := openLibrary()
+	if err != nil {
+		return nil, err
+	}
+	list, err := lib.List(library.Filter{Kind: library.KindPattern})
+	if err != nil {
+		return nil, err
+	}
+
+	type synced struct {
+		slot  library.SlotRecord
+		entry render.SheetEntry
+	}
+	var found []synced
+	for _, e := range list {
+		var records []library.SlotRecord
+		for _, r := range e.Slots {
+			if midiPort == "" || strings.Contains(strings.ToLower(r.Port), strings.ToLower(midiPort)) {
+				records = append(records, r)
+			}
+		}
+		if len(records) == 0 {
+			continue
+		}
+		_, data, err := lib.Data(e.ID)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := conv.ParsePattern(data, converter.Format(e.Format))
+		if err != nil {
+			return nil, fmt.Errorf("%s (%s): %w", e.ID, e.Name, err)
+		}
+		for _, r := range records {
+			found = append(found, synced{r, render.SheetEntry{Slot: r.Slot, Title: e.Name, Pattern: pattern}})
+		}
+	}
+
+	// Slot labels sort by the device's slot order where it can parse them
+	slotIndex := func(label string) int { return -1 }
+	if dev, ok := conv.GetDevice().(converter.SlotDevice); ok {
+		slotIndex = func(label string) int {
+			if i, err := dev.ParseSlot(label); err == nil {
+				return i
+			}
+			return -1
+		}
+	}
+	slices.SortStableFunc(found, func(a, b synced) int {
+		if c := strings.Compare(a.slot.Port, b.slot.Port); c != 0 {
+			return c
+		}
+		if c := slotIndex(a.slot.Slot) - slotIndex(b.slot.Slot); c != 0 {
+			return c
+		}
+		return strings.Compare(a.slot.Slot, b.slot.Slot)
+	})
+
+	entries := make([]render.SheetEntry, len(found))
+	for i, f := range found {
+		entries[i] = f.entry
+	}
+	return entries, nil
+}
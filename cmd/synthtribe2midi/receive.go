@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/james-see/synthtribe2midi/pkg/transfer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	receiveSlot    string
+	receiveVerify  bool
+	receiveRetries int
+	receiveTimeout time.Duration
+	receiveList    bool
+)
+
+var receiveCmd = &cobra.Command{
+	Use:   "receive",
+	Short: "Read a pattern from a connected device's pattern slot into a file",
+	Long: `Request the pattern in one slot of a connected device over MIDI and save
+it, the counterpart of send. The slot is given as on the front panel (for the
+TD-3 group, section, and pattern, e.g. 2B8, or A1 for group 1). The output
+format follows the --output extension: .syx keeps the dump as the device
+sent it, while .seq and .mid are converted with the usual options. A dump
+that does not arrive or does not parse is requested again, up to --retries
+times; --verify reads the slot twice and fails if the dumps differ.
+--list-ports shows the MIDI inputs to choose --port from.
+
+Examples:
+  synthtribe2midi receive --list-ports
+  synthtribe2midi receive --port TD-3 --pattern A1 -o a1.seq
+  synthtribe2midi receive --port TD-3 --pattern 3B8 -o bassline.mid --verify`,
+	Args:         cobra.NoArgs,
+	RunE:         runReceive,
+	SilenceUsage: true,
+}
+
+func init() {
+	receiveCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI port name (or unique part of it)")
+	receiveCmd.Flags().StringVar(&receiveSlot, "pattern", "", "Slot to read, e.g. A1 or 2B8")
+	receiveCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output .syx, .seq, or .mid file path (default: <slot>.syx)")
+	receiveCmd.Flags().BoolVar(&receiveVerify, "verify", false, "Read the slot twice and fail if the dumps differ")
+	receiveCmd.Flags().IntVar(&receiveRetries, "retries", 2, "Re-request the pattern this many times after a timeout or damaged dump")
+	receiveCmd.Flags().DurationVar(&receiveTimeout, "timeout", transfer.DefaultTimeout, "Time to wait for the pattern dump")
+	receiveCmd.Flags().BoolVar(&receiveList, "list-ports", false, "List the MIDI input ports and exit")
+	rootCmd.AddCommand(receiveCmd)
+}
+
+func runReceive(cmd *cobra.Command, args []string) error {
+	if receiveList {
+		b, err := mididevice.Default()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("MIDI inputs (%s):\n", b.Name())
+		printPorts("in", b.Inputs)
+		return nil
+	}
+	device, err := getDevice()
+	if err != nil {
+		return err
+	}
+	dev, ok := device.(converter.PatternRequester)
+	if !ok {
+		return fmt.Errorf("%s does not support pattern dump requests", device.Name())
+	}
+	if midiPort == "" || receiveSlot == "" {
+		return fmt.Errorf("--port and --pattern are required")
+	}
+	slot, err := dev.ParseSlot(receiveSlot)
+	if err != nil {
+		return err
+	}
+	output := outputFile
+	if output == "" {
+		output = dev.SlotName(slot) + ".syx"
+	}
+	format := converter.DetectFormat(output)
+	if format != converter.FormatSyx && format != converter.FormatSeq && format != converter.FormatMIDI {
+		return fmt.Errorf("cannot tell the output format of %s (use a .syx, .seq, or .mid file name)", output)
+	}
+
+	out, err := mididevice.OpenOutput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+	in, err := mididevice.OpenInput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	opts := transfer.Options{Timeout: receiveTimeout, Retries: receiveRetries, Verify: receiveVerify}
+	if sysexID >= 0 {
+		opts.DeviceID = uint8(sysexID)
+	}
+	data, err := transfer.Pull(out, in, dev, slot, opts)
+	if err != nil {
+		return err
+	}
+
+	if format != converter.FormatSyx {
+		resp, err := service.Convert(context.Background(), service.ConvertRequest{
+			Data:    data,
+			From:    converter.FormatSyx,
+			To:      []converter.Format{format},
+			Device:  deviceName,
+			Options: getOptions(),
+		})
+		if err != nil {
+			return fmt.Errorf("slot %s: conversion failed: %w", dev.SlotName(slot), err)
+		}
+		data = resp.Outputs[0].Data
+		printWarnings(resp.Warnings)
+	}
+	if err := converter.WriteFileAtomic(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	fmt.Printf("%-4s -> %s\n", dev.SlotName(slot), output)
+	return nil
+}
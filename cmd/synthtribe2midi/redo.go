@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	redoSince  string
+	redoAll    bool
+	redoDryRun bool
+)
+
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Re-run recorded conversions whose input changed",
+	Long: `Repeat the conversions in the history with their original device and
+options. By default only conversions whose input file changed since are re-run;
+--all re-runs every one, e.g. to regenerate a library after upgrading. Outputs
+that would come out identical are left untouched, so redo is safe to repeat.
+
+Examples:
+  synthtribe2midi redo --since yesterday
+  synthtribe2midi redo --since 7d --all --dry-run`,
+	Args:         cobra.NoArgs,
+	RunE:         runRedo,
+	SilenceUsage: true,
+}
+
+func init() {
+	redoCmd.Flags().StringVar(&redoSince, "since", "", "Only conversions since this time (today, yesterday, 12h, 7d, 2006-01-02)")
+	redoCmd.Flags().BoolVar(&redoAll, "all", false, "Re-run conversions even if their input is unchanged")
+	redoCmd.Flags().BoolVar(&redoDryRun, "dry-run", false, "Show what would be re-run without writing anything")
+	redoCmd.Flags().StringVar(&historyDir, "history-dir", "", "History directory (default: user config dir)")
+	_ = redoCmd.MarkFlagRequired("since")
+	rootCmd.AddCommand(redoCmd)
+}
+
+func runRedo(cmd *cobra.Command, args []string) error {
+	since, err := history.ParseSince(redoSince, time.Now())
+	if err != nil {
+		return err
+	}
+	log, err := openHistory()
+	if err != nil {
+		return err
+	}
+	records, err := log.List()
+	if err != nil {
+		return err
+	}
+
+	var redone, current, failed int
+	for _, rec := range history.Latest(records, since) {
+		name := fmt.Sprintf("%s  %s", rec.ID, filepath.Base(rec.Input.Path))
+		changed, err := rec.InputChanged()
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("%s: input no longer exists, skipped\n", name)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		if !changed && !redoAll {
+			current++
+			continue
+		}
+
+		conv, err := rec.Converter()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		data, err := rec.Convert(conv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		if rec.Unchanged(data) {
+			current++
+			continue
+		}
+		if redoDryRun {
+			fmt.Printf("%s: would re-run %s\n", name, rec.Command)
+			redone++
+			continue
+		}
+
+		err = recordConversion(rec.Rerun(), conv.Warnings(), func() error {
+			for i, out := range rec.Outputs {
+				if err := converter.WriteFileAtomic(out.Path, data[i], 0644); err != nil {
+					return fmt.Errorf("failed to write output file: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		printWarnings(conv.Warnings())
+		fmt.Printf("%s: re-ran %s\n", name, rec.Command)
+		redone++
+	}
+
+	verb := "Re-ran"
+	if redoDryRun {
+		verb = "Would re-run"
+	}
+	fmt.Printf("%s %d conversion(s), %d up to date\n", verb, redone, current)
+	if failed > 0 {
+		return fmt.Errorf("%d conversion(s) failed", failed)
+	}
+	return nil
+}
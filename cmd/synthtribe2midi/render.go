@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/spf13/cobra"
+)
+
+var renderScale int
+
+var renderImageCmd = &cobra.Command{
+	Use:   "render-image [input]",
+	Short: "Render a pattern as a PNG piano roll",
+	Long: `Draw a .seq, .syx, or MIDI pattern as a PNG piano roll: one column per
+step, accented notes in red, tied steps joined, and slides as arrows.
+
+Example:
+  synthtribe2midi render-image acid.seq -o acid.png --scale 2`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runRenderImage,
+	SilenceUsage: true,
+}
+
+func init() {
+	renderImageCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output .png file path")
+	renderImageCmd.Flags().IntVar(&renderScale, "scale", 1, "Zoom factor")
+	rootCmd.AddCommand(renderImageCmd)
+}
+
+func runRenderImage(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	output := getOutputPath(input, ".png")
+	if renderScale < 1 {
+		return fmt.Errorf("--scale must be at least 1")
+	}
+
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+	pattern, err := conv.ReadPatternFile(input)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := render.PNG(&buf, pattern, render.PNGOptions{Scale: renderScale}); err != nil {
+		return err
+	}
+	if err := converter.WriteFileAtomic(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	if keepMTime {
+		if err := converter.PreserveModTime(input, output); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Rendered %s -> %s\n", input, output)
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/transfer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sendSlot    string
+	sendDelay   time.Duration
+	sendVerify  bool
+	sendRetries int
+	sendTimeout time.Duration
+	sendList    bool
+)
+
+var sendCmd = &cobra.Command{
+	Use:   "send <input>...",
+	Short: "Send patterns straight to a connected device's pattern slots",
+	Long: `Convert pattern files of any supported format and write them over MIDI to
+the pattern memory of a connected device, without SynthTribe. The first
+input goes to --slot, given as on the front panel (for the TD-3 group,
+section, and pattern, e.g. 2B8), and any further inputs to the slots after
+it. With --verify each slot is read back and compared, and rewritten if it
+differs. --list-ports shows the MIDI outputs to choose --port from.
+
+Examples:
+  synthtribe2midi send --list-ports
+  synthtribe2midi send acid.mid --port TD-3 --slot 1A1
+  synthtribe2midi send a.seq b.seq c.syx --port TD-3 --slot 3B1 --verify`,
+	RunE:         runSend,
+	SilenceUsage: true,
+}
+
+func init() {
+	sendCmd.Flags().StringVarP(&midiPort, "port", "p", "", "MIDI output port name (or unique part of it)")
+	sendCmd.Flags().StringVar(&sendSlot, "slot", "", "Slot to write the first pattern to, e.g. 1A1")
+	sendCmd.Flags().DurationVar(&sendDelay, "delay", 200*time.Millisecond, "Pause between patterns so the device can store each one")
+	sendCmd.Flags().BoolVar(&sendVerify, "verify", false, "Read every written slot back and compare it with the pattern")
+	sendCmd.Flags().IntVar(&sendRetries, "retries", 2, "Rewrite a slot this many times if verification fails")
+	sendCmd.Flags().DurationVar(&sendTimeout, "timeout", transfer.DefaultTimeout, "Time to wait for each read-back with --verify")
+	sendCmd.Flags().BoolVar(&sendList, "list-ports", false, "List the MIDI output ports and exit")
+	rootCmd.AddCommand(sendCmd)
+}
+
+func runSend(cmd *cobra.Command, args []string) error {
+	if sendList {
+		b, err := mididevice.Default()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("MIDI outputs (%s):\n", b.Name())
+		printPorts("out", b.Outputs)
+		return nil
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("no input files given (or use --list-ports)")
+	}
+	device, err := getDevice()
+	if err != nil {
+		return err
+	}
+	dev, ok := device.(converter.SlotDevice)
+	if !ok {
+		return fmt.Errorf("%s does not support writing pattern slots", device.Name())
+	}
+	if midiPort == "" || sendSlot == "" {
+		return fmt.Errorf("--port and --slot are required")
+	}
+	start, err := dev.ParseSlot(sendSlot)
+	if err != nil {
+		return err
+	}
+	if start+len(args) > dev.Slots() {
+		return fmt.Errorf("%d pattern(s) from slot %s do not fit: the last slot is %s", len(args), dev.SlotName(start), dev.SlotName(dev.Slots()-1))
+	}
+
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+
+	// Convert everything up front so a bad file aborts before any slot is overwritten
+	msgs := make([][]byte, len(args))
+	for i, input := range args {
+		pattern, err := conv.ReadPatternFile(input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		if msgs[i], err = conv.PatternToSlotSyx(pattern, start+i); err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		printWarnings(conv.Warnings())
+	}
+
+	out, err := mididevice.OpenOutput(midiPort)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	opts := transfer.Options{Timeout: sendTimeout, Retries: sendRetries, Verify: sendVerify}
+	if sysexID >= 0 {
+		opts.DeviceID = uint8(sysexID)
+	}
+	var requester converter.PatternRequester
+	var in mididevice.In
+	if sendVerify {
+		if requester, ok = dev.(converter.PatternRequester); !ok {
+			return fmt.Errorf("%s does not support reading slots back for --verify", device.Name())
+		}
+		if in, err = mididevice.OpenInput(midiPort); err != nil {
+			return err
+		}
+		defer func() { _ = in.Close() }()
+	}
+
+	for i, input := range args {
+		if i > 0 {
+			time.Sleep(sendDelay)
+		}
+		slot := dev.SlotName(start + i)
+		if err := transfer.Push(out, in, requester, start+i, msgs[i], opts); err != nil {
+			return fmt.Errorf("failed to write %s to slot %s: %w", input, slot, err)
+		}
+		fmt.Printf("%-4s <- %s\n", slot, input)
+	}
+	fmt.Printf("Sent %d pattern(s) to %s\n", len(args), out.Name())
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shareQR     string
+	shareQRSize int
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Encode patterns as short share strings and back",
+	Long: `Share strings are compact, URL-safe encodings of a whole pattern (notes,
+accents, slides, ties, and tempo) that fit in a tweet, chat message, or QR
+code, e.g. st1.EASwqwO3BbcBqwSrBrcE...`,
+}
+
+var shareEncodeCmd = &cobra.Command{
+	Use:   "encode [input]",
+	Short: "Print the share string of a pattern file",
+	Long: `Print the share string of a pattern file. With --qr, also render it as a
+QR code image for liner notes or flyers; scan it back with a phone or with
+"share decode --qr".
+
+Example:
+  synthtribe2midi share encode pattern.seq --qr pattern-qr.png`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShareEncode,
+}
+
+var shareDecodeCmd = &cobra.Command{
+	Use:   "decode [share-string]",
+	Short: "Write a share string to a pattern file",
+	Long: `Decode a share string, given as an argument or read from a QR code image
+with --qr, and write it as .seq, .syx, or MIDI, chosen by the output file
+extension.
+
+Examples:
+  synthtribe2midi share decode st1.EASwqwO3BbcBqwSrBrcE... -o pattern.seq
+  synthtribe2midi share decode --qr flyer-photo.jpg -o pattern.seq`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if shareQR != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runShareDecode,
+}
+
+func init() {
+	shareDecodeCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (required)")
+	_ = shareDecodeCmd.MarkFlagRequired("output")
+	shareDecodeCmd.Flags().StringVar(&shareQR, "qr", "", "Read the share string from a QR code image (PNG or JPEG)")
+	shareEncodeCmd.Flags().StringVar(&shareQR, "qr", "", "Also write the share string as a QR code PNG")
+	shareEncodeCmd.Flags().IntVar(&shareQRSize, "qr-size", render.DefaultQRSize, "QR code image size in pixels")
+
+	shareCmd.AddCommand(shareEncodeCmd, shareDecodeCmd)
+	rootCmd.AddCommand(shareCmd)
+}
+
+func runShareEncode(cmd *cobra.Command, args []string) error {
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+	pattern, err := conv.ReadPatternFile(args[0])
+	if err != nil {
+		return err
+	}
+	s, err := converter.EncodeShareString(pattern)
+	if err != nil {
+		return err
+	}
+	fmt.Println(s)
+
+	if shareQR != "" {
+		var buf bytes.Buffer
+		if err := render.QR(&buf, s, shareQRSize); err != nil {
+			return err
+		}
+		if err := converter.WriteFileAtomic(shareQR, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write QR code: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote QR code to %s\n", shareQR)
+	}
+	return nil
+}
+
+func runShareDecode(cmd *cobra.Command, args []string) error {
+	var share string
+	if shareQR != "" {
+		f, err := os.Open(shareQR)
+		if err != nil {
+			return err
+		}
+		share, err = render.ReadQR(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", shareQR, err)
+		}
+	} else {
+		share = args[0]
+	}
+
+	pattern, err := converter.DecodeShareString(share)
+	if err != nil {
+		return err
+	}
+
+	format := converter.DetectFormat(outputFile)
+	if format == converter.FormatUnknown {
+		return fmt.Errorf("cannot tell the output format of %s (use .seq, .syx, or .mid)", outputFile)
+	}
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+	data, err := conv.GeneratePattern(pattern, format)
+	if err != nil {
+		return err
+	}
+	printWarnings(conv.Warnings())
+	if err := converter.WriteFileAtomic(outputFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	fmt.Printf("Decoded %d-step pattern -> %s\n", len(pattern.Steps), outputFile)
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsLib  bool
+	statsJSON bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [files or directories...]",
+	Short: "Summarize the patterns in folders or the library",
+	Long: `Analyze many patterns at once and report their tempo and key
+distribution, average density, and slide and accent usage. Directories are
+searched recursively; files that are not patterns are skipped.
+
+Examples:
+  synthtribe2midi stats ~/patterns
+  synthtribe2midi stats --lib --json`,
+	RunE:         runStats,
+	SilenceUsage: true,
+}
+
+func init() {
+	statsCmd.Flags().BoolVar(&statsLib, "lib", false, "Summarize the pattern library")
+	statsCmd.Flags().StringVar(&libraryDir, "library", "", "Library directory or server URL for --lib (default: $SYNTHTRIBE2MIDI_LIBRARY or user config dir)")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the stats as JSON")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !statsLib {
+		return fmt.Errorf("give files or directories to summarize, or --lib for the library")
+	}
+	conv, err := newConverter()
+	if err != nil {
+		return err
+	}
+
+	stats := analysis.NewStats()
+	stats.Names = noteNames
+	skipped := 0
+	for _, root := range args {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				// Skip hidden folders and library stores, which --lib covers
+				if path != root && (strings.HasPrefix(d.Name(), ".") || library.IsLibrary(path)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if converter.DetectFormat(path) == converter.FormatUnknown {
+				return nil
+			}
+			pattern, err := conv.ReadPatternFile(path)
+			if err != nil {
+				skipped++
+				return nil
+			}
+			stats.Add(analysis.Analyze(pattern))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if statsLib {
+		n, err := addLibraryStats(stats)
+		if err != nil {
+			return err
+		}
+		skipped += n
+	}
+
+	if statsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	stats.WriteReport(os.Stdout)
+	if skipped > 0 {
+		fmt.Printf("\n%d file(s) could not be read as patterns\n", skipped)
+	}
+	return nil
+}
+
+// addLibraryStats adds every pattern in the library to stats and returns
+// the number that could not be analyzed
+func addLibraryStats(stats *analysis.Stats) (int, error) {
+	lib, err := openLibrary()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := lib.List(library.Filter{Kind: library.KindPattern})
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	for _, e := range entries {
+		_, data, err := lib.Data(e.ID)
+		if err != nil {
+			return 0, err
+		}
+		dev, ok := devices.Lookup(e.Device)
+		if !ok {
+			if dev, err = getDevice(); err != nil {
+				return 0, err
+			}
+		}
+		r, err := analysis.AnalyzeData(dev, converter.Format(e.Format), data)
+		if err != nil {
+			skipped++
+			continue
+		}
+		stats.Add(r)
+	}
+	return skipped, nil
+}
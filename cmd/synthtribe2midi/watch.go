@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/james-see/synthtribe2midi/pkg/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchTo       string
+	watchOutDir   string
+	watchInterval time.Duration
+	watchExisting bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <dir>",
+	Short: "Convert files as they appear in a folder",
+	Long: `Watch a folder and convert every .mid, .seq, or .syx file that is added
+or changed to one format, e.g. to have MIDI ready for a DAW as soon as
+patterns are exported from SynthTribe. The folder is watched (not
+recursively) through file system events, or polled every --interval where
+those are unavailable, and a file is converted once it has been unchanged
+for --interval, so files still being written are left alone. Outputs are named
+after their input, in the folder itself or in --out-dir; files already in
+the target format are skipped. Runs until interrupted with Ctrl+C.
+
+For several folders or formats, or to run in the background, use the
+watch section of the daemon config instead.
+
+Examples:
+  synthtribe2midi watch ~/SynthTribe/exports --to midi
+  synthtribe2midi watch ./drop --to seq --out-dir ./converted --existing`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runWatch,
+	SilenceUsage: true,
+}
+
+func init() {
+	watchCmd.Flags().StringVar(&watchTo, "to", "", "Output format: seq, syx, or midi")
+	watchCmd.Flags().StringVar(&watchOutDir, "out-dir", "", "Write outputs to this directory (default: the watched folder)")
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 500*time.Millisecond, "How long a file must be unchanged before it is converted")
+	watchCmd.Flags().BoolVar(&watchExisting, "existing", false, "Also convert files already in the folder at startup")
+	_ = watchCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	to := service.ParseFormat(watchTo)
+	if to != converter.FormatSeq && to != converter.FormatSyx && to != converter.FormatMIDI {
+		return fmt.Errorf("unknown --to format %q (use seq, syx, or midi)", watchTo)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("cannot watch %s: %w", dir, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("cannot watch %s: not a directory", dir)
+	}
+	if watchOutDir != "" {
+		if err := os.MkdirAll(watchOutDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	var converted, failed int
+	var w *watch.Watcher
+	w = watch.New(dir, watchInterval, func(input string) error {
+		in := converter.DetectFormat(input)
+		if in == converter.FormatUnknown || in == to {
+			return nil
+		}
+		output := service.BatchOutput(input, watchOutDir, to)
+		done, err := convertFile(cmd, input, "", []string{output}, []converter.Format{to})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: failed: %v\n", input, err)
+			failed++
+			return err
+		}
+		// Don't pick up our own output when writing into the watched folder
+		w.Ignore(output)
+		if done {
+			fmt.Printf("%s -> %s\n", input, output)
+			converted++
+		} else {
+			fmt.Printf("%s: up to date\n", input)
+		}
+		return nil
+	})
+	w.ProcessExisting = watchExisting
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Printf("Watching %s for files to convert to %s; press Ctrl+C to stop\n", dir, service.DescribeFormat(to))
+	if err := w.Run(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("\nConverted %d file(s), %d failed\n", converted, failed)
+	return nil
+}
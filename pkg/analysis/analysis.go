@@ -0,0 +1,182 @@
+// Package analysis computes musical features of patterns (key, density,
+// articulation) for search, tagging, and reporting
+package analysis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Density thresholds for the "sparse" and "busy" tags
+const (
+	SparseDensity = 0.4
+	BusyDensity   = 0.8
+)
+
+// Result holds the computed features of a pattern
+type Result struct {
+	Key        string  `json:"key,omitempty"`   // Estimated key, e.g. "Am" or "F#"
+	Tempo      float64 `json:"tempo,omitempty"` // Pattern tempo in BPM
+	Density    float64 `json:"density"`         // Fraction of steps that sound
+	Notes      int     `json:"notes"`           // Note onsets (tied steps excluded)
+	LowNote    uint8   `json:"low_note,omitempty"`
+	HighNote   uint8   `json:"high_note,omitempty"`
+	HasSlides  bool    `json:"has_slides,omitempty"`
+	HasAccents bool    `json:"has_accents,omitempty"`
+	HasTies    bool    `json:"has_ties,omitempty"`
+	Slides     int     `json:"slides,omitempty"`  // Note onsets with slide
+	Accents    int     `json:"accents,omitempty"` // Note onsets with accent
+}
+
+// Analyze computes the features of a pattern
+func Analyze(p *converter.Pattern) *Result {
+	r := &Result{Tempo: p.Tempo, Key: DetectKey(p)}
+
+	length := p.Length
+	if length <= 0 || length > len(p.Steps) {
+		length = len(p.Steps)
+	}
+
+	sounding := 0
+	for _, s := range p.Steps[:length] {
+		if !s.Gate {
+			continue
+		}
+		sounding++
+		if !s.Tie {
+			r.Notes++
+			if s.Slide {
+				r.Slides++
+			}
+			if s.Accent {
+				r.Accents++
+			}
+		}
+		if sounding == 1 || s.Note < r.LowNote {
+			r.LowNote = s.Note
+		}
+		if s.Note > r.HighNote {
+			r.HighNote = s.Note
+		}
+		r.HasSlides = r.HasSlides || s.Slide
+		r.HasAccents = r.HasAccents || s.Accent
+		r.HasTies = r.HasTies || s.Tie
+	}
+	if length > 0 {
+		r.Density = float64(sounding) / float64(length)
+	}
+	return r
+}
+
+// AnalyzeData parses pattern data in the given format and analyzes it
+func AnalyzeData(dev converter.Device, format converter.Format, data []byte) (*Result, error) {
+	p, err := converter.New(dev).ParsePattern(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return Analyze(p), nil
+}
+
+// Tags returns search tags describing the result, e.g. "key:Am", "slides",
+// "sparse"
+func (r *Result) Tags() []string {
+	var tags []string
+	if r.Key != "" {
+		tags = append(tags, "key:"+r.Key)
+	}
+	if r.HasSlides {
+		tags = append(tags, "slides")
+	}
+	if r.HasAccents {
+		tags = append(tags, "accents")
+	}
+	if r.HasTies {
+		tags = append(tags, "ties")
+	}
+	switch {
+	case r.Notes == 0:
+	case r.Density < SparseDensity:
+		tags = append(tags, "sparse")
+	case r.Density >= BusyDensity:
+		tags = append(tags, "busy")
+	}
+	return tags
+}
+
+// String returns a one-line summary of the result
+func (r *Result) String() string {
+	return r.Describe(converter.NoteNaming{})
+}
+
+// Describe returns a one-line summary of the result with the key named by
+// names
+func (r *Result) Describe(names converter.NoteNaming) string {
+	key := names.Key(r.Key)
+	if key == "" {
+		key = "-"
+	}
+	return fmt.Sprintf("key %s, %d notes, density %.0f%%, %.0f BPM", key, r.Notes, r.Density*100, r.Tempo)
+}
+
+var pitchClasses = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// Krumhansl-Kessler key profiles
+var (
+	majorProfile = [12]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [12]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// DetectKey estimates the key of a pattern by correlating its pitch-class
+// histogram (weighted by sounding steps) with major and minor key profiles.
+// It returns "" for patterns without notes.
+func DetectKey(p *converter.Pattern) string {
+	var hist [12]float64
+	total := 0.0
+	for _, s := range p.Steps {
+		if s.Gate {
+			hist[s.Note%12]++
+			total++
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+
+	best, bestKey := math.Inf(-1), ""
+	for tonic := 0; tonic < 12; tonic++ {
+		if c := correlate(hist, majorProfile, tonic); c > best {
+			best, bestKey = c, pitchClasses[tonic]
+		}
+		if c := correlate(hist, minorProfile, tonic); c > best {
+			best, bestKey = c, pitchClasses[tonic]+"m"
+		}
+	}
+	return bestKey
+}
+
+// correlate returns the Pearson correlation of the histogram with a key
+// profile rotated to the given tonic
+func correlate(hist, profile [12]float64, tonic int) float64 {
+	var meanH, meanP float64
+	for i := 0; i < 12; i++ {
+		meanH += hist[i]
+		meanP += profile[i]
+	}
+	meanH /= 12
+	meanP /= 12
+
+	var num, denH, denP float64
+	for i := 0; i < 12; i++ {
+		h := hist[(i+tonic)%12] - meanH
+		q := profile[i] - meanP
+		num += h * q
+		denH += h * h
+		denP += q * q
+	}
+	if denH == 0 || denP == 0 {
+		return 0
+	}
+	return num / math.Sqrt(denH*denP)
+}
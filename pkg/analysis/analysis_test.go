@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func notes(ns ...uint8) *converter.Pattern {
+	p := &converter.Pattern{Length: 16, Tempo: 128, Steps: make([]converter.Step, 16)}
+	for i, n := range ns {
+		if n != 0 {
+			p.Steps[i] = converter.Step{Note: n, Gate: true, Velocity: 100}
+		}
+	}
+	return p
+}
+
+func TestDetectKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern *converter.Pattern
+		want    string
+	}{
+		{"C major scale", notes(60, 62, 64, 65, 67, 69, 71, 72, 60, 64, 67, 72), "C"},
+		{"A minor arpeggio", notes(45, 48, 52, 57, 45, 48, 52, 57, 45, 0, 45, 52), "Am"},
+		{"empty", notes(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectKey(tt.pattern); got != tt.want {
+				t.Errorf("DetectKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	p := notes(36, 0, 36, 0, 48)
+	p.Steps[2].Slide = true
+	p.Steps[3] = converter.Step{Note: 36, Gate: true, Tie: true, Velocity: 100}
+
+	r := Analyze(p)
+	if r.Notes != 3 || r.Density != 4.0/16 || r.Tempo != 128 {
+		t.Errorf("Analyze() = %+v", r)
+	}
+	if r.LowNote != 36 || r.HighNote != 48 || !r.HasSlides || r.HasAccents || !r.HasTies {
+		t.Errorf("Analyze() = %+v", r)
+	}
+
+	want := []string{"key:" + r.Key, "slides", "ties", "sparse"}
+	if got := r.Tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+}
+
+func TestStats(t *testing.T) {
+	a := notes(45, 48, 52, 57, 45, 48, 52, 57)
+	a.Steps[1].Slide = true
+	a.Steps[2].Accent = true
+	b := notes(36, 0, 36, 0)
+	b.Tempo = 135
+	empty := notes()
+	empty.Tempo = 0
+
+	s := NewStats()
+	for _, p := range []*converter.Pattern{a, b, empty} {
+		s.Add(Analyze(p))
+	}
+
+	if s.Patterns != 3 || s.Keys["Am"] != 1 || s.Keys[""] != 1 {
+		t.Errorf("keys = %v", s.Keys)
+	}
+	if s.Tempos[120] != 1 || s.Tempos[130] != 1 || s.NoTempo != 1 {
+		t.Errorf("tempos = %v, no tempo = %d", s.Tempos, s.NoTempo)
+	}
+	if s.AvgNotes != 10.0/3 || s.SlideRate != 0.1 || s.AccentRate != 0.1 || s.WithSlides != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	if s.LowNote != 36 || s.HighNote != 57 {
+		t.Errorf("range = %d-%d, want 36-57", s.LowNote, s.HighNote)
+	}
+
+	// Note 0 is a real note, not an empty range
+	low := &converter.Pattern{Length: 2, Steps: []converter.Step{{Note: 0, Gate: true}, {Note: 12, Gate: true}}}
+	lowStats := NewStats()
+	lowStats.Add(Analyze(low))
+	lowStats.Add(Analyze(notes(36)))
+	if lowStats.LowNote != 0 || lowStats.HighNote != 36 {
+		t.Errorf("range with note 0 = %d-%d, want 0-36", lowStats.LowNote, lowStats.HighNote)
+	}
+	if r := Analyze(low); r.LowNote != 0 || r.HighNote != 12 {
+		t.Errorf("Analyze() range = %d-%d, want 0-12", r.LowNote, r.HighNote)
+	}
+
+	var buf strings.Builder
+	s.WriteReport(&buf)
+	for _, want := range []string{"Patterns:      3", "130-139", "(unknown)", "Am", "10% of notes"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	s.Names = converter.NoteNaming{Style: converter.NoteNamesSolfege, MiddleC: 3}
+	s.WriteReport(&buf)
+	for _, want := range []string{"Do1 to La2", "Lam"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("solfège report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// TempoBucket is the width in BPM of the tempo distribution buckets
+const TempoBucket = 10
+
+// Stats aggregates the features of many patterns, e.g. a folder or library
+type Stats struct {
+	Patterns    int            `json:"patterns"`
+	Keys        map[string]int `json:"keys"`   // Patterns per detected key ("" = no notes)
+	Tempos      map[int]int    `json:"tempos"` // Patterns per tempo bucket, keyed by its lowest BPM
+	NoTempo     int            `json:"no_tempo"`
+	AvgDensity  float64        `json:"avg_density"`
+	AvgNotes    float64        `json:"avg_notes"`
+	SlideRate   float64        `json:"slide_rate"`  // Fraction of notes with slide
+	AccentRate  float64        `json:"accent_rate"` // Fraction of notes with accent
+	WithSlides  int            `json:"with_slides"` // Patterns using slides
+	WithAccents int            `json:"with_accents"`
+	WithTies    int            `json:"with_ties"`
+	LowNote     uint8          `json:"low_note,omitempty"`
+	HighNote    uint8          `json:"high_note,omitempty"`
+
+	// Names selects how WriteReport names notes and keys
+	Names converter.NoteNaming `json:"-"`
+
+	density  float64
+	notes    int
+	slides   int
+	accents  int
+	hasNotes bool // LowNote and HighNote are set; note 0 is a real note
+}
+
+// NewStats returns empty stats
+func NewStats() *Stats {
+	return &Stats{Keys: make(map[string]int), Tempos: make(map[int]int)}
+}
+
+// Add includes a pattern's analysis in the stats
+func (s *Stats) Add(r *Result) {
+	s.Patterns++
+	s.Keys[r.Key]++
+	if r.Tempo > 0 {
+		s.Tempos[int(r.Tempo)/TempoBucket*TempoBucket]++
+	} else {
+		s.NoTempo++
+	}
+
+	s.density += r.Density
+	s.notes += r.Notes
+	s.slides += r.Slides
+	s.accents += r.Accents
+	if r.HasSlides {
+		s.WithSlides++
+	}
+	if r.HasAccents {
+		s.WithAccents++
+	}
+	if r.HasTies {
+		s.WithTies++
+	}
+	if r.Notes > 0 {
+		if !s.hasNotes || r.LowNote < s.LowNote {
+			s.LowNote = r.LowNote
+		}
+		s.HighNote = max(s.HighNote, r.HighNote)
+		s.hasNotes = true
+	}
+
+	s.AvgDensity = s.density / float64(s.Patterns)
+	s.AvgNotes = float64(s.notes) / float64(s.Patterns)
+	if s.notes > 0 {
+		s.SlideRate = float64(s.slides) / float64(s.notes)
+		s.AccentRate = float64(s.accents) / float64(s.notes)
+	}
+}
+
+// WriteReport prints the stats as a text report with bar charts
+func (s *Stats) WriteReport(w io.Writer) {
+	fmt.Fprintf(w, "Patterns:      %d\n", s.Patterns)
+	if s.Patterns == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Avg density:   %.0f%%\n", s.AvgDensity*100)
+	fmt.Fprintf(w, "Avg notes:     %.1f\n", s.AvgNotes)
+	fmt.Fprintf(w, "Slides:        %.0f%% of notes, in %d pattern(s)\n", s.SlideRate*100, s.WithSlides)
+	fmt.Fprintf(w, "Accents:       %.0f%% of notes, in %d pattern(s)\n", s.AccentRate*100, s.WithAccents)
+	fmt.Fprintf(w, "Ties:          in %d pattern(s)\n", s.WithTies)
+	if s.hasNotes {
+		fmt.Fprintf(w, "Range:         %s to %s\n", s.Names.Name(s.LowNote), s.Names.Name(s.HighNote))
+	}
+
+	keys := make([]string, 0, len(s.Keys))
+	for k := range s.Keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if s.Keys[keys[i]] != s.Keys[keys[j]] {
+			return s.Keys[keys[i]] > s.Keys[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintln(w, "\nKeys:")
+	for _, k := range keys {
+		label := s.Names.Key(k)
+		if label == "" {
+			label = "(none)"
+		}
+		writeBar(w, label, s.Keys[k], s.Patterns)
+	}
+
+	fmt.Fprintln(w, "\nTempo:")
+	tempos := make([]int, 0, len(s.Tempos))
+	for t := range s.Tempos {
+		tempos = append(tempos, t)
+	}
+	sort.Ints(tempos)
+	for _, t := range tempos {
+		writeBar(w, fmt.Sprintf("%d-%d", t, t+TempoBucket-1), s.Tempos[t], s.Patterns)
+	}
+	if s.NoTempo > 0 {
+		writeBar(w, "(unknown)", s.NoTempo, s.Patterns)
+	}
+}
+
+// writeBar prints one row of a distribution chart
+func writeBar(w io.Writer, label string, n, total int) {
+	const width = 30
+	bar := int(math.Round(float64(n) / float64(total) * width))
+	fmt.Fprintf(w, "  %-10s %-*s %d\n", label, width, strings.Repeat("#", bar), n)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compressMiddleware compresses text and JSON responses with gzip or
+// deflate when the client accepts it. Binary downloads (patterns, zips,
+// images) are sent as is: they are small or already compressed, and their
+// Content-Length and ranges must describe the real bytes.
+func compressMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := acceptedEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		cw := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = cw
+		c.Next()
+		cw.close()
+	}
+}
+
+// acceptedEncoding picks gzip or deflate from an Accept-Encoding header
+func acceptedEncoding(header string) string {
+	best := ""
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip":
+			return "gzip"
+		case "deflate":
+			best = "deflate"
+		}
+	}
+	return best
+}
+
+// compressible reports whether a response of this content type is worth
+// compressing; streams are left alone so events arrive immediately
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", mediaType == "application/javascript":
+		return true
+	}
+	return false
+}
+
+// compressWriter decides on the first write, once the handler has set the
+// content type, whether to compress the response
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	decided  bool
+	w        io.WriteCloser
+}
+
+func (w *compressWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+		return
+	}
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	h.Del("Accept-Ranges")
+	if w.encoding == "gzip" {
+		w.w = gzip.NewWriter(w.ResponseWriter)
+	} else {
+		w.w, _ = flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.w == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.w.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been compressed so far
+func (w *compressWriter) Flush() {
+	if f, ok := w.w.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) close() {
+	if w.w != nil {
+		_ = w.w.Close()
+	}
+}
+
+// sendData sends a binary download with its exact Content-Length and an
+// ETag derived from the content, so clients can cache converted files and
+// revalidate them (If-None-Match) or fetch part of them (Range)
+func sendData(c *gin.Context, contentType string, data []byte) {
+	sum := sha256.Sum256(data)
+	c.Header("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	c.Header("Content-Type", contentType)
+	http.ServeContent(c.Writer, c.Request, "", time.Time{}, bytes.NewReader(data))
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(nil, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil)
+	req.Header.Set("Accept-Encoding", "br;q=1.0, gzip;q=0.8")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct{ Devices []any }
+	if err := json.NewDecoder(zr).Decode(&list); err != nil || len(list.Devices) == 0 {
+		t.Errorf("decompressed body: %v, %d devices", err, len(list.Devices))
+	}
+
+	for _, accept := range []string{"", "gzip;q=0", "identity"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q", accept, got)
+		}
+	}
+}
+
+func TestDownloadCaching(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(nil, Options{})
+	share, err := converter.EncodeShareString(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 45, Gate: true, Velocity: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "/api/v1/share/" + share + "?format=seq"
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("GET %s = %d, ETag %q, Content-Encoding %q", url, w.Code, etag, w.Header().Get("Content-Encoding"))
+	}
+	full := w.Body.Bytes()
+	if got := w.Header().Get("Content-Length"); got != strconv.Itoa(len(full)) {
+		t.Errorf("Content-Length = %s, want %d", got, len(full))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	part, _ := io.ReadAll(w.Body)
+	if w.Code != http.StatusPartialContent || string(part) != string(full[:4]) {
+		t.Errorf("range GET = %d, % X", w.Code, part)
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/httpapi"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// inspectFormat asks the multi-target conversion for the inspect JSON
+const inspectFormat = "json"
+
+// handleConvertMulti godoc
+// @Summary Convert a pattern to several formats at once
+// @Description Upload a .seq, .syx, .303, TR pattern (.prm), Circuit session (.ncs), or MIDI file and receive a zip with one rendition per requested format. The input is parsed once, so all renditions hold the same pattern; "json" adds the inspect JSON (steps and analysis).
+// @Tags convert
+// @Accept multipart/form-data
+// @Produce application/zip
+// @Param file formData file true "Pattern file to convert"
+// @Param to query string true "Comma-separated formats: seq, syx, midi, json"
+// @Param from query string false "Source format, overriding the file extension: seq, syx, midi, or 303"
+// @Param device query string false "Device (default: td3)"
+// @Param device_id query int false "SysEx device ID for .syx output (0-127)"
+// @Param transpose query int false "Semitones to transpose the outputs by (-127 to 127)"
+// @Param strict query bool false "Fail with the warnings instead of fixing the pattern up"
+// @Param channel query int false "MIDI channel read from MIDI input and written to MIDI output (1-16)"
+// @Param gate_length query number false "Fraction of a step plain notes sound in MIDI output (default 0.75)"
+// @Param accent_threshold query int false "Lowest velocity read as an accent from MIDI (default 101)"
+// @Param ghost_threshold query int false "Velocity below which drum hits are read as ghost notes (default 60)"
+// @Param slide_mode query string false "How slides are read from MIDI: interval (default), legato, or none"
+// @Param bar query int false "Read only this bar (1-based) of multi-bar MIDI"
+// @Param downmix query string false "Fit MIDI clips longer than the device's patterns: fold, densest, thin, or halve"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/convert [post]
+func handleConvertMulti(c *gin.Context) {
+	var formats []converter.Format
+	withReport := false
+	for _, name := range strings.Split(c.Query("to"), ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+		case inspectFormat:
+			withReport = true
+		case "seq", "syx":
+			formats = append(formats, converter.Format(name))
+		case "midi", "mid":
+			formats = append(formats, converter.FormatMIDI)
+		default:
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("unknown format %q (use seq, syx, midi, or json)", name))
+			return
+		}
+	}
+	if len(formats) == 0 && !withReport {
+		respondError(c, http.StatusBadRequest, "to must list at least one format (seq, syx, midi, json)")
+		return
+	}
+
+	data, header, ok := readUpload(c)
+	if !ok {
+		return
+	}
+	from, ok := uploadFrom(c)
+	if !ok {
+		return
+	}
+	opts, err := conversionOptions(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The input is parsed once, so all renditions hold the same pattern
+	resp, err := service.Convert(c.Request.Context(), service.ConvertRequest{
+		Data:     data,
+		Filename: header.Filename,
+		From:     from,
+		To:       formats,
+		Device:   c.Query("device"),
+		Options:  opts,
+		Limits:   requestLimits(c),
+	})
+	if err != nil {
+		serviceError(c, err, httpapi.FromHint(c.Request))
+		return
+	}
+	zipped, err := resp.Zip(header.Filename, withReport)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if warnings := resp.WarningStrings(); len(warnings) > 0 {
+		c.Header("X-Conversion-Warnings", strings.Join(warnings, "; "))
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", service.BaseName(header.Filename)))
+	sendData(c, "application/zip", zipped)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+func TestConvertMulti(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(newRouter(nil, Options{}))
+	defer srv.Close()
+
+	td3 := devices.NewTD3()
+	seq, err := td3.GenerateSeq(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 45, Gate: true, Velocity: 127, Accent: true}, {Note: 48, Gate: true, Velocity: 100, Slide: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(to string) *http.Response {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, _ := w.CreateFormFile("file", "line.seq")
+		_, _ = part.Write(seq)
+		_ = w.Close()
+		resp, err := http.Post(srv.URL+"/api/v1/convert?to="+to, w.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post("syx,midi,json")
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /convert status = %d: %s", resp.StatusCode, data)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("response is not a zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		_ = rc.Close()
+	}
+	if len(files) != 3 || files["line.syx"] == nil || files["line.mid"] == nil {
+		t.Fatalf("zip holds %d files: %v", len(files), zr.File)
+	}
+	p, err := td3.ParseSyx(files["line.syx"])
+	if err != nil {
+		t.Fatalf("zipped .syx: %v", err)
+	}
+	if s := p.Steps[0]; s.Note != 45 || !s.Accent || !p.Steps[1].Slide {
+		t.Errorf("zipped .syx steps = %+v", p.Steps[:2])
+	}
+	var report service.Report
+	if err := json.Unmarshal(files["line.json"], &report); err != nil {
+		t.Fatalf("inspect JSON: %v", err)
+	}
+	if report.Format != "seq" || report.Analysis == nil || report.Steps[0].NoteName != "A2" || !report.Steps[1].Slide {
+		t.Errorf("inspect JSON = %+v", report)
+	}
+
+	for _, to := range []string{"", "wav"} {
+		resp := post(to)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("POST /convert?to=%s status = %d, want 400", to, resp.StatusCode)
+		}
+	}
+
+	// The same file as JSON, for clients without multipart support
+	body, _ := json.Marshal(map[string]any{"file": base64.StdEncoding.EncodeToString(seq), "filename": "line.seq", "transpose": 12})
+	resp, err = http.Post(srv.URL+"/api/v1/convert/seq2syx", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Disposition") != "attachment; filename=line.syx" {
+		t.Fatalf("POST JSON /convert/seq2syx = %d %v: %s", resp.StatusCode, resp.Header, data)
+	}
+	if p, err := td3.ParseSyx(data); err != nil || p.Steps[0].Note != 57 {
+		t.Errorf("JSON upload converted to %v, %v; want the transposed note 57", p, err)
+	}
+}
+
+func TestConversionOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(nil, Options{})
+
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 45, Gate: true, Velocity: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(query string, form map[string]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("file", "line.seq")
+		_, _ = part.Write(seq)
+		for k, v := range form {
+			_ = mw.WriteField(k, v)
+		}
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/convert/seq2midi"+query, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Options from the query string and the form both apply
+	w := post("?transpose=12", map[string]string{"channel": "2"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("seq2midi with options = %d: %s", w.Code, w.Body)
+	}
+	s, err := smf.ReadFrom(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ch, key, vel uint8
+	found := false
+	for _, ev := range s.Tracks[0] {
+		if ev.Message.GetNoteStart(&ch, &key, &vel) {
+			found = true
+			break
+		}
+	}
+	if !found || ch != 1 || key != 57 {
+		t.Errorf("note on channel %d key %d, want channel 1 (2nd) key 57", ch, key)
+	}
+
+	for _, query := range []string{"?channel=17", "?gate_length=0", "?slide_mode=glide", "?strict=maybe", "?bar=0", "?device_id=300"} {
+		if w := post(query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("seq2midi%s = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Environment variables read for CORS settings not given by flag or config,
+// each a comma-separated list
+const (
+	EnvCORSOrigins = "SYNTHTRIBE2MIDI_CORS_ORIGINS"
+	EnvCORSMethods = "SYNTHTRIBE2MIDI_CORS_METHODS"
+	EnvCORSHeaders = "SYNTHTRIBE2MIDI_CORS_HEADERS"
+)
+
+// Default CORS policy: any origin may call the API, without credentials
+var (
+	DefaultCORSOrigins = []string{"*"}
+	DefaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	DefaultCORSHeaders = []string{"Content-Type", "Authorization", RequestIDHeader}
+)
+
+// corsExposedHeaders are the response headers browsers may read
+var corsExposedHeaders = []string{RequestIDHeader, "X-Conversion-Warnings", "X-SysEx-Device-ID", "Content-Disposition", "ETag"}
+
+// CORSConfig is the cross-origin policy for browser clients
+type CORSConfig struct {
+	// Origins allowed to call the API, e.g. https://app.example.com; "*"
+	// allows any origin and "none" disables cross-origin access
+	Origins []string `yaml:"origins"`
+	// Methods and Headers allowed in cross-origin requests
+	Methods []string `yaml:"methods"`
+	Headers []string `yaml:"headers"`
+	// Credentials lets browsers send cookies and auth headers; it requires
+	// explicit origins
+	Credentials bool `yaml:"credentials"`
+}
+
+// WithDefaults fills unset fields from the environment, then the defaults
+func (c CORSConfig) WithDefaults() CORSConfig {
+	fill := func(list []string, env string, def []string) []string {
+		if len(list) > 0 {
+			return list
+		}
+		if v := os.Getenv(env); v != "" {
+			return splitList(v)
+		}
+		return def
+	}
+	c.Origins = fill(c.Origins, EnvCORSOrigins, DefaultCORSOrigins)
+	c.Methods = fill(c.Methods, EnvCORSMethods, DefaultCORSMethods)
+	c.Headers = fill(c.Headers, EnvCORSHeaders, DefaultCORSHeaders)
+	return c
+}
+
+// Validate rejects policies that would let any site act with a user's
+// credentials
+func (c CORSConfig) Validate() error {
+	if c.Credentials && slices.Contains(c.Origins, "*") {
+		return errors.New("CORS credentials require explicit allowed origins, not *")
+	}
+	for _, o := range c.Origins {
+		if o != "*" && o != "none" && !strings.Contains(o, "://") {
+			return fmt.Errorf("CORS origin %q must include the scheme, e.g. https://%s", o, o)
+		}
+	}
+	return nil
+}
+
+// allows reports whether origin may make cross-origin requests
+func (c CORSConfig) allows(origin string) bool {
+	for _, o := range c.Origins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware applies the CORS policy. Requests from other origins are
+// still served (CORS is enforced by browsers), but without the headers that
+// let a page read the response.
+func corsMiddleware(cfg CORSConfig) gin.HandlerFunc {
+	cfg = cfg.WithDefaults()
+	methods := strings.Join(cfg.Methods, ", ")
+	headers := strings.Join(cfg.Headers, ", ")
+	exposed := strings.Join(corsExposedHeaders, ", ")
+	wildcard := slices.Contains(cfg.Origins, "*") && !cfg.Credentials
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin != "" && cfg.allows(origin) {
+			if wildcard {
+				c.Header("Access-Control-Allow-Origin", "*")
+			} else {
+				c.Header("Access-Control-Allow-Origin", origin)
+				c.Writer.Header().Add("Vary", "Origin")
+			}
+			if cfg.Credentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			c.Header("Access-Control-Expose-Headers", exposed)
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
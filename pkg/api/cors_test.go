@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		cfg         CORSConfig
+		method      string
+		origin      string
+		wantOrigin  string
+		wantCreds   string
+		wantStatus  int
+		wantMethods bool
+	}{
+		{"default any origin", CORSConfig{}, http.MethodGet, "https://a.example", "*", "", http.StatusOK, true},
+		{"no origin header", CORSConfig{}, http.MethodGet, "", "", "", http.StatusOK, false},
+		{"listed origin", CORSConfig{Origins: []string{"https://a.example/"}}, http.MethodGet, "https://a.example", "https://a.example", "", http.StatusOK, true},
+		{"unlisted origin", CORSConfig{Origins: []string{"https://a.example"}}, http.MethodGet, "https://evil.example", "", "", http.StatusOK, false},
+		{"credentials", CORSConfig{Origins: []string{"https://a.example"}, Credentials: true}, http.MethodGet, "https://a.example", "https://a.example", "true", http.StatusOK, true},
+		{"disabled", CORSConfig{Origins: []string{"none"}}, http.MethodGet, "https://a.example", "", "", http.StatusOK, false},
+		{"preflight", CORSConfig{Origins: []string{"https://a.example"}}, http.MethodOptions, "https://a.example", "https://a.example", "", http.StatusNoContent, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvCORSOrigins, "")
+			r := newRouter(nil, Options{CORS: tt.cfg})
+			req := httptest.NewRequest(tt.method, "/health", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			h := w.Header()
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+			if got := h.Get("Access-Control-Allow-Methods") != ""; got != tt.wantMethods {
+				t.Errorf("Allow-Methods set = %v, want %v", got, tt.wantMethods)
+			}
+			if tt.wantOrigin != "" && tt.wantOrigin != "*" && h.Get("Vary") == "" {
+				t.Error("echoed origin without Vary: Origin")
+			}
+		})
+	}
+}
+
+func TestCORSConfig(t *testing.T) {
+	t.Setenv(EnvCORSOrigins, "https://a.example, https://b.example,")
+	t.Setenv(EnvCORSMethods, "")
+	cfg := CORSConfig{Headers: []string{"X-Custom"}}.WithDefaults()
+	if len(cfg.Origins) != 2 || cfg.Origins[1] != "https://b.example" {
+		t.Errorf("origins from env = %q", cfg.Origins)
+	}
+	if len(cfg.Methods) != len(DefaultCORSMethods) || len(cfg.Headers) != 1 {
+		t.Errorf("methods %q, headers %q; want defaults and the given headers", cfg.Methods, cfg.Headers)
+	}
+
+	tests := []struct {
+		cfg     CORSConfig
+		wantErr bool
+	}{
+		{CORSConfig{Origins: []string{"*"}}, false},
+		{CORSConfig{Origins: []string{"https://a.example"}, Credentials: true}, false},
+		{CORSConfig{Origins: []string{"*"}, Credentials: true}, true},
+		{CORSConfig{Origins: []string{"a.example"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want error %v", tt.cfg, err, tt.wantErr)
+		}
+	}
+}
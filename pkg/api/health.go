@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+)
+
+// readyTimeout bounds how long a readiness check may take
+const readyTimeout = 5 * time.Second
+
+// Check is a readiness check of a dependency, such as storage or a MIDI
+// backend. Run returns nil when the dependency is usable.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // ok, failed, or disabled
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Readiness is the response of the readiness endpoint
+type Readiness struct {
+	Status string        `json:"status"` // ready or unready
+	Checks []CheckResult `json:"checks"`
+}
+
+// libraryCheck checks that the library storage can be read; without a
+// library the library endpoints are off, which does not make the API unready
+func libraryCheck(lib *library.Library) Check {
+	return Check{Name: "library", Run: func(ctx context.Context) error {
+		if lib == nil {
+			return errCheckDisabled
+		}
+		return lib.Check()
+	}}
+}
+
+// errCheckDisabled marks a check whose dependency is not configured
+var errCheckDisabled = errors.New("disabled")
+
+// handleLivez godoc
+// @Summary Liveness probe
+// @Description Reports that the process is up and serving requests; it checks no dependencies, so a failing dependency never gets the server restarted
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /livez [get]
+func handleLivez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyzHandler godoc
+// @Summary Readiness probe
+// @Description Runs the dependency checks (library storage and, in the daemon, the MIDI backend and watch folders) and responds 503 if any fails
+// @Tags health
+// @Produce json
+// @Success 200 {object} Readiness
+// @Failure 503 {object} Readiness
+// @Router /readyz [get]
+func readyzHandler(checks []Check) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ready := runChecks(c.Request.Context(), checks)
+		status := http.StatusOK
+		if ready.Status != "ready" {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, ready)
+	}
+}
+
+// runChecks runs the checks concurrently, each bounded by readyTimeout
+func runChecks(ctx context.Context, checks []Check) Readiness {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	ready := Readiness{Status: "ready", Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			errc := make(chan error, 1)
+			go func() { errc <- check.Run(ctx) }()
+
+			var err error
+			select {
+			case err = <-errc:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			res := CheckResult{Name: check.Name, Status: "ok", Duration: time.Since(start).Round(time.Microsecond).String()}
+			switch {
+			case errors.Is(err, errCheckDisabled):
+				res.Status = "disabled"
+			case err != nil:
+				res.Status, res.Error = "failed", err.Error()
+			}
+			ready.Checks[i] = res
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range ready.Checks {
+		if res.Status == "failed" {
+			ready.Status = "unready"
+		}
+	}
+	return ready
+}
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job states
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job event types sent on a job's event stream
+const (
+	EventProgress = "progress"
+	EventWarning  = "warning"
+	EventDone     = "done"
+	EventFailed   = "failed"
+)
+
+// Job retention: finished jobs are kept for jobRetention, and at most
+// maxFinishedJobs of them
+const (
+	jobRetention    = time.Hour
+	maxFinishedJobs = 100
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment, so
+// proxies do not close it
+const sseKeepAlive = 15 * time.Second
+
+// JobInfo describes an asynchronous job
+type JobInfo struct {
+	ID       string    `json:"id"`
+	Kind     string    `json:"kind"` // convert or backup
+	Status   string    `json:"status"`
+	Progress int       `json:"progress"`
+	Total    int       `json:"total"`
+	Message  string    `json:"message,omitempty"`
+	Warnings []string  `json:"warnings,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Result   string    `json:"result,omitempty"` // file name of the result
+	Created  time.Time `json:"created"`
+	Finished time.Time `json:"finished,omitzero"`
+}
+
+// JobEvent is one update on a job's event stream
+type JobEvent struct {
+	Type     string `json:"type"`
+	Progress int    `json:"progress"`
+	Total    int    `json:"total"`
+	Message  string `json:"message,omitempty"`
+}
+
+// job is a running or finished job with its subscribers
+type job struct {
+	mu          sync.Mutex
+	info        JobInfo
+	result      []byte
+	contentType string
+	subscribers []chan JobEvent
+}
+
+// jobContext lets a job's task report progress and warnings
+type jobContext struct {
+	j *job
+}
+
+// Progress records that done of the job's total units of work are complete
+func (jc jobContext) Progress(done int, msg string) {
+	jc.j.publish(func(info *JobInfo) JobEvent {
+		info.Progress, info.Message = done, msg
+		return JobEvent{Type: EventProgress, Progress: done, Total: info.Total, Message: msg}
+	})
+}
+
+// Warn records a warning without stopping the job
+func (jc jobContext) Warn(msg string) {
+	jc.j.publish(func(info *JobInfo) JobEvent {
+		info.Warnings = append(info.Warnings, msg)
+		return JobEvent{Type: EventWarning, Progress: info.Progress, Total: info.Total, Message: msg}
+	})
+}
+
+// jobTask does a job's work and returns its result file
+type jobTask func(jc jobContext) (data []byte, name, contentType string, err error)
+
+// publish updates the job and sends the resulting event to subscribers;
+// a final event closes their streams
+func (j *job) publish(update func(info *JobInfo) JobEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	ev := update(&j.info)
+	final := ev.Type == EventDone || ev.Type == EventFailed
+	for _, ch := range j.subscribers {
+		if !final {
+			// A stalled client misses progress updates rather than
+			// holding up the job
+			select {
+			case ch <- ev:
+			default:
+			}
+			continue
+		}
+		// but always gets the final event
+		select {
+		case ch <- ev:
+		default:
+			<-ch
+			ch <- ev
+		}
+		close(ch)
+	}
+	if final {
+		j.subscribers = nil
+	}
+}
+
+// subscribe returns the job's current state and a channel of its further
+// events, or a nil channel if the job has finished
+func (j *job) subscribe() (JobInfo, chan JobEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.info.Status != JobRunning {
+		return j.snapshot(), nil
+	}
+	ch := make(chan JobEvent, 16)
+	j.subscribers = append(j.subscribers, ch)
+	return j.snapshot(), ch
+}
+
+func (j *job) unsubscribe(ch chan JobEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if i := slices.Index(j.subscribers, ch); i >= 0 {
+		j.subscribers = slices.Delete(j.subscribers, i, i+1)
+	}
+}
+
+// snapshot copies the job info; the caller holds j.mu
+func (j *job) snapshot() JobInfo {
+	info := j.info
+	info.Warnings = slices.Clone(info.Warnings)
+	return info
+}
+
+// Info returns a copy of the job's state
+func (j *job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.snapshot()
+}
+
+// jobStore keeps the jobs of one server in memory
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{jobs: make(map[string]*job)}
+}
+
+// start runs task in the background as a new job of total units of work
+func (s *jobStore) start(kind string, total int, task jobTask) JobInfo {
+	j := &job{info: JobInfo{ID: newRequestID(), Kind: kind, Status: JobRunning, Total: total, Created: time.Now()}}
+	s.mu.Lock()
+	s.prune(time.Now())
+	s.jobs[j.info.ID] = j
+	s.mu.Unlock()
+
+	go func() {
+		data, name, contentType, err := task(jobContext{j})
+		j.publish(func(info *JobInfo) JobEvent {
+			info.Finished = time.Now()
+			if err != nil {
+				info.Status, info.Error = JobFailed, err.Error()
+				return JobEvent{Type: EventFailed, Progress: info.Progress, Total: info.Total, Message: err.Error()}
+			}
+			j.result, j.contentType = data, contentType
+			info.Status, info.Result, info.Progress = JobDone, name, info.Total
+			return JobEvent{Type: EventDone, Progress: info.Total, Total: info.Total, Message: name}
+		})
+	}()
+	return j.Info()
+}
+
+// prune drops expired finished jobs and the oldest beyond maxFinishedJobs;
+// the caller holds s.mu
+func (s *jobStore) prune(now time.Time) {
+	var finished []JobInfo
+	for id, j := range s.jobs {
+		info := j.Info()
+		if info.Status == JobRunning {
+			continue
+		}
+		if now.Sub(info.Finished) > jobRetention {
+			delete(s.jobs, id)
+			continue
+		}
+		finished = append(finished, info)
+	}
+	if len(finished) > maxFinishedJobs {
+		slices.SortFunc(finished, func(a, b JobInfo) int { return a.Finished.Compare(b.Finished) })
+		for _, info := range finished[:len(finished)-maxFinishedJobs] {
+			delete(s.jobs, info.ID)
+		}
+	}
+}
+
+func (s *jobStore) get(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+// jobHandlers serves the asynchronous job endpoints
+type jobHandlers struct {
+	jobs *jobStore
+}
+
+// registerJobRoutes mounts the /jobs endpoints
+func registerJobRoutes(g *gin.RouterGroup, jobs *jobStore) {
+	h := &jobHandlers{jobs: jobs}
+
+	g.GET("/jobs", h.listJobs)
+	g.POST("/jobs/convert", h.startConvert)
+	g.POST("/jobs/backup", h.startBackup)
+	g.GET("/jobs/:id", h.getJob)
+	g.GET("/jobs/:id/events", h.jobEvents)
+	g.GET("/jobs/:id/result", h.jobResult)
+}
+
+// lookup finds the job named in the path or responds 404
+func (h *jobHandlers) lookup(c *gin.Context) (*job, bool) {
+	j, ok := h.jobs.get(c.Param("id"))
+	if !ok {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("unknown job %q", c.Param("id")))
+	}
+	return j, ok
+}
+
+// listJobs godoc
+// @Summary List jobs
+// @Description Returns the running jobs and those finished within the last hour, newest first
+// @Tags jobs
+// @Produce json
+// @Success 200 {object} map[string][]JobInfo
+// @Router /api/v1/jobs [get]
+func (h *jobHandlers) listJobs(c *gin.Context) {
+	h.jobs.mu.Lock()
+	list := make([]JobInfo, 0, len(h.jobs.jobs))
+	for _, j := range h.jobs.jobs {
+		list = append(list, j.Info())
+	}
+	h.jobs.mu.Unlock()
+	slices.SortFunc(list, func(a, b JobInfo) int { return b.Created.Compare(a.Created) })
+	c.JSON(http.StatusOK, gin.H{"jobs": list})
+}
+
+// getJob godoc
+// @Summary Get a job
+// @Description Returns a job's status, progress, and warnings
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} JobInfo
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/jobs/{id} [get]
+func (h *jobHandlers) getJob(c *gin.Context) {
+	if j, ok := h.lookup(c); ok {
+		c.JSON(http.StatusOK, j.Info())
+	}
+}
+
+// jobEvents godoc
+// @Summary Stream job progress
+// @Description Server-Sent Events stream of a job: a "job" event with its current state, then "progress" and "warning" events as they happen, and a final "done" or "failed" event, after which the stream ends
+// @Tags jobs
+// @Produce text/event-stream
+// @Param id path string true "Job ID"
+// @Success 200 {object} JobEvent
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/jobs/{id}/events [get]
+func (h *jobHandlers) jobEvents(c *gin.Context) {
+	j, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	info, events := j.subscribe()
+	if events != nil {
+		defer j.unsubscribe(events)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	writeEvent(c.Writer, "job", info)
+	if events == nil {
+		// Already finished: the state above is final
+		c.Writer.Flush()
+		return
+	}
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		c.Writer.Flush()
+		select {
+		case ev, open := <-events:
+			if !open {
+				return
+			}
+			writeEvent(c.Writer, ev.Type, ev)
+		case <-ticker.C:
+			_, _ = io.WriteString(c.Writer, ": keep-alive\n\n")
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload
+func writeEvent(w io.Writer, event string, data any) {
+	payload, _ := json.Marshal(data)
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// jobResult godoc
+// @Summary Download a job's result
+// @Description Returns the file a finished job produced: a zip of converted patterns or a backup bank
+// @Tags jobs
+// @Produce application/octet-stream
+// @Param id path string true "Job ID"
+// @Success 200 {file} binary
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/jobs/{id}/result [get]
+func (h *jobHandlers) jobResult(c *gin.Context) {
+	j, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	j.mu.Lock()
+	info, data, contentType := j.snapshot(), j.result, j.contentType
+	j.mu.Unlock()
+	switch info.Status {
+	case JobRunning:
+		respondError(c, http.StatusConflict, "job is still running")
+		return
+	case JobFailed:
+		respondError(c, http.StatusConflict, "job failed: "+info.Error)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+info.Result)
+	sendData(c, contentType, data)
+}
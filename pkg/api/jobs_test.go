@@ -0,0 +1,217 @@
+package api
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+// readEvents reads a Server-Sent Events stream until it ends
+func readEvents(t *testing.T, url string) (types []string, last JobEvent) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("events Content-Type = %q", ct)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if name, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+			types = append(types, name)
+		}
+		if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			_ = json.Unmarshal([]byte(data), &last)
+		}
+	}
+	return types, last
+}
+
+// waitJob polls a job until it finishes
+func waitJob(t *testing.T, url string) JobInfo {
+	t.Helper()
+	for range 500 {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info JobInfo
+		_ = json.NewDecoder(resp.Body).Decode(&info)
+		_ = resp.Body.Close()
+		if info.Status != JobRunning {
+			return info
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("job did not finish")
+	return JobInfo{}
+}
+
+func TestJobEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	store := newJobStore()
+	registerJobRoutes(r.Group("/api/v1"), store)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// The task waits for each step, so the stream sees every event live
+	step := make(chan struct{})
+	info := store.start("convert", 2, func(jc jobContext) ([]byte, string, string, error) {
+		<-step
+		jc.Progress(1, "first")
+		jc.Warn("first: a warning")
+		<-step
+		jc.Progress(2, "second")
+		return []byte("result"), "out.txt", "text/plain", nil
+	})
+	go func() {
+		// Let the stream subscribe before the task moves on
+		j, _ := store.get(info.ID)
+		for {
+			j.mu.Lock()
+			subscribed := len(j.subscribers) > 0
+			j.mu.Unlock()
+			if subscribed {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		step <- struct{}{}
+		step <- struct{}{}
+	}()
+
+	types, last := readEvents(t, srv.URL+"/api/v1/jobs/"+info.ID+"/events")
+	want := []string{"job", EventProgress, EventWarning, EventProgress, EventDone}
+	if strings.Join(types, ",") != strings.Join(want, ",") || last.Message != "out.txt" || last.Progress != 2 {
+		t.Errorf("events = %v (last %+v), want %v", types, last, want)
+	}
+
+	// Streams of finished jobs end after their state
+	types, _ = readEvents(t, srv.URL+"/api/v1/jobs/"+info.ID+"/events")
+	if len(types) != 1 {
+		t.Errorf("events of a finished job = %v, want only its state", types)
+	}
+}
+
+func TestConvertJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(newRouter(nil, Options{}))
+	defer srv.Close()
+
+	td3 := devices.NewTD3()
+	var bank []byte
+	for _, n := range []uint8{45, 48} {
+		syx, err := td3.GenerateSyx(&converter.Pattern{Length: 16, Steps: []converter.Step{{Note: n, Gate: true, Velocity: 100}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		bank = append(bank, syx...)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "bank.syx")
+	_, _ = part.Write(bank)
+	part, _ = mw.CreateFormFile("file", "broken.seq")
+	_, _ = part.Write([]byte("not a pattern"))
+	_ = mw.Close()
+
+	resp, err := http.Post(srv.URL+"/api/v1/jobs/convert?to=midi", mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info JobInfo
+	_ = json.NewDecoder(resp.Body).Decode(&info)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || info.ID == "" || info.Total != 3 {
+		t.Fatalf("POST /jobs/convert = %d %+v", resp.StatusCode, info)
+	}
+
+	// The finished job reports the skipped pattern and serves the zip
+	info = waitJob(t, srv.URL+"/api/v1/jobs/"+info.ID)
+	if info.Status != JobDone || info.Progress != 3 || len(info.Warnings) == 0 || info.Result != "converted.zip" {
+		t.Errorf("finished job = %+v", info)
+	}
+	resp, err = http.Get(srv.URL + "/api/v1/jobs/" + info.ID + "/result")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("result is not a zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "bank-01.mid,bank-02.mid" {
+		t.Errorf("zip holds %v", names)
+	}
+}
+
+func TestBackupJobFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(newRouter(nil, Options{}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/v1/jobs/backup?port=no-such-port-xyz", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info JobInfo
+	_ = json.NewDecoder(resp.Body).Decode(&info)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST /jobs/backup = %d", resp.StatusCode)
+	}
+
+	if info = waitJob(t, srv.URL+"/api/v1/jobs/"+info.ID); info.Status != JobFailed || info.Error == "" {
+		t.Errorf("backup of a missing port = %+v, want failed", info)
+	}
+	resp, err = http.Get(srv.URL + "/api/v1/jobs/" + info.ID + "/result")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("result of a failed job = %d, want 409", resp.StatusCode)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/jobs/backup":                 http.StatusBadRequest, // no port
+		"/api/v1/jobs/backup?port=x&device=x": http.StatusBadRequest,
+	} {
+		resp, err := http.Post(srv.URL+path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("POST %s = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+	resp, err = http.Get(srv.URL + "/api/v1/jobs/nope/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("events of an unknown job = %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/james-see/synthtribe2midi/pkg/transfer"
+)
+
+// startConvert godoc
+// @Summary Convert a bank of patterns in the background
+// @Description Upload one or more pattern files (a .syx bank holding several dumps counts as one pattern per dump) and start a job converting them all; follow it on /jobs/{id}/events and download the zip from /jobs/{id}/result. Patterns that fail are reported as warnings and skipped.
+// @Tags jobs
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Pattern files (repeat the field for several)"
+// @Param to query string true "Output format: seq, syx, or midi"
+// @Param device query string false "Device (default: td3)"
+// @Success 202 {object} JobInfo
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/jobs/convert [post]
+func (h *jobHandlers) startConvert(c *gin.Context) {
+	to := converter.Format(strings.ToLower(c.Query("to")))
+	if to == "mid" {
+		to = converter.FormatMIDI
+	}
+	if to != converter.FormatSeq && to != converter.FormatSyx && to != converter.FormatMIDI {
+		respondError(c, http.StatusBadRequest, "to must be seq, syx, or midi")
+		return
+	}
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
+		respondError(c, http.StatusBadRequest, "No file uploaded")
+		return
+	}
+	device, ok := devices.Shared(c.DefaultQuery("device", devices.DefaultID))
+	if !ok {
+		respondError(c, http.StatusBadRequest, devices.Validate(c.Query("device")).Error())
+		return
+	}
+	opts, err := conversionOptions(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Read every upload now; the request is gone when the job runs
+	limits := requestLimits(c)
+	var items []service.BankItem
+	for _, fh := range form.File["file"] {
+		f, err := fh.Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Failed to read file")
+			return
+		}
+		data, err := io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Failed to read file")
+			return
+		}
+		bank, err := service.SplitBank(fh.Filename, data, limits)
+		if err != nil {
+			respondError(c, http.StatusUnprocessableEntity, fmt.Sprintf("%s: %v", fh.Filename, err))
+			return
+		}
+		items = append(items, bank...)
+	}
+	if err := limits.CheckPatterns(len(items)); err != nil {
+		respondError(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	base := strings.TrimSuffix(filepath.Base(form.File["file"][0].Filename), filepath.Ext(form.File["file"][0].Filename))
+	if len(form.File["file"]) > 1 || base == "" {
+		base = "converted"
+	}
+	info := h.jobs.start("convert", len(items), func(jc jobContext) ([]byte, string, string, error) {
+		return convertBank(jc, items, device, opts, limits, to, base)
+	})
+	c.JSON(http.StatusAccepted, info)
+}
+
+// convertBank converts each item and zips the results
+func convertBank(jc jobContext, items []service.BankItem, device converter.Device, opts converter.ConvertOptions, limits converter.Limits, to converter.Format, base string) ([]byte, string, string, error) {
+	conv := converter.New(device)
+	conv.SetOptions(opts)
+	conv.SetLimits(limits)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	converted := 0
+	for i, item := range items {
+		data, warnings, err := convertItem(conv, item, to)
+		for _, w := range warnings {
+			jc.Warn(fmt.Sprintf("%s: %s", item.Name, w))
+		}
+		if err != nil {
+			jc.Warn(fmt.Sprintf("%s: skipped: %v", item.Name, err))
+		} else {
+			if err := service.AddZipFile(zw, item.Name+service.Ext(to), data); err != nil {
+				return nil, "", "", err
+			}
+			converted++
+		}
+		jc.Progress(i+1, item.Name)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", "", err
+	}
+	if converted == 0 {
+		return nil, "", "", errors.New("no pattern could be converted")
+	}
+	return buf.Bytes(), base + ".zip", "application/zip", nil
+}
+
+func convertItem(conv *converter.Converter, item service.BankItem, to converter.Format) ([]byte, []converter.Violation, error) {
+	if item.Format == to {
+		return nil, nil, fmt.Errorf("already in %s format", to)
+	}
+	pattern, err := conv.ParsePattern(item.Data, item.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conv.Generate(pattern, to)
+}
+
+// startBackup godoc
+// @Summary Back up a connected device in the background
+// @Description Start a job requesting every pattern slot from a device attached to the server; follow it on /jobs/{id}/events (one progress event per slot) and download the bank .syx from /jobs/{id}/result
+// @Tags jobs
+// @Produce json
+// @Param port query string true "MIDI port name (or unique part of it)"
+// @Param device query string false "Device (default: td3)"
+// @Param device_id query int false "SysEx device ID of the unit (0-127)"
+// @Param timeout query string false "Time to wait for each dump, e.g. 2s"
+// @Param retries query int false "Re-request a slot this many times after a timeout or damaged dump (default 2)"
+// @Param verify query bool false "Read every slot twice and fail if the dumps differ"
+// @Success 202 {object} JobInfo
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/jobs/backup [post]
+func (h *jobHandlers) startBackup(c *gin.Context) {
+	port, ok := param(c, "port")
+	if !ok {
+		respondError(c, http.StatusBadRequest, "port is required")
+		return
+	}
+	name := c.DefaultQuery("device", devices.DefaultID)
+	device, ok := devices.Shared(name)
+	if !ok {
+		respondError(c, http.StatusBadRequest, devices.Validate(name).Error())
+		return
+	}
+	requester, ok := device.(converter.PatternRequester)
+	if !ok {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("%s does not support pattern dump requests", device.Name()))
+		return
+	}
+
+	opts := transfer.Options{Retries: 2}
+	if v, ok := param(c, "device_id"); ok {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 0 || id > 127 {
+			respondError(c, http.StatusBadRequest, "device_id must be between 0 and 127")
+			return
+		}
+		opts.DeviceID = uint8(id)
+	}
+	if v, ok := param(c, "timeout"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			respondError(c, http.StatusBadRequest, "timeout must be a positive duration, e.g. 2s")
+			return
+		}
+		opts.Timeout = d
+	}
+	if v, ok := param(c, "retries"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 10 {
+			respondError(c, http.StatusBadRequest, "retries must be between 0 and 10")
+			return
+		}
+		opts.Retries = n
+	}
+	if v, ok := param(c, "verify"); ok {
+		verify, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "verify must be true or false")
+			return
+		}
+		opts.Verify = verify
+	}
+
+	prefix := name
+	if info, ok := devices.LookupInfo(name); ok {
+		prefix = info.ID
+	}
+	info := h.jobs.start("backup", requester.Slots(), func(jc jobContext) ([]byte, string, string, error) {
+		return backupDevice(jc, port, requester, opts, prefix)
+	})
+	c.JSON(http.StatusAccepted, info)
+}
+
+// backupDevice pulls every slot of the device, reporting each as progress,
+// and returns the bank
+func backupDevice(jc jobContext, port string, dev converter.PatternRequester, opts transfer.Options, prefix string) ([]byte, string, string, error) {
+	out, err := mididevice.OpenOutput(port)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer func() { _ = out.Close() }()
+	in, err := mididevice.OpenInput(port)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer func() { _ = in.Close() }()
+
+	var bank []byte
+	for slot := range dev.Slots() {
+		dump, err := transfer.Pull(out, in, dev, slot, opts)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("slot %s: %w", dev.SlotName(slot), err)
+		}
+		bank = append(bank, dump...)
+		jc.Progress(slot+1, dev.SlotName(slot))
+	}
+	return bank, prefix + "-" + time.Now().Format("20060102-150405") + ".syx", "application/octet-stream", nil
+}
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+)
+
+// libraryHandlers serves the pattern library endpoints
+type libraryHandlers struct {
+	lib *library.Library
+}
+
+// registerLibraryRoutes mounts the /patterns and /collections endpoints
+func registerLibraryRoutes(g *gin.RouterGroup, lib *library.Library) {
+	h := &libraryHandlers{lib: lib}
+
+	g.GET("/patterns", h.listPatterns)
+	g.POST("/patterns", h.uploadPattern)
+	g.GET("/patterns/:id", h.getPattern)
+	g.PUT("/patterns/:id", h.putPattern)
+	g.DELETE("/patterns/:id", h.deletePattern)
+	g.GET("/patterns/:id/data", h.getPatternData)
+	g.PUT("/patterns/:id/data", h.putPatternData)
+	g.GET("/patterns/:id/image", h.getPatternImage)
+	g.POST("/patterns/:id/tags", h.addTags)
+	g.DELETE("/patterns/:id/tags/:tag", h.removeTag)
+	g.PUT("/patterns/:id/rating", h.setRating)
+
+	g.GET("/collections", h.listCollections)
+	g.GET("/collections/:name", h.getCollection)
+	g.PUT("/collections/:name", h.putCollection)
+	g.DELETE("/collections/:name", h.deleteCollection)
+}
+
+// libraryError maps library errors to HTTP responses
+func libraryError(c *gin.Context, err error) {
+	if errors.Is(err, library.ErrNotFound) {
+		respondError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	respondError(c, http.StatusInternalServerError, err.Error())
+}
+
+// available responds with 503 when the server runs without a library
+func (h *libraryHandlers) available(c *gin.Context) bool {
+	if h.lib == nil {
+		respondError(c, http.StatusServiceUnavailable, "pattern library unavailable")
+		return false
+	}
+	return true
+}
+
+// listPatterns godoc
+// @Summary List library patterns
+// @Description Returns library entries, optionally filtered by tag, rating, or collection
+// @Tags library
+// @Produce json
+// @Param tag query string false "Only patterns with this tag"
+// @Param min_rating query int false "Only patterns rated at least this"
+// @Param collection query string false "Only patterns in this collection (in order)"
+// @Param kind query string false "Only patterns or patch dumps (pattern, patch)"
+// @Success 200 {object} map[string][]library.Entry
+// @Router /api/v1/patterns [get]
+func (h *libraryHandlers) listPatterns(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	minRating, _ := strconv.Atoi(c.Query("min_rating"))
+	entries, err := h.lib.List(library.Filter{
+		Tag:        c.Query("tag"),
+		MinRating:  minRating,
+		Collection: c.Query("collection"),
+		Kind:       c.Query("kind"),
+	})
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	if entries == nil {
+		entries = []library.Entry{}
+	}
+	c.JSON(http.StatusOK, gin.H{"patterns": entries})
+}
+
+// uploadPattern godoc
+// @Summary Add a pattern to the library
+// @Description Upload a pattern file with optional name and comma-separated tags
+// @Tags library
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Pattern file"
+// @Param name formData string false "Pattern name (default: file name)"
+// @Param tags formData string false "Comma-separated tags"
+// @Param kind formData string false "patch to store a synth patch SysEx dump unchanged"
+// @Param device query string false "Device (default: td3)"
+// @Success 201 {object} library.Entry
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/patterns [post]
+func (h *libraryHandlers) uploadPattern(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	data, header, ok := readUpload(c)
+	if !ok {
+		return
+	}
+
+	format := converter.DetectFormat(header.Filename)
+	if format == converter.FormatUnknown {
+		format = converter.DetectFormatFromContent(data)
+	}
+
+	name := c.PostForm("name")
+	if name == "" {
+		name = strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
+	}
+
+	var entry *library.Entry
+	var err error
+	if c.PostForm("kind") == library.KindPatch {
+		entry, err = h.lib.AddPatch(name, data)
+		if errors.Is(err, library.ErrInvalidPatch) {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		entry, err = h.lib.Add(name, string(format), c.DefaultQuery("device", devices.DefaultID), data)
+	}
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	if tags := c.PostForm("tags"); tags != "" {
+		if entry, err = h.lib.Tag(entry.ID, strings.Split(tags, ",")...); err != nil {
+			libraryError(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// getPattern godoc
+// @Summary Get library pattern metadata
+// @Tags library
+// @Produce json
+// @Param id path string true "Pattern ID or unique prefix"
+// @Success 200 {object} library.Entry
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/patterns/{id} [get]
+func (h *libraryHandlers) getPattern(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	entry, err := h.lib.Get(c.Param("id"))
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// putPattern godoc
+// @Summary Create or replace library pattern metadata
+// @Tags library
+// @Accept json
+// @Produce json
+// @Param id path string true "Pattern ID"
+// @Param entry body library.Entry true "Pattern metadata"
+// @Success 200 {object} library.Entry
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/patterns/{id} [put]
+func (h *libraryHandlers) putPattern(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	var entry library.Entry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	entry.ID = c.Param("id")
+	if err := h.lib.Store().SaveEntry(&entry); err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// deletePattern godoc
+// @Summary Remove a pattern from the library
+// @Tags library
+// @Param id path string true "Pattern ID or unique prefix"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/patterns/{id} [delete]
+func (h *libraryHandlers) deletePattern(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	if err := h.lib.Remove(c.Param("id")); err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// getPatternData godoc
+// @Summary Download a library pattern file
+// @Tags library
+// @Produce application/octet-stream
+// @Param id path string true "Pattern ID or unique prefix, or the hash of an earlier version"
+// @Success 200 {file} binary
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/patterns/{id}/data [get]
+func (h *libraryHandlers) getPatternData(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	entry, data, err := h.lib.Data(c.Param("id"))
+	if errors.Is(err, library.ErrNotFound) {
+		// Earlier pattern versions are stored by hash, without an entry
+		if data, err := h.lib.Store().ReadData(c.Param("id")); err == nil {
+			sendData(c, "application/octet-stream", data)
+			return
+		}
+	}
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+entry.Name+"."+entry.Format)
+	sendData(c, "application/octet-stream", data)
+}
+
+// putPatternData godoc
+// @Summary Store the file data of a library pattern
+// @Tags library
+// @Accept application/octet-stream
+// @Param id path string true "Pattern ID"
+// @Success 204
+// @Router /api/v1/patterns/{id}/data [put]
+func (h *libraryHandlers) putPatternData(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Failed to read body")
+		return
+	}
+	if err := h.lib.Store().WriteData(c.Param("id"), data); err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// addTags godoc
+// @Summary Tag a library pattern
+// @Tags library
+// @Accept json
+// @Produce json
+// @Param id path string true "Pattern ID or unique prefix"
+// @Param tags body map[string][]string true "Tags to add, as {\"tags\": [...]}"
+// @Success 200 {object} library.Entry
+// @Router /api/v1/patterns/{id}/tags [post]
+func (h *libraryHandlers) addTags(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	entry, err := h.lib.Tag(c.Param("id"), req.Tags...)
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// removeTag godoc
+// @Summary Remove a tag from a library pattern
+// @Tags library
+// @Produce json
+// @Param id path string true "Pattern ID or unique prefix"
+// @Param tag path string true "Tag to remove"
+// @Success 200 {object} library.Entry
+// @Router /api/v1/patterns/{id}/tags/{tag} [delete]
+func (h *libraryHandlers) removeTag(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	entry, err := h.lib.Untag(c.Param("id"), c.Param("tag"))
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// setRating godoc
+// @Summary Rate a library pattern
+// @Tags library
+// @Accept json
+// @Produce json
+// @Param id path string true "Pattern ID or unique prefix"
+// @Param rating body map[string]int true "Rating from 1 to 5 (0 clears), as {\"rating\": n}"
+// @Success 200 {object} library.Entry
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/patterns/{id}/rating [put]
+func (h *libraryHandlers) setRating(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	var req struct {
+		Rating int `json:"rating"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Rating < 0 || req.Rating > library.MaxRating {
+		respondError(c, http.StatusBadRequest, "rating must be between 0 and 5")
+		return
+	}
+	entry, err := h.lib.Rate(c.Param("id"), req.Rating)
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// listCollections godoc
+// @Summary List collections
+// @Tags library
+// @Produce json
+// @Success 200 {object} map[string][]library.Collection
+// @Router /api/v1/collections [get]
+func (h *libraryHandlers) listCollections(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	colls, err := h.lib.Collections()
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	if colls == nil {
+		colls = []library.Collection{}
+	}
+	c.JSON(http.StatusOK, gin.H{"collections": colls})
+}
+
+// getCollection godoc
+// @Summary Get a collection
+// @Tags library
+// @Produce json
+// @Param name path string true "Collection name"
+// @Success 200 {object} library.Collection
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/collections/{name} [get]
+func (h *libraryHandlers) getCollection(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	coll, err := h.lib.Collection(c.Param("name"))
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, coll)
+}
+
+// putCollection godoc
+// @Summary Create or replace a collection
+// @Description Sets the ordered pattern IDs of a collection
+// @Tags library
+// @Accept json
+// @Produce json
+// @Param name path string true "Collection name"
+// @Param collection body library.Collection true "Collection with ordered pattern IDs"
+// @Success 200 {object} library.Collection
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/collections/{name} [put]
+func (h *libraryHandlers) putCollection(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	var coll library.Collection
+	if err := c.ShouldBindJSON(&coll); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	coll.Name = c.Param("name")
+	if err := h.lib.Store().SaveCollection(&coll); err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, coll)
+}
+
+// deleteCollection godoc
+// @Summary Delete a collection
+// @Tags library
+// @Param name path string true "Collection name"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/collections/{name} [delete]
+func (h *libraryHandlers) deleteCollection(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	if err := h.lib.DeleteCollection(c.Param("name")); err != nil {
+		libraryError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
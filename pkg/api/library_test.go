@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+)
+
+func TestRemoteLibrary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	local, err := library.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	srv := httptest.NewServer(newRouter(local, Options{}))
+	defer srv.Close()
+
+	remote, err := library.OpenRemote(srv.URL)
+	if err != nil {
+		t.Fatalf("OpenRemote() error = %v", err)
+	}
+
+	entry, err := remote.Add("Shared", "seq", "td3", []byte{0x23, 0x98, 0x54, 0x76})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := remote.Tag(entry.ID, "acid"); err != nil {
+		t.Fatalf("Tag() error = %v", err)
+	}
+	if _, err := remote.Rate(entry.ID[:6], 4); err != nil {
+		t.Fatalf("Rate() by prefix error = %v", err)
+	}
+	if _, err := remote.AddToCollection("live set A", entry.ID); err != nil {
+		t.Fatalf("AddToCollection() error = %v", err)
+	}
+
+	// Changes made through the server land in the server's library
+	got, data, err := local.Data(entry.ID)
+	if err != nil {
+		t.Fatalf("local Data() error = %v", err)
+	}
+	if got.Rating != 4 || !got.HasTag("acid") || len(data) != 4 {
+		t.Errorf("local entry = %+v, %d bytes", got, len(data))
+	}
+
+	entries, err := remote.List(library.Filter{Collection: "live set A", MinRating: 3})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != entry.ID {
+		t.Errorf("List() = %+v", entries)
+	}
+
+	// Earlier versions are read back by hash
+	if _, err := remote.Update(entry.ID, "seq", []byte{0x23}, "edit"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, _, data, err := remote.VersionData(entry.ID, entry.Hash[:8]); err != nil || len(data) != 4 {
+		t.Errorf("VersionData() = %v, %v", data, err)
+	}
+
+	if err := remote.Remove(entry.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := local.Get(entry.ID); err == nil {
+		t.Error("pattern still present after remote Remove()")
+	}
+	if err := remote.DeleteCollection("missing"); err == nil {
+		t.Error("DeleteCollection() of unknown collection expected error")
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// limitsKey holds the server's parse limits in the gin context
+const limitsKey = "synthtribe2midi.limits"
+
+// limitsMiddleware makes the parse limits available to the handlers
+func limitsMiddleware(l converter.Limits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(limitsKey, l)
+		c.Next()
+	}
+}
+
+// requestLimits returns the parse limits of the server handling c
+func requestLimits(c *gin.Context) converter.Limits {
+	if l, ok := c.Get(limitsKey); ok {
+		return l.(converter.Limits)
+	}
+	return converter.DefaultLimits()
+}
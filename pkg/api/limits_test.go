@@ -0,0 +1,60 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestParseLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(nil, Options{Limits: &converter.Limits{MaxSysExMessages: 3, MaxPatterns: 2}})
+
+	syx, err := devices.NewTD3().GenerateSyx(&converter.Pattern{Length: 16, Steps: []converter.Step{{Note: 45, Gate: true, Velocity: 100}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(path string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("file", "bank.syx")
+		_, _ = part.Write(data)
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, path, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		dumps int
+		want  int
+	}{
+		{"within limits", "/api/v1/convert/syx2midi", 1, http.StatusOK},
+		{"too many messages", "/api/v1/convert/syx2midi", 4, http.StatusUnprocessableEntity},
+		{"multi-target", "/api/v1/convert?to=seq", 4, http.StatusUnprocessableEntity},
+		{"bank within limits", "/api/v1/jobs/convert?to=midi", 2, http.StatusAccepted},
+		{"too many patterns", "/api/v1/jobs/convert?to=midi", 3, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(tt.path, bytes.Repeat(syx, tt.dumps))
+			if w.Code != tt.want {
+				t.Fatalf("POST %s with %d dumps = %d, want %d: %s", tt.path, tt.dumps, w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusUnprocessableEntity && !strings.Contains(w.Body.String(), "too many") {
+				t.Errorf("error = %s, want it to name the limit", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(nil, Options{})
+
+	// A client's ID is kept and returned with the error body
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/devices/tb303", nil)
+	req.Header.Set(RequestIDHeader, "trace-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var body struct {
+		Error     string `json:"error"`
+		RequestID string `json:"request_id"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if got := w.Header().Get(RequestIDHeader); got != "trace-42" || body.RequestID != "trace-42" || body.Error == "" {
+		t.Errorf("response header %q, body %+v; want request ID trace-42", got, body)
+	}
+
+	// Unusable IDs are replaced
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(RequestIDHeader, "has spaces\n")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got == "" || got == "has spaces\n" {
+		t.Errorf("request ID = %q, want a generated one", got)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	r := gin.New()
+	r.Use(requestIDMiddleware(), accessLogMiddleware(slog.New(slog.NewJSONHandler(&buf, nil))), recoveryMiddleware())
+	r.GET("/boom", func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set(RequestIDHeader, "trace-7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("access log %q: %v", buf.String(), err)
+	}
+	if entry["request_id"] != "trace-7" || entry["status"] != float64(500) || entry["level"] != "ERROR" || entry["path"] != "/boom" {
+		t.Errorf("access log = %v", entry)
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/httpapi"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// defaultsKey holds the server's default conversion options in the gin context
+const defaultsKey = "synthtribe2midi.defaults"
+
+// convertDefaults holds the default conversion options of a server, which
+// clients can change
+type convertDefaults struct {
+	mu   sync.RWMutex
+	opts converter.ConvertOptions
+	save func(converter.ConvertOptions) error
+}
+
+func (d *convertDefaults) get() converter.ConvertOptions {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.opts
+}
+
+// set stores new defaults, persisting them first if the server was given a
+// way to
+func (d *convertDefaults) set(opts converter.ConvertOptions) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.save != nil {
+		if err := d.save(opts); err != nil {
+			return err
+		}
+	}
+	d.opts = opts
+	return nil
+}
+
+// defaultsMiddleware makes the default conversion options available to the
+// handlers
+func defaultsMiddleware(d *convertDefaults) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(defaultsKey, d)
+		c.Next()
+	}
+}
+
+// requestDefaults returns the default conversion options of the server
+// handling c
+func requestDefaults(c *gin.Context) converter.ConvertOptions {
+	if d, ok := c.Get(defaultsKey); ok {
+		return d.(*convertDefaults).get()
+	}
+	return converter.DefaultOptions()
+}
+
+// param returns a request parameter from the query string or, failing
+// that, the multipart or urlencoded form
+func param(c *gin.Context, name string) (string, bool) {
+	return httpapi.Param(c.Request, name)
+}
+
+// conversionOptions reads the conversion options of a convert request from
+// its query string or form (see service.ParseOptions for the names); options
+// not given keep the server's defaults
+func conversionOptions(c *gin.Context) (converter.ConvertOptions, error) {
+	return service.ParseOptionsFrom(requestDefaults(c), func(name string) (string, bool) { return param(c, name) })
+}
+
+// handleGetOptions godoc
+// @Summary Default conversion options
+// @Description Returns the conversion options used for parameters a convert request leaves out
+// @Tags convert
+// @Produce json
+// @Success 200 {object} converter.ConvertOptions
+// @Router /api/v1/options [get]
+func handleGetOptions(c *gin.Context) {
+	c.JSON(http.StatusOK, requestDefaults(c))
+}
+
+// handlePutOptions godoc
+// @Summary Change the default conversion options
+// @Description Replaces the default conversion options; only served when the server allows it (serve --allow-options-write). Servers started from the CLI keep them in the user config file, shared with the CLI and TUI
+// @Tags convert
+// @Accept json
+// @Produce json
+// @Param options body converter.ConvertOptions true "New default options"
+// @Success 200 {object} converter.ConvertOptions
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/options [put]
+func handlePutOptions(c *gin.Context) {
+	opts := converter.DefaultOptions()
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid options: "+err.Error())
+		return
+	}
+	d, ok := c.Get(defaultsKey)
+	if !ok {
+		respondError(c, http.StatusInternalServerError, "default options are not configurable")
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := d.(*convertDefaults).set(opts); err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, opts)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defaults := converter.DefaultOptions()
+	defaults.Transpose = 12
+	var saved []converter.ConvertOptions
+	failSave := false
+	r := newRouter(nil, Options{
+		Defaults:          &defaults,
+		AllowOptionsWrite: true,
+		SaveDefaults: func(opts converter.ConvertOptions) error {
+			if failSave {
+				return errors.New("read-only config")
+			}
+			saved = append(saved, opts)
+			return nil
+		},
+	})
+
+	dev := devices.NewTD3()
+	seq, err := dev.GenerateSeq(&converter.Pattern{Length: 16, Steps: []converter.Step{{Note: 45, Gate: true, Velocity: 100}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// firstNote converts the pattern to SysEx and returns its first note
+	firstNote := func(query string) uint8 {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("file", "line.seq")
+		_, _ = part.Write(seq)
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/convert/seq2syx"+query, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("convert = %d: %s", w.Code, w.Body)
+		}
+		p, err := dev.ParseSyx(w.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Steps[0].Note
+	}
+	request := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/options", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if got := firstNote(""); got != 57 {
+		t.Errorf("note with default transpose = %d, want 57", got)
+	}
+	if got := firstNote("?transpose=-12"); got != 33 {
+		t.Errorf("note with transpose parameter = %d, want 33", got)
+	}
+
+	w := request(http.MethodGet, "")
+	var got converter.ConvertOptions
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Transpose != 12 {
+		t.Fatalf("GET options = %s, %v", w.Body, err)
+	}
+
+	if w := request(http.MethodPut, `{"transpose": 7, "gate_length": 0.5}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT options = %d: %s", w.Code, w.Body)
+	}
+	if len(saved) != 1 || saved[0].Transpose != 7 || saved[0].GateNote != converter.DefaultGateNote {
+		t.Errorf("saved %+v", saved)
+	}
+	if got := firstNote(""); got != 52 {
+		t.Errorf("note after PUT = %d, want 52", got)
+	}
+
+	if w := request(http.MethodPut, `{"channel": 17}`); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT out-of-range channel = %d, want 400", w.Code)
+	}
+	failSave = true
+	if w := request(http.MethodPut, `{"transpose": 1}`); w.Code != http.StatusInternalServerError {
+		t.Errorf("PUT with failing save = %d, want 500", w.Code)
+	}
+	if got := firstNote(""); got != 52 {
+		t.Errorf("note after failed PUT = %d, want 52 (unchanged)", got)
+	}
+}
+
+func TestOptionsReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	saved := false
+	r := newRouter(nil, Options{SaveDefaults: func(converter.ConvertOptions) error {
+		saved = true
+		return nil
+	}})
+
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		req := httptest.NewRequest(method, "/api/v1/options", strings.NewReader(`{"transpose": 7}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if method == http.MethodGet && w.Code != http.StatusOK {
+			t.Errorf("GET options = %d, want 200", w.Code)
+		}
+		if method == http.MethodPut && w.Code == http.StatusOK {
+			t.Errorf("PUT options without AllowOptionsWrite = %d, want it refused", w.Code)
+		}
+	}
+	if saved {
+		t.Error("defaults were saved without AllowOptionsWrite")
+	}
+}
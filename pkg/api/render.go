@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/httpapi"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// maxRenderScale caps the piano roll zoom factor
+const maxRenderScale = 8
+
+// handleRenderPNG godoc
+// @Summary Render a pattern as a PNG piano roll
+// @Description Upload a .seq, .syx, or MIDI file and receive a piano roll image (accents in red, slides as arrows)
+// @Tags render
+// @Accept multipart/form-data
+// @Produce image/png
+// @Param file formData file true "Pattern file to render"
+// @Param from query string false "Source format, overriding the file extension: seq, syx, midi, or 303"
+// @Param scale query int false "Zoom factor (1-8, default 1)"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/render/png [post]
+func handleRenderPNG(c *gin.Context) {
+	data, header, ok := readUpload(c)
+	if !ok {
+		return
+	}
+
+	from, ok := uploadFrom(c)
+	if !ok {
+		return
+	}
+	renderPNG(c, service.ConvertRequest{Data: data, Filename: header.Filename, From: from})
+}
+
+// getPatternImage godoc
+// @Summary Render a library pattern as a PNG piano roll
+// @Tags library
+// @Produce image/png
+// @Param id path string true "Pattern ID or unique prefix"
+// @Param scale query int false "Zoom factor (1-8, default 1)"
+// @Success 200 {file} binary
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/patterns/{id}/image [get]
+func (h *libraryHandlers) getPatternImage(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	entry, data, err := h.lib.Data(c.Param("id"))
+	if err != nil {
+		libraryError(c, err)
+		return
+	}
+	renderPNG(c, service.ConvertRequest{Data: data, From: converter.Format(entry.Format)})
+}
+
+// renderPNG parses pattern data and responds with its piano roll
+func renderPNG(c *gin.Context, req service.ConvertRequest) {
+	scale := 1
+	if s := c.Query("scale"); s != "" {
+		var err error
+		if scale, err = strconv.Atoi(s); err != nil || scale < 1 || scale > maxRenderScale {
+			respondError(c, http.StatusBadRequest, "scale must be between 1 and 8")
+			return
+		}
+	}
+
+	req.Limits = requestLimits(c)
+	resp, err := service.Convert(c.Request.Context(), req)
+	if err != nil {
+		serviceError(c, err, httpapi.FromHint(c.Request))
+		return
+	}
+	pattern := resp.Pattern
+
+	var buf bytes.Buffer
+	if err := render.PNG(&buf, pattern, render.PNGOptions{Scale: scale}); err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendData(c, "image/png", buf.Bytes())
+}
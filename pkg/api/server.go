@@ -3,12 +3,20 @@ package api
 
 import (
 	"fmt"
-	"io"
+	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
 	"github.com/james-see/synthtribe2midi/pkg/converter"
 	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/httpapi"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/service"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -19,49 +27,156 @@ import (
 // @host localhost:8080
 // @BasePath /api/v1
 
-// StartServer starts the API server on the specified port
-func StartServer(port int) error {
-	r := gin.Default()
+// Options configures the API server
+type Options struct {
+	// CORS is the cross-origin policy; unset fields come from the
+	// environment or the defaults
+	CORS CORSConfig
+	// Checks are readiness checks run by /readyz in addition to the
+	// library storage check
+	Checks []Check
+	// Limits caps the work spent parsing uploads; nil means
+	// converter.DefaultLimits
+	Limits *converter.Limits
+	// Library serves the pattern library endpoints; nil disables them
+	// (they respond 503)
+	Library *library.Library
+	// Defaults are the conversion options for parameters a request leaves
+	// out; nil means converter.DefaultOptions
+	Defaults *converter.ConvertOptions
+	// AllowOptionsWrite registers PUT /api/v1/options, which lets any
+	// client change the defaults for every other client; off by default,
+	// so only GET is served
+	AllowOptionsWrite bool
+	// SaveDefaults, if set, persists defaults changed through
+	// PUT /api/v1/options, e.g. to the user config file
+	SaveDefaults func(converter.ConvertOptions) error
+}
+
+// StartServer starts the API server on the specified port, with the
+// default pattern library unless opts.Library is set
+func StartServer(port int, opts Options) error {
+	if err := opts.CORS.WithDefaults().Validate(); err != nil {
+		return err
+	}
+	lib := opts.Library
+	if lib == nil {
+		lib = OpenLibrary("")
+	}
+	return newRouter(lib, opts).Run(fmt.Sprintf(":%d", port))
+}
+
+// Handler returns the API as an http.Handler, for embedding in a larger
+// process such as the daemon. A nil library disables the library endpoints.
+func Handler(lib *library.Library, opts Options) http.Handler {
+	return newRouter(lib, opts)
+}
+
+// newRouter builds the standalone server: the API routes behind request
+// IDs, access logging, panic recovery, compression, and CORS. A nil library
+// disables the library endpoints.
+func newRouter(lib *library.Library, opts Options) *gin.Engine {
+	r := gin.New()
+	r.Use(requestIDMiddleware())
+	r.Use(accessLogMiddleware(slog.New(slog.NewJSONHandler(os.Stderr, nil))))
+	r.Use(recoveryMiddleware())
+	r.Use(compressMiddleware())
 	
 	// CORS middleware
-	r.Use(corsMiddleware())
+	r.Use(corsMiddleware(opts.CORS))
+	
+	opts.Library = lib
+	RegisterRoutes(r, opts)
+	return r
+}
+
+// RegisterRoutes mounts the API endpoints on r, so other gin applications
+// can serve them inside their own servers, e.g. under r.Group("/synth").
+// Only the parse limits are applied; request IDs, logging, recovery,
+// compression, and CORS are left to the host's middleware (opts.CORS is
+// ignored).
+func RegisterRoutes(r gin.IRouter, opts Options) {
+	// Uploads are untrusted: parse them within limits
+	limits := converter.DefaultLimits()
+	if opts.Limits != nil {
+		limits = *opts.Limits
+	}
+	defaults := &convertDefaults{opts: converter.DefaultOptions(), save: opts.SaveDefaults}
+	if opts.Defaults != nil {
+		defaults.opts = *opts.Defaults
+	}
+	g := r.Group("")
+	g.Use(limitsMiddleware(limits))
+	g.Use(defaultsMiddleware(defaults))
+	lib := opts.Library
 	
-	// Health check
-	r.GET("/health", healthCheck)
+	// Health check; /livez and /readyz are the liveness and readiness
+	// probes for orchestrators
+	ready := readyzHandler(append([]Check{libraryCheck(lib)}, opts.Checks...))
+	g.GET("/health", healthCheck)
+	g.GET("/livez", handleLivez)
+	g.GET("/readyz", ready)
 	
 	// API v1 routes
-	v1 := r.Group("/api/v1")
+	v1 := g.Group("/api/v1")
 	{
 		v1.GET("/health", healthCheck)
+		v1.GET("/livez", handleLivez)
+		v1.GET("/readyz", ready)
+		v1.GET("/options", handleGetOptions)
+		if opts.AllowOptionsWrite {
+			v1.PUT("/options", handlePutOptions)
+		}
+		v1.POST("/convert", handleConvertMulti)
 		v1.POST("/convert/midi2seq", handleMIDIToSeq)
 		v1.POST("/convert/seq2midi", handleSeqToMIDI)
 		v1.POST("/convert/midi2syx", handleMIDIToSyx)
 		v1.POST("/convert/syx2midi", handleSyxToMIDI)
 		v1.POST("/convert/seq2syx", handleSeqToSyx)
 		v1.POST("/convert/syx2seq", handleSyxToSeq)
+		v1.POST("/render/png", handleRenderPNG)
+		v1.POST("/share", handleShareEncode)
+		v1.GET("/share/:share", handleShareDecode)
+		v1.GET("/share/:share/qr", handleShareQR)
+		v1.GET("/version", handleVersion)
 		v1.GET("/formats", listFormats)
 		v1.GET("/devices", listDevices)
+		v1.GET("/devices/:id", getDevice)
 	}
 	
-	// Swagger docs
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Pattern library
+	registerLibraryRoutes(v1, lib)
 	
-	return r.Run(fmt.Sprintf(":%d", port))
+	// Background jobs (bank conversions, device backups)
+	registerJobRoutes(v1, newJobStore())
+	
+	// Swagger docs
+	g.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
 
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+// OpenLibrary opens the pattern library in dir (the default location if
+// empty) with automatic analysis enabled, or returns nil (library endpoints
+// then respond 503) if it cannot be opened
+func OpenLibrary(dir string) *library.Library {
+	var err error
+	if dir == "" {
+		dir, err = library.DefaultDir()
+	}
+	if err == nil {
+		var lib *library.Library
+		if lib, err = library.Open(dir); err == nil {
+			lib.SetAnalyzer(func(format, device string, data []byte) (*analysis.Result, error) {
+				dev, ok := devices.Shared(device)
+				if !ok {
+					dev, _ = devices.Shared(devices.DefaultID)
+				}
+				return analysis.AnalyzeData(dev, converter.Format(format), data)
+			})
+			return lib
 		}
-		
-		c.Next()
 	}
+	log.Printf("pattern library disabled: %v", err)
+	return nil
 }
 
 // healthCheck godoc
@@ -94,17 +209,35 @@ func listFormats(c *gin.Context) {
 
 // listDevices godoc
 // @Summary List supported devices
-// @Description Returns a list of supported Behringer devices
+// @Description Returns every registered device with its capabilities (steps, note range, memory slots), features, and supported formats
 // @Tags info
 // @Produce json
-// @Success 200 {object} map[string][]map[string]string
+// @Success 200 {object} map[string][]devices.Details
 // @Router /api/v1/devices [get]
 func listDevices(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"devices": []map[string]string{
-			{"id": "td3", "name": "Behringer TD-3", "description": "TB-303 clone"},
-		},
-	})
+	list := []devices.Details{}
+	for _, info := range devices.List() {
+		list = append(list, info.Details())
+	}
+	c.JSON(http.StatusOK, gin.H{"devices": list})
+}
+
+// getDevice godoc
+// @Summary Get a device
+// @Description Returns a device's capabilities, features, and supported formats by ID or alias
+// @Tags info
+// @Produce json
+// @Param id path string true "Device ID or alias, e.g. td3"
+// @Success 200 {object} devices.Details
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/devices/{id} [get]
+func getDevice(c *gin.Context) {
+	info, ok := devices.LookupInfo(c.Param("id"))
+	if !ok {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("unknown device %q", c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, info.Details())
 }
 
 // handleMIDIToSeq godoc
@@ -115,8 +248,17 @@ func listDevices(c *gin.Context) {
 // @Produce application/octet-stream
 // @Param file formance file true "MIDI file to convert"
 // @Param device query string false "Target device (default: td3)"
+// @Param channel query int false "Only read notes on this MIDI channel (1-16)"
+// @Param accent_threshold query int false "Lowest velocity read as an accent (default 101)"
+// @Param ghost_threshold query int false "Velocity below which drum hits are ghost notes (default 60)"
+// @Param slide_mode query string false "How slides are read: interval (default), legato, or none"
+// @Param bar query int false "Read only this bar (1-based) of multi-bar MIDI"
+// @Param downmix query string false "Fit MIDI clips longer than the device's patterns: fold, densest, thin, or halve"
+// @Param transpose query int false "Semitones to transpose the output by (-127 to 127)"
+// @Param strict query bool false "Fail with the warnings instead of fixing the pattern up"
 // @Success 200 {file} binary
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /api/v1/convert/midi2seq [post]
 func handleMIDIToSeq(c *gin.Context) {
 	handleConversion(c, "midi", "seq")
@@ -130,8 +272,15 @@ func handleMIDIToSeq(c *gin.Context) {
 // @Produce application/octet-stream
 // @Param file formance file true ".seq file to convert"
 // @Param device query string false "Source device (default: td3)"
+// @Param channel query int false "MIDI channel of the notes (1-16, default 1)"
+// @Param gate_length query number false "Fraction of a step plain notes sound (default 0.75)"
+// @Param gate_track query bool false "Add a fixed-pitch gate track"
+// @Param accent_track query bool false "Add a fixed-pitch accent track"
+// @Param transpose query int false "Semitones to transpose the output by (-127 to 127)"
+// @Param strict query bool false "Fail with the warnings instead of fixing the pattern up"
 // @Success 200 {file} binary
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /api/v1/convert/seq2midi [post]
 func handleSeqToMIDI(c *gin.Context) {
 	handleConversion(c, "seq", "midi")
@@ -145,8 +294,18 @@ func handleSeqToMIDI(c *gin.Context) {
 // @Produce application/octet-stream
 // @Param file formance file true "MIDI file to convert"
 // @Param device query string false "Target device (default: td3)"
+// @Param device_id query int false "SysEx device ID for the output (0-127)"
+// @Param channel query int false "Only read notes on this MIDI channel (1-16)"
+// @Param accent_threshold query int false "Lowest velocity read as an accent (default 101)"
+// @Param ghost_threshold query int false "Velocity below which drum hits are ghost notes (default 60)"
+// @Param slide_mode query string false "How slides are read: interval (default), legato, or none"
+// @Param bar query int false "Read only this bar (1-based) of multi-bar MIDI"
+// @Param downmix query string false "Fit MIDI clips longer than the device's patterns: fold, densest, thin, or halve"
+// @Param transpose query int false "Semitones to transpose the output by (-127 to 127)"
+// @Param strict query bool false "Fail with the warnings instead of fixing the pattern up"
 // @Success 200 {file} binary
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /api/v1/convert/midi2syx [post]
 func handleMIDIToSyx(c *gin.Context) {
 	handleConversion(c, "midi", "syx")
@@ -160,8 +319,15 @@ func handleMIDIToSyx(c *gin.Context) {
 // @Produce application/octet-stream
 // @Param file formance file true ".syx file to convert"
 // @Param device query string false "Source device (default: td3)"
+// @Param channel query int false "MIDI channel of the notes (1-16, default 1)"
+// @Param gate_length query number false "Fraction of a step plain notes sound (default 0.75)"
+// @Param gate_track query bool false "Add a fixed-pitch gate track"
+// @Param accent_track query bool false "Add a fixed-pitch accent track"
+// @Param transpose query int false "Semitones to transpose the output by (-127 to 127)"
+// @Param strict query bool false "Fail with the warnings instead of fixing the pattern up"
 // @Success 200 {file} binary
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /api/v1/convert/syx2midi [post]
 func handleSyxToMIDI(c *gin.Context) {
 	handleConversion(c, "syx", "midi")
@@ -175,8 +341,12 @@ func handleSyxToMIDI(c *gin.Context) {
 // @Produce application/octet-stream
 // @Param file formance file true ".seq file to convert"
 // @Param device query string false "Device (default: td3)"
+// @Param device_id query int false "SysEx device ID for the output (0-127)"
+// @Param transpose query int false "Semitones to transpose the output by (-127 to 127)"
+// @Param strict query bool false "Fail with the warnings instead of fixing the pattern up"
 // @Success 200 {file} binary
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /api/v1/convert/seq2syx [post]
 func handleSeqToSyx(c *gin.Context) {
 	handleConversion(c, "seq", "syx")
@@ -190,8 +360,11 @@ func handleSeqToSyx(c *gin.Context) {
 // @Produce application/octet-stream
 // @Param file formance file true ".syx file to convert"
 // @Param device query string false "Device (default: td3)"
+// @Param transpose query int false "Semitones to transpose the output by (-127 to 127)"
+// @Param strict query bool false "Fail with the warnings instead of fixing the pattern up"
 // @Success 200 {file} binary
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Router /api/v1/convert/syx2seq [post]
 func handleSyxToSeq(c *gin.Context) {
 	handleConversion(c, "syx", "seq")
@@ -199,71 +372,37 @@ func handleSyxToSeq(c *gin.Context) {
 
 func handleConversion(c *gin.Context, fromFormat, toFormat string) {
 	// Get uploaded file
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+	data, header, ok := readUpload(c)
+	if !ok {
 		return
 	}
-	defer func() { _ = file.Close() }()
 	
-	// Read file content
-	data, err := io.ReadAll(file)
+	// Conversion options (device ID, transpose, channel, ...)
+	opts, err := conversionOptions(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
-		return
-	}
-	
-	// Get device (default to TD-3)
-	deviceName := c.DefaultQuery("device", "td3")
-	var device converter.Device
-	switch deviceName {
-	case "td3", "td-3":
-		device = devices.NewTD3()
-	default:
-		device = devices.NewTD3()
-	}
-	
-	conv := converter.New(device)
-	
-	// Perform conversion
-	var result []byte
-	var outputExt string
-	
-	switch fromFormat + "2" + toFormat {
-	case "midi2seq":
-		result, err = conv.MIDIToSeq(data)
-		outputExt = ".seq"
-	case "seq2midi":
-		result, err = conv.SeqToMIDI(data)
-		outputExt = ".mid"
-	case "midi2syx":
-		result, err = conv.MIDIToSyx(data)
-		outputExt = ".syx"
-	case "syx2midi":
-		result, err = conv.SyxToMIDI(data)
-		outputExt = ".mid"
-	case "seq2syx":
-		result, err = conv.SeqToSyx(data)
-		outputExt = ".syx"
-	case "syx2seq":
-		result, err = conv.SyxToSeq(data)
-		outputExt = ".seq"
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported conversion"})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	
+	// Perform conversion; files of another format are rejected before the
+	// parser trips over them
+	resp, err := service.Convert(c.Request.Context(), service.ConvertRequest{
+		Data:     data,
+		Filename: header.Filename,
+		From:     converter.Format(fromFormat),
+		To:       []converter.Format{converter.Format(toFormat)},
+		Device:   c.Query("device"),
+		Options:  opts,
+		Limits:   requestLimits(c),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serviceError(c, err, httpapi.RouteHint(toFormat))
 		return
 	}
 	
-	// Generate output filename
-	outputName := header.Filename
-	if len(outputName) > 4 {
-		outputName = outputName[:len(outputName)-4] + outputExt
-	} else {
-		outputName = "converted" + outputExt
+	// Report the SysEx device ID of .syx input
+	if resp.InputDeviceID != nil {
+		c.Header("X-SysEx-Device-ID", strconv.Itoa(int(*resp.InputDeviceID)))
 	}
 	
 	// Set content type and headers
@@ -275,7 +414,13 @@ func handleConversion(c *gin.Context, fromFormat, toFormat string) {
 		contentType = "application/octet-stream"
 	}
 	
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", outputName))
-	c.Data(http.StatusOK, contentType, result)
+	// Report normalization warnings alongside the converted file
+	if warnings := resp.WarningStrings(); len(warnings) > 0 {
+		c.Header("X-Conversion-Warnings", strings.Join(warnings, "; "))
+	}
+	
+	out := resp.Outputs[0]
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", out.Name))
+	sendData(c, contentType, out.Data)
 }
 
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+)
+
+func TestDevicesEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(nil, Options{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil))
+	var list struct{ Devices []devices.Details }
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /devices = %d, %v", w.Code, err)
+	}
+	if len(list.Devices) != len(devices.List()) {
+		t.Errorf("GET /devices listed %d devices, want %d", len(list.Devices), len(devices.List()))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/devices/TD-3", nil))
+	var td3 devices.Details
+	if err := json.Unmarshal(w.Body.Bytes(), &td3); err != nil || td3.ID != "td3" || td3.MaxSteps == 0 || len(td3.Formats) == 0 {
+		t.Errorf("GET /devices/TD-3 = %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/devices/tb303", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /devices/tb303 = %d, want 404", w.Code)
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetBuildInfo("1.2.3", "abc123", "2026-01-02")
+	defer SetBuildInfo("dev", "none", "unknown")
+
+	w := httptest.NewRecorder()
+	newRouter(nil, Options{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/version", nil))
+	var info VersionInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /version = %d, %v", w.Code, err)
+	}
+	if info.Version != "1.2.3" || info.Commit != "abc123" || info.Date != "2026-01-02" {
+		t.Errorf("build info = %+v", info)
+	}
+	if len(info.Formats) == 0 || len(info.Devices) != len(devices.IDs()) || info.GoVersion == "" {
+		t.Errorf("version info = %+v", info)
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	failing := Check{Name: "midi", Run: func(context.Context) error { return errors.New("no MIDI backend") }}
+	slow := Check{Name: "slow", Run: func(context.Context) error { time.Sleep(time.Second); return nil }}
+	lib, err := library.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		lib        *library.Library
+		checks     []Check
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{"no library", nil, nil, http.StatusOK, map[string]string{"library": "disabled"}},
+		{"library", lib, nil, http.StatusOK, map[string]string{"library": "ok"}},
+		{"failing check", nil, []Check{failing}, http.StatusServiceUnavailable, map[string]string{"library": "disabled", "midi": "failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRouter(tt.lib, Options{Checks: tt.checks})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("GET /livez = %d, want 200", w.Code)
+			}
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			var ready Readiness
+			if err := json.Unmarshal(w.Body.Bytes(), &ready); err != nil || w.Code != tt.wantStatus {
+				t.Fatalf("GET /readyz = %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			got := map[string]string{}
+			for _, c := range ready.Checks {
+				got[c.Name] = c.Status
+			}
+			if !maps.Equal(got, tt.wantChecks) {
+				t.Errorf("checks = %v, want %v", got, tt.wantChecks)
+			}
+		})
+	}
+
+	// Checks that hang are cut off rather than holding the probe open
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ready := runChecks(ctx, []Check{slow})
+	if ready.Status != "unready" || ready.Checks[0].Error == "" {
+		t.Errorf("timed-out check = %+v", ready)
+	}
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	host := r.Group("/synth", func(c *gin.Context) {
+		c.Header("X-Host", "yes")
+		c.Next()
+	})
+	RegisterRoutes(host, Options{Limits: &converter.Limits{MaxSysExMessages: 1}})
+	r.GET("/other", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/synth/api/v1/version", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Host") != "yes" {
+		t.Errorf("GET /synth/api/v1/version = %d (X-Host %q), want 200 through the host middleware", w.Code, w.Header().Get("X-Host"))
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/synth/api/v1/patterns", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /synth/api/v1/patterns without a library = %d, want 503", w.Code)
+	}
+
+	// The limits apply to the mounted routes
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "bank.syx")
+	_, _ = part.Write([]byte{0xF0, 0x00, 0xF7, 0xF0, 0x00, 0xF7})
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/synth/api/v1/convert/syx2midi", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("POST beyond the limits = %d, want 422", w.Code)
+	}
+
+	// The host's own routes are left alone
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Host") != "" {
+		t.Errorf("GET /other = %d (X-Host %q)", w.Code, w.Header().Get("X-Host"))
+	}
+}
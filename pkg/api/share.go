@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/httpapi"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// handleShareEncode godoc
+// @Summary Encode a pattern as a share string
+// @Description Upload a .seq, .syx, or MIDI file and receive its compact URL-safe share string
+// @Tags share
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Pattern file to encode"
+// @Param from query string false "Source format, overriding the file extension: seq, syx, midi, or 303"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/share [post]
+func handleShareEncode(c *gin.Context) {
+	data, header, ok := readUpload(c)
+	if !ok {
+		return
+	}
+
+	from, ok := uploadFrom(c)
+	if !ok {
+		return
+	}
+	resp, err := service.Convert(c.Request.Context(), service.ConvertRequest{
+		Data:     data,
+		Filename: header.Filename,
+		From:     from,
+		Limits:   requestLimits(c),
+	})
+	if err != nil {
+		serviceError(c, err, httpapi.FromHint(c.Request))
+		return
+	}
+	share, err := converter.EncodeShareString(resp.Pattern)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"share": share})
+}
+
+// handleShareDecode godoc
+// @Summary Download a pattern from its share string
+// @Description Decode a share string into a .seq, .syx, or MIDI file, so share links can point straight at the API
+// @Tags share
+// @Produce application/octet-stream
+// @Param share path string true "Share string"
+// @Param format query string false "Output format: seq (default), syx, or midi"
+// @Param device query string false "Target device (default: td3)"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/share/{share} [get]
+func handleShareDecode(c *gin.Context) {
+	pattern, err := converter.DecodeShareString(c.Param("share"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var format converter.Format
+	var ext, contentType string
+	switch c.DefaultQuery("format", "seq") {
+	case "seq":
+		format, ext, contentType = converter.FormatSeq, ".seq", "application/octet-stream"
+	case "syx":
+		format, ext, contentType = converter.FormatSyx, ".syx", "application/octet-stream"
+	case "midi", "mid":
+		format, ext, contentType = converter.FormatMIDI, ".mid", "audio/midi"
+	default:
+		respondError(c, http.StatusBadRequest, "format must be seq, syx, or midi")
+		return
+	}
+
+	device, ok := devices.Shared(c.DefaultQuery("device", devices.DefaultID))
+	if !ok {
+		respondError(c, http.StatusBadRequest, devices.Validate(c.Query("device")).Error())
+		return
+	}
+	data, err := converter.New(device).GeneratePattern(pattern, format)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=pattern%s", ext))
+	sendData(c, contentType, data)
+}
+
+// handleShareQR godoc
+// @Summary Render a share string as a QR code
+// @Description Returns a PNG QR code of a share string for printing on liner notes or flyers
+// @Tags share
+// @Produce image/png
+// @Param share path string true "Share string"
+// @Param size query int false "Image size in pixels (64-2048, default 256)"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/share/{share}/qr [get]
+func handleShareQR(c *gin.Context) {
+	share := c.Param("share")
+	if _, err := converter.DecodeShareString(share); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	size := render.DefaultQRSize
+	if s := c.Query("size"); s != "" {
+		var err error
+		if size, err = strconv.Atoi(s); err != nil || size < 64 || size > 2048 {
+			respondError(c, http.StatusBadRequest, "size must be between 64 and 2048")
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := render.QR(&buf, share, size); err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendData(c, "image/png", buf.Bytes())
+}
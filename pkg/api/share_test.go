@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+)
+
+func TestShareEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := httptest.NewServer(newRouter(nil, Options{}))
+	defer srv.Close()
+
+	td3 := devices.NewTD3()
+	seq, err := td3.GenerateSeq(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 45, Gate: true, Velocity: 127, Accent: true}, {Note: 48, Gate: true, Velocity: 100, Slide: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, _ := w.CreateFormFile("file", "line.seq")
+	_, _ = part.Write(seq)
+	_ = w.Close()
+	resp, err := http.Post(srv.URL+"/api/v1/share", w.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded struct{ Share string }
+	_ = json.NewDecoder(resp.Body).Decode(&encoded)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /share status = %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/share/" + encoded.Share + "?format=seq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /share status = %d: %s", resp.StatusCode, data)
+	}
+	a, _ := td3.ParseSeq(seq)
+	b, err := td3.ParseSeq(data)
+	if err != nil {
+		t.Fatalf("decoded .seq: %v", err)
+	}
+	if diffs := converter.Diff(a, b); len(diffs) != 0 {
+		t.Errorf("shared pattern differs: %v", diffs)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/share/" + encoded.Share + "/qr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanned, err := render.ReadQR(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || scanned != encoded.Share {
+		t.Errorf("QR code reads %q, %v; want %q", scanned, err, encoded.Share)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/v1/share/st1.garbage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET /share with a bad string status = %d, want 400", resp.StatusCode)
+	}
+}
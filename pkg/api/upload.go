@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/httpapi"
+)
+
+// readUpload reads the pattern file sent in the "file" field of a multipart
+// request or JSON body, responding 400 with a hint at what went wrong if
+// there is none
+func readUpload(c *gin.Context) ([]byte, *multipart.FileHeader, bool) {
+	data, header, err := httpapi.ReadUpload(c.Request)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+	return data, header, true
+}
+
+// uploadFrom returns the from parameter naming the format of an upload, or
+// "" to detect it from the file extension, then the content
+func uploadFrom(c *gin.Context) (converter.Format, bool) {
+	from, _ := param(c, "from")
+	format, err := httpapi.ParseFrom(from)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return format, false
+	}
+	return format, true
+}
+
+// serviceError responds with a conversion error (see httpapi.ServiceError),
+// with where parsing stopped as parse_error
+func serviceError(c *gin.Context, err error, hint func(actual converter.Format) string) {
+	status, msg := httpapi.ServiceError(err, hint)
+	var perr *converter.ParseError
+	if errors.As(err, &perr) {
+		c.AbortWithStatusJSON(status, gin.H{"error": msg, "parse_error": perr, "request_id": RequestID(c.Request.Context())})
+		return
+	}
+	respondError(c, status, msg)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestUploadValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(nil, Options{})
+
+	td3 := devices.NewTD3()
+	pattern := &converter.Pattern{Length: 16, Steps: []converter.Step{{Note: 45, Gate: true, Velocity: 100}}}
+	seq, err := td3.GenerateSeq(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mid, err := converter.New(td3).GeneratePattern(pattern, converter.FormatMIDI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		field    string
+		filename string
+		data     []byte
+		want     int
+		wantMsg  string
+	}{
+		{"matching", "/api/v1/convert/seq2midi", "file", "line.seq", seq, http.StatusOK, ""},
+		{"MIDI to seq2midi", "/api/v1/convert/seq2midi", "file", "line.mid", mid, http.StatusUnprocessableEntity, "this looks like a MIDI file, not a .seq file"},
+		{"MIDI to syx2seq", "/api/v1/convert/syx2seq", "file", "line.syx", mid, http.StatusUnprocessableEntity, "use /api/v1/convert/midi2seq"},
+		{"text to seq2syx", "/api/v1/convert/seq2syx", "file", "line.txt", []byte("A2 C3 D3"), http.StatusUnprocessableEntity, "this looks like a text pattern, not a .seq file; use /api/v1/convert?from=303&to=syx"},
+		{"binary to midi2seq", "/api/v1/convert/midi2seq", "file", "line.mid", seq, http.StatusUnprocessableEntity, "this is not a MIDI file: it has no MThd header"},
+		{"empty", "/api/v1/convert/syx2midi", "file", "line.syx", nil, http.StatusUnprocessableEntity, "the uploaded file is empty"},
+		{"wrong field", "/api/v1/convert/seq2midi", "upload", "line.seq", seq, http.StatusBadRequest, `no "file" field; the upload is in "upload"`},
+		{"misnamed", "/api/v1/convert?to=seq", "file", "line.syx", mid, http.StatusUnprocessableEntity, "this looks like a MIDI file, not a SysEx dump; use /api/v1/convert?from=midi&to=seq"},
+		{"from overrides extension", "/api/v1/convert?to=seq&from=midi", "file", "line.syx", mid, http.StatusOK, ""},
+		{"unknown from", "/api/v1/convert?to=seq&from=wav", "file", "line.mid", mid, http.StatusBadRequest, "unknown from format"},
+		{"share", "/api/v1/share", "file", "line.seq", mid, http.StatusUnprocessableEntity, "use /api/v1/share?from=midi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			part, _ := mw.CreateFormFile(tt.field, tt.filename)
+			_, _ = part.Write(tt.data)
+			_ = mw.Close()
+			req := httptest.NewRequest(http.MethodPost, tt.path, &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("POST %s = %d, want %d: %s", tt.path, w.Code, tt.want, w.Body)
+			}
+			if tt.wantMsg == "" {
+				return
+			}
+			var resp map[string]string
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			if !strings.Contains(resp["error"], tt.wantMsg) {
+				t.Errorf("error = %q, want it to contain %q", resp["error"], tt.wantMsg)
+			}
+		})
+	}
+
+	// A request that is not multipart says how to send the file
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/convert/seq2midi", bytes.NewReader(seq))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "multipart/form-data") {
+		t.Errorf("raw upload = %d %s, want 400 asking for multipart/form-data", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+// VersionInfo describes the running server build
+type VersionInfo struct {
+	Version     string   `json:"version"`
+	Commit      string   `json:"commit"`
+	Date        string   `json:"date"`
+	GoVersion   string   `json:"go_version"`
+	Formats     []string `json:"formats"`
+	Conversions []string `json:"conversions"`
+	Devices     []string `json:"devices"`
+}
+
+var buildInfo = VersionInfo{Version: "dev", Commit: "none", Date: "unknown"}
+
+// SetBuildInfo sets the version, commit, and build date reported by the
+// version endpoint; main packages pass the values set by the release build
+func SetBuildInfo(version, commit, date string) {
+	buildInfo.Version, buildInfo.Commit, buildInfo.Date = version, commit, date
+}
+
+// handleVersion godoc
+// @Summary Version and build info
+// @Description Returns the server version, commit, and build date with the supported formats and registered devices, so clients can check compatibility
+// @Tags info
+// @Produce json
+// @Success 200 {object} VersionInfo
+// @Router /api/v1/version [get]
+func handleVersion(c *gin.Context) {
+	info := buildInfo
+	info.GoVersion = runtime.Version()
+	info.Formats = []string{"midi", "seq", "syx"}
+	info.Conversions = converter.GetSupportedConversions()
+	info.Devices = devices.IDs()
+
+	// Builds without release metadata (go install, go run) still know
+	// their commit from the module's VCS stamp
+	if bi, ok := debug.ReadBuildInfo(); ok && info.Commit == "none" {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = s.Value
+			case "vcs.time":
+				if info.Date == "unknown" {
+					info.Date = s.Value
+				}
+			}
+		}
+		if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+	}
+	c.JSON(http.StatusOK, info)
+}
@@ -0,0 +1,153 @@
+// Package backup pulls full pattern banks from hardware into timestamped
+// bank files and prunes old backups by retention policy
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/transfer"
+)
+
+// DefaultTimeout is how long to wait for each pattern dump
+const DefaultTimeout = transfer.DefaultTimeout
+
+// timeFormat is the timestamp embedded in bank file names
+const timeFormat = "20060102-150405"
+
+// Options controls a backup: the device ID, timeout, retries, and whether
+// each slot is read twice to verify it
+type Options = transfer.Options
+
+// Retention limits how many backups are kept; zero values disable a limit
+type Retention struct {
+	Keep   int           // Keep at most this many newest backups
+	MaxAge time.Duration // Delete backups older than this
+}
+
+// Pull requests every pattern slot of the device and returns the dumps in
+// slot order. Lost or damaged dumps are re-requested as opts allows.
+func Pull(out mididevice.Out, in mididevice.In, dev converter.PatternRequester, opts Options) ([][]byte, error) {
+	bank := make([][]byte, 0, dev.Slots())
+	for slot := 0; slot < dev.Slots(); slot++ {
+		dump, err := transfer.Pull(out, in, dev, slot, opts)
+		if err != nil {
+			return nil, err
+		}
+		bank = append(bank, dump)
+	}
+	return bank, nil
+}
+
+// WriteBank writes the dumps to a timestamped bank file
+// (<prefix>-YYYYMMDD-HHMMSS.syx) in dir and returns its path
+func WriteBank(dir, prefix string, bank [][]byte, at time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	var data []byte
+	for _, msg := range bank {
+		data = append(data, msg...)
+	}
+
+	path := filepath.Join(dir, prefix+"-"+at.Format(timeFormat)+".syx")
+	if err := converter.WriteFileAtomic(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return path, nil
+}
+
+// Prune deletes backups with the given prefix in dir that fall outside the
+// retention policy and returns the deleted paths. Files are dated by the
+// timestamp in their name, not their modification time.
+func Prune(dir, prefix string, r Retention, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type backupFile struct {
+		path string
+		at   time.Time
+	}
+	var files []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix+"-"), ".syx")
+		if e.IsDir() || stamp == name {
+			continue
+		}
+		at, err := time.ParseInLocation(timeFormat, stamp, now.Location())
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{filepath.Join(dir, name), at})
+	}
+
+	// Newest first
+	sort.Slice(files, func(i, j int) bool { return files[i].at.After(files[j].at) })
+
+	var deleted []string
+	for i, f := range files {
+		tooMany := r.Keep > 0 && i >= r.Keep
+		tooOld := r.MaxAge > 0 && now.Sub(f.at) > r.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, f.path)
+	}
+	return deleted, nil
+}
+
+// Job is a complete backup of one device: pull, write, and prune
+type Job struct {
+	Port      string
+	Dir       string
+	Prefix    string // Bank file name prefix (default "backup")
+	Device    converter.PatternRequester
+	Options   Options
+	Retention Retention
+}
+
+// Run performs the backup and returns the written bank file path
+func (j *Job) Run(now time.Time) (string, error) {
+	out, err := mididevice.OpenOutput(j.Port)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = out.Close() }()
+
+	in, err := mididevice.OpenInput(j.Port)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = in.Close() }()
+
+	bank, err := Pull(out, in, j.Device, j.Options)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := j.Prefix
+	if prefix == "" {
+		prefix = "backup"
+	}
+	path, err := WriteBank(j.Dir, prefix, bank, now)
+	if err != nil {
+		return "", err
+	}
+	if _, err := Prune(j.Dir, prefix, j.Retention, now); err != nil {
+		return path, fmt.Errorf("backup written but pruning failed: %w", err)
+	}
+	return path, nil
+}
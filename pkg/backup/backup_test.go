@@ -0,0 +1,101 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// fakeTD3 answers pattern requests addressed to its device ID with a
+// slot-addressed dump
+type fakeTD3 struct {
+	id     uint8
+	listen func([]byte)
+}
+
+func (f *fakeTD3) Name() string { return "fake TD-3" }
+func (f *fakeTD3) Close() error { return nil }
+func (f *fakeTD3) Listen(onMsg func([]byte)) (func(), error) {
+	f.listen = onMsg
+	return func() { f.listen = nil }, nil
+}
+func (f *fakeTD3) Send(req []byte) error {
+	if req[4] != f.id || req[6] != devices.PatternRequest {
+		return nil
+	}
+	slot := int(req[7])
+	dump, err := devices.NewTD3().GenerateSyxSlot(&converter.Pattern{
+		DeviceID: f.id,
+		Steps:    []converter.Step{{Note: uint8(36 + slot%24), Gate: true, Velocity: 100}},
+	}, slot)
+	if err != nil {
+		return err
+	}
+	f.listen(dump)
+	return nil
+}
+
+func TestPull(t *testing.T) {
+	td3 := devices.NewTD3()
+	dev := &fakeTD3{id: 2}
+
+	bank, err := Pull(dev, dev, td3, Options{DeviceID: 2})
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	if len(bank) != devices.MaxPatterns {
+		t.Fatalf("Pull() returned %d dumps, want %d", len(bank), devices.MaxPatterns)
+	}
+
+	// The bank file splits back into the individual dumps
+	path, err := WriteBank(t.TempDir(), "td3", bank, time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("WriteBank() error = %v", err)
+	}
+	if filepath.Base(path) != "td3-20261015-030000.syx" {
+		t.Errorf("WriteBank() path = %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	msgs, err := sysex.Split(data)
+	if err != nil || len(msgs) != devices.MaxPatterns {
+		t.Fatalf("Split() = %d messages, %v", len(msgs), err)
+	}
+	p, err := td3.ParseSyx(msgs[63])
+	if err != nil || p.Steps[0].Note != 36+63%24 {
+		t.Errorf("slot 63 = %+v, %v", p, err)
+	}
+
+	// A unit with another device ID does not answer
+	if _, err := Pull(dev, dev, td3, Options{DeviceID: 5, Timeout: 10 * time.Millisecond}); err == nil {
+		t.Error("Pull() from wrong device ID expected timeout")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for days := 0; days < 5; days++ {
+		if _, err := WriteBank(dir, "td3", nil, now.AddDate(0, 0, -days)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644)
+
+	deleted, err := Prune(dir, "td3", Retention{Keep: 4, MaxAge: 50 * time.Hour}, now)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	// Keep drops the oldest (4 days); MaxAge drops 3 days old
+	if len(deleted) != 2 {
+		t.Errorf("Prune() deleted %v", deleted)
+	}
+	remaining, _ := os.ReadDir(dir)
+	if len(remaining) != 4 {
+		t.Errorf("%d files remain, want 3 backups and notes.txt", len(remaining))
+	}
+}
@@ -0,0 +1,117 @@
+// Package bot shares patterns in chat: pattern files attached to messages
+// are converted and answered with the converted file, a short summary, and
+// a piano roll image
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+)
+
+// MaxFileSize is the largest attachment the bots download; pattern files are
+// a few KB at most
+const MaxFileSize = 1 << 20
+
+// Bot is a chat integration that runs until the context is cancelled
+type Bot interface {
+	Run(ctx context.Context) error
+}
+
+// Reply is the answer to an attached pattern file
+type Reply struct {
+	Name    string
+	Data    []byte
+	Summary string
+	// Image is a PNG piano roll of the pattern
+	Image []byte
+}
+
+// Converter converts attachments for the bots. MIDI files and written 303
+// notation are converted to MIDITarget; .seq and .syx files to MIDI.
+type Converter struct {
+	dev        converter.Device
+	MIDITarget converter.Format
+}
+
+// NewConverter creates a Converter for the given device that turns MIDI
+// files into .seq files
+func NewConverter(dev converter.Device) *Converter {
+	return &Converter{dev: dev, MIDITarget: converter.FormatSeq}
+}
+
+// Accepts reports whether a file name looks like a pattern file
+func Accepts(name string) bool {
+	return converter.DetectFormat(name) != converter.FormatUnknown
+}
+
+// Convert converts an attached pattern file
+func (c *Converter) Convert(name string, data []byte) (*Reply, error) {
+	from := converter.DetectFormat(name)
+	if from == converter.FormatUnknown {
+		from = converter.DetectFormatFromContent(data)
+	}
+	if from == converter.FormatUnknown {
+		return nil, fmt.Errorf("%s is not a .seq, .syx, .mid, .303, TR pattern, or Circuit session file", name)
+	}
+	to := converter.FormatMIDI
+	if from == converter.FormatMIDI || from.InputOnly() {
+		to = c.MIDITarget
+	}
+
+	conv := converter.New(c.dev)
+	pattern, err := conv.ParsePattern(data, from)
+	if err != nil {
+		return nil, err
+	}
+	out, err := conv.GeneratePattern(pattern, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var image bytes.Buffer
+	if err := render.PNG(&image, pattern, render.PNGOptions{Scale: 2}); err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	outName := base + ".mid"
+	if to != converter.FormatMIDI {
+		outName = base + "." + string(to)
+	}
+	return &Reply{
+		Name:    outName,
+		Data:    out,
+		Summary: summarize(name, outName, pattern, conv.Warnings()),
+		Image:   image.Bytes(),
+	}, nil
+}
+
+// summarize describes a converted pattern in a few lines of chat text
+func summarize(in, out string, p *converter.Pattern, warnings []converter.Violation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s -> %s\n", filepath.Base(in), out)
+	result := analysis.Analyze(p)
+	fmt.Fprintf(&b, "%d steps, %s", p.Length, result)
+	if tags := result.Tags(); len(tags) > 0 {
+		var features []string
+		for _, tag := range tags {
+			if !strings.HasPrefix(tag, "key:") {
+				features = append(features, tag)
+			}
+		}
+		if len(features) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(features, ", "))
+		}
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(&b, "\nwarning: %s", w)
+	}
+	return b.String()
+}
@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func testSeq(t *testing.T) []byte {
+	t.Helper()
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{
+		Length: 16,
+		Steps: []converter.Step{
+			{Note: 45, Gate: true, Velocity: 100, Accent: true},
+			{Note: 48, Gate: true, Velocity: 100, Slide: true},
+			{Note: 52, Gate: true, Velocity: 100},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seq
+}
+
+func TestConvert(t *testing.T) {
+	conv := NewConverter(devices.NewTD3())
+
+	reply, err := conv.Convert("acid line.seq", testSeq(t))
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if reply.Name != "acid line.mid" || converter.DetectFormatFromContent(reply.Data) != converter.FormatMIDI || len(reply.Image) == 0 {
+		t.Errorf("reply = %s, %d bytes", reply.Name, len(reply.Data))
+	}
+	for _, want := range []string{"acid line.seq -> acid line.mid", "3 steps", "3 notes", "slides", "accents"} {
+		if !strings.Contains(reply.Summary, want) {
+			t.Errorf("summary %q does not contain %q", reply.Summary, want)
+		}
+	}
+
+	// And back: MIDI converts to the configured target
+	conv.MIDITarget = converter.FormatSyx
+	back, err := conv.Convert(reply.Name, reply.Data)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if back.Name != "acid line.syx" || back.Data[0] != converter.SysExStart {
+		t.Errorf("reply = %s", back.Name)
+	}
+
+	if _, err := conv.Convert("notes.txt", []byte("hi")); err == nil {
+		t.Error("Convert() accepted a non-pattern file")
+	}
+}
+
+func TestTelegram(t *testing.T) {
+	seq := testSeq(t)
+
+	var mu sync.Mutex
+	var sent, caption, texts []string
+	polled, photos := 0, 0
+	done := make(chan struct{})
+
+	ok := func(w http.ResponseWriter, result any) {
+		data, _ := json.Marshal(result)
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": json.RawMessage(data)})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/botTOKEN/getUpdates":
+			polled++
+			if polled > 1 {
+				// Long poll until the bot shuts down
+				mu.Unlock()
+				<-r.Context().Done()
+				mu.Lock()
+				return
+			}
+			ok(w, []map[string]any{
+				{"update_id": 1, "message": map[string]any{
+					"message_id": 10, "chat": map[string]any{"id": 7},
+					"document": map[string]any{"file_id": "f1", "file_name": "line.seq", "file_size": len(seq)},
+				}},
+				{"update_id": 2, "message": map[string]any{
+					"message_id": 11, "chat": map[string]any{"id": 8},
+					"document": map[string]any{"file_id": "f1", "file_name": "line.seq"},
+				}},
+				{"update_id": 3, "message": map[string]any{
+					"message_id": 12, "chat": map[string]any{"id": 7},
+					"document": map[string]any{"file_id": "f2", "file_name": "broken.syx"},
+				}},
+			})
+		case "/botTOKEN/getFile":
+			ok(w, map[string]string{"file_path": "documents/" + r.FormValue("file_id")})
+		case "/file/botTOKEN/documents/f1":
+			_, _ = w.Write(seq)
+		case "/file/botTOKEN/documents/f2":
+			_, _ = w.Write([]byte{0xF0, 0x00, 0xF7})
+		case "/botTOKEN/sendDocument":
+			file, header, err := r.FormFile("document")
+			if err != nil {
+				t.Errorf("sendDocument: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			if r.FormValue("chat_id") != "7" || r.FormValue("reply_to_message_id") != "10" || len(data) == 0 {
+				t.Errorf("sendDocument fields = %v", r.MultipartForm.Value)
+			}
+			sent = append(sent, header.Filename)
+			caption = append(caption, r.FormValue("caption"))
+			ok(w, map[string]any{})
+		case "/botTOKEN/sendPhoto":
+			if _, header, err := r.FormFile("photo"); err != nil || header.Filename != "pianoroll.png" {
+				t.Errorf("sendPhoto: %v", err)
+			}
+			photos++
+			ok(w, map[string]any{})
+		case "/botTOKEN/sendMessage":
+			texts = append(texts, r.FormValue("text"))
+			ok(w, map[string]any{})
+			close(done)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	tg := NewTelegram("TOKEN", NewConverter(devices.NewTD3()))
+	tg.APIURL = srv.URL
+	tg.Chats = []int64{7}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- tg.Run(ctx) }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for replies")
+	}
+	cancel()
+	if err := <-errc; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0] != "line.mid" || !strings.Contains(caption[0], "3 notes") {
+		t.Errorf("sent = %v, captions = %v", sent, caption)
+	}
+	if photos != 1 {
+		t.Errorf("sent %d piano rolls, want 1", photos)
+	}
+	if len(texts) != 1 || !strings.Contains(texts[0], "Could not convert broken.syx") {
+		t.Errorf("texts = %v", texts)
+	}
+}
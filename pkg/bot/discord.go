@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord answers pattern files posted in Discord channels. The bot needs the
+// Message Content intent enabled in the developer portal to see attachments.
+type Discord struct {
+	token string
+	conv  *Converter
+
+	// Channels restricts the bot to these channel IDs; empty allows every
+	// channel the bot can read
+	Channels []string
+	// Client is the HTTP client used to download attachments
+	Client *http.Client
+}
+
+// NewDiscord creates a Discord bot with the given bot token
+func NewDiscord(token string, conv *Converter) *Discord {
+	return &Discord{
+		token:  token,
+		conv:   conv,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run connects to the gateway and answers messages until the context is
+// cancelled
+func (d *Discord) Run(ctx context.Context) error {
+	session, err := discordgo.New("Bot " + d.token)
+	if err != nil {
+		return fmt.Errorf("failed to create discord session: %w", err)
+	}
+	session.Identify.Intents = discordgo.IntentsGuildMessages |
+		discordgo.IntentsDirectMessages |
+		discordgo.IntentsMessageContent
+	session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		if m.Author == nil || m.Author.ID == s.State.User.ID || !d.allowed(m.ChannelID) {
+			return
+		}
+		for _, a := range m.Attachments {
+			if Accepts(a.Filename) {
+				d.handle(ctx, s, m.Message, a)
+			}
+		}
+	})
+
+	if err := session.Open(); err != nil {
+		return fmt.Errorf("failed to connect to discord: %w", err)
+	}
+	defer func() { _ = session.Close() }()
+	log.Printf("discord: connected as %s", session.State.User.Username)
+
+	<-ctx.Done()
+	return nil
+}
+
+// handle converts an attachment and replies with the result
+func (d *Discord) handle(ctx context.Context, s *discordgo.Session, m *discordgo.Message, a *discordgo.MessageAttachment) {
+	msg := &discordgo.MessageSend{Reference: m.Reference()}
+
+	reply, err := d.convert(ctx, a)
+	if err != nil {
+		msg.Content = fmt.Sprintf("Could not convert %s: %v", a.Filename, err)
+	} else {
+		msg.Content = "```\n" + reply.Summary + "\n```"
+		msg.Files = []*discordgo.File{{
+			Name:        reply.Name,
+			ContentType: "application/octet-stream",
+			Reader:      bytes.NewReader(reply.Data),
+		}, {
+			Name:        strings.TrimSuffix(reply.Name, filepath.Ext(reply.Name)) + ".png",
+			ContentType: "image/png",
+			Reader:      bytes.NewReader(reply.Image),
+		}}
+	}
+
+	if _, err := s.ChannelMessageSendComplex(m.ChannelID, msg); err != nil {
+		log.Printf("discord: %v", err)
+	}
+}
+
+func (d *Discord) convert(ctx context.Context, a *discordgo.MessageAttachment) (*Reply, error) {
+	if a.Size > MaxFileSize {
+		return nil, errors.New("file is too large")
+	}
+	data, err := download(ctx, d.Client, a.URL)
+	if err != nil {
+		return nil, err
+	}
+	return d.conv.Convert(a.Filename, data)
+}
+
+func (d *Discord) allowed(channel string) bool {
+	if len(d.Channels) == 0 {
+		return true
+	}
+	for _, id := range d.Channels {
+		if id == channel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TelegramAPI is the Telegram Bot API base URL
+const TelegramAPI = "https://api.telegram.org"
+
+// telegramMaxCaption is the longest caption Telegram accepts on a document
+const telegramMaxCaption = 1024
+
+// Telegram answers pattern files sent to a Telegram bot, using long polling
+type Telegram struct {
+	token string
+	conv  *Converter
+
+	// APIURL overrides the Bot API base URL (for tests or a local Bot API server)
+	APIURL string
+	// Chats restricts the bot to these chat IDs; empty allows every chat
+	Chats []int64
+	// Client is the HTTP client used for API calls
+	Client *http.Client
+}
+
+// NewTelegram creates a Telegram bot with the given bot token
+func NewTelegram(token string, conv *Converter) *Telegram {
+	return &Telegram{
+		token:  token,
+		conv:   conv,
+		APIURL: TelegramAPI,
+		Client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+type telegramMessage struct {
+	MessageID int64 `json:"message_id"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Document *struct {
+		FileID   string `json:"file_id"`
+		FileName string `json:"file_name"`
+		FileSize int64  `json:"file_size"`
+	} `json:"document"`
+}
+
+// Run polls for messages until the context is cancelled
+func (t *Telegram) Run(ctx context.Context) error {
+	var offset int64
+	for {
+		var updates []telegramUpdate
+		err := t.call(ctx, "getUpdates", url.Values{
+			"offset":          {strconv.FormatInt(offset, 10)},
+			"timeout":         {"30"},
+			"allowed_updates": {`["message"]`},
+		}, &updates)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Printf("telegram: %v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				t.handle(ctx, u.Message)
+			}
+		}
+	}
+}
+
+// handle converts a message's attached pattern file and replies with the result
+func (t *Telegram) handle(ctx context.Context, msg *telegramMessage) {
+	doc := msg.Document
+	if doc == nil || !Accepts(doc.FileName) || !t.allowed(msg.Chat.ID) {
+		return
+	}
+
+	reply, err := t.convert(ctx, doc.FileID, doc.FileName, doc.FileSize)
+	if err != nil {
+		err = t.call(ctx, "sendMessage", url.Values{
+			"chat_id":             {strconv.FormatInt(msg.Chat.ID, 10)},
+			"reply_to_message_id": {strconv.FormatInt(msg.MessageID, 10)},
+			"text":                {fmt.Sprintf("Could not convert %s: %v", doc.FileName, err)},
+		}, nil)
+	} else if err = t.sendFile(ctx, "sendDocument", "document", msg, reply.Name, reply.Data, reply.Summary); err == nil {
+		err = t.sendFile(ctx, "sendPhoto", "photo", msg, "pianoroll.png", reply.Image, "")
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("telegram: %v", err)
+	}
+}
+
+func (t *Telegram) allowed(chat int64) bool {
+	if len(t.Chats) == 0 {
+		return true
+	}
+	for _, id := range t.Chats {
+		if id == chat {
+			return true
+		}
+	}
+	return false
+}
+
+// convert downloads and converts an attached file
+func (t *Telegram) convert(ctx context.Context, fileID, name string, size int64) (*Reply, error) {
+	if size > MaxFileSize {
+		return nil, errors.New("file is too large")
+	}
+	var file struct {
+		FilePath string `json:"file_path"`
+	}
+	if err := t.call(ctx, "getFile", url.Values{"file_id": {fileID}}, &file); err != nil {
+		return nil, err
+	}
+	data, err := download(ctx, t.Client, t.APIURL+"/file/bot"+t.token+"/"+file.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	return t.conv.Convert(name, data)
+}
+
+// sendFile replies to a message with a file using the given method
+// (sendDocument or sendPhoto) and form field
+func (t *Telegram) sendFile(ctx context.Context, method, field string, msg *telegramMessage, name string, data []byte, caption string) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("chat_id", strconv.FormatInt(msg.Chat.ID, 10))
+	_ = w.WriteField("reply_to_message_id", strconv.FormatInt(msg.MessageID, 10))
+	if len(caption) > telegramMaxCaption {
+		caption = caption[:telegramMaxCaption-3] + "..."
+	}
+	if caption != "" {
+		_ = w.WriteField("caption", caption)
+	}
+	part, err := w.CreateFormFile(field, name)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.methodURL(method), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return t.do(req, nil)
+}
+
+func (t *Telegram) methodURL(method string) string {
+	return t.APIURL + "/bot" + t.token + "/" + method
+}
+
+// call invokes a Bot API method with form parameters and decodes its result
+func (t *Telegram) call(ctx context.Context, method string, params url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.methodURL(method), bytes.NewBufferString(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return t.do(req, result)
+}
+
+func (t *Telegram) do(req *http.Request, result any) error {
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		// Don't leak the bot token, which is part of the URL
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("telegram request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var r telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("invalid telegram response: %w", err)
+	}
+	if !r.OK {
+		return fmt.Errorf("telegram: %s", r.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// download fetches an attachment, refusing anything larger than MaxFileSize
+func download(ctx context.Context, client *http.Client, fileURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("failed to download attachment: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download attachment: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download attachment: %w", err)
+	}
+	if len(data) > MaxFileSize {
+		return nil, errors.New("file is too large")
+	}
+	return data, nil
+}
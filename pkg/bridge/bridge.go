@@ -0,0 +1,170 @@
+// Package bridge links a DAW to SynthTribe pattern files over a MIDI port:
+// pattern dumps the DAW sends are saved as .seq files, and pattern files
+// dropped into the folder are sent to the DAW as SysEx
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+	"github.com/james-see/synthtribe2midi/pkg/watch"
+)
+
+// timeFormat is the timestamp embedded in received file names
+const timeFormat = "20060102-150405"
+
+// Bridge converts between pattern dumps on a MIDI port and files in a folder
+type Bridge struct {
+	Conv *converter.Converter
+	// Dir receives captured patterns and is watched for files to play back
+	Dir string
+	// Prefix starts the names of captured files (default "pattern")
+	Prefix string
+	// Out is where dropped files are sent; nil disables playback
+	Out mididevice.Out
+	// Interval is the folder polling interval (default watch.DefaultInterval)
+	Interval time.Duration
+	// Logf reports activity; nil discards it
+	Logf func(format string, args ...any)
+
+	// mu serializes conversions, which arrive from both the MIDI listener
+	// and the folder watcher
+	mu      sync.Mutex
+	watcher *watch.Watcher
+}
+
+// Receive converts a pattern dump to a .seq file in Dir and returns its path
+func (b *Bridge) Receive(msg []byte) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pattern, err := b.Conv.ParsePattern(msg, converter.FormatSyx)
+	if err != nil {
+		return "", err
+	}
+	data, err := b.Conv.GeneratePattern(pattern, converter.FormatSeq)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(b.Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bridge folder: %w", err)
+	}
+	path := b.newPath(time.Now())
+	if err := converter.WriteFileAtomic(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	// Don't send a captured pattern straight back to the DAW
+	if b.watcher != nil {
+		b.watcher.Ignore(path)
+	}
+	return path, nil
+}
+
+// newPath returns an unused <prefix>-YYYYMMDD-HHMMSS.seq path in Dir, with a
+// counter appended when several patterns arrive within a second
+func (b *Bridge) newPath(at time.Time) string {
+	prefix := b.Prefix
+	if prefix == "" {
+		prefix = "pattern"
+	}
+	base := prefix + "-" + at.Format(timeFormat)
+	path := filepath.Join(b.Dir, base+".seq")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(b.Dir, base+"-"+strconv.Itoa(n)+".seq")
+	}
+}
+
+// Send converts a pattern file (.seq, .syx, .303, or MIDI) to SysEx and
+// sends it to Out
+func (b *Bridge) Send(path string) error {
+	if b.Out == nil {
+		return fmt.Errorf("no MIDI output to send %s to", path)
+	}
+
+	b.mu.Lock()
+	pattern, err := b.Conv.ReadPatternFile(path)
+	var data []byte
+	if err == nil {
+		data, err = b.Conv.GeneratePattern(pattern, converter.FormatSyx)
+	}
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	msgs, err := sysex.Split(data)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if err := b.Out.Send(msg); err != nil {
+			return fmt.Errorf("failed to send %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// Run captures pattern dumps from in and, with an Out, plays back files
+// dropped into Dir until ctx is cancelled. SysEx that is not a pattern dump
+// for the converter's device is reported and skipped.
+func (b *Bridge) Run(ctx context.Context, in mididevice.In) error {
+	if err := os.MkdirAll(b.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create bridge folder: %w", err)
+	}
+	if b.Out != nil {
+		b.watcher = watch.New(b.Dir, b.Interval, b.play)
+	}
+
+	stop, err := in.Listen(func(msg []byte) {
+		if len(msg) == 0 || msg[0] != sysex.Start {
+			return
+		}
+		path, err := b.Receive(msg)
+		if err != nil {
+			b.logf("Skipped SysEx from %s: %v", in.Name(), err)
+			return
+		}
+		b.logf("Captured %s", path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", in.Name(), err)
+	}
+	defer stop()
+
+	if b.watcher == nil {
+		<-ctx.Done()
+		return nil
+	}
+	return b.watcher.Run(ctx)
+}
+
+// play is the watcher handler: it sends pattern files and ignores the rest
+func (b *Bridge) play(path string) error {
+	if converter.DetectFormat(path) == converter.FormatUnknown {
+		return nil
+	}
+	if err := b.Send(path); err != nil {
+		b.logf("Failed to send %s: %v", filepath.Base(path), err)
+		return err
+	}
+	b.logf("Sent %s to %s", filepath.Base(path), b.Out.Name())
+	return nil
+}
+
+func (b *Bridge) logf(format string, args ...any) {
+	if b.Logf != nil {
+		b.Logf(format, args...)
+	}
+}
@@ -0,0 +1,145 @@
+package bridge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+// fakePort is a DAW-side port pair: the test delivers messages through
+// listen and collects what the bridge sends
+type fakePort struct {
+	mu     sync.Mutex
+	listen func(msg []byte)
+	sent   [][]byte
+}
+
+func (f *fakePort) Name() string { return "fake" }
+func (f *fakePort) Close() error { return nil }
+func (f *fakePort) Send(msg []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return nil
+}
+func (f *fakePort) Listen(onMsg func(msg []byte)) (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listen = onMsg
+	return func() {}, nil
+}
+func (f *fakePort) deliver(msg []byte) {
+	f.mu.Lock()
+	listen := f.listen
+	f.mu.Unlock()
+	listen(msg)
+}
+func (f *fakePort) sentCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+func testPattern() *converter.Pattern {
+	return &converter.Pattern{
+		Length: 16,
+		Steps: []converter.Step{
+			{Note: 48, Gate: true, Velocity: 100},
+			{Note: 51, Gate: true, Velocity: 127, Accent: true},
+		},
+	}
+}
+
+func TestReceive(t *testing.T) {
+	dev := devices.NewTD3()
+	syx, err := dev.GenerateSyx(testPattern())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bridge{Conv: converter.New(dev), Dir: t.TempDir(), Prefix: "td3"}
+
+	first, err := b.Receive(syx)
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	second, err := b.Receive(syx)
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if first == second || !strings.HasPrefix(filepath.Base(first), "td3-") || filepath.Ext(first) != ".seq" {
+		t.Errorf("Receive() paths = %s, %s", first, second)
+	}
+
+	data, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := dev.ParseSeq(data)
+	if err != nil || p.Steps[1].Note != 51 || !p.Steps[1].Accent {
+		t.Errorf("captured pattern = %+v, %v", p, err)
+	}
+
+	if _, err := b.Receive([]byte{0xF0, 0x7E, 0x7F, 0x06, 0x01, 0xF7}); err == nil {
+		t.Error("Receive() accepted an identity request")
+	}
+}
+
+func TestRunPlaysBackDroppedFiles(t *testing.T) {
+	dev := devices.NewTD3()
+	port := &fakePort{}
+	b := &Bridge{Conv: converter.New(dev), Dir: t.TempDir(), Out: port, Interval: 5 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx, port) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	waitFor(t, func() bool {
+		port.mu.Lock()
+		defer port.mu.Unlock()
+		return port.listen != nil
+	})
+
+	// A captured pattern is saved but not echoed back
+	syx, _ := dev.GenerateSyx(testPattern())
+	port.deliver(syx)
+
+	// A dropped file is sent
+	seq, _ := dev.GenerateSeq(testPattern())
+	if err := os.WriteFile(filepath.Join(b.Dir, "dropped.seq"), seq, 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return port.sentCount() > 0 })
+	time.Sleep(20 * time.Millisecond)
+
+	if n := port.sentCount(); n != 1 {
+		t.Fatalf("sent %d messages, want 1", n)
+	}
+	p, err := dev.ParseSyx(port.sent[0])
+	if err != nil || p.Steps[0].Note != 48 {
+		t.Errorf("sent pattern = %+v, %v", p, err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,202 @@
+// Package config reads and writes the user configuration file, config.yaml
+// in the synthtribe2midi user config directory, with the default conversion
+// options and settings for the interactive front-ends
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the user configuration file
+type Config struct {
+	// Convert holds the default conversion options of the CLI, the TUI, and
+	// the API server
+	Convert ConvertConfig `yaml:"convert,omitempty"`
+	TUI     TUIConfig     `yaml:"tui,omitempty"`
+	Pack    PackConfig    `yaml:"pack,omitempty"`
+	// Presets are named bundles of conversion options, selected with
+	// --preset or in the TUI, that are used instead of Convert
+	Presets map[string]ConvertConfig `yaml:"presets,omitempty"`
+}
+
+// PackConfig configures pattern packs
+type PackConfig struct {
+	// Registry is the URL of the pack registry searched and installed from
+	Registry string `yaml:"registry,omitempty"`
+}
+
+// ConvertConfig holds default conversion options, named like the CLI flags;
+// unset (zero) options keep the converter's defaults
+type ConvertConfig struct {
+	Transpose       int     `yaml:"transpose,omitempty"`
+	Octave          int     `yaml:"octave,omitempty"` // added to the transpose, in octaves
+	Channel         int     `yaml:"channel,omitempty"`
+	GateLength      float64 `yaml:"gate_length,omitempty"`
+	AccentThreshold int     `yaml:"accent_threshold,omitempty"`
+	GhostThreshold  int     `yaml:"ghost_threshold,omitempty"`
+	SlideMode       string  `yaml:"slide_mode,omitempty"`
+	Downmix         string  `yaml:"downmix,omitempty"`
+	Strict          bool    `yaml:"strict,omitempty"`
+	VerifyRoundtrip bool    `yaml:"verify_roundtrip,omitempty"`
+	Recover         bool    `yaml:"recover,omitempty"`
+	GateTrack       bool    `yaml:"gate_track,omitempty"`
+	GateNote        uint8   `yaml:"gate_note,omitempty"`
+	AccentTrack     bool    `yaml:"accent_track,omitempty"`
+	AccentNote      uint8   `yaml:"accent_note,omitempty"`
+}
+
+// Options returns the conversion options: converter.DefaultOptions with the
+// configured ones applied
+func (c ConvertConfig) Options() converter.ConvertOptions {
+	opts := converter.DefaultOptions()
+	opts.Transpose = c.Transpose + 12*c.Octave
+	opts.Channel = c.Channel
+	opts.GateLength = c.GateLength
+	opts.AccentThreshold = c.AccentThreshold
+	opts.GhostThreshold = c.GhostThreshold
+	opts.SlideMode = c.SlideMode
+	opts.Downmix = c.Downmix
+	opts.Strict = c.Strict
+	opts.VerifyRoundtrip = c.VerifyRoundtrip
+	opts.Recover = c.Recover
+	opts.GateTrack = c.GateTrack
+	opts.AccentTrack = c.AccentTrack
+	if c.GateNote != 0 {
+		opts.GateNote = c.GateNote
+	}
+	if c.AccentNote != 0 {
+		opts.AccentNote = c.AccentNote
+	}
+	return opts
+}
+
+// SetOptions stores the options that are kept between sessions; per-file
+// ones (device ID, groove, bar) are left out, as are defaults
+func (c *ConvertConfig) SetOptions(opts converter.ConvertOptions) {
+	*c = ConvertConfig{
+		Transpose:       opts.Transpose,
+		Channel:         opts.Channel,
+		GateLength:      opts.GateLength,
+		AccentThreshold: opts.AccentThreshold,
+		GhostThreshold:  opts.GhostThreshold,
+		SlideMode:       opts.SlideMode,
+		Downmix:         opts.Downmix,
+		Strict:          opts.Strict,
+		VerifyRoundtrip: opts.VerifyRoundtrip,
+		Recover:         opts.Recover,
+		GateTrack:       opts.GateTrack,
+		AccentTrack:     opts.AccentTrack,
+	}
+	if opts.GateNote != converter.DefaultGateNote {
+		c.GateNote = opts.GateNote
+	}
+	if opts.AccentNote != converter.DefaultAccentNote {
+		c.AccentNote = opts.AccentNote
+	}
+}
+
+// TUIConfig configures the terminal UI
+type TUIConfig struct {
+	Keys KeysConfig `yaml:"keys,omitempty"`
+}
+
+// KeysConfig selects the TUI key bindings
+type KeysConfig struct {
+	// Profile is the set of bindings to start from: "vim" (default, arrows
+	// plus j/k and h/l) or "arrows" (arrow keys and enter only)
+	Profile string `yaml:"profile,omitempty"`
+	// Bindings replaces the keys of single actions, e.g. up: [w, up]
+	Bindings map[string][]string `yaml:"bindings,omitempty"`
+}
+
+// DefaultPath returns the location of the configuration file in the user
+// config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "synthtribe2midi", "config.yaml"), nil
+}
+
+// Load reads a YAML configuration file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := cfg.Convert.Options().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid convert options in %s: %w", path, err)
+	}
+	for _, name := range cfg.PresetNames() {
+		if err := cfg.Presets[name].Options().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid preset %q in %s: %w", name, path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// PresetNames returns the names of the presets, sorted
+func (c *Config) PresetNames() []string {
+	return slices.Sorted(maps.Keys(c.Presets))
+}
+
+// Preset returns the conversion options of a preset, or of the convert
+// section for an empty name
+func (c *Config) Preset(name string) (ConvertConfig, error) {
+	if name == "" {
+		return c.Convert, nil
+	}
+	preset, ok := c.Presets[name]
+	if !ok {
+		if len(c.Presets) == 0 {
+			return ConvertConfig{}, fmt.Errorf("unknown preset %q: the config file defines no presets", name)
+		}
+		return ConvertConfig{}, fmt.Errorf("unknown preset %q (have %s)", name, strings.Join(c.PresetNames(), ", "))
+	}
+	return preset, nil
+}
+
+// Save writes the configuration to a YAML file, creating its directory
+func Save(path string, cfg *Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := converter.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// LoadDefault reads the configuration file at DefaultPath, returning an
+// empty configuration if there is none
+func LoadDefault() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return cfg, err
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("tui:\n  keys:\n    profile: arrows\n    bindings:\n      quit: [x]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TUI.Keys.Profile != "arrows" || len(cfg.TUI.Keys.Bindings["quit"]) != 1 {
+		t.Errorf("loaded %+v", cfg.TUI.Keys)
+	}
+
+	if err := os.WriteFile(path, []byte("tui:\n  colour: green\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("unknown setting accepted")
+	}
+
+	if err := os.WriteFile(path, []byte("convert:\n  gate_track: true\n  gate_note: 200\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "gate note 200") {
+		t.Errorf("Load() with gate_note 200 error = %v", err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	if cfg, err := LoadDefault(); err != nil || cfg.TUI.Keys.Profile != "" {
+		t.Errorf("LoadDefault() without a file = %+v, %v", cfg, err)
+	}
+}
+
+func TestConvertConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+	opts := converter.DefaultOptions()
+	opts.Transpose = -12
+	opts.GateLength = 0.5
+	opts.SlideMode = converter.SlideLegato
+	opts.AccentNote = 40
+	opts.Bar = 2 // per-file, not kept
+
+	cfg := &Config{TUI: TUIConfig{Keys: KeysConfig{Profile: "arrows"}}}
+	cfg.Convert.SetOptions(opts)
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "gate_note") {
+		t.Errorf("default gate note written:\n%s", data)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := opts
+	want.Bar = 0
+	if got := loaded.Convert.Options(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Options() = %+v, want %+v", got, want)
+	}
+	if loaded.TUI.Keys.Profile != "arrows" {
+		t.Errorf("keys profile lost: %+v", loaded.TUI)
+	}
+
+	if err := os.WriteFile(path, []byte("convert:\n  channel: 17\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("out-of-range channel accepted")
+	}
+}
+
+func TestPresets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `convert:
+  transpose: 12
+presets:
+  for-hardware:
+    slide_mode: legato
+    octave: -1
+    transpose: 2
+  for-ableton:
+    channel: 2
+    gate_length: 0.5
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cfg.PresetNames(), ","); got != "for-ableton,for-hardware" {
+		t.Errorf("PresetNames() = %s", got)
+	}
+
+	hw, err := cfg.Preset("for-hardware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := hw.Options(); o.Transpose != -10 || o.SlideMode != converter.SlideLegato || o.Channel != 0 {
+		t.Errorf("for-hardware options = %+v, want transpose -10 and legato only", o)
+	}
+	if c, err := cfg.Preset(""); err != nil || c.Transpose != 12 {
+		t.Errorf("Preset(\"\") = %+v, %v; want the convert section", c, err)
+	}
+	if _, err := cfg.Preset("for-live"); err == nil || !strings.Contains(err.Error(), "for-ableton, for-hardware") {
+		t.Errorf("Preset(unknown) error = %v, want the known names", err)
+	}
+
+	if err := os.WriteFile(path, []byte("presets:\n  bad:\n    channel: 17\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `"bad"`) {
+		t.Errorf("Load() of an invalid preset error = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// plainVelocity is the velocity typical plain notes are mapped to
+const plainVelocity = 100
+
+// AccentMap reads accents and velocities from MIDI the way a reference
+// performance plays them, instead of with a fixed accent threshold: notes
+// at or above Threshold are accents, and velocities are scaled so the
+// typical plain note reads as 100 and the typical accent as 127
+type AccentMap struct {
+	// Threshold is the lowest velocity read as an accent
+	Threshold uint8 `json:"threshold"`
+	// Plain and Accent are the median velocities of the reference's plain
+	// notes and accents
+	Plain  uint8 `json:"plain"`
+	Accent uint8 `json:"accent"`
+}
+
+// LearnAccents builds an accent map from the velocity distribution of MIDI
+// data: the threshold splits its note-on velocities into the two groups
+// that differ the most (Otsu's method), soft plain notes and loud accents
+func LearnAccents(data []byte) (*AccentMap, error) {
+	s, err := smf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse MIDI: %w", err)
+	}
+	var velocities []uint8
+	for _, track := range s.Tracks {
+		for _, ev := range track {
+			var channel, key, velocity uint8
+			if ev.Message.GetNoteStart(&channel, &key, &velocity) {
+				velocities = append(velocities, velocity)
+			}
+		}
+	}
+	if len(velocities) == 0 {
+		return nil, errors.New("accent reference MIDI has no notes")
+	}
+	slices.Sort(velocities)
+	if velocities[0] == velocities[len(velocities)-1] {
+		return nil, fmt.Errorf("accent reference MIDI plays every note at velocity %d, so it has no accents to learn", velocities[0])
+	}
+
+	// Try every split between two velocities, keeping the one with the
+	// largest variance between the groups
+	var total float64
+	for _, v := range velocities {
+		total += float64(v)
+	}
+	best, split := -1.0, 0
+	var sum float64
+	for i := 1; i < len(velocities); i++ {
+		sum += float64(velocities[i-1])
+		if velocities[i] == velocities[i-1] {
+			continue
+		}
+		n0, n1 := float64(i), float64(len(velocities)-i)
+		mean0, mean1 := sum/n0, (total-sum)/n1
+		if variance := n0 * n1 * (mean0 - mean1) * (mean0 - mean1); variance > best {
+			best, split = variance, i
+		}
+	}
+
+	plain, accents := velocities[:split], velocities[split:]
+	return &AccentMap{
+		Threshold: accents[0],
+		Plain:     plain[len(plain)/2],
+		Accent:    accents[len(accents)/2],
+	}, nil
+}
+
+// LoadAccents learns an accent map from a MIDI file
+func LoadAccents(path string) (*AccentMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accent reference: %w", err)
+	}
+	return LearnAccents(data)
+}
+
+// Validate checks that the map is usable
+func (a *AccentMap) Validate() error {
+	switch {
+	case a.Threshold < 1 || a.Threshold > 127:
+		return fmt.Errorf("accent map threshold %d out of range (1-127)", a.Threshold)
+	case a.Plain < 1 || a.Plain >= a.Threshold:
+		return fmt.Errorf("accent map plain velocity %d must be between 1 and the threshold %d", a.Plain, a.Threshold)
+	case a.Accent < a.Threshold || a.Accent > 127:
+		return fmt.Errorf("accent map accent velocity %d must be between the threshold %d and 127", a.Accent, a.Threshold)
+	}
+	return nil
+}
+
+// Map returns the velocity a note played at velocity is read with, and
+// whether it is an accent. Plain notes map to 1-100 and accents to
+// DefaultAccentThreshold-127, so the result reads the same with the default
+// threshold.
+func (a *AccentMap) Map(velocity uint8) (uint8, bool) {
+	if velocity >= a.Threshold {
+		v := math.Round(float64(velocity) * 127 / float64(a.Accent))
+		return uint8(max(DefaultAccentThreshold, min(127, v))), true
+	}
+	v := math.Round(float64(velocity) * plainVelocity / float64(a.Plain))
+	return uint8(max(1, min(plainVelocity, v))), false
+}
@@ -0,0 +1,112 @@
+package converter
+
+import (
+	"testing"
+)
+
+// keyboardTake is an expressive take: plain notes played at 50-70 and
+// accents at 90-100, all below the default accent threshold
+func keyboardTake(t *testing.T) []byte {
+	t.Helper()
+	velocities := []uint8{60, 50, 95, 70, 55, 90, 65, 100, 58, 62, 92, 68, 52, 66, 98, 64}
+	notes := make([]note, len(velocities))
+	for i, v := range velocities {
+		notes[i] = note{key: 48, velocity: v, step: uint32(i), length: 1}
+	}
+	return testMIDI(t, notes...)
+}
+
+func TestLearnAccents(t *testing.T) {
+	a, err := LearnAccents(keyboardTake(t))
+	if err != nil {
+		t.Fatalf("LearnAccents() error = %v", err)
+	}
+	want := AccentMap{Threshold: 90, Plain: 62, Accent: 95}
+	if *a != want {
+		t.Errorf("LearnAccents() = %+v, want %+v", *a, want)
+	}
+	if err := a.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	tests := []struct {
+		velocity uint8
+		want     uint8
+		accent   bool
+	}{
+		{62, 100, false},
+		{31, 50, false},
+		{89, 100, false},
+		{90, 120, true},
+		{95, 127, true},
+		{120, 127, true},
+		{60, 97, false},
+	}
+	for _, tt := range tests {
+		if got, accent := a.Map(tt.velocity); got != tt.want || accent != tt.accent {
+			t.Errorf("Map(%d) = %d, %v, want %d, %v", tt.velocity, got, accent, tt.want, tt.accent)
+		}
+	}
+}
+
+func TestLearnAccentsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not MIDI", []byte("not a MIDI file")},
+		{"no notes", testMIDI(t)},
+		{"one velocity", testMIDI(t, note{key: 48, velocity: 100, length: 1}, note{key: 50, velocity: 100, step: 1, length: 1})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LearnAccents(tt.data); err == nil {
+				t.Error("LearnAccents() expected error")
+			}
+		})
+	}
+
+	bad := ConvertOptions{Accents: &AccentMap{Threshold: 90, Plain: 95, Accent: 100}}
+	if err := bad.Validate(); err == nil {
+		t.Error("Validate() accepted a plain velocity above the threshold")
+	}
+}
+
+func TestParseMIDIAccentMap(t *testing.T) {
+	take := keyboardTake(t)
+	a, err := LearnAccents(take)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default threshold finds no accents in the take; the learned map
+	// finds the five played
+	for _, tt := range []struct {
+		name    string
+		accents *AccentMap
+		want    int
+	}{
+		{"default threshold", nil, 0},
+		{"learned", a, 5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMIDIConverter()
+			opts := DefaultOptions()
+			opts.Accents = tt.accents
+			m.SetOptions(opts)
+			pattern, err := m.ParseMIDI(take)
+			if err != nil {
+				t.Fatalf("ParseMIDI() error = %v", err)
+			}
+			accents := 0
+			for _, s := range pattern.Steps {
+				if s.Accent {
+					accents++
+				}
+			}
+			if accents != tt.want {
+				t.Errorf("got %d accents, want %d", accents, tt.want)
+			}
+		})
+	}
+}
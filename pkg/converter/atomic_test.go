@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pattern.seq")
+
+	if err := WriteFileAtomic(path, []byte("first"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("second"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic() over existing file error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second" {
+		t.Errorf("content = %q, want %q", data, "second")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want existing 0600 kept", info.Mode().Perm())
+	}
+
+	// A failed write leaves the old content and no temporary files behind
+	err := WriteAtomic(path, 0644, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Fatal("WriteAtomic() with failing writer succeeded")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second" {
+		t.Errorf("content after failed write = %q, want %q", data, "second")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the output", len(entries))
+	}
+
+	// Writing through a symlink replaces the target, not the link
+	link := filepath.Join(dir, "link.seq")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if err := WriteFileAtomic(link, []byte("third"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic(link) error = %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link replaced by a regular file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third" {
+		t.Errorf("target content = %q, want %q", data, "third")
+	}
+}
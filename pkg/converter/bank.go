@@ -0,0 +1,45 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MergePatterns combines the patterns of a bank into one multi-track
+// pattern with a melodic track per pattern, named after it, for writing a
+// whole bank to a single MIDI file. Tracks of multi-track patterns are kept,
+// named "<pattern> <track>". The merged pattern plays at the first
+// pattern's tempo, with a warning if others differ. Drum patterns cannot be
+// merged, as their voices would share one MIDI track.
+func MergePatterns(names []string, patterns []*Pattern) (*Pattern, []Violation, error) {
+	if len(patterns) == 0 {
+		return nil, nil, errors.New("no patterns to merge")
+	}
+	if len(names) != len(patterns) {
+		return nil, nil, fmt.Errorf("got %d names for %d patterns", len(names), len(patterns))
+	}
+
+	merged := &Pattern{Name: names[0], Tempo: patterns[0].Tempo, DeviceID: patterns[0].DeviceID}
+	var warnings []Violation
+	for i, p := range patterns {
+		if p.IsDrum() {
+			return nil, nil, fmt.Errorf("%s: drum patterns cannot be merged into one MIDI file", names[i])
+		}
+		if len(p.Steps) > 0 {
+			merged.Tracks = append(merged.Tracks, Track{Name: names[i], Steps: p.Steps})
+		}
+		for _, t := range p.Tracks {
+			if t.IsDrum() {
+				return nil, nil, fmt.Errorf("%s: drum track %s cannot be merged into one MIDI file", names[i], t.Name)
+			}
+			t.Name = names[i] + " " + t.Name
+			merged.Tracks = append(merged.Tracks, t)
+		}
+		merged.Length = max(merged.Length, p.StepCount())
+		if p.Tempo != merged.Tempo {
+			warnings = append(warnings, Violation{Step: -1, Field: "tempo", Severity: SeverityWarning,
+				Message: fmt.Sprintf("%s plays at %g BPM; merged at %g BPM of %s", names[i], p.Tempo, merged.Tempo, names[0])})
+		}
+	}
+	return merged, warnings, nil
+}
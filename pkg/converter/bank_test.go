@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+func TestMergePatterns(t *testing.T) {
+	line := func(note uint8, length int, tempo float64) *Pattern {
+		p := &Pattern{Tempo: tempo, Length: length, Steps: make([]Step, length)}
+		p.Steps[0] = Step{Note: note, Gate: true, Velocity: 100}
+		return p
+	}
+	poly := &Pattern{Tempo: 120, Length: 16, Tracks: []Track{{Name: "Lead", Steps: make([]Step, 16)}}}
+
+	merged, warnings, err := MergePatterns(
+		[]string{"bank-01", "bank-02", "bank-03"},
+		[]*Pattern{line(48, 16, 120), line(50, 8, 130), poly},
+	)
+	if err != nil {
+		t.Fatalf("MergePatterns() error = %v", err)
+	}
+	var names []string
+	for _, tr := range merged.Tracks {
+		names = append(names, tr.Name)
+	}
+	if got := strings.Join(names, ","); got != "bank-01,bank-02,bank-03 Lead" {
+		t.Errorf("tracks = %s", got)
+	}
+	if merged.Length != 16 || merged.Tempo != 120 || len(merged.Steps) != 0 {
+		t.Errorf("merged = %d steps (length %d) at %g BPM", len(merged.Steps), merged.Length, merged.Tempo)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "bank-02 plays at 130 BPM") {
+		t.Errorf("warnings = %v, want the tempo of bank-02", warnings)
+	}
+
+	// Each pattern gets a MIDI track of its own
+	data, err := NewMIDIConverter().GenerateMIDI(merged)
+	if err != nil {
+		t.Fatalf("GenerateMIDI() error = %v", err)
+	}
+	s, err := smf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("smf.ReadFrom() error = %v", err)
+	}
+	if len(s.Tracks) != 4 {
+		t.Errorf("track count = %d, want the tempo track and 3", len(s.Tracks))
+	}
+
+	drums := &Pattern{Tempo: 120, Length: 16, Tracks: []Track{{Name: "BD", Note: 36, Steps: make([]Step, 16)}}}
+	if _, _, err := MergePatterns([]string{"a", "b"}, []*Pattern{line(48, 16, 120), drums}); err == nil {
+		t.Error("MergePatterns() merged a drum pattern")
+	}
+	if _, _, err := MergePatterns(nil, nil); err == nil {
+		t.Error("MergePatterns() merged nothing")
+	}
+}
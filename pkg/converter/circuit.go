@@ -0,0 +1,169 @@
+package converter
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Circuit session layout, as exported by Novation Components (.ncs): a
+// header, a header per note track, then each track's 8 patterns
+const (
+	circuitHeaderSize      = 24
+	circuitTrackHeaderSize = 16
+	circuitPatterns        = 8  // patterns per track
+	circuitNotesPerStep    = 6  // note slots of a step
+	circuitNoteSize        = 3  // note, velocity (0 = empty slot), gate
+	circuitGateTicks       = 6  // gate ticks per step
+	circuitMaxTracks       = 8  // the SL MkIII's 8 parts
+	circuitNameSize        = 16 // session name
+	circuitTrackNameSize   = 8
+)
+
+// circuitMagic starts Circuit session files
+var circuitMagic = []byte("NCS\x01")
+
+// ParseCircuit parses a Novation Circuit, Circuit Tracks, or SL MkIII session
+// (.ncs) into a multi-track pattern with a melodic track per note track,
+// made of the patterns of its chain. Chords are reduced to their lowest
+// note, notes held past their step become ties (and slides into a following
+// note), and velocities from accentThreshold up are accents. Monophonic
+// tracks come first, lowest first, so single-line devices such as the TD-3
+// play the bassline. Drum tracks are not read.
+func ParseCircuit(data []byte, accentThreshold uint8) (*Pattern, error) {
+	if !IsCircuitSession(data) {
+		return nil, errors.New("not a Circuit session: missing NCS header")
+	}
+	if len(data) < circuitHeaderSize {
+		return nil, fmt.Errorf("Circuit session too short: %d bytes", len(data))
+	}
+	tempo, tracks, steps := data[20], int(data[21]), int(data[22])
+	if tracks < 1 || tracks > circuitMaxTracks {
+		return nil, fmt.Errorf("Circuit session has %d note tracks, want 1-%d", tracks, circuitMaxTracks)
+	}
+	if steps != 16 && steps != 32 {
+		return nil, fmt.Errorf("Circuit session has %d-step patterns, want 16 or 32", steps)
+	}
+	patternSize := 2 + steps*circuitNotesPerStep*circuitNoteSize
+	size := circuitHeaderSize + tracks*(circuitTrackHeaderSize+circuitPatterns*patternSize)
+	if len(data) < size {
+		return nil, fmt.Errorf("Circuit session too short: %d bytes, need %d", len(data), size)
+	}
+
+	p := &Pattern{Name: circuitString(data[4 : 4+circuitNameSize]), Tempo: float64(tempo)}
+	if p.Name == "" {
+		p.Name = "Circuit Session"
+	}
+	if p.Tempo == 0 {
+		p.Tempo = 120
+	}
+	patterns := data[circuitHeaderSize+tracks*circuitTrackHeaderSize:]
+	type parsed struct {
+		track Track
+		chord bool
+		pitch float64 // mean note, to put the bassline first
+	}
+	var parts []parsed
+	for t := range tracks {
+		header := data[circuitHeaderSize+t*circuitTrackHeaderSize:]
+		first, last, channel := int(header[circuitTrackNameSize]), int(header[circuitTrackNameSize+1]), int(header[circuitTrackNameSize+2])
+		if first > last || last >= circuitPatterns || channel > 15 {
+			return nil, fmt.Errorf("track %d: invalid chain %d-%d or channel %d", t+1, first+1, last+1, channel+1)
+		}
+		name := circuitString(header[:circuitTrackNameSize])
+		if name == "" {
+			name = fmt.Sprintf("Track %d", t+1)
+		}
+
+		part := parsed{track: Track{Name: name, Channel: channel + 1}}
+		var gates []int
+		for n := first; n <= last; n++ {
+			pattern := patterns[(t*circuitPatterns+n)*patternSize:]
+			start, end := int(pattern[0]), int(pattern[1])
+			if start > end || end >= steps {
+				return nil, fmt.Errorf("%s pattern %d: invalid steps %d-%d", name, n+1, start+1, end+1)
+			}
+			for i := start; i <= end; i++ {
+				step, gate, chord := circuitStep(pattern[2+i*circuitNotesPerStep*circuitNoteSize:], accentThreshold)
+				part.track.Steps = append(part.track.Steps, step)
+				gates = append(gates, gate)
+				part.chord = part.chord || chord
+			}
+		}
+
+		// Held notes tie over the following empty steps, and slide into a
+		// note they reach
+		notes, sum := 0, 0
+		line := part.track.Steps
+		for i := range line {
+			if !line[i].Gate || line[i].Tie {
+				continue
+			}
+			notes++
+			sum += int(line[i].Note)
+			for j := i + 1; j < len(line) && (j-i)*circuitGateTicks < gates[i]; j++ {
+				if line[j].Gate {
+					line[j-1].Slide = true
+					break
+				}
+				line[j] = Step{Note: line[i].Note, Gate: true, Tie: true, Velocity: line[i].Velocity}
+			}
+		}
+		part.pitch = 128 // empty tracks last
+		if notes > 0 {
+			part.pitch = float64(sum) / float64(notes)
+		}
+		parts = append(parts, part)
+		p.Length = max(p.Length, len(part.track.Steps))
+	}
+
+	slices.SortStableFunc(parts, func(a, b parsed) int {
+		if a.chord != b.chord {
+			if a.chord {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.pitch, b.pitch)
+	})
+	for _, part := range parts {
+		p.Tracks = append(p.Tracks, part.track)
+	}
+	return p, nil
+}
+
+// circuitStep reads a step's note slots into a step playing the lowest note,
+// returning its gate in ticks and whether the step held a chord
+func circuitStep(slots []byte, accentThreshold uint8) (step Step, gate int, chord bool) {
+	for n := range circuitNotesPerStep {
+		note, velocity, ticks := slots[n*circuitNoteSize], slots[n*circuitNoteSize+1], slots[n*circuitNoteSize+2]
+		if velocity == 0 || note > 127 {
+			continue
+		}
+		if step.Gate {
+			chord = true
+			if note >= step.Note {
+				continue
+			}
+		}
+		step = Step{Note: note, Gate: true, Velocity: velocity, Accent: velocity >= accentThreshold}
+		gate = int(ticks)
+	}
+	return step, gate, chord
+}
+
+// circuitString returns a NUL- or space-padded name
+func circuitString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// IsCircuitSession reports whether data starts like a Circuit session
+func IsCircuitSession(data []byte) bool {
+	return bytes.HasPrefix(data, circuitMagic)
+}
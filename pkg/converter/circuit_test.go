@@ -0,0 +1,133 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+// circuitNote is a note of a test session: track, pattern, step, slot
+type circuitNote struct {
+	track, pattern, step, slot int
+	note, velocity, gate       byte
+}
+
+// testCircuit builds a session of 16-step patterns with the given track
+// names, chains (first and last pattern), and notes
+func testCircuit(names []string, chains [][2]byte, notes ...circuitNote) []byte {
+	const steps = 16
+	patternSize := 2 + steps*circuitNotesPerStep*circuitNoteSize
+	data := make([]byte, circuitHeaderSize+len(names)*(circuitTrackHeaderSize+circuitPatterns*patternSize))
+	copy(data, circuitMagic)
+	copy(data[4:], "ACID JAM")
+	data[20], data[21], data[22] = 128, byte(len(names)), steps
+
+	patterns := circuitHeaderSize + len(names)*circuitTrackHeaderSize
+	for t, name := range names {
+		header := data[circuitHeaderSize+t*circuitTrackHeaderSize:]
+		copy(header, name)
+		header[circuitTrackNameSize], header[circuitTrackNameSize+1], header[circuitTrackNameSize+2] = chains[t][0], chains[t][1], byte(t)
+		for n := range circuitPatterns {
+			data[patterns+(t*circuitPatterns+n)*patternSize+1] = steps - 1
+		}
+	}
+	for _, n := range notes {
+		at := patterns + (n.track*circuitPatterns+n.pattern)*patternSize + 2 + (n.step*circuitNotesPerStep+n.slot)*circuitNoteSize
+		data[at], data[at+1], data[at+2] = n.note, n.velocity, n.gate
+	}
+	return data
+}
+
+func TestParseCircuit(t *testing.T) {
+	data := testCircuit([]string{"Synth 1", "Synth 2", "MIDI 1"}, [][2]byte{{0, 0}, {0, 1}, {0, 0}},
+		// Synth 1 plays chords
+		circuitNote{0, 0, 0, 0, 60, 100, 6},
+		circuitNote{0, 0, 0, 1, 64, 100, 6},
+		// Synth 2 is a bassline over two patterns: a note held for three
+		// steps, a note sliding into the next, an accent, and one in pattern 2
+		circuitNote{1, 0, 0, 0, 36, 100, 18},
+		circuitNote{1, 0, 4, 0, 39, 100, 9},
+		circuitNote{1, 0, 5, 0, 41, 120, 3},
+		circuitNote{1, 1, 0, 0, 43, 100, 6},
+		// MIDI 1 is a higher line
+		circuitNote{2, 0, 0, 0, 72, 100, 6},
+	)
+	p, err := ParseCircuit(data, DefaultAccentThreshold)
+	if err != nil {
+		t.Fatalf("ParseCircuit() error = %v", err)
+	}
+	if p.Name != "ACID JAM" || p.Tempo != 128 || p.Length != 32 {
+		t.Errorf("pattern = %q at %g BPM, %d steps", p.Name, p.Tempo, p.Length)
+	}
+
+	var names []string
+	for _, tr := range p.Tracks {
+		names = append(names, tr.Name)
+	}
+	if got := strings.Join(names, ","); got != "Synth 2,MIDI 1,Synth 1" {
+		t.Fatalf("tracks = %s, want the bassline first and chords last", got)
+	}
+	bass := p.Tracks[0]
+	if bass.Channel != 2 || len(bass.Steps) != 32 {
+		t.Errorf("bass on channel %d with %d steps", bass.Channel, len(bass.Steps))
+	}
+	want := map[int]Step{
+		0:  {Note: 36, Gate: true, Velocity: 100},
+		1:  {Note: 36, Gate: true, Tie: true, Velocity: 100},
+		2:  {Note: 36, Gate: true, Tie: true, Velocity: 100},
+		4:  {Note: 39, Gate: true, Slide: true, Velocity: 100},
+		5:  {Note: 41, Gate: true, Accent: true, Velocity: 120},
+		16: {Note: 43, Gate: true, Velocity: 100},
+	}
+	for i, s := range bass.Steps {
+		if s != want[i] {
+			t.Errorf("bass step %d = %+v, want %+v", i+1, s, want[i])
+		}
+	}
+	if chord := p.Tracks[2].Steps[0]; chord.Note != 60 {
+		t.Errorf("chord step = %+v, want its lowest note", chord)
+	}
+
+	// Single-line devices play the bassline, and --downmix fits the chain in
+	c := New(&cappedDevice{})
+	opts := DefaultOptions()
+	opts.Downmix = DownmixDensest
+	c.SetOptions(opts)
+	pattern, err := c.ParsePattern(data, FormatCircuit)
+	if err != nil {
+		t.Fatalf("ParsePattern() error = %v", err)
+	}
+	if _, err := c.GeneratePattern(pattern, FormatSeq); err != nil {
+		t.Fatalf("GeneratePattern() error = %v", err)
+	}
+	if w := c.Warnings(); len(w) < 2 || !strings.Contains(w[0].Message, "dropped MIDI 1, Synth 1") {
+		t.Errorf("Warnings() = %v, want the dropped tracks and the downmix", w)
+	}
+	if DetectFormat("jam.ncs") != FormatCircuit || DetectFormatFromContent(data) != FormatCircuit {
+		t.Error("Circuit sessions are not detected")
+	}
+}
+
+func TestParseCircuitErrors(t *testing.T) {
+	valid := testCircuit([]string{"Synth 1"}, [][2]byte{{0, 0}})
+	corrupt := func(at int, b byte) []byte {
+		data := append([]byte(nil), valid...)
+		data[at] = b
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a session", []byte("MThd")},
+		{"truncated", valid[:100]},
+		{"no tracks", corrupt(21, 0)},
+		{"odd step count", corrupt(22, 12)},
+		{"backwards chain", corrupt(circuitHeaderSize+circuitTrackNameSize, 3)},
+		{"pattern past its steps", corrupt(circuitHeaderSize+circuitTrackHeaderSize+1, 16)},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCircuit(tt.data, DefaultAccentThreshold); err == nil {
+			t.Errorf("%s: ParseCircuit() expected error", tt.name)
+		}
+	}
+}
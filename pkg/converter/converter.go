@@ -1,11 +1,14 @@
 package converter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
 )
 
 // Format represents a file format
@@ -15,9 +18,18 @@ const (
 	FormatMIDI    Format = "midi"
 	FormatSeq     Format = "seq"
 	FormatSyx     Format = "syx"
+	Format303     Format = "303" // Written TB-303 notation (input only)
+	FormatTR      Format = "tr"  // Roland TR-8S/TR-08 pattern export (input only)
+	FormatCircuit Format = "circuit" // Novation Circuit session (input only)
 	FormatUnknown Format = "unknown"
 )
 
+// InputOnly reports whether patterns can be read from the format but not
+// written to it
+func (f Format) InputOnly() bool {
+	return f == Format303 || f == FormatTR || f == FormatCircuit
+}
+
 // DetectFormat detects the format of a file based on extension and content
 func DetectFormat(filename string) Format {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -28,6 +40,12 @@ func DetectFormat(filename string) Format {
 		return FormatSeq
 	case ".syx":
 		return FormatSyx
+	case ".303":
+		return Format303
+	case ".prm", ".tr8s":
+		return FormatTR
+	case ".ncs":
+		return FormatCircuit
 	default:
 		return FormatUnknown
 	}
@@ -44,10 +62,17 @@ func DetectFormatFromContent(data []byte) Format {
 		return FormatMIDI
 	}
 
-	// Check for SysEx (starts with F0)
+	// Check for SysEx (starts with F0), or SysEx written out as hex text
 	if data[0] == SysExStart {
 		return FormatSyx
 	}
+	if _, ok := sysex.FromText(data); ok {
+		return FormatSyx
+	}
+
+	if IsCircuitSession(data) {
+		return FormatCircuit
+	}
 
 	// Assume .seq format for other binary data
 	return FormatSeq
@@ -93,6 +118,11 @@ func (c *Converter) ConvertFile(inputPath, outputPath string) error {
 		outputData, err = c.SyxToMIDI(data)
 	case inputFormat == FormatSyx && outputFormat == FormatSeq:
 		outputData, err = c.SyxToSeq(data)
+	case (inputFormat == Format303 || inputFormat == FormatTR || inputFormat == FormatCircuit) && outputFormat != inputFormat:
+		var pattern *Pattern
+		if pattern, err = c.ParsePattern(data, inputFormat); err == nil {
+			outputData, err = c.GeneratePattern(pattern, outputFormat)
+		}
 	default:
 		return fmt.Errorf("unsupported conversion: %s to %s", inputFormat, outputFormat)
 	}
@@ -102,69 +132,335 @@ func (c *Converter) ConvertFile(inputPath, outputPath string) error {
 	}
 
 	// Write output
-	if err := os.WriteFile(outputPath, outputData, 0644); err != nil {
+	if err := WriteFileAtomic(outputPath, outputData, 0644); err != nil {
 		return fmt.Errorf("failed to write output file: %w", err)
 	}
 
 	return nil
 }
 
+// ConvertFiles converts a file to several outputs at once, e.g. a .seq and
+// a .syx, with formats taken from the output extensions. The input is parsed
+// once and every output is generated before any is written, so the outputs
+// stay consistent and a failed conversion writes none of them.
+func (c *Converter) ConvertFiles(inputPath string, outputPaths ...string) error {
+	if len(outputPaths) == 0 {
+		return errors.New("no output files given")
+	}
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+	inputFormat := DetectFormat(inputPath)
+	if inputFormat == FormatUnknown {
+		inputFormat = DetectFormatFromContent(data)
+	}
+	pattern, err := c.ParsePattern(data, inputFormat)
+	if err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+
+	formats := make([]Format, len(outputPaths))
+	for i, path := range outputPaths {
+		if formats[i] = DetectFormat(path); formats[i] == FormatUnknown {
+			return fmt.Errorf("cannot determine output format of %s", path)
+		}
+		if formats[i] == inputFormat {
+			return fmt.Errorf("unsupported conversion: %s to %s", inputFormat, formats[i])
+		}
+	}
+	outputs, err := c.GenerateFormats(pattern, formats...)
+	if err != nil {
+		return err
+	}
+
+	for i, path := range outputPaths {
+		if err := WriteFileAtomic(path, outputs[i], 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+	return nil
+}
+
+// GenerateFormats generates the pattern in each format, keeping the pattern
+// itself unchanged. Warnings collects those of every format.
+func (c *Converter) GenerateFormats(pattern *Pattern, formats ...Format) ([][]byte, error) {
+	outputs, warnings, err := c.GenerateAll(pattern, formats...)
+	c.setWarnings(warnings)
+	return outputs, err
+}
+
+// GenerateAll is GenerateFormats returning the warnings of every format
+// rather than keeping them for Warnings, so it is safe on a shared Converter
+func (c *Converter) GenerateAll(pattern *Pattern, formats ...Format) ([][]byte, []Violation, error) {
+	outputs := make([][]byte, len(formats))
+	var warnings []Violation
+	seen := make(map[string]bool)
+	for i, format := range formats {
+		data, w, err := c.Generate(pattern, format)
+		if err != nil {
+			return nil, warnings, fmt.Errorf("conversion to %s failed: %w", format, err)
+		}
+		outputs[i] = data
+		for _, v := range w {
+			if !seen[v.String()] {
+				seen[v.String()] = true
+				warnings = append(warnings, v)
+			}
+		}
+	}
+	return outputs, warnings, nil
+}
+
+// Generate generates file data in the given format from a copy of the
+// pattern, leaving the pattern itself unchanged, and returns the warnings of
+// normalizing it. Unlike GeneratePattern it keeps nothing in the Converter,
+// so it is safe on a shared Converter.
+func (c *Converter) Generate(pattern *Pattern, format Format) ([]byte, []Violation, error) {
+	return c.generate(pattern.Clone(), format)
+}
+
+// ParsePattern parses data in the given format into a Pattern, within the
+// converter's limits
+func (c *Converter) ParsePattern(data []byte, format Format) (*Pattern, error) {
+	return c.ParsePatternContext(context.Background(), data, format)
+}
+
+// parse parses data in the given format without checking limits
+func (c *Converter) parse(data []byte, format Format) (*Pattern, error) {
+	switch format {
+	case FormatMIDI:
+		return c.newMIDIConverter().ParseMIDI(data)
+	case FormatSeq, FormatSyx:
+		parse := c.device.ParseSeq
+		if format == FormatSyx {
+			parse = c.device.ParseSyx
+			data = syxData(data)
+		}
+		pattern, err := parse(data)
+		if err != nil {
+			return nil, locate(err, data)
+		}
+		return pattern, nil
+	case Format303:
+		return ParseNotation(data)
+	case FormatTR:
+		return ParseTR(data)
+	case FormatCircuit:
+		return ParseCircuit(data, c.opts.accentThreshold())
+	default:
+		return nil, fmt.Errorf("unsupported input format: %s", format)
+	}
+}
+
+// ReadPatternFile reads and parses a pattern file, detecting its format from
+// the extension or, failing that, the content
+func (c *Converter) ReadPatternFile(path string) (*Pattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	format := DetectFormat(path)
+	if format == FormatUnknown {
+		format = DetectFormatFromContent(data)
+	}
+	return c.ParsePattern(data, format)
+}
+
+// syxData returns SysEx data as bytes, decoding SysEx written out as hex
+// text (see sysex.FromText)
+func syxData(data []byte) []byte {
+	if msg, ok := sysex.FromText(data); ok {
+		return msg
+	}
+	return data
+}
+
 // MIDIToSeq converts MIDI data to .seq format
 func (c *Converter) MIDIToSeq(midiData []byte) ([]byte, error) {
-	midiConv := NewMIDIConverter()
-	pattern, err := midiConv.ParseMIDI(midiData)
+	pattern, err := c.ParsePattern(midiData, FormatMIDI)
 	if err != nil {
 		return nil, err
 	}
-	return c.device.GenerateSeq(pattern)
+	return c.GeneratePattern(pattern, FormatSeq)
 }
 
 // MIDIToSyx converts MIDI data to .syx format
 func (c *Converter) MIDIToSyx(midiData []byte) ([]byte, error) {
-	midiConv := NewMIDIConverter()
-	pattern, err := midiConv.ParseMIDI(midiData)
+	pattern, err := c.ParsePattern(midiData, FormatMIDI)
 	if err != nil {
 		return nil, err
 	}
-	return c.device.GenerateSyx(pattern)
+	return c.GeneratePattern(pattern, FormatSyx)
 }
 
 // SeqToMIDI converts .seq data to MIDI format
 func (c *Converter) SeqToMIDI(seqData []byte) ([]byte, error) {
-	pattern, err := c.device.ParseSeq(seqData)
+	pattern, err := c.ParsePattern(seqData, FormatSeq)
 	if err != nil {
 		return nil, err
 	}
-	midiConv := NewMIDIConverter()
-	return midiConv.GenerateMIDI(pattern)
+	return c.GeneratePattern(pattern, FormatMIDI)
 }
 
 // SeqToSyx converts .seq data to .syx format
 func (c *Converter) SeqToSyx(seqData []byte) ([]byte, error) {
-	pattern, err := c.device.ParseSeq(seqData)
+	pattern, err := c.ParsePattern(seqData, FormatSeq)
 	if err != nil {
 		return nil, err
 	}
-	return c.device.GenerateSyx(pattern)
+	return c.GeneratePattern(pattern, FormatSyx)
 }
 
 // SyxToMIDI converts .syx data to MIDI format
 func (c *Converter) SyxToMIDI(syxData []byte) ([]byte, error) {
-	pattern, err := c.device.ParseSyx(syxData)
+	pattern, err := c.ParsePattern(syxData, FormatSyx)
 	if err != nil {
 		return nil, err
 	}
-	midiConv := NewMIDIConverter()
-	return midiConv.GenerateMIDI(pattern)
+	return c.GeneratePattern(pattern, FormatMIDI)
 }
 
 // SyxToSeq converts .syx data to .seq format
 func (c *Converter) SyxToSeq(syxData []byte) ([]byte, error) {
-	pattern, err := c.device.ParseSyx(syxData)
+	pattern, err := c.ParsePattern(syxData, FormatSyx)
+	if err != nil {
+		return nil, err
+	}
+	return c.GeneratePattern(pattern, FormatSeq)
+}
+
+// GeneratePattern normalizes and validates a pattern in place and generates
+// file data in the given format, keeping the warnings for Warnings
+func (c *Converter) GeneratePattern(pattern *Pattern, format Format) ([]byte, error) {
+	data, warnings, err := c.generate(pattern, format)
+	c.setWarnings(warnings)
+	return data, err
+}
+
+// generate normalizes and validates a pattern in place and generates file
+// data in the given format
+func (c *Converter) generate(pattern *Pattern, format Format) ([]byte, []Violation, error) {
+	switch format {
+	case FormatMIDI:
+		return c.generateMIDI(pattern)
+	case FormatSeq:
+		return c.generateSeq(pattern)
+	case FormatSyx:
+		return c.generateSyx(pattern)
+	default:
+		return nil, nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// generateSeq normalizes and validates the pattern against the device and
+// generates .seq data
+func (c *Converter) generateSeq(pattern *Pattern) ([]byte, []Violation, error) {
+	warnings, err := c.prepare(pattern, c.device)
+	if err != nil {
+		return nil, warnings, err
+	}
+	data, err := c.device.GenerateSeq(pattern)
+	if err == nil && c.opts.VerifyRoundtrip {
+		err = c.verifyRoundtrip(pattern, data, FormatSeq)
+	}
+	return data, warnings, err
+}
+
+// generateSyx applies SysEx-specific options, normalizes and validates the
+// pattern against the device, and generates .syx data
+func (c *Converter) generateSyx(pattern *Pattern) ([]byte, []Violation, error) {
+	if c.opts.DeviceID != nil {
+		pattern.DeviceID = *c.opts.DeviceID
+	}
+	warnings, err := c.prepare(pattern, c.device)
+	if err != nil {
+		return nil, warnings, err
+	}
+	data, err := c.device.GenerateSyx(pattern)
+	if err == nil && c.opts.VerifyRoundtrip {
+		err = c.verifyRoundtrip(pattern, data, FormatSyx)
+	}
+	return data, warnings, err
+}
+
+// PatternToSlotSyx normalizes and validates a pattern and generates SysEx
+// that writes it to a memory slot of the current device
+func (c *Converter) PatternToSlotSyx(pattern *Pattern, slot int) ([]byte, error) {
+	dev, ok := c.device.(SlotDevice)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support writing pattern slots", c.device.Name())
+	}
+	if slot < 0 || slot >= dev.Slots() {
+		return nil, fmt.Errorf("slot %d out of range (0-%d)", slot, dev.Slots()-1)
+	}
+	if c.opts.DeviceID != nil {
+		pattern.DeviceID = *c.opts.DeviceID
+	}
+	warnings, err := c.prepare(pattern, c.device)
+	c.setWarnings(warnings)
 	if err != nil {
 		return nil, err
 	}
-	return c.device.GenerateSeq(pattern)
+	return dev.GenerateSyxSlot(pattern, slot)
+}
+
+// generateMIDI normalizes and validates the pattern against MIDI limits and
+// generates MIDI data
+func (c *Converter) generateMIDI(pattern *Pattern) ([]byte, []Violation, error) {
+	warnings, err := c.prepare(pattern, nil)
+	if err != nil {
+		return nil, warnings, err
+	}
+	data, err := c.newMIDIConverter().GenerateMIDI(pattern)
+	return data, warnings, err
+}
+
+// prepare folds, downmixes, transposes, normalizes, and validates a pattern
+// against dev (nil for MIDI limits) before generating it, returning the
+// warnings of fitting and normalizing it. In strict mode any warning fails.
+func (c *Converter) prepare(pattern *Pattern, dev Device) ([]Violation, error) {
+	// Devices that play one line take a multi-track pattern's first line,
+	// and long patterns are fitted into the device's steps
+	var fitted []Violation
+	if _, drums := dev.(DrumDevice); dev != nil && !drums {
+		if dropped := pattern.SingleTrack(); len(dropped) > 0 {
+			fitted = append(fitted, Violation{Step: -1, Field: "tracks", Severity: SeverityWarning,
+				Message: fmt.Sprintf("%s plays one track; dropped %s", dev.Name(), strings.Join(dropped, ", "))})
+		}
+	}
+	if dev != nil && c.opts.Downmix != "" {
+		fitted = append(fitted, pattern.Downmix(c.opts.Downmix, DeviceCapabilities(dev).MaxSteps)...)
+	}
+	if err := pattern.Transpose(c.opts.Transpose); err != nil {
+		return nil, err
+	}
+	warnings := append(fitted, pattern.Normalize()...)
+	if !c.opts.Strict {
+		return warnings, validate(pattern, dev)
+	}
+	violations := append(append([]Violation(nil), warnings...), pattern.Validate(dev)...)
+	if len(violations) > 0 {
+		return warnings, &ValidationError{Violations: violations}
+	}
+	return warnings, nil
+}
+
+// newMIDIConverter returns a MIDI converter configured with the converter options
+func (c *Converter) newMIDIConverter() *MIDIConverter {
+	midiConv := NewMIDIConverter()
+	midiConv.SetOptions(c.opts)
+	if drums, ok := c.device.(DrumDevice); ok {
+		midiConv.SetDrumLanes(drums.DrumLanes(), DeviceCapabilities(c.device).MaxSteps)
+	}
+	if layers, ok := c.device.(AccentLayerDevice); ok {
+		midiConv.SetAccentLayers(layers.AccentLayers())
+	}
+	if tracks, ok := c.device.(LaneTrackDevice); ok {
+		midiConv.SetLaneTracks(tracks.LaneTracks())
+	}
+	return midiConv
 }
 
 // GetSupportedConversions returns a list of supported conversion paths
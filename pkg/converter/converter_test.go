@@ -1,7 +1,18 @@
 package converter
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+
+	"gitlab.com/gomidi/midi/v2/smf"
 )
 
 func TestDetectFormat(t *testing.T) {
@@ -174,3 +185,420 @@ func TestStepDefaults(t *testing.T) {
 		t.Errorf("Default Velocity = %d, want 0", step.Velocity)
 	}
 }
+
+func TestGenerateMIDIGateTracks(t *testing.T) {
+	pattern := &Pattern{
+		Name:  "Gates",
+		Tempo: 120.0,
+		Steps: []Step{
+			{Note: 48, Gate: true, Velocity: 100},
+			{Note: 50, Gate: true, Accent: true, Velocity: 127},
+			{Note: 0, Gate: false},
+			{Note: 52, Gate: true, Slide: true, Velocity: 100},
+			{Note: 53, Gate: true, Velocity: 100},
+		},
+	}
+
+	opts := DefaultOptions()
+	opts.GateTrack = true
+	opts.AccentTrack = true
+
+	midiConv := NewMIDIConverter()
+	midiConv.SetOptions(opts)
+	data, err := midiConv.GenerateMIDI(pattern)
+	if err != nil {
+		t.Fatalf("GenerateMIDI() error = %v", err)
+	}
+
+	s, err := smf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to read generated MIDI: %v", err)
+	}
+	if len(s.Tracks) != 3 {
+		t.Fatalf("track count = %d, want 3", len(s.Tracks))
+	}
+
+	countNoteOns := func(track smf.Track, note uint8) (count int, accents int) {
+		for _, ev := range track {
+			var ch, key, vel uint8
+			if ev.Message.GetNoteStart(&ch, &key, &vel) {
+				if key != note {
+					t.Errorf("trigger note = %d, want %d", key, note)
+				}
+				count++
+				if vel == 127 {
+					accents++
+				}
+			}
+		}
+		return count, accents
+	}
+
+	gates, gateAccents := countNoteOns(s.Tracks[1], DefaultGateNote)
+	if gates != 4 || gateAccents != 1 {
+		t.Errorf("gate track has %d notes (%d accented), want 4 (1 accented)", gates, gateAccents)
+	}
+
+	accents, _ := countNoteOns(s.Tracks[2], DefaultAccentNote)
+	if accents != 1 {
+		t.Errorf("accent track has %d notes, want 1", accents)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := &Pattern{
+		Tempo: 120.0,
+		Steps: []Step{
+			{Note: 48, Gate: true},
+			{Note: 50, Gate: true, Accent: true},
+			{Note: 12, Gate: false},
+		},
+	}
+	b := &Pattern{
+		Tempo: 130.0,
+		Steps: []Step{
+			{Note: 48, Gate: true},
+			{Note: 51, Gate: true, Slide: true},
+			{Note: 99, Gate: false},
+			{Note: 55, Gate: true},
+		},
+	}
+
+	want := []Difference{
+		{Step: -1, Field: "length", Before: 3, After: 4},
+		{Step: -1, Field: "tempo", Before: 120.0, After: 130.0},
+		{Step: 1, Field: "note", Before: uint8(50), After: uint8(51)},
+		{Step: 1, Field: "accent", Before: true, After: false},
+		{Step: 1, Field: "slide", Before: false, After: true},
+		{Step: 3, Field: "gate", Before: false, After: true},
+	}
+
+	got := Diff(a, b)
+	if len(got) != len(want) {
+		t.Fatalf("Diff() returned %d differences, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Diff()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if diffs := Diff(a, a); len(diffs) != 0 {
+		t.Errorf("Diff(a, a) = %v, want no differences", diffs)
+	}
+}
+
+// cappedDevice is a mockDevice that declares TD-3-like limits
+type cappedDevice struct{ mockDevice }
+
+func (c *cappedDevice) Capabilities() Capabilities {
+	return Capabilities{MaxSteps: 4, MinNote: 24, MaxNote: 96}
+}
+
+func TestPatternValidate(t *testing.T) {
+	pattern := &Pattern{
+		Steps: []Step{
+			{Note: 48, Gate: true, Tie: true, Velocity: 100},
+			{Note: 12, Gate: true, Velocity: 100},
+			{Note: 0, Gate: false, Tie: true},
+			{Note: 100, Gate: true, Velocity: 200},
+			{Note: 48, Gate: true, Velocity: 100},
+		},
+	}
+
+	violations := pattern.Validate(&cappedDevice{})
+
+	want := []string{
+		"length: 5 steps exceeds device maximum of 4",
+		"step 1 tie: first step cannot be tied",
+		"step 2 note: 12 is outside device range 24-96",
+		"step 3 tie: tie on a rest",
+		"step 4 velocity: 200 is outside 0-127",
+		"step 4 note: 100 is outside device range 24-96",
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("Validate() returned %d violations, want %d: %v", len(violations), len(want), violations)
+	}
+	for i, v := range violations {
+		if v.String() != want[i] {
+			t.Errorf("violation %d = %q, want %q", i, v.String(), want[i])
+		}
+	}
+
+	// Generation fails early on errors but not on warnings
+	conv := New(&cappedDevice{})
+	if _, _, err := conv.generateSeq(pattern); err == nil {
+		t.Error("generateSeq() expected validation error")
+	} else if verr, ok := err.(*ValidationError); !ok || len(verr.Violations) != 4 {
+		t.Errorf("generateSeq() error = %v, want ValidationError with 4 violations", err)
+	}
+
+	valid := &Pattern{Steps: []Step{{Note: 48, Gate: true, Velocity: 100}, {Gate: false, Tie: true}}}
+	if _, _, err := conv.generateSeq(valid); err != nil {
+		t.Errorf("generateSeq() unexpected error for warnings only: %v", err)
+	}
+}
+
+func TestPatternNormalize(t *testing.T) {
+	pattern := &Pattern{
+		Steps: []Step{
+			{Note: 48, Gate: true, Tie: true},
+			{Note: 50, Gate: true, Tie: true},
+			{Note: 0, Gate: false, Tie: true, Accent: true},
+			{Note: 52, Gate: true, Tie: true, Slide: true},
+			{Note: 0, Gate: false},
+		},
+	}
+
+	warnings := pattern.Normalize()
+
+	want := []Step{
+		{Note: 48, Gate: true},
+		{Note: 48, Gate: true, Tie: true},
+		{Note: 0, Gate: false},
+		{Note: 52, Gate: true},
+		{Note: 0, Gate: false},
+	}
+	for i := range want {
+		if pattern.Steps[i] != want[i] {
+			t.Errorf("step %d = %+v, want %+v", i, pattern.Steps[i], want[i])
+		}
+	}
+	if len(warnings) != 6 {
+		t.Errorf("Normalize() returned %d warnings, want 6: %v", len(warnings), warnings)
+	}
+
+	// A canonical pattern is left untouched
+	if again := pattern.Normalize(); len(again) != 0 {
+		t.Errorf("second Normalize() returned warnings: %v", again)
+	}
+}
+
+// multiTrackPattern has two melodic lines on their own channels and a kick
+func multiTrackPattern() *Pattern {
+	p := &Pattern{Tempo: 120, Tracks: []Track{
+		{Name: "Bass", Channel: 2, Steps: make([]Step, 16)},
+		{Name: "Lead", Steps: make([]Step, 16)},
+		{Name: "BD", Note: 36, Steps: make([]Step, 16)},
+	}}
+	for i := 0; i < 16; i += 4 {
+		p.Tracks[0].Steps[i] = Step{Note: 36, Gate: true, Velocity: 100}
+		p.Tracks[1].Steps[i+2] = Step{Note: 60, Gate: true, Velocity: 100}
+		p.Tracks[2].Steps[i] = Step{Note: 36, Gate: true, Velocity: 100}
+	}
+	return p
+}
+
+func TestGenerateMIDIMultiTrack(t *testing.T) {
+	pattern := multiTrackPattern()
+	if err := pattern.Transpose(12); err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewMIDIConverter().GenerateMIDI(pattern)
+	if err != nil {
+		t.Fatalf("GenerateMIDI() error = %v", err)
+	}
+	s, err := smf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drum hits share the tempo track; each line is a track of its own on
+	// its channel, transposed while the drums are not
+	if len(s.Tracks) != 3 {
+		t.Fatalf("track count = %d, want 3", len(s.Tracks))
+	}
+	for _, tt := range []struct {
+		track   int
+		channel uint8
+		key     uint8
+	}{
+		{0, DrumChannel, 36},
+		{1, 1, 48},
+		{2, 0, 72},
+	} {
+		hits := 0
+		for _, ev := range s.Tracks[tt.track] {
+			var channel, key, velocity uint8
+			if ev.Message.GetNoteStart(&channel, &key, &velocity) {
+				if channel != tt.channel || key != tt.key {
+					t.Errorf("track %d plays note %d on channel %d, want %d on %d", tt.track, key, channel, tt.key, tt.channel)
+				}
+				hits++
+			}
+		}
+		if hits != 4 {
+			t.Errorf("track %d has %d notes, want 4", tt.track, hits)
+		}
+	}
+}
+
+func TestPatternSingleTrack(t *testing.T) {
+	// Single-line devices play the first melodic track and warn about the rest
+	pattern := multiTrackPattern()
+	warnings, err := New(&mockDevice{}).prepare(pattern, &mockDevice{})
+	if err != nil {
+		t.Fatalf("prepare() error = %v", err)
+	}
+	if len(pattern.Tracks) != 0 || pattern.Length != 16 || pattern.Steps[0].Note != 36 || !pattern.Steps[0].Gate {
+		t.Errorf("prepare() left %d tracks, steps %v", len(pattern.Tracks), pattern.Steps)
+	}
+	if len(warnings) != 1 || warnings[0].Field != "tracks" || !strings.Contains(warnings[0].Message, "Lead, BD") {
+		t.Errorf("prepare() warnings = %v, want the dropped tracks", warnings)
+	}
+
+	// Patterns with their own steps keep their tracks
+	kept := &Pattern{Steps: []Step{{Note: 48, Gate: true}}, Tracks: []Track{{Name: "Lead"}}}
+	if dropped := kept.SingleTrack(); dropped != nil || len(kept.Tracks) != 1 {
+		t.Errorf("SingleTrack() = %v, left %d tracks", dropped, len(kept.Tracks))
+	}
+
+	bad := multiTrackPattern()
+	bad.Tracks[0].Channel = 17
+	if err := validate(bad, nil); err == nil || !strings.Contains(err.Error(), "Bass channel") {
+		t.Errorf("validate() = %v, want channel error", err)
+	}
+}
+
+func TestConvertFiles(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "line.303")
+	if err := os.WriteFile(input, []byte("note C D# G C'\ntime o o - o\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conv := New(&mockDevice{})
+
+	outputs := []string{filepath.Join(dir, "line.seq"), filepath.Join(dir, "line.syx"), filepath.Join(dir, "line.mid")}
+	if err := conv.ConvertFiles(input, outputs...); err != nil {
+		t.Fatalf("ConvertFiles() error = %v", err)
+	}
+	for _, path := range outputs {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("output %s not written: %v", filepath.Base(path), err)
+		}
+	}
+
+	// One bad output stops all of them
+	partial := []string{filepath.Join(dir, "other.seq"), filepath.Join(dir, "other.txt")}
+	if err := conv.ConvertFiles(input, partial...); err == nil {
+		t.Error("ConvertFiles() with unknown output format succeeded")
+	}
+	if _, err := os.Stat(partial[0]); !os.IsNotExist(err) {
+		t.Error("ConvertFiles() wrote an output despite failing")
+	}
+	if err := conv.ConvertFiles(input); err == nil {
+		t.Error("ConvertFiles() without outputs succeeded")
+	}
+}
+
+// sysexOnlyDevice parses SysEx only from bytes, as real devices do
+type sysexOnlyDevice struct{ mockDevice }
+
+func (d *sysexOnlyDevice) ParseSyx(data []byte) (*Pattern, error) {
+	if len(data) == 0 || data[0] != SysExStart {
+		return nil, errors.New("not a SysEx message")
+	}
+	return d.mockDevice.ParseSyx(data)
+}
+
+func TestConvertFileHexText(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "paste.txt")
+	if err := os.WriteFile(input, []byte("# pasted from a forum\nF0 00 20 32 F7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conv := New(&sysexOnlyDevice{})
+
+	for _, output := range []string{"out.mid", "out.seq"} {
+		if err := conv.ConvertFile(input, filepath.Join(dir, output)); err != nil {
+			t.Errorf("ConvertFile(%s) error = %v", output, err)
+		}
+	}
+	if _, err := conv.ParsePattern([]byte("F0 00 20 32 F7"), FormatSyx); err != nil {
+		t.Errorf("ParsePattern() of hex text error = %v", err)
+	}
+}
+
+func TestConverterConcurrent(t *testing.T) {
+	// MIDI files of different resolutions and tempos, each converted once on
+	// its own converter for the results to expect
+	opts := ConvertOptions{Transpose: 12, GateNote: DefaultGateNote, AccentNote: DefaultAccentNote}
+	type input struct {
+		midi     []byte
+		pattern  *Pattern
+		outputs  [][]byte
+		warnings []Violation
+	}
+	inputs := make([]input, 8)
+	for i := range inputs {
+		m := NewMIDIConverter()
+		if i%2 == 1 {
+			m.ticksPerQuarter = 96
+		}
+		p := &Pattern{Tempo: float64(100 + 10*i), Length: 16, Steps: []Step{{Note: uint8(40 + i), Gate: true, Velocity: 100}}}
+		data, err := m.GenerateMIDI(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := input{midi: data}
+		conv := New(&mockDevice{})
+		conv.SetOptions(opts)
+		if in.pattern, err = conv.ParsePattern(data, FormatMIDI); err != nil {
+			t.Fatal(err)
+		}
+		if in.outputs, in.warnings, err = conv.GenerateAll(in.pattern, FormatMIDI, FormatSeq); err != nil {
+			t.Fatal(err)
+		}
+		inputs[i] = in
+	}
+
+	conv := New(&mockDevice{})
+	conv.SetOptions(opts)
+	var wg sync.WaitGroup
+	errs := make(chan error, len(inputs))
+	for _, in := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				p, err := conv.ParsePattern(in.midi, FormatMIDI)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(p, in.pattern) {
+					errs <- fmt.Errorf("parsed %+v, want %+v", p, in.pattern)
+					return
+				}
+				outputs, warnings, err := conv.GenerateAll(p, FormatMIDI, FormatSeq)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(outputs, in.outputs) || !reflect.DeepEqual(warnings, in.warnings) {
+					errs <- fmt.Errorf("outputs or warnings %v differ from a sequential conversion's %v", warnings, in.warnings)
+					return
+				}
+				if !reflect.DeepEqual(p, in.pattern) {
+					errs <- errors.New("GenerateAll changed the caller's pattern")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestPatternLegacyLanes(t *testing.T) {
+	var p Pattern
+	if err := json.Unmarshal([]byte(`{"Name":"Old","Lanes":[{"Name":"BD","Note":36,"Steps":[{"Gate":true}]}]}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Old" || len(p.Tracks) != 1 || p.Tracks[0].Name != "BD" || !p.IsDrum() {
+		t.Errorf("decoded %+v, want the BD lane as a track", p)
+	}
+}
@@ -0,0 +1,224 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// Edge device constants
+const (
+	EdgeModelID  = 0x07 // Edge model ID in Behringer SysEx
+	EdgeMaxSteps = 32
+
+	// Edge SEQ file offsets: the TD-3 container header followed by the
+	// nibble-encoded pattern length, then a velocity byte (0 = no hit) for
+	// each of the 32 steps of each voice
+	edgeLengthOffset = NotesOffset                      // 36
+	edgeVoiceOffset  = edgeLengthOffset + 2             // 38
+	EdgeSeqSize      = edgeVoiceOffset + 2*EdgeMaxSteps // 102
+)
+
+// edgeSeqName is the device name stored in Edge .seq headers
+const edgeSeqName = "EDGE"
+
+// edgeVoices are the Edge's two voices, with the General MIDI drum notes
+// they play on channel 10
+var edgeVoices = []converter.Track{
+	{Name: "VCO1", Note: 36}, // bass drum
+	{Name: "VCO2", Note: 38}, // snare drum
+}
+
+// edgeSyxLayout is the Edge pattern dump body: device ID, model ID and
+// command header, pattern length and the velocity data, then an XOR checksum
+var edgeSyxLayout = sysex.Layout{HeaderLen: 3, Checksum: sysex.XOR}
+
+func init() {
+	RegisterInfo(Info{
+		ID:          "edge",
+		Name:        "Behringer Edge",
+		Description: "Semi-modular percussion synth (2 voices, 32 steps with velocity)",
+		New:         func() converter.Device { return NewEdge() },
+	})
+	sysex.RegisterModel(sysex.Model{Manufacturer: sysex.Behringer, ID: EdgeModelID, Name: "Behringer Edge", Device: "edge"})
+}
+
+// Edge implements the Device interface for the Behringer Edge. Its patterns
+// are two lanes of hits, one per voice, each hit with its own velocity; in
+// MIDI, each voice is a track of its own.
+type Edge struct{}
+
+// NewEdge creates a new Edge device handler
+func NewEdge() *Edge {
+	return &Edge{}
+}
+
+// Name returns the device name
+func (e *Edge) Name() string {
+	return "Behringer Edge"
+}
+
+// ID returns the device ID
+func (e *Edge) ID() uint8 {
+	return 0
+}
+
+// Capabilities returns the pattern limits
+func (e *Edge) Capabilities() converter.Capabilities {
+	return converter.Capabilities{
+		MaxSteps: EdgeMaxSteps,
+		MinNote:  0,
+		MaxNote:  127,
+		Defaults: drumDefaults,
+	}
+}
+
+// DrumLanes returns the voices and their MIDI drum notes
+func (e *Edge) DrumLanes() []converter.Track {
+	return slices.Clone(edgeVoices)
+}
+
+// LaneTracks reports that Edge MIDI files hold a track per voice
+func (e *Edge) LaneTracks() bool {
+	return true
+}
+
+// SysExLayout returns the pattern dump layout
+func (e *Edge) SysExLayout() sysex.Layout {
+	return edgeSyxLayout
+}
+
+// ParseSeq parses a .seq file into a drum Pattern
+func (e *Edge) ParseSeq(data []byte) (*converter.Pattern, error) {
+	if len(data) < EdgeSeqSize {
+		return nil, converter.ErrorfAt(len(data), "pattern data", "seq data too short: got %d bytes, need at least %d", len(data), EdgeSeqSize)
+	}
+	if !bytes.HasPrefix(data, td3HeaderMagic) {
+		return nil, converter.ErrorAt(0, "magic bytes", errors.New("invalid Edge seq file: wrong magic bytes"))
+	}
+	if name := seqDeviceName(data); name != edgeSeqName {
+		return nil, converter.ErrorfAt(seqNameOffset, "device name", "not an Edge seq file: device name %q", name)
+	}
+
+	length := int(data[edgeLengthOffset])*16 + int(data[edgeLengthOffset+1])
+	if length == 0 || length > EdgeMaxSteps {
+		length = EdgeMaxSteps
+	}
+	return e.pattern("Edge Pattern", length, EdgeMaxSteps, data[edgeVoiceOffset:]), nil
+}
+
+// GenerateSeq generates .seq data from a Pattern
+func (e *Edge) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	length, tracks := voiceTracks(edgeVoices, pattern, EdgeMaxSteps)
+
+	data := make([]byte, EdgeSeqSize)
+	copy(data, edgeSeqHeader[:])
+	data[edgeLengthOffset] = byte(length / 16)
+	data[edgeLengthOffset+1] = byte(length % 16)
+	putEdgeVelocities(data[edgeVoiceOffset:], tracks, EdgeMaxSteps)
+	return data, nil
+}
+
+// ParseSyx parses a pattern dump
+func (e *Edge) ParseSyx(data []byte) (*converter.Pattern, error) {
+	if !sysex.HasManufacturer(data, sysex.Behringer) || len(data) < 7 || data[5] != EdgeModelID {
+		return nil, converter.ErrorfAt(min(5, len(data)), "model ID", "not an Edge pattern dump: %s", sysex.Describe(data))
+	}
+	msg, err := sysex.Parse(data, e.SysExLayout())
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Payload) < 1 {
+		return nil, converter.ErrorAt(msg.PayloadOffset(), "pattern length", errors.New("no payload in Edge pattern dump"))
+	}
+
+	length := int(msg.Payload[0])
+	if length == 0 || length > EdgeMaxSteps {
+		return nil, converter.ErrorfAt(msg.PayloadOffset(), "pattern length", "pattern length %d out of range (1-%d)", length, EdgeMaxSteps)
+	}
+	if need := 1 + len(edgeVoices)*length; len(msg.Payload) < need {
+		return nil, converter.ErrorfAt(msg.PayloadOffset()+len(msg.Payload), "pattern data", "syx data too short: got %d payload bytes, need %d", len(msg.Payload), need)
+	}
+	pattern := e.pattern("Edge SysEx Pattern", length, length, msg.Payload[1:])
+	pattern.DeviceID = msg.Header[0]
+	return pattern, nil
+}
+
+// GenerateSyx generates a pattern dump; dumps hold only the pattern's steps
+func (e *Edge) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	length, tracks := voiceTracks(edgeVoices, pattern, EdgeMaxSteps)
+
+	payload := make([]byte, len(edgeVoices)*length)
+	putEdgeVelocities(payload, tracks, length)
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, EdgeModelID, PatternDump).
+		Payload(byte(length)).
+		Payload(payload...).
+		Checksum(e.SysExLayout().Checksum).
+		Build()
+}
+
+// pattern builds a drum pattern of length steps from velocity data holding
+// stride steps per voice. Hits at or above the default accent threshold
+// are accented and those below the default ghost threshold are ghost notes,
+// so other devices keep the Edge's loud and soft hits.
+func (e *Edge) pattern(name string, length, stride int, data []byte) *converter.Pattern {
+	pattern := &converter.Pattern{
+		Name:   name,
+		Length: length,
+		Tempo:  120.0,
+		Tracks: e.DrumLanes(),
+	}
+	for v := range pattern.Tracks {
+		lane := &pattern.Tracks[v]
+		lane.Steps = make([]converter.Step, length)
+		for i := range lane.Steps {
+			velocity := data[v*stride+i] & 0x7F
+			if velocity == 0 {
+				continue
+			}
+			lane.Steps[i] = converter.Step{
+				Note:     lane.Note,
+				Gate:     true,
+				Velocity: velocity,
+				Accent:   velocity >= converter.DefaultAccentThreshold,
+				Ghost:    velocity < converter.DefaultGhostThreshold,
+			}
+		}
+	}
+	return pattern
+}
+
+// putEdgeVelocities writes the hits of each voice as velocity data of stride
+// steps. Hits without a velocity play at 100, accented hits too soft to read
+// as accents at 127, and ghost notes too loud to read as such at the ghost
+// velocity.
+func putEdgeVelocities(data []byte, tracks [][]converter.Step, stride int) {
+	for v, steps := range tracks {
+		for i, s := range steps {
+			if !s.Gate {
+				continue
+			}
+			velocity := min(s.Velocity, 127)
+			if velocity == 0 {
+				velocity = 100
+			}
+			switch {
+			case s.Accent && velocity < converter.DefaultAccentThreshold:
+				velocity = 127
+			case s.Ghost && !s.Accent && velocity >= converter.DefaultGhostThreshold:
+				velocity = converter.GhostVelocity
+			}
+			data[v*stride+i] = velocity
+		}
+	}
+}
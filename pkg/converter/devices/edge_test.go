@@ -0,0 +1,124 @@
+package devices
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// edgePattern is a 32-step Edge pattern with a kick every beat at rising
+// velocities, from ghost notes to accents, and an off-beat second voice
+func edgePattern() *converter.Pattern {
+	p := &converter.Pattern{Length: EdgeMaxSteps, Tracks: NewEdge().DrumLanes()}
+	for v := range p.Tracks {
+		p.Tracks[v].Steps = make([]converter.Step, EdgeMaxSteps)
+	}
+	for i := 0; i < EdgeMaxSteps; i += 4 {
+		velocity := uint8(40 + i*2)
+		p.Tracks[0].Steps[i] = converter.Step{Note: 36, Gate: true, Velocity: velocity,
+			Accent: velocity >= converter.DefaultAccentThreshold, Ghost: velocity < converter.DefaultGhostThreshold}
+		p.Tracks[1].Steps[i+2] = converter.Step{Note: 38, Gate: true, Velocity: 90}
+	}
+	return p
+}
+
+func TestEdgeRoundTrip(t *testing.T) {
+	d := NewEdge()
+	pattern := edgePattern()
+	for _, tt := range []struct {
+		name     string
+		generate func(*converter.Pattern) ([]byte, error)
+		parse    func([]byte) (*converter.Pattern, error)
+	}{
+		{"seq", d.GenerateSeq, d.ParseSeq},
+		{"syx", d.GenerateSyx, d.ParseSyx},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.generate(pattern)
+			if err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			got, err := tt.parse(data)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if got.Length != EdgeMaxSteps || len(got.Tracks) != 2 {
+				t.Fatalf("got length %d, %d lanes", got.Length, len(got.Tracks))
+			}
+			for v, lane := range pattern.Tracks {
+				for i, want := range lane.Steps {
+					if got.Tracks[v].Steps[i] != want {
+						t.Errorf("%s step %d = %+v, want %+v", lane.Name, i+1, got.Tracks[v].Steps[i], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEdgeMIDI(t *testing.T) {
+	pattern := edgePattern()
+	seq, err := NewEdge().GenerateSeq(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv := converter.New(NewEdge())
+	mid, err := conv.SeqToMIDI(seq)
+	if err != nil {
+		t.Fatalf("SeqToMIDI() error = %v", err)
+	}
+
+	// A tempo track, then one track per voice holding only its notes
+	s, err := smf.ReadFrom(bytes.NewReader(mid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Tracks) != 3 {
+		t.Fatalf("got %d tracks, want 3", len(s.Tracks))
+	}
+	for v, lane := range pattern.Tracks {
+		hits := 0
+		for _, ev := range s.Tracks[v+1] {
+			var channel, key, velocity uint8
+			if ev.Message.GetNoteStart(&channel, &key, &velocity) {
+				if key != lane.Note {
+					t.Errorf("%s track plays note %d, want %d", lane.Name, key, lane.Note)
+				}
+				hits++
+			}
+		}
+		if hits != EdgeMaxSteps/4 {
+			t.Errorf("%s track has %d hits, want %d", lane.Name, hits, EdgeMaxSteps/4)
+		}
+	}
+
+	back, err := conv.MIDIToSeq(mid)
+	if err != nil {
+		t.Fatalf("MIDIToSeq() error = %v", err)
+	}
+	got, err := NewEdge().ParseSeq(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for v, lane := range pattern.Tracks {
+		for i, want := range lane.Steps {
+			if g := got.Tracks[v].Steps[i]; g != want {
+				t.Errorf("%s step %d = %+v, want %+v", lane.Name, i+1, g, want)
+			}
+		}
+	}
+}
+
+func TestEdgeWrongDevice(t *testing.T) {
+	seq, _ := NewEdge().GenerateSeq(edgePattern())
+	if _, err := NewTD3().ParseSeq(seq); err == nil || !strings.Contains(err.Error(), "--device edge") {
+		t.Errorf("TD3.ParseSeq(Edge seq) error = %v, want hint", err)
+	}
+	rd8Syx, _ := NewRD8().GenerateSyx(rdxPattern(NewRD8()))
+	if _, err := NewEdge().ParseSyx(rd8Syx); err == nil || !strings.Contains(err.Error(), "--device rd8") {
+		t.Errorf("Edge.ParseSyx(RD-8 dump) error = %v, want hint", err)
+	}
+}
@@ -0,0 +1,39 @@
+package devices
+
+// seqHeader is the fixed start of a SynthTribe .seq file, up to the notes
+type seqHeader [NotesOffset]byte
+
+// Header templates, built once: generating a .seq file copies its device's
+// template instead of writing the header byte by byte. Arrays are copied by
+// value, so the templates cannot be changed through a generated file.
+var (
+	// The TD-3 header's fill length is 112, as written by SynthTribe,
+	// although fewer bytes follow
+	td3SeqHeader  = newSeqHeader("TD-3", 0x70)
+	ms1SeqHeader  = newSeqHeader(ms1SeqName, MS1SeqSize-NotesOffset)
+	rd6SeqHeader  = newSeqHeader(rd6SeqName, RD6SoftSeqSize-NotesOffset)
+	rd8SeqHeader  = newSeqHeader(rd8.seqName, rd8.seqSize()-NotesOffset)
+	rd9SeqHeader  = newSeqHeader(rd9.seqName, rd9.seqSize()-NotesOffset)
+	edgeSeqHeader = newSeqHeader(edgeSeqName, EdgeSeqSize-NotesOffset)
+	// TD-3-MO files keep the TD-3 name so that SynthTribe still opens them
+	td3moSeqHeader = newSeqHeader("TD-3", TD3MOSeqSize-NotesOffset)
+)
+
+// newSeqHeader builds a .seq header: the magic bytes, then device name and
+// version as length-prefixed UTF-16, then the number of bytes that follow.
+// Only 4-character device names fit the 32-byte header.
+func newSeqHeader(name string, remaining int) seqHeader {
+	var h seqHeader
+	copy(h[:], td3HeaderMagic)
+	h[7] = byte(len(name) * 2)
+	for i := 0; i < len(name); i++ {
+		h[9+i*2] = name[i]
+	}
+	h[19] = 0x0a
+	for i, c := range []byte("1.3.7") {
+		h[21+i*2] = c
+	}
+	h[32] = byte(remaining >> 8)
+	h[33] = byte(remaining)
+	return h
+}
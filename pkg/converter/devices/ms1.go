@@ -0,0 +1,251 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// MS-1 device constants
+const (
+	MS1ModelID  = 0x02 // MS-1 model ID in Behringer SysEx
+	MS1MaxSteps = 32
+
+	// MS-1 SEQ file offsets: the TD-3 container header followed by notes, the
+	// sequence length, and 32-bit tie and rest masks, all nibble-encoded
+	ms1NotesOffset  = NotesOffset                    // 36
+	ms1LengthOffset = ms1NotesOffset + MS1MaxSteps*2 // 100
+	ms1TieOffset    = ms1LengthOffset + 2            // 102
+	ms1RestOffset   = ms1TieOffset + 8               // 110
+	MS1SeqSize      = ms1RestOffset + 8              // 118
+)
+
+// K-2 model ID in Behringer SysEx. The K-2 has no step sequencer, so it is
+// only registered for identification.
+const K2ModelID = 0x03
+
+// ms1SeqName is the device name stored in MS-1 .seq headers
+const ms1SeqName = "MS-1"
+
+// ms1SyxLayout is the MS-1 sequence dump body: device ID, model ID and
+// command header, sequence length and step payload, then an XOR checksum
+var ms1SyxLayout = sysex.Layout{HeaderLen: 3, Checksum: sysex.XOR}
+
+func init() {
+	RegisterInfo(Info{
+		ID:          "ms1",
+		Aliases:     []string{"ms-1"},
+		Name:        "Behringer MS-1",
+		Description: "SH-101 clone (32-step sequencer)",
+		New:         func() converter.Device { return NewMS1() },
+	})
+	sysex.RegisterModel(sysex.Model{Manufacturer: sysex.Behringer, ID: MS1ModelID, Name: "Behringer MS-1", Device: "ms1"})
+	sysex.RegisterModel(sysex.Model{Manufacturer: sysex.Behringer, ID: K2ModelID, Name: "Behringer K-2"})
+}
+
+// MS1 implements the Device interface for the Behringer MS-1 step sequencer.
+// The MS-1 sequencer has rests and ties but no accent or slide, so those
+// flags are dropped on export; arpeggiator settings are not converted.
+type MS1 struct{}
+
+// NewMS1 creates a new MS-1 device handler
+func NewMS1() *MS1 {
+	return &MS1{}
+}
+
+// Name returns the device name
+func (m *MS1) Name() string {
+	return "Behringer MS-1"
+}
+
+// ID returns the device ID
+func (m *MS1) ID() uint8 {
+	return 0
+}
+
+// Capabilities returns the MS-1 sequence limits. Notes are stored relative to
+// MIDI note 24, as on the TD-3.
+func (m *MS1) Capabilities() converter.Capabilities {
+	return converter.Capabilities{
+		MaxSteps: MS1MaxSteps,
+		MinNote:  24,
+		MaxNote:  127,
+		// The MS-1 sequencer has no slides to read from MIDI
+		Defaults: converter.Defaults{SlideMode: converter.SlideNone},
+		Lossy:    []string{"accent", "slide"},
+	}
+}
+
+// SysExLayout returns the MS-1 sequence dump layout
+func (m *MS1) SysExLayout() sysex.Layout {
+	return ms1SyxLayout
+}
+
+// ParseSeq parses an MS-1 .seq file into a Pattern
+func (m *MS1) ParseSeq(data []byte) (*converter.Pattern, error) {
+	if len(data) < MS1SeqSize {
+		return nil, converter.ErrorfAt(len(data), "pattern data", "seq data too short: got %d bytes, need at least %d", len(data), MS1SeqSize)
+	}
+	if !bytes.HasPrefix(data, td3HeaderMagic) {
+		return nil, converter.ErrorfAt(0, "magic bytes", "invalid MS-1 seq file: wrong magic bytes")
+	}
+	if name := seqDeviceName(data); name != ms1SeqName {
+		return nil, converter.ErrorfAt(seqNameOffset, "device name", "not an MS-1 seq file: device name %q", name)
+	}
+
+	length := int(data[ms1LengthOffset])*16 + int(data[ms1LengthOffset+1])
+	if length == 0 || length > MS1MaxSteps {
+		length = MS1MaxSteps
+	}
+	tie := nibbleMask(data[ms1TieOffset : ms1TieOffset+8])
+	rest := nibbleMask(data[ms1RestOffset : ms1RestOffset+8])
+
+	pattern := &converter.Pattern{
+		Name:   "MS-1 Sequence",
+		Steps:  make([]converter.Step, length),
+		Length: length,
+		Tempo:  120.0,
+	}
+	for i := range pattern.Steps {
+		note := int(data[ms1NotesOffset+i*2])*16 + int(data[ms1NotesOffset+i*2+1])
+		pattern.Steps[i] = converter.Step{
+			Note:     uint8(min(note+24, 127)),
+			Gate:     rest&(1<<i) == 0,
+			Tie:      tie&(1<<i) == 0 && i > 0, // 0 means sustain, as on the TD-3
+			Velocity: 100,
+		}
+	}
+	return pattern, nil
+}
+
+// GenerateSeq generates MS-1 .seq data from a Pattern
+func (m *MS1) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+
+	data := make([]byte, MS1SeqSize)
+	copy(data, ms1SeqHeader[:])
+
+	length := min(len(pattern.Steps), MS1MaxSteps)
+	var tie, rest uint32
+	for i := 0; i < MS1MaxSteps; i++ {
+		var step converter.Step
+		if i < len(pattern.Steps) {
+			step = pattern.Steps[i]
+		}
+		note := max(int(step.Note)-24, 0)
+		data[ms1NotesOffset+i*2] = byte(note / 16)
+		data[ms1NotesOffset+i*2+1] = byte(note % 16)
+		if !step.Tie {
+			tie |= 1 << i
+		}
+		if !step.Gate {
+			rest |= 1 << i
+		}
+	}
+	data[ms1LengthOffset] = byte(length / 16)
+	data[ms1LengthOffset+1] = byte(length % 16)
+	putNibbleMask(data[ms1TieOffset:ms1TieOffset+8], tie)
+	putNibbleMask(data[ms1RestOffset:ms1RestOffset+8], rest)
+	return data, nil
+}
+
+// ParseSyx parses an MS-1 sequence dump
+func (m *MS1) ParseSyx(data []byte) (*converter.Pattern, error) {
+	if !sysex.HasManufacturer(data, sysex.Behringer) || len(data) < 7 || data[5] != MS1ModelID {
+		return nil, converter.ErrorfAt(min(5, len(data)), "model ID", "not an MS-1 sequence dump: %s", sysex.Describe(data))
+	}
+	msg, err := sysex.Parse(data, m.SysExLayout())
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Payload) < 1 {
+		return nil, converter.ErrorfAt(msg.PayloadOffset(), "pattern length", "MS-1 sequence dump has no payload")
+	}
+
+	length := int(msg.Payload[0])
+	if length == 0 || length > MS1MaxSteps {
+		return nil, converter.ErrorfAt(msg.PayloadOffset(), "pattern length", "MS-1 sequence length %d out of range (1-%d)", length, MS1MaxSteps)
+	}
+	if len(msg.Payload) < 1+length*2 {
+		return nil, converter.ErrorfAt(msg.PayloadOffset()+len(msg.Payload), "pattern data", "syx data too short: got %d payload bytes, need %d", len(msg.Payload), 1+length*2)
+	}
+
+	pattern := &converter.Pattern{
+		Name:     "MS-1 SysEx Sequence",
+		DeviceID: msg.Header[0],
+		Steps:    parseSyxSteps(msg.Payload[1:], length),
+		Length:   length,
+		Tempo:    120.0,
+	}
+	for i := range pattern.Steps {
+		pattern.Steps[i].Accent = false
+		pattern.Steps[i].Slide = false
+		pattern.Steps[i].Velocity = 100
+	}
+	return pattern, nil
+}
+
+// GenerateSyx generates an MS-1 sequence dump
+func (m *MS1) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+
+	length := min(len(pattern.Steps), MS1MaxSteps)
+	if length == 0 {
+		return nil, errors.New("pattern has no steps")
+	}
+	payload := syxStepPayload(pattern, length)
+	for i := 1; i < len(payload); i += 2 {
+		payload[i] &^= 0x02 | 0x04 // No accent or slide on the MS-1
+	}
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, MS1ModelID, PatternDump).
+		Payload(byte(length)).
+		Payload(payload...).
+		Checksum(m.SysExLayout().Checksum).
+		Build()
+}
+
+// seqNameOffset is where the length-prefixed device name of a .seq header starts
+const seqNameOffset = 7
+
+// seqDeviceName returns the device name stored in a SynthTribe .seq header
+// (UTF-16, length-prefixed), or "" if the header is malformed
+func seqDeviceName(data []byte) string {
+	if len(data) < 8 {
+		return ""
+	}
+	n := int(data[7])
+	if n%2 != 0 || len(data) < 8+n {
+		return ""
+	}
+	name := make([]byte, 0, n/2)
+	for i := 8; i < 8+n; i += 2 {
+		name = append(name, data[i+1])
+	}
+	return string(name)
+}
+
+// nibbleMask decodes a bitmask stored as nibbles in the TD-3 order: each pair
+// of bytes holds one byte of the mask, high nibble first
+func nibbleMask(data []byte) uint32 {
+	var mask uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		mask |= (uint32(data[i+1]) | uint32(data[i])<<4) << (i * 4)
+	}
+	return mask
+}
+
+// putNibbleMask encodes a bitmask as nibbles in the order read by nibbleMask
+func putNibbleMask(data []byte, mask uint32) {
+	for i := 0; i+1 < len(data); i += 2 {
+		b := mask >> (i * 4)
+		data[i] = byte(b>>4) & 0x0F
+		data[i+1] = byte(b) & 0x0F
+	}
+}
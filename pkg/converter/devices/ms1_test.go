@@ -0,0 +1,141 @@
+package devices
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+func TestMS1RoundTrip(t *testing.T) {
+	m := NewMS1()
+
+	steps := make([]converter.Step, 20)
+	for i := range steps {
+		steps[i] = converter.Step{Note: uint8(36 + i), Gate: true, Velocity: 100}
+	}
+	steps[3] = converter.Step{Note: 38, Gate: true, Tie: true, Velocity: 100}
+	steps[5] = converter.Step{Note: 24, Velocity: 100}
+	steps[17].Accent = true // dropped: the MS-1 has no accent
+	pattern := &converter.Pattern{Length: len(steps), Steps: steps}
+
+	for _, tt := range []struct {
+		name     string
+		generate func(*converter.Pattern) ([]byte, error)
+		parse    func([]byte) (*converter.Pattern, error)
+	}{
+		{"seq", m.GenerateSeq, m.ParseSeq},
+		{"syx", m.GenerateSyx, m.ParseSyx},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.generate(pattern)
+			if err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			got, err := tt.parse(data)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if got.Length != len(steps) || len(got.Steps) != len(steps) {
+				t.Fatalf("got %d steps (length %d), want %d", len(got.Steps), got.Length, len(steps))
+			}
+			for i, want := range steps {
+				want.Accent = false
+				if got.Steps[i] != want {
+					t.Errorf("step %d = %+v, want %+v", i, got.Steps[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestMS1WrongDevice(t *testing.T) {
+	m := NewMS1()
+	pattern := &converter.Pattern{Steps: []converter.Step{{Note: 36, Gate: true}}}
+
+	ms1Seq, _ := m.GenerateSeq(pattern)
+	if _, err := NewTD3().ParseSeq(ms1Seq); err == nil || !strings.Contains(err.Error(), "--device ms1") {
+		t.Errorf("TD3.ParseSeq(MS-1 seq) error = %v, want hint", err)
+	}
+	td3Seq, _ := NewTD3().GenerateSeq(pattern)
+	if _, err := m.ParseSeq(td3Seq); err == nil {
+		t.Error("ParseSeq(TD-3 seq) expected error")
+	}
+
+	ms1Syx, _ := m.GenerateSyx(pattern)
+	if _, err := NewTD3().ParseSyx(ms1Syx); err == nil || !strings.Contains(err.Error(), "--device ms1") {
+		t.Errorf("TD3.ParseSyx(MS-1 dump) error = %v, want hint", err)
+	}
+	td3Syx, _ := NewTD3().GenerateSyx(pattern)
+	if _, err := m.ParseSyx(td3Syx); err == nil || !strings.Contains(err.Error(), "--device td3") {
+		t.Errorf("ParseSyx(TD-3 dump) error = %v, want hint", err)
+	}
+
+	k2 := []byte{0xF0, 0x00, 0x20, 0x32, 0x00, K2ModelID, 0x40, 0x00, 0xF7}
+	if got, want := sysex.Describe(k2), "this is a SysEx message from Behringer K-2 (device ID 0)"; got != want {
+		t.Errorf("Describe(K-2) = %q, want %q", got, want)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"td3", "Behringer TD-3"},
+		{"TD-3", "Behringer TD-3"},
+		{"ms-1", "Behringer MS-1"},
+		{"sokkos", "x0xb0x"},
+	}
+	for _, tt := range tests {
+		dev, ok := Lookup(tt.name)
+		if !ok {
+			t.Errorf("Lookup(%q) not found", tt.name)
+			continue
+		}
+		if dev.Name() != tt.want {
+			t.Errorf("Lookup(%q) = %s, want %s", tt.name, dev.Name(), tt.want)
+		}
+	}
+	if _, ok := Lookup("tb303"); ok {
+		t.Error("Lookup(tb303) expected not found")
+	}
+	if got := strings.Join(IDs(), ","); got != "edge,ms1,rd6,rd8,rd9,td3,td3mo,volcabass,x0xb0x" {
+		t.Errorf("IDs() = %s", got)
+	}
+}
+
+func TestDetails(t *testing.T) {
+	td3, _ := LookupInfo("td3")
+	d := td3.Details()
+	if d.MaxSteps != MaxSteps || d.MinNote != 24 || d.Slots != 64 {
+		t.Errorf("TD-3 details = %+v", d)
+	}
+	if got := strings.Join(d.Features, ","); got != "slots,pattern_request,sysex_layout" {
+		t.Errorf("TD-3 features = %s", got)
+	}
+	if got := strings.Join(d.Formats, ","); got != "seq,syx,midi" {
+		t.Errorf("TD-3 formats = %s", got)
+	}
+
+	ms1, _ := LookupInfo("ms1")
+	if d := ms1.Details(); d.Slots != 0 || len(d.Features) != 1 || d.Features[0] != FeatureSysExLayout {
+		t.Errorf("MS-1 details = %+v", d)
+	}
+
+	rd6, _ := LookupInfo("rd-6")
+	if d := rd6.Details(); d.MaxSteps != RD6MaxSteps || strings.Join(d.Features, ",") != "sysex_layout,drums" {
+		t.Errorf("RD-6 details = %+v", d)
+	}
+	rd9, _ := LookupInfo("rd-9")
+	if d := rd9.Details(); d.MaxSteps != RDXMaxSteps || strings.Join(d.Features, ",") != "sysex_layout,drums" {
+		t.Errorf("RD-9 details = %+v", d)
+	}
+	if d := rd9.Details(); d.Defaults.GateLength != 0.25 || d.Defaults.SlideMode != converter.SlideNone {
+		t.Errorf("RD-9 defaults = %+v", d.Defaults)
+	}
+	if d := td3.Details(); d.Defaults != (converter.Defaults{}) {
+		t.Errorf("TD-3 defaults = %+v, want none", d.Defaults)
+	}
+}
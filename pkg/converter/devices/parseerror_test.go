@@ -0,0 +1,149 @@
+package devices
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// TestParseErrorLocation damages generated files and checks that the parse
+// error points at the damaged byte and shows the bytes around it
+func TestParseErrorLocation(t *testing.T) {
+	pattern := &converter.Pattern{Length: 16, Tempo: 120, Steps: make([]converter.Step, 16)}
+	for i := range pattern.Steps {
+		pattern.Steps[i] = converter.Step{Note: 48, Gate: true, Velocity: 100}
+	}
+
+	tests := []struct {
+		name   string
+		device string
+		format converter.Format
+		damage func(data []byte, payload int) []byte // payload is the SysEx payload offset
+		offset func(data []byte, payload int) int
+		field  string
+	}{
+		{
+			name:   "wrong magic",
+			device: "td3",
+			format: converter.FormatSeq,
+			damage: func(data []byte, _ int) []byte { data[0] = 'M'; return data },
+			offset: func([]byte, int) int { return 0 },
+			field:  "magic bytes",
+		},
+		{
+			name:   "truncated seq",
+			device: "td3",
+			format: converter.FormatSeq,
+			damage: func(data []byte, _ int) []byte { return data[:50] },
+			offset: func([]byte, int) int { return 50 },
+			field:  "pattern data",
+		},
+		{
+			name:   "other device name",
+			device: "rd8",
+			format: converter.FormatSeq,
+			damage: func(data []byte, _ int) []byte { data[seqNameOffset+2] = 'X'; return data },
+			offset: func([]byte, int) int { return seqNameOffset },
+			field:  "device name",
+		},
+		{
+			name:   "byte not 7-bit",
+			device: "td3",
+			format: converter.FormatSyx,
+			damage: func(data []byte, _ int) []byte { data[20] = 0x90; return data },
+			offset: func([]byte, int) int { return 20 },
+			field:  "data byte",
+		},
+		{
+			name:   "checksum",
+			device: "td3",
+			format: converter.FormatSyx,
+			damage: func(data []byte, payload int) []byte { data[payload] ^= 1; return data },
+			offset: func(data []byte, _ int) int { return len(data) - 2 },
+			field:  "checksum",
+		},
+		{
+			name:   "missing end byte",
+			device: "ms1",
+			format: converter.FormatSyx,
+			damage: func(data []byte, _ int) []byte { return data[:len(data)-1] },
+			offset: func(data []byte, _ int) int { return len(data) - 2 },
+			field:  "end byte",
+		},
+		{
+			name:   "length out of range",
+			device: "ms1",
+			format: converter.FormatSyx,
+			damage: func(data []byte, payload int) []byte {
+				data[payload] = 0x7F
+				return reseal(data, payload, sysex.XOR)
+			},
+			offset: func(_ []byte, payload int) int { return payload },
+			field:  "pattern length",
+		},
+		{
+			name:   "not a nibble pair",
+			device: "x0xb0x",
+			format: converter.FormatSyx,
+			damage: func(data []byte, payload int) []byte {
+				data[payload+4] = 0x10
+				return reseal(data, payload, sysex.SumMask)
+			},
+			offset: func(_ []byte, payload int) int { return payload + 4 },
+			field:  "step 3",
+		},
+		{
+			name:   "packed note out of range",
+			device: "volcabass",
+			format: converter.FormatSyx,
+			// Set the high bit of the first note in the group's high-bit byte
+			damage: func(data []byte, payload int) []byte { data[payload] |= 1; return data },
+			offset: func(_ []byte, payload int) int { return payload + 1 },
+			field:  "step 1 note",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, err := New(tt.device)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := converter.New(dev)
+			data, _, err := c.Generate(pattern, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload := 0
+			if tt.format == converter.FormatSyx {
+				msg, err := sysex.Parse(data, dev.(converter.SysExDevice).SysExLayout())
+				if err != nil {
+					t.Fatal(err)
+				}
+				payload = msg.PayloadOffset()
+			}
+			offset := tt.offset(data, payload)
+			data = tt.damage(data, payload)
+
+			_, err = c.ParsePattern(data, tt.format)
+			var perr *converter.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("error %v is not located", err)
+			}
+			if perr.Offset != offset || perr.Field != tt.field {
+				t.Errorf("located at byte %d in the %s, want byte %d in the %s", perr.Offset, perr.Field, offset, tt.field)
+			}
+			if !strings.Contains(perr.Context, "[") {
+				t.Errorf("context %q does not mark the byte", perr.Context)
+			}
+		})
+	}
+}
+
+// reseal recomputes the checksum of a damaged SysEx message
+func reseal(data []byte, payload int, sum sysex.Checksum) []byte {
+	data[len(data)-2] = sum.Sum(data[payload : len(data)-2])
+	return data
+}
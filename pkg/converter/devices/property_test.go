@@ -0,0 +1,149 @@
+package devices
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// randomPattern returns a random pattern the device can hold: lanes of hits
+// and accents for drum machines, otherwise a line of notes in the device's
+// range with accents, slides, and ties, up to its maximum length. Tied steps
+// carry no accent, and slides go to another note: a held MIDI note shows
+// neither an accent part way through nor a glide to its own pitch.
+func randomPattern(r *rand.Rand, dev converter.Device) *converter.Pattern {
+	caps := converter.DeviceCapabilities(dev)
+	maxSteps := caps.MaxSteps
+	if maxSteps == 0 {
+		maxSteps = 64
+	}
+	n := 1 + r.IntN(maxSteps)
+	p := &converter.Pattern{Name: "Random", Tempo: float64(60 + r.IntN(141)), Length: n}
+	if drums, ok := dev.(converter.DrumDevice); ok {
+		for _, lane := range drums.DrumLanes() {
+			lane.Steps = make([]converter.Step, n)
+			for i := range lane.Steps {
+				if r.IntN(3) == 0 {
+					lane.Steps[i] = converter.Step{Gate: true, Accent: r.IntN(4) == 0, Velocity: 100}
+				}
+			}
+			p.Tracks = append(p.Tracks, lane)
+		}
+		return p
+	}
+
+	lo, hi := int(caps.MinNote), int(caps.MaxNote)
+	layers := false
+	if d, ok := dev.(converter.AccentLayerDevice); ok {
+		layers = d.AccentLayers()
+	}
+	p.Steps = make([]converter.Step, n)
+	for i := range p.Steps {
+		if r.IntN(4) == 0 {
+			continue // rest
+		}
+		s := converter.Step{Note: uint8(lo + r.IntN(hi-lo+1)), Gate: true, Accent: r.IntN(3) == 0, Velocity: 100}
+		if i > 0 && p.Steps[i-1].Gate && r.IntN(4) == 0 {
+			s = converter.Step{Note: p.Steps[i-1].Note, Gate: true, Tie: true, Velocity: 100}
+		}
+		s.SubAccent = layers && s.Accent && r.IntN(3) == 0
+		p.Steps[i] = s
+	}
+	for i := range n - 1 {
+		if p.Steps[i].Gate && p.Steps[i+1].Gate && p.Steps[i+1].Note != p.Steps[i].Note {
+			p.Steps[i].Slide = r.IntN(4) == 0
+		}
+	}
+	return p
+}
+
+// TestRoundTripProperty generates random patterns for every registered
+// device and checks that each survives Generate then Parse in every format:
+// .seq and .syx with the round-trip check, which compares the steps the
+// device stores, and MIDI compared step by step
+func TestRoundTripProperty(t *testing.T) {
+	runs := 200
+	if testing.Short() {
+		runs = 20
+	}
+	for n, id := range IDs() {
+		t.Run(id, func(t *testing.T) {
+			r := rand.New(rand.NewPCG(uint64(n), 3763))
+			dev, err := New(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := converter.New(dev)
+			opts := converter.DefaultOptions()
+			opts.VerifyRoundtrip = true
+			// Read MIDI back the way it is written: held notes slide, and
+			// every bar is read
+			opts.SlideMode = converter.SlideLegato
+			opts.Downmix = converter.DownmixFold
+			c.SetOptions(opts)
+
+			for range runs {
+				pattern := randomPattern(r, dev)
+				pattern.Normalize()
+				for _, f := range []converter.Format{converter.FormatSeq, converter.FormatSyx, converter.FormatMIDI} {
+					data, _, err := c.Generate(pattern, f)
+					if err != nil {
+						t.Fatalf("%s of %s: %v", f, describeSteps(pattern), err)
+					}
+					if f != converter.FormatMIDI {
+						continue
+					}
+					back, err := c.ParsePattern(data, f)
+					if err != nil {
+						t.Fatalf("MIDI of %s does not parse: %v", describeSteps(pattern), err)
+					}
+					if back.Tempo != pattern.Tempo {
+						t.Errorf("MIDI of %s: tempo %g, want %g", describeSteps(pattern), back.Tempo, pattern.Tempo)
+					}
+					for _, d := range converter.Diff(pattern, back) {
+						if d.Step >= 0 {
+							t.Fatalf("MIDI of %s reads back differently: %s", describeSteps(pattern), d)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// describeSteps prints a pattern's steps for a failure message: a note and
+// its Accent, Slide, and Tie marks, or "-" for a rest, per step of a line
+// (e.g. "36A 36T 38S -"), and "x" hit, "X" accent, or "." per step of a
+// drum track
+func describeSteps(p *converter.Pattern) string {
+	var tokens []string
+	for _, t := range p.Tracks {
+		hits := []byte(strings.Repeat(".", len(t.Steps)))
+		for i, s := range t.Steps {
+			switch {
+			case s.Accent:
+				hits[i] = 'X'
+			case s.Gate:
+				hits[i] = 'x'
+			}
+		}
+		tokens = append(tokens, t.Name+":"+string(hits))
+	}
+	for _, s := range p.Steps {
+		if !s.Gate {
+			tokens = append(tokens, "-")
+			continue
+		}
+		token := fmt.Sprint(s.Note)
+		for i, set := range []bool{s.Accent, s.Slide, s.Tie} {
+			if set {
+				token += string("AST"[i])
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return strings.Join(tokens, " ")
+}
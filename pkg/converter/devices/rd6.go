@@ -0,0 +1,312 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// RD-6 device constants
+const (
+	RD6ModelID  = 0x04 // RD-6 model ID in Behringer SysEx
+	RD6MaxSteps = 16
+
+	// RD-6 SEQ file offsets: the TD-3 container header followed by the
+	// pattern length, then 16-bit step masks for the accent track and each
+	// voice, all nibble-encoded. Files written with ghost notes add a mask
+	// of the soft steps; files without one are read without ghost notes.
+	rd6LengthOffset = NotesOffset          // 36
+	rd6AccentOffset = rd6LengthOffset + 2  // 38
+	rd6VoiceOffset  = rd6AccentOffset + 4  // 42
+	RD6SeqSize      = rd6VoiceOffset + 8*4 // 74
+	rd6SoftOffset   = RD6SeqSize           // 74
+	RD6SoftSeqSize  = rd6SoftOffset + 4    // 78
+)
+
+// rd6SeqName is the device name stored in RD-6 .seq headers
+const rd6SeqName = "RD-6"
+
+// drumDefaults are the conversion defaults of the drum machines: short
+// trigger notes, and no slides
+var drumDefaults = converter.Defaults{GateLength: 0.25, SlideMode: converter.SlideNone}
+
+// rd6Voices are the RD-6's drum voices in pattern order, with the General
+// MIDI drum notes they play on channel 10
+var rd6Voices = []converter.Track{
+	{Name: "BD", Note: 36}, // bass drum
+	{Name: "SD", Note: 38}, // snare drum
+	{Name: "LT", Note: 45}, // low tom
+	{Name: "HT", Note: 50}, // high tom
+	{Name: "CY", Note: 49}, // cymbal
+	{Name: "OH", Note: 46}, // open hi-hat
+	{Name: "CH", Note: 42}, // closed hi-hat
+	{Name: "CP", Note: 39}, // clap
+}
+
+// rd6SyxLayout is the RD-6 pattern dump body: device ID, model ID and
+// command header, pattern length and two bytes per step, then an XOR checksum
+var rd6SyxLayout = sysex.Layout{HeaderLen: 3, Checksum: sysex.XOR}
+
+func init() {
+	RegisterInfo(Info{
+		ID:          "rd6",
+		Aliases:     []string{"rd-6"},
+		Name:        "Behringer RD-6",
+		Description: "TR-606 clone drum machine (8 voices, 16 steps)",
+		New:         func() converter.Device { return NewRD6() },
+	})
+	sysex.RegisterModel(sysex.Model{Manufacturer: sysex.Behringer, ID: RD6ModelID, Name: "Behringer RD-6", Device: "rd6"})
+}
+
+// RD6 implements the Device interface for the Behringer RD-6 drum machine.
+// Its patterns are lanes of hits, one per voice, with a shared accent track:
+// a step is accented for every voice when any of its hits is. Soft steps
+// are shared the same way, but play soft only when all their hits are ghost
+// notes.
+type RD6 struct{}
+
+// NewRD6 creates a new RD-6 device handler
+func NewRD6() *RD6 {
+	return &RD6{}
+}
+
+// Name returns the device name
+func (r *RD6) Name() string {
+	return "Behringer RD-6"
+}
+
+// ID returns the device ID
+func (r *RD6) ID() uint8 {
+	return 0
+}
+
+// Capabilities returns the RD-6 pattern limits
+func (r *RD6) Capabilities() converter.Capabilities {
+	return converter.Capabilities{
+		MaxSteps: RD6MaxSteps,
+		MinNote:  0,
+		MaxNote:  127,
+		Defaults: drumDefaults,
+		Lossy:    []string{"accent"}, // shared by the voices of a step
+	}
+}
+
+// DrumLanes returns the RD-6 voices and their MIDI drum notes
+func (r *RD6) DrumLanes() []converter.Track {
+	return slices.Clone(rd6Voices)
+}
+
+// SysExLayout returns the RD-6 pattern dump layout
+func (r *RD6) SysExLayout() sysex.Layout {
+	return rd6SyxLayout
+}
+
+// ParseSeq parses an RD-6 .seq file into a drum Pattern
+func (r *RD6) ParseSeq(data []byte) (*converter.Pattern, error) {
+	if len(data) < RD6SeqSize {
+		return nil, converter.ErrorfAt(len(data), "pattern data", "seq data too short: got %d bytes, need at least %d", len(data), RD6SeqSize)
+	}
+	if !bytes.HasPrefix(data, td3HeaderMagic) {
+		return nil, converter.ErrorfAt(0, "magic bytes", "invalid RD-6 seq file: wrong magic bytes")
+	}
+	if name := seqDeviceName(data); name != rd6SeqName {
+		return nil, converter.ErrorfAt(seqNameOffset, "device name", "not an RD-6 seq file: device name %q", name)
+	}
+
+	length := int(data[rd6LengthOffset])*16 + int(data[rd6LengthOffset+1])
+	if length == 0 || length > RD6MaxSteps {
+		length = RD6MaxSteps
+	}
+	accent := nibbleMask(data[rd6AccentOffset : rd6AccentOffset+4])
+	hits := make([]uint32, len(rd6Voices))
+	for v := range rd6Voices {
+		off := rd6VoiceOffset + v*4
+		hits[v] = nibbleMask(data[off : off+4])
+	}
+	var soft uint32
+	if len(data) >= RD6SoftSeqSize {
+		soft = nibbleMask(data[rd6SoftOffset:RD6SoftSeqSize])
+	}
+	return rd6Pattern("RD-6 Pattern", length, hits, accent, soft), nil
+}
+
+// GenerateSeq generates RD-6 .seq data from a Pattern
+func (r *RD6) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	length, hits, accent, soft := rd6Masks(pattern)
+
+	data := make([]byte, RD6SoftSeqSize)
+	copy(data, rd6SeqHeader[:])
+	data[rd6LengthOffset] = byte(length / 16)
+	data[rd6LengthOffset+1] = byte(length % 16)
+	putNibbleMask(data[rd6AccentOffset:rd6AccentOffset+4], accent)
+	for v, mask := range hits {
+		off := rd6VoiceOffset + v*4
+		putNibbleMask(data[off:off+4], mask)
+	}
+	putNibbleMask(data[rd6SoftOffset:RD6SoftSeqSize], soft)
+	return data, nil
+}
+
+// ParseSyx parses an RD-6 pattern dump
+func (r *RD6) ParseSyx(data []byte) (*converter.Pattern, error) {
+	if !sysex.HasManufacturer(data, sysex.Behringer) || len(data) < 7 || data[5] != RD6ModelID {
+		return nil, converter.ErrorfAt(min(5, len(data)), "model ID", "not an RD-6 pattern dump: %s", sysex.Describe(data))
+	}
+	msg, err := sysex.Parse(data, r.SysExLayout())
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Payload) < 1 {
+		return nil, converter.ErrorfAt(msg.PayloadOffset(), "pattern length", "RD-6 pattern dump has no payload")
+	}
+
+	length := int(msg.Payload[0])
+	if length == 0 || length > RD6MaxSteps {
+		return nil, converter.ErrorfAt(msg.PayloadOffset(), "pattern length", "RD-6 pattern length %d out of range (1-%d)", length, RD6MaxSteps)
+	}
+	if len(msg.Payload) < 1+length*2 {
+		return nil, converter.ErrorfAt(msg.PayloadOffset()+len(msg.Payload), "pattern data", "syx data too short: got %d payload bytes, need %d", len(msg.Payload), 1+length*2)
+	}
+
+	// Each step is the first seven voices, then the last voice, accent, and
+	// soft flag
+	hits := make([]uint32, len(rd6Voices))
+	var accent, soft uint32
+	for i := range length {
+		lo, hi := msg.Payload[1+i*2], msg.Payload[2+i*2]
+		for v := range hits {
+			bit := lo >> v
+			if v == 7 {
+				bit = hi
+			}
+			if bit&0x01 != 0 {
+				hits[v] |= 1 << i
+			}
+		}
+		if hi&0x02 != 0 {
+			accent |= 1 << i
+		}
+		if hi&0x04 != 0 {
+			soft |= 1 << i
+		}
+	}
+	pattern := rd6Pattern("RD-6 SysEx Pattern", length, hits, accent, soft)
+	pattern.DeviceID = msg.Header[0]
+	return pattern, nil
+}
+
+// GenerateSyx generates an RD-6 pattern dump
+func (r *RD6) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	length, hits, accent, soft := rd6Masks(pattern)
+
+	payload := make([]byte, 0, length*2)
+	for i := range length {
+		var lo, hi byte
+		for v, mask := range hits {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			if v == 7 {
+				hi |= 0x01
+			} else {
+				lo |= 1 << v
+			}
+		}
+		if accent&(1<<i) != 0 {
+			hi |= 0x02
+		}
+		if soft&(1<<i) != 0 {
+			hi |= 0x04
+		}
+		payload = append(payload, lo, hi)
+	}
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, RD6ModelID, PatternDump).
+		Payload(byte(length)).
+		Payload(payload...).
+		Checksum(r.SysExLayout().Checksum).
+		Build()
+}
+
+// rd6Pattern builds a drum pattern from per-voice hit masks and the accent
+// and soft masks
+func rd6Pattern(name string, length int, hits []uint32, accent, soft uint32) *converter.Pattern {
+	pattern := &converter.Pattern{
+		Name:   name,
+		Length: length,
+		Tempo:  120.0,
+		Tracks: slices.Clone(rd6Voices),
+	}
+	for v := range pattern.Tracks {
+		lane := &pattern.Tracks[v]
+		lane.Steps = make([]converter.Step, length)
+		for i := range lane.Steps {
+			if hits[v]&(1<<i) == 0 {
+				continue
+			}
+			lane.Steps[i] = converter.Step{Note: lane.Note, Gate: true, Velocity: 100}
+			switch {
+			case accent&(1<<i) != 0:
+				lane.Steps[i].Accent = true
+				lane.Steps[i].Velocity = 127
+			case soft&(1<<i) != 0:
+				lane.Steps[i].Ghost = true
+				lane.Steps[i].Velocity = converter.GhostVelocity
+			}
+		}
+	}
+	return pattern
+}
+
+// rd6Masks returns the length, per-voice hit masks, and accent and soft
+// masks of a pattern. Lanes are matched to voices by name, or else by drum
+// note; a pattern without lanes plays each gated step on the voice of its
+// note.
+func rd6Masks(pattern *converter.Pattern) (length int, hits []uint32, accent, soft uint32) {
+	length = min(max(pattern.StepCount(), 1), RD6MaxSteps)
+	hits = make([]uint32, len(rd6Voices))
+	var plain uint32 // steps with a hit that is not a ghost note
+
+	voice := func(name string, note uint8) int {
+		if v := slices.IndexFunc(rd6Voices, func(l converter.Track) bool { return name != "" && l.Name == name }); v >= 0 {
+			return v
+		}
+		return slices.IndexFunc(rd6Voices, func(l converter.Track) bool { return l.Note == note })
+	}
+	hit := func(v, i int, s converter.Step) {
+		if v < 0 || i >= length || !s.Gate {
+			return
+		}
+		hits[v] |= 1 << i
+		if s.Accent {
+			accent |= 1 << i
+		}
+		if s.Ghost {
+			soft |= 1 << i
+		} else {
+			plain |= 1 << i
+		}
+	}
+
+	for _, lane := range pattern.Tracks {
+		v := voice(lane.Name, lane.Note)
+		for i, s := range lane.Steps {
+			hit(v, i, s)
+		}
+	}
+	if !pattern.IsDrum() {
+		for i, s := range pattern.Steps {
+			hit(voice("", s.Note), i, s)
+		}
+	}
+	return length, hits, accent, soft &^ plain &^ accent
+}
@@ -0,0 +1,164 @@
+package devices
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// fourOnTheFloor is a drum pattern with kicks on the beats, claps on 5 and
+// 13, closed hats between, the last one accented, and a ghost kick on 16
+func fourOnTheFloor() *converter.Pattern {
+	p := &converter.Pattern{Length: 16, Tracks: NewRD6().DrumLanes()}
+	for v := range p.Tracks {
+		p.Tracks[v].Steps = make([]converter.Step, 16)
+	}
+	hit := func(v, i int, accent bool) {
+		p.Tracks[v].Steps[i] = converter.Step{Note: p.Tracks[v].Note, Gate: true, Velocity: 100, Accent: accent}
+		if accent {
+			p.Tracks[v].Steps[i].Velocity = 127
+		}
+	}
+	for i := 0; i < 16; i += 4 {
+		hit(0, i, false) // BD
+		hit(6, i+2, i == 12)
+	}
+	hit(7, 4, false) // CP
+	hit(7, 12, false)
+	p.Tracks[0].Steps[15] = converter.Step{Note: 36, Gate: true, Velocity: converter.GhostVelocity, Ghost: true}
+	return p
+}
+
+func TestRD6RoundTrip(t *testing.T) {
+	r := NewRD6()
+	pattern := fourOnTheFloor()
+
+	for _, tt := range []struct {
+		name     string
+		generate func(*converter.Pattern) ([]byte, error)
+		parse    func([]byte) (*converter.Pattern, error)
+	}{
+		{"seq", r.GenerateSeq, r.ParseSeq},
+		{"syx", r.GenerateSyx, r.ParseSyx},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.generate(pattern)
+			if err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			got, err := tt.parse(data)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if got.Length != 16 || len(got.Tracks) != 8 || len(got.Steps) != 0 {
+				t.Fatalf("got length %d, %d lanes, %d steps", got.Length, len(got.Tracks), len(got.Steps))
+			}
+			for v, lane := range pattern.Tracks {
+				for i, want := range lane.Steps {
+					if got.Tracks[v].Steps[i] != want {
+						t.Errorf("%s step %d = %+v, want %+v", lane.Name, i+1, got.Tracks[v].Steps[i], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRD6SharedAccent(t *testing.T) {
+	r := NewRD6()
+	pattern := fourOnTheFloor()
+	pattern.Tracks[0].Steps[14] = converter.Step{Note: 36, Gate: true, Velocity: 100}
+
+	data, err := r.GenerateSeq(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.ParseSeq(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The accented hat on step 15 accents the kick sharing it
+	if kick := got.Tracks[0].Steps[14]; !kick.Accent {
+		t.Errorf("kick on step 15 = %+v, want accented", kick)
+	}
+
+	// A ghost note sharing its step with a plain hit plays plain
+	pattern.Tracks[7].Steps[15] = converter.Step{Note: 39, Gate: true, Velocity: 100}
+	if data, err = r.GenerateSyx(pattern); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = r.ParseSyx(data); err != nil {
+		t.Fatal(err)
+	}
+	if kick := got.Tracks[0].Steps[15]; !kick.Gate || kick.Ghost {
+		t.Errorf("kick on step 16 = %+v, want a plain hit", kick)
+	}
+}
+
+func TestRD6FromNotes(t *testing.T) {
+	// A monophonic line plays each note on the voice of its drum note;
+	// notes no voice plays are dropped
+	pattern := &converter.Pattern{Steps: []converter.Step{
+		{Note: 36, Gate: true},
+		{Note: 38, Gate: true, Accent: true},
+		{Note: 60, Gate: true},
+		{Note: 42},
+	}}
+	data, err := NewRD6().GenerateSyx(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewRD6().ParseSyx(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Length != 4 || !got.Tracks[0].Steps[0].Gate || !got.Tracks[1].Steps[1].Accent {
+		t.Errorf("lanes = %+v", got.Tracks)
+	}
+	for _, lane := range got.Tracks {
+		if lane.Steps[2].Gate || lane.Steps[3].Gate {
+			t.Errorf("%s has hits on steps 3-4: %+v", lane.Name, lane.Steps)
+		}
+	}
+}
+
+func TestRD6MIDI(t *testing.T) {
+	conv := converter.New(NewRD6())
+	seq, err := NewRD6().GenerateSeq(fourOnTheFloor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mid, err := conv.SeqToMIDI(seq)
+	if err != nil {
+		t.Fatalf("SeqToMIDI() error = %v", err)
+	}
+	back, err := conv.MIDIToSeq(mid)
+	if err != nil {
+		t.Fatalf("MIDIToSeq() error = %v", err)
+	}
+	if string(back) != string(seq) {
+		t.Errorf("seq -> MIDI -> seq changed the pattern:\n got %x\nwant %x", back, seq)
+	}
+
+	// Drum patterns are not written for synths
+	p, _ := NewRD6().ParseSeq(seq)
+	if _, err := converter.New(NewTD3()).GeneratePattern(p, converter.FormatSeq); err == nil || !strings.Contains(err.Error(), "drum pattern") {
+		t.Errorf("TD-3 GeneratePattern(drum pattern) error = %v", err)
+	}
+}
+
+func TestRD6WrongDevice(t *testing.T) {
+	rd6Seq, _ := NewRD6().GenerateSeq(fourOnTheFloor())
+	if _, err := NewTD3().ParseSeq(rd6Seq); err == nil || !strings.Contains(err.Error(), "--device rd6") {
+		t.Errorf("TD3.ParseSeq(RD-6 seq) error = %v, want hint", err)
+	}
+	rd6Syx, _ := NewRD6().GenerateSyx(fourOnTheFloor())
+	if _, err := NewTD3().ParseSyx(rd6Syx); err == nil || !strings.Contains(err.Error(), "--device rd6") {
+		t.Errorf("TD3.ParseSyx(RD-6 dump) error = %v, want hint", err)
+	}
+	td3Seq, _ := NewTD3().GenerateSeq(&converter.Pattern{Steps: []converter.Step{{Note: 36, Gate: true}}})
+	if _, err := NewRD6().ParseSeq(td3Seq); err == nil {
+		t.Error("ParseSeq(TD-3 seq) expected error")
+	}
+}
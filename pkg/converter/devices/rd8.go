@@ -0,0 +1,320 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// RD-8 and RD-9 device constants
+const (
+	RD8ModelID    = 0x05 // RD-8 model ID in Behringer SysEx
+	RD9ModelID    = 0x06 // RD-9 model ID in Behringer SysEx
+	RDXMaxSteps   = 64
+	rdxStepSize   = 3 // flags, flam, probability
+	rdxLengthSize = 2 // nibble-encoded pattern length
+
+	// Step flags
+	rdxHit    = 0x01
+	rdxAccent = 0x02
+	rdxGhost  = 0x04
+)
+
+// rd8Voices are the RD-8's drum voices in pattern order, with the General
+// MIDI drum notes they play on channel 10
+var rd8Voices = []converter.Track{
+	{Name: "BD", Note: 36}, // bass drum
+	{Name: "SD", Note: 38}, // snare drum
+	{Name: "LT", Note: 45}, // low tom
+	{Name: "MT", Note: 47}, // mid tom
+	{Name: "HT", Note: 50}, // high tom
+	{Name: "RS", Note: 37}, // rim shot
+	{Name: "CP", Note: 39}, // clap
+	{Name: "CH", Note: 42}, // closed hi-hat
+	{Name: "OH", Note: 46}, // open hi-hat
+	{Name: "CY", Note: 49}, // cymbal
+	{Name: "CB", Note: 56}, // cowbell
+}
+
+// rd9Voices are the RD-9's drum voices in pattern order
+var rd9Voices = []converter.Track{
+	{Name: "BD", Note: 36}, // bass drum
+	{Name: "SD", Note: 38}, // snare drum
+	{Name: "LT", Note: 45}, // low tom
+	{Name: "MT", Note: 47}, // mid tom
+	{Name: "HT", Note: 50}, // high tom
+	{Name: "RS", Note: 37}, // rim shot
+	{Name: "CP", Note: 39}, // clap
+	{Name: "CH", Note: 42}, // closed hi-hat
+	{Name: "OH", Note: 46}, // open hi-hat
+	{Name: "CR", Note: 49}, // crash
+	{Name: "RD", Note: 51}, // ride
+}
+
+// rdxSyxLayout is the RD-8/RD-9 pattern dump body: device ID, model ID and
+// command header, pattern length and the track data, then an XOR checksum
+var rdxSyxLayout = sysex.Layout{HeaderLen: 3, Checksum: sysex.XOR}
+
+var (
+	rd8 = drumMachine{name: "Behringer RD-8", seqName: "RD-8", modelID: RD8ModelID, voices: rd8Voices}
+	rd9 = drumMachine{name: "Behringer RD-9", seqName: "RD-9", modelID: RD9ModelID, voices: rd9Voices}
+)
+
+func init() {
+	RegisterInfo(Info{
+		ID:          "rd8",
+		Aliases:     []string{"rd-8"},
+		Name:        rd8.name,
+		Description: "TR-808 clone drum machine (11 voices, 64 steps)",
+		New:         func() converter.Device { return NewRD8() },
+	})
+	RegisterInfo(Info{
+		ID:          "rd9",
+		Aliases:     []string{"rd-9"},
+		Name:        rd9.name,
+		Description: "TR-909 clone drum machine (11 voices, 64 steps)",
+		New:         func() converter.Device { return NewRD9() },
+	})
+	sysex.RegisterModel(sysex.Model{Manufacturer: sysex.Behringer, ID: RD8ModelID, Name: rd8.name, Device: "rd8"})
+	sysex.RegisterModel(sysex.Model{Manufacturer: sysex.Behringer, ID: RD9ModelID, Name: rd9.name, Device: "rd9"})
+}
+
+// RD8 implements the Device interface for the Behringer RD-8 drum machine
+type RD8 struct{ drumMachine }
+
+// NewRD8 creates a new RD-8 device handler
+func NewRD8() *RD8 {
+	return &RD8{rd8}
+}
+
+// RD9 implements the Device interface for the Behringer RD-9 drum machine
+type RD9 struct{ drumMachine }
+
+// NewRD9 creates a new RD-9 device handler
+func NewRD9() *RD9 {
+	return &RD9{rd9}
+}
+
+// drumMachine handles the patterns of the RD-8 and RD-9, which differ only
+// in their voices. Patterns are stored per track: each voice's steps in
+// turn, three bytes per step for the hit, accent, and ghost flags, the flam,
+// and the probability.
+type drumMachine struct {
+	name    string
+	seqName string
+	modelID byte
+	voices  []converter.Track
+}
+
+// Name returns the device name
+func (d *drumMachine) Name() string {
+	return d.name
+}
+
+// ID returns the device ID
+func (d *drumMachine) ID() uint8 {
+	return 0
+}
+
+// Capabilities returns the pattern limits
+func (d *drumMachine) Capabilities() converter.Capabilities {
+	return converter.Capabilities{
+		MaxSteps: RDXMaxSteps,
+		MinNote:  0,
+		MaxNote:  127,
+		Defaults: drumDefaults,
+	}
+}
+
+// DrumLanes returns the voices and their MIDI drum notes
+func (d *drumMachine) DrumLanes() []converter.Track {
+	return slices.Clone(d.voices)
+}
+
+// SysExLayout returns the pattern dump layout
+func (d *drumMachine) SysExLayout() sysex.Layout {
+	return rdxSyxLayout
+}
+
+// seqSize returns the size of a .seq file: the header, the pattern length,
+// and all 64 steps of every track
+func (d *drumMachine) seqSize() int {
+	return NotesOffset + rdxLengthSize + len(d.voices)*RDXMaxSteps*rdxStepSize
+}
+
+// ParseSeq parses a .seq file into a drum Pattern
+func (d *drumMachine) ParseSeq(data []byte) (*converter.Pattern, error) {
+	if len(data) < d.seqSize() {
+		return nil, converter.ErrorfAt(len(data), "pattern data", "seq data too short: got %d bytes, need at least %d", len(data), d.seqSize())
+	}
+	if !bytes.HasPrefix(data, td3HeaderMagic) {
+		return nil, converter.ErrorfAt(0, "magic bytes", "invalid %s seq file: wrong magic bytes", d.seqName)
+	}
+	if name := seqDeviceName(data); name != d.seqName {
+		return nil, converter.ErrorfAt(seqNameOffset, "device name", "not an %s seq file: device name %q", d.seqName, name)
+	}
+
+	length := int(data[NotesOffset])*16 + int(data[NotesOffset+1])
+	if length == 0 || length > RDXMaxSteps {
+		length = RDXMaxSteps
+	}
+	return d.pattern(d.seqName+" Pattern", length, RDXMaxSteps, data[NotesOffset+rdxLengthSize:]), nil
+}
+
+// GenerateSeq generates .seq data from a Pattern
+func (d *drumMachine) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	length, tracks := d.tracks(pattern)
+
+	data := make([]byte, d.seqSize())
+	header := rd8SeqHeader
+	if d.modelID == RD9ModelID {
+		header = rd9SeqHeader
+	}
+	copy(data, header[:])
+	data[NotesOffset] = byte(length / 16)
+	data[NotesOffset+1] = byte(length % 16)
+	d.putTracks(data[NotesOffset+rdxLengthSize:], tracks, RDXMaxSteps)
+	return data, nil
+}
+
+// ParseSyx parses a pattern dump
+func (d *drumMachine) ParseSyx(data []byte) (*converter.Pattern, error) {
+	if !sysex.HasManufacturer(data, sysex.Behringer) || len(data) < 7 || data[5] != d.modelID {
+		return nil, converter.ErrorfAt(min(5, len(data)), "model ID", "not an %s pattern dump: %s", d.seqName, sysex.Describe(data))
+	}
+	msg, err := sysex.Parse(data, d.SysExLayout())
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Payload) < 1 {
+		return nil, converter.ErrorfAt(msg.PayloadOffset(), "pattern length", "%s pattern dump has no payload", d.seqName)
+	}
+
+	length := int(msg.Payload[0])
+	if length == 0 || length > RDXMaxSteps {
+		return nil, converter.ErrorfAt(msg.PayloadOffset(), "pattern length", "%s pattern length %d out of range (1-%d)", d.seqName, length, RDXMaxSteps)
+	}
+	if need := 1 + len(d.voices)*length*rdxStepSize; len(msg.Payload) < need {
+		return nil, converter.ErrorfAt(msg.PayloadOffset()+len(msg.Payload), "pattern data", "syx data too short: got %d payload bytes, need %d", len(msg.Payload), need)
+	}
+	pattern := d.pattern(d.seqName+" SysEx Pattern", length, length, msg.Payload[1:])
+	pattern.DeviceID = msg.Header[0]
+	return pattern, nil
+}
+
+// GenerateSyx generates a pattern dump; dumps hold only the pattern's steps
+func (d *drumMachine) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	length, tracks := d.tracks(pattern)
+
+	payload := make([]byte, len(d.voices)*length*rdxStepSize)
+	d.putTracks(payload, tracks, length)
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, d.modelID, PatternDump).
+		Payload(byte(length)).
+		Payload(payload...).
+		Checksum(d.SysExLayout().Checksum).
+		Build()
+}
+
+// pattern builds a drum pattern of length steps from track data holding
+// stride steps per voice
+func (d *drumMachine) pattern(name string, length, stride int, data []byte) *converter.Pattern {
+	pattern := &converter.Pattern{
+		Name:   name,
+		Length: length,
+		Tempo:  120.0,
+		Tracks: d.DrumLanes(),
+	}
+	for v := range pattern.Tracks {
+		lane := &pattern.Tracks[v]
+		lane.Steps = make([]converter.Step, length)
+		for i := range lane.Steps {
+			b := data[(v*stride+i)*rdxStepSize:]
+			if b[0]&rdxHit == 0 {
+				continue
+			}
+			lane.Steps[i] = converter.Step{
+				Note:        lane.Note,
+				Gate:        true,
+				Velocity:    100,
+				Flam:        b[1] & 0x7F,
+				Probability: min(b[2]&0x7F, 100),
+			}
+			switch {
+			case b[0]&rdxAccent != 0:
+				lane.Steps[i].Accent = true
+				lane.Steps[i].Velocity = 127
+			case b[0]&rdxGhost != 0:
+				lane.Steps[i].Ghost = true
+				lane.Steps[i].Velocity = converter.GhostVelocity
+			}
+		}
+	}
+	return pattern
+}
+
+// putTracks writes the hits of each voice as track data of stride steps
+func (d *drumMachine) putTracks(data []byte, tracks [][]converter.Step, stride int) {
+	for v, steps := range tracks {
+		for i, s := range steps {
+			if !s.Gate {
+				continue
+			}
+			b := data[(v*stride+i)*rdxStepSize:]
+			b[0] = rdxHit
+			switch {
+			case s.Accent:
+				b[0] |= rdxAccent
+			case s.Ghost:
+				b[0] |= rdxGhost
+			}
+			b[1] = min(s.Flam, 127)
+			b[2] = min(s.Probability, 100)
+		}
+	}
+}
+
+// tracks returns the length and each voice's steps of a pattern
+func (d *drumMachine) tracks(pattern *converter.Pattern) (int, [][]converter.Step) {
+	return voiceTracks(d.voices, pattern, RDXMaxSteps)
+}
+
+// voiceTracks returns the length, up to maxSteps, and each voice's steps of
+// a pattern. Lanes are matched to voices by name, or else by drum note; a
+// pattern without lanes plays each gated step on the voice of its note.
+func voiceTracks(voices []converter.Track, pattern *converter.Pattern, maxSteps int) (int, [][]converter.Step) {
+	length := min(max(pattern.StepCount(), 1), maxSteps)
+	tracks := make([][]converter.Step, len(voices))
+	for v := range tracks {
+		tracks[v] = make([]converter.Step, length)
+	}
+
+	voice := func(name string, note uint8) int {
+		if v := slices.IndexFunc(voices, func(l converter.Track) bool { return name != "" && l.Name == name }); v >= 0 {
+			return v
+		}
+		return slices.IndexFunc(voices, func(l converter.Track) bool { return l.Note == note })
+	}
+	for _, lane := range pattern.Tracks {
+		if v := voice(lane.Name, lane.Note); v >= 0 {
+			copy(tracks[v], lane.Steps)
+		}
+	}
+	if !pattern.IsDrum() {
+		for i, s := range pattern.Steps {
+			if v := voice("", s.Note); v >= 0 && i < length {
+				tracks[v][i] = s
+			}
+		}
+	}
+	return length, tracks
+}
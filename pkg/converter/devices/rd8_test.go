@@ -0,0 +1,145 @@
+package devices
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// rdxPattern is a 64-step drum pattern for d with a kick every beat, a
+// flammed snare on the backbeats, hats at 50% probability between, and a
+// ghost snare before every beat but the first
+func rdxPattern(d converter.DrumDevice) *converter.Pattern {
+	p := &converter.Pattern{Length: RDXMaxSteps, Tracks: d.DrumLanes()}
+	for v := range p.Tracks {
+		p.Tracks[v].Steps = make([]converter.Step, RDXMaxSteps)
+	}
+	for i := 0; i < RDXMaxSteps; i += 4 {
+		p.Tracks[0].Steps[i] = converter.Step{Note: 36, Gate: true, Velocity: 100}
+		p.Tracks[7].Steps[i+2] = converter.Step{Note: 42, Gate: true, Velocity: 100, Probability: 50}
+		if i%8 == 4 {
+			p.Tracks[1].Steps[i] = converter.Step{Note: 38, Gate: true, Velocity: 127, Accent: true, Flam: 40}
+		}
+		if i > 0 {
+			p.Tracks[1].Steps[i-1] = converter.Step{Note: 38, Gate: true, Velocity: converter.GhostVelocity, Ghost: true}
+		}
+	}
+	return p
+}
+
+func TestRDXRoundTrip(t *testing.T) {
+	for _, d := range []interface {
+		converter.Device
+		converter.DrumDevice
+	}{NewRD8(), NewRD9()} {
+		pattern := rdxPattern(d)
+		for _, tt := range []struct {
+			name     string
+			generate func(*converter.Pattern) ([]byte, error)
+			parse    func([]byte) (*converter.Pattern, error)
+		}{
+			{"seq", d.GenerateSeq, d.ParseSeq},
+			{"syx", d.GenerateSyx, d.ParseSyx},
+		} {
+			t.Run(d.Name()+" "+tt.name, func(t *testing.T) {
+				data, err := tt.generate(pattern)
+				if err != nil {
+					t.Fatalf("generate error = %v", err)
+				}
+				got, err := tt.parse(data)
+				if err != nil {
+					t.Fatalf("parse error = %v", err)
+				}
+				if got.Length != RDXMaxSteps || len(got.Tracks) != 11 {
+					t.Fatalf("got length %d, %d lanes", got.Length, len(got.Tracks))
+				}
+				for v, lane := range pattern.Tracks {
+					for i, want := range lane.Steps {
+						if got.Tracks[v].Steps[i] != want {
+							t.Errorf("%s step %d = %+v, want %+v", lane.Name, i+1, got.Tracks[v].Steps[i], want)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRDXMIDI(t *testing.T) {
+	// MIDI keeps flams as grace hits but has no step probability
+	pattern := rdxPattern(NewRD8())
+	for i := range pattern.Tracks[7].Steps {
+		pattern.Tracks[7].Steps[i].Probability = 0
+	}
+	seq, err := NewRD8().GenerateSeq(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv := converter.New(NewRD8())
+	mid, err := conv.SeqToMIDI(seq)
+	if err != nil {
+		t.Fatalf("SeqToMIDI() error = %v", err)
+	}
+	back, err := conv.MIDIToSeq(mid)
+	if err != nil {
+		t.Fatalf("MIDIToSeq() error = %v", err)
+	}
+	got, err := NewRD8().ParseSeq(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Length != RDXMaxSteps {
+		t.Errorf("length = %d, want %d", got.Length, RDXMaxSteps)
+	}
+	for v, lane := range pattern.Tracks {
+		for i, want := range lane.Steps {
+			g := got.Tracks[v].Steps[i]
+			if g != want {
+				t.Errorf("%s step %d = %+v, want %+v", lane.Name, i+1, g, want)
+			}
+		}
+	}
+}
+
+func TestRDXFromTR(t *testing.T) {
+	// TR-8S instruments land on the voices playing their drum notes: the
+	// hand clap on CP and the crash on CY
+	tr, err := converter.ParseTR([]byte("BD=x...x...\nHC=....X...\nCC=x.......\nRC=..x.....\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, err := converter.New(NewRD8()).GeneratePattern(tr, converter.FormatSeq)
+	if err != nil {
+		t.Fatalf("GeneratePattern() error = %v", err)
+	}
+	got, err := NewRD8().ParseSeq(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := map[string]int{}
+	for _, lane := range got.Tracks {
+		for _, s := range lane.Steps {
+			if s.Gate {
+				hits[lane.Name]++
+			}
+		}
+	}
+	if hits["BD"] != 2 || hits["CP"] != 1 || hits["CY"] != 1 || len(hits) != 3 || !got.Tracks[6].Steps[4].Accent {
+		t.Errorf("RD-8 hits = %v, want BD 2, CP 1 (accented), CY 1", hits)
+	}
+}
+
+func TestRDXWrongDevice(t *testing.T) {
+	rd8Seq, _ := NewRD8().GenerateSeq(rdxPattern(NewRD8()))
+	if _, err := NewTD3().ParseSeq(rd8Seq); err == nil || !strings.Contains(err.Error(), "--device rd8") {
+		t.Errorf("TD3.ParseSeq(RD-8 seq) error = %v, want hint", err)
+	}
+	if _, err := NewRD9().ParseSeq(rd8Seq); err == nil {
+		t.Error("RD9.ParseSeq(RD-8 seq) expected error")
+	}
+	rd9Syx, _ := NewRD9().GenerateSyx(rdxPattern(NewRD9()))
+	if _, err := NewRD8().ParseSyx(rd9Syx); err == nil || !strings.Contains(err.Error(), "--device rd9") {
+		t.Errorf("RD8.ParseSyx(RD-9 dump) error = %v, want hint", err)
+	}
+}
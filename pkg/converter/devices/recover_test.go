@@ -0,0 +1,131 @@
+package devices
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// TestRecoverTruncated cuts the files of every device short and checks that
+// what is recovered is the original pattern, with the lost steps as rests
+func TestRecoverTruncated(t *testing.T) {
+	for n, id := range IDs() {
+		dev, err := New(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := converter.New(dev)
+		pattern := randomPattern(rand.New(rand.NewPCG(uint64(n), 3764)), dev)
+		for _, f := range []converter.Format{converter.FormatSeq, converter.FormatSyx} {
+			data, _, err := c.Generate(pattern, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := c.ParsePattern(data, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, size := range []int{len(data) - 1, len(data) * 2 / 3} {
+				got, warnings, err := c.Recover(data[:size], f)
+				if err != nil {
+					t.Errorf("%s %s cut to %d bytes: %v", id, f, size, err)
+					continue
+				}
+				if len(warnings) == 0 || !strings.Contains(warnings[0].Message, "missing") {
+					t.Errorf("%s %s cut to %d bytes: warnings %v", id, f, size, warnings)
+				}
+				for _, d := range converter.Diff(want, got) {
+					if d.Step >= 0 && !isRest(got, d.Step) {
+						t.Errorf("%s %s cut to %d bytes: %s", id, f, size, d)
+					}
+				}
+			}
+		}
+	}
+}
+
+// isRest reports whether step i of p is silent on every track
+func isRest(p *converter.Pattern, i int) bool {
+	if i < len(p.Steps) && p.Steps[i].Gate {
+		return false
+	}
+	for _, t := range p.Tracks {
+		if i < len(t.Steps) && t.Steps[i].Gate {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRecover(t *testing.T) {
+	pattern := &converter.Pattern{Length: 16, Tempo: 120}
+	for i := range 16 {
+		pattern.Steps = append(pattern.Steps, converter.Step{Note: uint8(36 + i), Gate: true, Accent: i%4 == 0, Velocity: 100})
+	}
+	c := converter.New(NewTD3())
+	seq, _, err := c.Generate(pattern, converter.FormatSeq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syx, _, err := c.Generate(pattern, converter.FormatSyx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	damage := func(data []byte, f func(b []byte) []byte) []byte {
+		return f(bytes.Clone(data))
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		format   converter.Format
+		warnings []string
+		rests    []int
+	}{
+		{"garbled header", damage(seq, func(b []byte) []byte { b[1], b[2] = 0, 0; return b }), converter.FormatSeq,
+			[]string{"bytes 1-2 of the header garbled; restored"}, nil},
+		{"rest mask cut off", seq[:len(seq)-4], converter.FormatSeq,
+			[]string{"bytes 142-145 missing: the file ends after 142 of 146 bytes", "steps 1-16 read as rests"}, []int{0, 15}},
+		{"missing end byte", syx[:len(syx)-1], converter.FormatSyx,
+			[]string{"byte 40 missing: the file ends after 40 of 41 bytes"}, nil},
+		{"truncated dump", syx[:len(syx)-8], converter.FormatSyx,
+			[]string{"bytes 33-40 missing: the file ends after 33 of 41 bytes", "steps 14-16 read as rests"}, []int{13, 15}},
+		{"junk around the dump", append(append([]byte{0x00, 0x13}, syx...), 0x42), converter.FormatSyx,
+			[]string{"bytes 0-1 before the F0 start byte ignored", "byte 43 after the F7 end byte ignored"}, nil},
+		{"stray status byte", damage(syx, func(b []byte) []byte { b[12] = 0x90; return b }), converter.FormatSyx,
+			[]string{"byte 12 garbled (not SysEx data)", "step 3 read as a rest"}, []int{2}},
+		{"wrong checksum", damage(syx, func(b []byte) []byte { b[len(b)-2] ^= 0x01; return b }), converter.FormatSyx,
+			[]string{"checksum 0x"}, nil},
+		{"wrong end byte", damage(syx, func(b []byte) []byte { b[len(b)-1] = 0x00; return b }), converter.FormatSyx,
+			[]string{"byte 40 is not the F7 end byte; restored"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := c.Recover(tt.data, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != len(tt.warnings) {
+				t.Fatalf("warnings = %v, want %q", warnings, tt.warnings)
+			}
+			for i, w := range warnings {
+				if !strings.Contains(w.String(), tt.warnings[i]) {
+					t.Errorf("warning %d = %q, want %q", i, w, tt.warnings[i])
+				}
+			}
+			for _, i := range tt.rests {
+				if got.Steps[i].Gate {
+					t.Errorf("step %d = %+v, want a rest", i+1, got.Steps[i])
+				}
+			}
+			if len(tt.rests) == 0 {
+				if diffs := converter.Diff(pattern, got); len(diffs) > 0 {
+					t.Errorf("recovered pattern differs: %v", diffs)
+				}
+			}
+		})
+	}
+}
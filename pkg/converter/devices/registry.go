@@ -0,0 +1,233 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Info describes a registered device handler
+type Info struct {
+	ID          string   // Name used with --device and the API, e.g. "td3"
+	Aliases     []string // Other accepted names, e.g. "td-3"
+	Name        string
+	Description string
+	New         func() converter.Device
+	// Formats lists the file formats the device handles (default
+	// DefaultFormats)
+	Formats []string
+
+	// perCall makes Shared return a new handler on every call, for
+	// handlers not known to be stateless
+	perCall bool
+}
+
+// DefaultFormats are the formats of a device handler that declares none:
+// its own .seq and .syx files, and MIDI through the generic converter
+var DefaultFormats = []string{"seq", "syx", "midi"}
+
+// Device features reported by Details
+const (
+	FeatureSlots          = "slots"           // Patterns can be written to memory slots
+	FeaturePatternRequest = "pattern_request" // Slots can be dumped on request, e.g. for backups
+	FeatureSysExLayout    = "sysex_layout"    // SysEx checksums can be validated
+	FeatureDrums          = "drums"           // Patterns are drum lanes, played on MIDI channel 10
+)
+
+// Details describes a device and what it can do, so clients can build
+// device-aware interfaces
+type Details struct {
+	ID          string   `json:"id"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	MaxSteps    int      `json:"max_steps"` // 0 = unlimited
+	MinNote     uint8    `json:"min_note"`
+	MaxNote     uint8    `json:"max_note"`
+	Slots       int      `json:"slots,omitempty"`
+	// Defaults are the conversion options suited to the device
+	Defaults converter.Defaults `json:"defaults"`
+	Features []string           `json:"features"`
+	Formats  []string           `json:"formats"`
+}
+
+// Details returns the device's capabilities and features, taken from a new
+// handler
+func (info Info) Details() Details {
+	dev := info.New()
+	caps := converter.DeviceCapabilities(dev)
+	d := Details{
+		ID:          info.ID,
+		Aliases:     info.Aliases,
+		Name:        info.Name,
+		Description: info.Description,
+		MaxSteps:    caps.MaxSteps,
+		MinNote:     caps.MinNote,
+		MaxNote:     caps.MaxNote,
+		Defaults:    caps.Defaults,
+		Features:    []string{},
+		Formats:     info.Formats,
+	}
+	if d.Formats == nil {
+		d.Formats = DefaultFormats
+	}
+	if slots, ok := dev.(converter.SlotDevice); ok {
+		d.Slots = slots.Slots()
+		d.Features = append(d.Features, FeatureSlots)
+	}
+	if _, ok := dev.(converter.PatternRequester); ok {
+		d.Features = append(d.Features, FeaturePatternRequest)
+	}
+	if _, ok := dev.(converter.SysExDevice); ok {
+		d.Features = append(d.Features, FeatureSysExLayout)
+	}
+	if _, ok := dev.(converter.DrumDevice); ok {
+		d.Features = append(d.Features, FeatureDrums)
+	}
+	return d
+}
+
+// DefaultID is the device used when none is named
+const DefaultID = "td3"
+
+// ErrUnknownDevice is returned for device names that are not registered
+var ErrUnknownDevice = errors.New("unknown device")
+
+var (
+	mu       sync.RWMutex // guards registry
+	registry = map[string]Info{}
+)
+
+// shared holds the handlers returned by Shared, keyed by the lowercased
+// name asked for, so aliases skip the registry search too
+var shared sync.Map
+
+// Register adds a device handler made by factory under a name, replacing
+// any handler of that name. It is the registration entry point for
+// third-party devices; the handler's Name is used as its display name, and
+// it handles DefaultFormats. Shared calls factory every time for these
+// handlers, so they may hold state.
+func Register(name string, factory func() converter.Device) {
+	RegisterInfo(Info{ID: name, Name: factory().Name(), New: factory, perCall: true})
+}
+
+// RegisterInfo adds a device handler to the registry, replacing any handler
+// with the same ID. The device files of this package register themselves
+// with it from init, with the aliases, description, and formats the device
+// list shows. Shared hands one handler made by info.New to every caller, so
+// the handlers must hold no state and be safe for concurrent use.
+func RegisterInfo(info Info) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[strings.ToLower(info.ID)] = info
+	shared.Clear()
+}
+
+// New returns a new handler for a device ID or alias, or DefaultID's when
+// name is empty
+func New(name string) (converter.Device, error) {
+	info, err := find(name)
+	if err != nil {
+		return nil, err
+	}
+	return info.New(), nil
+}
+
+// Validate checks that a device ID or alias is registered; empty names
+// stand for DefaultID
+func Validate(name string) error {
+	_, err := find(name)
+	return err
+}
+
+func find(name string) (Info, error) {
+	if strings.TrimSpace(name) == "" {
+		name = DefaultID
+	}
+	info, ok := LookupInfo(name)
+	if !ok {
+		return Info{}, fmt.Errorf("%w %q (use %s)", ErrUnknownDevice, name, strings.Join(IDs(), ", "))
+	}
+	return info, nil
+}
+
+// Lookup returns a new handler for a device ID or alias (case-insensitive)
+func Lookup(name string) (converter.Device, bool) {
+	info, ok := LookupInfo(name)
+	if !ok {
+		return nil, false
+	}
+	return info.New(), true
+}
+
+// Shared returns a handler for a device ID or alias like Lookup, but the same
+// one on every call, so hot paths such as the API server do not construct a
+// handler per request. Handlers registered with RegisterInfo hold no state,
+// so one can serve concurrent conversions; callers must not modify it.
+// Handlers registered with Register are not shared: each call returns a new
+// one.
+func Shared(name string) (converter.Device, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if dev, ok := shared.Load(key); ok {
+		return dev.(converter.Device), true
+	}
+	info, ok := LookupInfo(key)
+	if !ok {
+		return nil, false
+	}
+	if info.perCall {
+		return info.New(), true
+	}
+	// Aliases of a device share its ID's handler
+	id := strings.ToLower(info.ID)
+	dev, ok := shared.Load(id)
+	if !ok {
+		dev, _ = shared.LoadOrStore(id, info.New())
+	}
+	shared.Store(key, dev)
+	return dev.(converter.Device), true
+}
+
+// LookupInfo returns the registry entry for a device ID or alias
+func LookupInfo(name string) (Info, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	name = strings.ToLower(strings.TrimSpace(name))
+	if info, ok := registry[name]; ok {
+		return info, true
+	}
+	for _, info := range registry {
+		for _, alias := range info.Aliases {
+			if strings.EqualFold(alias, name) {
+				return info, true
+			}
+		}
+	}
+	return Info{}, false
+}
+
+// List returns all registered devices sorted by ID
+func List() []Info {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Info, 0, len(registry))
+	for _, info := range registry {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// IDs returns the IDs of all registered devices, sorted
+func IDs() []string {
+	list := List()
+	ids := make([]string, len(list))
+	for i, info := range list {
+		ids[i] = info.ID
+	}
+	return ids
+}
@@ -0,0 +1,121 @@
+package devices
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestShared(t *testing.T) {
+	dev, ok := Shared("TD-3")
+	if !ok || dev.Name() != NewTD3().Name() {
+		t.Fatalf("Shared(TD-3) = %v, %v", dev, ok)
+	}
+	if _, ok := Shared("tb303"); ok {
+		t.Error("Shared(tb303) found an unknown device")
+	}
+
+	// Registering a device again replaces its shared handler
+	info, _ := LookupInfo("td3")
+	defer RegisterInfo(info)
+	replaced := info
+	replaced.New = func() converter.Device { return NewMS1() }
+	RegisterInfo(replaced)
+	if dev, _ := Shared("td3"); dev.Name() != NewMS1().Name() {
+		t.Errorf("after re-registering, Shared(td3) = %s", dev.Name())
+	}
+}
+
+// acidBox is a third-party device as a plugin package would register it
+type acidBox struct{ *TD3 }
+
+func (acidBox) Name() string { return "Acid Box" }
+
+func TestRegister(t *testing.T) {
+	made := 0
+	Register("acidbox", func() converter.Device {
+		made++
+		return acidBox{NewTD3()}
+	})
+	defer func() {
+		mu.Lock()
+		delete(registry, "acidbox")
+		mu.Unlock()
+		shared.Clear()
+	}()
+
+	dev, err := New("AcidBox")
+	if err != nil || dev.Name() != "Acid Box" {
+		t.Fatalf("New(AcidBox) = %v, %v", dev, err)
+	}
+	if info, ok := LookupInfo("acidbox"); !ok || info.Name != "Acid Box" {
+		t.Errorf("LookupInfo(acidbox) = %+v, %v", info, ok)
+	}
+	if !slices.Contains(IDs(), "acidbox") {
+		t.Errorf("IDs() = %v, want acidbox listed", IDs())
+	}
+	// Third-party handlers may hold state, so they are not shared
+	made = 0
+	Shared("acidbox")
+	Shared("AcidBox")
+	if made != 2 {
+		t.Errorf("Shared(acidbox) made %d handlers for 2 calls, want one per call", made)
+	}
+
+	if dev, err := New(""); err != nil || dev.Name() != NewTD3().Name() {
+		t.Errorf("New(\"\") = %v, %v, want the default device", dev, err)
+	}
+	if err := Validate("tb303"); !errors.Is(err, ErrUnknownDevice) {
+		t.Errorf("Validate(tb303) error = %v, want ErrUnknownDevice", err)
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	for b.Loop() {
+		Lookup("td-3")
+	}
+}
+
+func BenchmarkShared(b *testing.B) {
+	for b.Loop() {
+		Shared("td-3")
+	}
+}
+
+// TestVerifyRoundtrip converts a pattern with every articulation to each
+// registered device with the round-trip check on: what a device cannot
+// store must be declared lossy, and everything else read back as written
+func TestVerifyRoundtrip(t *testing.T) {
+	line := &converter.Pattern{Tempo: 133, Length: 16}
+	drums := &converter.Pattern{Tempo: 133, Length: 16, Tracks: []converter.Track{
+		{Name: "BD", Note: 36, Steps: make([]converter.Step, 16)},
+		{Name: "SD", Note: 38, Steps: make([]converter.Step, 16)},
+	}}
+	for i := range 16 {
+		s := converter.Step{Note: uint8(36 + i%7), Gate: i%5 != 3, Velocity: 100}
+		if s.Gate {
+			s.Accent, s.Slide, s.Tie = i%3 == 0, i%4 == 1, i%6 == 2
+		}
+		line.Steps = append(line.Steps, s)
+		drums.Tracks[i%2].Steps[i] = converter.Step{Gate: i%3 == 0, Accent: i%6 == 0, Velocity: 100}
+	}
+
+	for _, id := range IDs() {
+		dev, _ := New(id)
+		pattern := line
+		if _, ok := dev.(converter.DrumDevice); ok {
+			pattern = drums
+		}
+		c := converter.New(dev)
+		opts := converter.DefaultOptions()
+		opts.VerifyRoundtrip = true
+		c.SetOptions(opts)
+		for _, f := range []converter.Format{converter.FormatSeq, converter.FormatSyx} {
+			if _, _, err := c.Generate(pattern, f); err != nil {
+				t.Errorf("%s %s: %v", id, f, err)
+			}
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
 )
 
 // TD3 device constants
@@ -39,6 +41,14 @@ const (
 	SysExEnd       = 0xF7
 	PatternDump    = 0x40
 	PatternRequest = 0x41
+	PatternWrite   = 0x42 // Pattern dump addressed to a memory slot
+)
+
+// TD-3 pattern memory: 4 groups, each with sections A and B of 8 patterns
+const (
+	TD3Groups          = 4
+	TD3Sections        = 2
+	TD3PatternsPerSect = 8
 )
 
 // TD3 header magic bytes
@@ -47,6 +57,16 @@ var td3HeaderMagic = []byte{0x23, 0x98, 0x54, 0x76}
 // TD3 implements the Device interface for Behringer TD-3
 type TD3 struct{}
 
+func init() {
+	RegisterInfo(Info{
+		ID:          "td3",
+		Aliases:     []string{"td-3"},
+		Name:        "Behringer TD-3",
+		Description: "TB-303 clone",
+		New:         func() converter.Device { return NewTD3() },
+	})
+}
+
 // NewTD3 creates a new TD-3 device handler
 func NewTD3() *TD3 {
 	return &TD3{}
@@ -62,18 +82,41 @@ func (t *TD3) ID() uint8 {
 	return TD3DeviceID
 }
 
+// Capabilities returns the TD-3 pattern limits. Notes are stored relative to
+// MIDI note 24, so lower notes cannot be represented.
+func (t *TD3) Capabilities() converter.Capabilities {
+	return converter.Capabilities{
+		MaxSteps: MaxSteps,
+		MinNote:  24,
+		MaxNote:  127,
+	}
+}
+
 // ParseSeq parses a .seq file into a Pattern
 // Format based on https://github.com/claziss/CraveSeq
 func (t *TD3) ParseSeq(data []byte) (*converter.Pattern, error) {
+	switch seqDeviceName(data) {
+	case ms1SeqName:
+		return nil, errors.New("this is an MS-1 .seq file; use --device ms1")
+	case rd6SeqName:
+		return nil, errors.New("this is an RD-6 .seq file; use --device rd6")
+	case rd8.seqName:
+		return nil, errors.New("this is an RD-8 .seq file; use --device rd8")
+	case rd9.seqName:
+		return nil, errors.New("this is an RD-9 .seq file; use --device rd9")
+	case edgeSeqName:
+		return nil, errors.New("this is an Edge .seq file; use --device edge")
+	}
+
 	// Check minimum size
 	if len(data) < TD3SeqMinSize {
-		return nil, fmt.Errorf("seq data too short: got %d bytes, need at least %d", len(data), TD3SeqMinSize)
+		return nil, converter.ErrorfAt(len(data), "pattern data", "seq data too short: got %d bytes, need at least %d", len(data), TD3SeqMinSize)
 	}
 
 	// Verify header magic
 	if data[0] != td3HeaderMagic[0] || data[1] != td3HeaderMagic[1] ||
 		data[2] != td3HeaderMagic[2] || data[3] != td3HeaderMagic[3] {
-		return nil, errors.New("invalid TD-3 seq file: wrong magic bytes")
+		return nil, converter.ErrorAt(0, "magic bytes", errors.New("invalid TD-3 seq file: wrong magic bytes"))
 	}
 
 	// Get sequence length from file
@@ -154,46 +197,8 @@ func (t *TD3) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
 	// Allocate full TD3 seq buffer
 	data := make([]byte, TD3SeqMinSize)
 
-	// Write header magic
-	copy(data[0:4], td3HeaderMagic)
-
-	// Write device name "TD-3" in UTF-16LE
-	data[4] = 0x00
-	data[5] = 0x00
-	data[6] = 0x00
-	data[7] = 0x08 // Device name length
-	data[8] = 0x00
-	data[9] = 0x54 // 'T'
-	data[10] = 0x00
-	data[11] = 0x44 // 'D'
-	data[12] = 0x00
-	data[13] = 0x2d // '-'
-	data[14] = 0x00
-	data[15] = 0x33 // '3'
-
-	// Version info
-	data[16] = 0x00
-	data[17] = 0x00
-	data[18] = 0x00
-	data[19] = 0x0a
-	data[20] = 0x00
-	data[21] = 0x31 // '1'
-	data[22] = 0x00
-	data[23] = 0x2e // '.'
-	data[24] = 0x00
-	data[25] = 0x33 // '3'
-	data[26] = 0x00
-	data[27] = 0x2e // '.'
-	data[28] = 0x00
-	data[29] = 0x37 // '7'
-	data[30] = 0x00
-	data[31] = 0x00
-
-	// Fill/length field
-	data[32] = 0x00
-	data[33] = 0x70 // 112 bytes remaining
-	data[34] = 0x00
-	data[35] = 0x00
+	// Header: magic, device name, version, and fill length
+	copy(data, td3SeqHeader[:])
 
 	seqLength := len(pattern.Steps)
 	if seqLength > MaxSteps {
@@ -258,70 +263,102 @@ func (t *TD3) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
 	return data, nil
 }
 
+// td3SyxLayout describes the TD-3 pattern dump body: device ID, model ID and
+// command header, step payload, then an XOR checksum
+var td3SyxLayout = sysex.Layout{HeaderLen: 3, Checksum: sysex.XOR}
+
+// td3SlotSyxLayout is the slot-addressed pattern write layout, which carries
+// the target slot as a fourth header byte
+var td3SlotSyxLayout = sysex.Layout{HeaderLen: 4, Checksum: sysex.XOR}
+
+// SysExLayout returns the TD-3 pattern dump layout, including its checksum scheme
+func (t *TD3) SysExLayout() sysex.Layout {
+	return td3SyxLayout
+}
+
+// Slots returns the number of TD-3 pattern memory slots
+func (t *TD3) Slots() int {
+	return MaxPatterns
+}
+
+// SlotName returns the front-panel label of a slot, e.g. "1A1" or "4B8"
+// (group, section, pattern)
+func (t *TD3) SlotName(slot int) string {
+	group := slot/(TD3Sections*TD3PatternsPerSect) + 1
+	section := "AB"[(slot/TD3PatternsPerSect)%TD3Sections]
+	pattern := slot%TD3PatternsPerSect + 1
+	return fmt.Sprintf("%d%c%d", group, section, pattern)
+}
+
+// ParseSlot parses a slot label such as "2B8". The group may be omitted
+// ("A1"), in which case group 1 is assumed.
+func (t *TD3) ParseSlot(label string) (int, error) {
+	s := strings.ToUpper(strings.TrimSpace(label))
+	group := 1
+	if len(s) == 3 {
+		group = int(s[0] - '0')
+		s = s[1:]
+	}
+	if len(s) != 2 || group < 1 || group > TD3Groups {
+		return 0, fmt.Errorf("invalid TD-3 slot %q: expected [1-4][A|B][1-8], e.g. 1A1", label)
+	}
+	section := strings.IndexByte("AB", s[0])
+	pattern := int(s[1] - '0')
+	if section < 0 || pattern < 1 || pattern > TD3PatternsPerSect {
+		return 0, fmt.Errorf("invalid TD-3 slot %q: expected [1-4][A|B][1-8], e.g. 1A1", label)
+	}
+	return (group-1)*TD3Sections*TD3PatternsPerSect + section*TD3PatternsPerSect + pattern - 1, nil
+}
+
 // ParseSyx parses a .syx SysEx file into a Pattern
 func (t *TD3) ParseSyx(data []byte) (*converter.Pattern, error) {
 	if len(data) < 10 {
-		return nil, errors.New("syx data too short")
+		return nil, converter.ErrorAt(len(data), "message", errors.New("syx data too short"))
 	}
 
 	// Validate SysEx structure
 	if data[0] != SysExStart {
-		return nil, errors.New("invalid SysEx: missing start byte")
+		return nil, converter.ErrorAt(0, "start byte", errors.New("invalid SysEx: missing start byte"))
 	}
 	if data[len(data)-1] != SysExEnd {
-		return nil, errors.New("invalid SysEx: missing end byte")
+		return nil, converter.ErrorAt(len(data)-1, "end byte", errors.New("invalid SysEx: missing end byte"))
 	}
 
 	// Verify Behringer manufacturer ID
-	if len(data) > 4 && data[1] == 0x00 && data[2] == TD3Manufacturer && data[3] == TD3ManufID2 {
+	if sysex.HasManufacturer(data, sysex.Behringer) {
 		return t.parseBehringerSyx(data)
 	}
 
-	return nil, errors.New("unrecognized SysEx format")
+	return nil, converter.ErrorfAt(1, "manufacturer ID", "unrecognized SysEx format: %s", sysex.Describe(data))
 }
 
 // parseBehringerSyx parses Behringer-specific SysEx format
 func (t *TD3) parseBehringerSyx(data []byte) (*converter.Pattern, error) {
-	pattern := &converter.Pattern{
-		Name:     "TD-3 SysEx Pattern",
-		DeviceID: TD3DeviceID,
-		Steps:    make([]converter.Step, 0, MaxSteps),
-		Length:   MaxSteps,
-		Tempo:    120.0,
+	// Reject dumps from other Behringer models with a pointer to the right device
+	if data[5] != TD3ModelID {
+		return nil, converter.ErrorfAt(5, "model ID", "not a TD-3 pattern dump: %s", sysex.Describe(data))
 	}
 
-	// Skip header bytes (F0, manufacturer ID, device ID, model ID, command)
-	headerLen := 8
-	if len(data) < headerLen+MaxSteps*2 {
-		return nil, fmt.Errorf("syx data too short: got %d, need at least %d", len(data), headerLen+MaxSteps*2)
+	layout := t.SysExLayout()
+	if data[6] == PatternWrite {
+		layout = td3SlotSyxLayout
 	}
 
-	// Parse step data from SysEx payload
-	for i := 0; i < MaxSteps; i++ {
-		offset := headerLen + i*2
-		if offset+1 >= len(data)-1 {
-			break
-		}
-
-		noteData := data[offset]
-		attrData := data[offset+1]
-
-		step := converter.Step{
-			Note:     (noteData & 0x7F) + 24, // Add octave offset
-			Gate:     (attrData & 0x01) != 0,
-			Accent:   (attrData & 0x02) != 0,
-			Slide:    (attrData & 0x04) != 0,
-			Tie:      (attrData & 0x08) != 0,
-			Velocity: 100,
-		}
-
-		if step.Accent {
-			step.Velocity = 127
-		}
-
-		pattern.Steps = append(pattern.Steps, step)
+	msg, err := sysex.Parse(data, layout)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Payload) < MaxSteps*2 {
+		return nil, converter.ErrorfAt(msg.PayloadOffset()+len(msg.Payload), "pattern data", "syx data too short: got %d payload bytes, need %d", len(msg.Payload), MaxSteps*2)
 	}
 
+	pattern := &converter.Pattern{
+		Name:     "TD-3 SysEx Pattern",
+		DeviceID: msg.Header[0],
+		Length:   MaxSteps,
+		Tempo:    120.0,
+	}
+	pattern.Steps = parseSyxSteps(msg.Payload, MaxSteps)
 	return pattern, nil
 }
 
@@ -331,40 +368,58 @@ func (t *TD3) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
 		return nil, errors.New("nil pattern")
 	}
 
-	// Calculate total message length
-	dataLen := MaxSteps * 2
-	totalLen := 1 + 3 + 1 + 1 + 1 + dataLen + 1 + 1
-
-	syx := make([]byte, totalLen)
-	idx := 0
-
-	// SysEx start
-	syx[idx] = SysExStart
-	idx++
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, TD3ModelID, PatternDump).
+		Payload(syxStepPayload(pattern, MaxSteps)...).
+		Checksum(t.SysExLayout().Checksum).
+		Build()
+}
 
-	// Behringer manufacturer ID (extended format: 00 20 32)
-	syx[idx] = 0x00
-	idx++
-	syx[idx] = TD3Manufacturer
-	idx++
-	syx[idx] = TD3ManufID2
-	idx++
+// GenerateSyxSlot generates a SysEx pattern write that stores the pattern in
+// the given memory slot
+func (t *TD3) GenerateSyxSlot(pattern *converter.Pattern, slot int) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	if slot < 0 || slot >= MaxPatterns {
+		return nil, fmt.Errorf("slot %d out of range (0-%d)", slot, MaxPatterns-1)
+	}
 
-	// Device ID
-	syx[idx] = TD3DeviceID
-	idx++
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, TD3ModelID, PatternWrite, uint8(slot)).
+		Payload(syxStepPayload(pattern, MaxSteps)...).
+		Checksum(td3SlotSyxLayout.Checksum).
+		Build()
+}
 
-	// Model ID
-	syx[idx] = TD3ModelID
-	idx++
+// PatternRequest generates the SysEx that asks the unit with deviceID to dump
+// a pattern slot
+func (t *TD3) PatternRequest(slot int, deviceID uint8) ([]byte, error) {
+	if slot < 0 || slot >= MaxPatterns {
+		return nil, fmt.Errorf("slot %d out of range (0-%d)", slot, MaxPatterns-1)
+	}
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(deviceID&0x7F, TD3ModelID, PatternRequest, uint8(slot)).
+		Build()
+}
 
-	// Command (pattern dump)
-	syx[idx] = PatternDump
-	idx++
+// IsPatternReply reports whether msg is the slot-addressed dump a TD-3 sends
+// in answer to PatternRequest. Dumps from units with another device ID are
+// rejected, so several TD-3s can share a MIDI chain.
+func (t *TD3) IsPatternReply(msg []byte, slot int, deviceID uint8) bool {
+	return len(msg) > 8 &&
+		sysex.HasManufacturer(msg, sysex.Behringer) &&
+		msg[4] == deviceID&0x7F &&
+		msg[5] == TD3ModelID &&
+		msg[6] == PatternWrite &&
+		int(msg[7]) == slot
+}
 
-	// Pattern data
-	var checksum uint8
-	for i := 0; i < MaxSteps; i++ {
+// syxStepPayload encodes the first steps of a pattern as Behringer SysEx
+// payload bytes: a note byte and an attribute byte per step
+func syxStepPayload(pattern *converter.Pattern, steps int) []byte {
+	payload := make([]byte, 0, steps*2)
+	for i := 0; i < steps; i++ {
 		var step converter.Step
 		if i < len(pattern.Steps) {
 			step = pattern.Steps[i]
@@ -375,10 +430,6 @@ func (t *TD3) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
 		if noteVal >= 24 {
 			noteVal -= 24
 		}
-		noteByte := noteVal & 0x7F
-		syx[idx] = noteByte
-		checksum ^= noteByte
-		idx++
 
 		// Attribute byte
 		var attr uint8
@@ -394,19 +445,37 @@ func (t *TD3) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
 		if step.Tie {
 			attr |= 0x08
 		}
-		syx[idx] = attr
-		checksum ^= attr
-		idx++
+
+		payload = append(payload, noteVal&0x7F, attr)
 	}
 
-	// Checksum (XOR of all data bytes)
-	syx[idx] = checksum & 0x7F
-	idx++
+	return payload
+}
 
-	// SysEx end
-	syx[idx] = SysExEnd
+// parseSyxSteps decodes steps from Behringer SysEx payload bytes (note byte,
+// attribute byte)
+func parseSyxSteps(payload []byte, steps int) []converter.Step {
+	result := make([]converter.Step, 0, steps)
+	for i := 0; i < steps; i++ {
+		noteData := payload[i*2]
+		attrData := payload[i*2+1]
 
-	return syx, nil
+		step := converter.Step{
+			Note:     (noteData & 0x7F) + 24, // Add octave offset
+			Gate:     (attrData & 0x01) != 0,
+			Accent:   (attrData & 0x02) != 0,
+			Slide:    (attrData & 0x04) != 0,
+			Tie:      (attrData & 0x08) != 0,
+			Velocity: 100,
+		}
+
+		if step.Accent {
+			step.Velocity = 127
+		}
+
+		result = append(result, step)
+	}
+	return result
 }
 
 // Helper function to ensure binary package is used
@@ -1,6 +1,7 @@
 package devices
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/james-see/synthtribe2midi/pkg/converter"
@@ -244,3 +245,192 @@ func TestTD3RoundTrip(t *testing.T) {
 		t.Errorf("Round trip: step 4 slide = %v, want %v", parsed.Steps[4].Slide, original.Steps[4].Slide)
 	}
 }
+
+func TestTD3SyxDeviceID(t *testing.T) {
+	td3 := NewTD3()
+
+	pattern := &converter.Pattern{
+		Name:     "Chain",
+		DeviceID: 5,
+		Steps:    []converter.Step{{Note: 48, Gate: true, Velocity: 100}},
+	}
+
+	data, err := td3.GenerateSyx(pattern)
+	if err != nil {
+		t.Fatalf("GenerateSyx() error = %v", err)
+	}
+	if data[4] != 5 {
+		t.Errorf("device ID byte = %d, want 5", data[4])
+	}
+
+	parsed, err := td3.ParseSyx(data)
+	if err != nil {
+		t.Fatalf("ParseSyx() error = %v", err)
+	}
+	if parsed.DeviceID != 5 {
+		t.Errorf("parsed DeviceID = %d, want 5", parsed.DeviceID)
+	}
+
+	// The converter option overrides the pattern's device ID
+	conv := converter.New(td3)
+	opts := conv.Options()
+	id := uint8(9)
+	opts.DeviceID = &id
+	conv.SetOptions(opts)
+
+	seqData, err := td3.GenerateSeq(pattern)
+	if err != nil {
+		t.Fatalf("GenerateSeq() error = %v", err)
+	}
+	out, err := conv.SeqToSyx(seqData)
+	if err != nil {
+		t.Fatalf("SeqToSyx() error = %v", err)
+	}
+	got, err := converter.ExtractDeviceID(out)
+	if err != nil {
+		t.Fatalf("ExtractDeviceID() error = %v", err)
+	}
+	if got != 9 {
+		t.Errorf("ExtractDeviceID() = %d, want 9", got)
+	}
+}
+
+func TestTD3SyxRoundTrip(t *testing.T) {
+	td3 := NewTD3()
+
+	original := &converter.Pattern{
+		Name:  "Test",
+		Steps: make([]converter.Step, MaxSteps),
+	}
+	original.Steps[0] = converter.Step{Note: 48, Gate: true, Velocity: 100}
+	original.Steps[1] = converter.Step{Note: 51, Gate: true, Accent: true, Velocity: 127}
+	original.Steps[2] = converter.Step{Note: 51, Gate: true, Tie: true, Velocity: 100}
+	original.Steps[5] = converter.Step{Note: 55, Gate: true, Slide: true, Velocity: 100}
+
+	data, err := td3.GenerateSyx(original)
+	if err != nil {
+		t.Fatalf("GenerateSyx() error = %v", err)
+	}
+
+	parsed, err := td3.ParseSyx(data)
+	if err != nil {
+		t.Fatalf("ParseSyx() error = %v", err)
+	}
+
+	for i, step := range original.Steps {
+		if !step.Gate {
+			continue
+		}
+		got := parsed.Steps[i]
+		if got.Note != step.Note || got.Accent != step.Accent || got.Slide != step.Slide || got.Tie != step.Tie {
+			t.Errorf("step %d = %+v, want %+v", i, got, step)
+		}
+	}
+}
+
+func TestTD3ParseSyxChecksumMismatch(t *testing.T) {
+	td3 := NewTD3()
+
+	data, err := td3.GenerateSyx(&converter.Pattern{
+		Steps: []converter.Step{{Note: 48, Gate: true, Velocity: 100}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateSyx() error = %v", err)
+	}
+
+	// Corrupt the checksum byte just before F7
+	data[len(data)-2] ^= 0x01
+	if _, err := td3.ParseSyx(data); err == nil {
+		t.Error("ParseSyx() expected checksum mismatch error")
+	}
+}
+
+func TestTD3Slots(t *testing.T) {
+	td3 := NewTD3()
+
+	tests := []struct {
+		label string
+		slot  int
+		name  string
+	}{
+		{"1A1", 0, "1A1"},
+		{"A1", 0, "1A1"},
+		{"b8", 15, "1B8"},
+		{"2A1", 16, "2A1"},
+		{"4B8", 63, "4B8"},
+	}
+	for _, tt := range tests {
+		slot, err := td3.ParseSlot(tt.label)
+		if err != nil {
+			t.Fatalf("ParseSlot(%q) error = %v", tt.label, err)
+		}
+		if slot != tt.slot {
+			t.Errorf("ParseSlot(%q) = %d, want %d", tt.label, slot, tt.slot)
+		}
+		if name := td3.SlotName(slot); name != tt.name {
+			t.Errorf("SlotName(%d) = %q, want %q", slot, name, tt.name)
+		}
+	}
+
+	for _, bad := range []string{"", "5A1", "1C1", "A9", "1A0", "12A1"} {
+		if _, err := td3.ParseSlot(bad); err == nil {
+			t.Errorf("ParseSlot(%q) expected error", bad)
+		}
+	}
+
+	data, err := td3.GenerateSyxSlot(&converter.Pattern{
+		Steps: []converter.Step{{Note: 48, Gate: true, Velocity: 100}},
+	}, 17)
+	if err != nil {
+		t.Fatalf("GenerateSyxSlot() error = %v", err)
+	}
+	if data[6] != PatternWrite || data[7] != 17 {
+		t.Errorf("header = % X, want write to slot 17", data[4:8])
+	}
+	parsed, err := td3.ParseSyx(data)
+	if err != nil {
+		t.Fatalf("ParseSyx() slot write error = %v", err)
+	}
+	if !parsed.Steps[0].Gate || parsed.Steps[0].Note != 48 {
+		t.Errorf("parsed step 0 = %+v", parsed.Steps[0])
+	}
+}
+
+func TestTD3SeqHeader(t *testing.T) {
+	// The header as written by SynthTribe: magic, "TD-3" and "1.3.7" in
+	// UTF-16, and the fill length
+	want := []byte{
+		0x23, 0x98, 0x54, 0x76, 0x00, 0x00, 0x00, 0x08,
+		0x00, 'T', 0x00, 'D', 0x00, '-', 0x00, '3',
+		0x00, 0x00, 0x00, 0x0a, 0x00, '1', 0x00, '.',
+		0x00, '3', 0x00, '.', 0x00, '7', 0x00, 0x00,
+		0x00, 0x70, 0x00, 0x00,
+	}
+	data, err := NewTD3().GenerateSeq(&converter.Pattern{Length: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[:NotesOffset], want) {
+		t.Errorf("header = % X, want % X", data[:NotesOffset], want)
+	}
+
+	// Writing into a generated file leaves the template alone
+	data[9] = 'X'
+	if again, _ := NewTD3().GenerateSeq(&converter.Pattern{Length: 16}); again[9] != 'T' {
+		t.Error("the header template was modified through generated data")
+	}
+}
+
+func BenchmarkTD3GenerateSeq(b *testing.B) {
+	td3 := NewTD3()
+	pattern := &converter.Pattern{Length: 16, Steps: make([]converter.Step, 16)}
+	for i := range pattern.Steps {
+		pattern.Steps[i] = converter.Step{Note: 45 + uint8(i%5), Gate: true, Velocity: 100, Accent: i%4 == 0}
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := td3.GenerateSeq(pattern); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// TD-3-MO extensions to the TD-3 formats. Its .seq files are TD-3 files
+// followed by 16-bit sub-accent and muted accent masks, nibble-encoded like
+// the tie and rest masks; its dumps are TD-3 dumps whose attribute bytes use
+// two more bits. TD-3 handlers ignore both, reading plain accents.
+const (
+	TD3MOSubAccentOffset   = TD3SeqMinSize              // 146
+	TD3MOMutedAccentOffset = TD3MOSubAccentOffset + 4   // 150
+	TD3MOSeqSize           = TD3MOMutedAccentOffset + 4 // 154
+
+	td3moSubAccent   = 0x10 // attribute bit of a sub-accent
+	td3moMutedAccent = 0x20 // attribute bit of a muted accent
+)
+
+func init() {
+	RegisterInfo(Info{
+		ID:          "td3mo",
+		Aliases:     []string{"td-3-mo", "devilfish"},
+		Name:        "Behringer TD-3-MO",
+		Description: "TB-303 clone with Devil Fish mods (sub-accents, muted accents)",
+		New:         func() converter.Device { return NewTD3MO() },
+	})
+}
+
+// TD3MO implements the Device interface for the Behringer TD-3-MO, the TD-3
+// with Devil Fish modifications. Its patterns are TD-3 patterns whose
+// accents can be sub-accents or muted accents.
+type TD3MO struct {
+	TD3
+}
+
+// NewTD3MO creates a new TD-3-MO device handler
+func NewTD3MO() *TD3MO {
+	return &TD3MO{}
+}
+
+// Name returns the device name
+func (t *TD3MO) Name() string {
+	return "Behringer TD-3-MO"
+}
+
+// AccentLayers reports that TD-3-MO patterns carry accent levels
+func (t *TD3MO) AccentLayers() bool {
+	return true
+}
+
+// ParseSeq parses a TD-3-MO .seq file; plain TD-3 files read as patterns
+// without accent levels
+func (t *TD3MO) ParseSeq(data []byte) (*converter.Pattern, error) {
+	pattern, err := t.TD3.ParseSeq(data)
+	if err != nil {
+		return nil, err
+	}
+	pattern.Name = "TD-3-MO Pattern"
+	if len(data) < TD3MOSeqSize {
+		return pattern, nil
+	}
+	sub := nibbleMask(data[TD3MOSubAccentOffset : TD3MOSubAccentOffset+4])
+	muted := nibbleMask(data[TD3MOMutedAccentOffset : TD3MOMutedAccentOffset+4])
+	for i := range pattern.Steps {
+		step := &pattern.Steps[i]
+		step.SubAccent = step.Accent && sub&(1<<i) != 0
+		step.MutedAccent = step.Accent && muted&(1<<i) != 0
+	}
+	return pattern, nil
+}
+
+// GenerateSeq generates TD-3-MO .seq data from a Pattern
+func (t *TD3MO) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
+	data, err := t.TD3.GenerateSeq(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var sub, muted uint32
+	for i, step := range pattern.Steps[:min(len(pattern.Steps), MaxSteps)] {
+		if step.Accent && step.SubAccent {
+			sub |= 1 << i
+		}
+		if step.Accent && step.MutedAccent {
+			muted |= 1 << i
+		}
+	}
+	data = append(data, make([]byte, TD3MOSeqSize-len(data))...)
+	copy(data, td3moSeqHeader[:])
+	putNibbleMask(data[TD3MOSubAccentOffset:TD3MOSubAccentOffset+4], sub)
+	putNibbleMask(data[TD3MOMutedAccentOffset:TD3MOMutedAccentOffset+4], muted)
+	return data, nil
+}
+
+// ParseSyx parses a TD-3-MO pattern dump or slot write
+func (t *TD3MO) ParseSyx(data []byte) (*converter.Pattern, error) {
+	pattern, err := t.TD3.ParseSyx(data)
+	if err != nil {
+		return nil, err
+	}
+	if !sysex.HasManufacturer(data, sysex.Behringer) {
+		return pattern, nil
+	}
+	layout := t.SysExLayout()
+	if data[6] == PatternWrite {
+		layout = td3SlotSyxLayout
+	}
+	msg, err := sysex.Parse(data, layout)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pattern.Steps {
+		attr := msg.Payload[i*2+1]
+		step := &pattern.Steps[i]
+		step.SubAccent = step.Accent && attr&td3moSubAccent != 0
+		step.MutedAccent = step.Accent && attr&td3moMutedAccent != 0
+	}
+	return pattern, nil
+}
+
+// GenerateSyx generates a TD-3-MO pattern dump
+func (t *TD3MO) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, TD3ModelID, PatternDump).
+		Payload(td3moStepPayload(pattern)...).
+		Checksum(t.SysExLayout().Checksum).
+		Build()
+}
+
+// GenerateSyxSlot generates a SysEx pattern write that stores the pattern,
+// with its accent levels, in the given memory slot
+func (t *TD3MO) GenerateSyxSlot(pattern *converter.Pattern, slot int) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	if slot < 0 || slot >= MaxPatterns {
+		return nil, fmt.Errorf("slot %d out of range (0-%d)", slot, MaxPatterns-1)
+	}
+	return sysex.NewBuilder(sysex.Behringer...).
+		Header(pattern.DeviceID&0x7F, TD3ModelID, PatternWrite, uint8(slot)).
+		Payload(td3moStepPayload(pattern)...).
+		Checksum(td3SlotSyxLayout.Checksum).
+		Build()
+}
+
+// td3moStepPayload encodes a pattern's steps as TD-3 dump payload with the
+// accent level bits set
+func td3moStepPayload(pattern *converter.Pattern) []byte {
+	payload := syxStepPayload(pattern, MaxSteps)
+	for i, step := range pattern.Steps[:min(len(pattern.Steps), MaxSteps)] {
+		if step.Accent && step.SubAccent {
+			payload[i*2+1] |= td3moSubAccent
+		}
+		if step.Accent && step.MutedAccent {
+			payload[i*2+1] |= td3moMutedAccent
+		}
+	}
+	return payload
+}
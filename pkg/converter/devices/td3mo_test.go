@@ -0,0 +1,162 @@
+package devices
+
+import (
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// td3moPattern is a 16-step pattern with a plain accent, a sub-accent, a
+// muted accent, and a muted sub-accent
+func td3moPattern() *converter.Pattern {
+	p := &converter.Pattern{Length: MaxSteps, Tempo: 120, Steps: make([]converter.Step, MaxSteps)}
+	for i := range p.Steps {
+		p.Steps[i] = converter.Step{Note: 36 + uint8(i%12), Gate: true, Velocity: 100}
+	}
+	for i, s := range []converter.Step{
+		{Accent: true},
+		{Accent: true, SubAccent: true},
+		{Accent: true, MutedAccent: true},
+		{Accent: true, SubAccent: true, MutedAccent: true},
+	} {
+		step := &p.Steps[i*4]
+		step.Accent, step.SubAccent, step.MutedAccent = s.Accent, s.SubAccent, s.MutedAccent
+		step.Velocity = 127
+	}
+	return p
+}
+
+// accentLevels reports each step's accent, sub-accent and muted accent flags
+func accentLevels(p *converter.Pattern) [][3]bool {
+	levels := make([][3]bool, len(p.Steps))
+	for i, s := range p.Steps {
+		levels[i] = [3]bool{s.Accent, s.SubAccent, s.MutedAccent}
+	}
+	return levels
+}
+
+func TestTD3MORoundTrip(t *testing.T) {
+	d := NewTD3MO()
+	pattern := td3moPattern()
+	for _, tt := range []struct {
+		name     string
+		generate func(*converter.Pattern) ([]byte, error)
+		parse    func([]byte) (*converter.Pattern, error)
+	}{
+		{"seq", d.GenerateSeq, d.ParseSeq},
+		{"syx", d.GenerateSyx, d.ParseSyx},
+		{"slot", func(p *converter.Pattern) ([]byte, error) { return d.GenerateSyxSlot(p, 5) }, d.ParseSyx},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.generate(pattern)
+			if err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			got, err := tt.parse(data)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			want := accentLevels(pattern)
+			for i, levels := range accentLevels(got) {
+				if levels != want[i] {
+					t.Errorf("step %d accent levels = %v, want %v", i+1, levels, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTD3MOCompatibility(t *testing.T) {
+	// TD-3 handlers read TD-3-MO files with plain accents, and the TD-3-MO
+	// reads TD-3 files without accent levels
+	mo, td3 := NewTD3MO(), NewTD3()
+	pattern := td3moPattern()
+
+	seq, err := mo.GenerateSeq(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq) != TD3MOSeqSize {
+		t.Errorf("seq size = %d, want %d", len(seq), TD3MOSeqSize)
+	}
+	syx, err := mo.GenerateSyx(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name  string
+		data  []byte
+		parse func([]byte) (*converter.Pattern, error)
+	}{
+		{"seq", seq, td3.ParseSeq},
+		{"syx", syx, td3.ParseSyx},
+	} {
+		got, err := tt.parse(tt.data)
+		if err != nil {
+			t.Fatalf("TD3 %s parse error = %v", tt.name, err)
+		}
+		for i, s := range got.Steps {
+			if s.Accent != pattern.Steps[i].Accent || s.SubAccent || s.MutedAccent {
+				t.Errorf("TD3 %s step %d = %+v, want plain accent %v", tt.name, i+1, s, pattern.Steps[i].Accent)
+			}
+		}
+	}
+
+	plain, err := td3.GenerateSeq(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := mo.ParseSeq(plain)
+	if err != nil {
+		t.Fatalf("TD3MO.ParseSeq(TD-3 seq) error = %v", err)
+	}
+	for i, s := range got.Steps {
+		if s.Accent != pattern.Steps[i].Accent || s.SubAccent || s.MutedAccent {
+			t.Errorf("TD-3 seq step %d = %+v, want plain accent %v", i+1, s, pattern.Steps[i].Accent)
+		}
+	}
+}
+
+func TestTD3MOMIDI(t *testing.T) {
+	// Sub-accents travel as velocity and muted accents as a controller
+	d := NewTD3MO()
+	pattern := td3moPattern()
+	seq, err := d.GenerateSeq(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv := converter.New(d)
+	mid, err := conv.SeqToMIDI(seq)
+	if err != nil {
+		t.Fatalf("SeqToMIDI() error = %v", err)
+	}
+	back, err := conv.MIDIToSeq(mid)
+	if err != nil {
+		t.Fatalf("MIDIToSeq() error = %v", err)
+	}
+	got, err := d.ParseSeq(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := accentLevels(pattern)
+	for i, levels := range accentLevels(got) {
+		if levels != want[i] {
+			t.Errorf("step %d accent levels = %v, want %v", i+1, levels, want[i])
+		}
+	}
+
+	// The TD-3 reads the same MIDI with plain accents
+	td3Seq, err := converter.New(NewTD3()).MIDIToSeq(mid)
+	if err != nil {
+		t.Fatalf("TD-3 MIDIToSeq() error = %v", err)
+	}
+	plain, err := NewTD3().ParseSeq(td3Seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range plain.Steps {
+		if s.Accent != pattern.Steps[i].Accent || s.SubAccent || s.MutedAccent {
+			t.Errorf("TD-3 step %d = %+v, want plain accent %v", i+1, s, pattern.Steps[i].Accent)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// Volca Bass sequence constants. A sequence is 16 steps of three bytes: the
+// MIDI note, the step flags, and the oscillators (VCO1-3) the step plays.
+const (
+	VolcaBassSteps    = 16
+	VolcaBassStepSize = 3
+	VolcaBassSeqSize  = VolcaBassSteps * VolcaBassStepSize // 48
+
+	VolcaBassGate  = 0x01 // Step plays a note; rests have no flags
+	VolcaBassSlide = 0x02 // Slide into the next step
+	VolcaBassVCOs  = 0x07 // Oscillator bits of a step playing all three
+)
+
+// Volca Bass SysEx framing: Korg's 3n channel byte, the 00 01 family and
+// model ID, and the current sequence dump function, then the sequence
+// 7-bit packed
+const (
+	VolcaBassModelID      = 0x2B
+	VolcaBassSequenceDump = 0x4C
+)
+
+// volcaBassSyxLayout is the Volca Bass sequence dump body: channel, family,
+// model, and function header, then the packed sequence, without checksum
+var volcaBassSyxLayout = sysex.Layout{HeaderLen: 5, Checksum: sysex.None}
+
+func init() {
+	RegisterInfo(Info{
+		ID:          "volcabass",
+		Aliases:     []string{"volca-bass", "volca"},
+		Name:        "Korg Volca Bass",
+		Description: "Analog bass synth (16-step sequencer with slides)",
+		New:         func() converter.Device { return NewVolcaBass() },
+	})
+	sysex.RegisterModel(sysex.Model{Manufacturer: sysex.Korg, ID: VolcaBassModelID, Name: "Korg Volca Bass", Device: "volcabass"})
+}
+
+// VolcaBass implements the Device interface for the Korg Volca Bass, so
+// basslines can move between the Volca and the TD-3 through the common
+// Pattern model. The Volca Bass has slides but no accent, so accents are
+// dropped on export; the oscillators a step plays are not converted.
+type VolcaBass struct{}
+
+// NewVolcaBass creates a new Volca Bass device handler
+func NewVolcaBass() *VolcaBass {
+	return &VolcaBass{}
+}
+
+// Name returns the device name
+func (v *VolcaBass) Name() string {
+	return "Korg Volca Bass"
+}
+
+// ID returns the device ID
+func (v *VolcaBass) ID() uint8 {
+	return 0
+}
+
+// Capabilities returns the Volca Bass sequence limits: 16 steps of any MIDI
+// note
+func (v *VolcaBass) Capabilities() converter.Capabilities {
+	return converter.Capabilities{
+		MaxSteps: VolcaBassSteps,
+		MinNote:  0,
+		MaxNote:  127,
+		Lossy:    []string{"accent", "tie"},
+	}
+}
+
+// SysExLayout returns the Volca Bass sequence dump layout
+func (v *VolcaBass) SysExLayout() sysex.Layout {
+	return volcaBassSyxLayout
+}
+
+// ParseSeq parses raw Volca Bass sequence data
+func (v *VolcaBass) ParseSeq(data []byte) (*converter.Pattern, error) {
+	if bytes.HasPrefix(data, td3HeaderMagic) {
+		return nil, errors.New("this is a TD-3 .seq file; use --device td3")
+	}
+	if len(data) != VolcaBassSeqSize {
+		return nil, converter.ErrorfAt(min(len(data), VolcaBassSeqSize), "sequence data", "Volca Bass sequence is %d bytes, want %d", len(data), VolcaBassSeqSize)
+	}
+
+	pattern := &converter.Pattern{
+		Name:   "Volca Bass Sequence",
+		Steps:  make([]converter.Step, VolcaBassSteps),
+		Length: VolcaBassSteps,
+		Tempo:  120.0, // Tempo is not stored with the sequence
+	}
+	for i := range pattern.Steps {
+		note, flags := data[i*VolcaBassStepSize], data[i*VolcaBassStepSize+1]
+		if flags&VolcaBassGate == 0 {
+			continue
+		}
+		if note > 127 {
+			return nil, converter.ErrorfAt(i*VolcaBassStepSize, fmt.Sprintf("step %d note", i+1), "step %d: invalid note %d", i+1, note)
+		}
+		pattern.Steps[i] = converter.Step{
+			Note:     note,
+			Gate:     true,
+			Slide:    flags&VolcaBassSlide != 0,
+			Velocity: 100,
+		}
+	}
+	return pattern, nil
+}
+
+// GenerateSeq generates raw Volca Bass sequence data. Patterns shorter than
+// 16 steps are padded with rests. The Volca Bass has no tie, so a tied step
+// becomes a repeat of the note with a slide into it from the previous step.
+func (v *VolcaBass) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	data := make([]byte, VolcaBassSeqSize)
+	for i, step := range pattern.Steps[:min(len(pattern.Steps), VolcaBassSteps)] {
+		if !step.Gate {
+			continue
+		}
+		if step.Note > 127 {
+			return nil, fmt.Errorf("step %d: invalid note %d", i+1, step.Note)
+		}
+		rec := data[i*VolcaBassStepSize:]
+		rec[0], rec[1], rec[2] = step.Note, VolcaBassGate, VolcaBassVCOs
+		if step.Slide {
+			rec[1] |= VolcaBassSlide
+		}
+		if step.Tie && i > 0 && data[(i-1)*VolcaBassStepSize+1]&VolcaBassGate != 0 {
+			data[(i-1)*VolcaBassStepSize+1] |= VolcaBassSlide
+		}
+	}
+	return data, nil
+}
+
+// ParseSyx parses a Volca Bass sequence dump
+func (v *VolcaBass) ParseSyx(data []byte) (*converter.Pattern, error) {
+	if !sysex.HasManufacturer(data, sysex.Korg) {
+		return nil, converter.ErrorfAt(min(1, len(data)), "manufacturer ID", "not a Volca Bass sequence dump: %s", sysex.Describe(data))
+	}
+	msg, err := sysex.Parse(data, v.SysExLayout())
+	if err != nil {
+		return nil, err
+	}
+	h := msg.Header
+	if h[0]&0xF0 != 0x30 || h[1] != 0x00 || h[2] != 0x01 || h[3] != VolcaBassModelID || h[4] != VolcaBassSequenceDump {
+		return nil, converter.ErrorfAt(2, "message header", "not a Volca Bass sequence dump: header % X", h)
+	}
+	raw, err := sysex.Unpack7(msg.Payload)
+	if err != nil {
+		return nil, converter.ErrorfAt(msg.PayloadOffset()+len(msg.Payload)-1, "packed data", "Volca Bass sequence dump: %w", err)
+	}
+	pattern, err := v.ParseSeq(raw)
+	if err != nil {
+		// Point into the dump rather than the unpacked sequence
+		var perr *converter.ParseError
+		if errors.As(err, &perr) {
+			perr.Offset = msg.PayloadOffset() + sysex.PackedOffset(perr.Offset)
+		}
+		return nil, err
+	}
+	pattern.DeviceID = h[0] & 0x0F
+	return pattern, nil
+}
+
+// GenerateSyx generates a Volca Bass sequence dump on the pattern's device
+// ID, taken as the MIDI channel (0-15)
+func (v *VolcaBass) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
+	raw, err := v.GenerateSeq(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return sysex.NewBuilder(sysex.Korg...).
+		Header(0x30|pattern.DeviceID&0x0F, 0x00, 0x01, VolcaBassModelID, VolcaBassSequenceDump).
+		Payload(sysex.Pack7(raw)...).
+		Checksum(v.SysExLayout().Checksum).
+		Build()
+}
@@ -0,0 +1,100 @@
+package devices
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+func TestVolcaBassRoundTrip(t *testing.T) {
+	v := NewVolcaBass()
+	pattern := &converter.Pattern{
+		Length: VolcaBassSteps,
+		Steps:  make([]converter.Step, VolcaBassSteps),
+	}
+	pattern.Steps[0] = converter.Step{Note: 36, Gate: true, Velocity: 100}
+	pattern.Steps[1] = converter.Step{Note: 48, Gate: true, Slide: true, Velocity: 100}
+	pattern.Steps[2] = converter.Step{Note: 51, Gate: true, Velocity: 100}
+	pattern.Steps[15] = converter.Step{Note: 127, Gate: true, Velocity: 100}
+
+	for _, tt := range []struct {
+		name     string
+		generate func(*converter.Pattern) ([]byte, error)
+		parse    func([]byte) (*converter.Pattern, error)
+	}{
+		{"seq", v.GenerateSeq, v.ParseSeq},
+		{"syx", v.GenerateSyx, v.ParseSyx},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.generate(pattern)
+			if err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			got, err := tt.parse(data)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if got.Length != VolcaBassSteps || len(got.Steps) != VolcaBassSteps {
+				t.Fatalf("got %d steps (length %d), want %d", len(got.Steps), got.Length, VolcaBassSteps)
+			}
+			for i := range pattern.Steps {
+				if got.Steps[i] != pattern.Steps[i] {
+					t.Errorf("step %d = %+v, want %+v", i, got.Steps[i], pattern.Steps[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVolcaBassGenerate(t *testing.T) {
+	v := NewVolcaBass()
+
+	// A TD-3 line: the accent is dropped, and the tie becomes a repeated
+	// note with a slide into it
+	line := &converter.Pattern{DeviceID: 3, Steps: []converter.Step{
+		{Note: 36, Gate: true, Accent: true, Velocity: 127},
+		{Note: 36, Gate: true, Tie: true},
+	}}
+	data, err := v.GenerateSeq(line)
+	if err != nil {
+		t.Fatalf("GenerateSeq() error = %v", err)
+	}
+	want := make([]byte, VolcaBassSeqSize)
+	copy(want, []byte{36, VolcaBassGate | VolcaBassSlide, VolcaBassVCOs, 36, VolcaBassGate, VolcaBassVCOs})
+	if !bytes.Equal(data, want) {
+		t.Errorf("GenerateSeq() = % X, want % X", data, want)
+	}
+
+	syx, err := v.GenerateSyx(line)
+	if err != nil {
+		t.Fatalf("GenerateSyx() error = %v", err)
+	}
+	if head := []byte{0xF0, 0x42, 0x33, 0x00, 0x01, VolcaBassModelID, VolcaBassSequenceDump}; !bytes.HasPrefix(syx, head) {
+		t.Errorf("GenerateSyx() = % X, want channel 4 header % X", syx, head)
+	}
+	if pattern, err := v.ParseSyx(syx); err != nil || pattern.DeviceID != 3 {
+		t.Errorf("ParseSyx() = %+v, %v, want channel 4", pattern, err)
+	}
+	if got := sysex.Describe(syx); got != "this is a Korg Volca Bass dump; use --device volcabass" {
+		t.Errorf("Describe() = %q", got)
+	}
+
+	// Dumps of other devices and short data are rejected
+	if _, err := NewTD3().ParseSyx(syx); err == nil {
+		t.Error("TD3.ParseSyx(Volca Bass dump) expected error")
+	}
+	td3Syx, _ := NewTD3().GenerateSyx(line)
+	if _, err := v.ParseSyx(td3Syx); err == nil || !strings.Contains(err.Error(), "--device td3") {
+		t.Errorf("ParseSyx(TD-3 dump) error = %v, want a device hint", err)
+	}
+	td3Seq, _ := NewTD3().GenerateSeq(line)
+	if _, err := v.ParseSeq(td3Seq); err == nil {
+		t.Error("ParseSeq(TD-3 .seq) expected error")
+	}
+	if _, err := v.ParseSeq(data[:10]); err == nil {
+		t.Error("ParseSeq(10 bytes) expected error")
+	}
+}
@@ -0,0 +1,225 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// x0xb0x pattern memory constants. A pattern is up to 16 step bytes, ended
+// early by an end-of-pattern marker; raw EEPROM and bank dumps are these
+// patterns back to back.
+const (
+	X0xb0xPatternSize = 16
+	X0xb0xNoteMask    = 0x3F // Note number, 0 = rest
+	X0xb0xAccent      = 0x40
+	X0xb0xSlide       = 0x80
+	X0xb0xEnd         = 0xFF // End of pattern marker
+	X0xb0xNoteOffset  = 23   // MIDI note = note number + offset (0x0D = C2)
+)
+
+// x0xb0x SysEx framing: the non-commercial manufacturer ID, an "X0" tag and
+// a command byte, then each pattern byte split into two nibbles since step
+// bytes use the high bit
+const (
+	X0xb0xManufacturer = 0x7D
+	X0xb0xPatternDump  = 0x01
+)
+
+var x0xb0xHeader = []byte{'X', '0', X0xb0xPatternDump}
+
+// x0xb0xSyxLayout is the x0xb0x pattern dump layout: tag and command header,
+// nibble payload, then a 7-bit sum checksum
+var x0xb0xSyxLayout = sysex.Layout{HeaderLen: len(x0xb0xHeader), Checksum: sysex.SumMask}
+
+// X0xb0x implements the Device interface for the x0xb0x DIY TB-303 clone
+// (stock and SokkOS firmware), so pattern archives can be moved to and from
+// the TD-3
+type X0xb0x struct{}
+
+func init() {
+	RegisterInfo(Info{
+		ID:          "x0xb0x",
+		Aliases:     []string{"xoxbox", "sokkos"},
+		Name:        "x0xb0x",
+		Description: "DIY TB-303 clone (stock and SokkOS firmware)",
+		New:         func() converter.Device { return NewX0xb0x() },
+	})
+}
+
+// NewX0xb0x creates a new x0xb0x device handler
+func NewX0xb0x() *X0xb0x {
+	return &X0xb0x{}
+}
+
+// Name returns the device name
+func (x *X0xb0x) Name() string {
+	return "x0xb0x"
+}
+
+// ID returns the device ID
+func (x *X0xb0x) ID() uint8 {
+	return 0
+}
+
+// Capabilities returns the x0xb0x pattern limits: 16 steps and 62 notes of
+// the 6-bit note field. The top note number is left out: accented and slid,
+// it would be written as the end-of-pattern marker.
+func (x *X0xb0x) Capabilities() converter.Capabilities {
+	return converter.Capabilities{
+		MaxSteps: X0xb0xPatternSize,
+		MinNote:  1 + X0xb0xNoteOffset,
+		MaxNote:  X0xb0xNoteMask - 1 + X0xb0xNoteOffset,
+		Lossy:    []string{"tie"},
+	}
+}
+
+// SysExLayout returns the x0xb0x pattern dump layout
+func (x *X0xb0x) SysExLayout() sysex.Layout {
+	return x0xb0xSyxLayout
+}
+
+// ParseSeq parses a raw x0xb0x pattern. Bank and EEPROM dumps hold several
+// patterns back to back; only the first is read.
+func (x *X0xb0x) ParseSeq(data []byte) (*converter.Pattern, error) {
+	if len(data) == 0 {
+		return nil, converter.ErrorAt(0, "pattern data", errors.New("x0xb0x pattern is empty"))
+	}
+	if bytes.HasPrefix(data, td3HeaderMagic) {
+		return nil, errors.New("this is a TD-3 .seq file; use --device td3")
+	}
+	if len(data) > X0xb0xPatternSize {
+		data = data[:X0xb0xPatternSize]
+	}
+
+	pattern := &converter.Pattern{
+		Name:  "x0xb0x Pattern",
+		Steps: make([]converter.Step, 0, X0xb0xPatternSize),
+		Tempo: 120.0, // Tempo is not stored with the pattern
+	}
+	for _, b := range data {
+		if b == X0xb0xEnd {
+			break
+		}
+		note := b & X0xb0xNoteMask
+		step := converter.Step{
+			Gate:   note != 0,
+			Accent: b&X0xb0xAccent != 0,
+			Slide:  b&X0xb0xSlide != 0,
+		}
+		if step.Gate {
+			step.Note = note + X0xb0xNoteOffset
+			step.Velocity = 100
+			if step.Accent {
+				step.Velocity = 127
+			}
+		}
+		pattern.Steps = append(pattern.Steps, step)
+	}
+	if len(pattern.Steps) == 0 {
+		return nil, converter.ErrorAt(0, "step 1", errors.New("x0xb0x pattern has no steps"))
+	}
+	pattern.Length = len(pattern.Steps)
+	return pattern, nil
+}
+
+// GenerateSeq generates a raw x0xb0x pattern, with an end marker after
+// patterns shorter than 16 steps
+func (x *X0xb0x) GenerateSeq(pattern *converter.Pattern) ([]byte, error) {
+	if pattern == nil {
+		return nil, errors.New("nil pattern")
+	}
+	data, err := x0xb0xSteps(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < X0xb0xPatternSize {
+		data = append(data, X0xb0xEnd)
+	}
+	return data, nil
+}
+
+// ParseSyx parses an x0xb0x pattern dump
+func (x *X0xb0x) ParseSyx(data []byte) (*converter.Pattern, error) {
+	if !sysex.HasManufacturer(data, []byte{X0xb0xManufacturer}) {
+		return nil, converter.ErrorfAt(min(1, len(data)), "manufacturer ID", "not an x0xb0x pattern dump: %s", sysex.Describe(data))
+	}
+	msg, err := sysex.Parse(data, x.SysExLayout())
+	if err != nil {
+		return nil, err
+	}
+	if string(msg.Header) != string(x0xb0xHeader) {
+		return nil, converter.ErrorfAt(2, "message header", "not an x0xb0x pattern dump: header % X", msg.Header)
+	}
+	if len(msg.Payload)%2 != 0 {
+		return nil, converter.ErrorfAt(msg.PayloadOffset()+len(msg.Payload)-1, "pattern data", "x0xb0x pattern dump has an odd payload length %d", len(msg.Payload))
+	}
+
+	raw := make([]byte, len(msg.Payload)/2)
+	for i := range raw {
+		hi, lo := msg.Payload[i*2], msg.Payload[i*2+1]
+		if hi > 0x0F || lo > 0x0F {
+			return nil, converter.ErrorfAt(msg.PayloadOffset()+i*2, fmt.Sprintf("step %d", i+1), "x0xb0x pattern dump byte %d is not a nibble pair", i)
+		}
+		raw[i] = hi<<4 | lo
+	}
+	pattern, err := x.ParseSeq(raw)
+	if err != nil {
+		// Point into the dump rather than the decoded pattern
+		var perr *converter.ParseError
+		if errors.As(err, &perr) {
+			perr.Offset = msg.PayloadOffset() + perr.Offset*2
+		}
+		return nil, err
+	}
+	return pattern, nil
+}
+
+// GenerateSyx generates an x0xb0x pattern dump
+func (x *X0xb0x) GenerateSyx(pattern *converter.Pattern) ([]byte, error) {
+	raw, err := x.GenerateSeq(pattern)
+	if err != nil {
+		return nil, err
+	}
+	payload := make([]byte, 0, len(raw)*2)
+	for _, b := range raw {
+		payload = append(payload, b>>4, b&0x0F)
+	}
+	return sysex.NewBuilder(X0xb0xManufacturer).
+		Header(x0xb0xHeader...).
+		Payload(payload...).
+		Checksum(x.SysExLayout().Checksum).
+		Build()
+}
+
+// x0xb0xSteps encodes pattern steps as x0xb0x step bytes. The x0xb0x has no
+// tie, so a tied step becomes a repeat of the note with a slide into it from
+// the previous step, which plays legato in the same way.
+func x0xb0xSteps(pattern *converter.Pattern) ([]byte, error) {
+	n := min(len(pattern.Steps), X0xb0xPatternSize)
+	data := make([]byte, n)
+	for i, step := range pattern.Steps[:n] {
+		if !step.Gate {
+			continue
+		}
+		note := int(step.Note) - X0xb0xNoteOffset
+		if note < 1 || note > X0xb0xNoteMask {
+			return nil, fmt.Errorf("step %d: note %d is outside the x0xb0x range (%d-%d)",
+				i+1, step.Note, 1+X0xb0xNoteOffset, X0xb0xNoteMask+X0xb0xNoteOffset)
+		}
+		data[i] = byte(note)
+		if step.Accent {
+			data[i] |= X0xb0xAccent
+		}
+		if step.Slide {
+			data[i] |= X0xb0xSlide
+		}
+		if step.Tie && i > 0 && data[i-1] != 0 {
+			data[i-1] |= X0xb0xSlide
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,129 @@
+package devices
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestX0xb0xParseSeq(t *testing.T) {
+	x := NewX0xb0x()
+
+	// C2, accented D#2 sliding, rest, C3, then end of pattern
+	data := []byte{0x0D, 0x10 | X0xb0xAccent | X0xb0xSlide, 0x00, 0x19, X0xb0xEnd, 0x0D}
+	pattern, err := x.ParseSeq(data)
+	if err != nil {
+		t.Fatalf("ParseSeq() error = %v", err)
+	}
+	want := []converter.Step{
+		{Note: 36, Gate: true, Velocity: 100},
+		{Note: 39, Gate: true, Accent: true, Slide: true, Velocity: 127},
+		{},
+		{Note: 48, Gate: true, Velocity: 100},
+	}
+	if pattern.Length != len(want) || len(pattern.Steps) != len(want) {
+		t.Fatalf("got %d steps (length %d), want %d", len(pattern.Steps), pattern.Length, len(want))
+	}
+	for i, step := range want {
+		if pattern.Steps[i] != step {
+			t.Errorf("step %d = %+v, want %+v", i, pattern.Steps[i], step)
+		}
+	}
+
+	// Bank dumps: only the first pattern is read
+	bank := bytes.Repeat([]byte{0x0D}, X0xb0xPatternSize*4)
+	pattern, err = x.ParseSeq(bank)
+	if err != nil {
+		t.Fatalf("ParseSeq(bank) error = %v", err)
+	}
+	if len(pattern.Steps) != X0xb0xPatternSize {
+		t.Errorf("ParseSeq(bank) = %d steps, want %d", len(pattern.Steps), X0xb0xPatternSize)
+	}
+
+	td3, _ := NewTD3().GenerateSeq(&converter.Pattern{Steps: []converter.Step{{Note: 36, Gate: true}}})
+	if _, err := x.ParseSeq(td3); err == nil {
+		t.Error("ParseSeq(TD-3 .seq) expected error")
+	}
+}
+
+func TestX0xb0xRoundTrip(t *testing.T) {
+	x := NewX0xb0x()
+	pattern := &converter.Pattern{
+		Length: 4,
+		Steps: []converter.Step{
+			{Note: 36, Gate: true, Velocity: 100},
+			{Note: 48, Gate: true, Accent: true, Slide: true, Velocity: 127},
+			{Note: 51, Gate: true, Velocity: 100},
+			{},
+		},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		generate func(*converter.Pattern) ([]byte, error)
+		parse    func([]byte) (*converter.Pattern, error)
+	}{
+		{"seq", x.GenerateSeq, x.ParseSeq},
+		{"syx", x.GenerateSyx, x.ParseSyx},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.generate(pattern)
+			if err != nil {
+				t.Fatalf("generate error = %v", err)
+			}
+			got, err := tt.parse(data)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if len(got.Steps) != len(pattern.Steps) {
+				t.Fatalf("got %d steps, want %d", len(got.Steps), len(pattern.Steps))
+			}
+			for i := range pattern.Steps {
+				if got.Steps[i] != pattern.Steps[i] {
+					t.Errorf("step %d = %+v, want %+v", i, got.Steps[i], pattern.Steps[i])
+				}
+			}
+		})
+	}
+}
+
+func TestX0xb0xGenerate(t *testing.T) {
+	x := NewX0xb0x()
+
+	// A tie becomes a repeated note with a slide into it
+	tied := &converter.Pattern{Steps: []converter.Step{
+		{Note: 36, Gate: true},
+		{Note: 36, Gate: true, Tie: true},
+	}}
+	data, err := x.GenerateSeq(tied)
+	if err != nil {
+		t.Fatalf("GenerateSeq() error = %v", err)
+	}
+	if want := []byte{0x0D | X0xb0xSlide, 0x0D, X0xb0xEnd}; !bytes.Equal(data, want) {
+		t.Errorf("GenerateSeq(tied) = % X, want % X", data, want)
+	}
+
+	// A full pattern has no end marker
+	full := &converter.Pattern{Steps: make([]converter.Step, X0xb0xPatternSize)}
+	if data, _ := x.GenerateSeq(full); len(data) != X0xb0xPatternSize {
+		t.Errorf("GenerateSeq(16 steps) = %d bytes, want %d", len(data), X0xb0xPatternSize)
+	}
+
+	low := &converter.Pattern{Steps: []converter.Step{{Note: 12, Gate: true}}}
+	if _, err := x.GenerateSeq(low); err == nil {
+		t.Error("GenerateSeq(note 12) expected range error")
+	}
+
+	syx, err := x.GenerateSyx(tied)
+	if err != nil {
+		t.Fatalf("GenerateSyx() error = %v", err)
+	}
+	if _, err := NewTD3().ParseSyx(syx); err == nil {
+		t.Error("TD3.ParseSyx(x0xb0x dump) expected error")
+	}
+	td3Syx, _ := NewTD3().GenerateSyx(tied)
+	if _, err := x.ParseSyx(td3Syx); err == nil {
+		t.Error("ParseSyx(TD-3 dump) expected error")
+	}
+}
@@ -0,0 +1,143 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Difference describes a single field that differs between two patterns
+type Difference struct {
+	Step   int // Step index, or -1 for pattern-level fields
+	Field  string
+	Before any
+	After  any
+}
+
+// String returns a human-readable description of the difference
+func (d Difference) String() string {
+	if d.Step < 0 {
+		return fmt.Sprintf("%s: %v -> %v", d.Field, d.Before, d.After)
+	}
+	return fmt.Sprintf("step %d %s: %v -> %v", d.Step+1, d.Field, d.Before, d.After)
+}
+
+// Diff compares two patterns and returns their differences in step order.
+// Steps missing from the shorter pattern compare as rests, and pitch and
+// articulation are only compared when both steps are gated. Tracks follow,
+// track by track, with the track's name in the field: drum hits and their
+// accents, and the notes and accents of melodic tracks.
+func Diff(a, b *Pattern) []Difference {
+	var diffs []Difference
+
+	if len(a.Steps) != len(b.Steps) {
+		diffs = append(diffs, Difference{Step: -1, Field: "length", Before: len(a.Steps), After: len(b.Steps)})
+	}
+	if a.Tempo != b.Tempo {
+		diffs = append(diffs, Difference{Step: -1, Field: "tempo", Before: a.Tempo, After: b.Tempo})
+	}
+
+	steps := len(a.Steps)
+	if len(b.Steps) > steps {
+		steps = len(b.Steps)
+	}
+
+	for i := 0; i < steps; i++ {
+		var sa, sb Step
+		if i < len(a.Steps) {
+			sa = a.Steps[i]
+		}
+		if i < len(b.Steps) {
+			sb = b.Steps[i]
+		}
+
+		if sa.Gate != sb.Gate {
+			diffs = append(diffs, Difference{Step: i, Field: "gate", Before: sa.Gate, After: sb.Gate})
+			continue
+		}
+		if !sa.Gate {
+			continue
+		}
+
+		if sa.Note != sb.Note {
+			diffs = append(diffs, Difference{Step: i, Field: "note", Before: sa.Note, After: sb.Note})
+		}
+		if sa.Accent != sb.Accent {
+			diffs = append(diffs, Difference{Step: i, Field: "accent", Before: sa.Accent, After: sb.Accent})
+		}
+		if sa.Slide != sb.Slide {
+			diffs = append(diffs, Difference{Step: i, Field: "slide", Before: sa.Slide, After: sb.Slide})
+		}
+		if sa.Tie != sb.Tie {
+			diffs = append(diffs, Difference{Step: i, Field: "tie", Before: sa.Tie, After: sb.Tie})
+		}
+	}
+
+	for i := range max(len(a.Tracks), len(b.Tracks)) {
+		var ta, tb Track
+		if i < len(a.Tracks) {
+			ta = a.Tracks[i]
+		}
+		if i < len(b.Tracks) {
+			tb = b.Tracks[i]
+		}
+		name := ta.Name
+		if name == "" {
+			name = tb.Name
+		}
+		for j := range max(len(ta.Steps), len(tb.Steps)) {
+			var sa, sb Step
+			if j < len(ta.Steps) {
+				sa = ta.Steps[j]
+			}
+			if j < len(tb.Steps) {
+				sb = tb.Steps[j]
+			}
+			if sa.Gate != sb.Gate {
+				diffs = append(diffs, Difference{Step: j, Field: name, Before: sa.Gate, After: sb.Gate})
+				continue
+			}
+			if !sa.Gate {
+				continue
+			}
+			if sa.Note != sb.Note && !(ta.IsDrum() || tb.IsDrum()) {
+				diffs = append(diffs, Difference{Step: j, Field: name + " note", Before: sa.Note, After: sb.Note})
+			}
+			if sa.Accent != sb.Accent {
+				diffs = append(diffs, Difference{Step: j, Field: name + " accent", Before: sa.Accent, After: sb.Accent})
+			}
+		}
+	}
+
+	return diffs
+}
+
+// SlotDiff returns the differences between two patterns in what dev keeps
+// in a pattern slot: Diff without the tempo, which slots do not store, and
+// without the fields dev declares lossy. It is how written patterns are
+// checked against what the device reads back.
+func SlotDiff(dev Device, a, b *Pattern) []Difference {
+	lossy := DeviceCapabilities(dev).Lossy
+	var diffs []Difference
+	for _, d := range Diff(a, b) {
+		if d.Field == "tempo" || lossyDifference(a, d, lossy) {
+			continue
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// lossyDifference reports whether d is in a field the device does not
+// store, or is the slide a dropped tie was written as
+func lossyDifference(pattern *Pattern, d Difference, lossy []string) bool {
+	field := d.Field
+	if i := strings.LastIndexByte(field, ' '); i >= 0 {
+		field = field[i+1:] // e.g. "BD accent"
+	}
+	if slices.Contains(lossy, field) {
+		return true
+	}
+	return field == "slide" && slices.Contains(lossy, "tie") &&
+		d.Step+1 < len(pattern.Steps) && pattern.Steps[d.Step+1].Tie
+}
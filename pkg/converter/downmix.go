@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"fmt"
+)
+
+// Downmix strategies: how a clip longer than a device's pattern is fitted
+// into it
+const (
+	// DownmixFold lays every bar over the first, later notes winning (the
+	// default)
+	DownmixFold = "fold"
+	// DownmixDensest keeps the bar with the most notes
+	DownmixDensest = "densest"
+	// DownmixThin merges neighbouring steps at double tempo until the clip
+	// fits, keeping the accented note of each pair, or else the first
+	DownmixThin = "thin"
+	// DownmixHalve reads the clip in eighth-note steps at double tempo,
+	// keeping the on-beat step of each pair
+	DownmixHalve = "halve"
+)
+
+// DownmixModes lists the valid downmix strategies
+var DownmixModes = []string{DownmixFold, DownmixDensest, DownmixThin, DownmixHalve}
+
+// Downmix fits the pattern's steps into maxSteps with the given strategy,
+// returning warnings that describe what was removed. Patterns that already
+// fit are left unchanged.
+func (p *Pattern) Downmix(mode string, maxSteps int) []Violation {
+	if maxSteps <= 0 || len(p.Steps) <= maxSteps {
+		return nil
+	}
+	from := len(p.Steps)
+	var msgs []string
+	switch mode {
+	case DownmixDensest:
+		bars := (from + maxSteps - 1) / maxSteps
+		best, kept := 0, -1
+		for b := range bars {
+			if n := countNotes(p.Steps[b*maxSteps : min(from, (b+1)*maxSteps)]); n > kept {
+				best, kept = b, n
+			}
+		}
+		bar := make([]Step, maxSteps)
+		copy(bar, p.Steps[best*maxSteps:])
+		msgs = append(msgs, fmt.Sprintf("kept bar %d of %d (%d notes); dropped %d notes of the other bars",
+			best+1, bars, kept, countNotes(p.Steps)-kept))
+		p.Steps = bar
+	case DownmixThin:
+		dropped := 0
+		for len(p.Steps) > maxSteps {
+			thinned := make([]Step, (len(p.Steps)+1)/2)
+			for i := range thinned {
+				first := p.Steps[2*i]
+				if 2*i+1 == len(p.Steps) {
+					thinned[i] = first
+					continue
+				}
+				second := p.Steps[2*i+1]
+				switch {
+				case !first.Gate:
+					thinned[i] = second
+				case second.Gate && second.Accent && !first.Accent:
+					thinned[i] = second
+					dropped++
+				default:
+					thinned[i] = first
+					if second.Gate {
+						dropped++
+					}
+				}
+			}
+			p.Steps = thinned
+			p.Tempo *= 2
+		}
+		msgs = append(msgs, fmt.Sprintf("thinned %d steps to %d at %g BPM, dropping %d notes (accents and first notes of each pair kept)",
+			from, len(p.Steps), p.Tempo, dropped))
+	case DownmixHalve:
+		halved := make([]Step, (from+1)/2)
+		offBeat := make([]Step, 0, from/2)
+		for i := range halved {
+			halved[i] = p.Steps[2*i]
+			if 2*i+1 < from {
+				offBeat = append(offBeat, p.Steps[2*i+1])
+			}
+		}
+		p.Steps = halved
+		p.Tempo *= 2
+		msgs = append(msgs, fmt.Sprintf("halved %d steps to %d at %g BPM, dropping %d off-beat notes",
+			from, len(halved), p.Tempo, countNotes(offBeat)))
+		if len(halved) > maxSteps {
+			msgs = append(msgs, fmt.Sprintf("dropped steps %d-%d (%d notes) past the device's %d",
+				maxSteps+1, len(halved), countNotes(halved[maxSteps:]), maxSteps))
+			p.Steps = halved[:maxSteps]
+		}
+	default:
+		folded := make([]Step, maxSteps)
+		overwritten := 0
+		for i, s := range p.Steps {
+			if !s.Gate {
+				continue
+			}
+			if folded[i%maxSteps].Gate {
+				overwritten++
+			}
+			folded[i%maxSteps] = s
+		}
+		msgs = append(msgs, fmt.Sprintf("folded %d steps onto %d, with %d notes overwritten by later bars",
+			from, maxSteps, overwritten))
+		p.Steps = folded
+	}
+	p.Length = len(p.Steps)
+
+	warnings := make([]Violation, len(msgs))
+	for i, msg := range msgs {
+		warnings[i] = Violation{Step: -1, Field: "steps", Message: msg, Severity: SeverityWarning}
+	}
+	return warnings
+}
+
+// countNotes returns the number of gated steps
+func countNotes(steps []Step) int {
+	n := 0
+	for _, s := range steps {
+		if s.Gate {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,96 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+// twoBarClip is a 32-step line with an accented second note in the first
+// bar and a busier second bar
+func twoBarClip() *Pattern {
+	p := &Pattern{Tempo: 120, Length: 32, Steps: make([]Step, 32)}
+	for i, note := range map[int]uint8{0: 48, 1: 50, 4: 52, 9: 53, 16: 55, 18: 57, 20: 59, 22: 60, 24: 62} {
+		p.Steps[i] = Step{Note: note, Gate: true, Velocity: 100}
+	}
+	p.Steps[1].Accent = true
+	return p
+}
+
+func TestPatternDownmix(t *testing.T) {
+	tests := []struct {
+		mode    string
+		tempo   float64
+		notes   map[int]uint8 // notes of the downmixed steps; others are rests
+		message string
+	}{
+		{DownmixFold, 120, map[int]uint8{0: 55, 1: 50, 2: 57, 4: 59, 6: 60, 8: 62, 9: 53}, "with 2 notes overwritten"},
+		{DownmixDensest, 120, map[int]uint8{0: 55, 2: 57, 4: 59, 6: 60, 8: 62}, "kept bar 2 of 2 (5 notes); dropped 4 notes"},
+		{DownmixThin, 240, map[int]uint8{0: 50, 2: 52, 4: 53, 8: 55, 9: 57, 10: 59, 11: 60, 12: 62}, "dropping 1 notes"},
+		{DownmixHalve, 240, map[int]uint8{0: 48, 2: 52, 8: 55, 9: 57, 10: 59, 11: 60, 12: 62}, "dropping 2 off-beat notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			p := twoBarClip()
+			warnings := p.Downmix(tt.mode, 16)
+			if len(p.Steps) != 16 || p.Length != 16 || p.Tempo != tt.tempo {
+				t.Errorf("got %d steps (length %d) at %g BPM, want 16 at %g", len(p.Steps), p.Length, p.Tempo, tt.tempo)
+			}
+			for i, s := range p.Steps {
+				if note, ok := tt.notes[i]; s.Gate != ok || s.Note != note {
+					t.Errorf("step %d = %+v, want note %d", i+1, s, note)
+				}
+			}
+			if len(warnings) != 1 || warnings[0].Severity != SeverityWarning || !strings.Contains(warnings[0].Message, tt.message) {
+				t.Errorf("warnings = %v, want %q", warnings, tt.message)
+			}
+		})
+	}
+
+	// Patterns that fit are left alone
+	p := twoBarClip()
+	if warnings := p.Downmix(DownmixDensest, 32); warnings != nil || len(p.Steps) != 32 {
+		t.Errorf("Downmix() of a fitting pattern = %v, %d steps", warnings, len(p.Steps))
+	}
+}
+
+func TestMIDIDownmix(t *testing.T) {
+	// A four-bar clip (of the capped device's four steps) busiest in bar 2
+	clip := testMIDI(t,
+		note{key: 48, velocity: 100, step: 0, length: 1},
+		note{key: 50, velocity: 100, step: 5, length: 1},
+		note{key: 52, velocity: 100, step: 6, length: 1},
+		note{key: 53, velocity: 100, step: 7, length: 1},
+		note{key: 55, velocity: 100, step: 12, length: 1},
+	)
+	c := New(&cappedDevice{})
+	opts := DefaultOptions()
+	opts.Downmix = DownmixDensest
+	c.SetOptions(opts)
+
+	pattern, err := c.ParsePattern(clip, FormatMIDI)
+	if err != nil {
+		t.Fatalf("ParsePattern() error = %v", err)
+	}
+	if len(pattern.Steps) != 16 {
+		t.Fatalf("read %d steps, want the whole 16-step clip", len(pattern.Steps))
+	}
+	if _, err := c.GeneratePattern(pattern, FormatSeq); err != nil {
+		t.Fatalf("GeneratePattern() error = %v", err)
+	}
+	if pattern.Length != 4 || pattern.Steps[1].Note != 50 || pattern.Steps[0].Gate {
+		t.Errorf("downmixed steps = %+v, want bar 2", pattern.Steps)
+	}
+	warnings := c.Warnings()
+	if len(warnings) == 0 || !strings.Contains(warnings[0].Message, "kept bar 2 of 4") {
+		t.Errorf("Warnings() = %v, want the kept bar", warnings)
+	}
+
+	opts.Bar = 2
+	if err := opts.Validate(); err == nil {
+		t.Error("Validate() accepted bar with downmix")
+	}
+	opts.Bar, opts.Downmix = 0, "squash"
+	if err := opts.Validate(); err == nil {
+		t.Error("Validate() accepted an unknown downmix strategy")
+	}
+}
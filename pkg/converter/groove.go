@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// GrooveSteps is the length of a groove template: one bar of 16th notes
+const GrooveSteps = 16
+
+// maxGrooveOffset keeps shifted notes from crossing their neighbours
+const maxGrooveOffset = 0.45
+
+// Groove is a timing and dynamics template taken from a played MIDI
+// performance, applied per step position when generating MIDI
+type Groove struct {
+	// Offsets moves each step early (negative) or late (positive), as a
+	// fraction of a step
+	Offsets []float64 `json:"offsets"`
+	// Velocity scales each step's velocity, relative to the average
+	Velocity []float64 `json:"velocity"`
+}
+
+// ExtractGroove builds a groove template from MIDI data: every note-on is
+// matched to its nearest 16th-note step, and the timing offset and velocity
+// at each step position within the bar are averaged over all bars and tracks
+func ExtractGroove(data []byte) (*Groove, error) {
+	s, err := smf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse MIDI: %w", err)
+	}
+	mt, ok := s.TimeFormat.(smf.MetricTicks)
+	if !ok || mt.Resolution() < 4 {
+		return nil, errors.New("groove MIDI must use metric (ticks per quarter) timing")
+	}
+	ticksPerStep := float64(mt.Resolution()) / 4
+
+	var offsets, velocities [GrooveSteps]float64
+	var counts [GrooveSteps]int
+	var total float64
+	notes := 0
+	for _, track := range s.Tracks {
+		var tick int64
+		for _, ev := range track {
+			tick += int64(ev.Delta)
+			msg := ev.Message
+			if len(msg) < 3 || msg[0]&0xF0 != 0x90 || msg[2] == 0 {
+				continue
+			}
+			pos := float64(tick) / ticksPerStep
+			step := math.Round(pos)
+			i := int(step) % GrooveSteps
+			offsets[i] += pos - step
+			velocities[i] += float64(msg[2])
+			counts[i]++
+			total += float64(msg[2])
+			notes++
+		}
+	}
+	if notes == 0 {
+		return nil, errors.New("groove MIDI has no notes")
+	}
+
+	g := &Groove{Offsets: make([]float64, GrooveSteps), Velocity: make([]float64, GrooveSteps)}
+	mean := total / float64(notes)
+	for i := range GrooveSteps {
+		g.Velocity[i] = 1
+		if counts[i] > 0 {
+			g.Offsets[i] = offsets[i] / float64(counts[i])
+			g.Velocity[i] = velocities[i] / float64(counts[i]) / mean
+		}
+	}
+	return g, nil
+}
+
+// LoadGroove extracts a groove template from a MIDI file
+func LoadGroove(path string) (*Groove, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read groove file: %w", err)
+	}
+	return ExtractGroove(data)
+}
+
+// Apply returns the grooved onset tick and velocity of the note at step
+func (g *Groove) Apply(step int, tick, ticksPerStep uint32, velocity uint8) (uint32, uint8) {
+	if len(g.Offsets) > 0 {
+		offset := g.Offsets[step%len(g.Offsets)]
+		offset = max(-maxGrooveOffset, min(maxGrooveOffset, offset))
+		tick = uint32(max(0, int64(tick)+int64(math.Round(offset*float64(ticksPerStep)))))
+	}
+	if len(g.Velocity) > 0 {
+		v := math.Round(float64(velocity) * g.Velocity[step%len(g.Velocity)])
+		velocity = uint8(max(1, min(127, v)))
+	}
+	return tick, velocity
+}
@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// swungMIDI plays 16ths for two bars with every off-beat 16th a third of a
+// step late and played softer
+func swungMIDI(t *testing.T) []byte {
+	t.Helper()
+	const ticksPerStep = 120
+	s := smf.New()
+	s.TimeFormat = smf.MetricTicks(4 * ticksPerStep)
+	var track smf.Track
+	var last uint32
+	for i := 0; i < 2*GrooveSteps; i++ {
+		tick, vel := uint32(i*ticksPerStep), uint8(120)
+		if i%2 == 1 {
+			tick, vel = tick+ticksPerStep/3, 60
+		}
+		track.Add(tick-last, midi.NoteOn(0, 48, vel))
+		track.Add(30, midi.NoteOff(0, 48))
+		last = tick + 30
+	}
+	track.Close(0)
+	if err := s.Add(track); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// noteOnTicks returns the absolute tick and velocity of each note-on in the
+// first track
+func noteOnTicks(t *testing.T, data []byte) (ticks []uint32, vels []uint8) {
+	t.Helper()
+	s, err := smf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to read generated MIDI: %v", err)
+	}
+	var tick uint32
+	for _, ev := range s.Tracks[0] {
+		tick += ev.Delta
+		var ch, key, vel uint8
+		if ev.Message.GetNoteStart(&ch, &key, &vel) {
+			ticks = append(ticks, tick)
+			vels = append(vels, vel)
+		}
+	}
+	return ticks, vels
+}
+
+func TestExtractGroove(t *testing.T) {
+	g, err := ExtractGroove(swungMIDI(t))
+	if err != nil {
+		t.Fatalf("ExtractGroove() error = %v", err)
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	if !near(g.Offsets[0], 0) || !near(g.Offsets[1], 1.0/3) {
+		t.Errorf("Offsets = %v, want 0 on beats and 1/3 off-beat", g.Offsets)
+	}
+	if !near(g.Velocity[0], 120.0/90) || !near(g.Velocity[1], 60.0/90) {
+		t.Errorf("Velocity = %v", g.Velocity)
+	}
+
+	if _, err := ExtractGroove([]byte("not midi")); err == nil {
+		t.Error("ExtractGroove() of invalid data succeeded")
+	}
+}
+
+func TestGenerateMIDIWithGroove(t *testing.T) {
+	g, err := ExtractGroove(swungMIDI(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := &Pattern{Tempo: 120, Steps: make([]Step, 4)}
+	for i := range pattern.Steps {
+		pattern.Steps[i] = Step{Note: 36, Gate: true, Velocity: 90}
+	}
+
+	opts := DefaultOptions()
+	opts.Groove = g
+	m := NewMIDIConverter()
+	m.SetOptions(opts)
+	data, err := m.GenerateMIDI(pattern)
+	if err != nil {
+		t.Fatalf("GenerateMIDI() error = %v", err)
+	}
+
+	ticks, vels := noteOnTicks(t, data)
+	wantTicks := []uint32{0, 160, 240, 400}
+	wantVels := []uint8{120, 60, 120, 60}
+	for i := range wantTicks {
+		if i >= len(ticks) || ticks[i] != wantTicks[i] || vels[i] != wantVels[i] {
+			t.Fatalf("note-ons = %v %v, want %v %v", ticks, vels, wantTicks, wantVels)
+		}
+	}
+}
+
+func TestGenerateMIDISlideOverlap(t *testing.T) {
+	// A slide overlaps the next note; its note-off must not come out as a
+	// negative (wrapped) delta
+	pattern := &Pattern{Tempo: 120, Steps: []Step{
+		{Note: 36, Gate: true, Slide: true, Velocity: 100},
+		{Note: 38, Gate: true, Velocity: 100},
+	}}
+	data, err := NewMIDIConverter().GenerateMIDI(pattern)
+	if err != nil {
+		t.Fatalf("GenerateMIDI() error = %v", err)
+	}
+	ticks, _ := noteOnTicks(t, data)
+	if len(ticks) != 2 || ticks[0] != 0 || ticks[1] != 120 {
+		t.Errorf("note-on ticks = %v, want [0 120]", ticks)
+	}
+}
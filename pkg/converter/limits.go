@@ -0,0 +1,148 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLimitExceeded is wrapped by errors for input beyond the parse limits
+var ErrLimitExceeded = errors.New("input exceeds the parse limits")
+
+// Limits caps the work spent parsing untrusted input, so crafted files
+// cannot tie up a server. Zero fields are unlimited.
+type Limits struct {
+	MaxSysExMessages int           // SysEx messages in one file
+	MaxPatterns      int           // patterns in one bank
+	MaxSteps         int           // steps decoded into one pattern
+	MaxEvents        int           // note events read from a MIDI file
+	Timeout          time.Duration // time budget for parsing one file
+}
+
+// DefaultLimits returns the limits the API applies to uploads
+func DefaultLimits() Limits {
+	return Limits{
+		MaxSysExMessages: 256,
+		MaxPatterns:      128,
+		MaxSteps:         1024,
+		MaxEvents:        20000,
+		Timeout:          5 * time.Second,
+	}
+}
+
+// LimitError reports input beyond one of the parse limits
+type LimitError struct {
+	What  string // what was counted, e.g. "SysEx messages"
+	Limit int
+}
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("too many %s (limit %d)", e.What, e.Limit)
+}
+
+// Unwrap makes errors.Is(err, ErrLimitExceeded) match
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// check returns a LimitError if n exceeds limit
+func check(what string, n, limit int) error {
+	if limit > 0 && n > limit {
+		return &LimitError{What: what, Limit: limit}
+	}
+	return nil
+}
+
+// CheckPatterns checks the number of patterns in a bank
+func (l Limits) CheckPatterns(n int) error {
+	return check("patterns", n, l.MaxPatterns)
+}
+
+// CheckInput checks raw input before it is parsed. SysEx data bytes are
+// 7-bit, so every F0 starts a message.
+func (l Limits) CheckInput(data []byte, format Format) error {
+	if format != FormatSyx {
+		return nil
+	}
+	return check("SysEx messages", bytes.Count(data, []byte{SysExStart}), l.MaxSysExMessages)
+}
+
+// checkPattern checks a parsed pattern
+func (l Limits) checkPattern(p *Pattern) error {
+	return check("steps", p.StepCount(), l.MaxSteps)
+}
+
+// SetLimits sets the limits applied when parsing input
+func (c *Converter) SetLimits(l Limits) {
+	c.limits = l
+}
+
+// ParsePatternContext parses data like ParsePattern within the converter's
+// limits, giving up when ctx is done or the time budget runs out
+func (c *Converter) ParsePatternContext(ctx context.Context, data []byte, format Format) (*Pattern, error) {
+	if format == FormatSyx {
+		data = syxData(data)
+	}
+	if err := c.limits.CheckInput(data, format); err != nil {
+		return nil, err
+	}
+	if c.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, c.limits.Timeout,
+			fmt.Errorf("parsing took longer than %s: %w", c.limits.Timeout, ErrLimitExceeded))
+		defer cancel()
+	}
+
+	var pattern *Pattern
+	var err error
+	if format == FormatMIDI {
+		pattern, err = c.newMIDIConverter().parseMIDI(ctx, data, c.limits)
+	} else {
+		pattern, err = parseContext(ctx, func() (*Pattern, error) { return c.parse(data, format) })
+	}
+	if err == nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.limits.checkPattern(pattern); err != nil {
+		return nil, err
+	}
+	return pattern, nil
+}
+
+// parseContext runs a parser that does not watch ctx itself, giving up when
+// ctx is done; the parser then finishes in the background and its result is
+// dropped. A panic in the parser is raised again in the caller.
+func parseContext(ctx context.Context, parse func() (*Pattern, error)) (*Pattern, error) {
+	if ctx.Done() == nil {
+		return parse()
+	}
+	type result struct {
+		pattern *Pattern
+		err     error
+		panic   any
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		defer func() {
+			r.panic = recover()
+			done <- r
+		}()
+		r.pattern, r.err = parse()
+	}()
+	select {
+	case r := <-done:
+		if r.panic != nil {
+			panic(r.panic)
+		}
+		return r.pattern, r.err
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
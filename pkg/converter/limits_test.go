@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// slowDevice takes a while to parse .seq files
+type slowDevice struct{ mockDevice }
+
+func (d *slowDevice) ParseSeq(data []byte) (*Pattern, error) {
+	time.Sleep(time.Second)
+	return d.mockDevice.ParseSeq(data)
+}
+
+func TestParseLimits(t *testing.T) {
+	midi := testMIDI(t, note{0, 48, 100, 0, 1}, note{0, 50, 100, 1, 1}, note{0, 52, 100, 2, 1})
+	syx := bytes.Repeat([]byte{SysExStart, 0x00, SysExEnd}, 3)
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		data    []byte
+		format  Format
+		wantErr bool
+	}{
+		{"unlimited", Limits{}, midi, FormatMIDI, false},
+		{"within limits", DefaultLimits(), midi, FormatMIDI, false},
+		{"MIDI events", Limits{MaxEvents: 5}, midi, FormatMIDI, true},
+		{"SysEx messages", Limits{MaxSysExMessages: 2}, syx, FormatSyx, true},
+		{"steps", Limits{MaxSteps: 2}, []byte("note C D E"), Format303, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := New(&mockDevice{})
+			conv.SetLimits(tt.limits)
+			_, err := conv.ParsePattern(tt.data, tt.format)
+			if got := errors.Is(err, ErrLimitExceeded); got != tt.wantErr {
+				t.Errorf("ParsePattern() = %v, want limit error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Parsing stops once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(&mockDevice{}).ParsePatternContext(ctx, midi, FormatMIDI); !errors.Is(err, context.Canceled) {
+		t.Errorf("ParsePatternContext() with a canceled context = %v", err)
+	}
+
+	// The time budget covers parsers that do not watch the context
+	slow := New(&slowDevice{})
+	slow.SetLimits(Limits{Timeout: 10 * time.Millisecond})
+	start := time.Now()
+	if _, err := slow.ParsePattern([]byte{0}, FormatSeq); !errors.Is(err, ErrLimitExceeded) || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("ParsePattern() past the time budget = %v after %s", err, time.Since(start))
+	}
+
+	// ConvertFile parses within the limits too
+	dir := t.TempDir()
+	input := filepath.Join(dir, "line.303")
+	if err := os.WriteFile(input, []byte("note C D E"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	capped := New(&mockDevice{})
+	capped.SetLimits(Limits{MaxSteps: 2})
+	if err := capped.ConvertFile(input, filepath.Join(dir, "line.seq")); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ConvertFile() = %v, want limit error", err)
+	}
+
+	// A resolution below one tick per step is rejected rather than dividing by zero
+	lowRes := bytes.Clone(midi)
+	lowRes[12], lowRes[13] = 0, 2
+	if _, err := NewMIDIConverter().ParseMIDI(lowRes); err == nil {
+		t.Error("ParseMIDI() accepted 2 ticks per quarter note")
+	}
+}
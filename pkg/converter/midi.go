@@ -2,29 +2,102 @@ package converter
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"slices"
+	"sort"
 
 	"gitlab.com/gomidi/midi/v2"
 	"gitlab.com/gomidi/midi/v2/smf"
 )
 
-// MIDIConverter handles MIDI file parsing and generation
+// MIDIConverter handles MIDI file parsing and generation. Its fields are
+// only the defaults of each conversion, never changed by one, so a
+// MIDIConverter is safe for concurrent use once its options are set.
 type MIDIConverter struct {
-	ticksPerQuarter uint16
-	tempo           float64
+	ticksPerQuarter uint16  // resolution of generated files
+	tempo           float64 // tempo of parsed files without a tempo event
+	opts            ConvertOptions
+	drums           []Track // voices parsed files are read into, for drum machines
+	drumSteps       int     // most steps of a drum pattern (0 = unlimited)
+	accentLayers    bool    // read sub-accents and muted accents
+	laneTracks      bool    // write each drum lane to a track of its own
 }
 
+// Accent levels in MIDI: a sub-accent is an accented note softer than
+// FullAccentVelocity, written at SubAccentVelocity, and a muted accent is an
+// accented note played while controller MutedAccentCC is on (64 or more)
+const (
+	SubAccentVelocity  = 112
+	FullAccentVelocity = 120
+	MutedAccentCC      = 80 // General Purpose Controller 5
+)
+
+// GhostVelocity is the velocity ghost notes without a soft enough velocity
+// of their own are written at
+const GhostVelocity = 40
+
 // NewMIDIConverter creates a new MIDI converter
 func NewMIDIConverter() *MIDIConverter {
 	return &MIDIConverter{
 		ticksPerQuarter: 480,
 		tempo:           120.0,
+		opts:            DefaultOptions(),
 	}
 }
 
+// SetOptions sets the conversion options used when generating MIDI
+func (m *MIDIConverter) SetOptions(opts ConvertOptions) {
+	m.opts = opts
+}
+
+// SetAccentLayers makes parsing read sub-accents and muted accents
+func (m *MIDIConverter) SetAccentLayers(on bool) {
+	m.accentLayers = on
+}
+
+// SetLaneTracks makes generated MIDI hold each drum lane in a track of its
+// own, named after the voice, instead of all lanes in one
+func (m *MIDIConverter) SetLaneTracks(on bool) {
+	m.laneTracks = on
+}
+
+// SetDrumLanes makes parsing read notes into drum lanes, one per voice,
+// matched by drum note, of up to maxSteps steps (0 = unlimited); notes no
+// voice plays are dropped
+func (m *MIDIConverter) SetDrumLanes(lanes []Track, maxSteps int) {
+	m.drums = lanes
+	m.drumSteps = maxSteps
+}
+
+// timedMessage is a generated event at an absolute tick
+type timedMessage struct {
+	tick uint32
+	msg  smf.Message
+	off  bool
+}
+
+// noteEvent is a parsed note on or off at an absolute tick
+type noteEvent struct {
+	tick     int64
+	note     uint8
+	channel  uint8
+	velocity uint8
+	on       bool
+	muted    bool // MutedAccentCC was on
+}
+
+// noteSpan records when a generated note sounds, for building trigger tracks
+type noteSpan struct {
+	tick     uint32
+	duration uint32
+	accent   bool
+}
+
 // ParseMIDIFile reads a MIDI file and extracts pattern data
 func (m *MIDIConverter) ParseMIDIFile(filename string) (*Pattern, error) {
 	data, err := os.ReadFile(filename)
@@ -36,6 +109,11 @@ func (m *MIDIConverter) ParseMIDIFile(filename string) (*Pattern, error) {
 
 // ParseMIDI parses MIDI data and extracts pattern data
 func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
+	return m.parseMIDI(context.Background(), data, Limits{})
+}
+
+// parseMIDI parses MIDI data within limits, giving up when ctx is done
+func (m *MIDIConverter) parseMIDI(ctx context.Context, data []byte, limits Limits) (*Pattern, error) {
 	reader := bytes.NewReader(data)
 
 	s, err := smf.ReadFrom(reader)
@@ -43,9 +121,11 @@ func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
 		return nil, fmt.Errorf("failed to parse MIDI: %w", err)
 	}
 
-	// Get ticks per quarter note from time format
+	// Get ticks per quarter note from time format. The file's resolution
+	// and tempo stay local so a MIDIConverter can parse concurrently.
+	ticksPerQuarter := m.ticksPerQuarter
 	if mt, ok := s.TimeFormat.(smf.MetricTicks); ok {
-		m.ticksPerQuarter = mt.Resolution()
+		ticksPerQuarter = mt.Resolution()
 	}
 
 	pattern := &Pattern{
@@ -56,14 +136,9 @@ func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
 	}
 
 	// Calculate ticks per step (assuming 16th notes in a 4/4 bar)
-	ticksPerStep := int64(m.ticksPerQuarter) / 4
-
-	// Track note events
-	type noteEvent struct {
-		tick     int64
-		note     uint8
-		velocity uint8
-		on       bool
+	ticksPerStep := int64(ticksPerQuarter) / 4
+	if ticksPerStep == 0 {
+		return nil, fmt.Errorf("unsupported MIDI resolution of %d ticks per quarter note", ticksPerQuarter)
 	}
 
 	var events []noteEvent
@@ -72,6 +147,10 @@ func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
 	// Process all tracks
 	for _, track := range s.Tracks {
 		currentTick = 0
+		var controller [16]uint8 // MutedAccentCC value of each channel
+		if err := context.Cause(ctx); err != nil {
+			return nil, err
+		}
 		for _, ev := range track {
 			currentTick += int64(ev.Delta)
 
@@ -81,8 +160,9 @@ func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
 			if len(msg) >= 6 && msg[0] == 0xFF && msg[1] == 0x51 && msg[2] == 0x03 {
 				microsecondsPerBeat := uint32(msg[3])<<16 | uint32(msg[4])<<8 | uint32(msg[5])
 				if microsecondsPerBeat > 0 {
-					m.tempo = 60000000.0 / float64(microsecondsPerBeat)
-					pattern.Tempo = m.tempo
+					// Rounded to hundredths, undoing the whole
+					// microseconds the tempo was written in
+					pattern.Tempo = math.Round(6000000000.0/float64(microsecondsPerBeat)) / 100
 				}
 			}
 
@@ -93,14 +173,29 @@ func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
 				status := msg[0]
 				noteNum := msg[1]
 				velocity := msg[2]
+				channel := status & 0x0F
+				if m.opts.Channel > 0 && status >= 0x80 && status <= 0xBF && channel != m.opts.midiChannel() {
+					continue
+				}
+				if status >= 0xB0 && status <= 0xBF && msg[1] == MutedAccentCC {
+					controller[channel] = msg[2]
+				}
+
+				if status >= 0x80 && status <= 0x9F {
+					if err := check("MIDI note events", len(events)+1, limits.MaxEvents); err != nil {
+						return nil, err
+					}
+				}
 
 				// Note On (0x90-0x9F)
 				if status >= 0x90 && status <= 0x9F && velocity > 0 {
 					events = append(events, noteEvent{
 						tick:     currentTick,
 						note:     noteNum,
+						channel:  channel,
 						velocity: velocity,
 						on:       true,
+						muted:    controller[channel] >= 64,
 					})
 				}
 				// Note Off (0x80-0x8F) or Note On with velocity 0
@@ -108,6 +203,7 @@ func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
 					events = append(events, noteEvent{
 						tick:     currentTick,
 						note:     noteNum,
+						channel:  channel,
 						velocity: 0,
 						on:       false,
 					})
@@ -116,48 +212,160 @@ func (m *MIDIConverter) ParseMIDI(data []byte) (*Pattern, error) {
 		}
 	}
 
-	// Quantize events to steps
-	steps := make([]Step, 16)
-	for i := range steps {
-		steps[i] = Step{Note: 0, Gate: false}
+	// With a bar selected, only its notes are read; otherwise every bar
+	// folds onto the one pattern
+	var barStart, barEnd int64
+	if m.opts.Bar > 0 {
+		barStart = int64(m.opts.Bar-1) * 16 * ticksPerStep
+		barEnd = barStart + 16*ticksPerStep
 	}
 
-	// Process note on events
-	for _, ev := range events {
+	// Drum machines take the hits of each voice's note into its lane
+	if len(m.drums) > 0 {
+		pattern.Steps = nil
+		pattern.Tracks = m.parseDrumHits(events, ticksPerStep, barStart, barEnd)
+		pattern.Length = pattern.StepCount()
+		return pattern, nil
+	}
+
+	// Quantize events to steps: one bar, or with a downmix strategy every
+	// bar, fitted to the device when the pattern is generated
+	numSteps := 16
+	if m.opts.Downmix != "" {
+		// The bars the notes start in, or are held at least half a step into
+		var last int64
+		for _, ev := range events {
+			if ev.on {
+				last = max(last, ev.tick)
+			} else {
+				last = max(last, ev.tick-ticksPerStep/2)
+			}
+		}
+		numSteps = int(last/ticksPerStep)/16*16 + 16
+		if err := check("steps", numSteps, limits.MaxSteps); err != nil {
+			return nil, err
+		}
+	}
+	steps := make([]Step, numSteps)
+
+	// Process note on events, remembering when each step's note starts and
+	// ends for legato slide detection
+	onTicks, offTicks := make([]int64, numSteps), make([]int64, numSteps)
+	for i, ev := range events {
 		if !ev.on {
 			continue
 		}
-
-		stepIndex := int(ev.tick / ticksPerStep)
-		if stepIndex >= 16 {
-			stepIndex = stepIndex % 16
+		if err := context.Cause(ctx); err != nil {
+			return nil, err
 		}
+		if m.opts.Bar > 0 && (ev.tick < barStart || ev.tick >= barEnd) {
+			continue
+		}
+
+		stepIndex := int((ev.tick-barStart)/ticksPerStep) % numSteps
 
 		steps[stepIndex].Note = ev.note
 		steps[stepIndex].Gate = true
-		steps[stepIndex].Velocity = ev.velocity
-		steps[stepIndex].Accent = ev.velocity > 100
+		velocity, accent := m.opts.readVelocity(ev.velocity)
+		steps[stepIndex].Velocity = velocity
+		steps[stepIndex].Accent = accent
+		if m.accentLayers {
+			steps[stepIndex].SubAccent = accent && velocity < FullAccentVelocity
+			steps[stepIndex].MutedAccent = steps[stepIndex].Accent && ev.muted
+		}
+
+		onTicks[stepIndex] = ev.tick
+		offTicks[stepIndex] = ev.tick
+		for _, off := range events[i+1:] {
+			if !off.on && off.note == ev.note && off.channel == ev.channel && off.tick >= ev.tick {
+				offTicks[stepIndex] = off.tick
+				break
+			}
+		}
+	}
+
+	// A note held through at least half of the following empty steps ties
+	// over them, as tied notes are written
+	for i := range numSteps {
+		if !steps[i].Gate || steps[i].Tie {
+			continue
+		}
+		held := int((offTicks[i] - onTicks[i] + ticksPerStep/2) / ticksPerStep)
+		for j := i + 1; j < min(i+held, numSteps) && !steps[j].Gate; j++ {
+			steps[j] = Step{Note: steps[i].Note, Gate: true, Tie: true, Velocity: steps[i].Velocity}
+			onTicks[j], offTicks[j] = onTicks[i], offTicks[i]
+		}
 	}
 
-	// Detect slides and ties by looking at consecutive notes
-	for i := 0; i < 15; i++ {
+	// Detect slides by looking at consecutive notes
+	for i := 0; i < numSteps-1; i++ {
 		if steps[i].Gate && steps[i+1].Gate {
-			// If notes are adjacent and the second is the same or close, it might be a slide
 			noteDiff := int(steps[i+1].Note) - int(steps[i].Note)
-			if noteDiff >= -2 && noteDiff <= 2 && noteDiff != 0 {
-				steps[i].Slide = true
-			}
-			// If same note, it's a tie
-			if steps[i].Note == steps[i+1].Note {
-				steps[i].Tie = true
+			switch m.opts.SlideMode {
+			case SlideLegato:
+				// A note held into the next one glides to it
+				if noteDiff != 0 && offTicks[i] > onTicks[i+1] {
+					steps[i].Slide = true
+				}
+			case SlideNone:
+			default:
+				// If notes are adjacent and the second is close, it might be a slide
+				if noteDiff >= -2 && noteDiff <= 2 && noteDiff != 0 {
+					steps[i].Slide = true
+				}
 			}
 		}
 	}
 
 	pattern.Steps = steps
+	pattern.Length = numSteps
 	return pattern, nil
 }
 
+// parseDrumHits reads note on events into a lane per drum voice, one bar
+// with a bar selected or else as many bars as the hits span, up to the
+// device's maximum. Hits are rounded to the nearest step, so a second hit on
+// a step makes the first a flam.
+func (m *MIDIConverter) parseDrumHits(events []noteEvent, ticksPerStep, barStart, barEnd int64) []Track {
+	steps := 16
+	if m.opts.Bar == 0 {
+		var last int64
+		for _, ev := range events {
+			last = max(last, ev.tick)
+		}
+		bars := int((last+ticksPerStep/2)/ticksPerStep)/16 + 1
+		steps = bars * 16
+		if m.drumSteps > 0 {
+			steps = min(steps, m.drumSteps)
+		}
+	}
+
+	lanes := make([]Track, len(m.drums))
+	lastHit := make([][]int64, len(m.drums))
+	for i, l := range m.drums {
+		lanes[i] = Track{Name: l.Name, Note: l.Note, Steps: make([]Step, steps)}
+		lastHit[i] = make([]int64, steps)
+	}
+	for _, ev := range events {
+		if !ev.on || (m.opts.Bar > 0 && (ev.tick < barStart || ev.tick >= barEnd)) {
+			continue
+		}
+		lane := slices.IndexFunc(lanes, func(l Track) bool { return l.Note == ev.note })
+		if lane < 0 {
+			continue
+		}
+		i := int((ev.tick-barStart+ticksPerStep/2)/ticksPerStep) % steps
+		velocity, accent := m.opts.readVelocity(ev.velocity)
+		hit := Step{Note: ev.note, Gate: true, Velocity: velocity, Accent: accent, Ghost: !accent && ev.velocity < m.opts.ghostThreshold()}
+		if prev := lanes[lane].Steps[i]; prev.Gate && ev.tick > lastHit[lane][i] {
+			hit.Flam = uint8(min(((ev.tick-lastHit[lane][i])*254+ticksPerStep/2)/ticksPerStep, 127))
+		}
+		lanes[lane].Steps[i] = hit
+		lastHit[lane][i] = ev.tick
+	}
+	return lanes
+}
+
 // GenerateMIDI creates MIDI data from a Pattern
 func (m *MIDIConverter) GenerateMIDI(pattern *Pattern) ([]byte, error) {
 	if pattern == nil {
@@ -194,25 +402,105 @@ func (m *MIDIConverter) GenerateMIDI(pattern *Pattern) ([]byte, error) {
 
 	// Total ticks based on actual pattern length
 	// (pattern.Length steps * ticks per step)
-	numSteps := len(pattern.Steps)
+	numSteps := pattern.StepCount()
 	if numSteps == 0 {
 		numSteps = 16
 	}
 	totalPatternTicks := uint32(numSteps) * ticksPerStep
 
-	// Default note length (75% of step for staccato feel, like 303)
-	defaultNoteLength := (ticksPerStep * 3) / 4
+	// Length of notes without slide or tie (by default 75% of a step for a
+	// staccato feel, like a 303)
+	gateLength := m.opts.GateLength
+	if gateLength <= 0 {
+		gateLength = DefaultGateLength
+	}
+	defaultNoteLength := uint32(float64(ticksPerStep) * gateLength)
 	if defaultNoteLength == 0 {
-		defaultNoteLength = ticksPerStep - 1
+		defaultNoteLength = 1
 	}
 
-	channel := uint8(0)
-	var currentTick uint32
+	// The pattern's own steps and drum tracks go in the tempo track (unless
+	// the device keeps lanes apart); melodic tracks get tracks of their own
+	channel := m.opts.midiChannel()
+	events, spans := m.lineEvents(pattern.Steps, channel, ticksPerStep, defaultNoteLength)
+	trackEvents := make([][]timedMessage, len(pattern.Tracks))
+	for v := range pattern.Tracks {
+		t := &pattern.Tracks[v]
+		if !t.IsDrum() {
+			trackEvents[v], _ = m.lineEvents(t.Steps, t.midiChannel(channel), ticksPerStep, defaultNoteLength)
+			continue
+		}
+		trackEvents[v] = m.drumEvents(t, ticksPerStep, defaultNoteLength)
+		if !m.laneTracks {
+			events = append(events, trackEvents[v]...)
+		}
+	}
+
+	currentTick := addTimedMessages(&track, events)
+
+	// Ensure the pattern is exactly 1 bar long by adding padding
+	if currentTick < totalPatternTicks {
+		remainingTicks := totalPatternTicks - currentTick
+		// Add a silent note-off event at the end to pad the duration
+		track.Add(remainingTicks, smf.Message([]byte{0xFF, 0x06, 0x00})) // Marker event as padding
+	}
 
-	// Pre-calculate note durations considering ties
-	// A tie means the NEXT step sustains the current note
-	for i := 0; i < len(pattern.Steps); i++ {
-		step := pattern.Steps[i]
+	// Add end of track
+	track.Close(0)
+
+	if err := s.Add(track); err != nil {
+		return nil, fmt.Errorf("failed to add track: %w", err)
+	}
+
+	// Tracks written to MIDI tracks of their own follow the tempo track
+	for v := range pattern.Tracks {
+		t := &pattern.Tracks[v]
+		if t.IsDrum() && !m.laneTracks {
+			continue
+		}
+		var own smf.Track
+		own.Add(0, smf.MetaTrackSequenceName(t.Name))
+		end := addTimedMessages(&own, trackEvents[v])
+		own.Close(max(totalPatternTicks, end) - end)
+		if err := s.Add(own); err != nil {
+			return nil, fmt.Errorf("failed to add %s track: %w", t.Name, err)
+		}
+	}
+
+	// Optional CV-style trigger tracks for driving modular gear
+	if m.opts.GateTrack {
+		gate := buildTriggerTrack("Gate", spans, m.opts.GateNote, false, totalPatternTicks)
+		if err := s.Add(gate); err != nil {
+			return nil, fmt.Errorf("failed to add gate track: %w", err)
+		}
+	}
+	if m.opts.AccentTrack {
+		accent := buildTriggerTrack("Accent", spans, m.opts.AccentNote, true, totalPatternTicks)
+		if err := s.Add(accent); err != nil {
+			return nil, fmt.Errorf("failed to add accent track: %w", err)
+		}
+	}
+
+	// Write to buffer
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write MIDI: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// lineEvents renders a melodic line on channel: a tie means the next step
+// sustains the current note, and slides overlap the next note. It returns the
+// line's events and when its notes sound.
+func (m *MIDIConverter) lineEvents(steps []Step, channel uint8, ticksPerStep, noteLength uint32) ([]timedMessage, []noteSpan) {
+	var events []timedMessage
+	var spans []noteSpan
+	muted := false // MutedAccentCC is on
+
+	for i := 0; i < len(steps); i++ {
+		step := steps[i]
 
 		// Skip rests
 		if !step.Gate {
@@ -225,7 +513,6 @@ func (m *MIDIConverter) GenerateMIDI(pattern *Pattern) ([]byte, error) {
 		}
 
 		stepTick := uint32(i) * ticksPerStep
-		delta := stepTick - currentTick
 
 		// Note on
 		velocity := step.Velocity
@@ -234,14 +521,27 @@ func (m *MIDIConverter) GenerateMIDI(pattern *Pattern) ([]byte, error) {
 		}
 		if step.Accent {
 			velocity = 127
+			if step.SubAccent {
+				velocity = SubAccentVelocity
+			}
+		}
+		if m.opts.Groove != nil {
+			stepTick, velocity = m.opts.Groove.Apply(i, stepTick, ticksPerStep, velocity)
 		}
 
-		noteOn := midi.NoteOn(channel, step.Note, velocity)
-		track.Add(delta, noteOn)
-		currentTick = stepTick
+		// Muted accents switch the controller on for their notes; it is
+		// sent before the note on, sorted with the note offs
+		if on := step.Accent && step.MutedAccent; on != muted {
+			value := uint8(0)
+			if on {
+				value = 127
+			}
+			events = append(events, timedMessage{tick: stepTick, msg: smf.Message(midi.ControlChange(channel, MutedAccentCC, value)), off: true})
+			muted = on
+		}
 
 		// Calculate note duration - check how many following steps are ties
-		noteDuration := defaultNoteLength
+		noteDuration := noteLength
 
 		// Check for slides - extend note to overlap with next
 		if step.Slide {
@@ -250,8 +550,8 @@ func (m *MIDIConverter) GenerateMIDI(pattern *Pattern) ([]byte, error) {
 
 		// Check for ties in following steps
 		tieCount := 0
-		for j := i + 1; j < len(pattern.Steps); j++ {
-			if pattern.Steps[j].Tie && pattern.Steps[j].Gate {
+		for j := i + 1; j < len(steps); j++ {
+			if steps[j].Tie && steps[j].Gate {
 				tieCount++
 			} else {
 				break
@@ -264,36 +564,116 @@ func (m *MIDIConverter) GenerateMIDI(pattern *Pattern) ([]byte, error) {
 			if !step.Slide {
 				noteDuration -= ticksPerStep / 8 // Slight gap before next note
 			}
+			// A slide from the last tied step overlaps the next note
+			if steps[i+tieCount].Slide {
+				noteDuration = ticksPerStep*uint32(tieCount+1) + ticksPerStep/4
+			}
 		}
 
-		// Note off
-		noteOff := midi.NoteOff(channel, step.Note)
-		track.Add(noteDuration, noteOff)
-		currentTick += noteDuration
+		events = append(events,
+			timedMessage{tick: stepTick, msg: smf.Message(midi.NoteOn(channel, step.Note, velocity))},
+			timedMessage{tick: stepTick + noteDuration, msg: smf.Message(midi.NoteOff(channel, step.Note)), off: true})
+
+		spans = append(spans, noteSpan{tick: stepTick, duration: noteDuration, accent: step.Accent})
 	}
+	return events, spans
+}
 
-	// Ensure the pattern is exactly 1 bar long by adding padding
-	if currentTick < totalPatternTicks {
-		remainingTicks := totalPatternTicks - currentTick
-		// Add a silent note-off event at the end to pad the duration
-		track.Add(remainingTicks, smf.Message([]byte{0xFF, 0x06, 0x00})) // Marker event as padding
+// drumEvents renders a drum track's hits, which sound for the gate length on
+// the track's channel (the drum channel by default). MIDI has no step
+// probability, so every hit is written.
+func (m *MIDIConverter) drumEvents(t *Track, ticksPerStep, noteLength uint32) []timedMessage {
+	channel := t.midiChannel(DrumChannel)
+	var events []timedMessage
+	for i, step := range t.Steps {
+		if !step.Gate {
+			continue
+		}
+		stepTick := uint32(i) * ticksPerStep
+		// Accented hits and ghost notes keep a velocity loud or soft
+		// enough to read back as such, so per-hit velocities survive
+		velocity := step.Velocity
+		if velocity == 0 {
+			velocity = 100
+		}
+		switch {
+		case step.Accent && velocity < m.opts.accentThreshold():
+			velocity = 127
+		case step.Ghost && !step.Accent && velocity >= m.opts.ghostThreshold():
+			velocity = min(GhostVelocity, m.opts.ghostThreshold()-1)
+		}
+		if m.opts.Groove != nil {
+			stepTick, velocity = m.opts.Groove.Apply(i, stepTick, ticksPerStep, velocity)
+		}
+		// A flam is a softer grace hit just before the main one
+		if grace := (uint32(step.Flam)*ticksPerStep + 127) / 254; grace > 0 && grace <= stepTick {
+			events = append(events,
+				timedMessage{tick: stepTick - grace, msg: smf.Message(midi.NoteOn(channel, t.Note, velocity/2+1))},
+				timedMessage{tick: stepTick, msg: smf.Message(midi.NoteOff(channel, t.Note)), off: true})
+		}
+		events = append(events,
+			timedMessage{tick: stepTick, msg: smf.Message(midi.NoteOn(channel, t.Note, velocity))},
+			timedMessage{tick: stepTick + noteLength, msg: smf.Message(midi.NoteOff(channel, t.Note)), off: true})
 	}
+	return events
+}
 
-	// Add end of track
-	track.Close(0)
+// addTimedMessages adds events to a track in time order, returning the tick
+// of the last one. Slides overlap the next note, so events are placed by
+// absolute time; at equal times a note ends before the next one starts.
+func addTimedMessages(track *smf.Track, events []timedMessage) uint32 {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].tick != events[j].tick {
+			return events[i].tick < events[j].tick
+		}
+		return events[i].off && !events[j].off
+	})
+	var currentTick uint32
+	for _, ev := range events {
+		track.Add(ev.tick-currentTick, ev.msg)
+		currentTick = ev.tick
+	}
+	return currentTick
+}
 
-	if err := s.Add(track); err != nil {
-		return nil, fmt.Errorf("failed to add track: %w", err)
+// buildTriggerTrack creates a track of fixed-pitch notes following the given spans.
+// Gate tracks encode accents as velocity; accent tracks only trigger on accents.
+func buildTriggerTrack(name string, spans []noteSpan, note uint8, accentOnly bool, totalTicks uint32) smf.Track {
+	var track smf.Track
+	track.Add(0, smf.MetaTrackSequenceName(name))
+
+	var triggers []noteSpan
+	for _, span := range spans {
+		if !accentOnly || span.accent {
+			triggers = append(triggers, span)
+		}
 	}
 
-	// Write to buffer
-	var buf bytes.Buffer
-	_, err := s.WriteTo(&buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write MIDI: %w", err)
+	channel := uint8(0)
+	var currentTick uint32
+	for i, span := range triggers {
+		// A fixed-pitch trigger must end before it retriggers (slides overlap)
+		duration := span.duration
+		if i+1 < len(triggers) && span.tick+duration > triggers[i+1].tick {
+			duration = triggers[i+1].tick - span.tick
+		}
+
+		velocity := uint8(100)
+		if span.accent {
+			velocity = 127
+		}
+
+		track.Add(span.tick-currentTick, midi.NoteOn(channel, note, velocity))
+		track.Add(duration, midi.NoteOff(channel, note))
+		currentTick = span.tick + duration
 	}
 
-	return buf.Bytes(), nil
+	var remainingTicks uint32
+	if currentTick < totalTicks {
+		remainingTicks = totalTicks - currentTick
+	}
+	track.Close(remainingTicks)
+	return track
 }
 
 // WriteMIDIFile writes MIDI data to a file
@@ -302,7 +682,7 @@ func (m *MIDIConverter) WriteMIDIFile(pattern *Pattern, filename string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, data, 0644)
+	return WriteFileAtomic(filename, data, 0644)
 }
 
 // Ensure io.Reader is used (for interface compliance)
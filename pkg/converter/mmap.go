@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"os"
+)
+
+// MappedFile is the content of a file opened by MapFile
+type MappedFile struct {
+	data  []byte
+	unmap func() error
+}
+
+// MapFile reads a file like os.ReadFile, but memory-maps it where the
+// platform allows, so a large bank or archive is paged in from disk as it is
+// read rather than copied into RAM up front. When the file cannot be mapped
+// (an empty file, a pipe, or a platform without mmap) it is read instead.
+//
+// The mapping is private: changes to Bytes never reach the file. Bytes must
+// not be used after Close, and the file must not be truncated meanwhile.
+func MapFile(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() && info.Size() > 0 && int64(int(info.Size())) == info.Size() {
+		if data, err := mmap(f, int(info.Size())); err == nil {
+			return &MappedFile{data: data, unmap: func() error { return munmap(data) }}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MappedFile{data: data}, nil
+}
+
+// Bytes returns the file's content
+func (f *MappedFile) Bytes() []byte {
+	return f.data
+}
+
+// Mapped reports whether the content is memory-mapped rather than read
+func (f *MappedFile) Mapped() bool {
+	return f.unmap != nil
+}
+
+// Close releases the mapping; closing twice is harmless
+func (f *MappedFile) Close() error {
+	unmap := f.unmap
+	f.data, f.unmap = nil, nil
+	if unmap == nil {
+		return nil
+	}
+	return unmap()
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package converter
+
+import (
+	"errors"
+	"os"
+)
+
+// mmap is not supported here, so MapFile always reads files
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func munmap(data []byte) error {
+	return nil
+}
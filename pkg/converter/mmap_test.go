@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMapFile(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte{0xF0, 0x00, 0x20, 0x32, 0xF7}, 100000)
+	path := filepath.Join(dir, "dump.syx")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := MapFile(path)
+	if err != nil {
+		t.Fatalf("MapFile() error = %v", err)
+	}
+	if !bytes.Equal(f.Bytes(), content) {
+		t.Error("mapped content differs from the file")
+	}
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" && !f.Mapped() {
+		t.Error("file was read rather than mapped")
+	}
+	// Writes stay private to the mapping
+	f.Bytes()[0] = 0
+	if err := f.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, content) {
+		t.Error("write to the mapping reached the file")
+	}
+
+	// Empty files cannot be mapped and fall back to reading
+	empty := filepath.Join(dir, "empty.syx")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := MapFile(empty); err != nil || f.Mapped() || len(f.Bytes()) != 0 {
+		t.Errorf("MapFile(empty) = %v, %v", f, err)
+	}
+
+	if _, err := MapFile(filepath.Join(dir, "missing.syx")); !os.IsNotExist(err) {
+		t.Errorf("MapFile(missing) error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package converter
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of f copy-on-write, so parsers that fix up
+// their input in place still work
+func mmap(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+}
+
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,43 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// SetModTime sets the modification (and access) time of files, e.g. to
+// give converted outputs the date of the pattern they came from
+func SetModTime(t time.Time, paths ...string) error {
+	for _, path := range paths {
+		if err := os.Chtimes(path, t, t); err != nil {
+			return fmt.Errorf("failed to set modification time: %w", err)
+		}
+	}
+	return nil
+}
+
+// PreserveModTime gives outputs the modification time of src
+func PreserveModTime(src string, outputs ...string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return SetModTime(info.ModTime(), outputs...)
+}
+
+// UpToDate reports whether every output exists and is at least as new as
+// input, the way make decides a target needs no rebuild
+func UpToDate(input string, outputs ...string) bool {
+	in, err := os.Stat(input)
+	if err != nil || len(outputs) == 0 {
+		return false
+	}
+	for _, path := range outputs {
+		out, err := os.Stat(path)
+		if err != nil || out.ModTime().Before(in.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPreserveModTime(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "pattern.seq")
+	out := filepath.Join(dir, "pattern.mid")
+	for _, path := range []string{src, out} {
+		if err := os.WriteFile(path, []byte{1}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	made := time.Date(2019, 3, 14, 21, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(src, made, made); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PreserveModTime(src, out); err != nil {
+		t.Fatalf("PreserveModTime() error = %v", err)
+	}
+	if info, _ := os.Stat(out); !info.ModTime().Equal(made) {
+		t.Errorf("output mtime = %v, want %v", info.ModTime(), made)
+	}
+	if err := PreserveModTime(filepath.Join(dir, "missing.seq"), out); err == nil {
+		t.Error("PreserveModTime() with missing source succeeded")
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "pattern.seq")
+	out := filepath.Join(dir, "pattern.mid")
+	for _, path := range []string{src, out} {
+		if err := os.WriteFile(path, []byte{1}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	tests := []struct {
+		name    string
+		src     time.Time
+		out     time.Time
+		outputs []string
+		want    bool
+	}{
+		{"newer output", older, newer, []string{out}, true},
+		{"same time", older, older, []string{out}, true},
+		{"older output", newer, older, []string{out}, false},
+		{"missing output", older, newer, []string{out, filepath.Join(dir, "pattern.syx")}, false},
+		{"no outputs", older, newer, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetModTime(tt.src, src); err != nil {
+				t.Fatal(err)
+			}
+			if err := SetModTime(tt.out, out); err != nil {
+				t.Fatal(err)
+			}
+			if got := UpToDate(src, tt.outputs...); got != tt.want {
+				t.Errorf("UpToDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package converter
+
+import "fmt"
+
+// Normalize rewrites conflicting step flags into their canonical form so a
+// pattern behaves identically on every device regardless of source format
+// quirks. It returns a warning for every change made.
+//
+// Canonical semantics:
+//   - rests carry no tie, accent, or slide
+//   - the first step and steps following a rest cannot be tied
+//   - a tied step sustains the previous pitch
+//   - a slide into a rest (including wrapping to the first step) is dropped
+//   - only accented steps carry a sub-accent or muted accent
+//   - melodic tracks follow the rules above, each on its own
+//   - drum hits are not tied or slid, accented hits are not ghost notes, and
+//     drum rests carry no accent, ghost, flam, or probability
+func (p *Pattern) Normalize() []Violation {
+	var warnings []Violation
+	warn := func(i int, field, msg string) {
+		warnings = append(warnings, Violation{Step: i, Field: field, Message: msg, Severity: SeverityWarning})
+	}
+
+	normalizeLine(p.Steps, warn)
+	for t := range p.Tracks {
+		track := &p.Tracks[t]
+		if !track.IsDrum() {
+			normalizeLine(track.Steps, func(i int, field, msg string) { warn(i, track.Name+" "+field, msg) })
+			continue
+		}
+		for i := range track.Steps {
+			step := &track.Steps[i]
+			if step.Tie || step.Slide {
+				step.Tie, step.Slide = false, false
+				warn(i, track.Name, "cleared tie or slide on a drum hit")
+			}
+			if !step.Gate && (step.Accent || step.Ghost || step.Flam > 0 || step.Probability > 0) {
+				step.Accent, step.Ghost, step.Flam, step.Probability = false, false, 0, 0
+				warn(i, track.Name, "cleared accent, ghost, flam, or probability on a rest")
+			}
+			if step.Accent && step.Ghost {
+				step.Ghost = false
+				warn(i, track.Name, "cleared ghost on an accented hit")
+			}
+		}
+	}
+
+	return warnings
+}
+
+// normalizeLine applies the canonical semantics to the steps of a line of
+// notes, calling warn for every change made
+func normalizeLine(steps []Step, warn func(i int, field, msg string)) {
+	for i := range steps {
+		step := &steps[i]
+
+		if !step.Gate {
+			if step.Tie {
+				step.Tie = false
+				warn(i, "tie", "cleared tie on a rest")
+			}
+			if step.Accent {
+				step.Accent = false
+				warn(i, "accent", "cleared accent on a rest")
+			}
+			if step.Slide {
+				step.Slide = false
+				warn(i, "slide", "cleared slide on a rest")
+			}
+		}
+		if !step.Accent && (step.SubAccent || step.MutedAccent) {
+			step.SubAccent, step.MutedAccent = false, false
+			warn(i, "accent", "cleared sub-accent or muted accent without an accent")
+		}
+		if !step.Gate {
+			continue
+		}
+
+		if step.Tie {
+			switch {
+			case i == 0:
+				step.Tie = false
+				warn(i, "tie", "first step cannot be tied; playing a new note")
+			case !steps[i-1].Gate:
+				step.Tie = false
+				warn(i, "tie", "tie after a rest; playing a new note")
+			case step.Note != steps[i-1].Note:
+				warn(i, "note", "tied step sustains the previous pitch")
+				step.Note = steps[i-1].Note
+			}
+		}
+	}
+
+	// Slides need a following note to glide into
+	for i := range steps {
+		step := &steps[i]
+		next := steps[(i+1)%len(steps)]
+		if step.Slide && !next.Gate {
+			step.Slide = false
+			warn(i, "slide", "cleared slide into a rest")
+		}
+	}
+}
+
+// Transpose shifts every gated note by the given number of semitones,
+// leaving drum tracks alone. It fails, leaving the pattern unchanged, if a
+// note would leave the MIDI range.
+func (p *Pattern) Transpose(semitones int) error {
+	if semitones == 0 {
+		return nil
+	}
+	lines := [][]Step{p.Steps}
+	for _, t := range p.Tracks {
+		if !t.IsDrum() {
+			lines = append(lines, t.Steps)
+		}
+	}
+	for _, steps := range lines {
+		for i, step := range steps {
+			if n := int(step.Note) + semitones; step.Gate && (n < 0 || n > 127) {
+				return fmt.Errorf("step %d: transposing %s by %d leaves the MIDI note range", i+1, NoteName(step.Note), semitones)
+			}
+		}
+	}
+	for _, steps := range lines {
+		for i := range steps {
+			if steps[i].Gate {
+				steps[i].Note = uint8(int(steps[i].Note) + semitones)
+			}
+		}
+	}
+	return nil
+}
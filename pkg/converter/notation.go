@@ -0,0 +1,303 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NotationBaseNote is the MIDI note of an untransposed C in 303 notation;
+// DOWN and UP shift by an octave, giving the TD-3's C1-C4 range
+const NotationBaseNote = 36
+
+// notationField is a row (or column) of written 303 notation
+type notationField int
+
+const (
+	fieldNote notationField = iota
+	fieldOctave
+	fieldUp
+	fieldDown
+	fieldAccent
+	fieldSlide
+	fieldTime
+	fieldStep
+)
+
+var notationLabels = map[string]notationField{
+	"note": fieldNote, "notes": fieldNote, "pitch": fieldNote,
+	"oct": fieldOctave, "octave": fieldOctave, "u/d": fieldOctave, "up/down": fieldOctave, "updown": fieldOctave, "transpose": fieldOctave,
+	"up": fieldUp, "down": fieldDown, "dn": fieldDown,
+	"acc": fieldAccent, "accent": fieldAccent,
+	"sl": fieldSlide, "slide": fieldSlide,
+	"time": fieldTime, "gate": fieldTime,
+	"step": fieldStep,
+}
+
+var notationPitches = map[string]int{
+	"C": 0, "C#": 1, "DB": 1, "D": 2, "D#": 3, "EB": 3, "E": 4, "F": 5,
+	"F#": 6, "GB": 6, "G": 7, "G#": 8, "AB": 8, "A": 9, "A#": 10, "BB": 10, "B": 11,
+}
+
+// ParseNotation parses the written TB-303 pattern notation found in printed
+// manuals, books, and forum posts. Each field is a labelled row with one
+// token per step, with "." for an empty cell:
+//
+//	# Everyday acid
+//	tempo  128
+//	note   C   C   D#  .   G   C'  .   C
+//	u/d    D   .   .   .   .   .   .   U
+//	accent A   .   .   .   A   .   .   .
+//	slide  .   S   .   .   .   S   .   .
+//	time   o   o   -   0   o   o   -   o
+//
+// The same fields may instead be columns under a header line, one step per
+// line ("step note u/d accent slide time"). Notes are C to B with sharps or
+// flats and "'" for the upper C; U/D (or separate up/down rows) shift an
+// octave. Time is o (note), - (tie), or 0 (rest); without a time row every
+// step with a note plays. As on the 303 itself, a note row listing only the
+// pitches of the note steps in order (pitch mode) is also accepted.
+func ParseNotation(data []byte) (*Pattern, error) {
+	p := &Pattern{Tempo: 120}
+	rows := map[notationField][]string{}
+	var header []notationField
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		line = strings.ReplaceAll(line, "|", " ")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		label := strings.ToLower(strings.TrimSuffix(fields[0], ":"))
+		switch label {
+		case "tempo", "bpm":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: expected one tempo value", lineNo)
+			}
+			tempo, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || tempo <= 0 {
+				return nil, fmt.Errorf("line %d: invalid tempo %q", lineNo, fields[1])
+			}
+			p.Tempo = tempo
+			continue
+		case "name", "title":
+			p.Name = strings.TrimSpace(strings.SplitN(line, fields[0], 2)[1])
+			continue
+		}
+
+		if header != nil {
+			// Step-per-line table under a header
+			if len(fields) > len(header) {
+				return nil, fmt.Errorf("line %d: %d columns, header has %d", lineNo, len(fields), len(header))
+			}
+			for i, f := range header {
+				cell := "."
+				if i < len(fields) {
+					cell = fields[i]
+				}
+				rows[f] = append(rows[f], cell)
+			}
+			continue
+		}
+
+		if cols, ok := notationHeader(fields); ok {
+			header = cols
+			continue
+		}
+		field, ok := notationLabels[label]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown row %q", lineNo, fields[0])
+		}
+		if field == fieldStep {
+			continue
+		}
+		rows[field] = append(rows[field], fields[1:]...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rows[fieldNote]) == 0 && len(rows[fieldTime]) == 0 {
+		return nil, errors.New("no note or time row found")
+	}
+	if err := buildNotationSteps(p, rows); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// notationHeader reports whether a line is a column header naming at least
+// two fields, e.g. "step note u/d accent slide time"
+func notationHeader(fields []string) ([]notationField, bool) {
+	if len(fields) < 2 {
+		return nil, false
+	}
+	cols := make([]notationField, len(fields))
+	for i, f := range fields {
+		field, ok := notationLabels[strings.ToLower(strings.TrimSuffix(f, ":"))]
+		if !ok {
+			return nil, false
+		}
+		cols[i] = field
+	}
+	return cols, true
+}
+
+// notationTime is the time-row value of a step
+type notationTime int
+
+const (
+	timeNote notationTime = iota
+	timeTie
+	timeRest
+)
+
+func parseNotationTime(tok string) (notationTime, error) {
+	switch strings.ToLower(tok) {
+	case "o", "1", "•", "*", "n", "x":
+		return timeNote, nil
+	case "-", "_", "~", "t":
+		return timeTie, nil
+	case "0", ".", "r":
+		return timeRest, nil
+	}
+	return 0, fmt.Errorf("invalid time %q (use o, -, or 0)", tok)
+}
+
+// empty reports whether a cell is a placeholder
+func empty(tok string) bool {
+	return tok == "." || tok == "-" || tok == "_"
+}
+
+func buildNotationSteps(p *Pattern, rows map[notationField][]string) error {
+	notes := rows[fieldNote]
+
+	// Time row, or every step with a pitch plays
+	var times []notationTime
+	if len(rows[fieldTime]) > 0 {
+		for _, tok := range rows[fieldTime] {
+			t, err := parseNotationTime(tok)
+			if err != nil {
+				return err
+			}
+			times = append(times, t)
+		}
+	} else {
+		for _, tok := range notes {
+			if empty(tok) {
+				times = append(times, timeRest)
+			} else {
+				times = append(times, timeNote)
+			}
+		}
+	}
+	n := len(times)
+	if n > 255 {
+		return fmt.Errorf("pattern has %d steps", n)
+	}
+
+	// Pitch mode: one pitch per note step rather than per step
+	noteSteps := 0
+	for _, t := range times {
+		if t == timeNote {
+			noteSteps++
+		}
+	}
+	pitchMode := len(notes) < n && len(notes) == noteSteps
+
+	cell := func(f notationField, step, pitchIndex int) string {
+		i := step
+		if pitchMode && f != fieldTime {
+			i = pitchIndex
+		}
+		if i < len(rows[f]) {
+			return rows[f][i]
+		}
+		return "."
+	}
+
+	p.Length = n
+	p.Steps = make([]Step, n)
+	pitchIndex := -1
+	for i, t := range times {
+		if t == timeRest {
+			continue
+		}
+		if t == timeTie {
+			if i == 0 || !p.Steps[i-1].Gate {
+				return fmt.Errorf("step %d: tie without a note to sustain", i+1)
+			}
+			prev := p.Steps[i-1]
+			p.Steps[i] = Step{Note: prev.Note, Gate: true, Tie: true, Velocity: prev.Velocity}
+			continue
+		}
+
+		pitchIndex++
+		tok := cell(fieldNote, i, pitchIndex)
+		if empty(tok) {
+			return fmt.Errorf("step %d: note step without a pitch", i+1)
+		}
+		note, err := notationNote(tok, cell(fieldOctave, i, pitchIndex), cell(fieldUp, i, pitchIndex), cell(fieldDown, i, pitchIndex))
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		step := Step{Note: note, Gate: true, Velocity: 100}
+		step.Accent = !empty(cell(fieldAccent, i, pitchIndex))
+		step.Slide = !empty(cell(fieldSlide, i, pitchIndex))
+		if step.Accent {
+			step.Velocity = 127
+		}
+		p.Steps[i] = step
+	}
+	return nil
+}
+
+// notationNote converts a written pitch and its octave marks to a MIDI note.
+// The u/d cell holds U or D (UU/DD for two octaves); any mark in a separate
+// up or down row shifts one octave.
+func notationNote(pitch, octave, up, down string) (uint8, error) {
+	name := strings.ToUpper(pitch)
+	shift := 0
+	for strings.HasSuffix(name, "'") || strings.HasSuffix(name, "^") {
+		shift += 12
+		name = name[:len(name)-1]
+	}
+	pc, ok := notationPitches[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid note %q", pitch)
+	}
+
+	if !empty(octave) {
+		for _, r := range strings.ToUpper(octave) {
+			switch r {
+			case 'U':
+				shift += 12
+			case 'D':
+				shift -= 12
+			default:
+				return 0, fmt.Errorf("invalid octave mark %q (use U or D)", octave)
+			}
+		}
+	}
+	if !empty(up) {
+		shift += 12
+	}
+	if !empty(down) {
+		shift -= 12
+	}
+
+	note := NotationBaseNote + pc + shift
+	if note < 0 || note > 127 {
+		return 0, fmt.Errorf("note %s out of range", pitch)
+	}
+	return uint8(note), nil
+}
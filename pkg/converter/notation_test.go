@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNotation(t *testing.T) {
+	// C D# . G C' . with a tie, rest, accents, slides, and octave marks
+	want := []Step{
+		{Note: 24, Gate: true, Velocity: 127, Accent: true},
+		{Note: 36, Gate: true, Velocity: 100, Slide: true},
+		{Note: 36, Gate: true, Velocity: 100, Tie: true},
+		{},
+		{Note: 43, Gate: true, Velocity: 127, Accent: true},
+		{Note: 48, Gate: true, Velocity: 100},
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"rows", `
+# Everyday acid
+tempo: 132
+note   C  D# .  .  G  C
+u/d    D  .  .  .  .  U
+accent A  .  .  .  A  .
+slide  .  S  .  .  .  .
+time   o  o  -  0  o  o
+`},
+		{"rows with separators and up/down rows", `
+bpm 132
+step   1  2  3  4 | 5  6
+note   C  D# .  . | G  C'
+down   x  .  .  . | .  .
+acc    A  .  .  . | A  .
+slide  .  S  .  . | .  .
+time   o  o  -  0 | o  o
+`},
+		{"pitch mode", `
+tempo 132
+note   C  D# G  C'
+u/d    D  .  .  .
+accent A  .  A  .
+slide  .  S  .  .
+time   o  o  -  0  o  o
+`},
+		{"step per line", `
+tempo 132
+step note u/d accent slide time
+1    C    D   A      .     o
+2    D#   .   .      S     o
+3    .    .   .      .     -
+4    .    .   .      .     0
+5    G    .   A      .     o
+6    C    U   .      .     o
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseNotation([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ParseNotation() error = %v", err)
+			}
+			if p.Tempo != 132 || p.Length != len(want) {
+				t.Errorf("tempo = %v, length = %d", p.Tempo, p.Length)
+			}
+			// D# is pitch class 3 above base C: 39
+			expected := append([]Step(nil), want...)
+			expected[1].Note, expected[2].Note = 39, 39
+			if diffs := Diff(&Pattern{Steps: expected, Tempo: 132}, p); len(diffs) != 0 {
+				t.Errorf("steps differ: %v", diffs)
+			}
+		})
+	}
+}
+
+func TestParseNotationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"empty", "# nothing\n", "no note or time row"},
+		{"unknown row", "note C\nvolume 11\n", `unknown row "volume"`},
+		{"bad note", "note C H\n", `invalid note "H"`},
+		{"bad octave", "note C D\nu/d X .\n", "invalid octave mark"},
+		{"bad time", "note C\ntime ?\n", `invalid time "?"`},
+		{"leading tie", "note . C\ntime - o\n", "tie without a note"},
+		{"missing pitch", "note C . D\ntime o o o o\n", "step 2: note step without a pitch"},
+		{"bad tempo", "tempo fast\nnote C\n", "invalid tempo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseNotation([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseNotation() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
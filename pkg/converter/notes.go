@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var noteNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// NoteName returns the name of a MIDI note with middle C (60) as C4,
+// e.g. 45 -> "A2"
+func NoteName(note uint8) string {
+	return fmt.Sprintf("%s%d", noteNames[note%12], int(note)/12-1)
+}
+
+// Note naming styles
+const (
+	NoteNamesSharps  = "sharps"  // C, C#, D, ... B (the default)
+	NoteNamesFlats   = "flats"   // C, Db, D, ... B
+	NoteNamesGerman  = "german"  // flats, with H for B and B for Bb
+	NoteNamesSolfege = "solfege" // fixed do: Do, Do#, Re, ... Si
+)
+
+// NoteNamingStyles lists the valid note naming styles
+var NoteNamingStyles = []string{NoteNamesSharps, NoteNamesFlats, NoteNamesGerman, NoteNamesSolfege}
+
+var namingClasses = map[string][12]string{
+	NoteNamesFlats:   {"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"},
+	NoteNamesGerman:  {"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "B", "H"},
+	NoteNamesSolfege: {"Do", "Do#", "Re", "Re#", "Mi", "Fa", "Fa#", "Sol", "Sol#", "La", "La#", "Si"},
+}
+
+// NoteNaming selects how notes are named for display (inspect, grids,
+// analysis); files and JSON keep NoteName's names. The zero value names
+// notes like NoteName.
+type NoteNaming struct {
+	// Style is one of NoteNamingStyles; empty means sharps
+	Style string `json:"style,omitempty"`
+	// MiddleC is the octave of middle C (MIDI note 60): 4 as in scientific
+	// pitch notation, or 3 as on Yamaha gear and in many DAWs; 0 means 4
+	MiddleC int `json:"middle_c,omitempty"`
+}
+
+// Validate checks the naming style and middle C octave
+func (n NoteNaming) Validate() error {
+	if n.Style != "" && !slices.Contains(NoteNamingStyles, n.Style) {
+		return fmt.Errorf("unknown note naming %q (use %s)", n.Style, strings.Join(NoteNamingStyles, ", "))
+	}
+	if n.MiddleC != 0 && (n.MiddleC < 3 || n.MiddleC > 5) {
+		return fmt.Errorf("middle C octave %d out of range (3-5)", n.MiddleC)
+	}
+	return nil
+}
+
+// PitchClass returns the name of a pitch class (0 = C), e.g. "Db"
+func (n NoteNaming) PitchClass(pc int) string {
+	pc = (pc%12 + 12) % 12
+	if names, ok := namingClasses[n.Style]; ok {
+		return names[pc]
+	}
+	return noteNames[pc]
+}
+
+// Name returns the name of a MIDI note, e.g. 45 -> "A2", or "La1" in
+// solfège with middle C as C3
+func (n NoteNaming) Name(note uint8) string {
+	middleC := n.MiddleC
+	if middleC == 0 {
+		middleC = 4
+	}
+	return fmt.Sprintf("%s%d", n.PitchClass(int(note)), int(note)/12-5+middleC)
+}
+
+// Key renames a key as written by analysis, e.g. "A#m" -> "Bbm"; keys it
+// does not recognize are returned unchanged
+func (n NoteNaming) Key(key string) string {
+	tonic, minor := strings.CutSuffix(key, "m")
+	pc := slices.Index(noteNames[:], tonic)
+	if pc < 0 {
+		return key
+	}
+	if minor {
+		return n.PitchClass(pc) + "m"
+	}
+	return n.PitchClass(pc)
+}
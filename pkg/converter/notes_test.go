@@ -0,0 +1,56 @@
+package converter
+
+import "testing"
+
+func TestNoteName(t *testing.T) {
+	tests := []struct {
+		note uint8
+		want string
+	}{
+		{0, "C-1"},
+		{45, "A2"},
+		{60, "C4"},
+		{61, "C#4"},
+		{127, "G9"},
+	}
+	for _, tt := range tests {
+		if got := NoteName(tt.note); got != tt.want {
+			t.Errorf("NoteName(%d) = %q, want %q", tt.note, got, tt.want)
+		}
+	}
+}
+
+func TestNoteNaming(t *testing.T) {
+	tests := []struct {
+		naming NoteNaming
+		note   uint8
+		want   string
+	}{
+		{NoteNaming{}, 70, "A#4"},
+		{NoteNaming{Style: NoteNamesFlats}, 70, "Bb4"},
+		{NoteNaming{Style: NoteNamesGerman}, 70, "B4"},
+		{NoteNaming{Style: NoteNamesGerman}, 71, "H4"},
+		{NoteNaming{Style: NoteNamesSolfege}, 67, "Sol4"},
+		{NoteNaming{MiddleC: 3}, 60, "C3"},
+		{NoteNaming{MiddleC: 3}, 0, "C-2"},
+		{NoteNaming{Style: NoteNamesFlats, MiddleC: 5}, 61, "Db5"},
+	}
+	for _, tt := range tests {
+		if got := tt.naming.Name(tt.note); got != tt.want {
+			t.Errorf("%+v.Name(%d) = %q, want %q", tt.naming, tt.note, got, tt.want)
+		}
+	}
+
+	german := NoteNaming{Style: NoteNamesGerman}
+	for key, want := range map[string]string{"A#m": "Bm", "B": "H", "Am": "Am", "": "", "?": "?"} {
+		if got := german.Key(key); got != want {
+			t.Errorf("Key(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	for _, n := range []NoteNaming{{Style: "dutch"}, {MiddleC: 6}} {
+		if err := n.Validate(); err == nil {
+			t.Errorf("%+v accepted", n)
+		}
+	}
+}
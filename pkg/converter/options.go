@@ -0,0 +1,216 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Default trigger notes for gate/accent tracks (GM kick and side stick)
+const (
+	DefaultGateNote   = 36
+	DefaultAccentNote = 37
+)
+
+// DefaultGateLength is the fraction of a step that a note without slide or
+// tie sounds in generated MIDI (staccato, like a 303)
+const DefaultGateLength = 0.75
+
+// DefaultAccentThreshold is the lowest velocity read as an accent from MIDI
+const DefaultAccentThreshold = 101
+
+// DefaultGhostThreshold is the velocity below which drum hits are read as
+// ghost notes from MIDI
+const DefaultGhostThreshold = 60
+
+// Slide modes: how slides are detected when reading MIDI
+const (
+	// SlideInterval marks a slide between consecutive notes up to two
+	// semitones apart (the default)
+	SlideInterval = "interval"
+	// SlideLegato marks a slide where a note is held into the next one
+	SlideLegato = "legato"
+	// SlideNone never reads slides from MIDI
+	SlideNone = "none"
+)
+
+// SlideModes lists the valid slide modes
+var SlideModes = []string{SlideInterval, SlideLegato, SlideNone}
+
+// ConvertOptions controls optional conversion behavior
+type ConvertOptions struct {
+	// GateTrack adds a MIDI track of fixed-pitch notes mirroring each gated
+	// step, with accents encoded as velocity (CV-style gate output)
+	GateTrack bool  `json:"gate_track,omitempty"`
+	GateNote  uint8 `json:"gate_note"`
+
+	// AccentTrack adds a MIDI track of fixed-pitch notes on accented steps only
+	AccentTrack bool  `json:"accent_track,omitempty"`
+	AccentNote  uint8 `json:"accent_note"`
+
+	// DeviceID overrides the SysEx device ID written to .syx output so a
+	// specific unit in a multi-device chain responds; nil keeps the pattern's ID
+	DeviceID *uint8 `json:"device_id,omitempty"`
+
+	// Groove shifts the timing and velocity of generated MIDI notes
+	// following a template taken from another performance (see ExtractGroove)
+	Groove *Groove `json:"groove,omitempty"`
+
+	// Transpose shifts every note of the output by this many semitones
+	Transpose int `json:"transpose,omitempty"`
+
+	// Channel is the MIDI channel (1-16) of generated notes and the only
+	// channel read from MIDI input; 0 writes channel 1 and reads them all
+	Channel int `json:"channel,omitempty"`
+
+	// GateLength is the fraction of a step that a note without slide or tie
+	// sounds in generated MIDI; 0 means DefaultGateLength
+	GateLength float64 `json:"gate_length,omitempty"`
+
+	// AccentThreshold is the lowest velocity read as an accent from MIDI;
+	// 0 means DefaultAccentThreshold
+	AccentThreshold int `json:"accent_threshold,omitempty"`
+
+	// GhostThreshold is the velocity below which drum hits are read as
+	// ghost notes from MIDI; 0 means DefaultGhostThreshold, and 1 reads none
+	GhostThreshold int `json:"ghost_threshold,omitempty"`
+
+	// Accents reads accents and velocities from MIDI the way a reference
+	// performance plays them (see LearnAccents), in place of AccentThreshold
+	Accents *AccentMap `json:"accents,omitempty"`
+
+	// SlideMode selects how slides are read from MIDI; empty means
+	// SlideInterval
+	SlideMode string `json:"slide_mode,omitempty"`
+
+	// Bar selects one bar (1-based) of multi-bar MIDI input; 0 folds every
+	// bar onto one pattern
+	Bar int `json:"bar,omitempty"`
+
+	// Downmix reads every bar of MIDI input and fits patterns longer than
+	// the device's with this strategy (see DownmixModes), warning about what
+	// it removes; empty folds MIDI input onto one bar as it is read
+	Downmix string `json:"downmix,omitempty"`
+
+	// Strict fails conversions that produce warnings instead of fixing the
+	// pattern up
+	Strict bool `json:"strict,omitempty"`
+
+	// VerifyRoundtrip parses every generated .seq and .syx back with the
+	// device and fails the conversion if it reads differently, catching
+	// encoder bugs before the file reaches the hardware
+	VerifyRoundtrip bool `json:"verify_roundtrip,omitempty"`
+
+	// Recover salvages what it can of damaged .seq and .syx input (see
+	// Converter.Recover) instead of failing to parse it, warning about the
+	// bytes that could not be used
+	Recover bool `json:"recover,omitempty"`
+}
+
+// DefaultOptions returns the default conversion options
+func DefaultOptions() ConvertOptions {
+	return ConvertOptions{
+		GateNote:   DefaultGateNote,
+		AccentNote: DefaultAccentNote,
+	}
+}
+
+// Defaults are conversion options suited to a device, such as short gates
+// for drum hits. They apply where neither a preset nor a flag sets the
+// option; zero values leave the general defaults.
+type Defaults struct {
+	Octave     int     `json:"octave,omitempty"`      // octaves added to the transpose
+	GateLength float64 `json:"gate_length,omitempty"` // fraction of a step notes sound
+	SlideMode  string  `json:"slide_mode,omitempty"`  // how slides are read from MIDI
+}
+
+// WithDefaults returns the options with those left unset (zero) taken from
+// a device's defaults
+func (o ConvertOptions) WithDefaults(d Defaults) ConvertOptions {
+	if o.Transpose == 0 {
+		o.Transpose = 12 * d.Octave
+	}
+	if o.GateLength == 0 {
+		o.GateLength = d.GateLength
+	}
+	if o.SlideMode == "" {
+		o.SlideMode = d.SlideMode
+	}
+	return o
+}
+
+// Validate checks that the options are in range
+func (o ConvertOptions) Validate() error {
+	switch {
+	case o.Transpose < -127 || o.Transpose > 127:
+		return fmt.Errorf("transpose %d out of range (-127 to 127)", o.Transpose)
+	case o.Channel < 0 || o.Channel > 16:
+		return fmt.Errorf("channel %d out of range (1-16)", o.Channel)
+	case o.GateLength < 0 || o.GateLength > 1:
+		return fmt.Errorf("gate length %g out of range (0-1)", o.GateLength)
+	case o.AccentThreshold < 0 || o.AccentThreshold > 127:
+		return fmt.Errorf("accent threshold %d out of range (1-127)", o.AccentThreshold)
+	case o.GhostThreshold < 0 || o.GhostThreshold > 127:
+		return fmt.Errorf("ghost threshold %d out of range (1-127)", o.GhostThreshold)
+	case o.GhostThreshold > 0 && o.ghostThreshold() >= o.accentThreshold():
+		return fmt.Errorf("ghost threshold %d must be below the accent threshold %d", o.ghostThreshold(), o.accentThreshold())
+	case o.GateNote > 127:
+		return fmt.Errorf("gate note %d out of range (0-127)", o.GateNote)
+	case o.AccentNote > 127:
+		return fmt.Errorf("accent note %d out of range (0-127)", o.AccentNote)
+	case o.Bar < 0:
+		return errors.New("bar must be 1 or more")
+	}
+	if o.Accents != nil {
+		if err := o.Accents.Validate(); err != nil {
+			return err
+		}
+	}
+	switch o.SlideMode {
+	case "", SlideInterval, SlideLegato, SlideNone:
+	default:
+		return fmt.Errorf("unknown slide mode %q (use interval, legato, or none)", o.SlideMode)
+	}
+	switch o.Downmix {
+	case "", DownmixFold, DownmixDensest, DownmixThin, DownmixHalve:
+	default:
+		return fmt.Errorf("unknown downmix strategy %q (use fold, densest, thin, or halve)", o.Downmix)
+	}
+	if o.Downmix != "" && o.Bar > 0 {
+		return errors.New("bar and downmix cannot be combined")
+	}
+	return nil
+}
+
+// accentThreshold returns the lowest velocity read as an accent
+func (o ConvertOptions) accentThreshold() uint8 {
+	if o.AccentThreshold > 0 {
+		return uint8(o.AccentThreshold)
+	}
+	return DefaultAccentThreshold
+}
+
+// ghostThreshold returns the velocity below which drum hits are ghost
+// notes; the default stays below a lower accent threshold
+func (o ConvertOptions) ghostThreshold() uint8 {
+	if o.GhostThreshold > 0 {
+		return uint8(o.GhostThreshold)
+	}
+	return min(DefaultGhostThreshold, o.accentThreshold()-1)
+}
+
+// readVelocity returns the velocity a MIDI note is read with, and whether it
+// is an accent
+func (o ConvertOptions) readVelocity(velocity uint8) (uint8, bool) {
+	if o.Accents != nil {
+		return o.Accents.Map(velocity)
+	}
+	return velocity, velocity >= o.accentThreshold()
+}
+
+// midiChannel returns the 0-based MIDI channel for generated notes
+func (o ConvertOptions) midiChannel() uint8 {
+	if o.Channel > 0 {
+		return uint8(o.Channel - 1)
+	}
+	return 0
+}
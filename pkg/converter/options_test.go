@@ -0,0 +1,270 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+func TestConvertOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ConvertOptions
+		wantErr bool
+	}{
+		{"defaults", DefaultOptions(), false},
+		{"all set", ConvertOptions{Transpose: -12, Channel: 16, GateLength: 1, AccentThreshold: 90, SlideMode: SlideLegato, Bar: 3, Strict: true}, false},
+		{"transpose", ConvertOptions{Transpose: 128}, true},
+		{"channel", ConvertOptions{Channel: 17}, true},
+		{"gate length", ConvertOptions{GateLength: 1.5}, true},
+		{"accent threshold", ConvertOptions{AccentThreshold: 128}, true},
+		{"ghost threshold", ConvertOptions{GhostThreshold: 128}, true},
+		{"ghost above accent", ConvertOptions{GhostThreshold: 90, AccentThreshold: 80}, true},
+		{"accent below default ghost", ConvertOptions{AccentThreshold: 50}, false},
+		{"slide mode", ConvertOptions{SlideMode: "glide"}, true},
+		{"bar", ConvertOptions{Bar: -1}, true},
+		{"gate note", ConvertOptions{GateNote: 128}, true},
+		{"accent note", ConvertOptions{AccentNote: 255}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	device := Defaults{Octave: -1, GateLength: 0.25, SlideMode: SlideNone}
+	tests := []struct {
+		name string
+		opts ConvertOptions
+		want ConvertOptions
+	}{
+		{"unset", ConvertOptions{}, ConvertOptions{Transpose: -12, GateLength: 0.25, SlideMode: SlideNone}},
+		{"set", ConvertOptions{Transpose: 3, GateLength: 0.5, SlideMode: SlideLegato}, ConvertOptions{Transpose: 3, GateLength: 0.5, SlideMode: SlideLegato}},
+		{"partly set", ConvertOptions{GateLength: 1, Channel: 2}, ConvertOptions{Transpose: -12, GateLength: 1, SlideMode: SlideNone, Channel: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.WithDefaults(device); got != tt.want {
+				t.Errorf("WithDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := DefaultOptions().WithDefaults(Defaults{}); got != DefaultOptions() {
+		t.Errorf("WithDefaults(none) = %+v, want DefaultOptions()", got)
+	}
+}
+
+// note is a MIDI note for building test files, in 16th-note steps
+type note struct {
+	channel, key, velocity uint8
+	step, length           uint32
+}
+
+// testMIDI builds a one-track MIDI file at 480 ticks per quarter
+func testMIDI(t *testing.T, notes ...note) []byte {
+	t.Helper()
+	const ticksPerStep = 120
+	var events []timedMessage
+	for _, n := range notes {
+		start := n.step * ticksPerStep
+		events = append(events,
+			timedMessage{tick: start, msg: smf.Message(midi.NoteOn(n.channel, n.key, n.velocity))},
+			timedMessage{tick: start + n.length*ticksPerStep - 1, msg: smf.Message(midi.NoteOff(n.channel, n.key)), off: true})
+	}
+	// Overlapping notes put the events out of order
+	sort.SliceStable(events, func(i, j int) bool { return events[i].tick < events[j].tick })
+	s := smf.New()
+	s.TimeFormat = smf.MetricTicks(480)
+	var track smf.Track
+	var tick uint32
+	for _, ev := range events {
+		track.Add(ev.tick-tick, ev.msg)
+		tick = ev.tick
+	}
+	track.Close(0)
+	if err := s.Add(track); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseMIDIOptions(t *testing.T) {
+	data := testMIDI(t,
+		note{0, 48, 100, 0, 1},
+		note{0, 50, 110, 1, 2}, // held into the next note
+		note{0, 60, 100, 2, 1},
+		note{1, 72, 100, 4, 1},  // second channel
+		note{0, 55, 127, 16, 1}, // second bar
+	)
+	parse := func(opts ConvertOptions) []Step {
+		t.Helper()
+		m := NewMIDIConverter()
+		m.SetOptions(opts)
+		p, err := m.ParseMIDI(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Steps
+	}
+
+	steps := parse(DefaultOptions())
+	if !steps[0].Gate || steps[0].Note != 55 || !steps[4].Gate {
+		t.Errorf("default parse should fold bar 2 onto step 1 and read all channels: %+v", steps[:5])
+	}
+	if !steps[1].Accent || steps[2].Accent {
+		t.Errorf("default accent threshold: %+v", steps[:3])
+	}
+
+	opts := DefaultOptions()
+	opts.Bar = 1
+	steps = parse(opts)
+	if !steps[0].Slide || steps[1].Slide {
+		t.Errorf("interval slides: %+v", steps[:3])
+	}
+
+	opts = DefaultOptions()
+	opts.Bar, opts.Channel, opts.SlideMode, opts.AccentThreshold = 1, 1, SlideLegato, 111
+	steps = parse(opts)
+	if steps[0].Note != 48 || steps[4].Gate {
+		t.Errorf("bar 1 on channel 1 = %+v", steps[:5])
+	}
+	if steps[0].Slide || !steps[1].Slide || steps[1].Accent {
+		t.Errorf("legato slides and accent threshold 111: %+v", steps[:3])
+	}
+
+	opts = DefaultOptions()
+	opts.Bar, opts.SlideMode = 2, SlideNone
+	steps = parse(opts)
+	if steps[0].Note != 55 || steps[1].Gate {
+		t.Errorf("bar 2 = %+v", steps[:2])
+	}
+	for i, s := range steps {
+		if s.Slide {
+			t.Errorf("step %d slides with slide mode none", i+1)
+		}
+	}
+}
+
+func TestParseMIDIGhostNotes(t *testing.T) {
+	// Hits below the ghost threshold are ghost notes, and are written back
+	// soft enough to read as ghost notes again
+	hits := testMIDI(t,
+		note{channel: DrumChannel, key: 38, velocity: 30, step: 0, length: 1},
+		note{channel: DrumChannel, key: 38, velocity: 80, step: 1, length: 1},
+		note{channel: DrumChannel, key: 38, velocity: 120, step: 2, length: 1},
+	)
+	for _, tt := range []struct {
+		name      string
+		threshold int
+		want      [3]bool
+	}{
+		{"default", 0, [3]bool{true, false, false}},
+		{"threshold 90", 90, [3]bool{true, true, false}},
+		{"threshold 1", 1, [3]bool{false, false, false}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMIDIConverter()
+			opts := DefaultOptions()
+			opts.GhostThreshold = tt.threshold
+			m.SetOptions(opts)
+			m.SetDrumLanes([]Track{{Name: "SD", Note: 38}}, 16)
+			pattern, err := m.ParseMIDI(hits)
+			if err != nil {
+				t.Fatalf("ParseMIDI() error = %v", err)
+			}
+			for i, want := range tt.want {
+				if got := pattern.Tracks[0].Steps[i].Ghost; got != want {
+					t.Errorf("step %d ghost = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	pattern := &Pattern{Tracks: []Track{{Name: "SD", Note: 38, Steps: []Step{{Note: 38, Gate: true, Velocity: 100, Ghost: true}}}}}
+	data, err := NewMIDIConverter().GenerateMIDI(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMIDIConverter()
+	m.SetDrumLanes([]Track{{Name: "SD", Note: 38}}, 16)
+	back, err := m.ParseMIDI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := back.Tracks[0].Steps[0]; !s.Ghost || s.Velocity != GhostVelocity {
+		t.Errorf("ghost note read back as %+v", s)
+	}
+}
+
+func TestGenerateMIDIOptions(t *testing.T) {
+	conv := New(&mockDevice{})
+	opts := DefaultOptions()
+	opts.Transpose, opts.Channel, opts.GateLength = 12, 3, 0.5
+	conv.SetOptions(opts)
+
+	data, err := conv.GeneratePattern(&Pattern{Length: 16, Steps: []Step{{Note: 48, Gate: true}}}, FormatMIDI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := smf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var onTick, offTick int64 = -1, -1
+	var tick int64
+	for _, ev := range s.Tracks[0] {
+		tick += int64(ev.Delta)
+		var ch, key, vel uint8
+		switch {
+		case ev.Message.GetNoteStart(&ch, &key, &vel):
+			onTick = tick
+			if ch != 2 || key != 60 {
+				t.Errorf("note on channel %d key %d, want channel 2 (3rd) key 60", ch, key)
+			}
+		case ev.Message.GetNoteEnd(&ch, &key):
+			offTick = tick
+		}
+	}
+	if onTick != 0 || offTick != 60 {
+		t.Errorf("note spans ticks %d-%d, want 0-60 (half a step)", onTick, offTick)
+	}
+}
+
+func TestTransposeAndStrict(t *testing.T) {
+	p := &Pattern{Steps: []Step{{Note: 120, Gate: true}, {Note: 0}}}
+	if err := p.Transpose(12); err == nil || p.Steps[0].Note != 120 {
+		t.Errorf("transposing out of range = %v, note %d; want error and unchanged", err, p.Steps[0].Note)
+	}
+	if err := p.Transpose(-12); err != nil || p.Steps[0].Note != 108 || p.Steps[1].Note != 0 {
+		t.Errorf("Transpose(-12) = %v, steps %+v", err, p.Steps)
+	}
+
+	// A tie after a rest is fixed up with a warning, or fails when strict
+	tied := func() *Pattern {
+		return &Pattern{Length: 16, Steps: []Step{{}, {Note: 48, Gate: true, Tie: true}}}
+	}
+	conv := New(&mockDevice{})
+	if _, err := conv.GeneratePattern(tied(), FormatSeq); err != nil || len(conv.Warnings()) != 1 {
+		t.Errorf("lenient conversion = %v, warnings %v", err, conv.Warnings())
+	}
+	opts := DefaultOptions()
+	opts.Strict = true
+	conv.SetOptions(opts)
+	_, err := conv.GeneratePattern(tied(), FormatSeq)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Violations) != 1 {
+		t.Errorf("strict conversion = %v, want a ValidationError with the warning", err)
+	}
+}
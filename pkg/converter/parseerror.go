@@ -0,0 +1,94 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// contextBytes is how many bytes either side of a parse error are shown
+const contextBytes = 8
+
+// ParseError locates a failure to parse a .seq or .syx file: the byte
+// offset the parser stopped at, the field it was reading there, and the
+// bytes around it, which tell a damaged file (garbage amid the expected
+// layout) from one of another format or firmware version (a different
+// layout altogether)
+type ParseError struct {
+	Offset  int    `json:"offset"`
+	Field   string `json:"field"`
+	Context string `json:"context,omitempty"` // hex bytes around Offset, the one at Offset in brackets
+	Err     error  `json:"-"`
+}
+
+// Error implements the error interface
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%v (at byte %d, in the %s", e.Err, e.Offset, e.Field)
+	if e.Context != "" {
+		msg += ": " + e.Context
+	}
+	return msg + ")"
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorAt returns err located at offset while reading field. The context
+// is filled in when the converter returns the error.
+func ErrorAt(offset int, field string, err error) error {
+	return &ParseError{Offset: offset, Field: field, Err: err}
+}
+
+// ErrorfAt is ErrorAt with a formatted error
+func ErrorfAt(offset int, field, format string, args ...any) error {
+	return ErrorAt(offset, field, fmt.Errorf(format, args...))
+}
+
+// locate adds the context from data to a ParseError in err, turning the
+// offset of a SysEx framing error into one
+func locate(err error, data []byte) error {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		var serr *sysex.OffsetError
+		if !errors.As(err, &serr) {
+			return err
+		}
+		perr = &ParseError{Offset: serr.Offset, Field: serr.Field, Err: err}
+		err = perr
+	}
+	if perr.Context == "" {
+		perr.Context = hexContext(data, perr.Offset)
+	}
+	return err
+}
+
+// hexContext shows the bytes around offset, e.g. "... 00 10 [F5] 01 F7"
+// with the byte at offset in brackets, or "[end]" past the last one
+func hexContext(data []byte, offset int) string {
+	if offset < 0 || offset > len(data) {
+		return ""
+	}
+	from, to := max(offset-contextBytes, 0), min(offset+contextBytes+1, len(data))
+	var parts []string
+	if from > 0 {
+		parts = append(parts, "...")
+	}
+	for i := from; i < to; i++ {
+		if i == offset {
+			parts = append(parts, fmt.Sprintf("[%02X]", data[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%02X", data[i]))
+		}
+	}
+	switch {
+	case offset == len(data):
+		parts = append(parts, "[end]")
+	case to < len(data):
+		parts = append(parts, "...")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,47 @@
+package converter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+func TestHexContext(t *testing.T) {
+	data := make([]byte, 20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	tests := []struct {
+		name   string
+		data   []byte
+		offset int
+		want   string
+	}{
+		{"start", data, 0, "[00] 01 02 03 04 05 06 07 08 ..."},
+		{"middle", data, 10, "... 02 03 04 05 06 07 08 09 [0A] 0B 0C 0D 0E 0F 10 11 12 ..."},
+		{"last byte", data, 19, "... 0B 0C 0D 0E 0F 10 11 12 [13]"},
+		{"past the end", data[:3], 3, "00 01 02 [end]"},
+		{"out of range", data, 21, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hexContext(tt.data, tt.offset); got != tt.want {
+				t.Errorf("hexContext(%d) = %q, want %q", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocateSysExError(t *testing.T) {
+	data := []byte{0xF0, 0x00, 0x20, 0x32, 0x90, 0xF7}
+	err := locate(sysex.Validate(data), data)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Offset != 4 || perr.Field != "data byte" || perr.Context != "F0 00 20 32 [90] F7" {
+		t.Fatalf("locate = %#v", err)
+	}
+	want := "invalid SysEx: byte at position 4 is > 127 (0x90) (at byte 4, in the data byte: F0 00 20 32 [90] F7)"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
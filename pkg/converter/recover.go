@@ -0,0 +1,347 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// Recover salvages what it can of .seq or .syx data that the device cannot
+// parse, such as a truncated file, a SysEx dump without its end byte, or a
+// file with a garbled header. The damaged file is laid over the file the
+// device writes for an empty pattern: bytes missing from its end, and SysEx
+// bytes that are not data bytes, are taken from that file, and if it still
+// does not parse, so is a header that differs. A SysEx checksum is then
+// recomputed. The steps stored in unusable bytes are read as rests, and the
+// warnings returned say which bytes (counted from 0) were unusable.
+//
+// Patterns of every length the device holds are tried, longest first, so
+// formats whose size depends on the pattern length are recovered too.
+func (c *Converter) Recover(data []byte, format Format) (*Pattern, []Violation, error) {
+	if format != FormatSeq && format != FormatSyx {
+		return nil, nil, fmt.Errorf("cannot recover %s input, only .seq and .syx", format)
+	}
+	if format == FormatSyx {
+		data = syxData(data)
+	}
+	if err := c.limits.CheckInput(data, format); err != nil {
+		return nil, nil, err
+	}
+
+	var framing []Violation
+	offset := 0 // bytes dropped before the message, for reported offsets
+	if format == FormatSyx {
+		data, offset, framing = frameSyx(data)
+	}
+
+	maxSteps := DeviceCapabilities(c.device).MaxSteps
+	if maxSteps == 0 {
+		maxSteps = 16
+	}
+	sizes := make(map[int]bool)
+	for n := maxSteps; n > 0; n-- {
+		layout, err := c.fileLayout(format, n)
+		if err != nil || sizes[len(layout.empty)] || !layout.fits(data, format) {
+			continue
+		}
+		sizes[len(layout.empty)] = true
+		for _, restoreHeader := range []bool{false, true} {
+			fixed, damage := layout.repair(data, format, restoreHeader, offset)
+			pattern, err := c.parse(fixed, format)
+			if err != nil {
+				continue
+			}
+			layout.clearSteps(pattern, damage)
+			if err := c.limits.checkPattern(pattern); err != nil {
+				return nil, nil, err
+			}
+			return pattern, append(framing, damage.violations()...), nil
+		}
+	}
+	return nil, nil, errors.New("no part of it could be read")
+}
+
+// EmptyFile returns the .seq or .syx file the device writes for an empty
+// pattern of its maximum length: the file Recover repairs damaged input from
+func EmptyFile(dev Device, format Format) ([]byte, error) {
+	n := DeviceCapabilities(dev).MaxSteps
+	if n == 0 {
+		n = 16
+	}
+	return generateDevice(dev, probePattern(dev, n, -1, 0), format)
+}
+
+// fileLayout maps the file a device writes for patterns of one length
+type fileLayout struct {
+	empty    []byte  // the file of an empty pattern
+	steps    [][]int // offsets of the bytes holding each step
+	header   int     // length of the header before the first step byte
+	checksum int     // offset of the SysEx checksum byte, or -1
+	payload  int     // offset of the SysEx payload the checksum covers
+	sum      sysex.Checksum
+}
+
+// fileLayout finds the bytes of each step by generating files with one step
+// set at a time and comparing them with the file of an empty pattern
+func (c *Converter) fileLayout(format Format, n int) (*fileLayout, error) {
+	empty, err := generateDevice(c.device, probePattern(c.device, n, -1, 0), format)
+	if err != nil {
+		return nil, err
+	}
+	l := &fileLayout{empty: empty, steps: make([][]int, n), header: len(empty), checksum: -1}
+	if dev, ok := c.device.(SysExDevice); ok && format == FormatSyx {
+		layout := dev.SysExLayout()
+		if manufacturer, err := sysex.ManufacturerID(empty); err == nil && layout.Checksum != nil && layout.Checksum != sysex.None {
+			l.checksum = len(empty) - 2
+			l.payload = 1 + len(manufacturer) + layout.HeaderLen
+			l.sum = layout.Checksum
+		}
+	}
+
+	for i := range n {
+		for variant := range 2 {
+			file, err := generateDevice(c.device, probePattern(c.device, n, i, variant), format)
+			if err != nil || len(file) != len(empty) {
+				continue
+			}
+			for off := range file {
+				if file[off] != empty[off] && off != l.checksum && !slices.Contains(l.steps[i], off) {
+					l.steps[i] = append(l.steps[i], off)
+					l.header = min(l.header, off)
+				}
+			}
+		}
+	}
+	return l, nil
+}
+
+// fits reports whether data can be a damaged file of this layout: no longer
+// than it, and for a SysEx message ending in F7, the same length
+func (l *fileLayout) fits(data []byte, format Format) bool {
+	if len(data) > len(l.empty) {
+		return false
+	}
+	if format == FormatSyx && len(data) > 1 && data[len(data)-1] == sysex.End {
+		return len(data) == len(l.empty)
+	}
+	return true
+}
+
+// damage records the unusable bytes of a file being recovered
+type damage struct {
+	offset   int   // bytes dropped before the file, added to reported offsets
+	size     int   // size of the complete file
+	missing  int   // offset where the truncated file ends, or -1
+	garbled  []int // SysEx bytes that are not data bytes
+	end      bool  // the SysEx end byte was something else
+	header   []int // header bytes restored
+	checksum string
+	steps    []int // steps read as rests
+}
+
+// repair lays data over the empty file, taking from it the bytes data lacks
+// and, when restoreHeader is set, the header bytes that differ
+func (l *fileLayout) repair(data []byte, format Format, restoreHeader bool, offset int) ([]byte, *damage) {
+	fixed := bytes.Clone(l.empty)
+	copy(fixed, data)
+	d := &damage{offset: offset, size: len(fixed), missing: -1}
+	if len(data) < len(fixed) {
+		d.missing = len(data)
+	}
+
+	if format == FormatSyx {
+		for i := 1; i < len(data) && i < len(fixed)-1; i++ {
+			if data[i] > 0x7F {
+				fixed[i] = l.empty[i]
+				d.garbled = append(d.garbled, i)
+			}
+		}
+		if last := len(fixed) - 1; len(data) == len(fixed) && data[last] != sysex.End {
+			fixed[last] = sysex.End
+			d.end = true
+		}
+	}
+	if restoreHeader {
+		for i := 0; i < l.header && i < len(data); i++ {
+			if fixed[i] != l.empty[i] && !slices.Contains(d.garbled, i) {
+				fixed[i] = l.empty[i]
+				d.header = append(d.header, i)
+			}
+		}
+	}
+
+	if l.checksum >= 0 && l.payload <= l.checksum {
+		want := l.sum.Sum(fixed[l.payload:l.checksum])
+		intact := d.missing < 0 && !slices.ContainsFunc(d.garbled, func(i int) bool { return i < l.checksum })
+		if fixed[l.checksum] != want && intact {
+			d.checksum = fmt.Sprintf("%s checksum 0x%02X does not match the data (0x%02X), so some bytes are corrupted; it was recomputed",
+				l.sum.Name(), fixed[l.checksum], want)
+		}
+		fixed[l.checksum] = want
+	}
+	return fixed, d
+}
+
+// clearSteps turns the steps held in unusable bytes into rests, recording
+// them in d
+func (l *fileLayout) clearSteps(p *Pattern, d *damage) {
+	unusable := func(off int) bool {
+		return (d.missing >= 0 && off >= d.missing) || slices.Contains(d.garbled, off)
+	}
+	for i, offsets := range l.steps {
+		if !slices.ContainsFunc(offsets, unusable) {
+			continue
+		}
+		d.steps = append(d.steps, i)
+		if i < len(p.Steps) {
+			p.Steps[i] = Step{}
+		}
+		for t := range p.Tracks {
+			if i < len(p.Tracks[t].Steps) {
+				p.Tracks[t].Steps[i] = Step{}
+			}
+		}
+	}
+}
+
+// violations describes the damage as warnings
+func (d *damage) violations() []Violation {
+	var msgs []string
+	for _, span := range spans(d.header) {
+		msgs = append(msgs, fmt.Sprintf("%s of the header garbled; restored", d.bytes(span[0], span[1])))
+	}
+	for _, span := range spans(d.garbled) {
+		msgs = append(msgs, fmt.Sprintf("%s garbled (not SysEx data)", d.bytes(span[0], span[1])))
+	}
+	if d.end {
+		msgs = append(msgs, fmt.Sprintf("%s is not the F7 end byte; restored", d.bytes(d.size-1, d.size-1)))
+	}
+	if d.missing >= 0 {
+		msgs = append(msgs, fmt.Sprintf("%s missing: the file ends after %d of %d bytes", d.bytes(d.missing, d.size-1), d.missing, d.size))
+	}
+	if d.checksum != "" {
+		msgs = append(msgs, d.checksum)
+	}
+	switch len(d.steps) {
+	case 0:
+	case 1:
+		msgs = append(msgs, describeSteps(d.steps)+" read as a rest")
+	default:
+		msgs = append(msgs, describeSteps(d.steps)+" read as rests")
+	}
+
+	violations := make([]Violation, len(msgs))
+	for i, msg := range msgs {
+		violations[i] = Violation{Step: -1, Field: "input", Message: msg, Severity: SeverityWarning}
+	}
+	return violations
+}
+
+// bytes names a range of offsets in the file, e.g. "bytes 120-145"
+func (d *damage) bytes(from, to int) string {
+	return describeBytes(from+d.offset, to+d.offset)
+}
+
+// describeBytes names a range of offsets, e.g. "bytes 120-145" or "byte 3"
+func describeBytes(from, to int) string {
+	if from == to {
+		return fmt.Sprintf("byte %d", from)
+	}
+	return fmt.Sprintf("bytes %d-%d", from, to)
+}
+
+// frameSyx drops the bytes before the first F0 and after the last F7 of
+// SysEx data, returning how many were dropped from the start
+func frameSyx(data []byte) ([]byte, int, []Violation) {
+	var violations []Violation
+	start := bytes.IndexByte(data, sysex.Start)
+	if start > 0 {
+		violations = append(violations, Violation{Step: -1, Field: "input", Severity: SeverityWarning,
+			Message: describeBytes(0, start-1) + " before the F0 start byte ignored"})
+		data = data[start:]
+	}
+	start = max(start, 0)
+	if end := bytes.LastIndexByte(data, sysex.End); end >= 0 && end < len(data)-1 {
+		violations = append(violations, Violation{Step: -1, Field: "input", Severity: SeverityWarning,
+			Message: describeBytes(start+end+1, start+len(data)-1) + " after the F7 end byte ignored"})
+		data = data[:end+1]
+	}
+	return data, start, violations
+}
+
+// spans groups sorted offsets into runs of consecutive ones
+func spans(offsets []int) [][2]int {
+	var runs [][2]int
+	for _, off := range offsets {
+		if n := len(runs); n > 0 && runs[n-1][1] == off-1 {
+			runs[n-1][1] = off
+			continue
+		}
+		runs = append(runs, [2]int{off, off})
+	}
+	return runs
+}
+
+// describeSteps lists step indexes 1-based, joining runs, e.g. "steps 1, 9-16"
+func describeSteps(steps []int) string {
+	var parts []string
+	for _, span := range spans(steps) {
+		if span[0] == span[1] {
+			parts = append(parts, fmt.Sprint(span[0]+1))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", span[0]+1, span[1]+1))
+		}
+	}
+	if len(steps) == 1 {
+		return "step " + parts[0]
+	}
+	return "steps " + strings.Join(parts, ", ")
+}
+
+// probePattern returns an empty pattern of n steps, the way the device holds
+// patterns, with step (unless -1) set: every flag and the highest note for
+// variant 0, a plain hit or the lowest note tied for variant 1
+func probePattern(dev Device, n, step, variant int) *Pattern {
+	p := &Pattern{Length: n, Tempo: 120}
+	caps := DeviceCapabilities(dev)
+	set := func(s *Step, drum bool) {
+		switch {
+		case drum && variant == 0:
+			*s = Step{Gate: true, Accent: true, Velocity: 127, Flam: 64, Probability: 50}
+		case drum:
+			*s = Step{Gate: true, Ghost: true, Velocity: 40}
+		case variant == 0:
+			*s = Step{Note: caps.MaxNote, Gate: true, Accent: true, Slide: true, SubAccent: true, Velocity: 127}
+		default:
+			*s = Step{Note: caps.MinNote, Gate: true, Tie: step > 0, Velocity: 100}
+		}
+	}
+	if drums, ok := dev.(DrumDevice); ok {
+		for _, lane := range drums.DrumLanes() {
+			lane.Steps = make([]Step, n)
+			if step >= 0 {
+				set(&lane.Steps[step], true)
+			}
+			p.Tracks = append(p.Tracks, lane)
+		}
+		return p
+	}
+	p.Steps = make([]Step, n)
+	if step >= 0 {
+		set(&p.Steps[step], false)
+	}
+	return p
+}
+
+// generateDevice writes a pattern in the device's own format as it is,
+// without normalizing or validating it
+func generateDevice(dev Device, p *Pattern, format Format) ([]byte, error) {
+	if format == FormatSyx {
+		return dev.GenerateSyx(p)
+	}
+	return dev.GenerateSeq(p)
+}
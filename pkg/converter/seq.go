@@ -38,6 +38,9 @@ func (s *SeqConverter) GenerateSeq(pattern *Pattern) ([]byte, error) {
 	if s.device == nil {
 		return nil, errors.New("no device configured")
 	}
+	if err := validate(pattern, s.device); err != nil {
+		return nil, err
+	}
 	return s.device.GenerateSeq(pattern)
 }
 
@@ -47,7 +50,7 @@ func (s *SeqConverter) WriteSeqFile(pattern *Pattern, filename string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, data, 0644)
+	return WriteFileAtomic(filename, data, 0644)
 }
 
 // ValidateSeq validates .seq data structure
@@ -0,0 +1,114 @@
+package converter
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strings"
+)
+
+// SharePrefix starts every share string and carries the encoding version
+const SharePrefix = "st1."
+
+// Share string step flags
+const (
+	shareGate   = 0x80
+	shareAccent = 0x01
+	shareSlide  = 0x02
+	shareTie    = 0x04
+)
+
+// EncodeShareString encodes a pattern as a compact URL-safe string that fits
+// in a tweet or QR code (52 characters for 16 steps). The string holds the
+// step count, tempo, and each step's note, gate, accent, slide, and tie;
+// names and exact velocities are not kept.
+//
+// Layout before base64url: length (1 byte), tempo in tenths of a BPM
+// (2 bytes, 0 = unset), 2 bytes per step (gate<<7|note, flags), and a
+// checksum byte to catch mistyped strings.
+func EncodeShareString(p *Pattern) (string, error) {
+	steps := p.Steps
+	if p.Length > 0 && p.Length < len(steps) {
+		steps = steps[:p.Length]
+	}
+	if len(steps) > math.MaxUint8 {
+		return "", fmt.Errorf("pattern has %d steps; share strings hold at most %d", len(steps), math.MaxUint8)
+	}
+	if p.Tempo < 0 || p.Tempo*10 > math.MaxUint16 {
+		return "", fmt.Errorf("tempo %.1f cannot be encoded", p.Tempo)
+	}
+
+	buf := make([]byte, 3, 3+2*len(steps)+1)
+	buf[0] = byte(len(steps))
+	binary.BigEndian.PutUint16(buf[1:3], uint16(math.Round(p.Tempo*10)))
+	for _, s := range steps {
+		b0 := s.Note & 0x7F
+		if s.Gate {
+			b0 |= shareGate
+		}
+		var b1 byte
+		if s.Accent {
+			b1 |= shareAccent
+		}
+		if s.Slide {
+			b1 |= shareSlide
+		}
+		if s.Tie {
+			b1 |= shareTie
+		}
+		buf = append(buf, b0, b1)
+	}
+	buf = append(buf, shareChecksum(buf))
+	return SharePrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// DecodeShareString decodes a string made by EncodeShareString. Gated steps
+// get velocity 100, or 127 when accented.
+func DecodeShareString(s string) (*Pattern, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, SharePrefix) {
+		return nil, fmt.Errorf("not a share string (expected %q prefix)", SharePrefix)
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, SharePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid share string: %w", err)
+	}
+	if len(buf) < 4 || len(buf) != 3+2*int(buf[0])+1 {
+		return nil, errors.New("invalid share string: wrong length")
+	}
+	if buf[len(buf)-1] != shareChecksum(buf[:len(buf)-1]) {
+		return nil, errors.New("invalid share string: checksum mismatch (mistyped?)")
+	}
+
+	n := int(buf[0])
+	p := &Pattern{
+		Length: n,
+		Tempo:  float64(binary.BigEndian.Uint16(buf[1:3])) / 10,
+		Steps:  make([]Step, n),
+	}
+	for i := range p.Steps {
+		b0, b1 := buf[3+2*i], buf[4+2*i]
+		step := Step{
+			Note:   b0 &^ shareGate,
+			Gate:   b0&shareGate != 0,
+			Accent: b1&shareAccent != 0,
+			Slide:  b1&shareSlide != 0,
+			Tie:    b1&shareTie != 0,
+		}
+		if step.Gate {
+			step.Velocity = 100
+			if step.Accent {
+				step.Velocity = 127
+			}
+		}
+		p.Steps[i] = step
+	}
+	return p, nil
+}
+
+func shareChecksum(data []byte) byte {
+	return byte(crc32.ChecksumIEEE(data))
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShareString(t *testing.T) {
+	p := &Pattern{
+		Length: 16,
+		Tempo:  128.5,
+		Steps:  make([]Step, 16),
+	}
+	p.Steps[0] = Step{Note: 45, Gate: true, Velocity: 127, Accent: true}
+	p.Steps[1] = Step{Note: 48, Gate: true, Velocity: 100, Slide: true}
+	p.Steps[2] = Step{Note: 48, Gate: true, Velocity: 100, Tie: true}
+	p.Steps[3] = Step{Note: 48, Gate: true, Velocity: 100}
+
+	s, err := EncodeShareString(p)
+	if err != nil {
+		t.Fatalf("EncodeShareString() error = %v", err)
+	}
+	if !strings.HasPrefix(s, SharePrefix) || len(s) != 52 {
+		t.Errorf("share string %q: want %q prefix and 52 characters", s, SharePrefix)
+	}
+
+	got, err := DecodeShareString(s)
+	if err != nil {
+		t.Fatalf("DecodeShareString() error = %v", err)
+	}
+	if diffs := Diff(p, got); len(diffs) != 0 {
+		t.Errorf("round trip differs: %v", diffs)
+	}
+	if got.Length != 16 || got.Steps[0].Velocity != 127 || got.Steps[1].Velocity != 100 {
+		t.Errorf("decoded = %+v", got)
+	}
+
+	// A mistyped character is caught by the checksum or length check
+	typo := []byte(s)
+	if typo[10] == 'A' {
+		typo[10] = 'B'
+	} else {
+		typo[10] = 'A'
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"no prefix", "hello", "not a share string"},
+		{"bad base64", SharePrefix + "!!!", "invalid share string"},
+		{"truncated", s[:len(s)-4], "wrong length"},
+		{"typo", string(typo), "checksum mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeShareString(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecodeShareString() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
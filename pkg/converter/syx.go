@@ -4,12 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
 )
 
 // SysEx constants
 const (
-	SysExStart = 0xF0
-	SysExEnd   = 0xF7
+	SysExStart = sysex.Start
+	SysExEnd   = sysex.End
 )
 
 // SyxConverter handles .syx file parsing and generation
@@ -50,6 +52,9 @@ func (s *SyxConverter) GenerateSyx(pattern *Pattern) ([]byte, error) {
 	if s.device == nil {
 		return nil, errors.New("no device configured")
 	}
+	if err := validate(pattern, s.device); err != nil {
+		return nil, err
+	}
 	return s.device.GenerateSyx(pattern)
 }
 
@@ -59,65 +64,34 @@ func (s *SyxConverter) WriteSyxFile(pattern *Pattern, filename string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, data, 0644)
+	return WriteFileAtomic(filename, data, 0644)
 }
 
-// ValidateSyx validates .syx data structure
+// ValidateSyx validates .syx data structure, including the checksum when the
+// device describes its SysEx layout
 func (s *SyxConverter) ValidateSyx(data []byte) error {
-	if len(data) < 2 {
-		return errors.New("syx data too short")
-	}
-	
-	if data[0] != SysExStart {
-		return fmt.Errorf("invalid SysEx: expected start byte 0x%02X, got 0x%02X", SysExStart, data[0])
-	}
-	
-	if data[len(data)-1] != SysExEnd {
-		return fmt.Errorf("invalid SysEx: expected end byte 0x%02X, got 0x%02X", SysExEnd, data[len(data)-1])
-	}
-	
-	// Check all data bytes are 7-bit (valid MIDI data)
-	for i := 1; i < len(data)-1; i++ {
-		if data[i] > 127 {
-			return fmt.Errorf("invalid SysEx: byte at position %d is > 127 (0x%02X)", i, data[i])
-		}
+	if dev, ok := s.device.(SysExDevice); ok {
+		_, err := sysex.Parse(data, dev.SysExLayout())
+		return err
 	}
-	
-	return nil
+	return sysex.Validate(data)
 }
 
 // ExtractManufacturerID extracts the manufacturer ID from SysEx data
 func ExtractManufacturerID(data []byte) ([]byte, error) {
-	if len(data) < 4 {
-		return nil, errors.New("syx data too short for manufacturer ID")
-	}
-	
-	if data[0] != SysExStart {
-		return nil, errors.New("invalid SysEx start")
-	}
-	
-	// Check if extended manufacturer ID (starts with 0x00)
-	if data[1] == 0x00 {
-		if len(data) < 5 {
-			return nil, errors.New("syx data too short for extended manufacturer ID")
-		}
-		return data[1:4], nil
+	return sysex.ManufacturerID(data)
+}
+
+// ExtractDeviceID extracts the SysEx device ID from Behringer SysEx data.
+// Units in a daisy chain are distinguished by this ID.
+func ExtractDeviceID(data []byte) (uint8, error) {
+	if !IsBehringerSyx(data) {
+		return 0, errors.New("not a Behringer SysEx message")
 	}
-	
-	// Single byte manufacturer ID
-	return data[1:2], nil
+	return data[4], nil
 }
 
 // IsBehringerSyx checks if the SysEx data is from a Behringer device
 func IsBehringerSyx(data []byte) bool {
-	if len(data) < 5 {
-		return false
-	}
-	
-	// Behringer extended manufacturer ID: 00 20 32
-	return data[0] == SysExStart &&
-		data[1] == 0x00 &&
-		data[2] == 0x20 &&
-		data[3] == 0x32
+	return len(data) >= 5 && sysex.HasManufacturer(data, sysex.Behringer)
 }
-
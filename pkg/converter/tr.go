@@ -0,0 +1,195 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// trFlam is the flam of flammed hits in TR pattern exports, a quarter step
+const trFlam = 64
+
+// trInstruments are the instruments of the TR-8S, in its track order, with
+// the General MIDI drum notes they play
+var trInstruments = []Track{
+	{Name: "BD", Note: 36}, // bass drum
+	{Name: "SD", Note: 38}, // snare drum
+	{Name: "LT", Note: 45}, // low tom
+	{Name: "MT", Note: 47}, // mid tom
+	{Name: "HT", Note: 50}, // high tom
+	{Name: "RS", Note: 37}, // rim shot
+	{Name: "HC", Note: 39}, // hand clap
+	{Name: "CH", Note: 42}, // closed hi-hat
+	{Name: "OH", Note: 46}, // open hi-hat
+	{Name: "CC", Note: 49}, // crash cymbal
+	{Name: "RC", Note: 51}, // ride cymbal
+}
+
+// tr08Instruments are the instruments of the TR-08, whose HC is the high
+// conga rather than the hand clap
+var tr08Instruments = []Track{
+	{Name: "BD", Note: 36}, // bass drum
+	{Name: "SD", Note: 38}, // snare drum
+	{Name: "LT", Note: 45}, // low tom
+	{Name: "MT", Note: 47}, // mid tom
+	{Name: "HT", Note: 50}, // high tom
+	{Name: "RS", Note: 37}, // rim shot
+	{Name: "CP", Note: 39}, // hand clap
+	{Name: "CH", Note: 42}, // closed hi-hat
+	{Name: "OH", Note: 46}, // open hi-hat
+	{Name: "CY", Note: 49}, // cymbal
+	{Name: "CB", Note: 56}, // cowbell
+	{Name: "LC", Note: 64}, // low conga
+	{Name: "MC", Note: 63}, // mid conga
+	{Name: "HC", Note: 62}, // high conga
+	{Name: "CL", Note: 75}, // claves
+	{Name: "MA", Note: 70}, // maracas
+}
+
+// ParseTR parses a Roland TR-8S or TR-08 pattern export (.prm or .tr8s)
+// into a drum pattern. Exports are text, a KEY=VALUE pair per line, with a
+// row of steps for each instrument used:
+//
+//	; TR-8S pattern export
+//	MODEL=TR-8S
+//	NAME=HOUSE 01
+//	TEMPO=124.0
+//	LENGTH=16
+//	BD=x...x...x...x...
+//	HC=....X.......X...
+//	CH=..x...x...x.g.x.
+//
+// Steps are x (hit), X (accent), g (ghost note), f (flammed hit), or . (no
+// hit); spaces and "|" between steps are ignored, and a repeated row
+// continues the previous one. MODEL selects the instrument names, TR-8S
+// (the default) or TR-08; LENGTH (the last step) defaults to the longest
+// row, and steps beyond it are dropped.
+func ParseTR(data []byte) (*Pattern, error) {
+	p := &Pattern{Name: "TR Pattern", Tempo: 120}
+	instruments := trInstruments
+	length := 0
+	rows := map[string][]Step{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "MODEL":
+			if len(rows) > 0 {
+				return nil, fmt.Errorf("line %d: MODEL must come before the instrument rows", lineNo)
+			}
+			switch strings.ToUpper(strings.ReplaceAll(value, "-", "")) {
+			case "TR8S":
+				instruments = trInstruments
+			case "TR08":
+				instruments = tr08Instruments
+			default:
+				return nil, fmt.Errorf("line %d: unknown model %q (use TR-8S or TR-08)", lineNo, value)
+			}
+		case "NAME":
+			p.Name = value
+		case "TEMPO", "BPM":
+			tempo, err := strconv.ParseFloat(value, 64)
+			if err != nil || tempo <= 0 {
+				return nil, fmt.Errorf("line %d: invalid tempo %q", lineNo, value)
+			}
+			p.Tempo = tempo
+		case "LENGTH":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("line %d: invalid length %q", lineNo, value)
+			}
+			length = n
+		default:
+			if !slices.ContainsFunc(instruments, func(t Track) bool { return t.Name == key }) {
+				return nil, fmt.Errorf("line %d: unknown instrument %q", lineNo, key)
+			}
+			steps, err := parseTRSteps(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			rows[key] = append(rows[key], steps...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no instrument rows found")
+	}
+
+	if length == 0 {
+		for _, steps := range rows {
+			length = max(length, len(steps))
+		}
+	}
+	for _, inst := range instruments {
+		steps, ok := rows[inst.Name]
+		if !ok {
+			continue
+		}
+		track := Track{Name: inst.Name, Note: inst.Note, Steps: make([]Step, length)}
+		for i, s := range steps[:min(len(steps), length)] {
+			if s.Gate {
+				s.Note = inst.Note
+				track.Steps[i] = s
+			}
+		}
+		p.Tracks = append(p.Tracks, track)
+	}
+	p.Length = length
+	return p, nil
+}
+
+// parseTRSteps parses a row of TR steps
+func parseTRSteps(row string) ([]Step, error) {
+	var steps []Step
+	for _, c := range row {
+		switch c {
+		case ' ', '\t', '|':
+		case '.', '-':
+			steps = append(steps, Step{})
+		case 'x':
+			steps = append(steps, Step{Gate: true, Velocity: 100})
+		case 'X':
+			steps = append(steps, Step{Gate: true, Velocity: 127, Accent: true})
+		case 'g':
+			steps = append(steps, Step{Gate: true, Velocity: GhostVelocity, Ghost: true})
+		case 'f':
+			steps = append(steps, Step{Gate: true, Velocity: 100, Flam: trFlam})
+		default:
+			return nil, fmt.Errorf("unknown step %q (use x, X, g, f, or .)", c)
+		}
+	}
+	return steps, nil
+}
+
+// IsTRPattern reports whether text data looks like a TR pattern export: a
+// KEY=VALUE line naming a TR instrument or the model
+func IsTRPattern(data []byte) bool {
+	for line := range strings.Lines(string(data)) {
+		key, _, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		named := func(t Track) bool { return t.Name == key }
+		if key == "MODEL" || slices.ContainsFunc(trInstruments, named) || slices.ContainsFunc(tr08Instruments, named) {
+			return true
+		}
+	}
+	return false
+}
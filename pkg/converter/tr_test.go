@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"testing"
+)
+
+const tr8sExport = `; TR-8S pattern export
+MODEL=TR-8S
+NAME=HOUSE 01
+TEMPO=124.0
+LENGTH=16
+BD=x...x...x...x...
+HC=....X.......X...
+CH=..x...x...x.g.f.|xxxx
+`
+
+func TestParseTR(t *testing.T) {
+	p, err := ParseTR([]byte(tr8sExport))
+	if err != nil {
+		t.Fatalf("ParseTR() error = %v", err)
+	}
+	if p.Name != "HOUSE 01" || p.Tempo != 124 || p.Length != 16 || !p.IsDrum() {
+		t.Errorf("got %q at %g BPM, length %d", p.Name, p.Tempo, p.Length)
+	}
+	if len(p.Tracks) != 3 || p.Tracks[1].Name != "HC" || p.Tracks[1].Note != 39 {
+		t.Fatalf("tracks = %+v", p.Tracks)
+	}
+	for i, s := range p.Tracks[0].Steps {
+		if s.Gate != (i%4 == 0) || (s.Gate && s.Note != 36) {
+			t.Errorf("BD step %d = %+v", i+1, s)
+		}
+	}
+	if clap := p.Tracks[1].Steps[4]; !clap.Accent || clap.Velocity != 127 {
+		t.Errorf("HC step 5 = %+v, want accent", clap)
+	}
+	hats := p.Tracks[2].Steps
+	if len(hats) != 16 || !hats[12].Ghost || hats[14].Flam != trFlam {
+		t.Errorf("CH steps = %+v, want a ghost on 13 and a flam on 15, cut at 16", hats)
+	}
+
+	// The TR-08's HC is the high conga
+	p, err = ParseTR([]byte("MODEL=TR-08\nCP=x...\nHC=..x.\n"))
+	if err != nil {
+		t.Fatalf("ParseTR(TR-08) error = %v", err)
+	}
+	if p.Length != 4 || p.Tracks[0].Note != 39 || p.Tracks[1].Note != 62 {
+		t.Errorf("TR-08 tracks = %+v", p.Tracks)
+	}
+}
+
+func TestParseTRErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no rows", "NAME=Empty\n"},
+		{"no equals", "BD x...x...\n"},
+		{"unknown instrument", "ZZ=x...\n"},
+		{"TR-08 instrument on TR-8S", "CB=x...\n"},
+		{"unknown step", "BD=x..?\n"},
+		{"bad tempo", "TEMPO=fast\nBD=x\n"},
+		{"bad length", "LENGTH=0\nBD=x\n"},
+		{"unknown model", "MODEL=TR-909\nBD=x\n"},
+		{"late model", "BD=x\nMODEL=TR-08\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTR([]byte(tt.data)); err == nil {
+				t.Error("ParseTR() expected error")
+			}
+		})
+	}
+
+	if !IsTRPattern([]byte(tr8sExport)) || IsTRPattern([]byte("note C D E\naccent A . .\n")) {
+		t.Error("IsTRPattern() misread TR export or 303 notation")
+	}
+	if got := DetectFormat("groove.PRM"); got != FormatTR {
+		t.Errorf("DetectFormat(.PRM) = %s", got)
+	}
+}
@@ -1,6 +1,14 @@
 // Package converter provides conversion between MIDI and Behringer SynthTribe formats
 package converter
 
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
 // Step represents a single step in a pattern
 type Step struct {
 	Note     uint8 // MIDI note number (0-127)
@@ -9,6 +17,46 @@ type Step struct {
 	Gate     bool  // Note on/off
 	Tie      bool  // Tie to next step
 	Velocity uint8 // Velocity (0-127)
+
+	// Drum machine step settings, which other devices ignore
+	Flam        uint8 `json:",omitempty"` // Flam spacing of a drum hit (0 = none, up to 127 = half a step)
+	Probability uint8 `json:",omitempty"` // Chance the step plays, in percent (0 = always)
+	Ghost       bool  `json:",omitempty"` // Soft (ghost) hit, below a plain hit as an accent is above it
+
+	// TD-3-MO accent levels of accented steps, which other devices play as
+	// plain accents
+	SubAccent   bool `json:",omitempty"` // Softer second accent level
+	MutedAccent bool `json:",omitempty"` // Accent sweep on the filter without the volume boost
+}
+
+// Track is one part of a multi-track pattern: a drum voice struck on the
+// steps whose Gate is set, or a melodic line whose steps carry their own
+// notes, as in a polyphonic pattern
+type Track struct {
+	Name string // e.g. "BD" or "Bass"
+	// Note is the MIDI note every hit of a drum track plays; melodic
+	// tracks leave it 0
+	Note uint8
+	// Channel is the MIDI channel (1-16) the track plays on; 0 means
+	// channel 10 for drum tracks and the conversion's channel otherwise
+	Channel int `json:",omitempty"`
+	// Steps of the track: for drum tracks, Gate marks a hit, and Accent
+	// and Velocity are as for notes
+	Steps []Step
+}
+
+// IsDrum reports whether the track is a drum voice rather than a line
+func (t *Track) IsDrum() bool {
+	return t.Note != 0
+}
+
+// midiChannel returns the 0-based MIDI channel of the track, or fallback
+// when it has none
+func (t *Track) midiChannel(fallback uint8) uint8 {
+	if t.Channel < 1 || t.Channel > 16 {
+		return fallback
+	}
+	return uint8(t.Channel - 1)
 }
 
 // Pattern represents a sequence pattern
@@ -18,6 +66,79 @@ type Pattern struct {
 	Length   int    // Number of steps (typically 16)
 	Tempo    float64
 	DeviceID uint8
+	// Tracks holds the parts of multi-track patterns, such as the voices of
+	// a drum machine or the lines of a polyphonic pattern, which have no
+	// Steps of their own; single-track patterns have none
+	Tracks []Track `json:",omitempty"`
+}
+
+// IsDrum reports whether the pattern is a drum pattern, made of drum tracks
+// only
+func (p *Pattern) IsDrum() bool {
+	return len(p.Tracks) > 0 && !slices.ContainsFunc(p.Tracks, func(t Track) bool { return !t.IsDrum() })
+}
+
+// StepCount returns the number of steps of the pattern: its Steps, or for a
+// multi-track pattern its longest track
+func (p *Pattern) StepCount() int {
+	n := len(p.Steps)
+	for _, t := range p.Tracks {
+		n = max(n, len(t.Steps))
+	}
+	return n
+}
+
+// SingleTrack turns a multi-track pattern into the single line played by
+// devices like the TD-3: its first melodic track becomes the pattern's
+// Steps, and every track is dropped. It returns the names of the other
+// tracks dropped; patterns with Steps or without a melodic track are left
+// unchanged.
+func (p *Pattern) SingleTrack() (dropped []string) {
+	line := slices.IndexFunc(p.Tracks, func(t Track) bool { return !t.IsDrum() })
+	if len(p.Steps) > 0 || line < 0 {
+		return nil
+	}
+	for i, t := range p.Tracks {
+		if i != line {
+			dropped = append(dropped, t.Name)
+		}
+	}
+	p.Steps = p.Tracks[line].Steps
+	p.Length = len(p.Steps)
+	p.Tracks = nil
+	return dropped
+}
+
+// UnmarshalJSON decodes a pattern, reading the drum lanes of patterns saved
+// before tracks replaced them as tracks
+func (p *Pattern) UnmarshalJSON(data []byte) error {
+	type pattern Pattern
+	var v struct {
+		pattern
+		Lanes []Track
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = Pattern(v.pattern)
+	if p.Tracks == nil {
+		p.Tracks = v.Lanes
+	}
+	return nil
+}
+
+// Clone returns a copy of the pattern that shares no steps with it
+func (p *Pattern) Clone() *Pattern {
+	c := *p
+	c.Steps = append([]Step(nil), p.Steps...)
+	if p.Tracks != nil {
+		c.Tracks = make([]Track, len(p.Tracks))
+		for i, t := range p.Tracks {
+			c.Tracks[i] = t
+			c.Tracks[i].Steps = append([]Step(nil), t.Steps...)
+		}
+	}
+	return &c
 }
 
 // ConversionResult holds the result of a conversion
@@ -38,14 +159,104 @@ type Device interface {
 	GenerateSyx(pattern *Pattern) ([]byte, error)
 }
 
-// Converter handles format conversions
+// Capabilities describes the limits of a device's pattern format
+type Capabilities struct {
+	MaxSteps int   // Maximum steps per pattern (0 = unlimited)
+	MinNote  uint8 // Lowest representable MIDI note
+	MaxNote  uint8 // Highest representable MIDI note
+	Defaults Defaults // Conversion options suited to the device
+
+	// Lossy lists the step fields ("accent", "slide", "tie") the device
+	// does not store as they are: exports drop or share them, and a dropped
+	// tie becomes a slide into a repeated note
+	Lossy []string
+}
+
+// CapabilityProvider is implemented by devices that declare their limits
+type CapabilityProvider interface {
+	Capabilities() Capabilities
+}
+
+// DeviceCapabilities returns the limits of a device, falling back to the
+// full MIDI range for devices (or a nil device) that declare none
+func DeviceCapabilities(dev Device) Capabilities {
+	if p, ok := dev.(CapabilityProvider); ok {
+		return p.Capabilities()
+	}
+	return Capabilities{MinNote: 0, MaxNote: 127}
+}
+
+// SysExDevice is implemented by devices that describe their SysEx message
+// layout, so checksums can be validated generically
+type SysExDevice interface {
+	SysExLayout() sysex.Layout
+}
+
+// SlotDevice is implemented by devices with addressable pattern memory, so
+// patterns can be written to a specific slot over MIDI
+type SlotDevice interface {
+	// Slots returns the number of pattern memory slots
+	Slots() int
+	// SlotName returns the front-panel label of a slot index
+	SlotName(slot int) string
+	// ParseSlot parses a front-panel slot label into a slot index
+	ParseSlot(label string) (int, error)
+	// GenerateSyxSlot generates SysEx that stores the pattern in a slot
+	GenerateSyxSlot(pattern *Pattern, slot int) ([]byte, error)
+}
+
+// DrumDevice is implemented by drum machines, whose patterns are lanes of
+// drum hits rather than a line of notes
+type DrumDevice interface {
+	// DrumLanes returns the device's voices in order, each with the MIDI
+	// drum note it maps to and no steps
+	DrumLanes() []Track
+}
+
+// DrumChannel is the MIDI channel of drum lanes (channel 10, zero-based)
+const DrumChannel = 9
+
+// AccentLayerDevice is implemented by devices whose accents have levels
+// (sub-accents and muted accents), which MIDI parsing then reads back
+type AccentLayerDevice interface {
+	// AccentLayers reports whether the device stores accent levels
+	AccentLayers() bool
+}
+
+// LaneTrackDevice is implemented by drum devices whose MIDI files hold each
+// voice in a track of its own
+type LaneTrackDevice interface {
+	// LaneTracks reports whether each lane is written to its own track
+	LaneTracks() bool
+}
+
+// PatternRequester is implemented by slot devices that dump a memory slot
+// when sent a request message
+type PatternRequester interface {
+	SlotDevice
+	// PatternRequest generates the SysEx request for a slot's pattern
+	PatternRequest(slot int, deviceID uint8) ([]byte, error)
+	// IsPatternReply reports whether msg is the dump answering such a request
+	IsPatternReply(msg []byte, slot int, deviceID uint8) bool
+}
+
+// Converter handles format conversions. Once set up with SetDevice,
+// SetOptions, and SetLimits, a Converter is safe for concurrent use: parsing
+// and generating only read its configuration, and each conversion keeps its
+// state in local values. Only Warnings reports on earlier calls; goroutines
+// sharing a Converter take their warnings from Generate or GenerateAll.
 type Converter struct {
 	device Device
+	opts   ConvertOptions
+	limits Limits
+
+	mu       sync.Mutex // guards warnings
+	warnings []Violation
 }
 
 // New creates a new Converter with the specified device
 func New(device Device) *Converter {
-	return &Converter{device: device}
+	return &Converter{device: device, opts: DefaultOptions()}
 }
 
 // GetDevice returns the current device
@@ -58,3 +269,29 @@ func (c *Converter) SetDevice(device Device) {
 	c.device = device
 }
 
+
+// Options returns the current conversion options
+func (c *Converter) Options() ConvertOptions {
+	return c.opts
+}
+
+// SetOptions sets the conversion options
+func (c *Converter) SetOptions(opts ConvertOptions) {
+	c.opts = opts
+}
+
+// Warnings returns the warnings produced by the most recent conversion. On a
+// Converter shared between goroutines "most recent" is ambiguous; use the
+// warnings returned by Generate or GenerateAll instead.
+func (c *Converter) Warnings() []Violation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.warnings
+}
+
+// setWarnings keeps the warnings of a conversion for Warnings
+func (c *Converter) setWarnings(warnings []Violation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.warnings = warnings
+}
@@ -0,0 +1,151 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Severity classifies a validation violation
+type Severity int
+
+const (
+	// SeverityWarning marks a combination that devices ignore or reinterpret
+	SeverityWarning Severity = iota
+	// SeverityError marks a pattern that cannot be represented on the device
+	SeverityError
+)
+
+// String returns the severity name
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Violation describes a single constraint a pattern breaks
+type Violation struct {
+	Step     int // Step index, or -1 for pattern-level violations
+	Field    string
+	Message  string
+	Severity Severity
+}
+
+// String returns a human-readable description of the violation
+func (v Violation) String() string {
+	if v.Step < 0 {
+		return fmt.Sprintf("%s: %s", v.Field, v.Message)
+	}
+	return fmt.Sprintf("step %d %s: %s", v.Step+1, v.Field, v.Message)
+}
+
+// ValidationError reports the violations that fail a conversion: those of
+// error severity, or every violation in strict mode
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return "invalid pattern: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the pattern against the device's constraints and returns
+// all violations. A nil device checks only generic MIDI limits.
+func (p *Pattern) Validate(dev Device) []Violation {
+	var violations []Violation
+	caps := DeviceCapabilities(dev)
+
+	if caps.MaxSteps > 0 && p.StepCount() > caps.MaxSteps {
+		violations = append(violations, Violation{
+			Step:     -1,
+			Field:    "length",
+			Message:  fmt.Sprintf("%d steps exceeds device maximum of %d", p.StepCount(), caps.MaxSteps),
+			Severity: SeverityError,
+		})
+	}
+	if _, drums := dev.(DrumDevice); dev != nil && !drums && slices.ContainsFunc(p.Tracks, func(t Track) bool { return t.IsDrum() }) {
+		violations = append(violations, Violation{
+			Step:     -1,
+			Field:    "tracks",
+			Message:  fmt.Sprintf("drum pattern cannot be played by %s", dev.Name()),
+			Severity: SeverityError,
+		})
+	}
+
+	violations = append(violations, validateLine(p.Steps, caps, "")...)
+	for _, track := range p.Tracks {
+		if track.Channel < 0 || track.Channel > 16 {
+			violations = append(violations, Violation{Step: -1, Field: track.Name + " channel", Message: fmt.Sprintf("%d is outside 1-16", track.Channel), Severity: SeverityError})
+		}
+		if !track.IsDrum() {
+			violations = append(violations, validateLine(track.Steps, caps, track.Name+" ")...)
+			continue
+		}
+		for i, step := range track.Steps {
+			if step.Flam > 127 {
+				violations = append(violations, Violation{Step: i, Field: track.Name + " flam", Message: fmt.Sprintf("%d is outside 0-127", step.Flam), Severity: SeverityError})
+			}
+			if step.Probability > 100 {
+				violations = append(violations, Violation{Step: i, Field: track.Name + " probability", Message: fmt.Sprintf("%d%% is over 100%%", step.Probability), Severity: SeverityError})
+			}
+		}
+	}
+
+	return violations
+}
+
+// validateLine checks the steps of a line of notes against the device's
+// limits, naming fields with the given prefix
+func validateLine(steps []Step, caps Capabilities, prefix string) []Violation {
+	var violations []Violation
+	for i, step := range steps {
+		if step.Velocity > 127 {
+			violations = append(violations, Violation{
+				Step:     i,
+				Field:    prefix + "velocity",
+				Message:  fmt.Sprintf("%d is outside 0-127", step.Velocity),
+				Severity: SeverityError,
+			})
+		}
+
+		if !step.Gate {
+			if step.Tie {
+				violations = append(violations, Violation{Step: i, Field: prefix + "tie", Message: "tie on a rest", Severity: SeverityWarning})
+			}
+			continue
+		}
+
+		if step.Note < caps.MinNote || step.Note > caps.MaxNote {
+			violations = append(violations, Violation{
+				Step:     i,
+				Field:    prefix + "note",
+				Message:  fmt.Sprintf("%d is outside device range %d-%d", step.Note, caps.MinNote, caps.MaxNote),
+				Severity: SeverityError,
+			})
+		}
+		if step.Tie && i == 0 {
+			violations = append(violations, Violation{Step: i, Field: prefix + "tie", Message: "first step cannot be tied", Severity: SeverityWarning})
+		}
+	}
+	return violations
+}
+
+// validate returns a ValidationError if the pattern has error-severity violations
+func validate(p *Pattern, dev Device) error {
+	var errs []Violation
+	for _, v := range p.Validate(dev) {
+		if v.Severity == SeverityError {
+			errs = append(errs, v)
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Violations: errs}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// verifyRoundtrip parses data generated from pattern back with the device
+// and fails with the differences, so an encoder bug shows up at conversion
+// time instead of on the hardware. Tempo and length, which device patterns
+// do not all store, are not compared, nor are the fields the device
+// declares lossy.
+func (c *Converter) verifyRoundtrip(pattern *Pattern, data []byte, format Format) error {
+	back, err := c.parse(data, format)
+	if err != nil {
+		return fmt.Errorf("round-trip check failed: generated %s does not parse: %w", format, err)
+	}
+	var diffs []string
+	for _, d := range SlotDiff(c.device, pattern, back) {
+		if d.Step < 0 {
+			continue
+		}
+		diffs = append(diffs, d.String())
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("round-trip check failed: generated %s reads back differently: %s", format, strings.Join(diffs, "; "))
+	}
+	return nil
+}
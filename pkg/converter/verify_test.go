@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// stepDevice stores a byte of note and a byte of flags per step; tieBug
+// writes each tie on the step before it, as a swapped nibble would
+type stepDevice struct {
+	mockDevice
+	tieBug bool
+	lossy  []string
+}
+
+func (d *stepDevice) Capabilities() Capabilities {
+	return Capabilities{MinNote: 0, MaxNote: 127, Lossy: d.lossy}
+}
+
+func (d *stepDevice) GenerateSeq(pattern *Pattern) ([]byte, error) {
+	data := make([]byte, 2*len(pattern.Steps))
+	for i, s := range pattern.Steps {
+		data[2*i] = s.Note
+		for bit, set := range []bool{s.Gate, s.Accent, s.Slide, s.Tie} {
+			if set && !(bit == 1 && slices.Contains(d.lossy, "accent")) {
+				data[2*i+1] |= 1 << bit
+			}
+		}
+		if d.tieBug && s.Tie {
+			data[2*i+1] &^= 1 << 3
+			data[2*i-1] |= 1 << 3
+		}
+	}
+	return data, nil
+}
+
+func (d *stepDevice) ParseSeq(data []byte) (*Pattern, error) {
+	p := &Pattern{Length: len(data) / 2}
+	for i := 0; i+1 < len(data); i += 2 {
+		f := data[i+1]
+		p.Steps = append(p.Steps, Step{Note: data[i], Gate: f&1 != 0, Accent: f&2 != 0, Slide: f&4 != 0, Tie: f&8 != 0})
+	}
+	return p, nil
+}
+
+func TestVerifyRoundtrip(t *testing.T) {
+	pattern := &Pattern{Tempo: 128, Length: 4, Steps: []Step{
+		{Note: 36, Gate: true, Accent: true, Velocity: 127},
+		{Note: 36, Gate: true, Tie: true, Velocity: 100},
+		{},
+		{Note: 48, Gate: true, Slide: true, Velocity: 100},
+	}}
+	tests := []struct {
+		name    string
+		device  *stepDevice
+		verify  bool
+		wantErr string
+	}{
+		{"faithful encoder", &stepDevice{}, true, ""},
+		{"encoder bug", &stepDevice{tieBug: true}, true, "step 1 tie: false -> true; step 2 tie: true -> false"},
+		{"encoder bug unchecked", &stepDevice{tieBug: true}, false, ""},
+		{"accents the device drops", &stepDevice{lossy: []string{"accent"}}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.device)
+			opts := DefaultOptions()
+			opts.VerifyRoundtrip = tt.verify
+			c.SetOptions(opts)
+			_, _, err := c.Generate(pattern, FormatSeq)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Generate() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Generate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+)
+
+// Commands accepted from automation integrations
+const (
+	CommandBackup = "backup"
+	CommandPush   = "push"
+)
+
+// PushRequest asks the daemon to write a library pattern to a device slot.
+// Without a pattern ID, a random pattern matching Tag and MinRating is
+// chosen ("pattern of the day").
+type PushRequest struct {
+	Pattern   string `json:"pattern"`
+	Tag       string `json:"tag"`
+	MinRating int    `json:"min_rating"`
+	Slot      string `json:"slot"`
+	Port      string `json:"port"`
+}
+
+// HandleCommand runs a named command with an optional JSON payload
+func (d *Daemon) HandleCommand(name string, payload []byte) error {
+	switch name {
+	case CommandBackup:
+		// An optional payload names the port to back up; default is all
+		port := strings.TrimSpace(string(payload))
+		ran := false
+		var errs []error
+		for _, b := range d.backups {
+			if port == "" || strings.EqualFold(port, b.job.Port) {
+				if err := b.trigger(time.Now()); err != nil {
+					errs = append(errs, err)
+				}
+				ran = true
+			}
+		}
+		if !ran {
+			return fmt.Errorf("no backup configured for port %q", port)
+		}
+		return errors.Join(errs...)
+
+	case CommandPush:
+		var req PushRequest
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &req); err != nil {
+				return fmt.Errorf("invalid push request: %w", err)
+			}
+		}
+		if req.Port == "" {
+			req.Port = d.cfg.MQTT.Port
+		}
+		err := d.push(req)
+		if err != nil {
+			d.emit(Event{Type: EventPushFailed, Pattern: req.Pattern, Port: req.Port, Slot: req.Slot, Error: err.Error()})
+		}
+		return err
+
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// push writes a library pattern to a device slot
+func (d *Daemon) push(req PushRequest) error {
+	if d.lib == nil {
+		return errors.New("pattern library unavailable")
+	}
+	dev, ok := d.dev.(converter.SlotDevice)
+	if !ok {
+		return fmt.Errorf("%s does not support writing pattern slots", d.dev.Name())
+	}
+	if req.Port == "" {
+		return errors.New("no MIDI port given")
+	}
+
+	slot := 0
+	if req.Slot != "" {
+		var err error
+		if slot, err = dev.ParseSlot(req.Slot); err != nil {
+			return err
+		}
+	}
+
+	entry, err := pickPattern(d.lib, req)
+	if err != nil {
+		return err
+	}
+	req.Pattern = entry.ID
+	_, data, err := d.lib.Data(entry.ID)
+	if err != nil {
+		return err
+	}
+
+	conv := converter.New(d.dev)
+	pattern, err := conv.ParsePattern(data, converter.Format(entry.Format))
+	if err != nil {
+		return err
+	}
+	msg, err := conv.PatternToSlotSyx(pattern, slot)
+	if err != nil {
+		return err
+	}
+
+	out, err := mididevice.OpenOutput(req.Port)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+	if err := out.Send(msg); err != nil {
+		return err
+	}
+
+	slotName := dev.SlotName(slot)
+	if _, err := d.lib.RecordSlot(entry.ID, out.Name(), slotName); err != nil {
+		return err
+	}
+	d.emit(Event{Type: EventPushed, Pattern: entry.ID, Port: out.Name(), Slot: slotName})
+	return nil
+}
+
+// pickPattern resolves the requested pattern, or picks a random one matching
+// the request's filter
+func pickPattern(lib *library.Library, req PushRequest) (*library.Entry, error) {
+	if req.Pattern != "" {
+		return lib.Get(req.Pattern)
+	}
+	entries, err := lib.List(library.Filter{Tag: req.Tag, MinRating: req.MinRating})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no library patterns match the request")
+	}
+	return &entries[rand.Intn(len(entries))], nil
+}
@@ -0,0 +1,169 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/api"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/schedule"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the daemon configuration file
+type Config struct {
+	// Device is the target device for conversions (default devices.DefaultID)
+	Device string `yaml:"device"`
+	// MIDIBackend selects the MIDI backend used to reach hardware (default: automatic)
+	MIDIBackend string `yaml:"midi_backend"`
+
+	Server  ServerConfig   `yaml:"server"`
+	Watch   []WatchConfig  `yaml:"watch"`
+	Backups []BackupConfig `yaml:"backups"`
+	MQTT    MQTTConfig     `yaml:"mqtt"`
+}
+
+// ServerConfig configures the embedded API server
+type ServerConfig struct {
+	// Enabled turns the API server on (default true)
+	Enabled *bool `yaml:"enabled"`
+	// Port is the listen port (default 8080)
+	Port int `yaml:"port"`
+	// Library is the pattern library directory (default: user config dir)
+	Library string `yaml:"library"`
+	// CORS is the cross-origin policy for browser clients (default: any
+	// origin, without credentials)
+	CORS api.CORSConfig `yaml:"cors"`
+}
+
+// WatchConfig configures a watch folder
+type WatchConfig struct {
+	// Dir is the folder to watch
+	Dir string `yaml:"dir"`
+	// To lists the formats to convert new files to (seq, syx, midi)
+	To []string `yaml:"to"`
+	// Output is where converted files are written (default: Dir)
+	Output string `yaml:"output"`
+	// Interval is how long a file must be unchanged before it is converted,
+	// and the polling interval where file system events are unavailable
+	// (default 2s)
+	Interval time.Duration `yaml:"interval"`
+	// Existing also converts files already present at startup
+	Existing bool `yaml:"existing"`
+	// PreserveMTime gives converted files the modification time of the input
+	PreserveMTime bool `yaml:"preserve_mtime"`
+	// SkipUpToDate leaves outputs alone that are already newer than the
+	// input, so restarts with Existing don't redo a whole library
+	SkipUpToDate bool `yaml:"skip_up_to_date"`
+}
+
+// BackupConfig configures a scheduled hardware backup
+type BackupConfig struct {
+	// Port is the MIDI port of the device (or a unique part of its name)
+	Port string `yaml:"port"`
+	// Dir is where bank files are written
+	Dir string `yaml:"dir"`
+	// Schedule is a cron expression, e.g. "0 3 * * *" or "@daily"
+	Schedule string `yaml:"schedule"`
+	// DeviceID is the SysEx device ID of the unit (default 0)
+	DeviceID uint8 `yaml:"device_id"`
+	// Keep limits the number of backups retained (0 = unlimited)
+	Keep int `yaml:"keep"`
+	// MaxAge deletes backups older than this (0 = never)
+	MaxAge time.Duration `yaml:"max_age"`
+	// Timeout is the per-pattern reply timeout (default 2s)
+	Timeout time.Duration `yaml:"timeout"`
+	// Retries re-requests a pattern after a timeout or damaged dump
+	Retries int `yaml:"retries"`
+	// Verify reads every slot twice and fails if the dumps differ
+	Verify bool `yaml:"verify"`
+}
+
+// LoadConfig reads and validates a YAML daemon configuration
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ServerEnabled reports whether the API server should run
+func (c *Config) ServerEnabled() bool {
+	return c.Server.Enabled == nil || *c.Server.Enabled
+}
+
+func (c *Config) validate() error {
+	if c.Device == "" {
+		c.Device = devices.DefaultID
+	}
+	if err := devices.Validate(c.Device); err != nil {
+		return err
+	}
+	if c.Server.Port == 0 {
+		c.Server.Port = 8080
+	}
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
+		return fmt.Errorf("server.port %d out of range", c.Server.Port)
+	}
+	if err := c.Server.CORS.WithDefaults().Validate(); err != nil {
+		return fmt.Errorf("server.cors: %w", err)
+	}
+
+	for i := range c.Watch {
+		w := &c.Watch[i]
+		if w.Dir == "" {
+			return fmt.Errorf("watch[%d]: dir is required", i)
+		}
+		if len(w.To) == 0 {
+			return fmt.Errorf("watch[%d]: at least one target format is required in 'to'", i)
+		}
+		for _, f := range w.To {
+			if _, err := parseFormat(f); err != nil {
+				return fmt.Errorf("watch[%d]: %w", i, err)
+			}
+		}
+		if w.Output == "" {
+			w.Output = w.Dir
+		}
+	}
+
+	for i, b := range c.Backups {
+		if b.Port == "" || b.Dir == "" || b.Schedule == "" {
+			return fmt.Errorf("backups[%d]: port, dir, and schedule are required", i)
+		}
+		sched, err := schedule.Parse(b.Schedule)
+		if err != nil {
+			return fmt.Errorf("backups[%d]: %w", i, err)
+		}
+		if _, err := sched.Next(time.Now()); err != nil {
+			return fmt.Errorf("backups[%d]: %w", i, err)
+		}
+		if b.DeviceID > 127 {
+			return fmt.Errorf("backups[%d]: device_id must be between 0 and 127", i)
+		}
+		if b.Retries < 0 {
+			return fmt.Errorf("backups[%d]: retries must not be negative", i)
+		}
+	}
+
+	if c.MQTT.Broker != "" && !strings.Contains(c.MQTT.Broker, "://") {
+		return fmt.Errorf("mqtt.broker %q must be a URL such as tcp://host:1883", c.MQTT.Broker)
+	}
+
+	if !c.ServerEnabled() && len(c.Watch) == 0 && len(c.Backups) == 0 && c.MQTT.Broker == "" {
+		return errors.New("nothing to do: the server is disabled and no watch folders, backups, or MQTT broker are configured")
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+// Package daemon runs the API server, watch-folder conversions, and scheduled
+// hardware backups in a single long-running process
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/api"
+	"github.com/james-see/synthtribe2midi/pkg/backup"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/schedule"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+	"github.com/james-see/synthtribe2midi/pkg/watch"
+)
+
+// StatusPath is the daemon health/status endpoint
+const StatusPath = "/api/v1/daemon/status"
+
+// watchStallTimeout is the shortest time without a scan after which a watch
+// folder makes the daemon unready
+const watchStallTimeout = 30 * time.Second
+
+// Daemon runs the configured services
+type Daemon struct {
+	cfg      *Config
+	started  time.Time
+	dev      converter.Device
+	lib      *library.Library
+	watchers []*watch.Watcher
+	backups  []*scheduledBackup
+
+	sinksMu sync.Mutex
+	sinks   []func(Event)
+}
+
+// scheduledBackup runs a backup job on a schedule and records the outcome
+type scheduledBackup struct {
+	job      *backup.Job
+	schedule *schedule.Schedule
+	emit     func(Event)
+
+	running sync.Mutex // held for the whole job; runs share the MIDI port and folder
+
+	mu     sync.Mutex
+	status BackupStatus
+}
+
+// BackupStatus reports the state of a scheduled backup
+type BackupStatus struct {
+	Port      string    `json:"port"`
+	Schedule  string    `json:"schedule"`
+	NextRun   time.Time `json:"next_run"`
+	LastRun   time.Time `json:"last_run,omitzero"`
+	LastFile  string    `json:"last_file,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Status reports the state of the daemon, its watch folders, and backups
+type Status struct {
+	Status  string         `json:"status"`
+	Uptime  string         `json:"uptime"`
+	Server  bool           `json:"server"`
+	Watches []watch.Stats  `json:"watches"`
+	Backups []BackupStatus `json:"backups"`
+}
+
+// New creates a daemon from a validated configuration
+func New(cfg *Config) *Daemon {
+	return &Daemon{cfg: cfg}
+}
+
+// Run starts all services and blocks until ctx is cancelled or a service fails
+func (d *Daemon) Run(ctx context.Context) error {
+	d.started = time.Now()
+	if d.cfg.MIDIBackend != "" {
+		if err := mididevice.Use(d.cfg.MIDIBackend); err != nil {
+			return err
+		}
+	}
+	dev, err := devices.New(d.cfg.Device)
+	if err != nil {
+		return err
+	}
+	d.dev = dev
+	if d.cfg.ServerEnabled() || d.cfg.MQTT.Broker != "" {
+		d.lib = api.OpenLibrary(d.cfg.Server.Library)
+	}
+
+	for _, wc := range d.cfg.Watch {
+		if err := os.MkdirAll(wc.Output, 0755); err != nil {
+			return fmt.Errorf("failed to create output folder: %w", err)
+		}
+		d.watchers = append(d.watchers, newWatcher(wc, dev, d.emit))
+	}
+
+	if len(d.cfg.Backups) > 0 {
+		requester, ok := dev.(converter.PatternRequester)
+		if !ok {
+			return fmt.Errorf("%s does not support pattern dump requests", dev.Name())
+		}
+		for _, bc := range d.cfg.Backups {
+			sched, err := schedule.Parse(bc.Schedule)
+			if err != nil {
+				return err
+			}
+			next, err := sched.Next(time.Now())
+			if err != nil {
+				return err
+			}
+			d.backups = append(d.backups, &scheduledBackup{
+				schedule: sched,
+				emit:     d.emit,
+				job: &backup.Job{
+					Port:      bc.Port,
+					Dir:       bc.Dir,
+					Prefix:    d.cfg.Device,
+					Device:    requester,
+					Options:   backup.Options{DeviceID: bc.DeviceID, Timeout: bc.Timeout, Retries: bc.Retries, Verify: bc.Verify},
+					Retention: backup.Retention{Keep: bc.Keep, MaxAge: bc.MaxAge},
+				},
+				status: BackupStatus{Port: bc.Port, Schedule: bc.Schedule, NextRun: next},
+			})
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if d.cfg.MQTT.Broker != "" {
+		client := connectMQTT(d.cfg.MQTT, d)
+		defer client.close()
+	}
+
+	var wg sync.WaitGroup
+	for _, b := range d.backups {
+		wg.Add(1)
+		go func(b *scheduledBackup) {
+			defer wg.Done()
+			log.Printf("backing up %s on schedule %q", b.job.Port, b.schedule)
+			if err := schedule.Run(ctx, b.schedule, b.run); err != nil {
+				log.Printf("backups of %s stopped: %v", b.job.Port, err)
+			}
+		}(b)
+	}
+	for _, w := range d.watchers {
+		wg.Add(1)
+		go func(w *watch.Watcher) {
+			defer wg.Done()
+			log.Printf("watching %s", w.Dir())
+			_ = w.Run(ctx)
+		}(w)
+	}
+
+	if d.cfg.ServerEnabled() {
+		err = d.serve(ctx)
+		cancel()
+	} else {
+		// No server: just run the watchers and backups until stopped
+		<-ctx.Done()
+	}
+
+	wg.Wait()
+	return err
+}
+
+// serve runs the API server with the daemon status endpoint until ctx is done
+func (d *Daemon) serve(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(StatusPath, d.handleStatus)
+	mux.Handle("/", api.Handler(d.lib, api.Options{CORS: d.cfg.Server.CORS, Checks: d.readyChecks()}))
+
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", d.cfg.Server.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("API server listening on %s", srv.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// Status returns the current daemon status
+func (d *Daemon) Status() Status {
+	s := Status{
+		Status:  "healthy",
+		Uptime:  time.Since(d.started).Round(time.Second).String(),
+		Server:  d.cfg.ServerEnabled(),
+		Watches: make([]watch.Stats, 0, len(d.watchers)),
+		Backups: make([]BackupStatus, 0, len(d.backups)),
+	}
+	for _, w := range d.watchers {
+		s.Watches = append(s.Watches, w.Stats())
+	}
+	for _, b := range d.backups {
+		b.mu.Lock()
+		s.Backups = append(s.Backups, b.status)
+		b.mu.Unlock()
+	}
+	return s
+}
+
+// readyChecks are the readiness checks of the daemon's own services: the
+// MIDI backend when backups need it, and that watch folders are still being
+// scanned
+func (d *Daemon) readyChecks() []api.Check {
+	var checks []api.Check
+	if len(d.backups) > 0 {
+		checks = append(checks, api.Check{Name: "midi", Run: func(context.Context) error {
+			b, err := mididevice.Default()
+			if err != nil {
+				return err
+			}
+			_, err = b.Outputs()
+			return err
+		}})
+	}
+	if len(d.watchers) > 0 {
+		checks = append(checks, api.Check{Name: "watch", Run: func(context.Context) error {
+			return d.checkWatchers(time.Now())
+		}})
+	}
+	return checks
+}
+
+// checkWatchers reports a watch folder that has not been scanned for
+// several intervals, e.g. because it was removed or its watcher is stuck.
+// Folders watched through file system events are only scanned on changes;
+// their watchers fall back to polling when the folder goes away.
+func (d *Daemon) checkWatchers(now time.Time) error {
+	for i, w := range d.watchers {
+		interval := d.cfg.Watch[i].Interval
+		if interval <= 0 {
+			interval = watch.DefaultInterval
+		}
+		stats := w.Stats()
+		if stats.Events {
+			continue
+		}
+		last := stats.LastScan
+		if last.IsZero() {
+			last = d.started
+		}
+		if now.Sub(last) > max(3*interval, watchStallTimeout) {
+			if stats.LastError != "" {
+				return fmt.Errorf("%s not scanned since %s: %s", w.Dir(), last.Format(time.RFC3339), stats.LastError)
+			}
+			return fmt.Errorf("%s not scanned since %s", w.Dir(), last.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// run performs one scheduled backup, waiting for a triggered one to finish
+func (b *scheduledBackup) run(at time.Time) {
+	b.running.Lock()
+	defer b.running.Unlock()
+	b.backup(at)
+}
+
+// trigger performs a backup on request, failing if one is already running
+func (b *scheduledBackup) trigger(at time.Time) error {
+	if !b.running.TryLock() {
+		return fmt.Errorf("backup of %s already running", b.job.Port)
+	}
+	defer b.running.Unlock()
+	b.backup(at)
+	return nil
+}
+
+// backup runs the job and records the outcome; the caller holds running
+func (b *scheduledBackup) backup(at time.Time) {
+	path, err := b.job.Run(at)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status.LastRun = at
+	// A schedule that stops matching ends schedule.Run, which reports it
+	b.status.NextRun, _ = b.schedule.Next(at)
+	if err != nil {
+		b.status.LastError = err.Error()
+		b.emit(Event{Type: EventBackupFailed, Port: b.job.Port, Error: err.Error()})
+		return
+	}
+	b.status.LastFile = path
+	b.status.LastError = ""
+	b.emit(Event{Type: EventBackup, Port: b.job.Port, Output: path})
+}
+
+func (d *Daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(d.Status())
+}
+
+// newWatcher creates a watcher that converts new files in the folder to each
+// configured target format
+func newWatcher(wc WatchConfig, dev converter.Device, emit func(Event)) *watch.Watcher {
+	conv := converter.New(dev)
+
+	var w *watch.Watcher
+	w = watch.New(wc.Dir, wc.Interval, func(path string) error {
+		in := converter.DetectFormat(path)
+		if in == converter.FormatUnknown {
+			return nil
+		}
+
+		base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		for _, name := range wc.To {
+			out, _ := parseFormat(name)
+			if out == in {
+				continue
+			}
+			outPath := filepath.Join(wc.Output, base+service.Ext(out))
+			if wc.SkipUpToDate && converter.UpToDate(path, outPath) {
+				continue
+			}
+			if err := conv.ConvertFile(path, outPath); err != nil {
+				emit(Event{Type: EventConvertFailed, Input: path, Output: outPath, Error: err.Error()})
+				return fmt.Errorf("%s: %w", filepath.Base(path), err)
+			}
+			if wc.PreserveMTime {
+				if err := converter.PreserveModTime(path, outPath); err != nil {
+					return fmt.Errorf("%s: %w", filepath.Base(path), err)
+				}
+			}
+			// Don't convert our own output back when writing into the watched folder
+			w.Ignore(outPath)
+			emit(Event{Type: EventConverted, Input: path, Output: outPath})
+		}
+		return nil
+	})
+	w.ProcessExisting = wc.Existing
+	return w
+}
+
+// parseFormat parses a target format name from the config
+func parseFormat(name string) (converter.Format, error) {
+	switch strings.ToLower(name) {
+	case "seq":
+		return converter.FormatSeq, nil
+	case "syx":
+		return converter.FormatSyx, nil
+	case "mid", "midi":
+		return converter.FormatMIDI, nil
+	default:
+		return converter.FormatUnknown, fmt.Errorf("unknown format %q (use seq, syx, or midi)", name)
+	}
+}
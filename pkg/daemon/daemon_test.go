@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/backup"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/watch"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "daemon.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+server:
+  port: 9090
+watch:
+  - dir: /tmp/in
+    to: [midi, syx]
+    interval: 500ms
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Device != "td3" || cfg.Server.Port != 9090 || !cfg.ServerEnabled() {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	w := cfg.Watch[0]
+	if w.Output != "/tmp/in" || w.Interval != 500*time.Millisecond || len(w.To) != 2 {
+		t.Errorf("watch = %+v", w)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"unknown field", "sever: {}", "field sever not found"},
+		{"bad format", "watch: [{dir: in, to: [wav]}]", "unknown format"},
+		{"missing dir", "watch: [{to: [seq]}]", "dir is required"},
+		{"nothing to do", "server: {enabled: false}", "nothing to do"},
+		{"bad schedule", "backups: [{port: TD-3, dir: b, schedule: '0 25 * * *'}]", "out of range"},
+		{"backup missing port", "backups: [{dir: b, schedule: '@daily'}]", "port, dir, and schedule are required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.config))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatchFolderConversion(t *testing.T) {
+	dir := t.TempDir()
+	var events []Event
+	emit := func(ev Event) { events = append(events, ev) }
+	w := newWatcher(WatchConfig{Dir: dir, Output: dir, To: []string{"midi", "seq"}, PreserveMTime: true}, devices.NewTD3(), emit)
+	_ = w.Scan()
+
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 48, Gate: true, Velocity: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "line.seq"), seq, 0644); err != nil {
+		t.Fatal(err)
+	}
+	made := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "line.seq"), made, made); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = w.Scan()
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "line.mid")); err != nil {
+		t.Errorf("expected converted line.mid: %v", err)
+	} else if !info.ModTime().Equal(made) {
+		t.Errorf("line.mid mtime = %v, want the source's %v", info.ModTime(), made)
+	}
+	// The generated .mid must not be converted back over the source
+	if stats := w.Stats(); stats.Processed != 1 || stats.Failed != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if len(events) != 1 || events[0].Type != EventConverted {
+		t.Errorf("events = %+v, want one %s event", events, EventConverted)
+	}
+}
+
+func TestWatchSkipsUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 48, Gate: true, Velocity: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"old.seq": seq, "old.mid": []byte("keep"), "new.seq": seq} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old.seq"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	w := newWatcher(WatchConfig{Dir: dir, Output: dir, To: []string{"midi"}, Existing: true, SkipUpToDate: true}, devices.NewTD3(), func(ev Event) { events = append(events, ev) })
+	for i := 0; i < 2; i++ {
+		_ = w.Scan()
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "old.mid")); string(data) != "keep" {
+		t.Error("up-to-date old.mid was converted again")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.mid")); err != nil {
+		t.Errorf("expected converted new.mid: %v", err)
+	}
+	if len(events) != 1 || events[0].Output != filepath.Join(dir, "new.mid") {
+		t.Errorf("events = %+v, want only new.mid converted", events)
+	}
+}
+
+func TestCheckWatchers(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now()
+	d := &Daemon{cfg: &Config{Watch: []WatchConfig{{Dir: dir, Interval: time.Second}}}, started: start}
+	d.watchers = []*watch.Watcher{watch.New(dir, time.Second, func(string) error { return nil })}
+
+	if err := d.checkWatchers(start.Add(10 * time.Second)); err != nil {
+		t.Errorf("unscanned watcher shortly after start: %v", err)
+	}
+	if err := d.checkWatchers(start.Add(time.Minute)); err == nil {
+		t.Error("watcher never scanned for a minute reported healthy")
+	}
+	_ = d.watchers[0].Scan()
+	if err := d.checkWatchers(time.Now().Add(10 * time.Second)); err != nil {
+		t.Errorf("recently scanned watcher: %v", err)
+	}
+}
+
+func TestHandleCommand(t *testing.T) {
+	lib, err := library.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := lib.Add("A", "seq", "td3", []byte{1})
+	_, _ = lib.Add("B", "seq", "td3", []byte{2})
+	_, _ = lib.Rate(a.ID, 5)
+
+	d := New(&Config{Device: "td3", MQTT: MQTTConfig{Port: "TD-3"}})
+	d.dev = devices.NewTD3()
+	d.lib = lib
+
+	var events []Event
+	d.Subscribe(func(ev Event) { events = append(events, ev) })
+
+	if err := d.HandleCommand("reboot", nil); err == nil {
+		t.Error("unknown command expected error")
+	}
+	if err := d.HandleCommand(CommandBackup, nil); err == nil {
+		t.Error("backup without configured backups expected error")
+	}
+	// A trigger does not start a second backup alongside a running one
+	b := &scheduledBackup{job: &backup.Job{Port: "TD-3"}, emit: d.emit}
+	d.backups = []*scheduledBackup{b}
+	b.running.Lock()
+	if err := d.HandleCommand(CommandBackup, []byte("td-3")); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("backup while one is running error = %v", err)
+	}
+	b.running.Unlock()
+	d.backups = nil
+
+	if err := d.HandleCommand(CommandPush, []byte(`{"slot": "9Z9"}`)); err == nil {
+		t.Error("push to invalid slot expected error")
+	}
+	if len(events) != 1 || events[0].Type != EventPushFailed || events[0].Port != "TD-3" {
+		t.Errorf("events = %+v, want a push_failed event on the default port", events)
+	}
+
+	// A random pick honours the filter
+	entry, err := pickPattern(lib, PushRequest{MinRating: 4})
+	if err != nil || entry.ID != a.ID {
+		t.Errorf("pickPattern() = %+v, %v", entry, err)
+	}
+	if _, err := pickPattern(lib, PushRequest{Tag: "none"}); err == nil {
+		t.Error("pickPattern() with no matches expected error")
+	}
+}
@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"log"
+	"time"
+)
+
+// Event types published by the daemon
+const (
+	EventConverted     = "converted"
+	EventConvertFailed = "convert_failed"
+	EventBackup        = "backup"
+	EventBackupFailed  = "backup_failed"
+	EventPushed        = "pushed"
+	EventPushFailed    = "push_failed"
+)
+
+// Event describes something the daemon did, for logs and automation
+type Event struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Input   string    `json:"input,omitempty"`
+	Output  string    `json:"output,omitempty"`
+	Port    string    `json:"port,omitempty"`
+	Slot    string    `json:"slot,omitempty"`
+	Pattern string    `json:"pattern,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// String returns a one-line description of the event for logs
+func (e Event) String() string {
+	s := e.Type
+	if e.Input != "" {
+		s += " " + e.Input
+	}
+	if e.Output != "" {
+		s += " -> " + e.Output
+	}
+	if e.Pattern != "" {
+		s += " pattern " + e.Pattern
+	}
+	if e.Port != "" {
+		s += " on " + e.Port
+	}
+	if e.Slot != "" {
+		s += " slot " + e.Slot
+	}
+	if e.Error != "" {
+		s += ": " + e.Error
+	}
+	return s
+}
+
+// Subscribe registers fn to receive every subsequent event
+func (d *Daemon) Subscribe(fn func(Event)) {
+	d.sinksMu.Lock()
+	defer d.sinksMu.Unlock()
+	d.sinks = append(d.sinks, fn)
+}
+
+// emit logs an event and forwards it to subscribers
+func (d *Daemon) emit(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+
+	log.Print(ev)
+
+	d.sinksMu.Lock()
+	sinks := append([]func(Event){}, d.sinks...)
+	d.sinksMu.Unlock()
+	for _, fn := range sinks {
+		fn(ev)
+	}
+}
@@ -0,0 +1,92 @@
+package daemon
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTConfig configures the MQTT integration. The daemon publishes events to
+// <topic>/events, its availability ("online"/"offline", retained) to
+// <topic>/status, and accepts commands on <topic>/cmd/<command>.
+type MQTTConfig struct {
+	// Broker is the broker URL, e.g. tcp://homeassistant.local:1883
+	Broker   string `yaml:"broker"`
+	ClientID string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Topic is the topic prefix (default "synthtribe2midi")
+	Topic string `yaml:"topic"`
+	// Port is the default MIDI port for push commands
+	Port string `yaml:"port"`
+}
+
+// mqttClient bridges daemon events and commands to an MQTT broker
+type mqttClient struct {
+	client mqtt.Client
+	topic  string
+}
+
+// connectMQTT starts connecting to the broker, subscribes to commands once
+// connected, and forwards daemon events
+func connectMQTT(cfg MQTTConfig, d *Daemon) *mqttClient {
+	topic := strings.TrimSuffix(cfg.Topic, "/")
+	if topic == "" {
+		topic = "synthtribe2midi"
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "synthtribe2midi"
+	}
+
+	m := &mqttClient{topic: topic}
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10*time.Second).
+		SetWill(topic+"/status", "offline", 1, true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			// (Re)subscribe and announce on every connect
+			c.Publish(topic+"/status", 1, true, "online")
+			c.Subscribe(topic+"/cmd/+", 1, func(_ mqtt.Client, msg mqtt.Message) {
+				cmd := strings.TrimPrefix(msg.Topic(), topic+"/cmd/")
+				// Run commands off the client's dispatch goroutine; backups take a while
+				go func() {
+					if err := d.HandleCommand(cmd, msg.Payload()); err != nil {
+						log.Printf("mqtt command %s: %v", cmd, err)
+					}
+				}()
+			})
+		})
+
+	// Keep retrying in the background so the daemon starts even when the
+	// broker comes up later (e.g. both booting on the same Pi)
+	m.client = mqtt.NewClient(opts)
+	m.client.Connect()
+	log.Printf("connecting to MQTT broker %s (topic %s)", cfg.Broker, topic)
+
+	d.Subscribe(m.publish)
+	return m
+}
+
+// publish sends an event to <topic>/events
+func (m *mqttClient) publish(ev Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	m.client.Publish(m.topic+"/events", 0, false, data)
+}
+
+// close announces the daemon going offline and disconnects
+func (m *mqttClient) close() {
+	m.client.Publish(m.topic+"/status", 1, true, "offline").WaitTimeout(time.Second)
+	m.client.Disconnect(250)
+}
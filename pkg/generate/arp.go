@@ -0,0 +1,76 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Arpeggio styles
+const (
+	ArpUp     = "up"
+	ArpDown   = "down"
+	ArpUpDown = "updown"
+	ArpRandom = "random"
+)
+
+// ArpStyles lists the supported arpeggio styles
+var ArpStyles = []string{ArpUp, ArpDown, ArpUpDown, ArpRandom}
+
+// Arp builds a pattern that arpeggiates the chord notes in the given style
+// over octaves (1 or more), then seasons it with accents and slides
+func Arp(chord []uint8, style string, octaves int, opts Options) (*converter.Pattern, error) {
+	if len(chord) == 0 {
+		return nil, fmt.Errorf("empty chord")
+	}
+	octaves = max(octaves, 1)
+	var tones []uint8
+	for o := 0; o < octaves; o++ {
+		for _, n := range chord {
+			if int(n)+12*o > 127 {
+				return nil, fmt.Errorf("arpeggio exceeds the MIDI note range")
+			}
+			tones = append(tones, n+uint8(12*o))
+		}
+	}
+
+	var order []uint8
+	switch strings.ToLower(style) {
+	case ArpUp, "":
+		order = tones
+	case ArpDown:
+		for i := len(tones) - 1; i >= 0; i-- {
+			order = append(order, tones[i])
+		}
+	case ArpUpDown:
+		// Ping-pong without repeating the top and bottom notes
+		order = append(order, tones...)
+		for i := len(tones) - 2; i > 0; i-- {
+			order = append(order, tones[i])
+		}
+	case ArpRandom:
+	default:
+		return nil, fmt.Errorf("unknown arp style %q (use %s)", style, strings.Join(ArpStyles, ", "))
+	}
+
+	rng := opts.rand()
+	n := opts.steps()
+	p := &converter.Pattern{
+		Name:   "Arp",
+		Length: n,
+		Tempo:  opts.tempo(),
+		Steps:  make([]converter.Step, n),
+	}
+	for i := range p.Steps {
+		var note uint8
+		if order == nil {
+			note = tones[rng.Intn(len(tones))]
+		} else {
+			note = order[i%len(order)]
+		}
+		p.Steps[i] = converter.Step{Note: note, Gate: true, Velocity: normalVelocity}
+	}
+	season(p, rng)
+	return p, nil
+}
@@ -0,0 +1,73 @@
+package generate
+
+import (
+	"fmt"
+	"strings"
+)
+
+var chordRoots = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// chordQualities maps chord symbol suffixes to intervals above the root
+var chordQualities = map[string][]int{
+	"":     {0, 4, 7},
+	"maj":  {0, 4, 7},
+	"M":    {0, 4, 7},
+	"m":    {0, 3, 7},
+	"min":  {0, 3, 7},
+	"-":    {0, 3, 7},
+	"5":    {0, 7},
+	"6":    {0, 4, 7, 9},
+	"m6":   {0, 3, 7, 9},
+	"7":    {0, 4, 7, 10},
+	"maj7": {0, 4, 7, 11},
+	"M7":   {0, 4, 7, 11},
+	"m7":   {0, 3, 7, 10},
+	"min7": {0, 3, 7, 10},
+	"-7":   {0, 3, 7, 10},
+	"m7b5": {0, 3, 6, 10},
+	"dim":  {0, 3, 6},
+	"dim7": {0, 3, 6, 9},
+	"aug":  {0, 4, 8},
+	"+":    {0, 4, 8},
+	"sus2": {0, 2, 7},
+	"sus4": {0, 5, 7},
+	"sus":  {0, 5, 7},
+	"9":    {0, 4, 7, 10, 14},
+	"m9":   {0, 3, 7, 10, 14},
+	"add9": {0, 4, 7, 14},
+}
+
+// ParseChord returns the MIDI notes of a chord symbol such as "Am7", "F#",
+// or "Bbmaj7", with the root in the octave starting at base (e.g. 36 for C2)
+func ParseChord(symbol string, base uint8) ([]uint8, error) {
+	s := strings.TrimSpace(symbol)
+	if s == "" {
+		return nil, fmt.Errorf("empty chord")
+	}
+	root, ok := chordRoots[strings.ToUpper(s[:1])[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid chord %q: unknown root %q", symbol, s[:1])
+	}
+	s = s[1:]
+	switch {
+	case strings.HasPrefix(s, "#"):
+		root, s = root+1, s[1:]
+	case strings.HasPrefix(s, "b"):
+		root, s = root-1, s[1:]
+	}
+	intervals, ok := chordQualities[s]
+	if !ok {
+		return nil, fmt.Errorf("invalid chord %q: unknown quality %q", symbol, s)
+	}
+
+	root = (root + 12) % 12
+	notes := make([]uint8, len(intervals))
+	for i, iv := range intervals {
+		n := int(base) + root + iv
+		if n > 127 {
+			return nil, fmt.Errorf("chord %q is out of MIDI range at this octave", symbol)
+		}
+		notes[i] = uint8(n)
+	}
+	return notes, nil
+}
@@ -0,0 +1,180 @@
+// Package generate builds new patterns as quick, musically coherent starting
+// points: random lines following a style's constraints and weights or a
+// Markov model learned from existing patterns, and
+// arpeggios from chord symbols
+package generate
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Default generator settings
+const (
+	DefaultSteps = 16
+	DefaultTempo = 120.0
+	// DefaultBase is the lowest root note, C2 (C1 in TD-3 notation)
+	DefaultBase = converter.NotationBaseNote
+)
+
+// Velocities of generated steps, matching the TD-3 accent levels
+const (
+	normalVelocity = 100
+	accentVelocity = 127
+)
+
+// Options controls generation
+type Options struct {
+	Steps int     // Pattern length (default DefaultSteps)
+	Tempo float64 // BPM (default DefaultTempo)
+	Seed  int64   // Random seed; the same seed gives the same pattern (0 = time based)
+}
+
+func (o Options) steps() int {
+	if o.Steps <= 0 {
+		return DefaultSteps
+	}
+	return o.Steps
+}
+
+func (o Options) tempo() float64 {
+	if o.Tempo <= 0 {
+		return DefaultTempo
+	}
+	return o.Tempo
+}
+
+func (o Options) rand() *rand.Rand {
+	seed := o.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// season adds accents (mostly on downbeats) and occasional slides between
+// differing notes, the way a 303 line is usually programmed
+func season(p *converter.Pattern, rng *rand.Rand) {
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		if !s.Gate {
+			continue
+		}
+		chance := 0.15
+		if i%4 == 0 {
+			chance = 0.6
+		}
+		if rng.Float64() < chance {
+			s.Accent = true
+			s.Velocity = accentVelocity
+		}
+		if i+1 < len(p.Steps) && p.Steps[i+1].Gate && p.Steps[i+1].Note != s.Note && rng.Float64() < 0.2 {
+			s.Slide = true
+		}
+	}
+}
+
+// Generate builds a random pattern following the style: notes are drawn
+// from its allowed notes by weight, no further than MaxJump from the
+// previous note, with rests, ties, slides, and accents at its probabilities
+func Generate(style *Style, opts Options) (*converter.Pattern, error) {
+	allowed, err := style.AllowedNotes()
+	if err != nil {
+		return nil, err
+	}
+	if len(allowed) == 0 {
+		return nil, errors.New("the style allows no notes")
+	}
+
+	rng := opts.rand()
+	n := opts.steps()
+	p := &converter.Pattern{
+		Name:   style.Name,
+		Length: n,
+		Tempo:  opts.tempo(),
+		Steps:  make([]converter.Step, n),
+	}
+
+	var prev *converter.Step
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		switch {
+		case prev != nil && prev.Gate && rng.Float64() < style.Tie:
+			*s = converter.Step{Note: prev.Note, Gate: true, Tie: true, Velocity: prev.Velocity, Accent: prev.Accent}
+		case rng.Float64() < style.Rest:
+			// Rest
+		default:
+			var last *uint8
+			if prev != nil && prev.Gate {
+				last = &prev.Note
+			}
+			*s = converter.Step{Note: pickNote(style, allowed, last, rng), Gate: true, Velocity: normalVelocity}
+			chance := style.Accent
+			if i%4 == 0 {
+				chance = style.DownbeatAccent
+			}
+			if rng.Float64() < chance {
+				s.Accent = true
+				s.Velocity = accentVelocity
+			}
+		}
+		prev = s
+	}
+
+	// Slides glide into the next sounding note
+	for i := 0; i+1 < n; i++ {
+		s, next := &p.Steps[i], p.Steps[i+1]
+		if s.Gate && next.Gate && !next.Tie && next.Note != s.Note && rng.Float64() < style.Slide {
+			s.Slide = true
+		}
+	}
+	return p, nil
+}
+
+// pickNote draws a weighted note within MaxJump of the previous one, or the
+// nearest allowed note if none is close enough
+func pickNote(style *Style, allowed []uint8, last *uint8, rng *rand.Rand) uint8 {
+	candidates := allowed
+	if last != nil && style.MaxJump > 0 {
+		candidates = nil
+		for _, n := range allowed {
+			if abs(int(n)-int(*last)) <= style.MaxJump {
+				candidates = append(candidates, n)
+			}
+		}
+		if len(candidates) == 0 {
+			nearest := allowed[0]
+			for _, n := range allowed {
+				if abs(int(n)-int(*last)) < abs(int(nearest)-int(*last)) {
+					nearest = n
+				}
+			}
+			return nearest
+		}
+	}
+
+	total := 0.0
+	for _, n := range candidates {
+		total += style.weight(n)
+	}
+	if total <= 0 {
+		return candidates[rng.Intn(len(candidates))]
+	}
+	r := rng.Float64() * total
+	for _, n := range candidates {
+		if r -= style.weight(n); r < 0 {
+			return n
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
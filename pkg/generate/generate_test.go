@@ -0,0 +1,227 @@
+package generate
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   []uint8
+	}{
+		{"C", []uint8{36, 40, 43}},
+		{"Am7", []uint8{45, 48, 52, 55}},
+		{"F#", []uint8{42, 46, 49}},
+		{"Bbmaj7", []uint8{46, 50, 53, 57}},
+		{"esus4", []uint8{40, 45, 47}},
+	}
+	for _, tt := range tests {
+		got, err := ParseChord(tt.symbol, DefaultBase)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseChord(%q) = %v, %v, want %v", tt.symbol, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "H", "Cblah"} {
+		if _, err := ParseChord(bad, DefaultBase); err == nil {
+			t.Errorf("ParseChord(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestArp(t *testing.T) {
+	chord := []uint8{45, 48, 52}
+	tests := []struct {
+		style string
+		want  []uint8
+	}{
+		{ArpUp, []uint8{45, 48, 52, 57, 60, 64, 45}},
+		{ArpDown, []uint8{64, 60, 57, 52, 48, 45, 64}},
+		{ArpUpDown, []uint8{45, 48, 52, 57, 60, 64, 60, 57, 52, 48, 45}},
+	}
+	for _, tt := range tests {
+		p, err := Arp(chord, tt.style, 2, Options{Seed: 1})
+		if err != nil {
+			t.Fatalf("Arp(%s) error = %v", tt.style, err)
+		}
+		if p.Length != DefaultSteps || len(p.Steps) != DefaultSteps {
+			t.Fatalf("Arp(%s) length = %d", tt.style, p.Length)
+		}
+		for i, want := range tt.want {
+			if s := p.Steps[i]; s.Note != want || !s.Gate {
+				t.Errorf("Arp(%s) step %d = %+v, want note %d", tt.style, i, s, want)
+			}
+		}
+	}
+
+	// Random arps only use chord tones and are reproducible by seed
+	a, _ := Arp(chord, ArpRandom, 1, Options{Seed: 7})
+	b, _ := Arp(chord, ArpRandom, 1, Options{Seed: 7})
+	if !reflect.DeepEqual(a, b) {
+		t.Error("same seed gave different patterns")
+	}
+	for i, s := range a.Steps {
+		if s.Note != 45 && s.Note != 48 && s.Note != 52 {
+			t.Errorf("random step %d note %d is not a chord tone", i, s.Note)
+		}
+		if s.Accent != (s.Velocity == accentVelocity) {
+			t.Errorf("step %d accent %v with velocity %d", i, s.Accent, s.Velocity)
+		}
+	}
+
+	if _, err := Arp(chord, "sideways", 1, Options{}); err == nil {
+		t.Error("Arp() with unknown style succeeded")
+	}
+}
+
+func TestPresets(t *testing.T) {
+	names := Presets()
+	if len(names) < 2 || names[0] != DefaultPreset {
+		t.Fatalf("Presets() = %v", names)
+	}
+	for _, name := range names {
+		s, err := LoadStyle(name)
+		if err != nil {
+			t.Errorf("LoadStyle(%s) error = %v", name, err)
+			continue
+		}
+		if s.Name != name {
+			t.Errorf("preset %s is named %q", name, s.Name)
+		}
+	}
+	if _, err := LoadStyle("no-such-style"); err == nil {
+		t.Error("LoadStyle() of unknown preset succeeded")
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"valid", "root: A\nscale: minor\noctave: 2\n", ""},
+		{"notes", "notes: [A2, C3, E3]\n", ""},
+		{"bad probability", "root: A\nscale: minor\noctave: 2\nrest: 1.5\n", "between 0 and 1"},
+		{"bad scale", "root: A\nscale: lydian-dominant\noctave: 2\n", "unknown scale"},
+		{"bad note", "notes: [H2]\n", "invalid note"},
+		{"unknown field", "root: A\nscale: minor\nswing: 0.5\n", "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStyle([]byte(tt.yaml), "test.yaml")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ParseStyle() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ParseStyle() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	style := &Style{
+		Name:           "test",
+		Notes:          []string{"A2", "C3", "E3", "A3"},
+		MaxJump:        5,
+		Rest:           0.2,
+		Tie:            0.1,
+		Slide:          0.3,
+		DownbeatAccent: 1,
+		Weights:        map[string]float64{"A": 0, "A3": 5},
+	}
+	p, err := Generate(style, Options{Seed: 3})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	again, _ := Generate(style, Options{Seed: 3})
+	if !reflect.DeepEqual(p, again) {
+		t.Error("same seed gave different patterns")
+	}
+
+	for i, s := range p.Steps {
+		if !s.Gate {
+			continue
+		}
+		// A2 weighs nothing, so only C3, E3, and A3 are drawn
+		if s.Note != 48 && s.Note != 52 && s.Note != 57 {
+			t.Errorf("step %d note %d not allowed", i, s.Note)
+		}
+		if prev := p.Steps[max(i-1, 0)]; i > 0 && prev.Gate && abs(int(s.Note)-int(prev.Note)) > style.MaxJump {
+			t.Errorf("step %d jumps %d -> %d", i, prev.Note, s.Note)
+		}
+		if i%4 == 0 && !s.Tie && !s.Accent {
+			t.Errorf("downbeat step %d not accented", i)
+		}
+	}
+
+	// Constraints at their extremes
+	rests, _ := Generate(&Style{Notes: []string{"C2"}, Rest: 1}, Options{Seed: 1})
+	for i, s := range rests.Steps {
+		if s.Gate {
+			t.Errorf("rest 1: step %d sounds", i)
+		}
+	}
+}
+
+func TestMarkov(t *testing.T) {
+	line := func(notes ...uint8) *converter.Pattern {
+		p := &converter.Pattern{Length: len(notes), Tempo: 130}
+		for i, n := range notes {
+			s := converter.Step{Note: n, Gate: n != 0, Velocity: normalVelocity}
+			if i == 0 {
+				s.Accent, s.Velocity = true, accentVelocity
+			}
+			p.Steps = append(p.Steps, s)
+		}
+		return p
+	}
+	m := NewModel(2)
+	m.Learn(line(45, 0, 45, 48, 45, 0, 45, 52))
+	m.Learn(line(45, 0, 45, 48, 45, 0, 45, 55))
+
+	if m.Patterns != 2 || m.Steps != 8 || m.Tempo != 130 {
+		t.Errorf("model = %d patterns, %d steps, %g BPM", m.Patterns, m.Steps, m.Tempo)
+	}
+	// Every pattern opens with an accented A2
+	if got := m.Transitions["^ ^"]; len(got) != 1 || got["45a"] != 2 {
+		t.Errorf("start transitions = %v", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadModel(path)
+	if err != nil {
+		t.Fatalf("LoadModel() error = %v", err)
+	}
+	p, err := loaded.Generate(Options{Seed: 5})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	again, _ := loaded.Generate(Options{Seed: 5})
+	if !reflect.DeepEqual(p, again) {
+		t.Error("same seed gave different patterns")
+	}
+	if len(p.Steps) != 8 || p.Tempo != 130 {
+		t.Errorf("generated %d steps at %g BPM", len(p.Steps), p.Tempo)
+	}
+	if s := p.Steps[0]; s.Note != 45 || !s.Accent {
+		t.Errorf("first step = %+v, want accented A2", s)
+	}
+	for i, s := range p.Steps {
+		if s.Gate && s.Note != 45 && s.Note != 48 && s.Note != 52 && s.Note != 55 {
+			t.Errorf("step %d note %d never appears in the corpus", i, s.Note)
+		}
+	}
+
+	if _, err := NewModel(1).Generate(Options{}); err == nil {
+		t.Error("Generate() with an empty model succeeded")
+	}
+}
@@ -0,0 +1,222 @@
+package generate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// DefaultOrder is the number of previous steps a model looks at
+const DefaultOrder = 2
+
+// Tokens of the step sequences a model learns
+const (
+	restToken  = "-"
+	startToken = "^"
+)
+
+// Model is a Markov chain over pattern steps learned from a corpus. Each
+// step is a token such as "45" (A2), "45as" (accented, sliding), or "-"
+// (rest). Transitions count which token followed each context of up to
+// Order previous tokens, so generation can back off to shorter contexts
+// the corpus never continued.
+type Model struct {
+	Order       int                       `json:"order"`
+	Patterns    int                       `json:"patterns"`
+	Steps       int                       `json:"steps"` // Most common pattern length
+	Tempo       float64                   `json:"tempo"` // Average tempo
+	Transitions map[string]map[string]int `json:"transitions"`
+
+	lengths  map[int]int
+	tempoSum float64
+}
+
+// NewModel returns an empty model looking at order previous steps
+func NewModel(order int) *Model {
+	if order <= 0 {
+		order = DefaultOrder
+	}
+	return &Model{Order: order, Transitions: map[string]map[string]int{}, lengths: map[int]int{}}
+}
+
+// Learn adds a pattern's step sequence to the model
+func (m *Model) Learn(p *converter.Pattern) {
+	if len(p.Steps) == 0 {
+		return
+	}
+	if m.lengths == nil {
+		m.lengths = map[int]int{}
+	}
+	m.Patterns++
+	m.lengths[len(p.Steps)]++
+	m.tempoSum += p.Tempo
+	m.Tempo = m.tempoSum / float64(m.Patterns)
+	for n, count := range m.lengths {
+		if count > m.lengths[m.Steps] || (count == m.lengths[m.Steps] && n > m.Steps) {
+			m.Steps = n
+		}
+	}
+
+	history := make([]string, m.Order)
+	for i := range history {
+		history[i] = startToken
+	}
+	for _, s := range p.Steps {
+		token := stepToken(s)
+		for k := 0; k <= m.Order; k++ {
+			key := contextKey(history[len(history)-k:])
+			if m.Transitions[key] == nil {
+				m.Transitions[key] = map[string]int{}
+			}
+			m.Transitions[key][token]++
+		}
+		history = append(history[1:], token)
+	}
+}
+
+// LoadModel reads a model written by Save
+func LoadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model: %w", err)
+	}
+	m := &Model{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse model %s: %w", path, err)
+	}
+	if m.Order <= 0 || len(m.Transitions[""]) == 0 {
+		return nil, fmt.Errorf("model %s is empty", path)
+	}
+	return m, nil
+}
+
+// Save writes the model as JSON
+func (m *Model) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return converter.WriteFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Generate walks the chain to build a new pattern. Options.Steps and
+// Options.Tempo default to the corpus' most common length and average tempo.
+func (m *Model) Generate(opts Options) (*converter.Pattern, error) {
+	if m.Patterns == 0 || len(m.Transitions[""]) == 0 {
+		return nil, errors.New("the model has not learned any patterns")
+	}
+	if opts.Steps <= 0 {
+		opts.Steps = m.Steps
+	}
+	if opts.Tempo <= 0 {
+		opts.Tempo = m.Tempo
+	}
+
+	rng := opts.rand()
+	n := opts.steps()
+	p := &converter.Pattern{
+		Name:   "Markov",
+		Length: n,
+		Tempo:  opts.tempo(),
+		Steps:  make([]converter.Step, n),
+	}
+	history := make([]string, m.Order)
+	for i := range history {
+		history[i] = startToken
+	}
+	for i := range p.Steps {
+		token := m.next(history, rng)
+		p.Steps[i] = tokenStep(token)
+		history = append(history[1:], token)
+	}
+
+	// Backing off can join steps the corpus never joined; keep the
+	// articulation consistent with the notes that ended up next to each other
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		if s.Tie && (i == 0 || !p.Steps[i-1].Gate || p.Steps[i-1].Note != s.Note) {
+			s.Tie = false
+		}
+		if s.Slide && (i+1 >= n || !p.Steps[i+1].Gate) {
+			s.Slide = false
+		}
+	}
+	return p, nil
+}
+
+// next draws the token following history, from the longest context the
+// model has seen
+func (m *Model) next(history []string, rng *rand.Rand) string {
+	for k := len(history); k >= 0; k-- {
+		counts := m.Transitions[contextKey(history[len(history)-k:])]
+		if len(counts) == 0 {
+			continue
+		}
+		tokens := make([]string, 0, len(counts))
+		total := 0
+		for t, c := range counts {
+			tokens = append(tokens, t)
+			total += c
+		}
+		sort.Strings(tokens) // Map order is random; keep seeds reproducible
+		r := rng.Intn(total)
+		for _, t := range tokens {
+			if r -= counts[t]; r < 0 {
+				return t
+			}
+		}
+	}
+	return restToken
+}
+
+func contextKey(tokens []string) string {
+	return strings.Join(tokens, " ")
+}
+
+// stepToken encodes a step as its note followed by a for accent, s for
+// slide, and t for tie
+func stepToken(s converter.Step) string {
+	if !s.Gate {
+		return restToken
+	}
+	token := strconv.Itoa(int(s.Note))
+	if s.Accent {
+		token += "a"
+	}
+	if s.Slide {
+		token += "s"
+	}
+	if s.Tie {
+		token += "t"
+	}
+	return token
+}
+
+// tokenStep decodes a token written by stepToken
+func tokenStep(token string) converter.Step {
+	digits := strings.TrimRight(token, "ast")
+	note, err := strconv.Atoi(digits)
+	if err != nil || note < 0 || note > 127 {
+		return converter.Step{}
+	}
+	flags := token[len(digits):]
+	s := converter.Step{
+		Note:     uint8(note),
+		Gate:     true,
+		Velocity: normalVelocity,
+		Accent:   strings.Contains(flags, "a"),
+		Slide:    strings.Contains(flags, "s"),
+		Tie:      strings.Contains(flags, "t"),
+	}
+	if s.Accent {
+		s.Velocity = accentVelocity
+	}
+	return s
+}
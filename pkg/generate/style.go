@@ -0,0 +1,234 @@
+package generate
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultPreset is the style used when none is given
+const DefaultPreset = "acid"
+
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+// scales maps scale names to intervals above the root
+var scales = map[string][]int{
+	"major":            {0, 2, 4, 5, 7, 9, 11},
+	"minor":            {0, 2, 3, 5, 7, 8, 10},
+	"harmonic-minor":   {0, 2, 3, 5, 7, 8, 11},
+	"dorian":           {0, 2, 3, 5, 7, 9, 10},
+	"phrygian":         {0, 1, 3, 5, 7, 8, 10},
+	"mixolydian":       {0, 2, 4, 5, 7, 9, 10},
+	"minor-pentatonic": {0, 3, 5, 7, 10},
+	"major-pentatonic": {0, 2, 4, 7, 9},
+	"blues":            {0, 3, 5, 6, 7, 10},
+	"chromatic":        {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+}
+
+// Style holds the constraints and weights of the generator. Styles are
+// stored as YAML presets; see the presets directory for examples.
+type Style struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+
+	// Root and Scale give the allowed notes, from the root in Octave (C2 is
+	// MIDI note 36) up Range octaves. Notes lists them explicitly instead,
+	// e.g. [A2, C3, E3].
+	Root   string   `yaml:"root"`
+	Scale  string   `yaml:"scale"`
+	Octave int      `yaml:"octave"`
+	Range  int      `yaml:"range"`
+	Notes  []string `yaml:"notes"`
+
+	// MaxJump is the largest interval between consecutive notes in
+	// semitones (0 = unlimited)
+	MaxJump int `yaml:"max_jump"`
+
+	// Probabilities (0-1) per step
+	Rest           float64 `yaml:"rest"`
+	Tie            float64 `yaml:"tie"`
+	Slide          float64 `yaml:"slide"`
+	Accent         float64 `yaml:"accent"`
+	DownbeatAccent float64 `yaml:"downbeat_accent"` // Accent chance on steps 1, 5, 9, 13
+
+	// Weights make notes more likely, by pitch class ("A") or note ("A2");
+	// other notes weigh 1
+	Weights map[string]float64 `yaml:"weights"`
+}
+
+// Presets returns the names of the built-in styles
+func Presets() []string {
+	entries, _ := presetFiles.ReadDir("presets")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Scales returns the names of the scales a style may use
+func Scales() []string {
+	names := make([]string, 0, len(scales))
+	for name := range scales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadStyle returns a built-in preset by name, or reads a style from a YAML
+// file
+func LoadStyle(nameOrPath string) (*Style, error) {
+	data, err := presetFiles.ReadFile(path.Join("presets", nameOrPath+".yaml"))
+	source := nameOrPath
+	if err != nil {
+		if data, err = os.ReadFile(nameOrPath); err != nil {
+			return nil, fmt.Errorf("unknown preset %q (built in: %s): %w", nameOrPath, strings.Join(Presets(), ", "), err)
+		}
+	}
+	return ParseStyle(data, source)
+}
+
+// ParseStyle parses and validates a YAML style
+func ParseStyle(data []byte, source string) (*Style, error) {
+	s := &Style{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse style %s: %w", source, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid style %s: %w", source, err)
+	}
+	return s, nil
+}
+
+// Validate checks the style's settings
+func (s *Style) Validate() error {
+	for name, p := range map[string]float64{
+		"rest": s.Rest, "tie": s.Tie, "slide": s.Slide, "accent": s.Accent, "downbeat_accent": s.DownbeatAccent,
+	} {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("%s probability %g must be between 0 and 1", name, p)
+		}
+	}
+	if s.MaxJump < 0 {
+		return fmt.Errorf("max_jump %d must not be negative", s.MaxJump)
+	}
+	for key, w := range s.Weights {
+		if w < 0 {
+			return fmt.Errorf("weight of %s must not be negative", key)
+		}
+	}
+	_, err := s.AllowedNotes()
+	return err
+}
+
+// AllowedNotes returns the MIDI notes the style may use, lowest first
+func (s *Style) AllowedNotes() ([]uint8, error) {
+	if len(s.Notes) > 0 {
+		notes := make([]uint8, 0, len(s.Notes))
+		for _, name := range s.Notes {
+			n, err := ParseNote(name)
+			if err != nil {
+				return nil, err
+			}
+			notes = append(notes, n)
+		}
+		sort.Slice(notes, func(i, j int) bool { return notes[i] < notes[j] })
+		return notes, nil
+	}
+
+	intervals, ok := scales[strings.ToLower(s.Scale)]
+	if !ok {
+		names := make([]string, 0, len(scales))
+		for name := range scales {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown scale %q (use %s, or list notes)", s.Scale, strings.Join(names, ", "))
+	}
+	root, err := ParseNote(s.Root + strconv.Itoa(s.Octave))
+	if err != nil {
+		return nil, err
+	}
+	octaves := max(s.Range, 1)
+	var notes []uint8
+	for o := 0; o < octaves; o++ {
+		for _, iv := range intervals {
+			if n := int(root) + 12*o + iv; n <= 127 {
+				notes = append(notes, uint8(n))
+			}
+		}
+	}
+	// The root an octave up closes the range
+	if top := int(root) + 12*octaves; top <= 127 {
+		notes = append(notes, uint8(top))
+	}
+	return notes, nil
+}
+
+// weight returns how likely a note is to be picked; a weight for the exact
+// note wins over one for its pitch class
+func (s *Style) weight(note uint8) float64 {
+	w, found := 1.0, false
+	for key, kw := range s.Weights {
+		if n, err := ParseNote(key); err == nil && n == note {
+			return kw
+		}
+		if pc, ok := pitchClass(key); ok && pc == int(note)%12 && (!found || kw > w) {
+			w, found = kw, true
+		}
+	}
+	return w
+}
+
+// ParseNote parses a note name with octave such as "A2", "C#3", or "Bb1",
+// with middle C (60) as C4
+func ParseNote(name string) (uint8, error) {
+	name = strings.TrimSpace(name)
+	i := 1
+	if len(name) > 1 && (name[1] == '#' || name[1] == 'b') {
+		i = 2
+	}
+	pc, ok := pitchClass(name[:min(i, len(name))])
+	if !ok || len(name) <= i {
+		return 0, fmt.Errorf("invalid note %q: want a name and octave like A2 or C#3", name)
+	}
+	octave, err := strconv.Atoi(name[i:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid note %q: want a name and octave like A2 or C#3", name)
+	}
+	n := (octave+1)*12 + pc
+	if n < 0 || n > 127 {
+		return 0, fmt.Errorf("note %q is out of MIDI range", name)
+	}
+	return uint8(n), nil
+}
+
+// pitchClass parses a note name without octave, e.g. "F#" -> 6
+func pitchClass(name string) (int, bool) {
+	if name == "" {
+		return 0, false
+	}
+	pc, ok := chordRoots[strings.ToUpper(name[:1])[0]]
+	if !ok {
+		return 0, false
+	}
+	switch name[1:] {
+	case "":
+	case "#":
+		pc++
+	case "b":
+		pc--
+	default:
+		return 0, false
+	}
+	return (pc + 12) % 12, true
+}
@@ -0,0 +1,369 @@
+// Package history keeps an audit log of conversions: which input produced
+// which outputs, with what device and options, and the content hash of each
+// file, so an output can be traced back, undone, or produced again
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+var (
+	// ErrNotFound is returned when no record has the requested ID
+	ErrNotFound = errors.New("not found")
+	// ErrModified is returned when undoing a conversion whose output was
+	// changed after it was written
+	ErrModified = errors.New("output modified since the conversion")
+)
+
+const (
+	logFile    = "history.jsonl"
+	backupsDir = "backups"
+)
+
+// File is an input or output of a conversion
+type File struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	Hash   string `json:"hash,omitempty"`
+	// Backup names the saved previous content of an overwritten output
+	Backup string `json:"backup,omitempty"`
+}
+
+// Record describes one conversion
+type Record struct {
+	ID       string                   `json:"id"`
+	Time     time.Time                `json:"time"`
+	Command  string                   `json:"command"`
+	Device   string                   `json:"device"`
+	From     string                   `json:"from,omitempty"` // Source device of a migration
+	Options  converter.ConvertOptions `json:"options"`
+	Input    File                     `json:"input"`
+	Outputs  []File                   `json:"outputs"`
+	Warnings []string                 `json:"warnings,omitempty"`
+	Undone   *time.Time               `json:"undone,omitempty"`
+}
+
+// Log is a conversion history stored as JSON lines in a directory, next to
+// backups of the files conversions overwrote
+type Log struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// DefaultDir returns the default history location in the user config directory
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "synthtribe2midi", "history"), nil
+}
+
+// Open opens (creating if necessary) the history in dir
+func Open(dir string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Join(dir, backupsDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &Log{dir: dir}, nil
+}
+
+// Record runs write, which creates the record's outputs, and logs the
+// conversion. Outputs that already exist are saved first so the conversion
+// can be undone. The ID and hashes are filled in and paths made absolute;
+// write may still add to rec, e.g. the conversion's warnings.
+func (l *Log) Record(rec *Record, write func() error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	rec.ID = newID(*rec)
+	if err := absPaths(rec); err != nil {
+		return err
+	}
+	if sum, err := hashFile(rec.Input.Path); err == nil {
+		rec.Input.Hash = sum
+	}
+
+	for i := range rec.Outputs {
+		out := &rec.Outputs[i]
+		data, err := os.ReadFile(out.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		out.Backup = rec.ID + "-" + strconv.Itoa(i) + filepath.Ext(out.Path)
+		if err := converter.WriteFileAtomic(filepath.Join(l.dir, backupsDir, out.Backup), data, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", out.Path, err)
+		}
+	}
+
+	if err := write(); err != nil {
+		l.removeBackups(rec)
+		return err
+	}
+
+	for i := range rec.Outputs {
+		sum, err := hashFile(rec.Outputs[i].Path)
+		if err != nil {
+			return fmt.Errorf("conversion written but not recorded: %w", err)
+		}
+		rec.Outputs[i].Hash = sum
+	}
+	if err := l.append(*rec); err != nil {
+		return fmt.Errorf("conversion written but not recorded: %w", err)
+	}
+	return nil
+}
+
+// List returns all records, oldest first
+func (l *Log) List() ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.read()
+}
+
+// Get returns the record with the given ID or unique ID prefix
+func (l *Log) Get(id string) (*Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	i, err := find(records, id)
+	if err != nil {
+		return nil, err
+	}
+	return &records[i], nil
+}
+
+// UpToDate reports whether the last recorded conversion of rec's input to
+// the same outputs still describes the files: it used the same device and
+// options, and the input and outputs have the hashes it
+// recorded. Without such a record there is nothing to contradict, so it
+// reports true.
+func (l *Log) UpToDate(rec Record) (bool, error) {
+	rec.Outputs = append([]File(nil), rec.Outputs...)
+	if err := absPaths(&rec); err != nil {
+		return false, err
+	}
+	l.mu.Lock()
+	records, err := l.read()
+	l.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		last := records[i]
+		if last.Undone != nil || last.Input.Path != rec.Input.Path || outputsKey(last) != outputsKey(rec) {
+			continue
+		}
+		if last.Device != rec.Device || last.From != rec.From || !sameOptions(last.Options, rec.Options) {
+			return false, nil
+		}
+		for _, f := range append([]File{last.Input}, last.Outputs...) {
+			if sum, err := hashFile(f.Path); err != nil || sum != f.Hash {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return true, nil
+}
+
+// sameOptions compares options the way they are stored in the log
+func sameOptions(a, b converter.ConvertOptions) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// Undo reverts a conversion: overwritten outputs are restored from their
+// backups and new outputs are deleted. Outputs changed since the conversion
+// are left alone with ErrModified unless force is set.
+func (l *Log) Undo(id string, force bool) (*Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	i, err := find(records, id)
+	if err != nil {
+		return nil, err
+	}
+	rec := &records[i]
+	if rec.Undone != nil {
+		return nil, fmt.Errorf("conversion %s was already undone", rec.ID)
+	}
+
+	if !force {
+		for _, out := range rec.Outputs {
+			sum, err := hashFile(out.Path)
+			if err == nil && sum != out.Hash {
+				return nil, fmt.Errorf("%s: %w (use --force to undo anyway)", out.Path, ErrModified)
+			}
+		}
+	}
+
+	for _, out := range rec.Outputs {
+		if out.Backup == "" {
+			if err := os.Remove(out.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.dir, backupsDir, out.Backup))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup of %s: %w", out.Path, err)
+		}
+		if err := converter.WriteFileAtomic(out.Path, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", out.Path, err)
+		}
+	}
+	l.removeBackups(rec)
+
+	now := time.Now().UTC()
+	rec.Undone = &now
+	if err := l.write(records); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// removeBackups deletes the saved previous contents of a record's outputs
+func (l *Log) removeBackups(rec *Record) {
+	for _, out := range rec.Outputs {
+		if out.Backup != "" {
+			_ = os.Remove(filepath.Join(l.dir, backupsDir, out.Backup))
+		}
+	}
+}
+
+func (l *Log) read() ([]Record, error) {
+	f, err := os.Open(filepath.Join(l.dir, logFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", logFile, line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+func (l *Log) append(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// write replaces the log with records
+func (l *Log) write(records []Record) error {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	return converter.WriteFileAtomic(filepath.Join(l.dir, logFile), buf.Bytes(), 0644)
+}
+
+// find returns the index of the record with the given ID or unique prefix
+func find(records []Record, id string) (int, error) {
+	match := -1
+	for i, rec := range records {
+		if rec.ID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(rec.ID, id) {
+			if match >= 0 {
+				return -1, fmt.Errorf("conversion ID %q is ambiguous", id)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return -1, fmt.Errorf("conversion %q: %w", id, ErrNotFound)
+	}
+	return match, nil
+}
+
+// newID derives a short ID from the time and files of a conversion
+func newID(rec Record) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s", rec.Time.UnixNano(), rec.Input.Path)
+	for _, out := range rec.Outputs {
+		fmt.Fprintf(h, "\x00%s", out.Path)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:10]
+}
+
+func absPaths(rec *Record) error {
+	var err error
+	if rec.Input.Path, err = filepath.Abs(rec.Input.Path); err != nil {
+		return err
+	}
+	for i := range rec.Outputs {
+		if rec.Outputs[i].Path, err = filepath.Abs(rec.Outputs[i].Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashFile returns the SHA-256 of a file's content as hex
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
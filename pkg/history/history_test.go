@@ -0,0 +1,124 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndUndo(t *testing.T) {
+	log, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "acid.mid")
+	fresh := filepath.Join(dir, "acid.seq")
+	existing := filepath.Join(dir, "acid.syx")
+	writeFile(t, input, "midi")
+	writeFile(t, existing, "old syx")
+
+	rec := &Record{
+		Command: "convert",
+		Device:  "td3",
+		Input:   File{Path: input, Format: "midi"},
+		Outputs: []File{{Path: fresh, Format: "seq"}, {Path: existing, Format: "syx"}},
+	}
+	err = log.Record(rec, func() error {
+		writeFile(t, fresh, "seq")
+		writeFile(t, existing, "new syx")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if rec.Input.Hash == "" || rec.Outputs[0].Hash == "" || rec.Outputs[0].Backup != "" || rec.Outputs[1].Backup == "" {
+		t.Errorf("Record() = %+v", rec)
+	}
+
+	// A failed write is not logged
+	if err := log.Record(&Record{Input: File{Path: input}}, func() error { return errors.New("boom") }); err == nil {
+		t.Error("Record() with failing write succeeded")
+	}
+	records, err := log.List()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("List() = %d records, %v", len(records), err)
+	}
+	got, err := log.Get(rec.ID[:4])
+	if err != nil || got.ID != rec.ID {
+		t.Fatalf("Get(prefix) = %v, %v", got, err)
+	}
+
+	// Undo refuses to clobber later edits unless forced
+	writeFile(t, fresh, "edited")
+	if _, err := log.Undo(rec.ID, false); !errors.Is(err, ErrModified) {
+		t.Fatalf("Undo() of modified output error = %v, want ErrModified", err)
+	}
+	if _, err := log.Undo(rec.ID, true); err != nil {
+		t.Fatalf("Undo(force) error = %v", err)
+	}
+	if _, err := os.Stat(fresh); !os.IsNotExist(err) {
+		t.Errorf("new output %s still exists", fresh)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old syx" {
+		t.Errorf("overwritten output = %q, want it restored", data)
+	}
+	if got, _ := log.Get(rec.ID); got.Undone == nil {
+		t.Error("record not marked undone")
+	}
+	if _, err := log.Undo(rec.ID, true); err == nil {
+		t.Error("second Undo() succeeded")
+	}
+	if _, err := log.Get("zzz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(unknown) error = %v", err)
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	log, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "acid.mid")
+	output := filepath.Join(dir, "acid.seq")
+	writeFile(t, input, "midi")
+	newRecord := func() Record {
+		return Record{
+			Command: "convert",
+			Device:  "td3",
+			Input:   File{Path: input, Format: "midi"},
+			Outputs: []File{{Path: output, Format: "seq"}},
+		}
+	}
+
+	// Nothing recorded yet
+	if ok, err := log.UpToDate(newRecord()); !ok || err != nil {
+		t.Errorf("UpToDate() without a record = %v, %v", ok, err)
+	}
+	rec := newRecord()
+	if err := log.Record(&rec, func() error { writeFile(t, output, "seq"); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := log.UpToDate(newRecord()); !ok {
+		t.Error("UpToDate() right after the conversion = false")
+	}
+
+	other := newRecord()
+	other.Device = "x0xb0x"
+	if ok, _ := log.UpToDate(other); ok {
+		t.Error("UpToDate() for another device = true")
+	}
+	writeFile(t, output, "edited")
+	if ok, _ := log.UpToDate(newRecord()); ok {
+		t.Error("UpToDate() with an edited output = true")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
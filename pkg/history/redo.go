@@ -0,0 +1,151 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+// ParseSince parses the start of a time window relative to now: "today",
+// "yesterday", a duration such as "90m", "12h" or "7d", a date
+// (2006-01-02), or an RFC 3339 timestamp
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use today, yesterday, a duration like 12h or 7d, or a date like 2006-01-02", s)
+}
+
+// Latest returns the records made at or after since that were not undone,
+// newest first, keeping only the most recent conversion of each output set
+func Latest(records []Record, since time.Time) []Record {
+	var latest []Record
+	seen := make(map[string]bool)
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if rec.Time.Before(since) {
+			continue
+		}
+		key := outputsKey(rec)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if rec.Undone == nil {
+			latest = append(latest, rec)
+		}
+	}
+	return latest
+}
+
+// InputChanged reports whether the record's input differs from when it was
+// converted
+func (r Record) InputChanged() (bool, error) {
+	sum, err := hashFile(r.Input.Path)
+	if err != nil {
+		return false, err
+	}
+	return sum != r.Input.Hash, nil
+}
+
+// Converter returns a converter for the record's target device and options
+func (r Record) Converter() (*converter.Converter, error) {
+	dev, ok := devices.Lookup(r.Device)
+	if !ok {
+		return nil, fmt.Errorf("unknown device %q", r.Device)
+	}
+	conv := converter.New(dev)
+	conv.SetOptions(r.Options)
+	return conv, nil
+}
+
+// Convert repeats the recorded conversion with conv (from Converter) and
+// returns the data of each output, in order. Migrations read the input with
+// their source device.
+func (r Record) Convert(conv *converter.Converter) ([][]byte, error) {
+	reader := conv
+	if r.From != "" {
+		from, ok := devices.Lookup(r.From)
+		if !ok {
+			return nil, fmt.Errorf("unknown source device %q", r.From)
+		}
+		reader = converter.New(from)
+	}
+	pattern, err := reader.ReadPatternFile(r.Input.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([][]byte, len(r.Outputs))
+	for i, out := range r.Outputs {
+		format := converter.Format(out.Format)
+		if format == converter.FormatUnknown || format == "" {
+			format = converter.DetectFormat(out.Path)
+		}
+		if outputs[i], err = conv.GeneratePattern(pattern, format); err != nil {
+			return nil, fmt.Errorf("conversion failed: %w", err)
+		}
+	}
+	return outputs, nil
+}
+
+// Rerun returns a new record for repeating r: same command, devices,
+// options, and files, without the results of the earlier run
+func (r Record) Rerun() Record {
+	rerun := Record{
+		Command: r.Command,
+		Device:  r.Device,
+		From:    r.From,
+		Options: r.Options,
+		Input:   File{Path: r.Input.Path, Format: r.Input.Format},
+	}
+	for _, out := range r.Outputs {
+		rerun.Outputs = append(rerun.Outputs, File{Path: out.Path, Format: out.Format})
+	}
+	return rerun
+}
+
+// Unchanged reports whether the output files already hold data, as
+// returned by Convert
+func (r Record) Unchanged(data [][]byte) bool {
+	for i, out := range r.Outputs {
+		current, err := os.ReadFile(out.Path)
+		if err != nil || !bytes.Equal(current, data[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func outputsKey(rec Record) string {
+	paths := make([]string, len(rec.Outputs))
+	for i, out := range rec.Outputs {
+		paths[i] = out.Path
+	}
+	return strings.Join(paths, "\x00")
+}
@@ -0,0 +1,118 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"today", time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{"Yesterday", time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"7d", time.Date(2024, 5, 3, 15, 30, 0, 0, time.UTC)},
+		{"2024-04-01", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-04-01T12:00:00Z", time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseSince(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "last week", "-3d"} {
+		if _, err := ParseSince(in, now); err == nil {
+			t.Errorf("ParseSince(%q) succeeded", in)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	day := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	undone := day
+	records := []Record{
+		{ID: "old", Time: day.Add(-time.Hour), Outputs: []File{{Path: "/a.seq"}}},
+		{ID: "a1", Time: day.Add(time.Hour), Outputs: []File{{Path: "/a.seq"}}},
+		{ID: "a2", Time: day.Add(2 * time.Hour), Outputs: []File{{Path: "/a.seq"}}},
+		{ID: "b", Time: day.Add(3 * time.Hour), Outputs: []File{{Path: "/b.seq"}}, Undone: &undone},
+	}
+	got := Latest(records, day)
+	if len(got) != 1 || got[0].ID != "a2" {
+		t.Errorf("Latest() = %+v, want only a2", got)
+	}
+}
+
+func TestRedo(t *testing.T) {
+	log, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "acid.seq")
+	output := filepath.Join(dir, "acid.syx")
+
+	td3 := converter.New(devices.NewTD3())
+	pattern := &converter.Pattern{Length: 16, Steps: make([]converter.Step, 16)}
+	pattern.Steps[0] = converter.Step{Note: 36, Gate: true, Velocity: 100}
+	writePattern(t, td3, pattern, input)
+
+	rec := &Record{
+		Command: "seq2syx",
+		Device:  "td3",
+		Input:   File{Path: input, Format: "seq"},
+		Outputs: []File{{Path: output, Format: "syx"}},
+	}
+	if err := log.Record(rec, func() error {
+		writePattern(t, td3, pattern, output)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := rec.InputChanged(); err != nil || changed {
+		t.Fatalf("InputChanged() = %v, %v before editing", changed, err)
+	}
+	conv, err := rec.Converter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := rec.Convert(conv)
+	if err != nil || !rec.Unchanged(data) {
+		t.Fatalf("Convert() of unchanged input: unchanged = %v, err = %v", rec.Unchanged(data), err)
+	}
+
+	pattern.Steps[1] = converter.Step{Note: 48, Gate: true, Accent: true, Velocity: 127}
+	writePattern(t, td3, pattern, input)
+	if changed, err := rec.InputChanged(); err != nil || !changed {
+		t.Fatalf("InputChanged() = %v, %v after editing", changed, err)
+	}
+	if data, err = rec.Convert(conv); err != nil || rec.Unchanged(data) {
+		t.Fatalf("Convert() of edited input: unchanged = %v, err = %v", rec.Unchanged(data), err)
+	}
+	got, err := td3.ParsePattern(data[0], converter.FormatSyx)
+	if err != nil || !got.Steps[1].Accent {
+		t.Errorf("Convert() = %+v, %v, want the edited step", got, err)
+	}
+
+	rerun := rec.Rerun()
+	if rerun.ID != "" || rerun.Input.Hash != "" || rerun.Outputs[0].Path != output || rerun.Device != "td3" {
+		t.Errorf("Rerun() = %+v", rerun)
+	}
+}
+
+func writePattern(t *testing.T, conv *converter.Converter, p *converter.Pattern, path string) {
+	t.Helper()
+	data, err := conv.GeneratePattern(p, converter.DetectFormat(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, string(data))
+}
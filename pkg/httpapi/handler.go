@@ -0,0 +1,232 @@
+// Package httpapi serves the converter endpoints with net/http alone, for
+// programs that embed the converter without the gin dependency of package
+// api. Both packages run conversions through package service, so the
+// endpoints behave the same.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// Options configures the handler
+type Options struct {
+	// Limits caps the work spent parsing uploads; nil means
+	// converter.DefaultLimits
+	Limits *converter.Limits
+}
+
+// handler serves the endpoints
+type handler struct {
+	limits converter.Limits
+}
+
+// NewHandler returns the converter endpoints as an http.Handler:
+//
+//	GET  /health, /livez
+//	GET  /api/v1/formats, /api/v1/devices, /api/v1/devices/{id}
+//	POST /api/v1/convert?to=seq,syx,midi,json
+//	POST /api/v1/convert/{from}2{to}, e.g. /api/v1/convert/midi2seq
+//
+// Mount it with http.StripPrefix to serve it under a path.
+func NewHandler(opts Options) http.Handler {
+	h := &handler{limits: converter.DefaultLimits()}
+	if opts.Limits != nil {
+		h.limits = *opts.Limits
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", h.health)
+	mux.HandleFunc("GET /livez", h.health)
+	mux.HandleFunc("GET /api/v1/health", h.health)
+	mux.HandleFunc("GET /api/v1/formats", h.formats)
+	mux.HandleFunc("GET /api/v1/devices", h.devices)
+	mux.HandleFunc("GET /api/v1/devices/{id}", h.device)
+	mux.HandleFunc("POST /api/v1/convert", h.convertMulti)
+	mux.HandleFunc("POST /api/v1/convert/{conversion}", h.convert)
+	return mux
+}
+
+func (h *handler) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "synthtribe2midi"})
+}
+
+func (h *handler) formats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{
+		"formats":     {"midi", "seq", "syx"},
+		"conversions": converter.GetSupportedConversions(),
+	})
+}
+
+func (h *handler) devices(w http.ResponseWriter, r *http.Request) {
+	list := []devices.Details{}
+	for _, info := range devices.List() {
+		list = append(list, info.Details())
+	}
+	writeJSON(w, http.StatusOK, map[string][]devices.Details{"devices": list})
+}
+
+func (h *handler) device(w http.ResponseWriter, r *http.Request) {
+	info, ok := devices.LookupInfo(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown device %q", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, info.Details())
+}
+
+// convert converts an upload between two formats, e.g. midi2seq
+func (h *handler) convert(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := strings.Cut(r.PathValue("conversion"), "2")
+	if !ok || !convertible(from) || !convertible(to) || from == to {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown conversion %q", r.PathValue("conversion")))
+		return
+	}
+	data, header, err := ReadUpload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := h.options(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := service.Convert(r.Context(), service.ConvertRequest{
+		Data:     data,
+		Filename: header.Filename,
+		From:     converter.Format(from),
+		To:       []converter.Format{converter.Format(to)},
+		Device:   r.URL.Query().Get("device"),
+		Options:  opts,
+		Limits:   h.limits,
+	})
+	if err != nil {
+		writeServiceError(w, err, RouteHint(to))
+		return
+	}
+	if resp.InputDeviceID != nil {
+		w.Header().Set("X-SysEx-Device-ID", strconv.Itoa(int(*resp.InputDeviceID)))
+	}
+	contentType := "application/octet-stream"
+	if to == string(converter.FormatMIDI) {
+		contentType = "audio/midi"
+	}
+	out := resp.Outputs[0]
+	writeFile(w, resp, out.Name, contentType, out.Data)
+}
+
+// convertMulti converts an upload to several formats at once, returning a zip
+func (h *handler) convertMulti(w http.ResponseWriter, r *http.Request) {
+	var formats []converter.Format
+	withReport := false
+	for _, name := range strings.Split(r.URL.Query().Get("to"), ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+		case "json":
+			withReport = true
+		case "seq", "syx", "midi", "mid":
+			formats = append(formats, service.ParseFormat(name))
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q (use seq, syx, midi, or json)", name))
+			return
+		}
+	}
+	if len(formats) == 0 && !withReport {
+		writeError(w, http.StatusBadRequest, "to must list at least one format (seq, syx, midi, json)")
+		return
+	}
+	data, header, err := ReadUpload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fromParam, _ := Param(r, "from")
+	from, err := ParseFrom(fromParam)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := h.options(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := service.Convert(r.Context(), service.ConvertRequest{
+		Data:     data,
+		Filename: header.Filename,
+		From:     from,
+		To:       formats,
+		Device:   r.URL.Query().Get("device"),
+		Options:  opts,
+		Limits:   h.limits,
+	})
+	if err != nil {
+		writeServiceError(w, err, FromHint(r))
+		return
+	}
+	zipped, err := resp.Zip(header.Filename, withReport)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeFile(w, resp, service.BaseName(header.Filename)+".zip", "application/zip", zipped)
+}
+
+// options reads the conversion options from the query string and form
+func (h *handler) options(r *http.Request) (converter.ConvertOptions, error) {
+	return service.ParseOptions(func(name string) (string, bool) { return Param(r, name) })
+}
+
+func convertible(format string) bool {
+	switch converter.Format(format) {
+	case converter.FormatMIDI, converter.FormatSeq, converter.FormatSyx:
+		return true
+	}
+	return false
+}
+
+// writeFile sends a converted file with the conversion's warnings
+func writeFile(w http.ResponseWriter, resp *service.ConvertResponse, name, contentType string, data []byte) {
+	if warnings := resp.WarningStrings(); len(warnings) > 0 {
+		w.Header().Set("X-Conversion-Warnings", strings.Join(warnings, "; "))
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+name)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends an error body shaped like package api's
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeServiceError sends a conversion error, with where parsing stopped as
+// parse_error like package api
+func writeServiceError(w http.ResponseWriter, err error, hint func(actual converter.Format) string) {
+	status, msg := ServiceError(err, hint)
+	var perr *converter.ParseError
+	if errors.As(err, &perr) {
+		writeJSON(w, status, map[string]any{"error": msg, "parse_error": perr})
+		return
+	}
+	writeError(w, status, msg)
+}
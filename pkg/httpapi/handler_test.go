@@ -0,0 +1,133 @@
+package httpapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(Options{})
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{Length: 16, Steps: []converter.Step{{Note: 45, Gate: true, Velocity: 100}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(path, filename string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("file", filename)
+		_, _ = part.Write(data)
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, path, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/api/v1/convert/seq2midi?transpose=12", "line.seq", seq)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "audio/midi" || w.Header().Get("Content-Disposition") != "attachment; filename=line.mid" {
+		t.Fatalf("seq2midi = %d %v: %s", w.Code, w.Header(), w.Body)
+	}
+	if p, err := converter.NewMIDIConverter().ParseMIDI(w.Body.Bytes()); err != nil || p.Steps[0].Note != 57 {
+		t.Errorf("converted MIDI = %v, want the transposed note 57", err)
+	}
+
+	w = post("/api/v1/convert?to=syx,json", "line.seq", seq)
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if w.Code != http.StatusOK || err != nil || len(zr.File) != 2 || zr.File[1].Name != "line.json" {
+		t.Fatalf("convert to syx,json = %d, %v", w.Code, err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		data    []byte
+		want    int
+		wantMsg string
+	}{
+		{"empty", "/api/v1/convert/midi2syx", nil, http.StatusUnprocessableEntity, "the uploaded file is empty"},
+		{"mismatch", "/api/v1/convert/syx2midi", []byte("MThd\x00\x00\x00\x06"), http.StatusUnprocessableEntity, "this looks like a MIDI file, not a SysEx dump"},
+		{"unknown conversion", "/api/v1/convert/wav2seq", seq, http.StatusNotFound, `unknown conversion "wav2seq"`},
+		{"same format", "/api/v1/convert/seq2seq", seq, http.StatusNotFound, `unknown conversion "seq2seq"`},
+		{"bad option", "/api/v1/convert/seq2midi?channel=17", seq, http.StatusBadRequest, "channel must be an integer between 1 and 16"},
+		{"unknown device", "/api/v1/convert/seq2midi?device=tb303", seq, http.StatusBadRequest, `unknown device "tb303"`},
+		{"bad target", "/api/v1/convert?to=wav", seq, http.StatusBadRequest, `unknown format "wav" (use seq, syx, midi, or json)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(tt.path, "upload.bin", tt.data)
+			var resp map[string]string
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			if w.Code != tt.want || resp["error"] != tt.wantMsg {
+				t.Errorf("POST %s = %d %q, want %d %q", tt.path, w.Code, resp["error"], tt.want, tt.wantMsg)
+			}
+		})
+	}
+
+	// A damaged file is reported with where parsing stopped
+	w = post("/api/v1/convert/seq2midi", "line.seq", seq[:50])
+	var resp struct {
+		Error      string                `json:"error"`
+		ParseError *converter.ParseError `json:"parse_error"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusBadRequest || resp.ParseError == nil || resp.ParseError.Offset != 50 || resp.ParseError.Field != "pattern data" {
+		t.Errorf("truncated seq2midi = %d %s", w.Code, w.Body)
+	}
+
+	// A JSON upload with a data URI, named after its media type
+	body, _ := json.Marshal(map[string]string{"file": "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(seq)})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/convert?to=midi&from=seq", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("JSON upload = %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/devices/td3", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/devices/td3 = %d", w.Code)
+	}
+}
+
+func TestDecodeContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		want      string
+		mediaType string
+		wantErr   bool
+	}{
+		{"base64", "TVRoZA==", "MThd", "", false},
+		{"unpadded", "TVRoZA", "MThd", "", false},
+		{"url-safe", "-_8", "\xfb\xff", "", false},
+		{"line breaks", "TVRo\nZA==", "MThd", "", false},
+		{"data URI", "data:audio/midi;base64,TVRoZA==", "MThd", "audio/midi", false},
+		{"data URI with parameters", "data:text/plain;charset=utf-8;base64,RjAgRjc=", "F0 F7", "text/plain", false},
+		{"percent-encoded data URI", "data:,F0%2000%20F7", "F0 00 F7", "text/plain", false},
+		{"data URI without data", "data:audio/midi;base64", "", "", true},
+		{"not base64", "not base64!", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, mediaType, err := DecodeContent(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (string(got) != tt.want || mediaType != tt.mediaType) {
+				t.Errorf("DecodeContent() = %q, %q, want %q, %q", got, mediaType, tt.want, tt.mediaType)
+			}
+		})
+	}
+}
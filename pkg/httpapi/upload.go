@@ -0,0 +1,230 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// MaxMemory is the part of a multipart upload held in memory; the rest
+// goes to temporary files
+const MaxMemory = 32 << 20
+
+// MaxJSONBody is the largest JSON upload body, room for a MaxMemory file
+// in base64
+const MaxJSONBody = MaxMemory * 3 / 2
+
+// ReadUpload reads the pattern file sent in the "file" field of a multipart
+// request, or of a JSON body (see readJSONUpload). Its errors say what went
+// wrong and are meant for the client.
+func ReadUpload(r *http.Request) ([]byte, *multipart.FileHeader, error) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		return readJSONUpload(r)
+	}
+	if err := r.ParseMultipartForm(MaxMemory); err != nil {
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			return nil, nil, errors.New(`upload the pattern as multipart/form-data in a "file" field, or as JSON with the file base64-encoded in "file"`)
+		}
+		return nil, nil, fmt.Errorf("Failed to read upload: %w", err)
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		if fields := slices.Sorted(maps.Keys(r.MultipartForm.File)); len(fields) > 0 {
+			return nil, nil, fmt.Errorf(`no "file" field; the upload is in %q, send it as "file"`, fields[0])
+		}
+		return nil, nil, errors.New("No file uploaded")
+	}
+
+	f, err := files[0].Open()
+	if err != nil {
+		return nil, nil, errors.New("Failed to read file")
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, errors.New("Failed to read file")
+	}
+	return data, files[0], nil
+}
+
+// readJSONUpload reads an upload sent as a JSON object, for clients that
+// cannot build multipart requests: "file" holds the file base64-encoded or
+// as a data URI, and "filename" its name, used like that of a multipart
+// upload. Other string, number, and boolean fields are request parameters
+// like form fields (see Param).
+func readJSONUpload(r *http.Request) ([]byte, *multipart.FileHeader, error) {
+	var body map[string]any
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, MaxJSONBody)).Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("Failed to read JSON upload: %w", err)
+	}
+	content, _ := body["file"].(string)
+	if content == "" {
+		return nil, nil, errors.New(`no "file" field; send the file base64-encoded or as a data URI in "file"`)
+	}
+	data, mediaType, err := DecodeContent(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf(`invalid "file": %w`, err)
+	}
+	name, _ := body["filename"].(string)
+	if name == "" {
+		name = "upload" + mediaTypeExt(mediaType)
+	}
+
+	r.PostForm = url.Values{}
+	for key, v := range body {
+		switch v := v.(type) {
+		case string:
+			if key != "file" && key != "filename" {
+				r.PostForm.Set(key, v)
+			}
+		case float64:
+			r.PostForm.Set(key, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			r.PostForm.Set(key, strconv.FormatBool(v))
+		}
+	}
+	return data, &multipart.FileHeader{Filename: name, Size: int64(len(data))}, nil
+}
+
+// DecodeContent decodes file content sent as text: a data URI
+// ("data:audio/midi;base64,TVRoZ..."), whose media type is returned, or
+// plain base64 in the standard or URL-safe alphabet, padded or not
+func DecodeContent(content string) ([]byte, string, error) {
+	rest, ok := strings.CutPrefix(content, "data:")
+	if !ok {
+		data, err := decodeBase64(content)
+		return data, "", err
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil, "", errors.New("data URI has no comma before the data")
+	}
+	mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if mediaType, _, _ = strings.Cut(mediaType, ";"); mediaType == "" {
+		mediaType = "text/plain"
+	}
+	if !isBase64 {
+		data, err := url.PathUnescape(payload)
+		return []byte(data), mediaType, err
+	}
+	data, err := decodeBase64(payload)
+	return data, mediaType, err
+}
+
+// decodeBase64 decodes base64 in any of the usual alphabets and paddings,
+// ignoring line breaks
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, s)
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	data, err := enc.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, errors.New("not valid base64")
+	}
+	return data, nil
+}
+
+// mediaTypeExt returns the file extension of a pattern file media type, or
+// "" to detect the format from the content
+func mediaTypeExt(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "audio/midi", "audio/mid", "audio/x-midi":
+		return ".mid"
+	case "application/x-sysex", "audio/x-sysex", "application/sysex":
+		return ".syx"
+	}
+	return ""
+}
+
+// Param returns a request parameter from the query string or, failing
+// that, the parsed form
+func Param(r *http.Request, name string) (string, bool) {
+	if v := r.URL.Query().Get(name); v != "" {
+		return v, true
+	}
+	if v := r.PostFormValue(name); v != "" {
+		return v, true
+	}
+	return "", false
+}
+
+// ParseFrom returns the from parameter naming the format of an upload, or
+// "" to detect it from the file extension, then the content
+func ParseFrom(from string) (converter.Format, error) {
+	if from == "" {
+		return "", nil
+	}
+	if format := service.ParseFormat(from); format != converter.FormatUnknown {
+		return format, nil
+	}
+	return converter.FormatUnknown, fmt.Errorf("unknown from format %q (use seq, syx, midi, or 303)", from)
+}
+
+// FromHint suggests repeating the request with from set to the format the
+// upload looks like
+func FromHint(r *http.Request) func(actual converter.Format) string {
+	return func(actual converter.Format) string {
+		q := r.URL.Query()
+		q.Set("from", string(actual))
+		return r.URL.Path + "?" + q.Encode()
+	}
+}
+
+// RouteHint suggests the fixed conversion endpoint to use for an upload
+// that looks like actual, or "" if it is already in the to format
+func RouteHint(to string) func(actual converter.Format) string {
+	return func(actual converter.Format) string {
+		switch {
+		case string(actual) == to:
+			return ""
+		case actual.InputOnly():
+			return "/api/v1/convert?from=" + string(actual) + "&to=" + to
+		}
+		return "/api/v1/convert/" + string(actual) + "2" + to
+	}
+}
+
+// ServiceError returns the status and client message of a conversion
+// error: 400 for bad requests and unparsable input, 422 for rejected input,
+// 500 otherwise. An upload in another format than declared gets a pointer
+// to the request to use, from hint (which may be nil or return "").
+func ServiceError(err error, hint func(actual converter.Format) string) (int, string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrRejectedInput):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrInvalidRequest), errors.Is(err, service.ErrInvalidInput):
+		status = http.StatusBadRequest
+	}
+	msg := err.Error()
+	if errors.Is(err, service.ErrEmpty) {
+		msg = "the uploaded file is empty"
+	}
+	var ferr *service.FormatError
+	if errors.As(err, &ferr) && ferr.Actual != converter.FormatUnknown && hint != nil {
+		if use := hint(ferr.Actual); use != "" {
+			msg += "; use " + use
+		}
+	}
+	return status, msg
+}
@@ -0,0 +1,366 @@
+package library
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/klauspost/compress/zstd"
+)
+
+// archiveVersion is the manifest format version written by Export
+const archiveVersion = 1
+
+// manifest is the archive's metadata file, written before the pattern data
+type manifest struct {
+	Version     int          `json:"version"`
+	Exported    time.Time    `json:"exported"`
+	Entries     []Entry      `json:"entries"`
+	Collections []Collection `json:"collections,omitempty"`
+}
+
+// ImportStats summarizes an archive import
+type ImportStats struct {
+	Added       int
+	Merged      int
+	Collections int
+}
+
+// Export writes the whole library (pattern files and their earlier
+// versions, metadata, tags, and collections) to w as a tar archive
+func (l *Library) Export(w io.Writer) error {
+	entries, err := l.List(Filter{})
+	if err != nil {
+		return err
+	}
+	colls, err := l.Collections()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	m, err := json.MarshalIndent(manifest{
+		Version:     archiveVersion,
+		Exported:    now,
+		Entries:     entries,
+		Collections: colls,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeTarFile(tw, "manifest.json", m, now); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		data, err := l.store.ReadData(e.ID)
+		if err != nil {
+			return err
+		}
+		if err := writeTarFile(tw, "patterns/"+e.ID+".dat", data, e.Updated); err != nil {
+			return err
+		}
+	}
+
+	// Earlier versions, once each since patterns can share content
+	written := make(map[string]bool)
+	for _, e := range entries {
+		for _, v := range e.Versions {
+			if v.Hash == e.Hash || written[v.Hash] {
+				continue
+			}
+			data, err := l.store.ReadData(v.Hash)
+			if err != nil {
+				return err
+			}
+			if err := writeTarFile(tw, "versions/"+v.Hash+".dat", data, v.Saved); err != nil {
+				return err
+			}
+			written[v.Hash] = true
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, mod time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: mod,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// Import merges a tar archive written by Export into the library. Patterns
+// already present keep their metadata but gain the archive's tags, and
+// collections gain any patterns they are missing.
+func (l *Library) Import(r io.Reader) (ImportStats, error) {
+	m, data, err := readArchive(r, nil)
+	if err != nil {
+		return ImportStats{}, err
+	}
+	return l.merge(m, data)
+}
+
+// readArchive reads the manifest and pattern data of a tar archive from r,
+// keyed by pattern ID or, for earlier versions, by hash. If whole is the entire uncompressed archive, r is ignored and pattern data
+// is sliced from whole rather than copied.
+func readArchive(r io.Reader, whole []byte) (*manifest, map[string][]byte, error) {
+	var m *manifest
+	data := make(map[string][]byte)
+
+	var br *bytes.Reader
+	if whole != nil {
+		br = bytes.NewReader(whole)
+		r = br
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		switch {
+		case hdr.Name == "manifest.json":
+			m = &manifest{}
+			if err := json.NewDecoder(tr).Decode(m); err != nil {
+				return nil, nil, fmt.Errorf("invalid archive manifest: %w", err)
+			}
+			if m.Version > archiveVersion {
+				return nil, nil, fmt.Errorf("archive version %d is newer than supported (%d)", m.Version, archiveVersion)
+			}
+		case strings.HasPrefix(hdr.Name, "patterns/"), strings.HasPrefix(hdr.Name, "versions/"):
+			id := strings.TrimSuffix(path.Base(hdr.Name), ".dat")
+			// A regular file's data follows its header, which the tar
+			// reader has just consumed
+			if br != nil && hdr.Typeflag == tar.TypeReg && hdr.Size <= int64(br.Len()) {
+				start, end := len(whole)-br.Len(), len(whole)-br.Len()+int(hdr.Size)
+				data[id] = whole[start:end:end]
+				continue
+			}
+			if data[id], err = io.ReadAll(tr); err != nil {
+				return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+			}
+		}
+	}
+	if m == nil {
+		return nil, nil, errors.New("not a library archive: missing manifest.json")
+	}
+	return m, data, nil
+}
+
+// merge adds the patterns and collections of an archive to the library
+func (l *Library) merge(m *manifest, data map[string][]byte) (ImportStats, error) {
+	var stats ImportStats
+	for _, e := range m.Entries {
+		if !validID(&e) {
+			return stats, fmt.Errorf("archive pattern %q has an invalid ID", e.ID)
+		}
+		d, ok := data[e.ID]
+		if !ok {
+			return stats, fmt.Errorf("archive is missing data for pattern %s", e.ID)
+		}
+		sum := sha256.Sum256(d)
+		if hex.EncodeToString(sum[:]) != e.Hash {
+			return stats, fmt.Errorf("pattern %s data does not match its hash", e.ID)
+		}
+
+		existing, err := l.store.Entry(e.ID)
+		if errors.Is(err, ErrNotFound) {
+			entry := e
+			if entry.Analysis == nil {
+				l.analyze(&entry, d)
+			}
+			if err := l.store.WriteData(entry.ID, d); err != nil {
+				return stats, err
+			}
+			if err := l.importVersions(&entry, data); err != nil {
+				return stats, err
+			}
+			if err := l.store.SaveEntry(&entry); err != nil {
+				return stats, err
+			}
+			stats.Added++
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		if _, err := l.Tag(existing.ID, e.Tags...); err != nil {
+			return stats, err
+		}
+		if existing.Rating == 0 && e.Rating > 0 {
+			if _, err := l.Rate(existing.ID, e.Rating); err != nil {
+				return stats, err
+			}
+		}
+		// Keep the earliest known creation time
+		if created := e.CreatedAt(); created.Before(existing.CreatedAt()) {
+			if _, err := l.update(existing.ID, func(x *Entry) error {
+				x.Created = created
+				return nil
+			}); err != nil {
+				return stats, err
+			}
+		}
+		stats.Merged++
+	}
+
+	for _, c := range m.Collections {
+		if len(c.Patterns) == 0 {
+			continue
+		}
+		if _, err := l.AddToCollection(c.Name, c.Patterns...); err != nil {
+			return stats, err
+		}
+		stats.Collections++
+	}
+	return stats, nil
+}
+
+// validID reports whether an entry's ID is the one add gives it: the start
+// of the hash of the content it was first added with. Archive IDs name store
+// files, so anything else could reach outside the library.
+func validID(e *Entry) bool {
+	first := e.versions()[0].Hash
+	if len(e.ID) != 12 || len(first) < 12 || e.ID != first[:12] {
+		return false
+	}
+	_, err := hex.DecodeString(e.ID)
+	return err == nil && strings.ToLower(e.ID) == e.ID
+}
+
+// importVersions stores the earlier versions of an imported pattern
+func (l *Library) importVersions(e *Entry, data map[string][]byte) error {
+	for _, v := range e.Versions {
+		if v.Hash == e.Hash {
+			continue
+		}
+		d, ok := data[v.Hash]
+		if !ok {
+			return fmt.Errorf("archive is missing version %s of pattern %s", v.ShortHash(), e.ID)
+		}
+		if sum := sha256.Sum256(d); hex.EncodeToString(sum[:]) != v.Hash {
+			return fmt.Errorf("version %s of pattern %s does not match its hash", v.ShortHash(), e.ID)
+		}
+		if err := l.store.WriteData(v.Hash, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Magic numbers used to detect archive compression on import
+var (
+	gzipMagic = []byte{0x1F, 0x8B}
+	zstdMagic = []byte{0x28, 0xB5, 0x2F, 0xFD}
+)
+
+// ExportFile writes a library archive to path, compressed according to its
+// extension: .tar.zst/.tzst (zstd), .tar.gz/.tgz (gzip), or plain .tar
+func (l *Library) ExportFile(p string) error {
+	name := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(name, ".zst") || strings.HasSuffix(name, ".tzst"):
+		return converter.WriteAtomic(p, 0644, func(w io.Writer) error {
+			zw, err := zstd.NewWriter(w)
+			if err != nil {
+				return err
+			}
+			if err := l.Export(zw); err != nil {
+				return err
+			}
+			return zw.Close()
+		})
+	case strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz"):
+		return converter.WriteAtomic(p, 0644, func(w io.Writer) error {
+			gw := gzip.NewWriter(w)
+			if err := l.Export(gw); err != nil {
+				return err
+			}
+			return gw.Close()
+		})
+	case strings.HasSuffix(name, ".tar"):
+		return converter.WriteAtomic(p, 0644, l.Export)
+	default:
+		return fmt.Errorf("unsupported archive extension %q (use .tar.zst, .tar.gz, or .tar)", path.Ext(p))
+	}
+}
+
+// ImportFile imports a library archive, detecting its compression from content
+func (l *Library) ImportFile(p string) (ImportStats, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return ImportStats{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	br := bufio.NewReader(f)
+	head, _ := br.Peek(4)
+
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return ImportStats{}, err
+		}
+		defer zr.Close()
+		r = zr
+	case bytes.HasPrefix(head, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return ImportStats{}, err
+		}
+		defer func() { _ = gr.Close() }()
+		r = gr
+	}
+	return l.Import(r)
+}
+
+// ImportFileMapped imports a library archive like ImportFile, but
+// memory-maps it (see converter.MapFile): an uncompressed .tar is then read
+// in place, each pattern paged in from disk as it is stored rather than the
+// whole archive held in RAM. Compressed archives are streamed as by
+// ImportFile.
+func (l *Library) ImportFileMapped(p string) (ImportStats, error) {
+	f, err := converter.MapFile(p)
+	if err != nil {
+		return ImportStats{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	data := f.Bytes()
+	if bytes.HasPrefix(data, zstdMagic) || bytes.HasPrefix(data, gzipMagic) {
+		return l.ImportFile(p)
+	}
+	m, items, err := readArchive(nil, data)
+	if err != nil {
+		return ImportStats{}, err
+	}
+	return l.merge(m, items)
+}
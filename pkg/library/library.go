@@ -0,0 +1,569 @@
+// Package library provides a pattern library with tags, ratings, and
+// collections on top of pluggable storage backends
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+)
+
+// ErrNotFound is returned when a pattern or collection does not exist
+var ErrNotFound = errors.New("not found")
+
+// MaxRating is the highest rating a pattern can be given
+const MaxRating = 5
+
+// Entry holds the metadata of a stored pattern
+type Entry struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Format  string    `json:"format"`
+	Device  string    `json:"device,omitempty"`
+	Hash    string    `json:"hash"`
+	Tags    []string  `json:"tags,omitempty"`
+	Rating  int       `json:"rating,omitempty"`
+	Added   time.Time `json:"added"`
+	Updated time.Time `json:"updated"`
+	// Created is when the pattern was made, e.g. the modification time of
+	// the file it was imported from; it defaults to Added
+	Created time.Time `json:"created,omitzero"`
+
+	// Analysis holds features computed when the pattern entered the library
+	Analysis *analysis.Result `json:"analysis,omitempty"`
+
+	// Slots records where the pattern was last synced to hardware
+	Slots []SlotRecord `json:"slots,omitempty"`
+
+	// Versions lists every content of a pattern that was updated, oldest
+	// first; the last is the current one
+	Versions []Version `json:"versions,omitempty"`
+
+	// Kind is KindPatch for stored patch dumps and empty for patterns
+	Kind string `json:"kind,omitempty"`
+	// Source identifies the sender of a patch dump, e.g. "Behringer Pro-800"
+	Source string `json:"source,omitempty"`
+}
+
+// Entry kinds, as used by Filter.Kind
+const (
+	KindPattern = "pattern"
+	KindPatch   = "patch"
+)
+
+// IsPatch reports whether the entry is a patch dump rather than a pattern
+func (e *Entry) IsPatch() bool {
+	return e.Kind == KindPatch
+}
+
+// CreatedAt returns when the pattern was made, falling back to when it was
+// added for entries stored before creation times were recorded
+func (e *Entry) CreatedAt() time.Time {
+	if e.Created.IsZero() {
+		return e.Added
+	}
+	return e.Created
+}
+
+// HasTag reports whether the entry carries the given tag
+func (e *Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Collection is a named, ordered list of patterns (e.g. "live set A")
+type Collection struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+}
+
+// Store is a library storage backend
+type Store interface {
+	Entries() ([]Entry, error)
+	Entry(id string) (*Entry, error)
+	SaveEntry(e *Entry) error
+	DeleteEntry(id string) error
+	ReadData(id string) ([]byte, error)
+	WriteData(id string, data []byte) error
+	Collections() ([]Collection, error)
+	SaveCollection(c *Collection) error
+	DeleteCollection(name string) error
+}
+
+// Filter selects entries when listing the library
+type Filter struct {
+	Tag        string
+	MinRating  int
+	Collection string
+	Kind       string // KindPattern or KindPatch; empty lists both
+	Sort       string // SortName (default), SortCreated, or SortAdded
+}
+
+// Sort orders for Filter.Sort; dates sort oldest first
+const (
+	SortName    = "name"
+	SortCreated = "created"
+	SortAdded   = "added"
+)
+
+// Analyzer computes the features of pattern data in the given format and
+// for the given device
+type Analyzer func(format, device string, data []byte) (*analysis.Result, error)
+
+// Library manages patterns stored in a Store
+type Library struct {
+	store    Store
+	analyzer Analyzer
+	// mu serializes edits, which read an entry or collection, change it,
+	// and save it back: stores lock single calls only, so concurrent edits
+	// (API, daemon, MQTT) would otherwise lose each other's changes
+	mu sync.Mutex
+}
+
+// New creates a Library backed by the given store
+func New(store Store) *Library {
+	return &Library{store: store}
+}
+
+// Open opens (creating if necessary) a local library in dir; a directory
+// created by OpenText opens as a text library
+func Open(dir string) (*Library, error) {
+	if IsText(dir) {
+		return OpenText(dir)
+	}
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	return New(store), nil
+}
+
+// DefaultDir returns the default library location in the user config directory
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "synthtribe2midi", "library"), nil
+}
+
+// Check verifies that the library's storage can be read
+func (l *Library) Check() error {
+	_, err := l.store.Collections()
+	return err
+}
+
+// SetAnalyzer enables automatic analysis: patterns entering the library are
+// analyzed and tagged with their features (key, density, slides, ...)
+func (l *Library) SetAnalyzer(a Analyzer) {
+	l.analyzer = a
+}
+
+// Store returns the library's storage backend
+func (l *Library) Store() Store {
+	return l.store
+}
+
+// Add stores pattern data in the library. Patterns are identified by content,
+// so adding the same data twice returns the existing entry.
+func (l *Library) Add(name, format, device string, data []byte) (*Entry, error) {
+	return l.add(&Entry{Name: name, Format: format, Device: device}, data)
+}
+
+// AddCreated is Add for a pattern made at the given time, e.g. the
+// modification time of the file it comes from
+func (l *Library) AddCreated(name, format, device string, data []byte, created time.Time) (*Entry, error) {
+	return l.add(&Entry{Name: name, Format: format, Device: device, Created: created.UTC()}, data)
+}
+
+// add stores data under a new entry built from the template, or returns the
+// existing entry for identical data
+func (l *Library) add(entry *Entry, data []byte) (*Entry, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	id := hash[:12]
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if existing, err := l.store.Entry(id); err == nil {
+		return existing, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	entry.ID = id
+	entry.Hash = hash
+	entry.Added = now
+	entry.Updated = now
+	if entry.Created.IsZero() {
+		entry.Created = now
+	}
+	if !entry.IsPatch() {
+		l.analyze(entry, data)
+	}
+
+	if err := l.store.WriteData(id, data); err != nil {
+		return nil, err
+	}
+	if err := l.store.SaveEntry(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Get returns the entry with the given ID or unique ID prefix
+func (l *Library) Get(id string) (*Entry, error) {
+	if entry, err := l.store.Entry(id); err == nil || !errors.Is(err, ErrNotFound) {
+		return entry, err
+	}
+
+	entries, err := l.store.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var match *Entry
+	for i := range entries {
+		if strings.HasPrefix(entries[i].ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("pattern ID %q is ambiguous", id)
+			}
+			match = &entries[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("pattern %q: %w", id, ErrNotFound)
+	}
+	return match, nil
+}
+
+// Data returns the stored file data of a pattern
+func (l *Library) Data(id string) (*Entry, []byte, error) {
+	entry, err := l.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := l.store.ReadData(entry.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, data, nil
+}
+
+// List returns entries matching the filter, in collection order when
+// filtering by collection and in the filter's sort order otherwise
+func (l *Library) List(filter Filter) ([]Entry, error) {
+	entries, err := l.store.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Collection != "" {
+		coll, err := l.Collection(filter.Collection)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]Entry, len(entries))
+		for _, e := range entries {
+			byID[e.ID] = e
+		}
+		entries = entries[:0]
+		for _, id := range coll.Patterns {
+			if e, ok := byID[id]; ok {
+				entries = append(entries, e)
+			}
+		}
+	} else {
+		sort.SliceStable(entries, func(i, j int) bool {
+			a, b := &entries[i], &entries[j]
+			switch filter.Sort {
+			case SortCreated:
+				return a.CreatedAt().Before(b.CreatedAt())
+			case SortAdded:
+				return a.Added.Before(b.Added)
+			default:
+				return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+			}
+		})
+	}
+
+	var result []Entry
+	for _, e := range entries {
+		if filter.Tag != "" && !e.HasTag(filter.Tag) {
+			continue
+		}
+		if e.Rating < filter.MinRating {
+			continue
+		}
+		if filter.Kind != "" && e.IsPatch() != (filter.Kind == KindPatch) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// Remove deletes a pattern and drops it from every collection
+func (l *Library) Remove(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, err := l.Get(id)
+	if err != nil {
+		return err
+	}
+
+	colls, err := l.store.Collections()
+	if err != nil {
+		return err
+	}
+	for i := range colls {
+		if removeIDs(&colls[i], entry.ID) {
+			if err := l.store.SaveCollection(&colls[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return l.store.DeleteEntry(entry.ID)
+}
+
+// Reanalyze recomputes a pattern's features, replacing its previous
+// automatic tags. Patch dumps are returned unchanged.
+func (l *Library) Reanalyze(id string) (*Entry, error) {
+	if l.analyzer == nil {
+		return nil, errors.New("no analyzer configured")
+	}
+	entry, data, err := l.Data(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsPatch() {
+		return entry, nil
+	}
+	return l.update(entry.ID, func(e *Entry) error {
+		if e.Analysis != nil {
+			e.Tags = without(e.Tags, e.Analysis.Tags())
+		}
+		e.Analysis = nil
+		l.analyze(e, data)
+		return nil
+	})
+}
+
+// analyze runs the analyzer (if any) on the entry's data and adds the
+// resulting tags. Patterns that cannot be analyzed are stored without
+// features.
+func (l *Library) analyze(e *Entry, data []byte) {
+	if l.analyzer == nil {
+		return
+	}
+	result, err := l.analyzer(e.Format, e.Device, data)
+	if err != nil {
+		return
+	}
+	e.Analysis = result
+	for _, tag := range result.Tags() {
+		if !e.HasTag(tag) {
+			e.Tags = append(e.Tags, tag)
+		}
+	}
+	sort.Strings(e.Tags)
+}
+
+// Tag adds tags to a pattern
+func (l *Library) Tag(id string, tags ...string) (*Entry, error) {
+	return l.update(id, func(e *Entry) error {
+		for _, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			if tag != "" && !e.HasTag(tag) {
+				e.Tags = append(e.Tags, tag)
+			}
+		}
+		sort.Strings(e.Tags)
+		return nil
+	})
+}
+
+// Untag removes tags from a pattern
+func (l *Library) Untag(id string, tags ...string) (*Entry, error) {
+	return l.update(id, func(e *Entry) error {
+		e.Tags = without(e.Tags, tags)
+		return nil
+	})
+}
+
+// Rate sets a pattern's rating from 1 to 5; 0 clears the rating
+func (l *Library) Rate(id string, rating int) (*Entry, error) {
+	if rating < 0 || rating > MaxRating {
+		return nil, fmt.Errorf("rating must be between 0 and %d", MaxRating)
+	}
+	return l.update(id, func(e *Entry) error {
+		e.Rating = rating
+		return nil
+	})
+}
+
+// Rename changes a pattern's display name
+func (l *Library) Rename(id, name string) (*Entry, error) {
+	return l.update(id, func(e *Entry) error {
+		e.Name = name
+		return nil
+	})
+}
+
+// update applies fn to an entry and saves it
+func (l *Library) update(id string, fn func(e *Entry) error) (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.updateLocked(id, fn)
+}
+
+// updateLocked is update for callers already holding l.mu
+func (l *Library) updateLocked(id string, fn func(e *Entry) error) (*Entry, error) {
+	entry, err := l.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(entry); err != nil {
+		return nil, err
+	}
+	entry.Updated = time.Now().UTC()
+	if err := l.store.SaveEntry(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Collections returns all collections sorted by name
+func (l *Library) Collections() ([]Collection, error) {
+	colls, err := l.store.Collections()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(colls, func(i, j int) bool { return colls[i].Name < colls[j].Name })
+	return colls, nil
+}
+
+// Collection returns the named collection
+func (l *Library) Collection(name string) (*Collection, error) {
+	colls, err := l.store.Collections()
+	if err != nil {
+		return nil, err
+	}
+	for i := range colls {
+		if colls[i].Name == name {
+			return &colls[i], nil
+		}
+	}
+	return nil, fmt.Errorf("collection %q: %w", name, ErrNotFound)
+}
+
+// AddToCollection appends patterns to a collection, creating it if needed.
+// Patterns already in the collection keep their position.
+func (l *Library) AddToCollection(name string, ids ...string) (*Collection, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("collection name is required")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	coll, err := l.Collection(name)
+	if errors.Is(err, ErrNotFound) {
+		coll = &Collection{Name: name}
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		entry, err := l.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if !containsID(coll.Patterns, entry.ID) {
+			coll.Patterns = append(coll.Patterns, entry.ID)
+		}
+	}
+
+	if err := l.store.SaveCollection(coll); err != nil {
+		return nil, err
+	}
+	return coll, nil
+}
+
+// RemoveFromCollection removes patterns from a collection
+func (l *Library) RemoveFromCollection(name string, ids ...string) (*Collection, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	coll, err := l.Collection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		entry, err := l.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		removeIDs(coll, entry.ID)
+	}
+
+	if err := l.store.SaveCollection(coll); err != nil {
+		return nil, err
+	}
+	return coll, nil
+}
+
+// DeleteCollection deletes a collection; its patterns stay in the library
+func (l *Library) DeleteCollection(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.Collection(name); err != nil {
+		return err
+	}
+	return l.store.DeleteCollection(name)
+}
+
+// without returns tags minus the removed ones
+func without(tags, removed []string) []string {
+	var kept []string
+	for _, t := range tags {
+		if !containsID(removed, t) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func containsID(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeIDs removes id from the collection and reports whether it was present
+func removeIDs(c *Collection, id string) bool {
+	kept := c.Patterns[:0]
+	for _, existing := range c.Patterns {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	removed := len(kept) != len(c.Patterns)
+	c.Patterns = kept
+	return removed
+}
@@ -0,0 +1,609 @@
+package library
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestLibraryAddAndGet(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	entry, err := lib.Add("Acid 1", "seq", "td3", []byte{0x23, 0x98, 0x54, 0x76})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if len(entry.ID) != 12 {
+		t.Errorf("ID = %q, want 12 hex characters", entry.ID)
+	}
+
+	// Adding identical data returns the existing entry
+	again, err := lib.Add("Duplicate", "seq", "td3", []byte{0x23, 0x98, 0x54, 0x76})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if again.ID != entry.ID || again.Name != "Acid 1" {
+		t.Errorf("duplicate Add() = %+v, want existing entry", again)
+	}
+
+	got, data, err := lib.Data(entry.ID[:6])
+	if err != nil {
+		t.Fatalf("Data() by prefix error = %v", err)
+	}
+	if got.ID != entry.ID || len(data) != 4 {
+		t.Errorf("Data() = %+v, %d bytes", got, len(data))
+	}
+
+	if _, err := lib.Get("ffffff"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() unknown error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLibraryCreated(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	made := time.Date(2019, 3, 14, 21, 0, 0, 0, time.UTC)
+	old, err := lib.AddCreated("Zebra", "seq", "td3", []byte{1}, made)
+	if err != nil {
+		t.Fatalf("AddCreated() error = %v", err)
+	}
+	if !old.Created.Equal(made) || old.Added.Equal(made) {
+		t.Errorf("AddCreated() = created %v, added %v", old.Created, old.Added)
+	}
+	fresh, _ := lib.Add("Acid", "seq", "td3", []byte{2})
+	if !fresh.Created.Equal(fresh.Added) {
+		t.Errorf("Add() created = %v, want added time %v", fresh.Created, fresh.Added)
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"Acid", "Zebra"}},
+		{SortCreated, []string{"Zebra", "Acid"}},
+		{SortAdded, []string{"Zebra", "Acid"}},
+	}
+	for _, tt := range tests {
+		entries, err := lib.List(Filter{Sort: tt.sort})
+		if err != nil {
+			t.Fatalf("List(%q) error = %v", tt.sort, err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("List(sort %q) = %v, want %v", tt.sort, names, tt.want)
+		}
+	}
+
+	// Importing an older copy of a pattern keeps the earlier creation time
+	archive := filepath.Join(t.TempDir(), "old.tar")
+	src, _ := Open(t.TempDir())
+	_, _ = src.AddCreated("Acid", "seq", "td3", []byte{2}, made.AddDate(-1, 0, 0))
+	if err := src.ExportFile(archive); err != nil {
+		t.Fatalf("ExportFile() error = %v", err)
+	}
+	if _, err := lib.ImportFile(archive); err != nil {
+		t.Fatalf("ImportFile() error = %v", err)
+	}
+	if got, _ := lib.Get(fresh.ID); !got.CreatedAt().Equal(made.AddDate(-1, 0, 0)) {
+		t.Errorf("created after import = %v", got.CreatedAt())
+	}
+}
+
+func TestLibraryTagsAndRatings(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	a, _ := lib.Add("A", "seq", "td3", []byte{1})
+	b, _ := lib.Add("B", "seq", "td3", []byte{2})
+
+	if _, err := lib.Tag(a.ID, "acid", "dark", "acid"); err != nil {
+		t.Fatalf("Tag() error = %v", err)
+	}
+	if _, err := lib.Tag(b.ID, "acid"); err != nil {
+		t.Fatalf("Tag() error = %v", err)
+	}
+	if _, err := lib.Rate(a.ID, 5); err != nil {
+		t.Fatalf("Rate() error = %v", err)
+	}
+	if _, err := lib.Rate(a.ID, 6); err == nil {
+		t.Error("Rate(6) expected error")
+	}
+
+	entries, err := lib.List(Filter{Tag: "acid"})
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("List(tag) = %d entries, err %v; want 2", len(entries), err)
+	}
+
+	entries, _ = lib.List(Filter{Tag: "acid", MinRating: 4})
+	if len(entries) != 1 || entries[0].ID != a.ID {
+		t.Errorf("List(tag, rating) = %+v, want only A", entries)
+	}
+
+	updated, err := lib.Untag(a.ID, "dark")
+	if err != nil {
+		t.Fatalf("Untag() error = %v", err)
+	}
+	if len(updated.Tags) != 1 || updated.Tags[0] != "acid" {
+		t.Errorf("Tags after Untag() = %v, want [acid]", updated.Tags)
+	}
+}
+
+func TestLibraryConcurrentEdits(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	a, _ := lib.Add("A", "seq", "td3", []byte{1})
+
+	const edits = 20
+	var wg sync.WaitGroup
+	for i := range edits {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := lib.Tag(a.ID, fmt.Sprintf("tag%d", i)); err != nil {
+				t.Errorf("Tag() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := lib.AddToCollection("set", a.ID); err != nil {
+				t.Errorf("AddToCollection() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := lib.Get(a.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(got.Tags) != edits {
+		t.Errorf("Tags = %v, want all %d concurrent tags kept", got.Tags, edits)
+	}
+}
+
+func TestLibraryCollections(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	a, _ := lib.Add("A", "seq", "td3", []byte{1})
+	b, _ := lib.Add("B", "seq", "td3", []byte{2})
+	c, _ := lib.Add("C", "seq", "td3", []byte{3})
+
+	if _, err := lib.AddToCollection("live set A", c.ID, a.ID, c.ID); err != nil {
+		t.Fatalf("AddToCollection() error = %v", err)
+	}
+	if _, err := lib.AddToCollection("live set A", b.ID); err != nil {
+		t.Fatalf("AddToCollection() error = %v", err)
+	}
+
+	entries, err := lib.List(Filter{Collection: "live set A"})
+	if err != nil {
+		t.Fatalf("List(collection) error = %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if len(names) != 3 || names[0] != "C" || names[1] != "A" || names[2] != "B" {
+		t.Errorf("collection order = %v, want [C A B]", names)
+	}
+
+	// Removing a pattern drops it from collections
+	if err := lib.Remove(a.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	coll, err := lib.Collection("live set A")
+	if err != nil {
+		t.Fatalf("Collection() error = %v", err)
+	}
+	if len(coll.Patterns) != 2 {
+		t.Errorf("collection has %d patterns after Remove(), want 2", len(coll.Patterns))
+	}
+
+	if err := lib.DeleteCollection("live set A"); err != nil {
+		t.Fatalf("DeleteCollection() error = %v", err)
+	}
+	if _, err := lib.Collection("live set A"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Collection() after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLibrarySlots(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	a, _ := lib.Add("A", "seq", "td3", []byte{1})
+	b, _ := lib.Add("B", "seq", "td3", []byte{2})
+	if _, err := lib.AddToCollection("live", b.ID, a.ID); err != nil {
+		t.Fatalf("AddToCollection() error = %v", err)
+	}
+
+	assignments, err := lib.AssignSlots("live", 62, 64)
+	if err != nil {
+		t.Fatalf("AssignSlots() error = %v", err)
+	}
+	if len(assignments) != 2 || assignments[0].Entry.ID != b.ID || assignments[1].Slot != 63 {
+		t.Errorf("AssignSlots() = %+v", assignments)
+	}
+	if _, err := lib.AssignSlots("live", 63, 64); err == nil {
+		t.Error("AssignSlots() past the last slot should fail")
+	}
+
+	if _, err := lib.RecordSlot(a.ID, "TD-3", "1A1"); err != nil {
+		t.Fatalf("RecordSlot() error = %v", err)
+	}
+	// Writing B to the same slot evicts A's record
+	got, err := lib.RecordSlot(b.ID, "TD-3", "1A1")
+	if err != nil {
+		t.Fatalf("RecordSlot() error = %v", err)
+	}
+	if len(got.Slots) != 1 || got.Slots[0].Slot != "1A1" {
+		t.Errorf("B slots = %+v", got.Slots)
+	}
+	if a, _ = lib.Get(a.ID); len(a.Slots) != 0 {
+		t.Errorf("A slots = %+v, want evicted", a.Slots)
+	}
+	if e, r, err := lib.SlotEntry("TD-3", "1A1"); err != nil || e == nil || e.ID != b.ID || r.Hash != b.Hash {
+		t.Errorf("SlotEntry(1A1) = %+v, %+v, %v, want B's current version", e, r, err)
+	}
+	if e, _, err := lib.SlotEntry("TD-3", "1A2"); err != nil || e != nil {
+		t.Errorf("SlotEntry(1A2) = %+v, %v, want none", e, err)
+	}
+}
+
+func TestCompareSlot(t *testing.T) {
+	pattern := func(notes ...uint8) *converter.Pattern {
+		p := &converter.Pattern{Length: 16, Steps: make([]converter.Step, 16)}
+		for i, n := range notes {
+			p.Steps[i] = converter.Step{Note: n, Gate: true}
+		}
+		return p
+	}
+	old, edited, updated := pattern(36), pattern(36, 48), pattern(40)
+
+	tests := []struct {
+		name                    string
+		device, library, synced *converter.Pattern
+		want                    SlotState
+	}{
+		{"in sync", old, old, old, SlotInSync},
+		{"same change on both sides", edited, edited, old, SlotInSync},
+		{"library changed", old, updated, old, SlotLibraryChanged},
+		{"device edited", edited, old, old, SlotDeviceChanged},
+		{"both changed", edited, updated, old, SlotConflict},
+		{"never synced", edited, old, nil, SlotConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareSlot(devices.NewTD3(), tt.device, tt.library, tt.synced); got != tt.want {
+				t.Errorf("CompareSlot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := ValidateConflictPolicy(ConflictFork); err != nil {
+		t.Errorf("ValidateConflictPolicy(fork) error = %v", err)
+	}
+	if err := ValidateConflictPolicy("merge"); err == nil {
+		t.Error("ValidateConflictPolicy(merge) accepted")
+	}
+}
+
+func TestLibraryVersions(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	a, _ := lib.Add("A", "seq", "td3", []byte{1})
+	_, _ = lib.Tag(a.ID, "acid")
+
+	if _, err := lib.Update(a.ID, "syx", []byte{2}, "device edit"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	updated, err := lib.Update(a.ID, "syx", []byte{3}, "")
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.ID != a.ID || updated.Format != "syx" || !updated.HasTag("acid") {
+		t.Errorf("updated entry = %+v", updated)
+	}
+	if same, _ := lib.Update(a.ID, "syx", []byte{3}, ""); len(same.Versions) != 3 {
+		t.Errorf("Update() to the current content added a version: %+v", same.Versions)
+	}
+
+	_, versions, err := lib.History(a.ID)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(versions) != 3 || versions[0].Hash != updated.Hash || versions[1].Note != "device edit" || versions[2].Hash != a.Hash {
+		t.Fatalf("History() = %+v", versions)
+	}
+
+	_, v, data, err := lib.VersionData(a.ID, a.Hash[:8])
+	if err != nil || v.Format != "seq" || string(data) != "\x01" {
+		t.Errorf("VersionData(first) = %+v, %v, %v", v, data, err)
+	}
+	if _, _, _, err := lib.VersionData(a.ID, "ffffffff"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("VersionData(unknown) error = %v, want ErrNotFound", err)
+	}
+
+	// Reverting is a new version; nothing is lost
+	reverted, err := lib.Revert(a.ID, a.Hash[:8])
+	if err != nil {
+		t.Fatalf("Revert() error = %v", err)
+	}
+	if _, data, _ := lib.Data(a.ID); reverted.Hash != a.Hash || reverted.Format != "seq" || string(data) != "\x01" {
+		t.Errorf("reverted entry = %+v, data %v", reverted, data)
+	}
+	if len(reverted.Versions) != 4 || reverted.Versions[3].Note != "revert to "+a.Hash[:12] {
+		t.Errorf("versions after Revert() = %+v", reverted.Versions)
+	}
+	if _, err := lib.Revert(a.ID, a.Hash); err == nil {
+		t.Error("Revert() to the current version should fail")
+	}
+
+	// Versions travel with archives
+	archive := filepath.Join(t.TempDir(), "backup.tar")
+	if err := lib.ExportFile(archive); err != nil {
+		t.Fatalf("ExportFile() error = %v", err)
+	}
+	dst, _ := Open(t.TempDir())
+	if _, err := dst.ImportFile(archive); err != nil {
+		t.Fatalf("ImportFile() error = %v", err)
+	}
+	if _, _, data, err := dst.VersionData(a.ID, updated.Hash); err != nil || string(data) != "\x03" {
+		t.Errorf("imported VersionData() = %v, %v", data, err)
+	}
+}
+
+func TestTextStore(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := OpenText(dir)
+	if err != nil {
+		t.Fatalf("OpenText() error = %v", err)
+	}
+	lib.Store().(*TextStore).SetDecoder(func(format, device string, data []byte) (*converter.Pattern, error) {
+		return &converter.Pattern{Length: 2, Steps: []converter.Step{{Note: 45, Gate: true, Accent: true}, {}}}, nil
+	})
+
+	data := make([]byte, 20)
+	data[17] = 0xab
+	a, err := lib.Add("Acid", "seq", "td3", data)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := lib.AddToCollection("set", a.ID); err != nil {
+		t.Fatalf("AddToCollection() error = %v", err)
+	}
+
+	file, err := os.ReadFile(filepath.Join(dir, "patterns", a.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"name": "Acid"`, `"1: A2 accent"`, `"2: rest"`, `"00 ab 00 00"`} {
+		if !strings.Contains(string(file), want) {
+			t.Errorf("pattern file lacks %s:\n%s", want, file)
+		}
+	}
+
+	// The directory reopens as a text library
+	if !IsText(dir) {
+		t.Fatal("IsText() = false")
+	}
+	lib, _ = Open(dir)
+	if _, ok := lib.Store().(*TextStore); !ok {
+		t.Fatalf("Open() store = %T, want *TextStore", lib.Store())
+	}
+	if _, got, err := lib.Data(a.ID[:6]); err != nil || !reflect.DeepEqual(got, data) {
+		t.Errorf("Data() = %v, %v, want %v", got, err, data)
+	}
+	if colls, _ := lib.Collections(); len(colls) != 1 || colls[0].Patterns[0] != a.ID {
+		t.Errorf("Collections() = %+v", colls)
+	}
+
+	// Earlier versions are files of their own
+	if _, err := lib.Update(a.ID, "syx", []byte{1, 2}, "edit"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, _, old, err := lib.VersionData(a.ID, a.Hash); err != nil || !reflect.DeepEqual(old, data) {
+		t.Errorf("VersionData() = %v, %v", old, err)
+	}
+	if list, _ := lib.List(Filter{}); len(list) != 1 || list[0].Format != "syx" {
+		t.Errorf("List() = %+v", list)
+	}
+
+	if err := lib.Remove(a.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := lib.Get(a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Remove() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLibraryExportImport(t *testing.T) {
+	src, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	a, _ := src.Add("A", "seq", "td3", []byte{1, 2, 3})
+	b, _ := src.Add("B", "syx", "td3", []byte{4, 5, 6})
+	_, _ = src.Tag(a.ID, "acid")
+	_, _ = src.Rate(b.ID, 3)
+	_, _ = src.AddToCollection("live", b.ID, a.ID)
+
+	imports := map[string]func(*Library, string) (ImportStats, error){
+		"ImportFile":       (*Library).ImportFile,
+		"ImportFileMapped": (*Library).ImportFileMapped,
+	}
+	for _, name := range []string{"backup.tar.zst", "backup.tgz", "backup.tar"} {
+		for importName, importFile := range imports {
+			t.Run(name+"/"+importName, func(t *testing.T) {
+				archive := filepath.Join(t.TempDir(), name)
+				if err := src.ExportFile(archive); err != nil {
+					t.Fatalf("ExportFile() error = %v", err)
+				}
+
+				dst, err := Open(t.TempDir())
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				// An existing copy of A is merged rather than duplicated
+				_, _ = dst.Add("Local A", "seq", "td3", []byte{1, 2, 3})
+
+				stats, err := importFile(dst, archive)
+				if err != nil {
+					t.Fatalf("%s() error = %v", importName, err)
+				}
+				if stats != (ImportStats{Added: 1, Merged: 1, Collections: 1}) {
+					t.Errorf("stats = %+v", stats)
+				}
+
+				gotA, _ := dst.Get(a.ID)
+				if gotA.Name != "Local A" || !gotA.HasTag("acid") {
+					t.Errorf("merged A = %+v", gotA)
+				}
+				entries, err := dst.List(Filter{Collection: "live"})
+				if err != nil || len(entries) != 2 || entries[0].ID != b.ID || entries[0].Rating != 3 {
+					t.Errorf("collection after import = %+v, %v", entries, err)
+				}
+			})
+		}
+	}
+}
+
+func TestLibraryImportRejectsCraftedID(t *testing.T) {
+	data := []byte{1, 2, 3}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	for _, id := range []string{`..\..\x`, "../../x", hash[:11] + "g", strings.ToUpper(hash[:12]), hash[1:13]} {
+		t.Run(id, func(t *testing.T) {
+			m, _ := json.Marshal(manifest{Version: archiveVersion, Entries: []Entry{{ID: id, Name: "X", Format: "seq", Hash: hash}}})
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			_ = writeTarFile(tw, "manifest.json", m, time.Now())
+			_ = writeTarFile(tw, "patterns/"+id+".dat", data, time.Now())
+			_ = tw.Close()
+
+			dir := t.TempDir()
+			lib, err := Open(dir)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if _, err := lib.Import(&buf); err == nil || !strings.Contains(err.Error(), "invalid ID") {
+				t.Errorf("Import() error = %v, want an invalid ID error", err)
+			}
+			if entries, _ := lib.List(Filter{}); len(entries) != 0 {
+				t.Errorf("Import() added %+v", entries)
+			}
+		})
+	}
+}
+
+func TestLibraryAutoTagging(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	result := &analysis.Result{Key: "Am", Tempo: 125, HasSlides: true}
+	lib.SetAnalyzer(func(format, device string, data []byte) (*analysis.Result, error) {
+		return result, nil
+	})
+
+	entry, err := lib.Add("A", "seq", "td3", []byte{1})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if entry.Analysis == nil || entry.Analysis.Tempo != 125 || !entry.HasTag("key:Am") || !entry.HasTag("slides") {
+		t.Errorf("Add() entry = %+v", entry)
+	}
+	_, _ = lib.Tag(entry.ID, "favourite")
+
+	// Reanalysis replaces automatic tags but keeps manual ones
+	result = &analysis.Result{Key: "C"}
+	entry, err = lib.Reanalyze(entry.ID)
+	if err != nil {
+		t.Fatalf("Reanalyze() error = %v", err)
+	}
+	if want := []string{"favourite", "key:C"}; !reflect.DeepEqual(entry.Tags, want) {
+		t.Errorf("tags after Reanalyze() = %v, want %v", entry.Tags, want)
+	}
+}
+
+func TestLibraryPatches(t *testing.T) {
+	lib, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	// Two-message dump from an unregistered Behringer model
+	dump := []byte{
+		0xF0, 0x00, 0x20, 0x32, 0x00, 0x7F, 0x10, 0x01, 0xF7,
+		0xF0, 0x00, 0x20, 0x32, 0x00, 0x7F, 0x10, 0x02, 0xF7,
+	}
+	patch, err := lib.AddPatch("Init pad", dump)
+	if err != nil {
+		t.Fatalf("AddPatch() error = %v", err)
+	}
+	if !patch.IsPatch() || patch.Source != "Behringer model 0x7F" {
+		t.Errorf("AddPatch() = %+v", patch)
+	}
+	if !reflect.DeepEqual(patch.Tags, []string{"patch", "behringer"}) {
+		t.Errorf("Tags = %v", patch.Tags)
+	}
+	if _, err := lib.AddPatch("Bad", []byte{0x01, 0x02}); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("AddPatch(non-SysEx) error = %v, want ErrInvalidPatch", err)
+	}
+
+	pattern, _ := lib.Add("Acid", "seq", "td3", []byte{1})
+	for kind, want := range map[string]string{KindPatch: patch.ID, KindPattern: pattern.ID} {
+		entries, err := lib.List(Filter{Kind: kind})
+		if err != nil || len(entries) != 1 || entries[0].ID != want {
+			t.Errorf("List(kind %s) = %+v, err %v", kind, entries, err)
+		}
+	}
+
+	_, msgs, err := lib.PatchMessages(patch.ID)
+	if err != nil {
+		t.Fatalf("PatchMessages() error = %v", err)
+	}
+	if len(msgs) != 2 || msgs[1][7] != 0x02 {
+		t.Errorf("PatchMessages() = % X", msgs)
+	}
+	if _, _, err := lib.PatchMessages(pattern.ID); err == nil {
+		t.Error("PatchMessages(pattern) expected error")
+	}
+}
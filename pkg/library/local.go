@@ -0,0 +1,209 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// index is the on-disk metadata of a local library
+type index struct {
+	Entries     map[string]*Entry `json:"entries"`
+	Collections []Collection      `json:"collections"`
+}
+
+// LocalStore keeps pattern data as files in a directory alongside a JSON index
+type LocalStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewLocalStore opens a local store in dir, creating it if necessary
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "patterns"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create library directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Dir returns the store's directory
+func (s *LocalStore) Dir() string {
+	return s.dir
+}
+
+func (s *LocalStore) indexPath() string {
+	return filepath.Join(s.dir, "index.json")
+}
+
+func (s *LocalStore) dataPath(id string) string {
+	return filepath.Join(s.dir, "patterns", id+".dat")
+}
+
+func (s *LocalStore) load() (*index, error) {
+	idx := &index{Entries: map[string]*Entry{}}
+	data, err := os.ReadFile(s.indexPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library index: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse library index: %w", err)
+	}
+	if idx.Entries == nil {
+		idx.Entries = map[string]*Entry{}
+	}
+	return idx, nil
+}
+
+func (s *LocalStore) save(idx *index) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := converter.WriteFileAtomic(s.indexPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write library index: %w", err)
+	}
+	return nil
+}
+
+// Entries returns all entries
+func (s *LocalStore) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(idx.Entries))
+	for _, e := range idx.Entries {
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// Entry returns the entry with the given ID
+func (s *LocalStore) Entry(id string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := idx.Entries[id]
+	if !ok {
+		return nil, fmt.Errorf("pattern %q: %w", id, ErrNotFound)
+	}
+	return e, nil
+}
+
+// SaveEntry creates or replaces an entry
+func (s *LocalStore) SaveEntry(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	entry := *e
+	idx.Entries[e.ID] = &entry
+	return s.save(idx)
+}
+
+// DeleteEntry removes an entry and its data
+func (s *LocalStore) DeleteEntry(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := idx.Entries[id]; !ok {
+		return fmt.Errorf("pattern %q: %w", id, ErrNotFound)
+	}
+	delete(idx.Entries, id)
+	if err := s.save(idx); err != nil {
+		return err
+	}
+	if err := os.Remove(s.dataPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove pattern data: %w", err)
+	}
+	return nil
+}
+
+// ReadData returns the stored file data of a pattern
+func (s *LocalStore) ReadData(id string) ([]byte, error) {
+	data, err := os.ReadFile(s.dataPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("pattern data %q: %w", id, ErrNotFound)
+	}
+	return data, err
+}
+
+// WriteData stores the file data of a pattern
+func (s *LocalStore) WriteData(id string, data []byte) error {
+	if err := converter.WriteFileAtomic(s.dataPath(id), data, 0644); err != nil {
+		return fmt.Errorf("failed to write pattern data: %w", err)
+	}
+	return nil
+}
+
+// Collections returns all collections
+func (s *LocalStore) Collections() ([]Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Collections, nil
+}
+
+// SaveCollection creates or replaces a collection
+func (s *LocalStore) SaveCollection(c *Collection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	coll := Collection{Name: c.Name, Patterns: append([]string(nil), c.Patterns...)}
+	for i := range idx.Collections {
+		if idx.Collections[i].Name == c.Name {
+			idx.Collections[i] = coll
+			return s.save(idx)
+		}
+	}
+	idx.Collections = append(idx.Collections, coll)
+	return s.save(idx)
+}
+
+// DeleteCollection removes a collection
+func (s *LocalStore) DeleteCollection(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range idx.Collections {
+		if idx.Collections[i].Name == name {
+			idx.Collections = append(idx.Collections[:i], idx.Collections[i+1:]...)
+			return s.save(idx)
+		}
+	}
+	return fmt.Errorf("collection %q: %w", name, ErrNotFound)
+}
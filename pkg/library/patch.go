@@ -0,0 +1,69 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// ErrInvalidPatch is returned when patch data is not a SysEx dump
+var ErrInvalidPatch = errors.New("invalid patch dump")
+
+// AddPatch stores a patch dump (one or more SysEx messages, e.g. a Pro-800
+// program) byte for byte. Patches are not converted or analyzed; the sender
+// is identified from the first message and recorded as the entry's source
+// and tags.
+func (l *Library) AddPatch(name string, data []byte) (*Entry, error) {
+	msgs, err := sysex.Split(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("%w: no SysEx messages", ErrInvalidPatch)
+	}
+	ident, err := sysex.Identify(msgs[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	entry := &Entry{
+		Name:   name,
+		Format: "syx",
+		Kind:   KindPatch,
+		Source: sourceName(ident),
+		Tags:   []string{KindPatch},
+	}
+	if ident.Manufacturer.Name != "" {
+		entry.Tags = append(entry.Tags, strings.ToLower(ident.Manufacturer.Name))
+	}
+	if ident.Model != nil && ident.Model.Device != "" {
+		entry.Device = ident.Model.Device
+	}
+	return l.add(entry, data)
+}
+
+// PatchMessages returns the SysEx messages of a stored patch dump, in the
+// order they should be sent back to the device
+func (l *Library) PatchMessages(id string) (*Entry, [][]byte, error) {
+	entry, data, err := l.Data(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !entry.IsPatch() {
+		return nil, nil, fmt.Errorf("%s (%s) is a pattern, not a patch", entry.ID, entry.Name)
+	}
+	msgs, err := sysex.Split(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", entry.ID, err)
+	}
+	return entry, msgs, nil
+}
+
+// sourceName describes the sender of a dump without its device ID, which
+// says where the dump came from rather than what it is
+func sourceName(ident sysex.Identity) string {
+	ident.HasDeviceID = false
+	return ident.String()
+}
@@ -0,0 +1,165 @@
+package library
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RemoteStore keeps the library on a synthtribe2midi server, using its
+// /api/v1/patterns and /api/v1/collections endpoints
+type RemoteStore struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewRemoteStore creates a store backed by the server at baseURL
+// (e.g. "http://studio:8080")
+func NewRemoteStore(baseURL string) (*RemoteStore, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid library server URL %q", baseURL)
+	}
+	return &RemoteStore{
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v1",
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// OpenRemote opens a library hosted by a synthtribe2midi server
+func OpenRemote(baseURL string) (*Library, error) {
+	store, err := NewRemoteStore(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return New(store), nil
+}
+
+// IsRemote reports whether a library location is a server URL rather than a
+// directory
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// do performs a request and decodes a JSON response into out (if non-nil).
+// A 404 response maps to ErrNotFound.
+func (s *RemoteStore) do(method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequest(method, s.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("library server request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%s: %w", path, ErrNotFound)
+		}
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return fmt.Errorf("library server: %s %s: %s", method, path, apiErr.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw, err = io.ReadAll(resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid library server response: %w", err)
+	}
+	return nil
+}
+
+// doJSON sends v as a JSON request body
+func (s *RemoteStore) doJSON(method, path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.do(method, path, "application/json", bytes.NewReader(data), nil)
+}
+
+// Entries returns all entries
+func (s *RemoteStore) Entries() ([]Entry, error) {
+	var resp struct {
+		Patterns []Entry `json:"patterns"`
+	}
+	if err := s.do(http.MethodGet, "/patterns", "", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Patterns, nil
+}
+
+// Entry returns the entry with the given ID
+func (s *RemoteStore) Entry(id string) (*Entry, error) {
+	var e Entry
+	if err := s.do(http.MethodGet, "/patterns/"+url.PathEscape(id), "", nil, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveEntry creates or replaces an entry
+func (s *RemoteStore) SaveEntry(e *Entry) error {
+	return s.doJSON(http.MethodPut, "/patterns/"+url.PathEscape(e.ID), e)
+}
+
+// DeleteEntry removes an entry and its data
+func (s *RemoteStore) DeleteEntry(id string) error {
+	return s.do(http.MethodDelete, "/patterns/"+url.PathEscape(id), "", nil, nil)
+}
+
+// ReadData returns the stored file data of a pattern
+func (s *RemoteStore) ReadData(id string) ([]byte, error) {
+	var data []byte
+	if err := s.do(http.MethodGet, "/patterns/"+url.PathEscape(id)+"/data", "", nil, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteData stores the file data of a pattern
+func (s *RemoteStore) WriteData(id string, data []byte) error {
+	return s.do(http.MethodPut, "/patterns/"+url.PathEscape(id)+"/data", "application/octet-stream", bytes.NewReader(data), nil)
+}
+
+// Collections returns all collections
+func (s *RemoteStore) Collections() ([]Collection, error) {
+	var resp struct {
+		Collections []Collection `json:"collections"`
+	}
+	if err := s.do(http.MethodGet, "/collections", "", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Collections, nil
+}
+
+// SaveCollection creates or replaces a collection
+func (s *RemoteStore) SaveCollection(c *Collection) error {
+	return s.doJSON(http.MethodPut, "/collections/"+url.PathEscape(c.Name), c)
+}
+
+// DeleteCollection removes a collection
+func (s *RemoteStore) DeleteCollection(name string) error {
+	return s.do(http.MethodDelete, "/collections/"+url.PathEscape(name), "", nil, nil)
+}
@@ -0,0 +1,184 @@
+package library
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// SlotRecord notes the hardware memory slot a pattern was last synced to
+type SlotRecord struct {
+	Port   string    `json:"port"`
+	Slot   string    `json:"slot"`
+	Synced time.Time `json:"synced"`
+	// Hash is the version of the pattern written to the slot
+	Hash string `json:"hash,omitempty"`
+}
+
+// SlotAssignment pairs a library pattern with a device memory slot
+type SlotAssignment struct {
+	Slot  int
+	Entry Entry
+}
+
+// AssignSlots lays out a collection in consecutive slots starting at start,
+// failing if it does not fit in the device's slots
+func (l *Library) AssignSlots(collection string, start, slots int) ([]SlotAssignment, error) {
+	coll, err := l.Collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	if len(coll.Patterns) == 0 {
+		return nil, fmt.Errorf("collection %q is empty", collection)
+	}
+	if start < 0 || start+len(coll.Patterns) > slots {
+		return nil, fmt.Errorf("collection %q has %d patterns, which do not fit in slots %d-%d",
+			collection, len(coll.Patterns), start, slots-1)
+	}
+
+	assignments := make([]SlotAssignment, 0, len(coll.Patterns))
+	for i, id := range coll.Patterns {
+		entry, err := l.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, SlotAssignment{Slot: start + i, Entry: *entry})
+	}
+	return assignments, nil
+}
+
+// RecordSlot records that a pattern now occupies a slot on the device at
+// port. Any other pattern recorded in that slot is cleared, since a slot
+// holds one pattern.
+func (l *Library) RecordSlot(id, port, slot string) (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, err := l.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := l.store.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == entry.ID {
+			continue
+		}
+		if dropSlot(&entries[i], func(r SlotRecord) bool { return r.Port == port && r.Slot == slot }) {
+			if err := l.store.SaveEntry(&entries[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return l.updateLocked(entry.ID, func(e *Entry) error {
+		dropSlot(e, func(r SlotRecord) bool { return r.Port == port })
+		e.Slots = append(e.Slots, SlotRecord{Port: port, Slot: slot, Synced: time.Now().UTC(), Hash: e.Hash})
+		return nil
+	})
+}
+
+// dropSlot removes matching slot records and reports whether any were removed
+func dropSlot(e *Entry, match func(SlotRecord) bool) bool {
+	kept := e.Slots[:0]
+	for _, r := range e.Slots {
+		if !match(r) {
+			kept = append(kept, r)
+		}
+	}
+	removed := len(kept) != len(e.Slots)
+	e.Slots = kept
+	return removed
+}
+
+// SlotEntry returns the pattern last synced to a slot on the device at
+// port and the record of that sync, or nil if none is recorded there
+func (l *Library) SlotEntry(port, slot string) (*Entry, *SlotRecord, error) {
+	entries, err := l.store.Entries()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range entries {
+		for _, r := range entries[i].Slots {
+			if r.Port == port && r.Slot == slot {
+				return &entries[i], &r, nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// SlotState is how a hardware slot compares with the library in a two-way
+// sync
+type SlotState int
+
+const (
+	// SlotInSync means the slot holds the library's pattern
+	SlotInSync SlotState = iota
+	// SlotLibraryChanged means the library's pattern for the slot changed
+	// since the last sync, but the slot did not
+	SlotLibraryChanged
+	// SlotDeviceChanged means the slot was edited on the device since the
+	// last sync, but the library's pattern did not change
+	SlotDeviceChanged
+	// SlotConflict means both changed, or the slot was never synced and
+	// holds something else
+	SlotConflict
+)
+
+// String returns the state as shown by lib sync
+func (s SlotState) String() string {
+	switch s {
+	case SlotInSync:
+		return "in sync"
+	case SlotLibraryChanged:
+		return "changed in library"
+	case SlotDeviceChanged:
+		return "edited on device"
+	default:
+		return "conflict"
+	}
+}
+
+// CompareSlot classifies a slot from the pattern the device holds, the
+// library's pattern for it, and the pattern last synced there (nil if the
+// slot was never synced). Patterns compare by what dev stores in the slot.
+func CompareSlot(dev converter.Device, device, library, synced *converter.Pattern) SlotState {
+	same := func(a, b *converter.Pattern) bool { return len(converter.SlotDiff(dev, a, b)) == 0 }
+	switch {
+	case same(device, library):
+		return SlotInSync
+	case synced != nil && same(device, synced):
+		return SlotLibraryChanged
+	case synced != nil && same(library, synced):
+		return SlotDeviceChanged
+	default:
+		return SlotConflict
+	}
+}
+
+// Conflict policies: how a two-way sync resolves a slot changed both on the
+// device and in the library
+const (
+	ConflictAsk     = "ask"     // ask for each conflict
+	ConflictDevice  = "device"  // keep the device's pattern as the library pattern's new version
+	ConflictLibrary = "library" // write the library's pattern over the device's
+	ConflictFork    = "fork"    // save the device's pattern as a new one, then write the library's
+)
+
+// ConflictPolicies lists the valid conflict policies
+var ConflictPolicies = []string{ConflictAsk, ConflictDevice, ConflictLibrary, ConflictFork}
+
+// ValidateConflictPolicy checks a conflict policy name
+func ValidateConflictPolicy(policy string) error {
+	for _, p := range ConflictPolicies {
+		if policy == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown conflict policy %q (use %s)", policy, strings.Join(ConflictPolicies, ", "))
+}
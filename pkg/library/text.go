@@ -0,0 +1,400 @@
+package library
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// textIndexFile marks a directory as a text library and holds its collections
+const textIndexFile = "library.json"
+
+// textDataLine is the number of bytes on each line of a pattern file's data
+const textDataLine = 16
+
+// Decoder parses pattern data in the given format and for the given device,
+// so a TextStore can write its steps out readably
+type Decoder func(format, device string, data []byte) (*converter.Pattern, error)
+
+// textIndex is the index file of a text library
+type textIndex struct {
+	Store       string       `json:"store"`
+	Patterns    []string     `json:"patterns"`
+	Collections []Collection `json:"collections"`
+}
+
+// textFile is one pattern file of a text library: the entry's metadata, its
+// steps for reading (and diffing), and its data as lines of hex. Files
+// without an ID hold earlier versions of a pattern, named by their hash.
+type textFile struct {
+	*Entry
+	Steps []string `json:"steps,omitempty"`
+	Data  []string `json:"data"`
+}
+
+// TextStore keeps a library as indented JSON text in a plain directory, one
+// file per pattern plus an index, so it can be kept in a git repository with
+// readable diffs. The data lines are what is stored; the steps are written
+// from them for reading and are not read back.
+type TextStore struct {
+	dir    string
+	decode Decoder
+	mu     sync.Mutex
+}
+
+// NewTextStore opens a text store in dir, creating it if necessary
+func NewTextStore(dir string) (*TextStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "patterns"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create library directory: %w", err)
+	}
+	s := &TextStore{dir: dir}
+	if _, err := os.Stat(s.indexPath()); errors.Is(err, os.ErrNotExist) {
+		if err := s.save(&textIndex{}); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// OpenText opens (creating if necessary) a text library in dir
+func OpenText(dir string) (*Library, error) {
+	store, err := NewTextStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	return New(store), nil
+}
+
+// IsText reports whether dir holds a text library
+func IsText(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, textIndexFile))
+	return err == nil
+}
+
+// IsLibrary reports whether dir holds a library, local or text
+func IsLibrary(dir string) bool {
+	if IsText(dir) {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, "patterns"))
+	return err == nil && info.IsDir()
+}
+
+// Dir returns the store's directory
+func (s *TextStore) Dir() string {
+	return s.dir
+}
+
+// SetDecoder makes the store write the steps of the patterns it can decode
+// into their files
+func (s *TextStore) SetDecoder(d Decoder) {
+	s.decode = d
+}
+
+func (s *TextStore) indexPath() string {
+	return filepath.Join(s.dir, textIndexFile)
+}
+
+func (s *TextStore) patternPath(id string) string {
+	return filepath.Join(s.dir, "patterns", id+".json")
+}
+
+func (s *TextStore) load() (*textIndex, error) {
+	data, err := os.ReadFile(s.indexPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library index: %w", err)
+	}
+	idx := &textIndex{}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse library index: %w", err)
+	}
+	return idx, nil
+}
+
+func (s *TextStore) save(idx *textIndex) error {
+	idx.Store = "text"
+	if idx.Patterns == nil {
+		idx.Patterns = []string{}
+	}
+	if idx.Collections == nil {
+		idx.Collections = []Collection{}
+	}
+	return writeJSON(s.indexPath(), idx, "library index")
+}
+
+// readFile reads a pattern file; a missing file reads as an empty one
+func (s *TextStore) readFile(id string) (*textFile, error) {
+	f := &textFile{}
+	data, err := os.ReadFile(s.patternPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pattern %q: %w", id, err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse pattern %q: %w", id, err)
+	}
+	return f, nil
+}
+
+// writeFile writes a pattern file, refreshing its steps from its data
+func (s *TextStore) writeFile(id string, f *textFile) error {
+	f.Steps = nil
+	if f.Entry != nil && s.decode != nil && f.Kind != KindPatch {
+		if data, err := decodeHexLines(f.Data); err == nil {
+			if p, err := s.decode(f.Format, f.Device, data); err == nil {
+				f.Steps = textSteps(p)
+			}
+		}
+	}
+	return writeJSON(s.patternPath(id), f, "pattern "+id)
+}
+
+// Entries returns all entries, in the order of the index
+func (s *TextStore) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(idx.Patterns))
+	for _, id := range idx.Patterns {
+		f, err := s.readFile(id)
+		if err != nil {
+			return nil, err
+		}
+		if f.Entry == nil {
+			return nil, fmt.Errorf("pattern %q is in the index but has no file", id)
+		}
+		entries = append(entries, *f.Entry)
+	}
+	return entries, nil
+}
+
+// Entry returns the entry with the given ID
+func (s *TextStore) Entry(id string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.readFile(id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Entry == nil || f.ID != id {
+		return nil, fmt.Errorf("pattern %q: %w", id, ErrNotFound)
+	}
+	return f.Entry, nil
+}
+
+// SaveEntry creates or replaces an entry
+func (s *TextStore) SaveEntry(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	f, err := s.readFile(e.ID)
+	if err != nil {
+		return err
+	}
+	entry := *e
+	f.Entry = &entry
+	if err := s.writeFile(e.ID, f); err != nil {
+		return err
+	}
+	if i, found := slices.BinarySearch(idx.Patterns, e.ID); !found {
+		idx.Patterns = slices.Insert(idx.Patterns, i, e.ID)
+		return s.save(idx)
+	}
+	return nil
+}
+
+// DeleteEntry removes an entry and its data
+func (s *TextStore) DeleteEntry(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	i, found := slices.BinarySearch(idx.Patterns, id)
+	if !found {
+		return fmt.Errorf("pattern %q: %w", id, ErrNotFound)
+	}
+	idx.Patterns = slices.Delete(idx.Patterns, i, i+1)
+	if err := s.save(idx); err != nil {
+		return err
+	}
+	if err := os.Remove(s.patternPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove pattern file: %w", err)
+	}
+	return nil
+}
+
+// ReadData returns the stored file data of a pattern
+func (s *TextStore) ReadData(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.readFile(id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Data == nil {
+		return nil, fmt.Errorf("pattern data %q: %w", id, ErrNotFound)
+	}
+	data, err := decodeHexLines(f.Data)
+	if err != nil {
+		return nil, fmt.Errorf("pattern data %q: %w", id, err)
+	}
+	return data, nil
+}
+
+// WriteData stores the file data of a pattern
+func (s *TextStore) WriteData(id string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.readFile(id)
+	if err != nil {
+		return err
+	}
+	f.Data = encodeHexLines(data)
+	return s.writeFile(id, f)
+}
+
+// Collections returns all collections
+func (s *TextStore) Collections() ([]Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Collections, nil
+}
+
+// SaveCollection creates or replaces a collection
+func (s *TextStore) SaveCollection(c *Collection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	coll := Collection{Name: c.Name, Patterns: append([]string(nil), c.Patterns...)}
+	for i := range idx.Collections {
+		if idx.Collections[i].Name == c.Name {
+			idx.Collections[i] = coll
+			return s.save(idx)
+		}
+	}
+	idx.Collections = append(idx.Collections, coll)
+	return s.save(idx)
+}
+
+// DeleteCollection removes a collection
+func (s *TextStore) DeleteCollection(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i := range idx.Collections {
+		if idx.Collections[i].Name == name {
+			idx.Collections = append(idx.Collections[:i], idx.Collections[i+1:]...)
+			return s.save(idx)
+		}
+	}
+	return fmt.Errorf("collection %q: %w", name, ErrNotFound)
+}
+
+// writeJSON writes v as indented JSON ending in a newline, as text files in
+// a git repository should
+func writeJSON(path string, v any, what string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := converter.WriteFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	return nil
+}
+
+// encodeHexLines splits data into lines of space-separated hex bytes, so a
+// change to a few bytes changes only their lines
+func encodeHexLines(data []byte) []string {
+	lines := []string{}
+	for chunk := range slices.Chunk(data, textDataLine) {
+		b := make([]string, len(chunk))
+		for i, c := range chunk {
+			b[i] = fmt.Sprintf("%02x", c)
+		}
+		lines = append(lines, strings.Join(b, " "))
+	}
+	return lines
+}
+
+func decodeHexLines(lines []string) ([]byte, error) {
+	var data []byte
+	for i, line := range lines {
+		b, err := hex.DecodeString(strings.ReplaceAll(line, " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("data line %d: %w", i+1, err)
+		}
+		data = append(data, b...)
+	}
+	return data, nil
+}
+
+// textSteps describes each step of a pattern on a line, e.g.
+// "3: A2 accent slide", or "4: rest"
+func textSteps(p *converter.Pattern) []string {
+	n := p.Length
+	if n <= 0 || n > len(p.Steps) {
+		n = len(p.Steps)
+	}
+	lines := make([]string, n)
+	for i, st := range p.Steps[:n] {
+		fields := []string{fmt.Sprintf("%d:", i+1)}
+		if st.Gate {
+			fields = append(fields, converter.NoteName(st.Note))
+		} else {
+			fields = append(fields, "rest")
+		}
+		if st.Accent {
+			fields = append(fields, "accent")
+		}
+		if st.Slide {
+			fields = append(fields, "slide")
+		}
+		if st.Tie {
+			fields = append(fields, "tie")
+		}
+		lines[i] = strings.Join(fields, " ")
+	}
+	return lines
+}
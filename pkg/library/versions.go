@@ -0,0 +1,127 @@
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Version is one content a pattern has had. Replaced content is kept in the
+// store under its hash, so identical takes share their data.
+type Version struct {
+	Hash   string    `json:"hash"`
+	Format string    `json:"format"`
+	Saved  time.Time `json:"saved"`
+	Note   string    `json:"note,omitempty"`
+}
+
+// ShortHash returns the abbreviated hash that names a version
+func (v Version) ShortHash() string {
+	return v.Hash[:min(len(v.Hash), 12)]
+}
+
+// versions returns every version of the entry, oldest first; an entry never
+// updated has just the content it was added with
+func (e *Entry) versions() []Version {
+	if len(e.Versions) > 0 {
+		return e.Versions
+	}
+	return []Version{{Hash: e.Hash, Format: e.Format, Saved: e.Added}}
+}
+
+// Update replaces the data of a pattern, keeping its ID, tags, rating,
+// collections, and slots. The replaced content stays available as an
+// earlier version (see History and Revert). Updating to the current content
+// changes nothing.
+func (l *Library) Update(id, format string, data []byte, note string) (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, old, err := l.Data(id)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if hash == entry.Hash && format == entry.Format {
+		return entry, nil
+	}
+
+	if err := l.store.WriteData(entry.Hash, old); err != nil {
+		return nil, err
+	}
+	if err := l.store.WriteData(entry.ID, data); err != nil {
+		return nil, err
+	}
+	return l.updateLocked(entry.ID, func(e *Entry) error {
+		e.Versions = append(e.versions(), Version{Hash: hash, Format: format, Saved: time.Now().UTC(), Note: note})
+		e.Hash = hash
+		e.Format = format
+		if !e.IsPatch() {
+			if e.Analysis != nil {
+				e.Tags = without(e.Tags, e.Analysis.Tags())
+			}
+			e.Analysis = nil
+			l.analyze(e, data)
+		}
+		return nil
+	})
+}
+
+// History returns a pattern and its versions, newest (the current content)
+// first
+func (l *Library) History(id string) (*Entry, []Version, error) {
+	entry, err := l.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	versions := slices.Clone(entry.versions())
+	slices.Reverse(versions)
+	return entry, versions, nil
+}
+
+// VersionData returns a version of a pattern, named by its hash or a unique
+// prefix of it, and its data
+func (l *Library) VersionData(id, version string) (*Entry, *Version, []byte, error) {
+	entry, err := l.Get(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var match *Version
+	for _, v := range entry.versions() {
+		if version != "" && strings.HasPrefix(v.Hash, version) {
+			if match != nil && match.Hash != v.Hash {
+				return nil, nil, nil, fmt.Errorf("version %q of pattern %s is ambiguous", version, entry.ID)
+			}
+			match = &v
+		}
+	}
+	if match == nil {
+		return nil, nil, nil, fmt.Errorf("version %q of pattern %s: %w", version, entry.ID, ErrNotFound)
+	}
+
+	key := match.Hash
+	if key == entry.Hash {
+		key = entry.ID
+	}
+	data, err := l.store.ReadData(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return entry, match, data, nil
+}
+
+// Revert makes an earlier version the current content of a pattern. The
+// revert is itself a new version, so the content it replaces is kept too.
+func (l *Library) Revert(id, version string) (*Entry, error) {
+	entry, v, data, err := l.VersionData(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if v.Hash == entry.Hash && v.Format == entry.Format {
+		return nil, fmt.Errorf("version %s is already the current content of pattern %s", v.ShortHash(), entry.ID)
+	}
+	return l.Update(entry.ID, v.Format, data, "revert to "+v.ShortHash())
+}
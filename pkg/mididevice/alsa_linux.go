@@ -0,0 +1,198 @@
+//go:build linux
+
+package mididevice
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// The ALSA raw backend talks to /dev/snd/midiC*D* directly. It needs no cgo
+// or libasound, so it is always available on Linux, e.g. on a headless Pi.
+func init() {
+	Register(&alsaBackend{devDir: "/dev/snd", procDir: "/proc/asound"})
+}
+
+// alsaBackend exposes ALSA raw MIDI devices as ports named after their card,
+// e.g. "TD-3 (hw:1,0)"
+type alsaBackend struct {
+	devDir  string
+	procDir string
+}
+
+var alsaDevice = regexp.MustCompile(`^midiC(\d+)D(\d+)$`)
+
+// alsaCard matches a /proc/asound/cards line such as
+// " 1 [TD3            ]: USB-Audio - TD-3"
+var alsaCard = regexp.MustCompile(`^\s*(\d+)\s+\[(\S+)\s*\]:.*? - (.+)$`)
+
+func (a *alsaBackend) Name() string { return "alsa" }
+
+// ports maps port names to device paths
+func (a *alsaBackend) ports() (map[string]string, error) {
+	entries, err := os.ReadDir(a.devDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	cards := a.cardNames()
+
+	ports := map[string]string{}
+	for _, e := range entries {
+		m := alsaDevice.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		card, _ := strconv.Atoi(m[1])
+		name, ok := cards[card]
+		if !ok {
+			name = "card " + m[1]
+		}
+		ports[fmt.Sprintf("%s (hw:%s,%s)", name, m[1], m[2])] = filepath.Join(a.devDir, e.Name())
+	}
+	return ports, nil
+}
+
+// cardNames reads the card names from /proc/asound/cards
+func (a *alsaBackend) cardNames() map[int]string {
+	names := map[int]string{}
+	f, err := os.Open(filepath.Join(a.procDir, "cards"))
+	if err != nil {
+		return names
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if m := alsaCard.FindStringSubmatch(scanner.Text()); m != nil {
+			card, _ := strconv.Atoi(m[1])
+			names[card] = strings.TrimSpace(m[3])
+		}
+	}
+	return names
+}
+
+func (a *alsaBackend) portNames() ([]string, error) {
+	ports, err := a.ports()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(ports))
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Outputs lists the raw MIDI devices
+func (a *alsaBackend) Outputs() ([]string, error) { return a.portNames() }
+
+// Inputs lists the raw MIDI devices
+func (a *alsaBackend) Inputs() ([]string, error) { return a.portNames() }
+
+func (a *alsaBackend) open(name string, flag int) (*os.File, error) {
+	ports, err := a.ports()
+	if err != nil {
+		return nil, err
+	}
+	path, ok := ports[name]
+	if !ok {
+		return nil, fmt.Errorf("MIDI port %q not found", name)
+	}
+	// Non-blocking so Close interrupts a pending read
+	f, err := os.OpenFile(path, flag|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// OpenOutput opens a raw MIDI device for writing
+func (a *alsaBackend) OpenOutput(name string) (Out, error) {
+	f, err := a.open(name, os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+	return &alsaOut{name: name, f: f}, nil
+}
+
+// OpenInput opens a raw MIDI device for reading
+func (a *alsaBackend) OpenInput(name string) (In, error) {
+	f, err := a.open(name, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	return &alsaIn{name: name, f: f}, nil
+}
+
+type alsaOut struct {
+	name string
+	f    *os.File
+}
+
+func (o *alsaOut) Name() string { return o.name }
+func (o *alsaOut) Close() error { return o.f.Close() }
+
+func (o *alsaOut) Send(msg []byte) error {
+	_, err := o.f.Write(msg)
+	return err
+}
+
+type alsaIn struct {
+	name string
+	f    *os.File
+
+	mu    sync.Mutex
+	onMsg func(msg []byte)
+	once  sync.Once
+}
+
+func (i *alsaIn) Name() string { return i.name }
+func (i *alsaIn) Close() error { return i.f.Close() }
+
+// Listen starts delivering messages to onMsg. The device is read by one
+// goroutine for the lifetime of the port; stop detaches the callback.
+func (i *alsaIn) Listen(onMsg func(msg []byte)) (func(), error) {
+	i.mu.Lock()
+	i.onMsg = onMsg
+	i.mu.Unlock()
+	i.once.Do(func() { go i.read() })
+
+	return func() {
+		i.mu.Lock()
+		i.onMsg = nil
+		i.mu.Unlock()
+	}, nil
+}
+
+func (i *alsaIn) read() {
+	var p streamParser
+	buf := make([]byte, 1024)
+	for {
+		n, err := i.f.Read(buf)
+		if n > 0 {
+			p.Feed(buf[:n], func(msg []byte) {
+				i.mu.Lock()
+				onMsg := i.onMsg
+				i.mu.Unlock()
+				if onMsg != nil {
+					onMsg(msg)
+				}
+			})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
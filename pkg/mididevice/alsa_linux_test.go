@@ -0,0 +1,52 @@
+package mididevice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestALSAPorts(t *testing.T) {
+	dev, proc := t.TempDir(), t.TempDir()
+	for _, name := range []string{"midiC1D0", "midiC2D0", "pcmC0D0p", "controlC1"} {
+		if err := os.WriteFile(filepath.Join(dev, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cards := " 0 [PCH            ]: HDA-Intel - HDA Intel PCH\n" +
+		"                      HDA Intel PCH at 0xf7f00000 irq 32\n" +
+		" 1 [TD3            ]: USB-Audio - TD-3\n" +
+		"                      Behringer TD-3 at usb-0000:00:14.0-1, full speed\n"
+	if err := os.WriteFile(filepath.Join(proc, "cards"), []byte(cards), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &alsaBackend{devDir: dev, procDir: proc}
+	got, err := a.Outputs()
+	if err != nil {
+		t.Fatalf("Outputs() error = %v", err)
+	}
+	want := []string{"TD-3 (hw:1,0)", "card 2 (hw:2,0)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Outputs() = %q, want %q", got, want)
+	}
+
+	out, err := OpenOut(a, "td-3")
+	if err != nil {
+		t.Fatalf("OpenOut() error = %v", err)
+	}
+	if err := out.Send([]byte{0xF0, 0x7E, 0xF7}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	_ = out.Close()
+	data, _ := os.ReadFile(filepath.Join(dev, "midiC1D0"))
+	if !reflect.DeepEqual(data, []byte{0xF0, 0x7E, 0xF7}) {
+		t.Errorf("device received % X", data)
+	}
+
+	missing := &alsaBackend{devDir: filepath.Join(dev, "missing"), procDir: proc}
+	if ports, err := missing.Outputs(); err != nil || len(ports) != 0 {
+		t.Errorf("Outputs() without /dev/snd = %q, %v; want none", ports, err)
+	}
+}
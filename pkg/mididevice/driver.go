@@ -0,0 +1,106 @@
+package mididevice
+
+import (
+	"fmt"
+
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+// DriverBackend adapts a gomidi driver to the Backend interface
+type DriverBackend struct {
+	name string
+	drv  drivers.Driver
+}
+
+// NewDriverBackend wraps a gomidi driver as the backend with the given name
+func NewDriverBackend(name string, drv drivers.Driver) *DriverBackend {
+	return &DriverBackend{name: name, drv: drv}
+}
+
+// Name returns the backend name
+func (d *DriverBackend) Name() string {
+	return d.name
+}
+
+// Outputs lists the driver's output port names
+func (d *DriverBackend) Outputs() ([]string, error) {
+	outs, err := d.drv.Outs()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(outs))
+	for i, out := range outs {
+		names[i] = out.String()
+	}
+	return names, nil
+}
+
+// OpenOutput opens the output port with the given name
+func (d *DriverBackend) OpenOutput(name string) (Out, error) {
+	outs, err := d.drv.Outs()
+	if err != nil {
+		return nil, err
+	}
+	for _, out := range outs {
+		if out.String() == name {
+			if err := out.Open(); err != nil {
+				return nil, err
+			}
+			return driverOut{out}, nil
+		}
+	}
+	return nil, fmt.Errorf("MIDI port %q not found", name)
+}
+
+// driverOut adapts a gomidi output port to Out
+type driverOut struct {
+	out drivers.Out
+}
+
+func (o driverOut) Name() string          { return o.out.String() }
+func (o driverOut) Send(msg []byte) error { return o.out.Send(msg) }
+func (o driverOut) Close() error          { return o.out.Close() }
+
+// Inputs lists the driver's input port names
+func (d *DriverBackend) Inputs() ([]string, error) {
+	ins, err := d.drv.Ins()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(ins))
+	for i, in := range ins {
+		names[i] = in.String()
+	}
+	return names, nil
+}
+
+// OpenInput opens the input port with the given name
+func (d *DriverBackend) OpenInput(name string) (In, error) {
+	ins, err := d.drv.Ins()
+	if err != nil {
+		return nil, err
+	}
+	for _, in := range ins {
+		if in.String() == name {
+			if err := in.Open(); err != nil {
+				return nil, err
+			}
+			return driverIn{in}, nil
+		}
+	}
+	return nil, fmt.Errorf("MIDI port %q not found", name)
+}
+
+// driverIn adapts a gomidi input port to In
+type driverIn struct {
+	in drivers.In
+}
+
+func (i driverIn) Name() string { return i.in.String() }
+func (i driverIn) Close() error { return i.in.Close() }
+
+func (i driverIn) Listen(onMsg func(msg []byte)) (func(), error) {
+	return i.in.Listen(func(msg []byte, _ int32) {
+		onMsg(msg)
+	}, drivers.ListenConfig{SysEx: true, SysExBufferSize: 4096})
+}
@@ -0,0 +1,227 @@
+// Package mididevice exchanges SysEx with hardware over MIDI ports through
+// pluggable backends
+package mididevice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoBackend is returned when no MIDI backend was compiled in
+var ErrNoBackend = errors.New("no MIDI backend available (build with -tags rtmidi or portmidi)")
+
+// Out is an open MIDI output port
+type Out interface {
+	Name() string
+	Send(msg []byte) error
+	Close() error
+}
+
+// In is an open MIDI input port
+type In interface {
+	Name() string
+	// Listen calls onMsg for every incoming message, including SysEx, until
+	// the returned stop function is called
+	Listen(onMsg func(msg []byte)) (stop func(), err error)
+	Close() error
+}
+
+// Backend enumerates and opens MIDI ports
+type Backend interface {
+	Name() string
+	Outputs() ([]string, error)
+	OpenOutput(name string) (Out, error)
+	Inputs() ([]string, error)
+	OpenInput(name string) (In, error)
+}
+
+// PortCreator is implemented by backends that create a port under the
+// requested name instead of opening an existing one, such as virtual ports
+// that a DAW connects to
+type PortCreator interface {
+	CreatesPorts() bool
+}
+
+var (
+	backends []Backend
+	selected Backend
+)
+
+// preferred is the order in which backends are chosen as the default when
+// none is selected: native APIs first, raw device access as a fallback
+var preferred = []string{"rtmidi", "portmidi", "alsa"}
+
+// buildTags names the build tag that compiles in each optional backend
+var buildTags = map[string]string{
+	"rtmidi":   "rtmidi",
+	"virtual":  "rtmidi",
+	"portmidi": "portmidi",
+}
+
+// Register makes a backend available
+func Register(b Backend) {
+	backends = append(backends, b)
+}
+
+// Backends returns the names of the available backends
+func Backends() []string {
+	names := make([]string, len(backends))
+	for i, b := range backends {
+		names[i] = b.Name()
+	}
+	return names
+}
+
+// Lookup returns the available backend with the given name
+func Lookup(name string) (Backend, error) {
+	for _, b := range backends {
+		if strings.EqualFold(b.Name(), name) {
+			return b, nil
+		}
+	}
+	if tag, ok := buildTags[strings.ToLower(name)]; ok {
+		return nil, fmt.Errorf("MIDI backend %q is not compiled in (build with -tags %s)", name, tag)
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("unknown MIDI backend %q: %w", name, ErrNoBackend)
+	}
+	return nil, fmt.Errorf("unknown MIDI backend %q (available: %s)", name, strings.Join(Backends(), ", "))
+}
+
+// Use selects the backend used by OpenOutput, OpenInput, Outputs, and Inputs
+// (the --midi-backend flag). An empty name restores the default choice.
+func Use(name string) error {
+	if name == "" {
+		selected = nil
+		return nil
+	}
+	b, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	selected = b
+	return nil
+}
+
+// Default returns the selected backend, or else the most preferred one
+// available
+func Default() (Backend, error) {
+	if selected != nil {
+		return selected, nil
+	}
+	for _, name := range preferred {
+		for _, b := range backends {
+			if b.Name() == name {
+				return b, nil
+			}
+		}
+	}
+	if len(backends) == 0 {
+		return nil, ErrNoBackend
+	}
+	return backends[0], nil
+}
+
+// Outputs lists the output ports of the default backend
+func Outputs() ([]string, error) {
+	b, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return b.Outputs()
+}
+
+// OpenOutput opens an output port of the default backend by name
+func OpenOutput(port string) (Out, error) {
+	b, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return OpenOut(b, port)
+}
+
+// Inputs lists the input ports of the default backend
+func Inputs() ([]string, error) {
+	b, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return b.Inputs()
+}
+
+// OpenInput opens an input port of the default backend by name
+func OpenInput(port string) (In, error) {
+	b, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return OpenIn(b, port)
+}
+
+// OpenOut opens the backend output port matching port. An exact name wins;
+// otherwise the port must be the only one whose name contains it
+// (case-insensitive), so "TD-3" matches "TD-3 MIDI 1". Backends that create
+// ports open port under exactly that name.
+func OpenOut(b Backend, port string) (Out, error) {
+	if createsPorts(b) {
+		return b.OpenOutput(port)
+	}
+	names, err := b.Outputs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list MIDI outputs: %w", err)
+	}
+	name, err := matchPort(names, port)
+	if err != nil {
+		return nil, err
+	}
+	return b.OpenOutput(name)
+}
+
+// OpenIn opens the backend input port matching port, using the same matching
+// rules as OpenOut
+func OpenIn(b Backend, port string) (In, error) {
+	if createsPorts(b) {
+		return b.OpenInput(port)
+	}
+	names, err := b.Inputs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list MIDI inputs: %w", err)
+	}
+	name, err := matchPort(names, port)
+	if err != nil {
+		return nil, err
+	}
+	return b.OpenInput(name)
+}
+
+// createsPorts reports whether b creates ports rather than opening them
+func createsPorts(b Backend) bool {
+	pc, ok := b.(PortCreator)
+	return ok && pc.CreatesPorts()
+}
+
+// matchPort resolves a port query against the available port names
+func matchPort(names []string, query string) (string, error) {
+	var matches []string
+	for _, name := range names {
+		if name == query {
+			return name, nil
+		}
+		if strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		if len(names) == 0 {
+			return "", fmt.Errorf("MIDI port %q not found: no output ports available", query)
+		}
+		return "", fmt.Errorf("MIDI port %q not found (available: %s)", query, strings.Join(names, ", "))
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("MIDI port %q is ambiguous (matches: %s)", query, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,166 @@
+package mididevice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	ports  []string
+	opened string
+}
+
+func (f *fakeBackend) Name() string               { return "fake" }
+func (f *fakeBackend) Outputs() ([]string, error) { return f.ports, nil }
+func (f *fakeBackend) OpenOutput(name string) (Out, error) {
+	f.opened = name
+	return nil, nil
+}
+func (f *fakeBackend) Inputs() ([]string, error)         { return f.ports, nil }
+func (f *fakeBackend) OpenInput(name string) (In, error) { return nil, nil }
+
+// namedBackend is a fakeBackend under another name
+type namedBackend struct {
+	fakeBackend
+	name string
+}
+
+func (n *namedBackend) Name() string { return n.name }
+
+// loopback is a fake device: every message sent to it is answered by reply
+type loopback struct {
+	reply  func(req []byte) [][]byte
+	listen func(msg []byte)
+}
+
+func (l *loopback) Name() string { return "loopback" }
+func (l *loopback) Close() error { return nil }
+func (l *loopback) Send(msg []byte) error {
+	for _, r := range l.reply(msg) {
+		if l.listen != nil {
+			l.listen(r)
+		}
+	}
+	return nil
+}
+func (l *loopback) Listen(onMsg func(msg []byte)) (func(), error) {
+	l.listen = onMsg
+	return func() { l.listen = nil }, nil
+}
+
+func TestOpenMatchesPort(t *testing.T) {
+	b := &fakeBackend{ports: []string{"Midi Through Port-0", "TD-3 MIDI 1", "TD-3 MO MIDI 1"}}
+
+	tests := []struct {
+		query   string
+		want    string
+		wantErr string
+	}{
+		{"TD-3 MIDI 1", "TD-3 MIDI 1", ""},
+		{"through", "Midi Through Port-0", ""},
+		{"td-3 mo", "TD-3 MO MIDI 1", ""},
+		{"TD-3", "", "ambiguous"},
+		{"Volca", "", "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			b.opened = ""
+			_, err := OpenOut(b, tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("OpenOut(%q) error = %v, want %q", tt.query, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OpenOut(%q) error = %v", tt.query, err)
+			}
+			if b.opened != tt.want {
+				t.Errorf("OpenOut(%q) opened %q, want %q", tt.query, b.opened, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequest(t *testing.T) {
+	dev := &loopback{reply: func(req []byte) [][]byte {
+		// Unrelated traffic first, then the reply
+		return [][]byte{{0xF8}, {0xF0, 0x7E, req[1], 0xF7}}
+	}}
+	accept := func(msg []byte) bool { return msg[0] == 0xF0 }
+
+	reply, err := Request(dev, dev, []byte{0xF0, 0x05, 0xF7}, accept, time.Second)
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if len(reply) != 4 || reply[2] != 0x05 {
+		t.Errorf("Request() = % X", reply)
+	}
+
+	silent := &loopback{reply: func([]byte) [][]byte { return nil }}
+	if _, err := Request(silent, silent, []byte{0xF0, 0xF7}, accept, 10*time.Millisecond); err != ErrTimeout {
+		t.Errorf("Request() error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestRequestAll(t *testing.T) {
+	dev := &loopback{reply: func(req []byte) [][]byte {
+		return [][]byte{{0xF0, 0x01, 0xF7}, {0xFE}, {0xF0, 0x02, 0xF7}}
+	}}
+	accept := func(msg []byte) bool { return msg[0] == 0xF0 }
+
+	replies, err := RequestAll(dev, dev, []byte{0xF0, 0x05, 0xF7}, accept, time.Second, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RequestAll() error = %v", err)
+	}
+	if len(replies) != 2 || replies[1][1] != 0x02 {
+		t.Errorf("RequestAll() = % X", replies)
+	}
+
+	silent := &loopback{reply: func([]byte) [][]byte { return nil }}
+	if _, err := RequestAll(silent, silent, nil, accept, 10*time.Millisecond, time.Millisecond); err != ErrTimeout {
+		t.Errorf("RequestAll() error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestUseBackend(t *testing.T) {
+	saved := backends
+	defer func() { backends, selected = saved, nil }()
+
+	fake := &fakeBackend{}
+	alsa := &namedBackend{name: "alsa"}
+	backends = []Backend{fake, alsa}
+
+	if b, _ := Default(); b != alsa {
+		t.Errorf("Default() = %s, want the preferred alsa backend", b.Name())
+	}
+	if err := Use("FAKE"); err != nil {
+		t.Fatalf("Use(FAKE) error = %v", err)
+	}
+	if b, _ := Default(); b != fake {
+		t.Errorf("Default() after Use = %s, want fake", b.Name())
+	}
+	if err := Use(""); err != nil {
+		t.Fatalf("Use(\"\") error = %v", err)
+	}
+	if b, _ := Default(); b != alsa {
+		t.Errorf("Default() after reset = %s, want alsa", b.Name())
+	}
+
+	for name, want := range map[string]string{
+		"portmidi": "-tags portmidi",
+		"virtual":  "-tags rtmidi",
+		"coremidi": "available: fake, alsa",
+	} {
+		if err := Use(name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Use(%q) error = %v, want %q", name, err, want)
+		}
+	}
+
+	backends = nil
+	if _, err := Default(); err != ErrNoBackend {
+		t.Errorf("Default() with no backends error = %v, want ErrNoBackend", err)
+	}
+}
@@ -0,0 +1,219 @@
+//go:build portmidi
+
+package mididevice
+
+/*
+#cgo LDFLAGS: -lportmidi
+#include <stdlib.h>
+#include <portmidi.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// The PortMidi backend needs cgo and libportmidi, so it is only compiled in
+// with -tags portmidi. It is an alternative where rtmidi is unreliable.
+func init() {
+	if C.Pm_Initialize() != C.pmNoError {
+		return
+	}
+	Register(&portMidiBackend{})
+}
+
+// portMidiPollInterval is how often input streams are polled for events
+const portMidiPollInterval = 2 * time.Millisecond
+
+type portMidiBackend struct{}
+
+func (p *portMidiBackend) Name() string { return "portmidi" }
+
+// devices returns the PortMidi device IDs of inputs or outputs by name
+func (p *portMidiBackend) devices(input bool) map[string]C.PmDeviceID {
+	ids := map[string]C.PmDeviceID{}
+	for id := C.PmDeviceID(0); id < C.PmDeviceID(C.Pm_CountDevices()); id++ {
+		info := C.Pm_GetDeviceInfo(id)
+		if info == nil {
+			continue
+		}
+		if (input && info.input != 0) || (!input && info.output != 0) {
+			ids[C.GoString(info.name)] = id
+		}
+	}
+	return ids
+}
+
+func (p *portMidiBackend) names(input bool) []string {
+	var names []string
+	for name := range p.devices(input) {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Outputs lists PortMidi output devices
+func (p *portMidiBackend) Outputs() ([]string, error) { return p.names(false), nil }
+
+// Inputs lists PortMidi input devices
+func (p *portMidiBackend) Inputs() ([]string, error) { return p.names(true), nil }
+
+// OpenOutput opens a PortMidi output device
+func (p *portMidiBackend) OpenOutput(name string) (Out, error) {
+	id, ok := p.devices(false)[name]
+	if !ok {
+		return nil, fmt.Errorf("MIDI port %q not found", name)
+	}
+	var stream unsafe.Pointer
+	if err := pmError(C.Pm_OpenOutput(&stream, id, nil, 0, nil, nil, 0)); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	return &portMidiOut{name: name, stream: stream}, nil
+}
+
+// OpenInput opens a PortMidi input device
+func (p *portMidiBackend) OpenInput(name string) (In, error) {
+	id, ok := p.devices(true)[name]
+	if !ok {
+		return nil, fmt.Errorf("MIDI port %q not found", name)
+	}
+	var stream unsafe.Pointer
+	if err := pmError(C.Pm_OpenInput(&stream, id, nil, 1024, nil, nil)); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	C.Pm_SetFilter(stream, C.PM_FILT_ACTIVE|C.PM_FILT_CLOCK)
+	return &portMidiIn{name: name, stream: stream}, nil
+}
+
+func pmError(err C.PmError) error {
+	if err >= 0 {
+		return nil
+	}
+	return errors.New(C.GoString(C.Pm_GetErrorText(err)))
+}
+
+type portMidiOut struct {
+	name   string
+	stream unsafe.Pointer
+}
+
+func (o *portMidiOut) Name() string { return o.name }
+func (o *portMidiOut) Close() error { return pmError(C.Pm_Close(o.stream)) }
+
+// Send writes SysEx as is and packs other messages into a PortMidi event
+func (o *portMidiOut) Send(msg []byte) error {
+	if len(msg) == 0 {
+		return nil
+	}
+	if msg[0] == 0xF0 {
+		buf := C.CBytes(msg)
+		defer C.free(buf)
+		return pmError(C.Pm_WriteSysEx(o.stream, 0, (*C.uchar)(buf)))
+	}
+	var packed C.PmMessage
+	for i := 0; i < len(msg) && i < 3; i++ {
+		packed |= C.PmMessage(msg[i]) << (8 * i)
+	}
+	return pmError(C.Pm_WriteShort(o.stream, 0, packed))
+}
+
+type portMidiIn struct {
+	name   string
+	stream unsafe.Pointer
+
+	mu      sync.Mutex
+	onMsg   func(msg []byte)
+	polling bool
+	closed  chan struct{}
+	done    chan struct{}
+}
+
+func (i *portMidiIn) Name() string { return i.name }
+
+func (i *portMidiIn) Close() error {
+	i.mu.Lock()
+	polling := i.polling
+	if polling {
+		close(i.closed)
+	}
+	i.mu.Unlock()
+	if polling {
+		<-i.done
+	}
+	return pmError(C.Pm_Close(i.stream))
+}
+
+// Listen starts delivering messages to onMsg. The stream is polled by one
+// goroutine until the port is closed; stop detaches the callback.
+func (i *portMidiIn) Listen(onMsg func(msg []byte)) (func(), error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.onMsg = onMsg
+	if !i.polling {
+		i.polling = true
+		i.closed = make(chan struct{})
+		i.done = make(chan struct{})
+		go i.poll()
+	}
+	return func() {
+		i.mu.Lock()
+		i.onMsg = nil
+		i.mu.Unlock()
+	}, nil
+}
+
+func (i *portMidiIn) poll() {
+	defer close(i.done)
+	var p streamParser
+	events := make([]C.PmEvent, 64)
+	emit := func(msg []byte) {
+		i.mu.Lock()
+		onMsg := i.onMsg
+		i.mu.Unlock()
+		if onMsg != nil {
+			onMsg(msg)
+		}
+	}
+
+	ticker := time.NewTicker(portMidiPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.closed:
+			return
+		case <-ticker.C:
+		}
+		for C.Pm_Poll(i.stream) == C.pmGotData {
+			n := int(C.Pm_Read(i.stream, &events[0], C.int32_t(len(events))))
+			if n <= 0 {
+				break
+			}
+			for _, ev := range events[:n] {
+				p.Feed(eventBytes(uint32(ev.message)), emit)
+			}
+		}
+	}
+}
+
+// eventBytes unpacks the bytes of a PortMidi event. SysEx arrives four bytes
+// per event; other messages use only the bytes their status calls for.
+func eventBytes(m uint32) []byte {
+	b := []byte{byte(m), byte(m >> 8), byte(m >> 16), byte(m >> 24)}
+	switch {
+	case b[0] < 0x80 || b[0] == 0xF0:
+		for j, c := range b {
+			if c == 0xF7 {
+				return b[:j+1]
+			}
+		}
+		return b
+	case b[0] >= 0xF8:
+		return b[:1]
+	default:
+		return b[:1+max(dataLen(b[0]), 0)]
+	}
+}
@@ -0,0 +1,91 @@
+package mididevice
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned when a device does not answer a request in time
+var ErrTimeout = errors.New("timed out waiting for device reply")
+
+// Request sends req and waits for the first incoming message accepted by
+// accept. Messages that are not accepted (other traffic, dumps from other
+// units) are ignored.
+func Request(out Out, in In, req []byte, accept func(msg []byte) bool, timeout time.Duration) ([]byte, error) {
+	replies := make(chan []byte, 1)
+	stop, err := in.Listen(func(msg []byte) {
+		if !accept(msg) {
+			return
+		}
+		reply := append([]byte(nil), msg...)
+		select {
+		case replies <- reply:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	if err := out.Send(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// RequestAll sends req and collects every accepted reply, for dumps that span
+// several messages. It waits up to timeout for the first reply and returns
+// once no further reply has arrived for idle. A nil req only listens, for
+// dumps started from the device's front panel.
+func RequestAll(out Out, in In, req []byte, accept func(msg []byte) bool, timeout, idle time.Duration) ([][]byte, error) {
+	var (
+		mu      sync.Mutex
+		replies [][]byte
+	)
+	arrived := make(chan struct{}, 1)
+	stop, err := in.Listen(func(msg []byte) {
+		if !accept(msg) {
+			return
+		}
+		mu.Lock()
+		replies = append(replies, append([]byte(nil), msg...))
+		mu.Unlock()
+		select {
+		case arrived <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	if req != nil {
+		if err := out.Send(req); err != nil {
+			return nil, err
+		}
+	}
+
+	wait := timeout
+	for {
+		select {
+		case <-arrived:
+			wait = idle
+		case <-time.After(wait):
+			mu.Lock()
+			defer mu.Unlock()
+			if len(replies) == 0 {
+				return nil, ErrTimeout
+			}
+			return replies, nil
+		}
+	}
+}
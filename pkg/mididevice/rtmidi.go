@@ -0,0 +1,52 @@
+//go:build rtmidi
+
+package mididevice
+
+import (
+	"gitlab.com/gomidi/midi/v2/drivers/rtmididrv"
+)
+
+// The rtmidi backend needs cgo and the platform MIDI headers (ALSA on Linux),
+// so it is only compiled in with -tags rtmidi. It also provides the virtual
+// backend, whose ports are created for other applications to connect to.
+func init() {
+	drv, err := rtmididrv.New()
+	if err != nil {
+		return
+	}
+	Register(NewDriverBackend("rtmidi", drv))
+	Register(&virtualBackend{drv: drv})
+}
+
+// virtualBackend creates virtual ports (ALSA sequencer, CoreMIDI) under the
+// requested name instead of opening hardware ports. Not supported on Windows.
+type virtualBackend struct {
+	drv *rtmididrv.Driver
+}
+
+func (v *virtualBackend) Name() string       { return "virtual" }
+func (v *virtualBackend) CreatesPorts() bool { return true }
+
+// Outputs returns no ports: virtual ports exist only once opened
+func (v *virtualBackend) Outputs() ([]string, error) { return nil, nil }
+
+// Inputs returns no ports: virtual ports exist only once opened
+func (v *virtualBackend) Inputs() ([]string, error) { return nil, nil }
+
+// OpenOutput creates a virtual output port that other applications read from
+func (v *virtualBackend) OpenOutput(name string) (Out, error) {
+	out, err := v.drv.OpenVirtualOut(name)
+	if err != nil {
+		return nil, err
+	}
+	return driverOut{out}, nil
+}
+
+// OpenInput creates a virtual input port that other applications send to
+func (v *virtualBackend) OpenInput(name string) (In, error) {
+	in, err := v.drv.OpenVirtualIn(name)
+	if err != nil {
+		return nil, err
+	}
+	return driverIn{in}, nil
+}
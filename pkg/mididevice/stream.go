@@ -0,0 +1,90 @@
+package mididevice
+
+// streamParser splits a raw MIDI byte stream (as read from a serial or raw
+// device) into complete messages, handling running status, SysEx, and
+// real-time bytes interleaved with other messages
+type streamParser struct {
+	msg     []byte
+	status  byte // Running status of the current channel message
+	need    int  // Data bytes still needed to complete msg
+	inSysEx bool
+}
+
+// dataLen returns the number of data bytes that follow a status byte, or -1
+// for status bytes that do not start a message of fixed length
+func dataLen(status byte) int {
+	switch {
+	case status >= 0x80 && status < 0xC0, status >= 0xE0 && status < 0xF0:
+		return 2
+	case status >= 0xC0 && status < 0xE0:
+		return 1
+	case status == 0xF1 || status == 0xF3:
+		return 1
+	case status == 0xF2:
+		return 2
+	case status == 0xF6:
+		return 0
+	}
+	return -1
+}
+
+// Feed parses data and calls emit for every completed message. Emitted
+// slices are not retained by the parser.
+func (p *streamParser) Feed(data []byte, emit func(msg []byte)) {
+	for _, b := range data {
+		switch {
+		case b >= 0xF8:
+			// Real-time messages may appear anywhere, even inside SysEx
+			emit([]byte{b})
+
+		case b == 0xF0:
+			p.inSysEx = true
+			p.status = 0
+			p.msg = append(p.msg[:0], b)
+
+		case b == 0xF7:
+			if p.inSysEx {
+				p.inSysEx = false
+				emit(append(append([]byte(nil), p.msg...), b))
+			}
+			p.msg = p.msg[:0]
+
+		case b >= 0x80:
+			// Any other status byte ends an unterminated SysEx
+			p.inSysEx = false
+			n := dataLen(b)
+			if n < 0 {
+				p.status = 0
+				p.msg = p.msg[:0]
+				continue
+			}
+			p.status = 0
+			if b < 0xF0 {
+				p.status = b
+			}
+			p.msg = append(p.msg[:0], b)
+			p.need = n
+			if n == 0 {
+				emit([]byte{b})
+				p.msg = p.msg[:0]
+			}
+
+		case p.inSysEx:
+			p.msg = append(p.msg, b)
+
+		default:
+			if len(p.msg) == 0 {
+				if p.status == 0 {
+					continue // Stray data byte
+				}
+				p.msg = append(p.msg, p.status)
+				p.need = dataLen(p.status)
+			}
+			p.msg = append(p.msg, b)
+			if p.need--; p.need == 0 {
+				emit(append([]byte(nil), p.msg...))
+				p.msg = p.msg[:0]
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package mididevice
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStreamParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		want   [][]byte
+	}{
+		{
+			name:   "note on and off",
+			chunks: [][]byte{{0x90, 0x3C, 0x64, 0x80, 0x3C, 0x00}},
+			want:   [][]byte{{0x90, 0x3C, 0x64}, {0x80, 0x3C, 0x00}},
+		},
+		{
+			name:   "running status",
+			chunks: [][]byte{{0x90, 0x3C, 0x64, 0x3E, 0x64, 0x40, 0x00}},
+			want:   [][]byte{{0x90, 0x3C, 0x64}, {0x90, 0x3E, 0x64}, {0x90, 0x40, 0x00}},
+		},
+		{
+			name:   "split across reads",
+			chunks: [][]byte{{0xC0}, {0x05, 0xB0, 0x07}, {0x7F}},
+			want:   [][]byte{{0xC0, 0x05}, {0xB0, 0x07, 0x7F}},
+		},
+		{
+			name:   "sysex with clock inside",
+			chunks: [][]byte{{0xF0, 0x00, 0x20, 0xF8, 0x32}, {0x01, 0xF7}},
+			want:   [][]byte{{0xF8}, {0xF0, 0x00, 0x20, 0x32, 0x01, 0xF7}},
+		},
+		{
+			name:   "system common cancels running status",
+			chunks: [][]byte{{0x90, 0x3C, 0x64, 0xF2, 0x10, 0x00, 0x3E, 0xF6}},
+			want:   [][]byte{{0x90, 0x3C, 0x64}, {0xF2, 0x10, 0x00}, {0xF6}},
+		},
+		{
+			name:   "unterminated sysex and stray bytes",
+			chunks: [][]byte{{0x12, 0xF0, 0x01, 0x02, 0x90, 0x3C, 0x64}},
+			want:   [][]byte{{0x90, 0x3C, 0x64}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p streamParser
+			var got [][]byte
+			for _, chunk := range tt.chunks {
+				p.Feed(chunk, func(msg []byte) { got = append(got, msg) })
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d messages % X, want %d % X", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if !bytes.Equal(got[i], tt.want[i]) {
+					t.Errorf("message %d = % X, want % X", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Package monitor decodes live MIDI traffic for the monitor command: channel
+// and system messages are named, SysEx is identified against the
+// manufacturer database, and pattern dumps of known devices are decoded
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// maxHexBytes limits the bytes printed for long messages
+const maxHexBytes = 16
+
+var channelMessages = map[byte]string{
+	0x80: "Note Off",
+	0x90: "Note On",
+	0xA0: "Poly Aftertouch",
+	0xB0: "Control Change",
+	0xC0: "Program Change",
+	0xD0: "Channel Pressure",
+	0xE0: "Pitch Bend",
+}
+
+var systemMessages = map[byte]string{
+	0xF1: "MTC Quarter Frame",
+	0xF2: "Song Position",
+	0xF3: "Song Select",
+	0xF6: "Tune Request",
+	0xF8: "Clock",
+	0xFA: "Start",
+	0xFB: "Continue",
+	0xFC: "Stop",
+	0xFE: "Active Sensing",
+	0xFF: "Reset",
+}
+
+// Decode returns a one-line description of a MIDI message, such as
+// "Note On ch 1 C3 vel 100" or "SysEx Behringer TD-3 (device ID 0), 115 bytes"
+func Decode(msg []byte) string {
+	if len(msg) == 0 {
+		return "empty message"
+	}
+	status := msg[0]
+	switch {
+	case status == sysex.Start:
+		return decodeSysEx(msg)
+	case status >= 0xF0:
+		if name, ok := systemMessages[status]; ok {
+			return name
+		}
+		return fmt.Sprintf("Undefined 0x%02X", status)
+	case status < 0x80:
+		return "Data without status"
+	}
+
+	name := channelMessages[status&0xF0]
+	ch := int(status&0x0F) + 1
+	data := func(i int) int {
+		if i < len(msg) {
+			return int(msg[i])
+		}
+		return 0
+	}
+	switch status & 0xF0 {
+	case 0x80, 0x90:
+		if status&0xF0 == 0x90 && data(2) == 0 {
+			name = "Note Off"
+		}
+		return fmt.Sprintf("%s ch %d %s vel %d", name, ch, converter.NoteName(uint8(data(1))), data(2))
+	case 0xA0:
+		return fmt.Sprintf("%s ch %d %s %d", name, ch, converter.NoteName(uint8(data(1))), data(2))
+	case 0xB0:
+		return fmt.Sprintf("%s ch %d cc %d = %d", name, ch, data(1), data(2))
+	case 0xC0:
+		return fmt.Sprintf("%s ch %d program %d", name, ch, data(1)+1)
+	case 0xD0:
+		return fmt.Sprintf("%s ch %d %d", name, ch, data(1))
+	default:
+		return fmt.Sprintf("%s ch %d %+d", name, ch, (data(1)|data(2)<<7)-8192)
+	}
+}
+
+// decodeSysEx identifies the sender of a SysEx message and, for universal
+// messages, their sub-IDs
+func decodeSysEx(msg []byte) string {
+	if err := sysex.Validate(msg); err != nil {
+		return fmt.Sprintf("SysEx, %d bytes: %v", len(msg), err)
+	}
+	ident, err := sysex.Identify(msg)
+	if err != nil {
+		return fmt.Sprintf("SysEx, %d bytes: %v", len(msg), err)
+	}
+
+	desc := fmt.Sprintf("SysEx %s, %d bytes", ident, len(msg))
+	if (msg[1] == 0x7E || msg[1] == 0x7F) && len(msg) > 4 {
+		desc = fmt.Sprintf("SysEx %s (device 0x%02X, sub-ID %02X %02X), %d bytes",
+			ident.Manufacturer.Name, msg[2], msg[3], msg[4], len(msg))
+		if msg[1] == 0x7E && msg[3] == 0x06 {
+			switch msg[4] {
+			case 0x01:
+				desc += ": identity request"
+			case 0x02:
+				desc += ": identity reply"
+			}
+		}
+	}
+	return desc
+}
+
+// DecodePattern decodes a SysEx pattern dump of any registered device. The
+// device named by the model database is tried first, then every other one.
+func DecodePattern(msg []byte) (*converter.Pattern, devices.Info, bool) {
+	var candidates []devices.Info
+	if ident, err := sysex.Identify(msg); err == nil && ident.Model != nil && ident.Model.Device != "" {
+		if info, ok := devices.LookupInfo(ident.Model.Device); ok {
+			candidates = append(candidates, info)
+		}
+	}
+	candidates = append(candidates, devices.List()...)
+
+	for _, info := range candidates {
+		if p, err := info.New().ParseSyx(msg); err == nil {
+			return p, info, true
+		}
+	}
+	return nil, devices.Info{}, false
+}
+
+// Monitor prints incoming messages with a timestamp, raw bytes, and their
+// decoding, and can copy SysEx to a capture file
+type Monitor struct {
+	W io.Writer
+	// Capture receives every SysEx message, e.g. a .syx file; nil disables it
+	Capture io.Writer
+	// HideRealtime skips clock, active sensing, and other real-time messages
+	HideRealtime bool
+	// Patterns draws decoded pattern dumps as a step grid
+	Patterns bool
+	// NoColor disables colors in pattern grids
+	NoColor bool
+
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+// Handle prints a message. It is safe to call from a MIDI listener.
+func (m *Monitor) Handle(msg []byte) {
+	if len(msg) == 0 || (m.HideRealtime && msg[0] >= 0xF8) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.start.IsZero() {
+		m.start = time.Now()
+	}
+	m.count++
+	elapsed := time.Since(m.start).Seconds()
+
+	fmt.Fprintf(m.W, "%9.3f  %-48s  %s\n", elapsed, hexBytes(msg), Decode(msg))
+	if msg[0] != sysex.Start {
+		return
+	}
+	if m.Capture != nil {
+		if _, err := m.Capture.Write(msg); err != nil {
+			fmt.Fprintf(m.W, "           capture failed: %v\n", err)
+		}
+	}
+	if p, info, ok := DecodePattern(msg); ok {
+		fmt.Fprintf(m.W, "           %s pattern: %d steps\n", info.Name, p.Length)
+		if m.Patterns {
+			fmt.Fprintln(m.W, indent(render.Grid(p, render.GridOptions{NoColor: m.NoColor}), "           "))
+		}
+	}
+}
+
+// Count returns the number of messages printed
+func (m *Monitor) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.count
+}
+
+// hexBytes formats a message as hex, eliding the middle of long messages
+func hexBytes(msg []byte) string {
+	if len(msg) <= maxHexBytes {
+		return fmt.Sprintf("% X", msg)
+	}
+	return fmt.Sprintf("% X .. % X", msg[:maxHexBytes-4], msg[len(msg)-3:])
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
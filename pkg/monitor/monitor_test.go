@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		msg  []byte
+		want string
+	}{
+		{[]byte{0x90, 0x3C, 0x64}, "Note On ch 1 C4 vel 100"},
+		{[]byte{0x91, 0x3C, 0x00}, "Note Off ch 2 C4 vel 0"},
+		{[]byte{0xB0, 0x4A, 0x7F}, "Control Change ch 1 cc 74 = 127"},
+		{[]byte{0xCF, 0x04}, "Program Change ch 16 program 5"},
+		{[]byte{0xE0, 0x00, 0x40}, "Pitch Bend ch 1 +0"},
+		{[]byte{0xF8}, "Clock"},
+		{[]byte{0xF0, 0x7E, 0x7F, 0x06, 0x01, 0xF7}, "identity request"},
+		{[]byte{0xF0, 0x00, 0x20, 0x32, 0x00, 0x01, 0x42, 0xF7}, "SysEx Behringer TD-3 (device ID 0), 8 bytes"},
+		{[]byte{0xF0, 0x41, 0x10}, "expected end byte"},
+	}
+	for _, tt := range tests {
+		if got := Decode(tt.msg); !strings.Contains(got, tt.want) {
+			t.Errorf("Decode(% X) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestMonitorHandle(t *testing.T) {
+	dump, err := devices.NewTD3().GenerateSyx(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 45, Gate: true, Velocity: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, capture bytes.Buffer
+	m := &Monitor{W: &out, Capture: &capture, HideRealtime: true, Patterns: true, NoColor: true}
+	m.Handle([]byte{0xF8})
+	m.Handle([]byte{0x90, 0x2D, 0x64})
+	m.Handle(dump)
+
+	if m.Count() != 2 {
+		t.Errorf("Count() = %d, want 2 (clock hidden)", m.Count())
+	}
+	if !bytes.Equal(capture.Bytes(), dump) {
+		t.Errorf("capture = % X, want only the SysEx dump", capture.Bytes())
+	}
+	text := out.String()
+	for _, want := range []string{"Note On ch 1 A2 vel 100", "F0 00 20 32", "TD-3 pattern: 16 steps", "A2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pack
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/library"
+)
+
+// Install adds a pack's patterns to lib, tagged with the pack's tags, and
+// gathers them in collection, or a collection named after the pack if empty.
+// Patterns are stored for the pack's device, or device if it names none.
+func Install(lib *library.Library, p *Pack, collection, device string) ([]*library.Entry, error) {
+	meta := p.Manifest.Pack
+	if meta.Device != "" {
+		device = meta.Device
+	}
+	entries := make([]*library.Entry, 0, len(p.Manifest.Files))
+	ids := make([]string, 0, len(p.Manifest.Files))
+	for _, f := range p.Manifest.Files {
+		name := strings.TrimSuffix(path.Base(f.Path), path.Ext(f.Path))
+		entry, err := lib.AddCreated(name, f.Format, device, p.Data[f.Path], meta.Created)
+		if err != nil {
+			return entries, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		if len(meta.Tags) > 0 {
+			if entry, err = lib.Tag(entry.ID, meta.Tags...); err != nil {
+				return entries, err
+			}
+		}
+		entries = append(entries, entry)
+		ids = append(ids, entry.ID)
+	}
+
+	if collection == "" {
+		collection = meta.Name
+	}
+	if _, err := lib.AddToCollection(collection, ids...); err != nil {
+		return entries, err
+	}
+	return entries, nil
+}
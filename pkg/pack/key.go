@@ -0,0 +1,112 @@
+package pack
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Prefixes of the text forms of keys
+const (
+	privateKeyPrefix = "stpk-secret:"
+	publicKeyPrefix  = "stpk:"
+)
+
+// DefaultKeyPath returns the default signing key location in the user
+// config directory
+func DefaultKeyPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "synthtribe2midi", "pack.key"), nil
+}
+
+// LoadKey reads a signing key written by SaveKey
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := strings.CutPrefix(strings.TrimSpace(string(data)), privateKeyPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a pack signing key", path)
+	}
+	seed, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s is not a pack signing key", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// SaveKey writes a signing key to path, readable only by its owner, and its
+// public key to path.pub
+func SaveKey(path string, key ed25519.PrivateKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	secret := privateKeyPrefix + base64.StdEncoding.EncodeToString(key.Seed()) + "\n"
+	if err := converter.WriteFileAtomic(path, []byte(secret), 0600); err != nil {
+		return fmt.Errorf("failed to write signing key: %w", err)
+	}
+	public := FormatPublicKey(key.Public().(ed25519.PublicKey)) + "\n"
+	if err := converter.WriteFileAtomic(path+".pub", []byte(public), 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	return nil
+}
+
+// LoadOrCreateKey reads the signing key at path, generating and saving a new
+// one if there is none; created reports which
+func LoadOrCreateKey(path string) (key ed25519.PrivateKey, created bool, err error) {
+	key, err = LoadKey(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		return key, false, err
+	}
+	if _, key, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		return nil, false, err
+	}
+	if err := SaveKey(path, key); err != nil {
+		return nil, false, err
+	}
+	return key, true, nil
+}
+
+// FormatPublicKey returns the text form of a public key, e.g.
+// "stpk:Mh3F...", which pack authors publish so users can check their packs
+func FormatPublicKey(key ed25519.PublicKey) string {
+	return publicKeyPrefix + base64.StdEncoding.EncodeToString(key)
+}
+
+// ParsePublicKey parses the text form of a public key, or reads it from a
+// .pub file
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, publicKeyPrefix) {
+		data, err := os.ReadFile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %q: want %s... or a .pub file", s, publicKeyPrefix)
+		}
+		s = strings.TrimSpace(string(data))
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, publicKeyPrefix))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key %q", s)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// Fingerprint returns a short ID of a public key for display
+func Fingerprint(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
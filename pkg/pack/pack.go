@@ -0,0 +1,299 @@
+// Package pack builds and verifies pattern packs: zstd-compressed tar
+// archives of pattern files with a manifest of their checksums, signed with
+// an Ed25519 key so anyone installing a pack can check it is complete,
+// unmodified, and from the signer they expect.
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Extension is the file extension of pattern packs
+const Extension = ".stpk"
+
+// manifestVersion is the manifest format version written by Build
+const manifestVersion = 1
+
+// Archive members besides the pattern files, which live under patterns/
+const (
+	manifestName  = "manifest.json"
+	signatureName = "manifest.sig"
+)
+
+// Limits on what Read decompresses, so a small pack cannot unpack into
+// gigabytes: the size of one archive member, and of all of them together
+var (
+	maxMemberSize   int64 = 8 << 20
+	maxUnpackedSize int64 = 128 << 20
+)
+
+// ErrInvalid reports a pack that fails verification: a missing, extra, or
+// modified file, or a bad signature
+var ErrInvalid = errors.New("invalid pack")
+
+// Metadata describes a pack
+type Metadata struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	Description string    `json:"description,omitempty"`
+	License     string    `json:"license,omitempty"`
+	Device      string    `json:"device,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// File is one pattern file of a pack
+type File struct {
+	Path   string `json:"path"` // slash-separated, relative to the pack's root
+	Format string `json:"format"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists a pack's metadata and the checksum of every pattern file;
+// it is what the signature covers
+type Manifest struct {
+	Version int      `json:"version"`
+	Pack    Metadata `json:"pack"`
+	Files   []File   `json:"files"`
+}
+
+// signature is the manifest.sig member: the signer's public key and its
+// signature of the manifest bytes
+type signature struct {
+	Key       []byte `json:"key"`
+	Signature []byte `json:"signature"`
+}
+
+// Pack is a verified pattern pack
+type Pack struct {
+	Manifest Manifest
+	// Signer is the public key that signed the manifest
+	Signer ed25519.PublicKey
+	// Data holds the content of each file, by path
+	Data map[string][]byte
+}
+
+// Build collects the pattern files (.seq, .syx, .mid, .303) under dir into
+// a pack, ready to be signed by Write. Other files are left out.
+func Build(dir string, meta Metadata) (*Pack, error) {
+	if meta.Name == "" {
+		meta.Name = filepath.Base(filepath.Clean(dir))
+	}
+	if meta.Created.IsZero() {
+		meta.Created = time.Now().UTC()
+	}
+
+	p := &Pack{
+		Manifest: Manifest{Version: manifestVersion, Pack: meta},
+		Data:     make(map[string][]byte),
+	}
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		format := converter.DetectFormat(name)
+		if format == converter.FormatUnknown || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		p.Manifest.Files = append(p.Manifest.Files, File{Path: rel, Format: string(format), Size: len(data), SHA256: checksum(data)})
+		p.Data[rel] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack directory: %w", err)
+	}
+	if len(p.Manifest.Files) == 0 {
+		return nil, fmt.Errorf("no pattern files (.seq, .syx, .mid, .303) in %s", dir)
+	}
+	return p, nil
+}
+
+// Write writes the pack to w, signing its manifest with key
+func (p *Pack) Write(w io.Writer, key ed25519.PrivateKey) error {
+	m, err := json.MarshalIndent(p.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	p.Signer = key.Public().(ed25519.PublicKey)
+	sig, err := json.Marshal(signature{Key: p.Signer, Signature: ed25519.Sign(key, m)})
+	if err != nil {
+		return err
+	}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	mod := p.Manifest.Pack.Created
+	if err := writeMember(tw, manifestName, m, mod); err != nil {
+		return err
+	}
+	if err := writeMember(tw, signatureName, sig, mod); err != nil {
+		return err
+	}
+	for _, f := range p.Manifest.Files {
+		if err := writeMember(tw, "patterns/"+f.Path, p.Data[f.Path], mod); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// WriteFile writes the pack to path
+func (p *Pack) WriteFile(path string, key ed25519.PrivateKey) error {
+	return converter.WriteAtomic(path, 0644, func(w io.Writer) error {
+		return p.Write(w, key)
+	})
+}
+
+func writeMember(tw *tar.Writer, name string, data []byte, mod time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: mod}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write pack: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write pack: %w", err)
+	}
+	return nil
+}
+
+// Read reads a pack from r and verifies it: the manifest's signature, and
+// that the pack holds exactly the files the manifest lists, with their
+// checksums. It fails with ErrInvalid if anything does not match.
+func Read(r io.Reader) (*Pack, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var manifest, sig []byte
+	var unpacked int64
+	data := make(map[string][]byte)
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pack: %w", err)
+		}
+		if hdr.Size > maxMemberSize {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalid, hdr.Name, maxMemberSize)
+		}
+		if unpacked += hdr.Size; unpacked > maxUnpackedSize {
+			return nil, fmt.Errorf("%w: files larger than %d bytes in total", ErrInvalid, maxUnpackedSize)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(io.LimitReader(tr, maxMemberSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pack: %w", err)
+		}
+		switch name := hdr.Name; {
+		case name == manifestName:
+			manifest = b
+		case name == signatureName:
+			sig = b
+		case strings.HasPrefix(name, "patterns/"):
+			data[strings.TrimPrefix(name, "patterns/")] = b
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalid, manifestName)
+	}
+	if sig == nil {
+		return nil, fmt.Errorf("%w: unsigned (missing %s)", ErrInvalid, signatureName)
+	}
+
+	var s signature
+	if err := json.Unmarshal(sig, &s); err != nil || len(s.Key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalid)
+	}
+	if !ed25519.Verify(s.Key, manifest, s.Signature) {
+		return nil, fmt.Errorf("%w: signature does not match the manifest", ErrInvalid)
+	}
+
+	p := &Pack{Signer: ed25519.PublicKey(s.Key), Data: data}
+	if err := json.Unmarshal(manifest, &p.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: malformed manifest: %v", ErrInvalid, err)
+	}
+	if p.Manifest.Version > manifestVersion {
+		return nil, fmt.Errorf("pack version %d is newer than supported (%d)", p.Manifest.Version, manifestVersion)
+	}
+	for _, f := range p.Manifest.Files {
+		if path.IsAbs(f.Path) || slices.Contains(strings.Split(f.Path, "/"), "..") {
+			return nil, fmt.Errorf("%w: unsafe path %q", ErrInvalid, f.Path)
+		}
+		d, ok := data[f.Path]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalid, f.Path)
+		}
+		if len(d) != f.Size || checksum(d) != f.SHA256 {
+			return nil, fmt.Errorf("%w: %s does not match its checksum", ErrInvalid, f.Path)
+		}
+	}
+	if len(data) != len(p.Manifest.Files) {
+		for name := range data {
+			if !slices.ContainsFunc(p.Manifest.Files, func(f File) bool { return f.Path == name }) {
+				return nil, fmt.Errorf("%w: %s is not in the manifest", ErrInvalid, name)
+			}
+		}
+	}
+	return p, nil
+}
+
+// ReadFile reads and verifies the pack at path
+func ReadFile(path string) (*Pack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Read(bytes.NewReader(data))
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,199 @@
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestBuildWriteRead(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"acid.seq":         "seq data",
+		"bank/bass.syx":    "\xf0syx\xf7",
+		"README.md":        "not a pattern",
+		".git/hooks/x.seq": "ignored",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+
+	p, err := Build(dir, Metadata{Name: "Acid Essentials", Author: "jc"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := p.Write(&buf, key); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got.Manifest.Pack.Name != "Acid Essentials" || len(got.Manifest.Files) != 2 {
+		t.Fatalf("manifest = %+v", got.Manifest)
+	}
+	if string(got.Data["bank/bass.syx"]) != "\xf0syx\xf7" || got.Manifest.Files[1].Format != "syx" {
+		t.Errorf("bank/bass.syx = %q, %+v", got.Data["bank/bass.syx"], got.Manifest.Files[1])
+	}
+	if !got.Signer.Equal(key.Public()) {
+		t.Error("Signer is not the signing key")
+	}
+
+	if _, err := Build(t.TempDir(), Metadata{}); err == nil {
+		t.Error("Build() of a directory without patterns should fail")
+	}
+}
+
+func TestReadTampered(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acid.seq"), []byte("seq data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Build(dir, Metadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	var buf bytes.Buffer
+	if err := p.Write(&buf, key); err != nil {
+		t.Fatal(err)
+	}
+	members := readMembers(t, buf.Bytes())
+
+	tests := []struct {
+		name   string
+		change func(m map[string][]byte)
+	}{
+		{"modified pattern", func(m map[string][]byte) { m["patterns/acid.seq"] = []byte("evil data") }},
+		{"missing pattern", func(m map[string][]byte) { delete(m, "patterns/acid.seq") }},
+		{"extra pattern", func(m map[string][]byte) { m["patterns/extra.seq"] = []byte("x") }},
+		{"modified manifest", func(m map[string][]byte) {
+			m[manifestName] = bytes.Replace(m[manifestName], []byte(`"acid.seq"`), []byte(`"acid2.seq"`), 1)
+		}},
+		{"unsigned", func(m map[string][]byte) { delete(m, signatureName) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := make(map[string][]byte)
+			for k, v := range members {
+				m[k] = v
+			}
+			tt.change(m)
+			if _, err := Read(bytes.NewReader(writeMembers(t, m))); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Read() error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestReadTooLarge(t *testing.T) {
+	defer func(member, total int64) { maxMemberSize, maxUnpackedSize = member, total }(maxMemberSize, maxUnpackedSize)
+	maxMemberSize, maxUnpackedSize = 1024, 2048
+
+	tests := []struct {
+		name    string
+		members map[string][]byte
+	}{
+		{"large member", map[string][]byte{"patterns/big.seq": make([]byte, 1025)}},
+		{"large total", map[string][]byte{
+			"patterns/a.seq": make([]byte, 1000),
+			"patterns/b.seq": make([]byte, 1000),
+			"patterns/c.seq": make([]byte, 1000),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(writeMembers(t, tt.members))); !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "larger than") {
+				t.Errorf("Read() error = %v, want a size error", err)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "pack.key")
+	key, created, err := LoadOrCreateKey(path)
+	if err != nil || !created {
+		t.Fatalf("LoadOrCreateKey() = %v, %v", created, err)
+	}
+	again, created, err := LoadOrCreateKey(path)
+	if err != nil || created || !again.Equal(key) {
+		t.Fatalf("second LoadOrCreateKey() = %v, %v", created, err)
+	}
+
+	public := key.Public().(ed25519.PublicKey)
+	for _, s := range []string{FormatPublicKey(public), path + ".pub"} {
+		got, err := ParsePublicKey(s)
+		if err != nil || !got.Equal(public) {
+			t.Errorf("ParsePublicKey(%q) = %v, %v", s, got, err)
+		}
+	}
+	if _, err := ParsePublicKey("stpk:AAAA"); err == nil {
+		t.Error("ParsePublicKey() of a short key should fail")
+	}
+	if len(Fingerprint(public)) != 16 {
+		t.Errorf("Fingerprint() = %q", Fingerprint(public))
+	}
+}
+
+func readMembers(t *testing.T, pack []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zstd.NewReader(bytes.NewReader(pack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	m := make(map[string][]byte)
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return m
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m[hdr.Name], err = io.ReadAll(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeMembers(t *testing.T, m map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, _ := zstd.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, data := range m {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,220 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RegistryEnv is the environment variable naming the default pack registry
+const RegistryEnv = "SYNTHTRIBE2MIDI_REGISTRY"
+
+// indexVersion is the newest registry index format understood
+const indexVersion = 1
+
+// maxPackSize limits the size of a downloaded pack
+const maxPackSize = 64 << 20
+
+// ErrNotFound reports a pack the registry does not list
+var ErrNotFound = errors.New("pack not found")
+
+// Listing is a pack in a registry index
+type Listing struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Device      string   `json:"device,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// URL locates the pack file, absolute or relative to the index
+	URL string `json:"url"`
+	// SHA256 is the checksum of the pack file, if given
+	SHA256 string `json:"sha256,omitempty"`
+	// Key is the public key (stpk:...) the pack must be signed with, if given
+	Key string `json:"key,omitempty"`
+}
+
+// Index is a registry's list of packs: a static JSON file that any web
+// server can host next to the packs it lists
+type Index struct {
+	Version int       `json:"version"`
+	Packs   []Listing `json:"packs"`
+}
+
+// Registry is a client of a pack registry
+type Registry struct {
+	index  *url.URL
+	client *http.Client
+}
+
+// NewRegistry creates a client of the registry whose index is at indexURL;
+// a URL not ending in .json is taken as the registry's root, holding
+// index.json
+func NewRegistry(indexURL string) (*Registry, error) {
+	u, err := url.Parse(indexURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid pack registry URL %q", indexURL)
+	}
+	if !strings.HasSuffix(u.Path, ".json") {
+		u = u.JoinPath("index.json")
+	}
+	return &Registry{
+		index:  u,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// URL returns the location of the registry's index
+func (r *Registry) URL() string {
+	return r.index.String()
+}
+
+// get fetches a URL, reading at most limit bytes of the response
+func (r *Registry) get(ctx context.Context, u string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("pack registry request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pack registry: GET %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("pack registry: GET %s: %w", u, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("pack registry: %s is larger than %d bytes", u, limit)
+	}
+	return data, nil
+}
+
+// Index fetches the registry's index
+func (r *Registry) Index(ctx context.Context) (*Index, error) {
+	data, err := r.get(ctx, r.URL(), maxPackSize)
+	if err != nil {
+		return nil, err
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid pack registry index: %w", err)
+	}
+	if index.Version > indexVersion {
+		return nil, fmt.Errorf("pack registry index version %d is newer than supported (%d)", index.Version, indexVersion)
+	}
+	return &index, nil
+}
+
+// Search returns the listed packs matching query (see Listing.Matches); an
+// empty query matches all
+func (r *Registry) Search(ctx context.Context, query string) ([]Listing, error) {
+	index, err := r.Index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found []Listing
+	for _, l := range index.Packs {
+		if l.Matches(query) {
+			found = append(found, l)
+		}
+	}
+	return found, nil
+}
+
+// Matches reports whether every word of query appears, ignoring case, in
+// the pack's name, description, author, device, or tags
+func (l Listing) Matches(query string) bool {
+	text := strings.ToLower(strings.Join(append([]string{l.Name, l.Description, l.Author, l.Device}, l.Tags...), " "))
+	return !slices.ContainsFunc(strings.Fields(strings.ToLower(query)), func(w string) bool {
+		return !strings.Contains(text, w)
+	})
+}
+
+// Find returns the listed pack named name, ignoring case: its newest version,
+// or the given one for "name@version"
+func (r *Registry) Find(ctx context.Context, name string) (Listing, error) {
+	index, err := r.Index(ctx)
+	if err != nil {
+		return Listing{}, err
+	}
+	name, version, _ := strings.Cut(name, "@")
+	var found *Listing
+	for i, l := range index.Packs {
+		if !strings.EqualFold(l.Name, name) || (version != "" && l.Version != version) {
+			continue
+		}
+		if found == nil || compareVersions(l.Version, found.Version) > 0 {
+			found = &index.Packs[i]
+		}
+	}
+	if found == nil {
+		if version != "" {
+			return Listing{}, fmt.Errorf("%w: %s version %s", ErrNotFound, name, version)
+		}
+		return Listing{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return *found, nil
+}
+
+// Download fetches a listed pack and verifies it: its checksum and signer,
+// if the listing gives them, as well as everything Read checks
+func (r *Registry) Download(ctx context.Context, l Listing) (*Pack, error) {
+	ref, err := url.Parse(l.URL)
+	if err != nil || l.URL == "" {
+		return nil, fmt.Errorf("invalid URL %q for pack %s", l.URL, l.Name)
+	}
+	data, err := r.get(ctx, r.index.ResolveReference(ref).String(), maxPackSize)
+	if err != nil {
+		return nil, err
+	}
+	if l.SHA256 != "" && !strings.EqualFold(checksum(data), l.SHA256) {
+		return nil, fmt.Errorf("%w: %s does not match the registry's checksum", ErrInvalid, l.Name)
+	}
+	p, err := Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if l.Key != "" {
+		key, err := ParsePublicKey(l.Key)
+		if err != nil {
+			return nil, fmt.Errorf("registry key of %s: %w", l.Name, err)
+		}
+		if !p.Signer.Equal(key) {
+			return nil, fmt.Errorf("%w: %s is signed by %s, not the registry's key %s", ErrInvalid, l.Name, Fingerprint(p.Signer), Fingerprint(key))
+		}
+	}
+	return p, nil
+}
+
+// compareVersions orders dotted versions numerically where both parts are
+// numbers, e.g. 1.10 after 1.9, and as text otherwise
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		x, errx := strconv.Atoi(as[i])
+		y, erry := strconv.Atoi(bs[i])
+		if errx == nil && erry == nil {
+			if x != y {
+				return x - y
+			}
+			continue
+		}
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return len(as) - len(bs)
+}
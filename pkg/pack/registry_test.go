@@ -0,0 +1,134 @@
+package pack
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/library"
+)
+
+// testRegistry serves an index of two versions of a signed pack, and a
+// second pack, as a static registry
+func testRegistry(t *testing.T) (*Registry, ed25519.PublicKey, *Index) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "acid.seq"), []byte("seq data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	public, key, _ := ed25519.GenerateKey(rand.Reader)
+	p, err := Build(dir, Metadata{Name: "Acid Essentials", Device: "td3", Tags: []string{"acid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.Write(&buf, key); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	index := &Index{Version: 1, Packs: []Listing{
+		{Name: "Acid Essentials", Version: "1.9", URL: "packs/acid-1.9.stpk", Tags: []string{"acid"}},
+		{Name: "Acid Essentials", Version: "1.10", URL: "packs/acid-1.10.stpk", SHA256: checksum(data), Key: FormatPublicKey(public)},
+		{Name: "Techno Drums", Version: "1.0", Author: "Kay", Device: "rd9", URL: "packs/drums.stpk"},
+	}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/registry/index.json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(index)
+	})
+	mux.HandleFunc("/registry/packs/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	r, err := NewRegistry(srv.URL + "/registry/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, public, index
+}
+
+func TestRegistrySearchFind(t *testing.T) {
+	r, _, _ := testRegistry(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"ACID", 2},
+		{"drums kay", 1},
+		{"drums acid", 0},
+		{"rd9", 1},
+	}
+	for _, tt := range tests {
+		found, err := r.Search(ctx, tt.query)
+		if err != nil || len(found) != tt.want {
+			t.Errorf("Search(%q) = %d packs, %v; want %d", tt.query, len(found), err, tt.want)
+		}
+	}
+
+	if l, err := r.Find(ctx, "acid essentials"); err != nil || l.Version != "1.10" {
+		t.Errorf("Find() = %+v, %v; want version 1.10", l, err)
+	}
+	if l, err := r.Find(ctx, "Acid Essentials@1.9"); err != nil || l.Version != "1.9" {
+		t.Errorf("Find(@1.9) = %+v, %v", l, err)
+	}
+	for _, name := range []string{"Missing", "Acid Essentials@2.0"} {
+		if _, err := r.Find(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Find(%q) error = %v, want ErrNotFound", name, err)
+		}
+	}
+
+	if _, err := NewRegistry("ftp://example.com/index.json"); err == nil {
+		t.Error("NewRegistry() accepted an ftp URL")
+	}
+}
+
+func TestRegistryDownload(t *testing.T) {
+	r, public, index := testRegistry(t)
+	ctx := context.Background()
+
+	p, err := r.Download(ctx, index.Packs[1])
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if !p.Signer.Equal(public) || string(p.Data["acid.seq"]) != "seq data" {
+		t.Errorf("downloaded %+v", p.Manifest)
+	}
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	for name, l := range map[string]Listing{
+		"checksum": {Name: "x", URL: index.Packs[1].URL, SHA256: checksum([]byte("other"))},
+		"key":      {Name: "x", URL: index.Packs[1].URL, Key: FormatPublicKey(other)},
+	} {
+		if _, err := r.Download(ctx, l); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Download() with the wrong %s: error = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	lib, err := library.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Install(lib, p, "", "ms1")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Install() = %v, %v", entries, err)
+	}
+	if e := entries[0]; e.Name != "acid" || e.Device != "td3" || len(e.Tags) != 1 {
+		t.Errorf("installed %+v", e)
+	}
+	if c, err := lib.Collection("Acid Essentials"); err != nil || len(c.Patterns) != 1 {
+		t.Errorf("collection = %+v, %v", c, err)
+	}
+}
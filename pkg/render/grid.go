@@ -0,0 +1,213 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// GridStepsPerRow is the number of steps per grid row; longer patterns wrap
+const GridStepsPerRow = 16
+
+// gridCell is the width of one step column, widened for longer note names
+const gridCell = 4
+
+// Grid colors, matching the piano roll
+var (
+	gridLabelStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+	gridBeatStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#C0C0C0")).Bold(true)
+	gridRestStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#444444"))
+	gridNoteStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#F0A030"))
+	gridAccentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF4040")).Bold(true)
+	gridFlagStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#39FF14"))
+)
+
+// GridOptions controls terminal grid rendering
+type GridOptions struct {
+	// NoColor disables ANSI colors
+	NoColor bool
+	// Names selects how notes are named
+	Names converter.NoteNaming
+}
+
+// Grid draws a pattern as a compact terminal grid with a column per step and
+// rows for the note, accent, slide, and tie of each step, e.g.
+//
+//	step  1   2   3   4   │5   6   7   8
+//	note  A2  C3  ·   E3  │A2  ·   ·   C3
+//	acc   ●               │●
+//	slide     ●           │
+//	tie                   │
+//
+// Multi-track patterns get a row per track instead: the hits of drum tracks
+// (● hit, ◉ accent, ○ ghost note) and the notes of melodic ones.
+func Grid(p *converter.Pattern, opts GridOptions) string {
+	style := func(s lipgloss.Style, text string) string {
+		if opts.NoColor {
+			return text
+		}
+		return s.Render(text)
+	}
+	steps := visibleSteps(p)
+	count := len(steps)
+	if len(p.Tracks) > 0 {
+		count = p.StepCount()
+		if p.Length > 0 {
+			count = min(count, p.Length)
+		}
+	}
+	width, labelWidth := gridCell, 6
+	for _, s := range steps {
+		if s.Gate {
+			width = max(width, len(opts.Names.Name(s.Note))+1)
+		}
+	}
+	for _, t := range p.Tracks {
+		labelWidth = max(labelWidth, len(t.Name)+1)
+		for _, s := range t.Steps {
+			if s.Gate && !t.IsDrum() {
+				width = max(width, len(opts.Names.Name(s.Note))+1)
+			}
+		}
+	}
+	cell := func(text string) string {
+		return fmt.Sprintf("%-*s", width, text)
+	}
+	noteCell := func(s converter.Step) string {
+		switch {
+		case !s.Gate:
+			return style(gridRestStyle, cell("·"))
+		case s.Accent:
+			return style(gridAccentStyle, cell(opts.Names.Name(s.Note)))
+		default:
+			return style(gridNoteStyle, cell(opts.Names.Name(s.Note)))
+		}
+	}
+
+	type gridRow struct {
+		label string
+		cell  func(i int) string
+	}
+	rows := []gridRow{{"step", func(i int) string {
+		st := gridLabelStyle
+		if i%4 == 0 {
+			st = gridBeatStyle
+		}
+		return style(st, cell(fmt.Sprint(i+1)))
+	}}}
+	if len(p.Steps) > 0 || len(p.Tracks) == 0 {
+		line := func(render func(converter.Step) string) func(int) string {
+			return func(i int) string {
+				if i >= len(steps) {
+					return cell("")
+				}
+				return render(steps[i])
+			}
+		}
+		rows = append(rows,
+			gridRow{"note", line(noteCell)},
+			gridRow{"acc", line(flagCell(cell, style, func(s converter.Step) bool { return s.Accent }))},
+			gridRow{"slide", line(flagCell(cell, style, func(s converter.Step) bool { return s.Slide }))},
+			gridRow{"tie", line(flagCell(cell, style, func(s converter.Step) bool { return s.Tie }))},
+		)
+	}
+	for _, t := range p.Tracks {
+		rows = append(rows, gridRow{t.Name, func(i int) string {
+			if i >= len(t.Steps) {
+				return cell("")
+			}
+			s := t.Steps[i]
+			switch {
+			case !t.IsDrum():
+				return noteCell(s)
+			case !s.Gate:
+				return style(gridRestStyle, cell("·"))
+			case s.Accent:
+				return style(gridAccentStyle, cell("◉"))
+			case s.Ghost:
+				return style(gridNoteStyle, cell("○"))
+			default:
+				return style(gridNoteStyle, cell("●"))
+			}
+		}})
+	}
+
+	var b strings.Builder
+	for start := 0; start < count; start += GridStepsPerRow {
+		end := min(start+GridStepsPerRow, count)
+		if start > 0 {
+			b.WriteString("\n")
+		}
+
+		for _, row := range rows {
+			var line strings.Builder
+			line.WriteString(style(gridLabelStyle, fmt.Sprintf("%-*s", labelWidth, row.label)))
+			for i := start; i < end; i++ {
+				if i > start && i%4 == 0 {
+					line.WriteString(style(gridLabelStyle, "│"))
+				}
+				line.WriteString(row.cell(i))
+			}
+			b.WriteString(strings.TrimRight(line.String(), " "))
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// flagCell returns a cell renderer marking gated steps where flag is set
+func flagCell(cell func(string) string, style func(lipgloss.Style, string) string, flag func(converter.Step) bool) func(converter.Step) string {
+	return func(s converter.Step) string {
+		if s.Gate && flag(s) {
+			return style(gridFlagStyle, cell("●"))
+		}
+		return cell("")
+	}
+}
+
+// NoteLine draws the first steps of a pattern on a single line, for lists
+// of patterns: the pitch class of each note (accents highlighted), "~" for
+// tied steps, and "·" for rests, e.g. "A  C  ·  E  ~  A# …"
+func NoteLine(p *converter.Pattern, steps int, opts GridOptions) string {
+	style := func(s lipgloss.Style, text string) string {
+		if opts.NoColor {
+			return text
+		}
+		return s.Render(text)
+	}
+
+	all := visibleSteps(p)
+	shown := all[:min(steps, len(all))]
+	width := 2
+	for _, s := range shown {
+		if s.Gate {
+			width = max(width, len(opts.Names.PitchClass(int(s.Note))))
+		}
+	}
+	cell := func(text string) string {
+		return fmt.Sprintf("%-*s", width, text)
+	}
+
+	var b strings.Builder
+	for i, s := range shown {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		switch {
+		case !s.Gate:
+			b.WriteString(style(gridRestStyle, cell("·")))
+		case s.Tie:
+			b.WriteString(style(gridNoteStyle, cell("~")))
+		case s.Accent:
+			b.WriteString(style(gridAccentStyle, cell(opts.Names.PitchClass(int(s.Note)))))
+		default:
+			b.WriteString(style(gridNoteStyle, cell(opts.Names.PitchClass(int(s.Note)))))
+		}
+	}
+	if len(all) > steps {
+		b.WriteString(" …")
+	}
+	return strings.TrimRight(b.String(), " ")
+}
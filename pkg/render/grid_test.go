@@ -0,0 +1,89 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestGrid(t *testing.T) {
+	p := &converter.Pattern{Steps: []converter.Step{
+		{Note: 45, Gate: true, Accent: true},
+		{Note: 48, Gate: true, Slide: true},
+		{},
+		{Note: 52, Gate: true, Tie: true},
+		{Note: 52, Gate: true},
+	}}
+
+	want := strings.Join([]string{
+		"step  1   2   3   4   │5",
+		"note  A2  C3  ·   E3  │E3",
+		"acc   ●               │",
+		"slide     ●           │",
+		"tie               ●   │",
+	}, "\n")
+	if got := Grid(p, GridOptions{NoColor: true}); got != want {
+		t.Errorf("Grid() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Patterns longer than a row wrap
+	long := &converter.Pattern{Steps: make([]converter.Step, 32)}
+	got := Grid(long, GridOptions{NoColor: true})
+	if n := strings.Count(got, "step "); n != 2 {
+		t.Errorf("32-step grid has %d rows of steps, want 2", n)
+	}
+	if !strings.Contains(got, "\nstep  17  18") {
+		t.Errorf("second row does not start at step 17:\n%s", got)
+	}
+
+	// Columns widen for longer names
+	sol := &converter.Pattern{Steps: []converter.Step{{Note: 45, Gate: true}, {Note: 68, Gate: true}}}
+	got = Grid(sol, GridOptions{NoColor: true, Names: converter.NoteNaming{Style: converter.NoteNamesSolfege, MiddleC: 3}})
+	if !strings.Contains(got, "step  1     2\nnote  La1   Sol#3") {
+		t.Errorf("solfège grid:\n%s", got)
+	}
+}
+
+func TestGridTracks(t *testing.T) {
+	p := &converter.Pattern{Length: 5, Tracks: []converter.Track{
+		{Name: "BD", Note: 36, Steps: []converter.Step{{Gate: true, Accent: true}, {}, {}, {}, {Gate: true}}},
+		{Name: "CH", Note: 42, Steps: []converter.Step{{}, {}, {Gate: true, Ghost: true}, {}, {}, {Gate: true}}},
+		{Name: "Bass", Steps: []converter.Step{{Note: 36, Gate: true}}},
+	}}
+
+	want := strings.Join([]string{
+		"step  1   2   3   4   │5",
+		"BD    ◉   ·   ·   ·   │●",
+		"CH    ·   ·   ○   ·   │·",
+		"Bass  C2              │",
+	}, "\n")
+	if got := Grid(p, GridOptions{NoColor: true}); got != want {
+		t.Errorf("Grid() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNoteLine(t *testing.T) {
+	p := &converter.Pattern{Steps: []converter.Step{
+		{Note: 45, Gate: true, Accent: true},
+		{Note: 49, Gate: true},
+		{},
+		{Note: 49, Gate: true, Tie: true},
+		{Note: 52, Gate: true},
+	}}
+	tests := []struct {
+		steps int
+		names converter.NoteNaming
+		want  string
+	}{
+		{5, converter.NoteNaming{}, "A  C# ·  ~  E"},
+		{3, converter.NoteNaming{}, "A  C# ·  …"},
+		{2, converter.NoteNaming{Style: converter.NoteNamesFlats}, "A  Db …"},
+		{2, converter.NoteNaming{Style: converter.NoteNamesSolfege}, "La  Do# …"},
+	}
+	for _, tt := range tests {
+		if got := NoteLine(p, tt.steps, GridOptions{NoColor: true, Names: tt.names}); got != tt.want {
+			t.Errorf("NoteLine(%d) = %q, want %q", tt.steps, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Page sizes for PDF cheat sheets
+const (
+	PageA4     = "A4"
+	PageLetter = "Letter"
+)
+
+// Cheat sheet layout in millimetres
+const (
+	sheetMargin   = 12.0
+	sheetLabel    = 14.0 // width of the row label column
+	sheetRow      = 5.0  // height of a grid row
+	sheetHeader   = 7.0  // height of a pattern's heading
+	sheetGap      = 5.0  // space between patterns
+	sheetGridRows = 5    // step, note, acc, slide, tie
+)
+
+// SheetEntry is one pattern of a cheat sheet
+type SheetEntry struct {
+	Slot    string // hardware slot, e.g. "1A1"; empty if unknown
+	Title   string // e.g. the file name; empty uses the pattern's name
+	Pattern *converter.Pattern
+}
+
+// PDFOptions controls cheat sheet rendering
+type PDFOptions struct {
+	// Title heads the first page
+	Title string
+	// PageSize is PageA4 (default) or PageLetter
+	PageSize string
+	// Names selects how notes and keys are named
+	Names converter.NoteNaming
+}
+
+// PDF writes a printable cheat sheet of patterns: for each its slot, name,
+// tempo, and key above a step grid like Grid's, flowing over as many pages
+// as needed
+func PDF(w io.Writer, entries []SheetEntry, opts PDFOptions) error {
+	size := opts.PageSize
+	if size == "" {
+		size = PageA4
+	}
+	if size != PageA4 && size != PageLetter {
+		return fmt.Errorf("unknown page size %q (use %s or %s)", size, PageA4, PageLetter)
+	}
+
+	pdf := fpdf.New("P", "mm", size, "")
+	pdf.SetCreator("synthtribe2midi", true)
+	pdf.SetTitle(opts.Title, true)
+	pdf.SetMargins(sheetMargin, sheetMargin, sheetMargin)
+	pdf.SetAutoPageBreak(false, sheetMargin)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageW, pageH := pdf.GetPageSize()
+	width := pageW - 2*sheetMargin
+
+	pdf.AddPage()
+	y := sheetMargin
+	if opts.Title != "" {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.SetXY(sheetMargin, y)
+		pdf.CellFormat(width, 8, tr(opts.Title), "", 0, "L", false, 0, "")
+		y += 12
+	}
+
+	for _, e := range entries {
+		steps := visibleSteps(e.Pattern)
+		gridRows := max(1, (len(steps)+GridStepsPerRow-1)/GridStepsPerRow)
+		height := sheetHeader + float64(gridRows)*(sheetGridRows*sheetRow+2)
+		if y+height > pageH-sheetMargin && y > sheetMargin {
+			pdf.AddPage()
+			y = sheetMargin
+		}
+
+		// Heading: slot and name on the left, tempo, key, and length on the right
+		title := e.Title
+		if title == "" {
+			title = e.Pattern.Name
+		}
+		if e.Slot != "" {
+			title = e.Slot + "   " + title
+		}
+		pdf.SetXY(sheetMargin, y)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(width/2, sheetHeader-1, tr(title), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(80, 80, 80)
+		pdf.CellFormat(width/2, sheetHeader-1, tr(sheetSummary(e.Pattern, len(steps), opts.Names)), "", 0, "R", false, 0, "")
+		y += sheetHeader
+
+		for start := 0; start < len(steps) || start == 0; start += GridStepsPerRow {
+			end := min(start+GridStepsPerRow, len(steps))
+			drawSheetGrid(pdf, tr, steps[start:end], start, y, width, opts.Names)
+			y += sheetGridRows*sheetRow + 2
+		}
+		y += sheetGap
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render PDF: %w", err)
+	}
+	return pdf.Output(w)
+}
+
+// sheetSummary returns the right-hand heading of a pattern, e.g.
+// "128 BPM · key Am · 16 steps"
+func sheetSummary(p *converter.Pattern, steps int, names converter.NoteNaming) string {
+	var parts []string
+	if p.Tempo > 0 {
+		parts = append(parts, fmt.Sprintf("%g BPM", p.Tempo))
+	}
+	if key := analysis.DetectKey(p); key != "" {
+		parts = append(parts, "key "+names.Key(key))
+	}
+	parts = append(parts, fmt.Sprintf("%d steps", steps))
+	return strings.Join(parts, " · ")
+}
+
+// drawSheetGrid draws up to GridStepsPerRow steps, numbered from first, as
+// rows of step numbers, notes, and accent, slide, and tie marks
+func drawSheetGrid(pdf *fpdf.Fpdf, tr func(string) string, steps []converter.Step, first int, y, width float64, names converter.NoteNaming) {
+	cell := (width - sheetLabel) / GridStepsPerRow
+	labels := []string{"step", "note", "acc", "slide", "tie"}
+	flags := []func(converter.Step) bool{
+		nil, nil,
+		func(s converter.Step) bool { return s.Accent },
+		func(s converter.Step) bool { return s.Slide },
+		func(s converter.Step) bool { return s.Tie },
+	}
+
+	// Accented notes get a shaded cell, as they are red on screen
+	pdf.SetFillColor(255, 220, 220)
+	for i, s := range steps {
+		if s.Gate && s.Accent {
+			pdf.Rect(sheetMargin+sheetLabel+float64(i)*cell, y+sheetRow, cell, sheetRow, "F")
+		}
+	}
+
+	for row, label := range labels {
+		rowY := y + float64(row)*sheetRow
+		pdf.SetXY(sheetMargin, rowY)
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.CellFormat(sheetLabel, sheetRow, label, "", 0, "L", false, 0, "")
+
+		for i, s := range steps {
+			x := sheetMargin + sheetLabel + float64(i)*cell
+			switch {
+			case row == 0:
+				pdf.SetFont("Helvetica", "", 7)
+				pdf.SetTextColor(110, 110, 110)
+				pdf.SetXY(x, rowY)
+				pdf.CellFormat(cell, sheetRow, fmt.Sprint(first+i+1), "", 0, "C", false, 0, "")
+			case row == 1 && !s.Gate:
+				pdf.SetTextColor(170, 170, 170)
+				pdf.SetFont("Helvetica", "", 9)
+				pdf.SetXY(x, rowY)
+				pdf.CellFormat(cell, sheetRow, tr("·"), "", 0, "C", false, 0, "")
+			case row == 1:
+				pdf.SetTextColor(0, 0, 0)
+				pdf.SetFont("Helvetica", "B", 9)
+				pdf.SetXY(x, rowY)
+				pdf.CellFormat(cell, sheetRow, tr(names.Name(s.Note)), "", 0, "C", false, 0, "")
+			case s.Gate && flags[row](s):
+				pdf.SetFillColor(0, 0, 0)
+				pdf.Circle(x+cell/2, rowY+sheetRow/2, 1.1, "F")
+			}
+		}
+	}
+
+	// Cell borders, heavier every beat
+	pdf.SetDrawColor(190, 190, 190)
+	for row := 0; row <= sheetGridRows; row++ {
+		pdf.SetLineWidth(0.1)
+		lineY := y + float64(row)*sheetRow
+		pdf.Line(sheetMargin+sheetLabel, lineY, sheetMargin+width, lineY)
+	}
+	for i := 0; i <= GridStepsPerRow; i++ {
+		pdf.SetLineWidth(0.1)
+		if i%4 == 0 {
+			pdf.SetLineWidth(0.4)
+		}
+		x := sheetMargin + sheetLabel + float64(i)*cell
+		pdf.Line(x, y, x, y+sheetGridRows*sheetRow)
+	}
+}
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestPDF(t *testing.T) {
+	p := &converter.Pattern{Name: "acid", Tempo: 128, Length: 16, Steps: make([]converter.Step, 16)}
+	for i := range p.Steps {
+		if i%2 == 0 {
+			p.Steps[i] = converter.Step{Note: 45, Gate: true, Velocity: 100, Accent: i%4 == 0}
+		}
+	}
+	long := &converter.Pattern{Length: 32, Steps: make([]converter.Step, 32)}
+
+	pages := regexp.MustCompile(`/Type /Page\b[^s]`)
+	tests := []struct {
+		name      string
+		entries   int
+		opts      PDFOptions
+		wantPages int
+	}{
+		{"one", 1, PDFOptions{Title: "Gig"}, 1},
+		// Six 16-step patterns fit on an A4 page
+		{"full page", 6, PDFOptions{}, 1},
+		{"overflow", 14, PDFOptions{PageSize: PageLetter}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := make([]SheetEntry, tt.entries)
+			for i := range entries {
+				entries[i] = SheetEntry{Slot: "1A1", Pattern: p}
+			}
+			var buf bytes.Buffer
+			if err := PDF(&buf, entries, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+				t.Fatalf("output does not start with a PDF header: %q", buf.Bytes()[:min(16, buf.Len())])
+			}
+			if got := len(pages.FindAll(buf.Bytes(), -1)); got != tt.wantPages {
+				t.Errorf("%d pages, want %d", got, tt.wantPages)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := PDF(&buf, []SheetEntry{{Pattern: long}}, PDFOptions{}); err != nil {
+		t.Errorf("32-step pattern: %v", err)
+	}
+	if err := PDF(&buf, nil, PDFOptions{PageSize: "A3"}); err == nil {
+		t.Error("unknown page size accepted")
+	}
+}
+
+func TestSheetSummary(t *testing.T) {
+	p := &converter.Pattern{Tempo: 130.5, Steps: []converter.Step{{Note: 46, Gate: true}, {Note: 49, Gate: true}, {Note: 53, Gate: true}}}
+	if got, want := sheetSummary(p, 16, converter.NoteNaming{Style: converter.NoteNamesFlats}), "130.5 BPM · key Bbm · 16 steps"; got != want {
+		t.Errorf("sheetSummary() = %q, want %q", got, want)
+	}
+}
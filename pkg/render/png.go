@@ -0,0 +1,181 @@
+// Package render draws patterns as images and terminal grids
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Piano roll cell size in pixels at scale 1
+const (
+	CellWidth  = 24
+	CellHeight = 12
+)
+
+// Piano roll colors
+var (
+	colorBackground = color.RGBA{0x2a, 0x2a, 0x2e, 0xff}
+	colorBlackKey   = color.RGBA{0x1f, 0x1f, 0x23, 0xff}
+	colorStepLine   = color.RGBA{0x36, 0x36, 0x3c, 0xff}
+	colorBeatLine   = color.RGBA{0x5a, 0x5a, 0x64, 0xff}
+	colorOctaveLine = color.RGBA{0x48, 0x48, 0x50, 0xff}
+	colorNote       = color.RGBA{0xf0, 0xa0, 0x30, 0xff}
+	colorAccent     = color.RGBA{0xff, 0x40, 0x40, 0xff}
+	colorSlide      = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+)
+
+// PNGOptions controls piano roll rendering
+type PNGOptions struct {
+	// Scale multiplies the cell size (default 1)
+	Scale int
+}
+
+// PNG draws a pattern as a piano roll: one column per step and one row per
+// semitone, with accented notes in red, tied steps joined, and slides drawn
+// as arrows to the next note
+func PNG(w io.Writer, p *converter.Pattern, opts PNGOptions) error {
+	return png.Encode(w, PianoRoll(p, opts))
+}
+
+// PianoRoll draws a pattern as a piano roll image
+func PianoRoll(p *converter.Pattern, opts PNGOptions) *image.RGBA {
+	scale := opts.Scale
+	if scale < 1 {
+		scale = 1
+	}
+	cw, ch := CellWidth*scale, CellHeight*scale
+
+	steps := visibleSteps(p)
+	low, high := noteRange(steps)
+	rows := int(high-low) + 1
+	cols := len(steps)
+	if cols == 0 {
+		cols = 16
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, cols*cw, rows*ch))
+	rowY := func(note uint8) int { return int(high-note) * ch }
+
+	// Keyboard rows and grid lines
+	draw.Draw(img, img.Bounds(), image.NewUniform(colorBackground), image.Point{}, draw.Src)
+	for i := int(low); i <= int(high); i++ {
+		n := uint8(i)
+		y := rowY(n)
+		if isBlackKey(n) {
+			fill(img, image.Rect(0, y, cols*cw, y+ch), colorBlackKey)
+		}
+		if n%12 == 0 {
+			fill(img, image.Rect(0, y+ch-1, cols*cw, y+ch), colorOctaveLine)
+		}
+	}
+	for i := 0; i < cols; i++ {
+		c := colorStepLine
+		if i%4 == 0 {
+			c = colorBeatLine
+		}
+		fill(img, image.Rect(i*cw, 0, i*cw+1, rows*ch), c)
+	}
+
+	// Notes; a tied step continues the previous note, so the two cells join
+	pad := scale
+	for i, s := range steps {
+		if !s.Gate {
+			continue
+		}
+		c := colorNote
+		if s.Accent {
+			c = colorAccent
+		}
+		y := rowY(s.Note)
+		x0, x1 := i*cw+pad+1, (i+1)*cw-pad
+		if s.Tie && i > 0 && steps[i-1].Gate {
+			x0 = i * cw
+		}
+		if i+1 < len(steps) && steps[i+1].Tie && steps[i+1].Gate {
+			x1 = (i + 1) * cw
+		}
+		fill(img, image.Rect(x0, y+pad, x1, y+ch-pad), c)
+	}
+
+	// Slides: arrow from the middle of the note to the middle of the next one
+	for i, s := range steps {
+		if !s.Gate || !s.Slide || i+1 >= len(steps) || !steps[i+1].Gate {
+			continue
+		}
+		next := steps[i+1]
+		arrow(img,
+			float64(i*cw+cw/2), float64(rowY(s.Note)+ch/2),
+			float64((i+1)*cw+cw/2), float64(rowY(next.Note)+ch/2),
+			float64(4*scale), float64(scale), colorSlide)
+	}
+	return img
+}
+
+// visibleSteps returns the steps within the pattern length
+func visibleSteps(p *converter.Pattern) []converter.Step {
+	if p.Length > 0 && p.Length < len(p.Steps) {
+		return p.Steps[:p.Length]
+	}
+	return p.Steps
+}
+
+// noteRange returns the rows to draw: the notes used plus a margin, and at
+// least an octave (C2 to C3 for empty patterns)
+func noteRange(steps []converter.Step) (low, high uint8) {
+	low, high = 127, 0
+	for _, s := range steps {
+		if s.Gate {
+			low = min(low, s.Note)
+			high = max(high, s.Note)
+		}
+	}
+	if low > high {
+		return 36, 48
+	}
+	lo, hi := int(low)-2, int(high)+2
+	for hi-lo < 12 {
+		lo--
+		hi++
+	}
+	return uint8(max(lo, 0)), uint8(min(hi, 127))
+}
+
+func isBlackKey(note uint8) bool {
+	switch note % 12 {
+	case 1, 3, 6, 8, 10:
+		return true
+	}
+	return false
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// arrow draws a line from (x0,y0) to (x1,y1) with an arrowhead at the end
+func arrow(img *image.RGBA, x0, y0, x1, y1, head, width float64, c color.RGBA) {
+	line(img, x0, y0, x1, y1, width, c)
+	angle := math.Atan2(y1-y0, x1-x0)
+	for _, side := range []float64{-1, 1} {
+		a := angle + math.Pi - side*math.Pi/6
+		line(img, x1, y1, x1+head*math.Cos(a), y1+head*math.Sin(a), width, c)
+	}
+}
+
+// line draws a line of the given width by stamping squares along it
+func line(img *image.RGBA, x0, y0, x1, y1, width float64, c color.RGBA) {
+	n := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	half := int(width / 2)
+	for i := 0; i <= n; i++ {
+		t := float64(i) / float64(n)
+		x := int(math.Round(x0 + t*(x1-x0)))
+		y := int(math.Round(y0 + t*(y1-y0)))
+		fill(img, image.Rect(x-half, y-half, x-half+int(width), y-half+int(width)), c)
+	}
+}
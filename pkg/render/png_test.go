@@ -0,0 +1,76 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestPianoRoll(t *testing.T) {
+	p := &converter.Pattern{
+		Length: 4,
+		Steps: []converter.Step{
+			{Note: 45, Gate: true, Slide: true},
+			{Note: 48, Gate: true, Accent: true},
+			{Note: 48, Gate: true, Tie: true},
+			{},
+		},
+	}
+
+	img := PianoRoll(p, PNGOptions{Scale: 2})
+	cw, ch := CellWidth*2, CellHeight*2
+	// 45-48 plus margin, widened to an octave: 40..53
+	if got, want := img.Bounds().Size().X, 4*cw; got != want {
+		t.Errorf("width = %d, want %d", got, want)
+	}
+	if got, want := img.Bounds().Size().Y, 14*ch; got != want {
+		t.Errorf("height = %d, want %d", got, want)
+	}
+
+	center := func(step int, note uint8) (int, int) {
+		return step*cw + cw/4, (53-int(note))*ch + ch/2
+	}
+	tests := []struct {
+		name string
+		step int
+		note uint8
+		want any
+	}{
+		{"note", 0, 45, colorNote},
+		{"accent", 1, 48, colorAccent},
+		{"tied step", 2, 48, colorNote},
+		{"rest", 3, 48, colorBackground},
+	}
+	for _, tt := range tests {
+		x, y := center(tt.step, tt.note)
+		if got := img.RGBAAt(x, y); got != tt.want {
+			t.Errorf("%s: pixel = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// The tie joins steps 1 and 2 without a gap
+	_, y := center(1, 48)
+	if got := img.RGBAAt(2*cw-1, y); got != colorAccent {
+		t.Errorf("tie gap pixel = %v, want accent color", got)
+	}
+
+	// The slide arrow ends in the middle of step 1
+	x, y := 1*cw+cw/2, (53-48)*ch+ch/2
+	if got := img.RGBAAt(x, y); got != colorSlide {
+		t.Errorf("slide arrow pixel = %v, want %v", got, colorSlide)
+	}
+
+	var buf bytes.Buffer
+	if err := PNG(&buf, &converter.Pattern{}, PNGOptions{}); err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	empty, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	if got := empty.Bounds().Size(); got.X != 16*CellWidth || got.Y != 13*CellHeight {
+		t.Errorf("empty pattern size = %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // scanned QR codes are often photos
+	_ "image/png"
+	"io"
+
+	"github.com/makiuchi-d/gozxing"
+	qrreader "github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/skip2/go-qrcode"
+)
+
+// DefaultQRSize is the default QR code image size in pixels
+const DefaultQRSize = 256
+
+// QR writes content (e.g. a share string) as a PNG QR code of the given size
+// in pixels, with medium error correction so printed codes survive some wear
+func QR(w io.Writer, content string, size int) error {
+	if size <= 0 {
+		size = DefaultQRSize
+	}
+	data, err := qrcode.Encode(content, qrcode.Medium, size)
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ReadQR decodes the QR code in a PNG or JPEG image
+func ReadQR(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	result, err := qrreader.NewQRCodeReader().Decode(bmp, nil)
+	if err != nil {
+		return "", fmt.Errorf("no QR code found: %w", err)
+	}
+	return result.GetText(), nil
+}
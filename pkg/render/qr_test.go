@@ -0,0 +1,36 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestQR(t *testing.T) {
+	const share = "st1.EASwqwO3BbcBqwSrBrcEtwCrArAAsASwALAEsASwBLAAsACw"
+
+	var buf bytes.Buffer
+	if err := QR(&buf, share, 0); err != nil {
+		t.Fatalf("QR() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != DefaultQRSize || size.Y != DefaultQRSize {
+		t.Errorf("size = %v, want %d square", size, DefaultQRSize)
+	}
+
+	got, err := ReadQR(&buf)
+	if err != nil {
+		t.Fatalf("ReadQR() error = %v", err)
+	}
+	if got != share {
+		t.Errorf("ReadQR() = %q, want %q", got, share)
+	}
+
+	if _, err := ReadQR(strings.NewReader("not an image")); err == nil {
+		t.Error("ReadQR() accepted a non-image")
+	}
+}
@@ -0,0 +1,191 @@
+// Package schedule parses cron-style schedules and runs jobs on them
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression
+// (minute hour day-of-month month day-of-week)
+type Schedule struct {
+	spec                         string
+	minute, hour, dom, month     uint64
+	dow                          uint64
+	domRestricted, dowRestricted bool
+}
+
+// field describes the valid range of a cron field
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+var descriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Parse parses a cron expression such as "0 3 * * *" (daily at 03:00),
+// "*/15 * * * *", or "30 2 * * 1-5". The descriptors @hourly, @daily,
+// @weekly, and @monthly are also accepted.
+func Parse(spec string) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if d, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day month weekday)", spec)
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday may be written as 0 or 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &Schedule{
+		spec:          spec,
+		minute:        sets[0],
+		hour:          sets[1],
+		dom:           sets[2],
+		month:         sets[3],
+		dow:           sets[4],
+		domRestricted: parts[2] != "*",
+		dowRestricted: parts[4] != "*",
+	}, nil
+}
+
+// parseField parses a comma-separated list of values, ranges, and steps
+func parseField(s string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(s, ",") {
+		rangePart, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s field %q", f.name, item)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s field %q", f.name, item)
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s field %q out of range (%d-%d)", f.name, item, f.min, f.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// String returns the original expression
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first matching time strictly after t (at minute
+// resolution), in t's location. Times are matched on the wall clock, so a
+// time skipped by a daylight saving change does not match and a repeated
+// hour matches only once. It fails if no time matches within five years.
+func (s *Schedule) Next(t time.Time) (time.Time, error) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = step(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()), time.Minute)
+		case !s.dayMatches(t):
+			t = step(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()), time.Minute)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = step(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location()), time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = step(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location()), time.Minute)
+		default:
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("schedule %q does not match any time in the next five years", s.spec)
+}
+
+// step returns next, or t advanced by d if next is not after t, as happens
+// when a wall-clock time falls in an hour that a daylight saving change
+// repeats
+func step(t, next time.Time, d time.Duration) time.Time {
+	if !next.After(t) {
+		return t.Add(d)
+	}
+	return next
+}
+
+// dayMatches applies cron's day rule: when both day-of-month and day-of-week
+// are restricted, either may match
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+// Run calls fn at every scheduled time until ctx is cancelled. It fails if
+// the schedule stops matching.
+func Run(ctx context.Context, s *Schedule, fn func(time.Time)) error {
+	for {
+		next, err := s.Next(time.Now())
+		if err != nil {
+			return err
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case at := <-timer.C:
+			fn(at)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// Thursday 2026-10-15 09:51
+	from := time.Date(2026, 10, 15, 9, 51, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"0 3 * * *", time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2026, 10, 16, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 1 *", time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC)},
+		// Day-of-month OR day-of-week when both are restricted
+		{"0 0 20 * 6", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got, err := s.Next(from); err != nil || !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	s, err := Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got, err := s.Next(time.Now()); err == nil {
+		t.Errorf("Next() = %v, want an error", got)
+	}
+}
+
+// TestNextLocal checks that schedules follow the wall clock in zones that
+// are not a whole number of hours from UTC and across daylight saving changes
+func TestNextLocal(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"half-hour offset", "0 3 * * *", time.Date(2026, 10, 15, 9, 51, 0, 0, kolkata), time.Date(2026, 10, 16, 3, 0, 0, 0, kolkata)},
+		{"half-hour offset hourly", "15 * * * *", time.Date(2026, 10, 15, 9, 51, 0, 0, kolkata), time.Date(2026, 10, 15, 10, 15, 0, 0, kolkata)},
+		{"daylight saving starts", "0 3 * * *", time.Date(2026, 3, 7, 12, 0, 0, 0, newYork), time.Date(2026, 3, 8, 3, 0, 0, 0, newYork)},
+		// 02:30 does not exist on 2026-03-08, so the next run is a day later
+		{"skipped hour", "30 2 * * *", time.Date(2026, 3, 7, 12, 0, 0, 0, newYork), time.Date(2026, 3, 9, 2, 30, 0, 0, newYork)},
+		{"daylight saving ends", "0 3 * * *", time.Date(2026, 10, 31, 12, 0, 0, 0, newYork), time.Date(2026, 11, 1, 3, 0, 0, 0, newYork)},
+		// 01:30 happens twice on 2026-11-01 but runs only the first time
+		{"repeated hour", "30 1 * * *", time.Date(2026, 11, 1, 1, 30, 0, 0, newYork), time.Date(2026, 11, 2, 1, 30, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got, err := s.Next(tt.from); err != nil || !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) expected error", spec)
+		}
+	}
+}
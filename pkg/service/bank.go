@@ -0,0 +1,57 @@
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// BankItem is one pattern of a file
+type BankItem struct {
+	Name   string // output base name, e.g. "bank-03"
+	Format converter.Format
+	Data   []byte
+}
+
+// SplitBank splits a file into the patterns it holds: a .syx file with
+// several dumps gives one pattern per dump, named after the file and its
+// position; any other file is a single pattern. SysEx written out as hex
+// text is decoded. Files with more SysEx messages or patterns than limits
+// allow are rejected.
+func SplitBank(filename string, data []byte, limits converter.Limits) ([]BankItem, error) {
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	format := converter.DetectFormat(filename)
+	if format == converter.FormatUnknown {
+		format = converter.DetectFormatFromContent(data)
+	}
+	if format == converter.FormatSyx {
+		if msg, ok := sysex.FromText(data); ok {
+			data = msg
+		}
+		if err := limits.CheckInput(data, format); err != nil {
+			return nil, err
+		}
+		if msgs, err := sysex.Split(data); err == nil && len(msgs) > 1 {
+			if err := limits.CheckPatterns(len(msgs)); err != nil {
+				return nil, err
+			}
+			items := make([]BankItem, len(msgs))
+			for i, msg := range msgs {
+				items[i] = BankItem{Name: fmt.Sprintf("%s-%02d", base, i+1), Format: format, Data: msg}
+			}
+			return items, nil
+		}
+	}
+	return []BankItem{{Name: base, Format: format, Data: data}}, nil
+}
+
+// PickBankItem returns the pattern at the 1-based index of a bank
+func PickBankItem(items []BankItem, index int) (BankItem, error) {
+	if index < 1 || index > len(items) {
+		return BankItem{}, fmt.Errorf("pattern index %d out of range: the file holds %d pattern(s)", index, len(items))
+	}
+	return items[index-1], nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// ExpandInputs expands the glob patterns of a batch (such as "*.seq", quoted
+// so the shell leaves them alone) into the files they match, in order and
+// each once. Directories are skipped; a pattern matching no file is an
+// error, so a typo does not pass for an empty batch.
+func ExpandInputs(patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		n := 0
+		for _, path := range matches {
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				continue
+			}
+			n++
+			if key := outputKey(path); !seen[key] {
+				seen[key] = true
+				files = append(files, path)
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("no files match %s", pattern)
+		}
+	}
+	return files, nil
+}
+
+// BatchOutput returns where a batch writes input converted to format: its
+// base name with the format's extension, in dir, or next to the input when
+// dir is empty
+func BatchOutput(input, dir string, format converter.Format) string {
+	name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)) + Ext(format)
+	if dir == "" {
+		dir = filepath.Dir(input)
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.seq", "b.seq", "c.mid"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "d.seq"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExpandInputs([]string{filepath.Join(dir, "*.seq"), filepath.Join(dir, "a.seq"), filepath.Join(dir, "c.mid")})
+	if err != nil {
+		t.Fatalf("ExpandInputs() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.seq"), filepath.Join(dir, "b.seq"), filepath.Join(dir, "c.mid")}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExpandInputs() = %v, want %v", got, want)
+	}
+
+	for _, pattern := range []string{"*.syx", "d.seq", "[a"} {
+		if _, err := ExpandInputs([]string{filepath.Join(dir, pattern)}); err == nil {
+			t.Errorf("ExpandInputs(%q) expected error", pattern)
+		}
+	}
+}
+
+func TestBatchOutput(t *testing.T) {
+	tests := []struct {
+		input, dir string
+		format     converter.Format
+		want       string
+	}{
+		{"pats/line.seq", "", converter.FormatMIDI, filepath.Join("pats", "line.mid")},
+		{"pats/line.seq", "converted", converter.FormatSyx, filepath.Join("converted", "line.syx")},
+		{"line.tar.seq", "out", converter.FormatMIDI, filepath.Join("out", "line.tar.mid")},
+	}
+	for _, tt := range tests {
+		if got := BatchOutput(tt.input, tt.dir, tt.format); got != tt.want {
+			t.Errorf("BatchOutput(%q, %q, %s) = %q, want %q", tt.input, tt.dir, tt.format, got, tt.want)
+		}
+	}
+}
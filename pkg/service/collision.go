@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Collision policies: what to do when an output of a batch would replace a
+// file written earlier in the batch from another input
+const (
+	CollisionAsk       = "ask"       // let the user choose (interactive front-ends only)
+	CollisionRename    = "rename"    // write to a free name, e.g. pattern-2.seq
+	CollisionSkip      = "skip"      // keep the earlier file, drop the new one
+	CollisionOverwrite = "overwrite" // replace the earlier file
+)
+
+// CollisionPolicies lists the valid collision policies
+var CollisionPolicies = []string{CollisionAsk, CollisionRename, CollisionSkip, CollisionOverwrite}
+
+// ValidateCollisionPolicy checks a collision policy name
+func ValidateCollisionPolicy(policy string) error {
+	for _, p := range CollisionPolicies {
+		if policy == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown collision policy %q (use %s)", policy, strings.Join(CollisionPolicies, ", "))
+}
+
+// Collision is an output two inputs map to, and how it was resolved
+type Collision struct {
+	Path     string // the contested output
+	Input    string // the input that wanted it second
+	Previous string // the input it was written from first
+	Policy   string // rename, skip, or overwrite
+	Renamed  string // the path written instead, for rename
+}
+
+// String describes the collision for a summary
+func (c Collision) String() string {
+	name := filepath.Base(c.Path)
+	switch c.Policy {
+	case CollisionRename:
+		return fmt.Sprintf("%s: %s already written from %s, wrote %s", c.Input, name, c.Previous, filepath.Base(c.Renamed))
+	case CollisionSkip:
+		return fmt.Sprintf("%s: skipped, %s already written from %s", c.Input, name, c.Previous)
+	default:
+		return fmt.Sprintf("%s: overwrote %s written from %s", c.Input, name, c.Previous)
+	}
+}
+
+// Outputs tracks which input each output path of a batch was claimed for,
+// so that two inputs mapping to the same name are caught instead of the
+// second silently replacing the first. It is safe for concurrent use.
+type Outputs struct {
+	mu     sync.Mutex
+	claims map[string]string
+}
+
+// NewOutputs returns an empty output tracker
+func NewOutputs() *Outputs {
+	return &Outputs{claims: make(map[string]string)}
+}
+
+func outputKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// Conflict returns the input path was claimed for, if that is another input
+// than input
+func (o *Outputs) Conflict(path, input string) (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	prev, ok := o.claims[outputKey(path)]
+	return prev, ok && prev != input
+}
+
+// Claim resolves path for input with a policy other than CollisionAsk and
+// records the claim. It returns the path to write, empty to skip the
+// output, and the collision if there was one.
+func (o *Outputs) Claim(path, input, policy string) (string, *Collision) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := outputKey(path)
+	prev, ok := o.claims[key]
+	if !ok || prev == input {
+		o.claims[key] = input
+		return path, nil
+	}
+
+	c := &Collision{Path: path, Input: input, Previous: prev, Policy: policy}
+	switch policy {
+	case CollisionSkip:
+		return "", c
+	case CollisionRename:
+		c.Renamed = o.freeName(path)
+		o.claims[outputKey(c.Renamed)] = input
+		return c.Renamed, c
+	default:
+		c.Policy = CollisionOverwrite
+		o.claims[key] = input
+		return path, c
+	}
+}
+
+// freeName returns path with the first suffix -2, -3, ... that is neither
+// claimed nor taken on disk
+func (o *Outputs) freeName(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, claimed := o.claims[outputKey(candidate)]; claimed {
+			continue
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			continue
+		}
+		return candidate
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputs(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "line.seq")
+	// line-2.seq is taken on disk, so renaming skips it
+	if err := os.WriteFile(filepath.Join(dir, "line-2.seq"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := NewOutputs()
+	if path, c := o.Claim(out, "a/line.mid", CollisionSkip); path != out || c != nil {
+		t.Fatalf("first claim = %q, %v", path, c)
+	}
+	if path, c := o.Claim(out, "a/line.mid", CollisionSkip); path != out || c != nil {
+		t.Errorf("same input again = %q, %v; want no collision", path, c)
+	}
+	if prev, ok := o.Conflict(out, "b/line.mid"); !ok || prev != "a/line.mid" {
+		t.Errorf("Conflict = %q, %v", prev, ok)
+	}
+
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{CollisionSkip, ""},
+		{CollisionRename, filepath.Join(dir, "line-3.seq")},
+		{CollisionRename, filepath.Join(dir, "line-4.seq")},
+		{CollisionOverwrite, out},
+	}
+	for _, tt := range tests {
+		path, c := o.Claim(out, "b/line.mid", tt.policy)
+		if path != tt.want || c == nil || c.Policy != tt.policy || c.Previous != "a/line.mid" {
+			t.Errorf("%s: path %q, collision %+v; want %q", tt.policy, path, c, tt.want)
+		}
+	}
+	// After overwriting, the output belongs to the second input
+	if prev, ok := o.Conflict(out, "a/line.mid"); !ok || prev != "b/line.mid" {
+		t.Errorf("Conflict after overwrite = %q, %v", prev, ok)
+	}
+
+	if err := ValidateCollisionPolicy("clobber"); err == nil {
+		t.Error("unknown policy accepted")
+	}
+}
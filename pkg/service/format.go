@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// ErrEmpty is returned for empty input
+var ErrEmpty = errors.New("the file is empty")
+
+// FormatError reports input that cannot be in the format it was declared as
+type FormatError struct {
+	Declared converter.Format
+	Actual   converter.Format // the format the data looks like, or FormatUnknown
+	Reason   string           // why the data is not Declared, when Actual is unknown
+}
+
+// Error implements the error interface
+func (e *FormatError) Error() string {
+	if e.Actual != converter.FormatUnknown {
+		return fmt.Sprintf("this looks like %s, not %s", DescribeFormat(e.Actual), DescribeFormat(e.Declared))
+	}
+	return fmt.Sprintf("this is not %s: %s", DescribeFormat(e.Declared), e.Reason)
+}
+
+// CheckFormat checks data against the format it was declared as, returning
+// a FormatError if its content plainly contradicts it
+func CheckFormat(data []byte, declared converter.Format) error {
+	if len(data) == 0 {
+		return ErrEmpty
+	}
+	actual := SniffFormat(data)
+	switch {
+	case actual == declared:
+		return nil
+	case actual != converter.FormatUnknown:
+		return &FormatError{Declared: declared, Actual: actual}
+	}
+
+	// Binary data without a signature may be a .seq file, but nothing else
+	err := &FormatError{Declared: declared, Actual: actual}
+	switch declared {
+	case converter.FormatMIDI:
+		err.Reason = "it has no MThd header"
+	case converter.FormatSyx:
+		err.Reason = "it does not start with F0"
+	case converter.Format303, converter.FormatTR:
+		err.Reason = "it holds binary data"
+	case converter.FormatCircuit:
+		err.Reason = "it has no NCS header"
+	default:
+		return nil
+	}
+	return err
+}
+
+// SniffFormat recognizes the formats with a signature: MIDI files, SysEx
+// dumps (also written out as hex text), Circuit sessions, TR pattern
+// exports, and text notation. Anything else is FormatUnknown.
+func SniffFormat(data []byte) converter.Format {
+	switch {
+	case len(data) == 0:
+		return converter.FormatUnknown
+	case strings.HasPrefix(string(data), "MThd"):
+		return converter.FormatMIDI
+	case data[0] == converter.SysExStart:
+		return converter.FormatSyx
+	case converter.IsCircuitSession(data):
+		return converter.FormatCircuit
+	case isHexSysEx(data):
+		return converter.FormatSyx
+	case isText(data) && converter.IsTRPattern(data):
+		return converter.FormatTR
+	case isText(data):
+		return converter.Format303
+	}
+	return converter.FormatUnknown
+}
+
+// isHexSysEx reports whether data is SysEx written out as hex text
+func isHexSysEx(data []byte) bool {
+	_, ok := sysex.FromText(data)
+	return ok
+}
+
+// isText reports whether data is UTF-8 text without control characters
+// other than whitespace
+func isText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseFormat parses a format name as used in flags and query parameters,
+// returning FormatUnknown for names it does not know
+func ParseFormat(name string) converter.Format {
+	switch f := converter.Format(strings.ToLower(name)); f {
+	case converter.FormatSeq, converter.FormatSyx, converter.FormatMIDI, converter.Format303, converter.FormatTR, converter.FormatCircuit:
+		return f
+	case "mid":
+		return converter.FormatMIDI
+	case "prm", "tr8s":
+		return converter.FormatTR
+	case "ncs":
+		return converter.FormatCircuit
+	}
+	return converter.FormatUnknown
+}
+
+// DescribeFormat names a format for messages
+func DescribeFormat(f converter.Format) string {
+	switch f {
+	case converter.FormatMIDI:
+		return "a MIDI file"
+	case converter.FormatSyx:
+		return "a SysEx dump"
+	case converter.Format303:
+		return "a text pattern"
+	case converter.FormatTR:
+		return "a TR pattern export"
+	case converter.FormatCircuit:
+		return "a Circuit session"
+	}
+	return "a ." + string(f) + " file"
+}
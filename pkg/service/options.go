@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// ParseOptions reads conversion options from named string parameters, such
+// as a query string; lookup returns a parameter and whether it was given.
+// The names match the CLI flags:
+//
+//	device_id         SysEx device ID for .syx output (0-127)
+//	transpose         semitones to transpose the output by
+//	channel           MIDI channel (1-16) written and read
+//	gate_length       fraction of a step plain notes sound (0-1)
+//	accent_threshold  lowest MIDI velocity read as an accent (1-127)
+//	ghost_threshold   MIDI velocity below which drum hits are ghost notes (1-127)
+//	slide_mode        interval, legato, or none
+//	bar               bar (1-based) of multi-bar MIDI input to read
+//	downmix           fold, densest, thin, or halve: fit long MIDI clips
+//	strict            fail on warnings instead of fixing the pattern up
+//	verify_roundtrip  fail if generated .seq or .syx reads back differently
+//	recover           salvage damaged .seq and .syx input, warning what was lost
+//	gate_track, gate_note, accent_track, accent_note  trigger tracks
+func ParseOptions(lookup func(name string) (string, bool)) (converter.ConvertOptions, error) {
+	return ParseOptionsFrom(converter.DefaultOptions(), lookup)
+}
+
+// ParseOptionsFrom is ParseOptions with the given options, such as a
+// server's configured defaults, in place of converter.DefaultOptions
+func ParseOptionsFrom(defaults converter.ConvertOptions, lookup func(name string) (string, bool)) (converter.ConvertOptions, error) {
+	opts := defaults
+
+	ints := []struct {
+		name     string
+		dst      *int
+		min, max int
+	}{
+		{"transpose", &opts.Transpose, -127, 127},
+		{"channel", &opts.Channel, 1, 16},
+		{"accent_threshold", &opts.AccentThreshold, 1, 127},
+		{"ghost_threshold", &opts.GhostThreshold, 1, 127},
+		{"bar", &opts.Bar, 1, 1 << 16},
+	}
+	for _, p := range ints {
+		v, ok := lookup(p.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < p.min || n > p.max {
+			return opts, fail(ErrInvalidRequest, fmt.Errorf("%s must be an integer between %d and %d", p.name, p.min, p.max))
+		}
+		*p.dst = n
+	}
+
+	notes := []struct {
+		name string
+		dst  *uint8
+	}{
+		{"gate_note", &opts.GateNote},
+		{"accent_note", &opts.AccentNote},
+	}
+	for _, p := range notes {
+		if v, ok := lookup(p.name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > 127 {
+				return opts, fail(ErrInvalidRequest, fmt.Errorf("%s must be between 0 and 127", p.name))
+			}
+			*p.dst = uint8(n)
+		}
+	}
+	if v, ok := lookup("device_id"); ok {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 0 || id > 127 {
+			return opts, fail(ErrInvalidRequest, errors.New("device_id must be between 0 and 127"))
+		}
+		deviceID := uint8(id)
+		opts.DeviceID = &deviceID
+	}
+
+	bools := []struct {
+		name string
+		dst  *bool
+	}{
+		{"strict", &opts.Strict},
+		{"verify_roundtrip", &opts.VerifyRoundtrip},
+		{"recover", &opts.Recover},
+		{"gate_track", &opts.GateTrack},
+		{"accent_track", &opts.AccentTrack},
+	}
+	for _, p := range bools {
+		if v, ok := lookup(p.name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return opts, fail(ErrInvalidRequest, fmt.Errorf("%s must be true or false", p.name))
+			}
+			*p.dst = b
+		}
+	}
+
+	if v, ok := lookup("gate_length"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 1 {
+			return opts, fail(ErrInvalidRequest, errors.New("gate_length must be a number above 0 and at most 1"))
+		}
+		opts.GateLength = f
+	}
+	if v, ok := lookup("slide_mode"); ok {
+		opts.SlideMode = v
+	}
+	if v, ok := lookup("downmix"); ok {
+		opts.Downmix = v
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, fail(ErrInvalidRequest, err)
+	}
+	return opts, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+
+	"github.com/james-see/synthtribe2midi/pkg/analysis"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// Report is the inspect JSON of a pattern: its steps and analysis
+type Report struct {
+	Source   string           `json:"source"`
+	Format   string           `json:"format"`
+	Device   string           `json:"device"`
+	Name     string           `json:"name,omitempty"`
+	Length   int              `json:"length"`
+	Analysis *analysis.Result `json:"analysis"`
+	Steps    []StepReport     `json:"steps"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// StepReport describes one step of a Report
+type StepReport struct {
+	Note     uint8  `json:"note"`
+	NoteName string `json:"note_name"`
+	Gate     bool   `json:"gate"`
+	Accent   bool   `json:"accent,omitempty"`
+	Slide    bool   `json:"slide,omitempty"`
+	Tie      bool   `json:"tie,omitempty"`
+	Velocity uint8  `json:"velocity,omitempty"`
+}
+
+// NewReport describes a parsed pattern for the inspect JSON
+func NewReport(p *converter.Pattern, source string, format converter.Format, dev converter.Device) *Report {
+	r := &Report{
+		Source:   source,
+		Format:   string(format),
+		Device:   dev.Name(),
+		Name:     p.Name,
+		Length:   len(p.Steps),
+		Analysis: analysis.Analyze(p),
+		Steps:    make([]StepReport, len(p.Steps)),
+	}
+	for i, s := range p.Steps {
+		r.Steps[i] = StepReport{Note: s.Note, NoteName: converter.NoteName(s.Note), Gate: s.Gate, Accent: s.Accent, Slide: s.Slide, Tie: s.Tie, Velocity: s.Velocity}
+	}
+	return r
+}
+
+// Report describes the converted pattern for the inspect JSON, with the
+// conversion's warnings
+func (r *ConvertResponse) Report(source string) *Report {
+	report := NewReport(r.Pattern, source, r.From, r.Device)
+	report.Warnings = r.WarningStrings()
+	return report
+}
+
+// Zip bundles the outputs, and with report the inspect JSON named after
+// source, into a zip archive
+func (r *ConvertResponse) Zip(source string, report bool) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, out := range r.Outputs {
+		if err := AddZipFile(zw, out.Name, out.Data); err != nil {
+			return nil, err
+		}
+	}
+	if report {
+		data, err := json.MarshalIndent(r.Report(source), "", "  ")
+		if err == nil {
+			err = AddZipFile(zw, BaseName(source)+".json", append(data, '\n'))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AddZipFile adds a file to a zip archive
+func AddZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,247 @@
+// Package service runs conversions for the front-ends (CLI, TUI, and the
+// HTTP APIs): device lookup, format detection and checks, options, parse
+// limits, warning collection, and output naming, independent of transport
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/sysex"
+)
+
+// DefaultDevice is the device used when a request names none
+const DefaultDevice = devices.DefaultID
+
+// Error kinds; errors returned by Convert match one of them with errors.Is
+// when the request or its input is at fault
+var (
+	// ErrInvalidRequest marks bad parameters: an unknown device or format,
+	// or invalid options
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrInvalidInput marks input that fails to parse
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrRejectedInput marks input refused before or after parsing: empty,
+	// in another format than declared, beyond the parse limits, or failing
+	// strict validation
+	ErrRejectedInput = errors.New("rejected input")
+)
+
+// Error is an error of one of the kinds above; its message is that of Err
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns both the kind and the underlying error
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+func fail(kind, err error) error {
+	return &Error{Kind: kind, Err: err}
+}
+
+// ConvertRequest is one conversion
+type ConvertRequest struct {
+	Data     []byte
+	Filename string             // input file name, for format detection and output naming
+	From     converter.Format   // input format; empty detects it from Filename, then the content
+	To       []converter.Format // output formats; none only parses the input
+	Device   string             // device ID or alias; empty means DefaultDevice
+	Options  converter.ConvertOptions
+	Limits   converter.Limits // zero for trusted input
+}
+
+// Output is one converted file
+type Output struct {
+	Format converter.Format
+	Name   string // Filename with the format's extension
+	Data   []byte
+}
+
+// ConvertResponse is the result of a conversion
+type ConvertResponse struct {
+	Pattern  *converter.Pattern
+	From     converter.Format // the input format used
+	Device   converter.Device
+	Outputs  []Output // one per requested format, in order
+	Warnings []converter.Violation
+
+	// InputDeviceID is the SysEx device ID of .syx input, if it has one
+	InputDeviceID *uint8
+}
+
+// Convert parses the request's input and generates each requested format.
+// A declared input format (From or the file extension) is checked against
+// the content first, so a mismatch fails with a FormatError rather than a
+// parser error.
+func Convert(ctx context.Context, req ConvertRequest) (*ConvertResponse, error) {
+	name := req.Device
+	if name == "" {
+		name = DefaultDevice
+	}
+	device, ok := devices.Shared(name)
+	if !ok {
+		return nil, fail(ErrInvalidRequest, fmt.Errorf("unknown device %q", name))
+	}
+	if err := req.Options.Validate(); err != nil {
+		return nil, fail(ErrInvalidRequest, err)
+	}
+
+	format, err := inputFormat(req)
+	if err != nil {
+		return nil, err
+	}
+	if msg, ok := sysex.FromText(req.Data); ok && format == converter.FormatSyx {
+		req.Data = msg
+	}
+	conv := converter.New(device)
+	conv.SetOptions(req.Options)
+	conv.SetLimits(req.Limits)
+	pattern, err := conv.ParsePatternContext(ctx, req.Data, format)
+	var damage []converter.Violation
+	if err != nil && req.Options.Recover && !errors.Is(err, converter.ErrLimitExceeded) && ctx.Err() == nil {
+		pattern, damage, err = recoverPattern(conv, device, req.Data, format, err)
+	}
+	switch {
+	case errors.Is(err, converter.ErrLimitExceeded):
+		return nil, fail(ErrRejectedInput, err)
+	case err != nil && ctx.Err() != nil:
+		return nil, err
+	case err != nil:
+		return nil, fail(ErrInvalidInput, err)
+	}
+
+	resp := &ConvertResponse{Pattern: pattern, From: format, Device: device}
+	if format == converter.FormatSyx {
+		if id, err := converter.ExtractDeviceID(req.Data); err == nil {
+			resp.InputDeviceID = &id
+		}
+	}
+	if len(req.To) == 0 {
+		resp.Warnings = damage
+		return resp, nil
+	}
+	outputs, warnings, err := conv.GenerateAll(pattern, req.To...)
+	if err != nil {
+		var verr *converter.ValidationError
+		if errors.As(err, &verr) {
+			return nil, fail(ErrRejectedInput, err)
+		}
+		return nil, err
+	}
+	base := BaseName(req.Filename)
+	for i, f := range req.To {
+		resp.Outputs = append(resp.Outputs, Output{Format: f, Name: base + Ext(f), Data: outputs[i]})
+	}
+	resp.Warnings = append(damage, warnings...)
+	return resp, nil
+}
+
+// recoverPattern salvages input the device failed to parse with parseErr
+// (see converter.Recover), unless it looks like another device's file: that
+// is a matter for the device setting, not damage
+func recoverPattern(conv *converter.Converter, device converter.Device, data []byte, format converter.Format, parseErr error) (*converter.Pattern, []converter.Violation, error) {
+	if info, ok := closerDevice(device, data, format); ok {
+		return nil, nil, fmt.Errorf("%w (not recovered: it looks like a %s file, for --device %s)", parseErr, info.Name, info.ID)
+	}
+	pattern, damage, err := conv.Recover(data, format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w (not recovered: %v)", parseErr, err)
+	}
+	return pattern, damage, nil
+}
+
+// closerDevice returns another registered device whose files start more
+// like data than those of device do. Only the first bytes are compared:
+// the header of a .seq file, or the IDs and first steps of a SysEx dump.
+func closerDevice(device converter.Device, data []byte, format converter.Format) (devices.Info, bool) {
+	if format == converter.FormatSyx {
+		if start := bytes.IndexByte(data, converter.SysExStart); start > 0 {
+			data = data[start:]
+		}
+	}
+	similarity := func(dev converter.Device) int {
+		file, err := converter.EmptyFile(dev, format)
+		if err != nil {
+			return -1
+		}
+		n := 0
+		for i := range min(len(file), len(data), 16) {
+			if file[i] == data[i] {
+				n++
+			}
+		}
+		return n
+	}
+
+	best, found := similarity(device), false
+	var closest devices.Info
+	for _, info := range devices.List() {
+		if n := similarity(info.New()); info.Name != device.Name() && n > best {
+			best, closest, found = n, info, true
+		}
+	}
+	return closest, found
+}
+
+// inputFormat returns the format to parse the request's input as, checking
+// a declared format against the content
+func inputFormat(req ConvertRequest) (converter.Format, error) {
+	if len(req.Data) == 0 {
+		return "", fail(ErrRejectedInput, ErrEmpty)
+	}
+	format := req.From
+	if format == "" {
+		format = converter.DetectFormat(req.Filename)
+	}
+	if format == converter.FormatUnknown {
+		return converter.DetectFormatFromContent(req.Data), nil
+	}
+	if req.Options.Recover && (format == converter.FormatSeq || format == converter.FormatSyx) {
+		return format, nil // damage may hide what it is
+	}
+	if err := CheckFormat(req.Data, format); err != nil {
+		return "", fail(ErrRejectedInput, err)
+	}
+	return format, nil
+}
+
+// BaseName returns a file name without its directory and extension, or
+// "converted" if that leaves nothing
+func BaseName(filename string) string {
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if base == "" || base == "." || base == string(filepath.Separator) {
+		return "converted"
+	}
+	return base
+}
+
+// Ext returns the file extension for a format
+func Ext(f converter.Format) string {
+	if f == converter.FormatMIDI {
+		return ".mid"
+	}
+	return "." + string(f)
+}
+
+// WarningStrings returns the warnings as text
+func (r *ConvertResponse) WarningStrings() []string {
+	msgs := make([]string, len(r.Warnings))
+	for i, w := range r.Warnings {
+		msgs[i] = w.String()
+	}
+	return msgs
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestConvert(t *testing.T) {
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{
+		Length: 16,
+		// A tie after a rest is fixed up with a warning
+		Steps: []converter.Step{{}, {Note: 48, Gate: true, Velocity: 100, Tie: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := Convert(context.Background(), ConvertRequest{
+		Data:     seq,
+		Filename: "dir/line.seq",
+		To:       []converter.Format{converter.FormatMIDI, converter.FormatSyx},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.From != converter.FormatSeq || resp.Device.Name() != devices.NewTD3().Name() {
+		t.Errorf("converted from %s on %s", resp.From, resp.Device.Name())
+	}
+	if len(resp.Outputs) != 2 || resp.Outputs[0].Name != "line.mid" || resp.Outputs[1].Name != "line.syx" {
+		t.Errorf("outputs = %+v", resp.Outputs)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Errorf("warnings = %v, want the tie fix-up", resp.Warnings)
+	}
+
+	// Parse only
+	resp, err = Convert(context.Background(), ConvertRequest{Data: seq, From: converter.FormatSeq})
+	if err != nil || resp.Pattern == nil || len(resp.Outputs) != 0 {
+		t.Errorf("parse only = %+v, %v", resp, err)
+	}
+
+	// SysEx pasted as hex text, with or without a .syx name
+	syx, err := devices.NewTD3().GenerateSyx(resp.Pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Convert(context.Background(), ConvertRequest{Data: syx, From: converter.FormatSyx, To: []converter.Format{converter.FormatSeq}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pasted := []byte(fmt.Sprintf("# pasted\n% X\n", syx))
+	for _, name := range []string{"paste.syx", "paste.txt"} {
+		resp, err := Convert(context.Background(), ConvertRequest{Data: pasted, Filename: name, To: []converter.Format{converter.FormatSeq}})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if resp.From != converter.FormatSyx || resp.InputDeviceID == nil || string(resp.Outputs[0].Data) != string(want.Outputs[0].Data) {
+			t.Errorf("%s: converted from %s to % X, want % X", name, resp.From, resp.Outputs[0].Data, want.Outputs[0].Data)
+		}
+	}
+
+	strict := converter.DefaultOptions()
+	strict.Strict = true
+	tests := []struct {
+		name string
+		req  ConvertRequest
+		kind error
+	}{
+		{"unknown device", ConvertRequest{Data: seq, Device: "tb303"}, ErrInvalidRequest},
+		{"invalid options", ConvertRequest{Data: seq, Options: converter.ConvertOptions{Channel: 17}}, ErrInvalidRequest},
+		{"empty", ConvertRequest{Filename: "x.seq"}, ErrRejectedInput},
+		{"format mismatch", ConvertRequest{Data: seq, From: converter.FormatMIDI}, ErrRejectedInput},
+		{"limits", ConvertRequest{Data: []byte{0xF0, 0xF7, 0xF0, 0xF7}, Limits: converter.Limits{MaxSysExMessages: 1}}, ErrRejectedInput},
+		{"strict", ConvertRequest{Data: seq, Filename: "x.seq", To: []converter.Format{converter.FormatMIDI}, Options: strict}, ErrRejectedInput},
+		{"unparsable", ConvertRequest{Data: []byte{1, 2, 3, 4, 5}, From: converter.FormatSeq}, ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Convert(context.Background(), tt.req); !errors.Is(err, tt.kind) {
+				t.Errorf("Convert() = %v, want %v", err, tt.kind)
+			}
+		})
+	}
+}
+
+func TestConvertRecover(t *testing.T) {
+	line := &converter.Pattern{Length: 16, Steps: []converter.Step{{Note: 48, Gate: true, Velocity: 100}}}
+	td3, err := devices.NewTD3().GenerateSeq(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms1, err := devices.NewMS1().GenerateSeq(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salvage := converter.DefaultOptions()
+	salvage.Recover = true
+
+	tests := []struct {
+		name    string
+		data    []byte
+		opts    converter.ConvertOptions
+		wantErr string
+		warning string
+	}{
+		{"truncated", td3[:len(td3)-2], converter.DefaultOptions(), "too short", ""},
+		{"truncated recovered", td3[:len(td3)-2], salvage, "", "bytes 144-145 missing"},
+		{"another device's file", ms1[:len(ms1)-2], salvage, "looks like a Behringer MS-1 file, for --device ms1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := Convert(context.Background(), ConvertRequest{Data: tt.data, Filename: "line.seq", Options: tt.opts})
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidInput) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Convert() = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Warnings) == 0 || !strings.Contains(resp.Warnings[0].String(), tt.warning) {
+				t.Errorf("warnings = %v, want %q", resp.Warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		declared converter.Format
+		want     string
+	}{
+		{"MIDI", "MThd\x00\x00\x00\x06", converter.FormatMIDI, ""},
+		{"MIDI as SysEx", "MThd\x00\x00\x00\x06", converter.FormatSyx, "this looks like a MIDI file, not a SysEx dump"},
+		{"text as .seq", "note C D E", converter.FormatSeq, "this looks like a text pattern, not a .seq file"},
+		{"binary as .seq", "\x23\x98\x54\x76\x00", converter.FormatSeq, ""},
+		{"binary as MIDI", "\x23\x98\x54\x76\x00", converter.FormatMIDI, "this is not a MIDI file: it has no MThd header"},
+		{"session as MIDI", "NCS\x01JAM", converter.FormatMIDI, "this looks like a Circuit session, not a MIDI file"},
+		{"MIDI as session", "MThd\x00\x00\x00\x06", converter.FormatCircuit, "this looks like a MIDI file, not a Circuit session"},
+		{"hex text as SysEx", "F0 00 20 32 F7", converter.FormatSyx, ""},
+		{"hex text as .seq", "F0 00 20 32 F7", converter.FormatSeq, "this looks like a SysEx dump, not a .seq file"},
+		{"empty", "", converter.FormatSeq, ErrEmpty.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if err := CheckFormat([]byte(tt.data), tt.declared); err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("CheckFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	for name, want := range map[string]string{
+		"line.seq":        "line",
+		"dir/line.mid":    "line",
+		"bank.backup.syx": "bank.backup",
+		"":                "converted",
+		".seq":            "converted",
+		"/":               "converted",
+	} {
+		if got := BaseName(name); got != want {
+			t.Errorf("BaseName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSplitBank(t *testing.T) {
+	td3 := devices.NewTD3()
+	var bank []byte
+	for i := range 3 {
+		syx, err := td3.GenerateSyx(&converter.Pattern{
+			Length: 16,
+			Steps:  []converter.Step{{Note: uint8(48 + i), Gate: true, Velocity: 100}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		bank = append(bank, syx...)
+	}
+
+	items, err := SplitBank("dir/backup.syx", bank, converter.DefaultLimits())
+	if err != nil || len(items) != 3 || items[2].Name != "backup-03" || items[2].Format != converter.FormatSyx {
+		t.Fatalf("SplitBank() = %+v, %v", items, err)
+	}
+	item, err := PickBankItem(items, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern, err := td3.ParseSyx(item.Data)
+	if err != nil || pattern.Steps[0].Note != 49 {
+		t.Errorf("pattern 2 = %+v, %v", pattern, err)
+	}
+	for _, index := range []int{0, 4} {
+		if _, err := PickBankItem(items, index); err == nil {
+			t.Errorf("PickBankItem(%d) succeeded", index)
+		}
+	}
+
+	if items, err := SplitBank("line.syx", bank[:len(bank)/3], converter.Limits{}); err != nil || len(items) != 1 || items[0].Name != "line" {
+		t.Errorf("SplitBank() of one dump = %+v, %v", items, err)
+	}
+
+	// Banks beyond the limits are rejected
+	for _, limits := range []converter.Limits{{MaxPatterns: 2}, {MaxSysExMessages: 2}} {
+		if _, err := SplitBank("backup.syx", bank, limits); !errors.Is(err, converter.ErrLimitExceeded) {
+			t.Errorf("SplitBank() with limits %+v error = %v, want a limit error", limits, err)
+		}
+	}
+}
+
+// BenchmarkConvert measures the API server's hot path: one upload converted
+// to every format
+func BenchmarkConvert(b *testing.B) {
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: 45, Gate: true, Velocity: 100}, {Note: 48, Gate: true, Velocity: 127, Accent: true}},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	req := ConvertRequest{
+		Data:     seq,
+		Filename: "line.seq",
+		To:       []converter.Format{converter.FormatMIDI, converter.FormatSyx, converter.FormatSeq},
+		Options:  converter.DefaultOptions(),
+		Limits:   converter.DefaultLimits(),
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Convert(context.Background(), req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
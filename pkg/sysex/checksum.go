@@ -0,0 +1,55 @@
+package sysex
+
+// Checksum is a checksum scheme used by a device's SysEx messages.
+// Device handlers select the scheme matching their hardware.
+type Checksum interface {
+	// Name identifies the scheme in error messages
+	Name() string
+	// Sum computes the checksum byte over a message payload
+	Sum(payload []byte) byte
+}
+
+// Built-in checksum schemes used by Behringer devices
+var (
+	// XOR combines all payload bytes with XOR, masked to 7 bits
+	XOR Checksum = xorChecksum{}
+	// SumMask adds all payload bytes and keeps the low 7 bits
+	SumMask Checksum = sumMaskChecksum{}
+	// None marks messages without a checksum byte
+	None Checksum = noChecksum{}
+)
+
+type xorChecksum struct{}
+
+func (xorChecksum) Name() string { return "xor" }
+
+func (xorChecksum) Sum(payload []byte) byte {
+	var sum byte
+	for _, b := range payload {
+		sum ^= b
+	}
+	return sum & 0x7F
+}
+
+type sumMaskChecksum struct{}
+
+func (sumMaskChecksum) Name() string { return "sum" }
+
+func (sumMaskChecksum) Sum(payload []byte) byte {
+	var sum byte
+	for _, b := range payload {
+		sum += b
+	}
+	return sum & 0x7F
+}
+
+type noChecksum struct{}
+
+func (noChecksum) Name() string { return "none" }
+
+func (noChecksum) Sum(payload []byte) byte { return 0 }
+
+// hasChecksum reports whether c adds a checksum byte to messages
+func hasChecksum(c Checksum) bool {
+	return c != nil && c != None
+}
@@ -0,0 +1,160 @@
+package sysex
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Manufacturer is a registered MIDI manufacturer ID
+type Manufacturer struct {
+	ID   []byte
+	Name string
+}
+
+// Model is a known device model within a manufacturer's SysEx space
+type Model struct {
+	Manufacturer []byte
+	ID           byte
+	Name         string
+	Device       string // CLI device name handling this model, if supported
+}
+
+// Universal and well-known manufacturer IDs
+var manufacturers = []Manufacturer{
+	{ID: []byte{0x01}, Name: "Sequential Circuits"},
+	{ID: []byte{0x04}, Name: "Moog"},
+	{ID: []byte{0x0F}, Name: "Ensoniq"},
+	{ID: []byte{0x10}, Name: "Oberheim"},
+	{ID: []byte{0x18}, Name: "E-mu"},
+	{ID: []byte{0x40}, Name: "Kawai"},
+	{ID: []byte{0x41}, Name: "Roland"},
+	{ID: []byte{0x42}, Name: "Korg"},
+	{ID: []byte{0x43}, Name: "Yamaha"},
+	{ID: []byte{0x44}, Name: "Casio"},
+	{ID: []byte{0x47}, Name: "Akai"},
+	{ID: []byte{0x7D}, Name: "Non-commercial"},
+	{ID: []byte{0x7E}, Name: "Universal Non-Real Time"},
+	{ID: []byte{0x7F}, Name: "Universal Real Time"},
+	{ID: []byte{0x00, 0x20, 0x29}, Name: "Focusrite/Novation"},
+	{ID: []byte{0x00, 0x20, 0x32}, Name: "Behringer"},
+	{ID: []byte{0x00, 0x20, 0x3C}, Name: "Elektron"},
+	{ID: []byte{0x00, 0x20, 0x6B}, Name: "Arturia"},
+}
+
+// Known device models, extended by device handlers via RegisterModel
+var models = []Model{
+	{Manufacturer: Behringer, ID: 0x01, Name: "Behringer TD-3", Device: "td3"},
+}
+
+// RegisterModel adds or replaces a known device model
+func RegisterModel(m Model) {
+	for i, existing := range models {
+		if bytes.Equal(existing.Manufacturer, m.Manufacturer) && existing.ID == m.ID {
+			models[i] = m
+			return
+		}
+	}
+	models = append(models, m)
+}
+
+// LookupManufacturer returns the manufacturer registered for an ID
+func LookupManufacturer(id []byte) (Manufacturer, bool) {
+	for _, m := range manufacturers {
+		if bytes.Equal(m.ID, id) {
+			return m, true
+		}
+	}
+	return Manufacturer{}, false
+}
+
+// LookupModel returns the known model for a manufacturer and model ID
+func LookupModel(manufacturer []byte, id byte) (Model, bool) {
+	for _, m := range models {
+		if bytes.Equal(m.Manufacturer, manufacturer) && m.ID == id {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// Identity describes the sender of a SysEx message
+type Identity struct {
+	Manufacturer Manufacturer
+	Model        *Model
+	DeviceID     byte
+	HasDeviceID  bool
+}
+
+// Identify decodes the manufacturer, and for Behringer and Korg messages the
+// device ID and model, of a SysEx message
+func Identify(data []byte) (Identity, error) {
+	id, err := ManufacturerID(data)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	ident := Identity{Manufacturer: Manufacturer{ID: id}}
+	if m, ok := LookupManufacturer(id); ok {
+		ident.Manufacturer = m
+	}
+
+	// Behringer messages carry device ID and model ID after the manufacturer
+	if bytes.Equal(id, Behringer) && len(data) > 6 {
+		ident.DeviceID = data[4]
+		ident.HasDeviceID = true
+		model, ok := LookupModel(id, data[5])
+		if !ok {
+			model = Model{Manufacturer: id, ID: data[5]}
+		}
+		ident.Model = &model
+	}
+
+	// Korg messages carry the MIDI channel (3n), then the 00 01 family and
+	// the model ID; only known models are named
+	if bytes.Equal(id, Korg) && len(data) > 6 && data[2]&0xF0 == 0x30 && data[3] == 0x00 && data[4] == 0x01 {
+		ident.DeviceID = data[2] & 0x0F
+		ident.HasDeviceID = true
+		if model, ok := LookupModel(id, data[5]); ok {
+			ident.Model = &model
+		}
+	}
+	return ident, nil
+}
+
+// String returns a human-readable description such as "Behringer TD-3 (device ID 0)"
+func (i Identity) String() string {
+	name := i.Manufacturer.Name
+	if name == "" {
+		name = fmt.Sprintf("unknown manufacturer (% X)", i.Manufacturer.ID)
+	}
+	if i.Model != nil {
+		if i.Model.Name != "" {
+			name = i.Model.Name
+		} else {
+			name = fmt.Sprintf("%s model 0x%02X", name, i.Model.ID)
+		}
+	}
+	if i.HasDeviceID {
+		name = fmt.Sprintf("%s (device ID %d)", name, i.DeviceID)
+	}
+	return name
+}
+
+// Hint explains what a message is and which device handles it, for use in
+// error messages when the wrong device was selected
+func (i Identity) Hint() string {
+	if i.Model != nil && i.Model.Device != "" {
+		return fmt.Sprintf("this is a %s dump; use --device %s", i.Model.Name, i.Model.Device)
+	}
+	return fmt.Sprintf("this is a SysEx message from %s", i.String())
+}
+
+// Describe identifies a SysEx message and returns its hint, or a generic
+// description when the data cannot be identified
+func Describe(data []byte) string {
+	ident, err := Identify(data)
+	if err != nil {
+		return "not a recognizable SysEx message"
+	}
+	return ident.Hint()
+}
@@ -0,0 +1,45 @@
+package sysex
+
+import "fmt"
+
+// Pack7 packs 8-bit data into 7-bit SysEx bytes the way Korg devices do:
+// each group of up to 7 bytes is preceded by a byte holding their high bits,
+// bit 0 for the first byte of the group
+func Pack7(data []byte) []byte {
+	packed := make([]byte, 0, len(data)+(len(data)+6)/7)
+	for i := 0; i < len(data); i += 7 {
+		group := data[i:min(i+7, len(data))]
+		var high byte
+		for j, b := range group {
+			high |= (b >> 7) << j
+		}
+		packed = append(packed, high)
+		for _, b := range group {
+			packed = append(packed, b&0x7F)
+		}
+	}
+	return packed
+}
+
+// PackedOffset returns the offset in Pack7 output of data byte i
+func PackedOffset(i int) int {
+	return i/7*8 + 1 + i%7
+}
+
+// Unpack7 reverses Pack7
+func Unpack7(packed []byte) ([]byte, error) {
+	data := make([]byte, 0, len(packed)-(len(packed)+7)/8)
+	for i := 0; i < len(packed); i += 8 {
+		group := packed[i:min(i+8, len(packed))]
+		if len(group) == 1 {
+			return nil, fmt.Errorf("packed data ends with a lone high-bit byte at offset %d", i)
+		}
+		for j, b := range group[1:] {
+			if b > 0x7F || group[0] > 0x7F {
+				return nil, fmt.Errorf("packed byte at offset %d is not 7-bit", i)
+			}
+			data = append(data, b|(group[0]>>j&1)<<7)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,264 @@
+// Package sysex provides building, parsing, and validation of MIDI System
+// Exclusive messages shared by device handlers and the live MIDI layer
+package sysex
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SysEx framing bytes
+const (
+	Start = 0xF0
+	End   = 0xF7
+)
+
+// Behringer is the extended manufacturer ID used by all Behringer devices
+var Behringer = []byte{0x00, 0x20, 0x32}
+
+// Korg is the manufacturer ID of Korg devices
+var Korg = []byte{0x42}
+
+// Layout describes how the body of a device message is laid out after the
+// manufacturer ID: a fixed-size header, the payload, and an optional checksum
+// (nil or None means the message carries no checksum byte)
+type Layout struct {
+	HeaderLen int
+	Checksum  Checksum
+}
+
+// Message is a parsed SysEx message
+type Message struct {
+	Manufacturer []byte
+	Header       []byte
+	Payload      []byte
+	Checksum     byte
+}
+
+// OffsetError is an error at a byte offset of a message or stream, with
+// the part of the message found there
+type OffsetError struct {
+	Offset int
+	Field  string
+	Err    error
+}
+
+// Error implements the error interface
+func (e *OffsetError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *OffsetError) Unwrap() error {
+	return e.Err
+}
+
+func errorAt(offset int, field string, err error) error {
+	return &OffsetError{Offset: offset, Field: field, Err: err}
+}
+
+// Builder assembles a SysEx message
+type Builder struct {
+	manufacturer []byte
+	header       []byte
+	payload      []byte
+	checksum     Checksum
+}
+
+// NewBuilder creates a Builder for the given manufacturer ID (1 or 3 bytes)
+func NewBuilder(manufacturer ...byte) *Builder {
+	return &Builder{manufacturer: manufacturer}
+}
+
+// Header appends bytes to the message header (device ID, model, command, ...)
+func (b *Builder) Header(data ...byte) *Builder {
+	b.header = append(b.header, data...)
+	return b
+}
+
+// Payload appends bytes to the message payload
+func (b *Builder) Payload(data ...byte) *Builder {
+	b.payload = append(b.payload, data...)
+	return b
+}
+
+// Checksum sets the checksum scheme appended after the payload
+func (b *Builder) Checksum(c Checksum) *Builder {
+	b.checksum = c
+	return b
+}
+
+// Build assembles the message and verifies that every data byte is 7-bit safe
+func (b *Builder) Build() ([]byte, error) {
+	if err := validateManufacturer(b.manufacturer); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, 0, 2+len(b.manufacturer)+len(b.header)+len(b.payload)+1)
+	msg = append(msg, Start)
+	msg = append(msg, b.manufacturer...)
+	msg = append(msg, b.header...)
+	msg = append(msg, b.payload...)
+	if hasChecksum(b.checksum) {
+		msg = append(msg, b.checksum.Sum(b.payload))
+	}
+	msg = append(msg, End)
+
+	if err := Validate(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Validate checks SysEx framing and that all data bytes are 7-bit
+func Validate(data []byte) error {
+	if len(data) < 2 {
+		return errorAt(len(data), "message", errors.New("syx data too short"))
+	}
+	if data[0] != Start {
+		return errorAt(0, "start byte", fmt.Errorf("invalid SysEx: expected start byte 0x%02X, got 0x%02X", Start, data[0]))
+	}
+	if data[len(data)-1] != End {
+		return errorAt(len(data)-1, "end byte", fmt.Errorf("invalid SysEx: expected end byte 0x%02X, got 0x%02X", End, data[len(data)-1]))
+	}
+	for i := 1; i < len(data)-1; i++ {
+		if data[i] > 0x7F {
+			return errorAt(i, "data byte", fmt.Errorf("invalid SysEx: byte at position %d is > 127 (0x%02X)", i, data[i]))
+		}
+	}
+	return nil
+}
+
+// Parse validates a single SysEx message, splits it according to layout, and
+// verifies its checksum
+func Parse(data []byte, layout Layout) (*Message, error) {
+	if err := Validate(data); err != nil {
+		return nil, err
+	}
+
+	manufacturer, err := ManufacturerID(data)
+	if err != nil {
+		return nil, err
+	}
+
+	body := data[1+len(manufacturer) : len(data)-1]
+	minLen := layout.HeaderLen
+	if hasChecksum(layout.Checksum) {
+		minLen++
+	}
+	if len(body) < minLen {
+		return nil, errorAt(len(data)-1, "message header", fmt.Errorf("syx message too short: body is %d bytes, need at least %d", len(body), minLen))
+	}
+
+	msg := &Message{
+		Manufacturer: manufacturer,
+		Header:       body[:layout.HeaderLen],
+		Payload:      body[layout.HeaderLen:],
+	}
+	if hasChecksum(layout.Checksum) {
+		msg.Payload = body[layout.HeaderLen : len(body)-1]
+		msg.Checksum = body[len(body)-1]
+		if want := layout.Checksum.Sum(msg.Payload); msg.Checksum != want {
+			return nil, errorAt(len(data)-2, "checksum", fmt.Errorf("%s checksum mismatch: got 0x%02X, want 0x%02X", layout.Checksum.Name(), msg.Checksum, want))
+		}
+	}
+	return msg, nil
+}
+
+// ManufacturerID returns the 1- or 3-byte manufacturer ID of a SysEx message
+func ManufacturerID(data []byte) ([]byte, error) {
+	if len(data) < 3 || data[0] != Start {
+		return nil, errorAt(len(data), "manufacturer ID", errors.New("syx data too short for manufacturer ID"))
+	}
+	if data[1] == 0x00 {
+		if len(data) < 5 {
+			return nil, errorAt(len(data), "manufacturer ID", errors.New("syx data too short for extended manufacturer ID"))
+		}
+		return data[1:4], nil
+	}
+	return data[1:2], nil
+}
+
+// PayloadOffset returns the offset of the payload in the message data
+func (m *Message) PayloadOffset() int {
+	return 1 + len(m.Manufacturer) + len(m.Header)
+}
+
+// HasManufacturer reports whether the message was sent by the given manufacturer
+func HasManufacturer(data []byte, manufacturer []byte) bool {
+	id, err := ManufacturerID(data)
+	if err != nil || len(id) != len(manufacturer) {
+		return false
+	}
+	for i := range id {
+		if id[i] != manufacturer[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Split separates a stream of concatenated SysEx messages (as found in bank
+// dumps) into individual messages. Bytes outside F0...F7 frames are rejected.
+func Split(stream []byte) ([][]byte, error) {
+	var messages [][]byte
+	start := -1
+	for i, b := range stream {
+		switch {
+		case b == Start:
+			if start >= 0 {
+				return nil, errorAt(i, "message start", fmt.Errorf("unterminated SysEx message at offset %d", start))
+			}
+			start = i
+		case b == End:
+			if start < 0 {
+				return nil, errorAt(i, "message end", fmt.Errorf("unexpected end byte at offset %d", i))
+			}
+			messages = append(messages, stream[start:i+1])
+			start = -1
+		case start < 0:
+			return nil, errorAt(i, "gap between messages", fmt.Errorf("unexpected byte 0x%02X outside SysEx message at offset %d", b, i))
+		}
+	}
+	if start >= 0 {
+		return nil, errorAt(len(stream), "message end", fmt.Errorf("unterminated SysEx message at offset %d", start))
+	}
+	return messages, nil
+}
+
+// ParseHex parses a message written as hex bytes, e.g. "F0 00 20 32 01 F7".
+// Bytes may be separated by spaces or commas and carry a 0x prefix.
+func ParseHex(text string) ([]byte, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t' || r == '\n' || r == '\r'
+	})
+	data := make([]byte, 0, len(fields))
+	for _, f := range fields {
+		f = strings.TrimPrefix(strings.TrimPrefix(f, "0x"), "0X")
+		// Unseparated runs such as "F0002032" hold several bytes
+		if len(f)%2 != 0 {
+			return nil, fmt.Errorf("invalid hex byte %q", f)
+		}
+		for i := 0; i < len(f); i += 2 {
+			b, err := strconv.ParseUint(f[i:i+2], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex byte %q", f[i:i+2])
+			}
+			data = append(data, byte(b))
+		}
+	}
+	return data, nil
+}
+
+func validateManufacturer(id []byte) error {
+	switch {
+	case len(id) == 1 && id[0] != 0x00 && id[0] <= 0x7F:
+		return nil
+	case len(id) == 3 && id[0] == 0x00:
+		return nil
+	default:
+		return fmt.Errorf("invalid manufacturer ID % X", id)
+	}
+}
@@ -0,0 +1,247 @@
+package sysex
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuilderBuild(t *testing.T) {
+	msg, err := NewBuilder(Behringer...).
+		Header(0x00, 0x01, 0x40).
+		Payload(0x10, 0x03, 0x22).
+		Checksum(XOR).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	want := []byte{0xF0, 0x00, 0x20, 0x32, 0x00, 0x01, 0x40, 0x10, 0x03, 0x22, 0x10 ^ 0x03 ^ 0x22, 0xF7}
+	if !bytes.Equal(msg, want) {
+		t.Errorf("Build() = % X, want % X", msg, want)
+	}
+}
+
+func TestBuilderRejectsUnsafeBytes(t *testing.T) {
+	_, err := NewBuilder(0x41).Payload(0x80).Build()
+	if err == nil {
+		t.Error("Build() expected error for 8-bit payload byte")
+	}
+
+	_, err = NewBuilder(0x00, 0x20).Build()
+	if err == nil {
+		t.Error("Build() expected error for malformed manufacturer ID")
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := []byte{0xF0, 0x00, 0x20, 0x32, 0x03, 0x01, 0x40, 0x10, 0x03, 0x13, 0xF7}
+
+	msg, err := Parse(data, Layout{HeaderLen: 3, Checksum: XOR})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !bytes.Equal(msg.Manufacturer, Behringer) {
+		t.Errorf("Manufacturer = % X, want % X", msg.Manufacturer, Behringer)
+	}
+	if !bytes.Equal(msg.Header, []byte{0x03, 0x01, 0x40}) {
+		t.Errorf("Header = % X", msg.Header)
+	}
+	if !bytes.Equal(msg.Payload, []byte{0x10, 0x03}) {
+		t.Errorf("Payload = % X", msg.Payload)
+	}
+	if msg.Checksum != 0x13 {
+		t.Errorf("Checksum = 0x%02X, want 0x13", msg.Checksum)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"no start", []byte{0x00, 0x20, 0x32, 0xF7}},
+		{"no end", []byte{0xF0, 0x00, 0x20, 0x32, 0x00}},
+		{"8-bit data", []byte{0xF0, 0x41, 0x90, 0xF7}},
+		{"short body", []byte{0xF0, 0x00, 0x20, 0x32, 0x00, 0xF7}},
+		{"bad checksum", []byte{0xF0, 0x00, 0x20, 0x32, 0x03, 0x01, 0x40, 0x10, 0x03, 0x14, 0xF7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data, Layout{HeaderLen: 3, Checksum: XOR}); err == nil {
+				t.Error("Parse() expected error")
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	stream := []byte{0xF0, 0x41, 0x01, 0xF7, 0xF0, 0x00, 0x20, 0x32, 0x02, 0xF7}
+
+	messages, err := Split(stream)
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Split() returned %d messages, want 2", len(messages))
+	}
+	if !HasManufacturer(messages[1], Behringer) {
+		t.Error("second message should be Behringer")
+	}
+
+	if _, err := Split([]byte{0xF0, 0x41, 0x01}); err == nil {
+		t.Error("Split() expected error for unterminated message")
+	}
+	_, err = Split([]byte{0xF0, 0x41, 0xF7, 0x12, 0xF0, 0x41, 0xF7})
+	var oerr *OffsetError
+	if !errors.As(err, &oerr) || oerr.Offset != 3 {
+		t.Errorf("Split() error = %v, want one at offset 3", err)
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	payload := []byte{0x7F, 0x01, 0x02}
+
+	tests := []struct {
+		checksum Checksum
+		want     byte
+	}{
+		{XOR, 0x7F ^ 0x01 ^ 0x02},
+		{SumMask, (0x7F + 0x01 + 0x02) & 0x7F},
+		{None, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.checksum.Name(), func(t *testing.T) {
+			if got := tt.checksum.Sum(payload); got != tt.want {
+				t.Errorf("Sum() = 0x%02X, want 0x%02X", got, tt.want)
+			}
+		})
+	}
+
+	// Messages without a checksum byte carry the payload through to the end
+	msg, err := NewBuilder(0x41).Header(0x10).Payload(payload...).Checksum(None).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	parsed, err := Parse(msg, Layout{HeaderLen: 1, Checksum: None})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !bytes.Equal(parsed.Payload, payload) {
+		t.Errorf("Payload = % X, want % X", parsed.Payload, payload)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	data := []byte{0xF0, 0x00, 0x20, 0x32, 0x02, 0x01, 0x40, 0x00, 0xF7}
+
+	ident, err := Identify(data)
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+	if got, want := ident.String(), "Behringer TD-3 (device ID 2)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got, want := ident.Hint(), "this is a Behringer TD-3 dump; use --device td3"; got != want {
+		t.Errorf("Hint() = %q, want %q", got, want)
+	}
+
+	roland, err := Identify([]byte{0xF0, 0x41, 0x10, 0x42, 0xF7})
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+	if got, want := roland.Hint(), "this is a SysEx message from Roland"; got != want {
+		t.Errorf("Hint() = %q, want %q", got, want)
+	}
+
+	unknown, err := Identify([]byte{0xF0, 0x00, 0x7F, 0x7F, 0x01, 0xF7})
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+	if got, want := unknown.String(), "unknown manufacturer (00 7F 7F)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestPack7(t *testing.T) {
+	data := []byte{0x01, 0x80, 0x7F, 0xFF, 0x00, 0x10, 0x81, 0x90, 0x02}
+	packed := Pack7(data)
+	want := []byte{0x4A, 0x01, 0x00, 0x7F, 0x7F, 0x00, 0x10, 0x01, 0x01, 0x10, 0x02}
+	if !bytes.Equal(packed, want) {
+		t.Errorf("Pack7() = % X, want % X", packed, want)
+	}
+	unpacked, err := Unpack7(packed)
+	if err != nil || !bytes.Equal(unpacked, data) {
+		t.Errorf("Unpack7() = % X, %v, want % X", unpacked, err, data)
+	}
+	if _, err := Unpack7([]byte{0x00, 0x01, 0x00}); err != nil {
+		t.Errorf("Unpack7() of a short group error = %v", err)
+	}
+	for _, bad := range [][]byte{{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x00}, {0x00, 0x80}} {
+		if _, err := Unpack7(bad); err == nil {
+			t.Errorf("Unpack7(% X) expected error", bad)
+		}
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []byte
+		wantErr bool
+	}{
+		{"F0 00 20 32 F7", []byte{0xF0, 0x00, 0x20, 0x32, 0xF7}, false},
+		{"0xf0,0x7d, 0xf7", []byte{0xF0, 0x7D, 0xF7}, false},
+		{"F0002032F7", []byte{0xF0, 0x00, 0x20, 0x32, 0xF7}, false},
+		{"", []byte{}, false},
+		{"F0 0", nil, true},
+		{"F0 GG", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHex(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHex(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !bytes.Equal(got, tt.want) {
+			t.Errorf("ParseHex(%q) = % X, want % X", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFromText(t *testing.T) {
+	msg := []byte{0xF0, 0x00, 0x20, 0x32, 0x00, 0x01, 0x40, 0x78, 0xF7}
+	tests := []struct {
+		name string
+		in   string
+		want []byte
+	}{
+		{"hex bytes", "F0 00 20 32 00 01 40 78 F7\n", msg},
+		{"comments", "# TD-3 pattern\nf0 00 20 32 // header\n00 01 40 78\nf7\n", msg},
+		{"intel hex", ":08000000F000203200014078FD\n:01000800F700\n:00000001FF\n", msg},
+		{"intel hex with address record", ":020000040000FA\n:09000000F000203200014078F705\n", msg},
+		{"not sysex", "00 01 02 03", nil},
+		{"notation", "C3 D3 E3", nil},
+		{"binary", string(msg), nil},
+		{"bad record checksum", ":08000000F00020320001407800\n:01000800F700\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromText([]byte(tt.in))
+			if ok != (tt.want != nil) || !bytes.Equal(got, tt.want) {
+				t.Errorf("FromText(%q) = % X, %v, want % X", tt.in, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIntelHexGap(t *testing.T) {
+	_, err := ParseIntelHex(":02000000F0000E\n:01001000F7F8\n")
+	if err == nil || !strings.Contains(err.Error(), "does not follow on") {
+		t.Errorf("ParseIntelHex() error = %v, want a gap error", err)
+	}
+}
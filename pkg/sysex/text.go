@@ -0,0 +1,136 @@
+package sysex
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Intel HEX record types
+const (
+	ihexData        = 0x00
+	ihexEOF         = 0x01
+	ihexSegment     = 0x02
+	ihexStartSeg    = 0x03
+	ihexLinear      = 0x04
+	ihexStartLinear = 0x05
+)
+
+// FromText decodes SysEx written out as text, as it is pasted into forums
+// and chats: hex bytes ("F0 00 20 32 ... F7", see ParseHex), with # or //
+// comments, or an Intel HEX dump. It reports false for anything else,
+// including text that decodes to something other than SysEx messages.
+func FromText(data []byte) ([]byte, bool) {
+	if len(data) == 0 || data[0] == Start || !utf8.Valid(data) {
+		return nil, false
+	}
+	msg, err := ParseText(string(data))
+	if err != nil || len(msg) < 2 || msg[0] != Start || msg[len(msg)-1] != End {
+		return nil, false
+	}
+	return msg, true
+}
+
+// ParseText parses bytes written as text: an Intel HEX dump if every line
+// is a record (starts with ':'), otherwise hex bytes with # or // comments
+func ParseText(text string) ([]byte, error) {
+	if isIntelHex(text) {
+		return ParseIntelHex(text)
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return ParseHex(b.String())
+}
+
+// isIntelHex reports whether every non-blank line of text is an Intel HEX
+// record
+func isIntelHex(text string) bool {
+	records := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line[0] != ':' {
+			return false
+		}
+		records++
+	}
+	return records > 0
+}
+
+// ParseIntelHex parses an Intel HEX dump into the bytes it holds. Data
+// records must follow on from each other, as they do in dumps of a single
+// file; record checksums are verified.
+func ParseIntelHex(text string) ([]byte, error) {
+	var data []byte
+	var base, next uint32
+	started := false
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		rec, err := hex.DecodeString(strings.TrimPrefix(line, ":"))
+		if err != nil || !strings.HasPrefix(line, ":") {
+			return nil, fmt.Errorf("line %d: not an Intel HEX record", n)
+		}
+		if len(rec) < 5 || len(rec) != 5+int(rec[0]) {
+			return nil, fmt.Errorf("line %d: record length does not match its byte count", n)
+		}
+		var sum byte
+		for _, b := range rec {
+			sum += b
+		}
+		if sum != 0 {
+			return nil, fmt.Errorf("line %d: record checksum mismatch", n)
+		}
+
+		payload := rec[4 : len(rec)-1]
+		switch rec[3] {
+		case ihexData:
+			addr := base + uint32(rec[1])<<8 + uint32(rec[2])
+			if started && addr != next {
+				return nil, fmt.Errorf("line %d: data at address 0x%X does not follow on from 0x%X", n, addr, next)
+			}
+			data = append(data, payload...)
+			next, started = addr+uint32(len(payload)), true
+		case ihexEOF:
+			return data, nil
+		case ihexSegment, ihexLinear:
+			if len(payload) != 2 {
+				return nil, fmt.Errorf("line %d: address record has %d data bytes, want 2", n, len(payload))
+			}
+			base = uint32(payload[0])<<8 | uint32(payload[1])
+			if rec[3] == ihexSegment {
+				base <<= 4
+			} else {
+				base <<= 16
+			}
+		case ihexStartSeg, ihexStartLinear:
+			// Execution start addresses mean nothing for a data dump
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type 0x%02X", n, rec[3])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, errors.New("no data records")
+	}
+	return data, nil
+}
@@ -0,0 +1,194 @@
+// Package transfer moves patterns between the computer and device pattern
+// slots with reply timeouts, automatic retries, partial-dump detection, and
+// read-back verification
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+)
+
+// DefaultTimeout is how long to wait for each reply
+const DefaultTimeout = 2 * time.Second
+
+// DefaultBackoff is the pause before a failed request is re-sent
+const DefaultBackoff = 250 * time.Millisecond
+
+var (
+	// ErrIncomplete is returned for a dump that arrived damaged or cut short
+	ErrIncomplete = errors.New("incomplete dump")
+	// ErrMismatch is returned when a slot read back differs from what was
+	// written or read before
+	ErrMismatch = errors.New("verification failed")
+)
+
+// Options controls a transfer
+type Options struct {
+	DeviceID uint8         // SysEx device ID of the unit
+	Timeout  time.Duration // Per-reply timeout (default DefaultTimeout)
+	Retries  int           // Extra attempts after a timeout, damaged dump, or mismatch
+	Backoff  time.Duration // Pause before retrying (default DefaultBackoff)
+	Verify   bool          // Read slots back and compare
+}
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return o.Timeout
+}
+
+// Retry calls fn until it succeeds or the retries are used up, pausing
+// between attempts, and returns the last error
+func Retry(opts Options, fn func() error) error {
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	var err error
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	if opts.Retries > 0 {
+		return fmt.Errorf("%w (after %d attempts)", err, opts.Retries+1)
+	}
+	return err
+}
+
+// Pull requests a slot's pattern dump. A timeout or a dump that does not
+// parse (e.g. truncated, or failing its checksum) re-sends the request; with
+// Verify the slot is requested twice and both dumps must match.
+func Pull(out mididevice.Out, in mididevice.In, dev converter.PatternRequester, slot int, opts Options) ([]byte, error) {
+	var dump []byte
+	err := Retry(opts, func() error {
+		var err error
+		if dump, err = request(out, in, dev, slot, opts); err != nil {
+			return err
+		}
+		if !opts.Verify {
+			return nil
+		}
+		again, err := request(out, in, dev, slot, opts)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(dump, again) {
+			return fmt.Errorf("%w: two reads returned different dumps", ErrMismatch)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("slot %s: %w", dev.SlotName(slot), err)
+	}
+	return dump, nil
+}
+
+// Push writes a slot message (from GenerateSyxSlot) to the device. With
+// Verify the slot is read back from in and compared with the written
+// pattern, and the write is repeated if they differ; without it in may be nil.
+func Push(out mididevice.Out, in mididevice.In, dev converter.PatternRequester, slot int, msg []byte, opts Options) error {
+	if !opts.Verify {
+		return out.Send(msg)
+	}
+
+	want, err := parse(dev, msg)
+	if err != nil {
+		return err
+	}
+	return Retry(opts, func() error {
+		if err := out.Send(msg); err != nil {
+			return err
+		}
+		dump, err := request(out, in, dev, slot, opts)
+		if err != nil {
+			return err
+		}
+		got, err := parse(dev, dump)
+		if err != nil {
+			return err
+		}
+		if want == nil {
+			if !bytes.Equal(dump, msg) {
+				return fmt.Errorf("%w: the slot does not hold the written pattern", ErrMismatch)
+			}
+			return nil
+		}
+		if diffs := slotDiff(dev, want, got); len(diffs) > 0 {
+			return fmt.Errorf("%w: the slot does not hold the written pattern: %s", ErrMismatch, diffs)
+		}
+		return nil
+	})
+}
+
+// Unchanged pulls a slot and reports whether it already holds the pattern
+// of msg (a slot message from GenerateSyxSlot), so a bank restore can push
+// only the slots that differ. Slots are compared as the device stores them,
+// ignoring names and device IDs.
+func Unchanged(out mididevice.Out, in mididevice.In, dev converter.PatternRequester, slot int, msg []byte, opts Options) (bool, error) {
+	want, err := parse(dev, msg)
+	if err != nil {
+		return false, err
+	}
+	// One read is enough: a misread slot is only rewritten
+	opts.Verify = false
+	dump, err := Pull(out, in, dev, slot, opts)
+	if err != nil {
+		return false, err
+	}
+	got, err := parse(dev, dump)
+	if err != nil {
+		return false, err
+	}
+	if want == nil {
+		return bytes.Equal(dump, msg), nil
+	}
+	return len(slotDiff(dev, want, got)) == 0, nil
+}
+
+// request sends one dump request and checks that the reply is complete
+func request(out mididevice.Out, in mididevice.In, dev converter.PatternRequester, slot int, opts Options) ([]byte, error) {
+	req, err := dev.PatternRequest(slot, opts.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	accept := func(msg []byte) bool { return dev.IsPatternReply(msg, slot, opts.DeviceID) }
+	reply, err := mididevice.Request(out, in, req, accept, opts.timeout())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parse(dev, reply); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIncomplete, err)
+	}
+	return reply, nil
+}
+
+// parse decodes a slot dump with the device's SysEx parser. Devices without
+// one are trusted as is, and a nil pattern is returned.
+func parse(dev converter.PatternRequester, msg []byte) (*converter.Pattern, error) {
+	d, ok := dev.(converter.Device)
+	if !ok {
+		return nil, nil
+	}
+	return d.ParseSyx(msg)
+}
+
+// slotDiff lists how a slot read back differs from the pattern written, in
+// what the device stores; names and device IDs are ignored
+func slotDiff(dev converter.PatternRequester, want, got *converter.Pattern) string {
+	var diffs []string
+	for _, d := range converter.SlotDiff(dev.(converter.Device), want, got) {
+		diffs = append(diffs, d.String())
+	}
+	return strings.Join(diffs, "; ")
+}
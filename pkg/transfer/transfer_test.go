@@ -0,0 +1,178 @@
+package transfer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+)
+
+// flakyTD3 stores slot writes and answers dump requests, failing the first
+// replies as scripted: "drop" loses a reply, "cut" truncates it, and
+// "ignore" loses a write
+type flakyTD3 struct {
+	slots  map[int][]byte
+	faults []string
+	listen func([]byte)
+	sent   int
+}
+
+func (f *flakyTD3) Name() string { return "flaky TD-3" }
+func (f *flakyTD3) Close() error { return nil }
+func (f *flakyTD3) Listen(onMsg func([]byte)) (func(), error) {
+	f.listen = onMsg
+	return func() { f.listen = nil }, nil
+}
+
+func (f *flakyTD3) fault() string {
+	if len(f.faults) == 0 {
+		return ""
+	}
+	fault := f.faults[0]
+	f.faults = f.faults[1:]
+	return fault
+}
+
+func (f *flakyTD3) Send(msg []byte) error {
+	f.sent++
+	slot := int(msg[7])
+	switch msg[6] {
+	case devices.PatternWrite:
+		if f.fault() != "ignore" {
+			f.slots[slot] = msg
+		}
+	case devices.PatternRequest:
+		dump := f.slots[slot]
+		switch f.fault() {
+		case "drop":
+			return nil
+		case "cut":
+			dump = append(append([]byte(nil), dump[:len(dump)/2]...), 0xF7)
+		}
+		f.listen(dump)
+	}
+	return nil
+}
+
+func slotDump(t *testing.T, note uint8, slot int) []byte {
+	t.Helper()
+	msg, err := devices.NewTD3().GenerateSyxSlot(&converter.Pattern{
+		Length: 16,
+		Steps:  []converter.Step{{Note: note, Gate: true, Velocity: 100}},
+	}, slot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestPull(t *testing.T) {
+	opts := Options{Timeout: 10 * time.Millisecond, Backoff: time.Millisecond}
+	tests := []struct {
+		name    string
+		faults  []string
+		retries int
+		verify  bool
+		wantErr error
+	}{
+		{"clean", nil, 0, false, nil},
+		{"lost reply", []string{"drop"}, 0, false, mididevice.ErrTimeout},
+		{"lost reply retried", []string{"drop"}, 1, false, nil},
+		{"partial dump", []string{"cut"}, 0, false, ErrIncomplete},
+		{"partial dump retried", []string{"cut", "drop"}, 2, false, nil},
+		{"verified", nil, 0, true, nil},
+		{"verify catches damage", []string{"", "cut"}, 0, true, ErrIncomplete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := slotDump(t, 40, 3)
+			dev := &flakyTD3{slots: map[int][]byte{3: want}, faults: tt.faults}
+			o := opts
+			o.Retries, o.Verify = tt.retries, tt.verify
+
+			got, err := Pull(dev, dev, devices.NewTD3(), 3, o)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Pull() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Pull() error = %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("Pull() = % X, want % X", got, want)
+			}
+		})
+	}
+}
+
+func TestPush(t *testing.T) {
+	opts := Options{Timeout: 10 * time.Millisecond, Backoff: time.Millisecond, Verify: true}
+	msg := slotDump(t, 45, 7)
+
+	dev := &flakyTD3{slots: map[int][]byte{7: slotDump(t, 36, 7)}, faults: []string{"ignore"}}
+	err := Push(dev, dev, devices.NewTD3(), 7, msg, opts)
+	if !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Push() with a lost write error = %v, want ErrMismatch", err)
+	}
+	if !strings.Contains(err.Error(), "step 1 note: 45 -> 36") {
+		t.Errorf("Push() error %q does not say how the slot differs", err)
+	}
+
+	dev.faults, dev.sent = []string{"ignore"}, 0
+	opts.Retries = 1
+	if err := Push(dev, dev, devices.NewTD3(), 7, msg, opts); err != nil {
+		t.Fatalf("Push() with retry error = %v", err)
+	}
+	if string(dev.slots[7]) != string(msg) || dev.sent != 4 {
+		t.Errorf("slot 7 = % X after %d messages", dev.slots[7], dev.sent)
+	}
+
+	// Without verification nothing is read back
+	dev.sent = 0
+	if err := Push(dev, nil, devices.NewTD3(), 7, msg, Options{}); err != nil || dev.sent != 1 {
+		t.Errorf("Push() unverified error = %v, sent %d", err, dev.sent)
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	opts := Options{Timeout: 10 * time.Millisecond, Backoff: time.Millisecond, Verify: true}
+	dev := &flakyTD3{slots: map[int][]byte{2: slotDump(t, 45, 2), 3: slotDump(t, 36, 3)}}
+
+	tests := []struct {
+		name string
+		slot int
+		msg  []byte
+		want bool
+	}{
+		{"same pattern", 2, slotDump(t, 45, 2), true},
+		{"edited on the device", 3, slotDump(t, 40, 3), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev.sent = 0
+			got, err := Unchanged(dev, dev, devices.NewTD3(), tt.slot, tt.msg, opts)
+			if err != nil {
+				t.Fatalf("Unchanged() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Unchanged() = %v, want %v", got, tt.want)
+			}
+			// The slot is read once and never written
+			if dev.sent != 1 {
+				t.Errorf("sent %d messages, want 1 request", dev.sent)
+			}
+		})
+	}
+
+	dev.faults = []string{"drop"}
+	if _, err := Unchanged(dev, dev, devices.NewTD3(), 2, slotDump(t, 45, 2), Options{Timeout: 10 * time.Millisecond}); !errors.Is(err, mididevice.ErrTimeout) {
+		t.Errorf("Unchanged() with a lost reply error = %v, want ErrTimeout", err)
+	}
+}
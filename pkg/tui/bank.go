@@ -0,0 +1,192 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// bankRows is the number of bank patterns listed at once
+const bankRows = 16
+
+// bankEntry is one pattern of a bank file
+type bankEntry struct {
+	item     service.BankItem
+	pattern  *converter.Pattern // nil if it cannot be read
+	err      error
+	selected bool
+}
+
+// openFile converts a picked file, or opens the bank browser if the file
+// holds several patterns
+func (m Model) openFile(path string) (Model, tea.Cmd) {
+	if m.comparing() {
+		return m.pickCompared(path)
+	}
+	m.selectedFile = path
+	if data, err := os.ReadFile(path); err == nil {
+		if items, err := service.SplitBank(path, data, converter.Limits{}); err == nil && len(items) > 1 {
+			m.bank = make([]bankEntry, len(items))
+			for i, item := range items {
+				m.bank[i] = bankEntry{item: item}
+				resp, err := service.Convert(context.Background(), service.ConvertRequest{
+					Data:    item.Data,
+					From:    item.Format,
+					Device:  m.opts.Device,
+					Options: m.convertOptions(),
+				})
+				if err != nil {
+					m.bank[i].err = err
+				} else {
+					m.bank[i].pattern = resp.Pattern
+				}
+			}
+			m.bankIndex = 0
+			m.state = StateBank
+			return m, nil
+		}
+	}
+	// Read errors are reported by the conversion
+	return m.startConversion(nil)
+}
+
+// updateBank handles keys in the bank browser: choose patterns, then
+// convert the chosen ones, or the highlighted one if none are chosen
+func (m Model) updateBank(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		if m.bankIndex > 0 {
+			m.bankIndex--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.bankIndex < len(m.bank)-1 {
+			m.bankIndex++
+		}
+	case key.Matches(msg, m.keys.Toggle):
+		if e := &m.bank[m.bankIndex]; e.err == nil {
+			e.selected = !e.selected
+		}
+	case key.Matches(msg, m.keys.All):
+		all := true
+		for _, e := range m.bank {
+			all = all && (e.selected || e.err != nil)
+		}
+		for i := range m.bank {
+			m.bank[i].selected = !all && m.bank[i].err == nil
+		}
+	case key.Matches(msg, m.keys.Select):
+		var chosen []service.BankItem
+		for _, e := range m.bank {
+			if e.selected {
+				chosen = append(chosen, e.item)
+			}
+		}
+		if len(chosen) == 0 {
+			if m.bank[m.bankIndex].err != nil {
+				return m, nil
+			}
+			chosen = []service.BankItem{m.bank[m.bankIndex].item}
+		}
+		return m.startConversion(chosen)
+	case key.Matches(msg, m.keys.Back):
+		m.state = StateMenu
+		m.bank = nil
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	}
+	return m, nil
+}
+
+// performBankConversion converts bank patterns to their planned files,
+// named after the bank and their position, skipping those that fail
+func (m Model) performBankConversion(plan []plannedOutput) tea.Cmd {
+	return func() tea.Msg {
+		to := converter.Format(m.conversion.ToFormat)
+		var done conversionDoneMsg
+		for _, p := range plan {
+			if p.path == "" {
+				continue
+			}
+			item := *p.item
+			resp, err := service.Convert(context.Background(), service.ConvertRequest{
+				Data:     item.Data,
+				Filename: item.Name + service.Ext(item.Format),
+				From:     item.Format,
+				To:       []converter.Format{to},
+				Device:   m.opts.Device,
+				Options:  m.convertOptions(),
+			})
+			if err != nil {
+				done.skipped = append(done.skipped, fmt.Sprintf("%s: %v", item.Name, err))
+				continue
+			}
+			if err := m.writeOutput(p.path, resp.Outputs[0].Data); err != nil {
+				return conversionDoneMsg{err: err}
+			}
+			done.outputs = append(done.outputs, p.path)
+			done.warnings = append(done.warnings, resp.Warnings...)
+			if len(plan) == 1 {
+				done.pattern = resp.Pattern
+			}
+		}
+		if len(done.outputs) == 0 {
+			return conversionDoneMsg{err: fmt.Errorf("no pattern could be converted: %s", strings.Join(done.skipped, "; "))}
+		}
+		done.outputFile = done.outputs[0]
+		return done
+	}
+}
+
+// viewBank renders the patterns of a bank with a one-line preview each
+func (m Model) viewBank() string {
+	var s strings.Builder
+
+	chosen := 0
+	for _, e := range m.bank {
+		if e.selected {
+			chosen++
+		}
+	}
+	s.WriteString(titleStyle.Render(fmt.Sprintf(" %s: %d PATTERNS ", strings.ToUpper(filepath.Base(m.selectedFile)), len(m.bank))))
+	s.WriteString("\n\n")
+
+	rows := bankRows
+	if m.opts.Inline {
+		rows = inlinePickerHeight
+	}
+	first := max(0, m.bankIndex-rows+1)
+	for i := first; i < min(len(m.bank), first+rows); i++ {
+		e := m.bank[i]
+		mark := "[ ]"
+		if e.selected {
+			mark = "[x]"
+		}
+		preview := ""
+		if e.err != nil {
+			preview = errorStyle.Render("✗ " + e.err.Error())
+		} else {
+			preview = render.NoteLine(e.pattern, render.GridStepsPerRow, render.GridOptions{Names: m.opts.NoteNames})
+		}
+		label := fmt.Sprintf("%s %-12s", mark, e.item.Name)
+		if i == m.bankIndex {
+			s.WriteString(selectedStyle.Render("▸ " + label))
+		} else {
+			s.WriteString(menuStyle.Render("  " + label))
+		}
+		s.WriteString(" " + preview + "\n")
+	}
+
+	s.WriteString(statusStyle.Render(fmt.Sprintf("%d chosen; %s converts the chosen patterns (or the highlighted one) %s → %s",
+		chosen, m.keys.Select.Help().Key, m.conversion.FromFormat, m.conversion.ToFormat)))
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Render(helpText(m.keys.Toggle, m.keys.All, m.keys.Back)))
+	return m.box(s.String())
+}
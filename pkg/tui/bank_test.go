@@ -0,0 +1,67 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestBankBrowser(t *testing.T) {
+	dir := t.TempDir()
+	var bank []byte
+	for _, note := range []uint8{45, 48, 52} {
+		syx, err := devices.NewTD3().GenerateSyx(&converter.Pattern{Length: 16, Steps: []converter.Step{{Note: note, Gate: true, Velocity: 100}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		bank = append(bank, syx...)
+	}
+	path := filepath.Join(dir, "bank.syx")
+	if err := os.WriteFile(path, bank, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(Options{OutputDir: filepath.Join(dir, "out")})
+	m.conversion = menuItems[3] // SYX → MIDI
+	m, _ = m.openFile(path)
+	if m.state != StateBank || len(m.bank) != 3 || m.bank[1].pattern == nil || m.bank[1].item.Name != "bank-02" {
+		t.Fatalf("state %v with %d patterns, want the bank browser with 3", m.state, len(m.bank))
+	}
+
+	press := func(k tea.KeyMsg) tea.Cmd {
+		model, cmd := m.Update(k)
+		m = model.(Model)
+		return cmd
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	var chosen []string
+	for _, e := range m.bank {
+		if e.selected {
+			chosen = append(chosen, e.item.Name)
+		}
+	}
+	if len(chosen) != 2 || chosen[0] != "bank-01" || chosen[1] != "bank-03" {
+		t.Fatalf("chosen %v, want all but the unchosen second", chosen)
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.state != StateConverting {
+		t.Fatalf("state %v after enter, want converting", m.state)
+	}
+	if len(m.plan) != 2 || m.plan[1].item.Name != "bank-03" {
+		t.Fatalf("planned %+v, want the chosen patterns", m.plan)
+	}
+	done := m.performBankConversion(m.plan)().(conversionDoneMsg)
+	if done.err != nil || len(done.outputs) != 2 || filepath.Base(done.outputs[1]) != "bank-03.mid" {
+		t.Fatalf("converted %v, %v", done.outputs, done.err)
+	}
+	if _, err := os.Stat(done.outputs[1]); err != nil {
+		t.Error(err)
+	}
+}
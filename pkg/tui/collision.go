@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// plannedOutput is a file a conversion is about to write
+type plannedOutput struct {
+	input string            // input file or bank pattern, for collision reports
+	item  *service.BankItem // bank pattern; nil converts the selected file
+	path  string            // empty skips the output
+}
+
+// outputPath returns where an output file of the selected input goes: next
+// to the input, or in the output directory if one is set
+func (m Model) outputPath(name string) string {
+	dir := filepath.Dir(m.selectedFile)
+	if m.opts.OutputDir != "" {
+		dir = m.opts.OutputDir
+	}
+	return filepath.Join(dir, name)
+}
+
+// collisionPolicy returns the policy for outputs another input of this
+// session already wrote
+func (m Model) collisionPolicy() string {
+	if m.opts.Collision == "" {
+		return service.CollisionAsk
+	}
+	return m.opts.Collision
+}
+
+// startConversion plans the outputs of the selected file, or of the chosen
+// patterns of a bank, resolves collisions with earlier outputs, and converts
+func (m Model) startConversion(items []service.BankItem) (Model, tea.Cmd) {
+	ext := service.Ext(converter.Format(m.conversion.ToFormat))
+	if items == nil {
+		m.plan = []plannedOutput{{input: m.selectedFile, path: m.outputPath(service.BaseName(m.selectedFile) + ext)}}
+	} else {
+		m.plan = make([]plannedOutput, len(items))
+		for i := range items {
+			m.plan[i] = plannedOutput{
+				input: fmt.Sprintf("%s (%s)", items[i].Name, filepath.Base(m.selectedFile)),
+				item:  &items[i],
+				path:  m.outputPath(items[i].Name + ext),
+			}
+		}
+	}
+	m.planIndex = 0
+	m.collisions = nil
+	m.collideAll = ""
+	m.applyAll = false
+	return m.resolvePlan()
+}
+
+// resolvePlan claims the planned outputs in turn, stopping to ask about a
+// collision unless a policy applies, and converts once all are resolved
+func (m Model) resolvePlan() (Model, tea.Cmd) {
+	for ; m.planIndex < len(m.plan); m.planIndex++ {
+		p := &m.plan[m.planIndex]
+		policy := m.collisionPolicy()
+		if policy == service.CollisionAsk {
+			policy = m.collideAll
+		}
+		if policy == "" {
+			if prev, ok := m.written.Conflict(p.path, p.input); ok {
+				m.conflictWith = prev
+				m.state = StateCollision
+				return m, nil
+			}
+		}
+		path, c := m.written.Claim(p.path, p.input, policy)
+		p.path = path
+		if c != nil {
+			m.collisions = append(m.collisions, *c)
+		}
+	}
+
+	if !slices.ContainsFunc(m.plan, func(p plannedOutput) bool { return p.path != "" }) {
+		m.state = StateConverting
+		return m, func() tea.Msg {
+			return conversionDoneMsg{err: errors.New("nothing converted: every output was skipped")}
+		}
+	}
+	m.state = StateConverting
+	if len(m.plan) == 1 && m.plan[0].item == nil {
+		return m, tea.Batch(m.spinner.Tick, m.performConversion(m.plan[0].path))
+	}
+	return m, tea.Batch(m.spinner.Tick, m.performBankConversion(m.plan))
+}
+
+// updateCollision handles the answer to a collision: rename, skip, or
+// overwrite this output, or every colliding one left if "all" is chosen
+func (m Model) updateCollision(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	policy := ""
+	switch {
+	case key.Matches(msg, m.keys.Rename):
+		policy = service.CollisionRename
+	case key.Matches(msg, m.keys.Skip):
+		policy = service.CollisionSkip
+	case key.Matches(msg, m.keys.Overwrite):
+		policy = service.CollisionOverwrite
+	case key.Matches(msg, m.keys.All):
+		m.applyAll = !m.applyAll
+		return m, nil
+	case key.Matches(msg, m.keys.Back):
+		m.state = StateMenu
+		m.plan = nil
+		return m, nil
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	default:
+		return m, nil
+	}
+
+	if m.applyAll {
+		m.collideAll = policy
+	}
+	p := &m.plan[m.planIndex]
+	path, c := m.written.Claim(p.path, p.input, policy)
+	p.path = path
+	if c != nil {
+		m.collisions = append(m.collisions, *c)
+	}
+	m.planIndex++
+	return m.resolvePlan()
+}
+
+// viewCollision asks what to do with an output another input already wrote
+func (m Model) viewCollision() string {
+	var s strings.Builder
+	p := m.plan[m.planIndex]
+
+	s.WriteString(titleStyle.Render(" OUTPUT EXISTS "))
+	s.WriteString("\n\n")
+	s.WriteString(fmt.Sprintf("%s would write %s,\nalready written from %s this session.\n",
+		p.input, filepath.Base(p.path), m.conflictWith))
+	if left := len(m.plan) - m.planIndex - 1; left > 0 {
+		mark := "[ ]"
+		if m.applyAll {
+			mark = "[x]"
+		}
+		s.WriteString(fmt.Sprintf("\n%s %s: do the same for later collisions (%d outputs left)\n", mark, m.keys.All.Help().Key, left))
+	}
+	s.WriteString(statusStyle.Render(helpText(m.keys.Rename, m.keys.Skip, m.keys.Overwrite)))
+	return m.box(s.String())
+}
+
+// viewCollisions lists the collisions of the last conversion for its result
+func (m Model) viewCollisions() string {
+	var s strings.Builder
+	for _, c := range m.collisions {
+		s.WriteString("\n")
+		s.WriteString(statusStyle.UnsetPaddingTop().Render("⚠ " + c.String()))
+	}
+	return s.String()
+}
@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+func TestCollisions(t *testing.T) {
+	dir := t.TempDir()
+	seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{Length: 16, Steps: []converter.Step{{Note: 45, Gate: true, Velocity: 100}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two inputs named alike map to the same output
+	inputs := []string{filepath.Join(dir, "a", "line.seq"), filepath.Join(dir, "b", "line.seq")}
+	for _, path := range inputs {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, seq, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "out")
+
+	convert := func(m Model, path string) Model {
+		m.conversion = menuItems[1] // SEQ → MIDI
+		m, _ = m.openFile(path)
+		if m.state == StateConverting {
+			model, _ := m.Update(m.performConversion(m.plan[0].path)())
+			m = model.(Model)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		policy  string
+		answer  string // key pressed at the prompt
+		want    string // second output, empty if skipped
+		wantErr bool
+	}{
+		{"ask rename", "", "r", "line-2.mid", false},
+		{"ask overwrite", service.CollisionAsk, "o", "line.mid", false},
+		{"ask skip", "", "s", "", true},
+		{"rename", service.CollisionRename, "", "line-2.mid", false},
+		{"skip", service.CollisionSkip, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.RemoveAll(out)
+			m := New(Options{OutputDir: out, Collision: tt.policy})
+			m = convert(m, inputs[0])
+			if m.err != nil || m.outputFile != filepath.Join(out, "line.mid") {
+				t.Fatalf("first conversion: %s, %v", m.outputFile, m.err)
+			}
+			// Converting the same input again is no collision
+			m = convert(m, inputs[0])
+			if m.state != StateResult || len(m.collisions) != 0 {
+				t.Fatalf("reconverting: state %v, collisions %v", m.state, m.collisions)
+			}
+
+			m.conversion = menuItems[1]
+			m, cmd := m.openFile(inputs[1])
+			if tt.answer != "" {
+				if m.state != StateCollision {
+					t.Fatalf("state %v, want the collision prompt", m.state)
+				}
+				var model tea.Model
+				model, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.answer)})
+				m = model.(Model)
+			}
+			if m.state != StateConverting || cmd == nil {
+				t.Fatalf("state %v, want converting", m.state)
+			}
+			// With nothing to write, the command reports that directly
+			done := cmd()
+			if m.plan[0].path != "" {
+				done = m.performConversion(m.plan[0].path)()
+			}
+			model, _ := m.Update(done)
+			m = model.(Model)
+
+			if (m.err != nil) != tt.wantErr {
+				t.Fatalf("err = %v", m.err)
+			}
+			if len(m.collisions) != 1 {
+				t.Fatalf("collisions = %v, want one reported", m.collisions)
+			}
+			if tt.want != "" && m.outputFile != filepath.Join(out, tt.want) {
+				t.Errorf("second output %s, want %s", m.outputFile, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// compareTitle is the menu entry of the compare view
+const compareTitle = "Compare"
+
+// compareRows is the number of differences listed at once
+const compareRows = 16
+
+// compareScreen holds the two files being compared and how they differ
+type compareScreen struct {
+	first  string
+	second string
+	diffs  []converter.Difference
+	err    error
+	offset int // first difference shown
+}
+
+// openCompare asks for the first of two pattern files to compare
+func (m Model) openCompare() (tea.Model, tea.Cmd) {
+	m.conversion = menuItems[m.menuIndex]
+	m.compare = compareScreen{}
+	m.filePicker.AllowedTypes = []string{".mid", ".midi", ".seq", ".syx"}
+	m.state = StateFilePicker
+	return m, m.filePicker.Init()
+}
+
+// comparing reports whether files are being picked for the compare view
+func (m Model) comparing() bool {
+	return m.conversion.Title == compareTitle
+}
+
+// pickCompared takes a file picked for comparison: the first asks for the
+// second, and the second shows the differences
+func (m Model) pickCompared(path string) (Model, tea.Cmd) {
+	if m.compare.first == "" {
+		m.compare.first = path
+		m.state = StateFilePicker
+		return m, nil
+	}
+	m.compare.second = path
+	m.compare.diffs, m.compare.err = m.diffFiles(m.compare.first, path)
+	m.compare.offset = 0
+	m.state = StateCompare
+	return m, nil
+}
+
+// diffFiles reads the patterns of two files and compares them, as the
+// diff command does
+func (m Model) diffFiles(a, b string) ([]converter.Difference, error) {
+	dev, err := m.device()
+	if err != nil {
+		return nil, err
+	}
+	conv := converter.New(dev)
+	conv.SetOptions(m.convertOptions())
+	pa, err := conv.ReadPatternFile(a)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(a), err)
+	}
+	pb, err := conv.ReadPatternFile(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(b), err)
+	}
+	return converter.Diff(pa, pb), nil
+}
+
+// updateCompare handles keys in the compare view: scroll the differences,
+// or go back to the menu
+func (m Model) updateCompare(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		m.compare.offset = max(0, m.compare.offset-1)
+	case key.Matches(msg, m.keys.Down):
+		m.compare.offset = max(0, min(m.compare.offset+1, len(m.compare.diffs)-compareRows))
+	case key.Matches(msg, m.keys.Select), key.Matches(msg, m.keys.Back):
+		m.state = StateMenu
+		m.conversion = MenuItem{}
+		m.compare = compareScreen{}
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	}
+	return m, nil
+}
+
+// viewCompare renders the differences between the two files
+func (m Model) viewCompare() string {
+	var s strings.Builder
+	c := m.compare
+
+	s.WriteString(titleStyle.Render(" COMPARE "))
+	s.WriteString("\n\n")
+	s.WriteString(fmt.Sprintf("A: %s\nB: %s\n\n", filepath.Base(c.first), filepath.Base(c.second)))
+
+	switch {
+	case c.err != nil:
+		s.WriteString(errorStyle.Render("✗ " + c.err.Error()))
+	case len(c.diffs) == 0:
+		s.WriteString(successStyle.Render("✓ Patterns are identical"))
+	default:
+		s.WriteString(statusStyle.UnsetPaddingTop().Render(fmt.Sprintf("Patterns differ in %d places (A -> B)", len(c.diffs))))
+		s.WriteString("\n")
+		for _, d := range c.diffs[c.offset:min(len(c.diffs), c.offset+compareRows)] {
+			s.WriteString(menuStyle.Render(d.String()))
+			s.WriteString("\n")
+		}
+		if len(c.diffs) > compareRows {
+			s.WriteString(helpStyle.UnsetMarginTop().Render(fmt.Sprintf("%d-%d of %d", c.offset+1, min(len(c.diffs), c.offset+compareRows), len(c.diffs))))
+		}
+	}
+	return m.box(s.String())
+}
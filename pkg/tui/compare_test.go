@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+)
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, note uint8) string {
+		seq, err := devices.NewTD3().GenerateSeq(&converter.Pattern{Length: 16, Steps: []converter.Step{{Note: note, Gate: true, Velocity: 100}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, seq, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a, b := write("a.seq", 45), write("b.seq", 48)
+
+	m := New(Options{})
+	m.menuIndex = slices.IndexFunc(menuItems, func(item MenuItem) bool { return item.Title == compareTitle })
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	if m.state != StateFilePicker || !m.comparing() {
+		t.Fatalf("state %v after choosing Compare, want the file picker", m.state)
+	}
+
+	m, _ = m.openFile(a)
+	if m.state != StateFilePicker || !strings.Contains(m.View(), "COMPARE a.seq WITH") {
+		t.Fatalf("state %v after the first file, want the picker for the second", m.state)
+	}
+	m, _ = m.openFile(b)
+	if m.state != StateCompare || m.compare.err != nil {
+		t.Fatalf("state %v, error %v after the second file", m.state, m.compare.err)
+	}
+	if len(m.compare.diffs) != 1 || m.compare.diffs[0].Field != "note" {
+		t.Errorf("diffs = %v, want the first step's note", m.compare.diffs)
+	}
+	if view := m.View(); !strings.Contains(view, "step 1 note: 45 -> 48") {
+		t.Errorf("view does not list the difference:\n%s", view)
+	}
+
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = model.(Model)
+	if m.state != StateMenu || m.comparing() {
+		t.Errorf("state %v after esc, want the menu", m.state)
+	}
+}
@@ -0,0 +1,95 @@
+package tui
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// droppedPath returns the file path in text sent by a terminal when a file
+// is dragged onto its window: a bracketed paste or a burst of keystrokes,
+// possibly quoted, with backslash-escaped spaces, or as a file:// URL. Of
+// several dropped files only the first is used.
+func droppedPath(text string) string {
+	text = strings.TrimSpace(text)
+	if first, _, ok := strings.Cut(text, "\n"); ok {
+		text = strings.TrimSpace(first)
+	}
+	if len(text) >= 2 && (text[0] == '\'' || text[0] == '"') {
+		if end := strings.IndexByte(text[1:], text[0]); end >= 0 {
+			return text[1 : end+1]
+		}
+	}
+	if strings.HasPrefix(text, "file://") {
+		if u, err := url.Parse(text); err == nil {
+			return u.Path
+		}
+	}
+
+	// Unescape "\ " and friends, stopping at the first unescaped space
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && filepath.Separator != '\\':
+			i++
+		case text[i] == ' ':
+			return b.String()
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+// isDrop reports whether a key message may be a dropped file path
+func isDrop(msg tea.KeyMsg) bool {
+	return msg.Paste || (msg.Type == tea.KeyRunes && len(msg.Runes) > 1)
+}
+
+// dropFile starts converting a file dropped onto the terminal, keeping the
+// highlighted conversion if it reads that kind of file and otherwise picking
+// the first one that does. It reports false if the text does not name a
+// file; terminals send dropped files with absolute paths.
+func (m Model) dropFile(text string) (Model, tea.Cmd, bool) {
+	path := droppedPath(text)
+	if !filepath.IsAbs(path) && path != "~" && !strings.HasPrefix(path, "~/") {
+		return m, nil, false
+	}
+	path, err := expandPath(path, "")
+	if err != nil {
+		return m, nil, false
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return m, nil, false
+	}
+
+	m.closeFilter()
+	if m.state == StateFilePicker && m.comparing() {
+		m, cmd := m.pickCompared(path)
+		return m, cmd, true
+	}
+	from := string(converter.DetectFormat(path))
+	if item := menuItems[m.menuIndex]; item.FromFormat == "" || item.FromFormat != from {
+		m.menuIndex = slices.IndexFunc(menuItems, func(item MenuItem) bool {
+			return item.FromFormat != "" && item.FromFormat == from
+		})
+	}
+	if m.menuIndex < 0 {
+		m.menuIndex = 0
+		m.selectedFile = path
+		m.state = StateResult
+		m.outputFile = ""
+		m.warnings = nil
+		m.pattern = nil
+		m.err = fmt.Errorf("cannot convert %s: drop a .mid, .seq, or .syx file", filepath.Base(path))
+		return m, nil, true
+	}
+	m.conversion = menuItems[m.menuIndex]
+	m, cmd := m.openFile(path)
+	return m, cmd, true
+}
@@ -0,0 +1,379 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/generate"
+	"github.com/james-see/synthtribe2midi/pkg/mididevice"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// Generator settings, in screen order
+const (
+	genStyle = iota
+	genKey
+	genScale
+	genDensity
+	genSeed
+	genFormat
+	genSlot // only for devices with pattern slots
+)
+
+// genLabels names the generator settings
+var genLabels = []string{"style", "key", "scale", "density", "seed", "format", "slot"}
+
+// genFormats are the formats a generated pattern can be saved in
+var genFormats = []converter.Format{converter.FormatSeq, converter.FormatSyx, converter.FormatMIDI}
+
+// pitchNames are the keys a generated line can be in
+var pitchNames = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// Density limits and step, in percent of steps that sound
+const (
+	minDensity  = 5
+	densityStep = 5
+)
+
+// generator holds the settings and the current pattern of the generator
+// screen; the pattern is rebuilt from the settings on every change
+type generator struct {
+	presets []string
+	scales  []string
+	style   *generate.Style // the chosen preset as loaded
+
+	field   int
+	preset  int
+	key     int // index into pitchNames
+	scale   int
+	density int
+	seed    int64
+	format  int
+	slot    int
+
+	pattern *converter.Pattern
+	err     error
+
+	// Outcome of the last save or push
+	status    string
+	statusErr bool
+}
+
+// generatorDoneMsg reports a finished save or push
+type generatorDoneMsg struct {
+	status   string
+	warnings int
+	err      error
+}
+
+// newGenerator starts from the default preset with a random seed
+func newGenerator() generator {
+	g := generator{
+		presets: generate.Presets(),
+		scales:  generate.Scales(),
+		seed:    randomSeed(),
+	}
+	g.preset = max(0, slices.Index(g.presets, generate.DefaultPreset))
+	g.loadPreset()
+	return g
+}
+
+// randomSeed returns a seed short enough to note down
+func randomSeed() int64 {
+	return rand.Int63n(999999) + 1
+}
+
+// loadPreset loads the chosen preset and takes its key, scale, and density
+func (g *generator) loadPreset() {
+	style, err := generate.LoadStyle(g.presets[g.preset])
+	if err != nil {
+		g.pattern, g.err = nil, err
+		return
+	}
+	g.style = style
+	if n, err := generate.ParseNote(style.Root + "2"); err == nil {
+		g.key = int(n % 12)
+	}
+	g.scale = max(0, slices.Index(g.scales, style.Scale))
+	g.density = int(math.Round((1-style.Rest)*100/densityStep)) * densityStep
+	g.density = max(minDensity, g.density)
+	g.regenerate()
+}
+
+// build generates the pattern of the current settings; the same settings
+// always give the same pattern
+func (g generator) build() (*converter.Pattern, error) {
+	if g.style == nil {
+		return nil, g.err
+	}
+	style := *g.style
+	style.Root = pitchNames[g.key]
+	style.Scale = g.scales[g.scale]
+	style.Notes = nil
+	style.Rest = 1 - float64(g.density)/100
+	return generate.Generate(&style, generate.Options{Seed: g.seed})
+}
+
+func (g *generator) regenerate() {
+	g.pattern, g.err = g.build()
+}
+
+// adjust moves the highlighted setting by delta and regenerates
+func (g *generator) adjust(delta, slots int) {
+	wrap := func(i, n int) int { return ((i+delta)%n + n) % n }
+	switch g.field {
+	case genStyle:
+		g.preset = wrap(g.preset, len(g.presets))
+		g.loadPreset()
+		return
+	case genKey:
+		g.key = wrap(g.key, len(pitchNames))
+	case genScale:
+		g.scale = wrap(g.scale, len(g.scales))
+	case genDensity:
+		g.density = min(100, max(minDensity, g.density+delta*densityStep))
+	case genSeed:
+		g.seed = max(1, g.seed+int64(delta))
+	case genFormat:
+		g.format = wrap(g.format, len(genFormats))
+		return
+	case genSlot:
+		g.slot = wrap(g.slot, slots)
+		return
+	}
+	g.regenerate()
+}
+
+// device returns the device conversions use
+func (m Model) device() (converter.Device, error) {
+	name := m.opts.Device
+	if name == "" {
+		name = service.DefaultDevice
+	}
+	dev, ok := devices.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown device %q", name)
+	}
+	return dev, nil
+}
+
+// slotDevice returns the device if patterns can be pushed to its slots
+func (m Model) slotDevice() (converter.SlotDevice, bool) {
+	dev, err := m.device()
+	if err != nil {
+		return nil, false
+	}
+	slots, ok := dev.(converter.SlotDevice)
+	return slots, ok
+}
+
+// genFields returns the number of settings shown, without the slot if the
+// device has none
+func (m Model) genFields() int {
+	if _, ok := m.slotDevice(); ok {
+		return genSlot + 1
+	}
+	return genSlot
+}
+
+// openGenerator shows the generator screen
+func (m Model) openGenerator() (tea.Model, tea.Cmd) {
+	m.gen = newGenerator()
+	m.state = StateGenerate
+	return m, nil
+}
+
+// updateGenerator handles keys on the generator screen: choose a setting,
+// change it, and save or push the result
+func (m Model) updateGenerator(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	slots := 0
+	if dev, ok := m.slotDevice(); ok {
+		slots = dev.Slots()
+	}
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		if m.gen.field > 0 {
+			m.gen.field--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.gen.field < m.genFields()-1 {
+			m.gen.field++
+		}
+	case key.Matches(msg, m.keys.Less):
+		m.gen.adjust(-1, slots)
+	case key.Matches(msg, m.keys.More):
+		m.gen.adjust(1, slots)
+	case key.Matches(msg, m.keys.Reseed):
+		m.gen.seed = randomSeed()
+		m.gen.regenerate()
+	case key.Matches(msg, m.keys.Save):
+		if m.gen.pattern != nil {
+			return m, m.saveGenerated()
+		}
+	case key.Matches(msg, m.keys.Push):
+		if m.gen.pattern != nil {
+			return m, m.pushGenerated()
+		}
+	case key.Matches(msg, m.keys.Back):
+		m.state = StateMenu
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	}
+	return m, nil
+}
+
+// generatedConverter returns a fresh copy of the generated pattern and a
+// converter for the device and options in use
+func (m Model) generatedConverter() (*converter.Pattern, *converter.Converter, error) {
+	dev, err := m.device()
+	if err != nil {
+		return nil, nil, err
+	}
+	// Converting normalizes the pattern in place, so build another one
+	pattern, err := m.gen.build()
+	if err != nil {
+		return nil, nil, err
+	}
+	conv := converter.New(dev)
+	conv.SetOptions(m.convertOptions())
+	return pattern, conv, nil
+}
+
+// saveGenerated writes the generated pattern, named after its style and
+// seed, to the output directory or else the working directory
+func (m Model) saveGenerated() tea.Cmd {
+	g := m.gen
+	return func() tea.Msg {
+		pattern, conv, err := m.generatedConverter()
+		if err != nil {
+			return generatorDoneMsg{err: err}
+		}
+		format := genFormats[g.format]
+		data, err := conv.GeneratePattern(pattern, format)
+		if err != nil {
+			return generatorDoneMsg{err: err}
+		}
+		dir := m.opts.OutputDir
+		if dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return generatorDoneMsg{err: err}
+			}
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%s-%d%s", g.presets[g.preset], g.seed, service.Ext(format)))
+		if err := converter.WriteFileAtomic(path, data, 0644); err != nil {
+			return generatorDoneMsg{err: err}
+		}
+		return generatorDoneMsg{status: "Saved " + path, warnings: len(conv.Warnings())}
+	}
+}
+
+// pushGenerated sends the generated pattern to the chosen slot of the
+// device on the MIDI port
+func (m Model) pushGenerated() tea.Cmd {
+	g := m.gen
+	return func() tea.Msg {
+		dev, ok := m.slotDevice()
+		if !ok {
+			return generatorDoneMsg{err: fmt.Errorf("%s does not support writing pattern slots", m.deviceName())}
+		}
+		if m.opts.Port == "" {
+			return generatorDoneMsg{err: fmt.Errorf("no MIDI port: start the TUI with --port")}
+		}
+		pattern, conv, err := m.generatedConverter()
+		if err != nil {
+			return generatorDoneMsg{err: err}
+		}
+		msg, err := conv.PatternToSlotSyx(pattern, g.slot)
+		if err != nil {
+			return generatorDoneMsg{err: err}
+		}
+		out, err := mididevice.OpenOutput(m.opts.Port)
+		if err != nil {
+			return generatorDoneMsg{err: err}
+		}
+		defer func() { _ = out.Close() }()
+		if err := out.Send(msg); err != nil {
+			return generatorDoneMsg{err: err}
+		}
+		return generatorDoneMsg{
+			status:   fmt.Sprintf("Pushed to slot %s on %s", dev.SlotName(g.slot), out.Name()),
+			warnings: len(conv.Warnings()),
+		}
+	}
+}
+
+// genValue renders the value of a generator setting
+func (m Model) genValue(field int) string {
+	g := m.gen
+	switch field {
+	case genStyle:
+		return g.presets[g.preset]
+	case genKey:
+		return m.opts.NoteNames.PitchClass(g.key)
+	case genScale:
+		return g.scales[g.scale]
+	case genDensity:
+		return fmt.Sprintf("%d%%", g.density)
+	case genSeed:
+		return fmt.Sprint(g.seed)
+	case genFormat:
+		return string(genFormats[g.format])
+	case genSlot:
+		if dev, ok := m.slotDevice(); ok {
+			return dev.SlotName(g.slot)
+		}
+	}
+	return ""
+}
+
+// viewGenerator renders the settings, the preview grid, and the outcome of
+// the last save or push
+func (m Model) viewGenerator() string {
+	var s strings.Builder
+	g := m.gen
+
+	s.WriteString(titleStyle.Render(" GENERATE "))
+	s.WriteString("\n\n")
+	for field := range m.genFields() {
+		label := fmt.Sprintf("%-8s", genLabels[field])
+		if field == g.field {
+			s.WriteString(selectedStyle.Render(fmt.Sprintf("▸ %s ◂ %s ▸", label, m.genValue(field))))
+		} else {
+			s.WriteString(menuStyle.Render(fmt.Sprintf("  %s   %s", label, m.genValue(field))))
+		}
+		s.WriteString("\n")
+	}
+	if g.field == genStyle && g.style != nil {
+		s.WriteString(lipgloss.NewStyle().Foreground(acidYellow).PaddingLeft(4).Render(g.style.Description))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	if g.err != nil {
+		s.WriteString(errorStyle.Render("✗ " + g.err.Error()))
+	} else {
+		s.WriteString(render.Grid(g.pattern, render.GridOptions{Names: m.opts.NoteNames}))
+	}
+	if g.status != "" {
+		s.WriteString("\n")
+		if g.statusErr {
+			s.WriteString(errorStyle.Render("✗ " + g.status))
+		} else {
+			s.WriteString(successStyle.Render("✓ " + g.status))
+		}
+	}
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Render(helpText(m.keys.Reseed, m.keys.Save, m.keys.Push, m.keys.Back)))
+	return m.box(s.String())
+}
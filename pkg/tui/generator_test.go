@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestGenerator(t *testing.T) {
+	dir := t.TempDir()
+	m := New(Options{OutputDir: dir})
+	m.menuIndex = slices.IndexFunc(menuItems, func(item MenuItem) bool { return item.Title == "Generate" })
+	press := func(k tea.KeyMsg) tea.Cmd {
+		model, cmd := m.Update(k)
+		m = model.(Model)
+		return cmd
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.state != StateGenerate || m.gen.pattern == nil {
+		t.Fatalf("state %v, err %v; want the generator with a pattern", m.state, m.gen.err)
+	}
+	if got := m.gen.presets[m.gen.preset]; got != "acid" || pitchNames[m.gen.key] != "A" || m.gen.scales[m.gen.scale] != "minor" || m.gen.density != 85 {
+		t.Errorf("settings %s %s %s %d%%, want the acid preset", got, pitchNames[m.gen.key], m.gen.scales[m.gen.scale], m.gen.density)
+	}
+
+	// The same settings give the same pattern; a change regenerates it
+	m.gen.seed = 42
+	m.gen.regenerate()
+	first := m.gen.pattern
+	if again, _ := m.gen.build(); !reflect.DeepEqual(again, first) {
+		t.Error("rebuilding with the same seed gave another pattern")
+	}
+	press(runes("j"))
+	press(tea.KeyMsg{Type: tea.KeyRight})
+	if pitchNames[m.gen.key] != "A#" || reflect.DeepEqual(m.gen.pattern, first) {
+		t.Errorf("key %s, want A# and a new pattern", pitchNames[m.gen.key])
+	}
+	press(runes("j"))
+	press(runes("j"))
+	press(runes("h"))
+	if m.gen.field != genDensity || m.gen.density != 80 {
+		t.Errorf("field %d density %d, want density 80", m.gen.field, m.gen.density)
+	}
+	if view := m.View(); !strings.Contains(view, "density") || !strings.Contains(view, "80%") {
+		t.Errorf("view lacks the density:\n%s", view)
+	}
+
+	done := press(runes("s"))().(generatorDoneMsg)
+	if done.err != nil {
+		t.Fatal(done.err)
+	}
+	path := filepath.Join(dir, "acid-42.seq")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("saved %q: %v", done.status, err)
+	}
+
+	// Pushing needs a port
+	done = press(runes("p"))().(generatorDoneMsg)
+	if done.err == nil || !strings.Contains(done.err.Error(), "--port") {
+		t.Errorf("push without a port: %v", done.err)
+	}
+	model, _ := m.Update(done)
+	if m = model.(Model); !m.gen.statusErr {
+		t.Error("push error not shown")
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.state != StateMenu {
+		t.Errorf("state %v after esc, want the menu", m.state)
+	}
+}
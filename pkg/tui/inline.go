@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// inlinePickerHeight is the number of files listed at once inline
+const inlinePickerHeight = 8
+
+// inlineBoxStyle replaces the bordered box inline
+var inlineBoxStyle = lipgloss.NewStyle().PaddingLeft(1)
+
+// inlineHeader is the one-line header that replaces the logo inline
+func inlineHeader() string {
+	return lipgloss.NewStyle().Foreground(acidGreen).Bold(true).Render("▚ synthtribe2midi")
+}
+
+// box frames a screen's content
+func (m Model) box(content string) string {
+	if m.opts.Inline {
+		return inlineBoxStyle.Render(content)
+	}
+	return boxStyle.Render(content)
+}
+
+// quit ends the program; inline, the final view is cleared so that only
+// the printed conversions remain
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	m.quitting = true
+	return m, tea.Quit
+}
+
+// summary is the line printed to the scrollback for a finished conversion
+func (m Model) summary() string {
+	if m.err != nil {
+		return errorStyle.Render(fmt.Sprintf("✗ %s: %v", filepath.Base(m.selectedFile), m.err) + m.collisionNotes())
+	}
+	line := fmt.Sprintf("✓ %s → %s", m.selectedFile, m.outputFile)
+	if len(m.outputs) > 1 {
+		line = fmt.Sprintf("✓ %s → %d files in %s", m.selectedFile, len(m.outputs), filepath.Dir(m.outputFile))
+	}
+	switch n := len(m.warnings); n {
+	case 0:
+	case 1:
+		line += " (1 warning)"
+	default:
+		line += fmt.Sprintf(" (%d warnings)", n)
+	}
+	return successStyle.Render(line + m.collisionNotes())
+}
+
+// collisionNotes lists the collisions of a conversion below its summary
+func (m Model) collisionNotes() string {
+	var s strings.Builder
+	for _, c := range m.collisions {
+		s.WriteString("\n  ⚠ " + c.String())
+	}
+	return s.String()
+}
@@ -0,0 +1,30 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestInline(t *testing.T) {
+	m := New(Options{Inline: true})
+	if view := m.View(); strings.Contains(view, "╭") || strings.Count(view, "\n") > 20 {
+		t.Errorf("inline menu is not compact:\n%s", view)
+	}
+
+	m.selectedFile = "line.mid"
+	model, cmd := m.Update(conversionDoneMsg{outputFile: "line.seq"})
+	m = model.(Model)
+	if cmd == nil {
+		t.Error("a finished conversion is not printed to the scrollback")
+	}
+	if got := m.summary(); !strings.Contains(got, "line.mid → line.seq") {
+		t.Errorf("summary = %q", got)
+	}
+
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if view := model.View(); view != "" {
+		t.Errorf("view after quitting = %q, want nothing left behind", view)
+	}
+}
@@ -0,0 +1,180 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/james-see/synthtribe2midi/pkg/config"
+)
+
+// Key profiles
+const (
+	// ProfileVim binds the arrow keys plus j/k and h/l (the default)
+	ProfileVim = "vim"
+	// ProfileArrows binds the arrow keys, enter, and esc only
+	ProfileArrows = "arrows"
+)
+
+// KeyMap holds the key bindings of the TUI
+type KeyMap struct {
+	Up        key.Binding
+	Down      key.Binding
+	Select    key.Binding // run a menu entry, pick a file
+	Open      key.Binding // enter a directory in the file picker
+	Parent    key.Binding // leave a directory in the file picker
+	Back      key.Binding // back to the menu
+	Filter    key.Binding // file picker filter box
+	Toggle    key.Binding // choose a pattern of a bank
+	All       key.Binding // choose every pattern of a bank
+	Less      key.Binding // lower a generator setting or option
+	More      key.Binding // raise a generator setting or option
+	Reseed    key.Binding // new random generator seed
+	Save      key.Binding // write the generated pattern, or the settings
+	Push      key.Binding // send the generated pattern to the device
+	Rename    key.Binding // write a colliding output under a free name
+	Skip      key.Binding // drop a colliding output
+	Overwrite key.Binding // replace the earlier output
+	Quit      key.Binding
+}
+
+// DefaultKeyMap returns the bindings of ProfileVim
+func DefaultKeyMap() KeyMap {
+	keys, _ := NewKeyMap(config.KeysConfig{})
+	return keys
+}
+
+// NewKeyMap returns the bindings of a key configuration: its profile, with
+// the keys of single actions replaced
+func NewKeyMap(cfg config.KeysConfig) (KeyMap, error) {
+	bindings := map[string][]string{
+		"up":        {"up", "k"},
+		"down":      {"down", "j"},
+		"select":    {"enter"},
+		"open":      {"right", "l", "enter"},
+		"parent":    {"left", "h", "backspace"},
+		"back":      {"esc"},
+		"filter":    {"/"},
+		"toggle":    {" "},
+		"all":       {"a"},
+		"less":      {"left", "h"},
+		"more":      {"right", "l"},
+		"reseed":    {"r"},
+		"save":      {"s"},
+		"push":      {"p"},
+		"rename":    {"r"},
+		"skip":      {"s"},
+		"overwrite": {"o"},
+		"quit":      {"q", "ctrl+c"},
+	}
+	switch cfg.Profile {
+	case "", ProfileVim:
+	case ProfileArrows:
+		bindings["up"] = []string{"up"}
+		bindings["down"] = []string{"down"}
+		bindings["open"] = []string{"right", "enter"}
+		bindings["parent"] = []string{"left", "backspace"}
+		bindings["less"] = []string{"left"}
+		bindings["more"] = []string{"right"}
+	default:
+		return KeyMap{}, fmt.Errorf("unknown key profile %q (use %s or %s)", cfg.Profile, ProfileVim, ProfileArrows)
+	}
+
+	for action, keys := range cfg.Bindings {
+		if _, ok := bindings[action]; !ok {
+			actions := slices.Sorted(maps.Keys(bindings))
+			return KeyMap{}, fmt.Errorf("unknown key action %q (use %s)", action, strings.Join(actions, ", "))
+		}
+		if len(keys) == 0 || slices.Contains(keys, "") {
+			return KeyMap{}, fmt.Errorf("no keys given for %s", action)
+		}
+		keys = slices.Clone(keys)
+		for i, k := range keys {
+			if k == "space" {
+				keys[i] = " "
+			}
+		}
+		bindings[action] = keys
+	}
+	// ctrl+c always gets you out
+	if !slices.Contains(bindings["quit"], "ctrl+c") {
+		bindings["quit"] = append(bindings["quit"], "ctrl+c")
+	}
+
+	binding := func(action, help string) key.Binding {
+		keys := bindings[action]
+		return key.NewBinding(key.WithKeys(keys...), key.WithHelp(keyNames(keys), help))
+	}
+	return KeyMap{
+		Up:        binding("up", "up"),
+		Down:      binding("down", "down"),
+		Select:    binding("select", "select"),
+		Open:      binding("open", "open"),
+		Parent:    binding("parent", "parent dir"),
+		Back:      binding("back", "back to menu"),
+		Filter:    binding("filter", "filter or go to path"),
+		Toggle:    binding("toggle", "choose"),
+		All:       binding("all", "choose all"),
+		Less:      binding("less", "less"),
+		More:      binding("more", "more"),
+		Reseed:    binding("reseed", "new seed"),
+		Save:      binding("save", "save"),
+		Push:      binding("push", "push to device"),
+		Rename:    binding("rename", "rename"),
+		Skip:      binding("skip", "skip"),
+		Overwrite: binding("overwrite", "overwrite"),
+		Quit:      binding("quit", "quit"),
+	}, nil
+}
+
+// keyNames returns keys as shown in help, e.g. "↑/k"
+func keyNames(keys []string) string {
+	symbols := map[string]string{"up": "↑", "down": "↓", "left": "←", "right": "→", " ": "space"}
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		if s, ok := symbols[k]; ok {
+			k = s
+		}
+		names[i] = k
+	}
+	return strings.Join(names, "/")
+}
+
+// filePickerKeys returns the file picker's bindings with the navigation
+// keys taken from keys
+func (keys KeyMap) filePickerKeys() filepicker.KeyMap {
+	fp := filepicker.DefaultKeyMap()
+	fp.Up = keys.Up
+	fp.Down = keys.Down
+	fp.Open = keys.Open
+	fp.Back = keys.Parent
+	fp.Select = keys.Select
+	return fp
+}
+
+// helpText renders bindings as help, e.g. "↑/k: up • q: quit"
+func helpText(bindings ...key.Binding) string {
+	parts := make([]string, len(bindings))
+	for i, b := range bindings {
+		parts[i] = b.Help().Key + ": " + b.Help().Desc
+	}
+	return strings.Join(parts, " • ")
+}
+
+// help renders the footer help of the current screen
+func (m Model) help() string {
+	switch m.state {
+	case StateResult:
+		return helpText(m.keys.Select, m.keys.Quit)
+	case StateGenerate, StateSettings:
+		return helpText(m.keys.Up, m.keys.Down, m.keys.Less, m.keys.More, m.keys.Quit)
+	case StateCollision:
+		return helpText(m.keys.Back, m.keys.Quit)
+	case StateCompare:
+		return helpText(m.keys.Up, m.keys.Down, m.keys.Back, m.keys.Quit)
+	}
+	return helpText(m.keys.Up, m.keys.Down, m.keys.Select, m.keys.Quit)
+}
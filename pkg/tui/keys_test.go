@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/config"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	press := func(keys KeyMap, k string) int {
+		m := New(Options{Keys: &keys})
+		m.menuIndex = 1
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		switch k {
+		case "up":
+			msg = tea.KeyMsg{Type: tea.KeyUp}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		}
+		model, _ := m.Update(msg)
+		return model.(Model).menuIndex
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.KeysConfig
+		key      string
+		want     int
+		wantHelp string
+	}{
+		{"vim j", config.KeysConfig{}, "j", 2, "↑/k: up • ↓/j: down • enter: select • q/ctrl+c: quit"},
+		{"vim arrow", config.KeysConfig{Profile: "vim"}, "up", 0, ""},
+		{"arrows ignores j", config.KeysConfig{Profile: "arrows"}, "j", 1, "↑: up • ↓: down • enter: select • q/ctrl+c: quit"},
+		{"arrows", config.KeysConfig{Profile: "arrows"}, "down", 2, ""},
+		{"rebound", config.KeysConfig{Bindings: map[string][]string{"down": {"s"}, "quit": {"x"}}}, "s", 2, "↑/k: up • s: down • enter: select • x/ctrl+c: quit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := NewKeyMap(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := press(keys, tt.key); got != tt.want {
+				t.Errorf("menu index after %q = %d, want %d", tt.key, got, tt.want)
+			}
+			if tt.wantHelp != "" {
+				if got := New(Options{Keys: &keys}).help(); got != tt.wantHelp {
+					t.Errorf("help = %q, want %q", got, tt.wantHelp)
+				}
+			}
+		})
+	}
+
+	for _, cfg := range []config.KeysConfig{
+		{Profile: "emacs"},
+		{Bindings: map[string][]string{"jump": {"x"}}},
+		{Bindings: map[string][]string{"up": {}}},
+	} {
+		if _, err := NewKeyMap(cfg); err == nil {
+			t.Errorf("NewKeyMap(%+v) succeeded", cfg)
+		}
+	}
+}
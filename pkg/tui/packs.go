@@ -0,0 +1,191 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/pack"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+// packRows is the number of registry packs listed at once
+const packRows = 12
+
+// packsScreen holds the state of the pack registry browser
+type packsScreen struct {
+	all       []pack.Listing
+	matches   []pack.Listing
+	index     int
+	query     textinput.Model
+	searching bool
+	busy      bool
+	status    string
+	statusErr bool
+}
+
+// packsLoadedMsg carries the registry's packs
+type packsLoadedMsg struct {
+	packs []pack.Listing
+	err   error
+}
+
+// packInstalledMsg reports the result of installing a pack
+type packInstalledMsg struct {
+	status string
+	err    error
+}
+
+// openPacks shows the pack browser and fetches the registry's index
+func (m Model) openPacks() (tea.Model, tea.Cmd) {
+	query := textinput.New()
+	query.Prompt = "search › "
+	query.Placeholder = "name, tag, or author"
+	m.packs = packsScreen{query: query, busy: true, status: "Loading packs…"}
+	m.state = StatePacks
+	registry := m.opts.Registry
+	return m, func() tea.Msg {
+		if registry == nil {
+			return packsLoadedMsg{err: errors.New("no pack registry: set pack.registry in the config file or pass --registry")}
+		}
+		packs, err := registry.Search(context.Background(), "")
+		return packsLoadedMsg{packs: packs, err: err}
+	}
+}
+
+// filterPacks lists the packs matching the search box
+func (m *Model) filterPacks() {
+	p := &m.packs
+	p.matches = nil
+	for _, l := range p.all {
+		if l.Matches(p.query.Value()) {
+			p.matches = append(p.matches, l)
+		}
+	}
+	p.index = min(p.index, max(len(p.matches)-1, 0))
+}
+
+// updatePacks handles keys in the pack browser: search the packs, and
+// install the highlighted one into the library
+func (m Model) updatePacks(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.packs.searching {
+		switch msg.String() {
+		case "esc", "enter":
+			m.packs.searching = false
+			m.packs.query.Blur()
+			return m, nil
+		case "ctrl+c":
+			return m.quit()
+		}
+		var cmd tea.Cmd
+		m.packs.query, cmd = m.packs.query.Update(msg)
+		m.filterPacks()
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		if m.packs.index > 0 {
+			m.packs.index--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.packs.index < len(m.packs.matches)-1 {
+			m.packs.index++
+		}
+	case key.Matches(msg, m.keys.Filter):
+		m.packs.searching = true
+		return m, m.packs.query.Focus()
+	case key.Matches(msg, m.keys.Select):
+		if m.packs.busy || len(m.packs.matches) == 0 {
+			return m, nil
+		}
+		listing := m.packs.matches[m.packs.index]
+		m.packs.busy = true
+		m.packs.status, m.packs.statusErr = "Installing "+listing.Name+"…", false
+		return m, m.installPack(listing)
+	case key.Matches(msg, m.keys.Back):
+		m.state = StateMenu
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	}
+	return m, nil
+}
+
+// installPack downloads and verifies a registry pack, then adds its
+// patterns to the library
+func (m Model) installPack(listing pack.Listing) tea.Cmd {
+	registry, open, device := m.opts.Registry, m.opts.Library, m.opts.Device
+	if device == "" {
+		device = service.DefaultDevice
+	}
+	return func() tea.Msg {
+		if open == nil {
+			return packInstalledMsg{err: errors.New("no library to install into")}
+		}
+		p, err := registry.Download(context.Background(), listing)
+		if err != nil {
+			return packInstalledMsg{err: err}
+		}
+		lib, err := open()
+		if err != nil {
+			return packInstalledMsg{err: err}
+		}
+		entries, err := pack.Install(lib, p, "", device)
+		if err != nil {
+			return packInstalledMsg{err: err}
+		}
+		return packInstalledMsg{status: fmt.Sprintf("Installed %d pattern(s) from %s (signed by %s)",
+			len(entries), p.Manifest.Pack.Name, pack.Fingerprint(p.Signer))}
+	}
+}
+
+// viewPacks renders the registry's packs
+func (m Model) viewPacks() string {
+	var s strings.Builder
+	p := m.packs
+
+	s.WriteString(titleStyle.Render(fmt.Sprintf(" PACKS: %d ", len(p.matches))))
+	s.WriteString("\n\n")
+	if p.searching || p.query.Value() != "" {
+		s.WriteString(p.query.View())
+		s.WriteString("\n\n")
+	}
+
+	rows := packRows
+	if m.opts.Inline {
+		rows = inlinePickerHeight
+	}
+	first := max(0, p.index-rows+1)
+	for i := first; i < min(len(p.matches), first+rows); i++ {
+		l := p.matches[i]
+		label := fmt.Sprintf("%-24s %-8s %-6s", l.Name, l.Version, l.Device)
+		if i == p.index {
+			s.WriteString(selectedStyle.Render("▸ " + label))
+		} else {
+			s.WriteString(menuStyle.Render("  " + label))
+		}
+		s.WriteString(" " + l.Description + "\n")
+	}
+	if len(p.matches) == 0 && !p.busy && p.status == "" {
+		s.WriteString(menuStyle.Render("No matching packs") + "\n")
+	}
+
+	if p.status != "" {
+		s.WriteString("\n")
+		switch {
+		case p.statusErr:
+			s.WriteString(errorStyle.Render("✗ " + p.status))
+		case p.busy:
+			s.WriteString(statusStyle.Render(m.spinner.View() + " " + p.status))
+		default:
+			s.WriteString(successStyle.Render("✓ " + p.status))
+		}
+	}
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Render(helpText(m.keys.Filter, m.keys.Select, m.keys.Back)))
+	return m.box(s.String())
+}
@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/pack"
+)
+
+func TestPacks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "line.seq"), []byte("seq data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := pack.Build(dir, pack.Metadata{Name: "Squelch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	var buf bytes.Buffer
+	if err := p.Write(&buf, key); err != nil {
+		t.Fatal(err)
+	}
+	index := pack.Index{Version: 1, Packs: []pack.Listing{
+		{Name: "Squelch", Tags: []string{"acid"}, URL: "squelch.stpk"},
+		{Name: "Four to the Floor", Device: "rd9", URL: "floor.stpk"},
+	}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.json" {
+			_ = json.NewEncoder(w).Encode(index)
+			return
+		}
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+	registry, err := pack.NewRegistry(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib, err := library.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(Options{Registry: registry, Library: func() (*library.Library, error) { return lib, nil }})
+	m.menuIndex = slices.IndexFunc(menuItems, func(item MenuItem) bool { return item.Title == "Packs" })
+	press := func(k tea.KeyMsg) tea.Cmd {
+		model, cmd := m.Update(k)
+		m = model.(Model)
+		return cmd
+	}
+	run := func(cmd tea.Cmd) {
+		model, _ := m.Update(cmd())
+		m = model.(Model)
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	run(press(tea.KeyMsg{Type: tea.KeyEnter}))
+	if m.state != StatePacks || len(m.packs.matches) != 2 {
+		t.Fatalf("state %v, %d packs (%s); want both packs", m.state, len(m.packs.matches), m.packs.status)
+	}
+
+	press(runes("/"))
+	for _, r := range "ACID" {
+		press(runes(string(r)))
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.packs.matches) != 1 || m.packs.searching {
+		t.Fatalf("search matched %+v", m.packs.matches)
+	}
+
+	run(press(tea.KeyMsg{Type: tea.KeyEnter}))
+	if m.packs.statusErr || !strings.Contains(m.packs.status, "Installed 1 pattern") {
+		t.Fatalf("install status %q", m.packs.status)
+	}
+	if c, err := lib.Collection("Squelch"); err != nil || len(c.Patterns) != 1 {
+		t.Errorf("collection = %+v, %v", c, err)
+	}
+
+	// Without a registry the screen says how to set one
+	m = New(Options{})
+	m.menuIndex = slices.IndexFunc(menuItems, func(item MenuItem) bool { return item.Title == "Packs" })
+	run(press(tea.KeyMsg{Type: tea.KeyEnter}))
+	if !m.packs.statusErr || !strings.Contains(m.View(), "pack.registry") {
+		t.Errorf("view without a registry:\n%s", m.View())
+	}
+}
@@ -0,0 +1,241 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxMatches is the number of filter matches listed at once
+const maxMatches = 12
+
+// newFilterInput creates the file picker's filter box. Typed text filters
+// the current directory by name; a path (starting with /, ~, ./, or ../)
+// jumps to a directory or picks a file on enter.
+func newFilterInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "› "
+	ti.Placeholder = "filter, or a path like ~/samples"
+	ti.CharLimit = 4096
+	return ti
+}
+
+// isPath reports whether filter box text is a path rather than a filter
+func isPath(s string) bool {
+	if s == "." || s == ".." || s == "~" || filepath.IsAbs(s) {
+		return true
+	}
+	for _, prefix := range []string{"/", "~/", "./", "../"} {
+		if strings.HasPrefix(s, prefix) || strings.HasPrefix(s, filepath.FromSlash(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPath resolves a path typed in the picker against dir, expanding a
+// leading ~ to the home directory
+func expandPath(s, dir string) (string, error) {
+	if s == "~" || strings.HasPrefix(s, "~/") || strings.HasPrefix(s, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		s = filepath.Join(home, s[1:])
+	}
+	if !filepath.IsAbs(s) {
+		s = filepath.Join(dir, s)
+	}
+	return filepath.Clean(s), nil
+}
+
+// filterEntries returns the visible directories and files of an allowed
+// type whose names contain filter, ignoring case, directories first
+func filterEntries(entries []os.DirEntry, filter string, allowed []string) []os.DirEntry {
+	filter = strings.ToLower(filter)
+	var dirs, files []os.DirEntry
+	for _, e := range entries {
+		if hidden, _ := filepicker.IsHidden(e.Name()); hidden {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(e.Name()), filter) {
+			continue
+		}
+		switch {
+		case e.IsDir():
+			dirs = append(dirs, e)
+		case allowedFile(e.Name(), allowed):
+			files = append(files, e)
+		}
+	}
+	return append(dirs, files...)
+}
+
+// allowedFile reports whether name has one of the allowed extensions
+func allowedFile(name string, allowed []string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, strings.ToLower(filepath.Ext(name)))
+}
+
+// openFilter shows the filter box over the current directory
+func (m *Model) openFilter() tea.Cmd {
+	m.filtering = true
+	m.pickErr = ""
+	m.filter.SetValue("")
+	m.entries, _ = os.ReadDir(m.filePicker.CurrentDirectory)
+	m.refreshMatches()
+	return m.filter.Focus()
+}
+
+// closeFilter hides the filter box
+func (m *Model) closeFilter() {
+	m.filtering = false
+	m.pickErr = ""
+	m.filter.Blur()
+}
+
+func (m *Model) refreshMatches() {
+	m.matches = nil
+	m.matchIndex = 0
+	if text := m.filter.Value(); !isPath(text) {
+		m.matches = filterEntries(m.entries, text, m.filePicker.AllowedTypes)
+	}
+}
+
+// openDirectory shows dir in the file picker and the filter box, starting at
+// its first entry
+func (m *Model) openDirectory(dir string) tea.Cmd {
+	fp := filepicker.New()
+	fp.KeyMap = m.filePicker.KeyMap
+	fp.AllowedTypes = m.filePicker.AllowedTypes
+	fp.AutoHeight = m.filePicker.AutoHeight
+	fp.SetHeight(m.filePicker.Height)
+	fp.CurrentDirectory = dir
+	m.filePicker = fp
+
+	m.filter.SetValue("")
+	m.entries, _ = os.ReadDir(dir)
+	m.refreshMatches()
+	return m.filePicker.Init()
+}
+
+// updateFilter handles messages while the filter box is open
+func (m Model) updateFilter(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		// Directory listings and cursor blinks
+		var pickerCmd, filterCmd tea.Cmd
+		m.filePicker, pickerCmd = m.filePicker.Update(msg)
+		m.filter, filterCmd = m.filter.Update(msg)
+		return m, tea.Batch(pickerCmd, filterCmd)
+	}
+
+	switch keyMsg.String() {
+	case "esc":
+		m.closeFilter()
+		return m, nil
+	case "ctrl+c":
+		return m.quit()
+	case "up", "ctrl+p":
+		if m.matchIndex > 0 {
+			m.matchIndex--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.matchIndex < len(m.matches)-1 {
+			m.matchIndex++
+		}
+		return m, nil
+	case "enter":
+		return m.pickFiltered()
+	}
+
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.pickErr = ""
+	m.refreshMatches()
+	return m, cmd
+}
+
+// pickFiltered opens the typed path or the highlighted match: directories
+// are shown in the picker, files are converted
+func (m Model) pickFiltered() (tea.Model, tea.Cmd) {
+	text := strings.TrimSpace(m.filter.Value())
+	var path string
+	switch {
+	case isPath(text):
+		p, err := expandPath(text, m.filePicker.CurrentDirectory)
+		if err != nil {
+			m.pickErr = err.Error()
+			return m, nil
+		}
+		path = p
+	case len(m.matches) > 0:
+		path = filepath.Join(m.filePicker.CurrentDirectory, m.matches[m.matchIndex].Name())
+	default:
+		m.pickErr = fmt.Sprintf("nothing matches %q", text)
+		return m, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		m.pickErr = fmt.Sprintf("no such file or directory: %s", path)
+		return m, nil
+	}
+	if info.IsDir() {
+		return m, m.openDirectory(path)
+	}
+	if !allowedFile(path, m.filePicker.AllowedTypes) {
+		m.pickErr = fmt.Sprintf("%s is not a %s file", filepath.Base(path), strings.Join(m.filePicker.AllowedTypes, " or "))
+		return m, nil
+	}
+	m.closeFilter()
+	return m.openFile(path)
+}
+
+// viewFilter renders the filter box and its matches
+func (m Model) viewFilter() string {
+	var s strings.Builder
+
+	s.WriteString(statusStyle.UnsetPaddingTop().Render(m.filePicker.CurrentDirectory))
+	s.WriteString("\n")
+	s.WriteString(m.filter.View())
+	s.WriteString("\n\n")
+
+	text := strings.TrimSpace(m.filter.Value())
+	if isPath(text) {
+		if path, err := expandPath(text, m.filePicker.CurrentDirectory); err == nil {
+			s.WriteString(helpStyle.UnsetMarginTop().Render("enter: open " + path))
+			s.WriteString("\n")
+		}
+	} else {
+		if len(m.matches) == 0 {
+			s.WriteString(helpStyle.UnsetMarginTop().Render("no matches"))
+			s.WriteString("\n")
+		}
+		// Keep the highlighted match in view
+		first := max(0, m.matchIndex-maxMatches+1)
+		for i := first; i < min(len(m.matches), first+maxMatches); i++ {
+			name := m.matches[i].Name()
+			if m.matches[i].IsDir() {
+				name += string(filepath.Separator)
+			}
+			if i == m.matchIndex {
+				s.WriteString(selectedStyle.Render("▸ " + name))
+			} else {
+				s.WriteString(menuStyle.Render("  " + name))
+			}
+			s.WriteString("\n")
+		}
+	}
+	if m.pickErr != "" {
+		s.WriteString(errorStyle.Render("✗ " + m.pickErr))
+		s.WriteString("\n")
+	}
+	return s.String()
+}
@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	dir := filepath.FromSlash("/music/lib")
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"~", home},
+		{"~/samples", filepath.Join(home, "samples")},
+		{"./acid", filepath.Join(dir, "acid")},
+		{"..", filepath.Dir(dir)},
+		{"/tmp/x.mid", filepath.FromSlash("/tmp/x.mid")},
+	}
+	for _, tt := range tests {
+		if !isPath(tt.in) {
+			t.Errorf("isPath(%q) = false", tt.in)
+		}
+		if got, err := expandPath(tt.in, dir); err != nil || got != tt.want {
+			t.Errorf("expandPath(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, filter := range []string{"acid", ".hidden", "~backup"} {
+		if isPath(filter) {
+			t.Errorf("isPath(%q) = true, want a filter", filter)
+		}
+	}
+}
+
+func TestFilePickerFilter(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Acid Line.mid", "acid.seq", "bass.mid", "notes.txt", ".acid.mid"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "acid-pack"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(Options{})
+	m.state = StateFilePicker
+	m.filePicker.AllowedTypes = []string{".mid", ".midi"}
+	m.filePicker.CurrentDirectory = dir
+	update := func(msg tea.Msg) {
+		model, _ := m.Update(msg)
+		m = model.(Model)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ACID")})
+	var names []string
+	for _, e := range m.matches {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || names[0] != "acid-pack" || names[1] != "Acid Line.mid" {
+		t.Fatalf("matches = %q, want the directory, then the MIDI file", names)
+	}
+
+	// Opening a directory keeps the filter box open on it
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.filtering || m.filePicker.CurrentDirectory != filepath.Join(dir, "acid-pack") {
+		t.Fatalf("after enter: filtering %v in %s", m.filtering, m.filePicker.CurrentDirectory)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("../notes.txt")})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.state != StateFilePicker || m.pickErr == "" {
+		t.Errorf("picking a .txt file: state %v, error %q", m.state, m.pickErr)
+	}
+}
+
+func TestDroppedPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/music/acid.mid", "/music/acid.mid"},
+		{" '/music/acid line.mid' ", "/music/acid line.mid"},
+		{`"/music/acid line.mid"`, "/music/acid line.mid"},
+		{`/music/acid\ line.mid`, "/music/acid line.mid"},
+		{"file:///music/acid%20line.mid", "/music/acid line.mid"},
+		{"/music/a.mid /music/b.mid", "/music/a.mid"},
+		{"/music/a.mid\n/music/b.mid", "/music/a.mid"},
+	}
+	for _, tt := range tests {
+		if got := droppedPath(tt.in); got != tt.want {
+			t.Errorf("droppedPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDropFile(t *testing.T) {
+	dir := t.TempDir()
+	seq := filepath.Join(dir, "line.seq")
+	if err := os.WriteFile(seq, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	txt := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(txt, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		menuIndex int
+		text      string
+		wantState State
+		wantTitle string
+	}{
+		{"picks the conversion", 0, "'" + seq + "'", StateConverting, "SEQ → MIDI"},
+		{"keeps the highlighted one", 4, seq, StateConverting, "SEQ → SYX"},
+		{"unsupported file", 0, txt, StateResult, ""},
+		{"not a path", 0, "line.seq", StateMenu, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(Options{})
+			m.menuIndex = tt.menuIndex
+			model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.text), Paste: true})
+			m = model.(Model)
+			if m.state != tt.wantState || m.conversion.Title != tt.wantTitle {
+				t.Errorf("state %v, conversion %q; want %v, %q", m.state, m.conversion.Title, tt.wantState, tt.wantTitle)
+			}
+			if tt.wantState == StateConverting && m.selectedFile != seq {
+				t.Errorf("selected %q", m.selectedFile)
+			}
+		})
+	}
+}
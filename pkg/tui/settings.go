@@ -0,0 +1,266 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/config"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+// setting is one conversion option on the settings screen
+type setting struct {
+	label  string
+	value  func(o converter.ConvertOptions, names converter.NoteNaming) string
+	adjust func(o *converter.ConvertOptions, delta int)
+}
+
+// settings are the conversion options the settings screen edits, the ones
+// kept in the config file
+var settings = []setting{
+	{"transpose", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		return fmt.Sprintf("%+d", o.Transpose)
+	}, func(o *converter.ConvertOptions, d int) {
+		o.Transpose = min(max(o.Transpose+d, -127), 127)
+	}},
+	{"channel", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		if o.Channel == 0 {
+			return "1 (read all)"
+		}
+		return fmt.Sprint(o.Channel)
+	}, func(o *converter.ConvertOptions, d int) {
+		o.Channel = min(max(o.Channel+d, 0), 16)
+	}},
+	{"gate length", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		return fmt.Sprintf("%.0f%%", gateLength(o)*100)
+	}, func(o *converter.ConvertOptions, d int) {
+		// In steps of 5%, rounded so repeated steps stay on the grid
+		o.GateLength = math.Round(min(max(gateLength(*o)+float64(d)*0.05, 0.05), 1)*20) / 20
+	}},
+	{"accent at", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		return fmt.Sprintf("velocity %d", accentThreshold(o))
+	}, func(o *converter.ConvertOptions, d int) {
+		o.AccentThreshold = min(max(accentThreshold(*o)+d, 1), 127)
+	}},
+	{"slides", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		return slideMode(o)
+	}, func(o *converter.ConvertOptions, d int) {
+		i := slices.Index(converter.SlideModes, slideMode(*o))
+		o.SlideMode = converter.SlideModes[(i+d+len(converter.SlideModes))%len(converter.SlideModes)]
+	}},
+	{"strict", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		return onOff(o.Strict)
+	}, func(o *converter.ConvertOptions, _ int) {
+		o.Strict = !o.Strict
+	}},
+	{"gate track", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		return onOff(o.GateTrack)
+	}, func(o *converter.ConvertOptions, _ int) {
+		o.GateTrack = !o.GateTrack
+	}},
+	{"gate note", func(o converter.ConvertOptions, names converter.NoteNaming) string {
+		return fmt.Sprintf("%s (%d)", names.Name(o.GateNote), o.GateNote)
+	}, func(o *converter.ConvertOptions, d int) {
+		o.GateNote = uint8(min(max(int(o.GateNote)+d, 0), 127))
+	}},
+	{"accent track", func(o converter.ConvertOptions, _ converter.NoteNaming) string {
+		return onOff(o.AccentTrack)
+	}, func(o *converter.ConvertOptions, _ int) {
+		o.AccentTrack = !o.AccentTrack
+	}},
+	{"accent note", func(o converter.ConvertOptions, names converter.NoteNaming) string {
+		return fmt.Sprintf("%s (%d)", names.Name(o.AccentNote), o.AccentNote)
+	}, func(o *converter.ConvertOptions, d int) {
+		o.AccentNote = uint8(min(max(int(o.AccentNote)+d, 0), 127))
+	}},
+}
+
+func gateLength(o converter.ConvertOptions) float64 {
+	if o.GateLength == 0 {
+		return converter.DefaultGateLength
+	}
+	return o.GateLength
+}
+
+func accentThreshold(o converter.ConvertOptions) int {
+	if o.AccentThreshold == 0 {
+		return converter.DefaultAccentThreshold
+	}
+	return o.AccentThreshold
+}
+
+func slideMode(o converter.ConvertOptions) string {
+	if o.SlideMode == "" {
+		return converter.SlideInterval
+	}
+	return o.SlideMode
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// settingsScreen holds the state of the settings screen
+type settingsScreen struct {
+	field     int
+	status    string
+	statusErr bool
+}
+
+// settingsSavedMsg reports the result of saving the settings
+type settingsSavedMsg struct {
+	path string
+	err  error
+}
+
+// openSettings shows the settings screen
+func (m Model) openSettings() (tea.Model, tea.Cmd) {
+	m.settings = settingsScreen{}
+	m.state = StateSettings
+	return m, nil
+}
+
+// presetNames returns the presets of the config file; the settings screen
+// lists them above the options when there are any
+func (m Model) presetNames() []string {
+	if m.opts.Config == nil {
+		return nil
+	}
+	return m.opts.Config.PresetNames()
+}
+
+// presetRows is 1 if the settings screen has a preset row, else 0
+func (m Model) presetRows() int {
+	return min(len(m.presetNames()), 1)
+}
+
+// choosePreset moves delta presets on from the current one, where "" is the
+// config file's convert section, and converts with its options
+func (m Model) choosePreset(delta int) Model {
+	names := append([]string{""}, m.presetNames()...)
+	i := (slices.Index(names, m.opts.Preset) + delta + len(names)) % len(names)
+	preset, err := m.opts.Config.Preset(names[i])
+	if err != nil {
+		m.settings.status, m.settings.statusErr = err.Error(), true
+		return m
+	}
+	opts := preset.Options()
+	m.opts.Convert = &opts
+	m.opts.Preset = names[i]
+	return m
+}
+
+// updateSettings handles keys on the settings screen; changes apply to the
+// next conversion straight away and are kept once saved
+func (m Model) updateSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	delta := 0
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		if m.settings.field > 0 {
+			m.settings.field--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.settings.field < len(settings)+m.presetRows()-1 {
+			m.settings.field++
+		}
+	case key.Matches(msg, m.keys.Less):
+		delta = -1
+	case key.Matches(msg, m.keys.More), key.Matches(msg, m.keys.Select):
+		delta = 1
+	case key.Matches(msg, m.keys.Save):
+		return m, m.saveSettings()
+	case key.Matches(msg, m.keys.Back):
+		m.state = StateMenu
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	}
+
+	if delta != 0 {
+		m.settings.status = ""
+		if m.settings.field < m.presetRows() {
+			return m.choosePreset(delta), nil
+		}
+		// The options are shared with earlier copies of the model, so
+		// change a copy of them
+		opts := m.convertOptions()
+		settings[m.settings.field-m.presetRows()].adjust(&opts, delta)
+		m.opts.Convert = &opts
+	}
+	return m, nil
+}
+
+// saveSettings writes the conversion options to the config file, where the
+// CLI and the API server read their defaults, or to the chosen preset
+func (m Model) saveSettings() tea.Cmd {
+	cfg, path, opts, preset := m.opts.Config, m.opts.ConfigPath, m.convertOptions(), m.opts.Preset
+	return func() tea.Msg {
+		if cfg == nil || path == "" {
+			return settingsSavedMsg{err: errors.New("no config file to save to")}
+		}
+		updated := *cfg
+		if preset == "" {
+			updated.Convert.SetOptions(opts)
+		} else {
+			var c config.ConvertConfig
+			c.SetOptions(opts)
+			updated.Presets = maps.Clone(cfg.Presets)
+			updated.Presets[preset] = c
+		}
+		if err := config.Save(path, &updated); err != nil {
+			return settingsSavedMsg{err: err}
+		}
+		*cfg = updated
+		return settingsSavedMsg{path: path}
+	}
+}
+
+// viewSettings renders the conversion options
+func (m Model) viewSettings() string {
+	var s strings.Builder
+	opts := m.convertOptions()
+
+	s.WriteString(titleStyle.Render(" SETTINGS "))
+	s.WriteString("\n\n")
+	type row struct{ label, value string }
+	rows := make([]row, 0, len(settings)+1)
+	if m.presetRows() > 0 {
+		preset := m.opts.Preset
+		if preset == "" {
+			preset = "(convert section)"
+		}
+		rows = append(rows, row{"preset", preset})
+	}
+	for _, st := range settings {
+		rows = append(rows, row{st.label, st.value(opts, m.opts.NoteNames)})
+	}
+	for i, r := range rows {
+		label, value := fmt.Sprintf("%-12s", r.label), r.value
+		if i == m.settings.field {
+			s.WriteString(selectedStyle.Render(fmt.Sprintf("▸ %s ◂ %s ▸", label, value)))
+		} else {
+			s.WriteString(menuStyle.Render(fmt.Sprintf("  %s   %s", label, value)))
+		}
+		s.WriteString("\n")
+	}
+
+	if m.settings.status != "" {
+		s.WriteString("\n")
+		if m.settings.statusErr {
+			s.WriteString(errorStyle.Render("✗ " + m.settings.status))
+		} else {
+			s.WriteString(successStyle.Render("✓ " + m.settings.status))
+		}
+	}
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Render(helpText(m.keys.Save, m.keys.Back)))
+	return m.box(s.String())
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/james-see/synthtribe2midi/pkg/config"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := &config.Config{TUI: config.TUIConfig{Keys: config.KeysConfig{Profile: ProfileVim}}}
+	opts := converter.DefaultOptions()
+	m := New(Options{Convert: &opts, Config: cfg, ConfigPath: path})
+	m.menuIndex = slices.IndexFunc(menuItems, func(item MenuItem) bool { return item.Title == "Settings" })
+	press := func(k tea.KeyMsg) tea.Cmd {
+		model, cmd := m.Update(k)
+		m = model.(Model)
+		return cmd
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.state != StateSettings {
+		t.Fatalf("state %v, want settings", m.state)
+	}
+	press(runes("h")) // transpose -1
+	press(runes("h"))
+	press(runes("j"))
+	press(runes("j"))
+	press(runes("l")) // gate length 80%
+	for range 4 {
+		press(runes("j"))
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter}) // gate track on
+
+	got := m.convertOptions()
+	if got.Transpose != -2 || got.GateLength != 0.8 || !got.GateTrack {
+		t.Errorf("options %+v, want transpose -2, gate length 0.8, gate track", got)
+	}
+	if opts.Transpose != 0 {
+		t.Error("the options passed in were changed in place")
+	}
+	if view := m.View(); !strings.Contains(view, "-2") || !strings.Contains(view, "80%") {
+		t.Errorf("view lacks the new values:\n%s", view)
+	}
+
+	model, _ := m.Update(press(runes("s"))())
+	m = model.(Model)
+	if m.settings.statusErr {
+		t.Fatalf("save failed: %s", m.settings.status)
+	}
+	loaded, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Convert.Options() != got || loaded.TUI.Keys.Profile != ProfileVim {
+		t.Errorf("saved %+v, want %+v with the keys kept", loaded, got)
+	}
+
+	// Without a config file the settings still apply, but cannot be saved
+	m = New(Options{})
+	m.state = StateSettings
+	model, _ = m.Update(press(runes("s"))())
+	if m = model.(Model); !m.settings.statusErr {
+		t.Error("saving without a config file succeeded")
+	}
+}
+
+func TestSettingsPresets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := &config.Config{Presets: map[string]config.ConvertConfig{
+		"for-ableton":  {Channel: 2},
+		"for-hardware": {SlideMode: converter.SlideLegato, Octave: -1},
+	}}
+	m := New(Options{Config: cfg, ConfigPath: path})
+	m.state = StateSettings
+	press := func(k tea.KeyMsg) tea.Cmd {
+		model, cmd := m.Update(k)
+		m = model.(Model)
+		return cmd
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	// The preset row comes first and cycles through the presets
+	press(runes("l"))
+	press(runes("l"))
+	if got := m.convertOptions(); m.opts.Preset != "for-hardware" || got.Transpose != -12 || got.SlideMode != converter.SlideLegato {
+		t.Fatalf("preset %q, options %+v; want for-hardware", m.opts.Preset, got)
+	}
+	if view := m.View(); !strings.Contains(view, "for-hardware") {
+		t.Errorf("view lacks the preset:\n%s", view)
+	}
+
+	// Changes are saved to the chosen preset
+	press(runes("j"))
+	press(runes("l")) // transpose -11
+	model, _ := m.Update(press(runes("s"))())
+	m = model.(Model)
+	if m.settings.statusErr {
+		t.Fatalf("save failed: %s", m.settings.status)
+	}
+	loaded, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hw := loaded.Presets["for-hardware"].Options(); hw.Transpose != -11 || loaded.Convert.Transpose != 0 || loaded.Presets["for-ableton"].Channel != 2 {
+		t.Errorf("saved %+v", loaded)
+	}
+
+	press(runes("k"))
+	press(runes("l"))
+	if m.opts.Preset != "" || m.convertOptions().SlideMode != "" {
+		t.Errorf("preset %q after cycling round, want the convert section", m.opts.Preset)
+	}
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/service"
+)
+
+var (
+	statusBarStyle = lipgloss.NewStyle().
+			Foreground(silverGray).
+			Background(darkGray).
+			Padding(0, 1)
+
+	statusKeyStyle = lipgloss.NewStyle().
+			Foreground(acidGreen).
+			Background(darkGray)
+
+	statusWarnStyle = lipgloss.NewStyle().
+			Foreground(acidYellow).
+			Background(darkGray).
+			Bold(true)
+)
+
+// deviceName returns the display name of the device conversions use
+func (m Model) deviceName() string {
+	name := m.opts.Device
+	if name == "" {
+		name = service.DefaultDevice
+	}
+	if info, ok := devices.LookupInfo(name); ok {
+		return info.Name
+	}
+	return name
+}
+
+// convertOptions returns the conversion options to convert with
+func (m Model) convertOptions() converter.ConvertOptions {
+	if m.opts.Convert != nil {
+		return *m.opts.Convert
+	}
+	return converter.DefaultOptions()
+}
+
+// viewStatusBar renders the context of the next conversion: device, output
+// directory, MIDI port, and options, with the last conversion's warnings
+func (m Model) viewStatusBar() string {
+	outDir := m.opts.OutputDir
+	if outDir == "" {
+		outDir = "next to input"
+	}
+	port := m.opts.Port
+	if port == "" {
+		port = "none"
+	}
+	fields := []string{
+		statusKeyStyle.Render("device ") + m.deviceName(),
+		statusKeyStyle.Render("out ") + outDir,
+		statusKeyStyle.Render("port ") + port,
+	}
+	if m.opts.Preset != "" {
+		fields = append(fields, statusKeyStyle.Render("preset ")+m.opts.Preset)
+	}
+	if summary := optionsSummary(m.convertOptions()); summary != "" {
+		fields = append(fields, statusKeyStyle.Render("opts ")+summary)
+	}
+	switch n := m.lastWarnings; n {
+	case 0:
+	case 1:
+		fields = append(fields, statusWarnStyle.Render("⚠ 1 warning"))
+	default:
+		fields = append(fields, statusWarnStyle.Render(fmt.Sprintf("⚠ %d warnings", n)))
+	}
+
+	bar := statusBarStyle
+	if m.width > 0 {
+		bar = bar.MaxWidth(m.width)
+	}
+	return bar.Render(strings.Join(fields, " │ "))
+}
+
+// optionsSummary lists the conversion options that differ from the
+// defaults, e.g. "transpose +12, ch 2, strict"
+func optionsSummary(o converter.ConvertOptions) string {
+	var parts []string
+	if o.Transpose != 0 {
+		parts = append(parts, fmt.Sprintf("transpose %+d", o.Transpose))
+	}
+	if o.Channel != 0 {
+		parts = append(parts, fmt.Sprintf("ch %d", o.Channel))
+	}
+	if o.Bar != 0 {
+		parts = append(parts, fmt.Sprintf("bar %d", o.Bar))
+	}
+	if o.SlideMode != "" {
+		parts = append(parts, "slides "+o.SlideMode)
+	}
+	if o.DeviceID != nil {
+		parts = append(parts, fmt.Sprintf("SysEx ID %d", *o.DeviceID))
+	}
+	if o.Groove != nil {
+		parts = append(parts, "groove")
+	}
+	if o.GateTrack {
+		parts = append(parts, "gate track")
+	}
+	if o.AccentTrack {
+		parts = append(parts, "accent track")
+	}
+	if o.Strict {
+		parts = append(parts, "strict")
+	}
+	return strings.Join(parts, ", ")
+}
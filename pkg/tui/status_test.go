@@ -0,0 +1,27 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/james-see/synthtribe2midi/pkg/converter"
+)
+
+func TestStatusBar(t *testing.T) {
+	opts := converter.DefaultOptions()
+	opts.Transpose = 12
+	opts.Strict = true
+	m := New(Options{Device: "ms1", Convert: &opts, OutputDir: "out", Port: "MS-1"})
+	m.lastWarnings = 2
+
+	bar := m.viewStatusBar()
+	for _, want := range []string{"MS-1", "out", "transpose +12, strict", "2 warnings"} {
+		if !strings.Contains(bar, want) {
+			t.Errorf("status bar %q does not show %q", bar, want)
+		}
+	}
+
+	if bar := New(Options{}).viewStatusBar(); !strings.Contains(bar, "TD-3") || !strings.Contains(bar, "next to input") || strings.Contains(bar, "opts") {
+		t.Errorf("default status bar = %q", bar)
+	}
+}
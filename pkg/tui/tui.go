@@ -2,17 +2,24 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/james-see/synthtribe2midi/pkg/config"
 	"github.com/james-see/synthtribe2midi/pkg/converter"
-	"github.com/james-see/synthtribe2midi/pkg/converter/devices"
+	"github.com/james-see/synthtribe2midi/pkg/library"
+	"github.com/james-see/synthtribe2midi/pkg/pack"
+	"github.com/james-see/synthtribe2midi/pkg/render"
+	"github.com/james-see/synthtribe2midi/pkg/service"
 )
 
 // Acid-inspired color scheme (303/acid aesthetic)
@@ -70,6 +77,12 @@ const (
 	StateFilePicker
 	StateConverting
 	StateResult
+	StateBank
+	StateGenerate
+	StateCollision
+	StateSettings
+	StatePacks
+	StateCompare
 )
 
 // MenuItem represents a menu option
@@ -87,6 +100,10 @@ var menuItems = []MenuItem{
 	{Title: "SYX → MIDI", Description: "Convert SysEx dump to MIDI file", FromFormat: "syx", ToFormat: "midi"},
 	{Title: "SEQ → SYX", Description: "Convert .seq pattern to SysEx dump", FromFormat: "seq", ToFormat: "syx"},
 	{Title: "SYX → SEQ", Description: "Convert SysEx dump to .seq pattern", FromFormat: "syx", ToFormat: "seq"},
+	{Title: compareTitle, Description: "Compare the patterns in two files step by step", FromFormat: "", ToFormat: ""},
+	{Title: "Generate", Description: "Generate an acid pattern with a live preview, then save or push it", FromFormat: "", ToFormat: ""},
+	{Title: "Packs", Description: "Search the pack registry and install packs into the library", FromFormat: "", ToFormat: ""},
+	{Title: "Settings", Description: "Default conversion options, shared with the CLI and API server", FromFormat: "", ToFormat: ""},
 	{Title: "Exit", Description: "Exit the application", FromFormat: "", ToFormat: ""},
 }
 
@@ -99,14 +116,64 @@ type Model struct {
 	selectedFile string
 	outputFile   string
 	conversion   MenuItem
+	warnings     []converter.Violation
+	pattern      *converter.Pattern
 	err          error
 	width        int
 	height       int
+
+	opts     Options
+	quitting bool
+	
+	// Bank browser, and the files written from a bank
+	bank       []bankEntry
+	bankIndex  int
+	outputs    []string
+	skipped    []string
+	
+	// Generator screen
+	gen generator
+	
+	// Settings screen
+	settings settingsScreen
+	
+	// Pack registry browser
+	packs packsScreen
+
+	// Compare view
+	compare compareScreen
+	
+	// Outputs written this session, and the outputs of the conversion
+	// being started with the collisions resolved so far
+	written      *service.Outputs
+	plan         []plannedOutput
+	planIndex    int
+	collisions   []service.Collision
+	collideAll   string // policy chosen for every later collision
+	applyAll     bool
+	conflictWith string
+	
+	// lastWarnings counts the warnings of the last conversion
+	lastWarnings int
+
+	keys KeyMap
+	
+	// Filter box of the file picker
+	filter     textinput.Model
+	filtering  bool
+	entries    []os.DirEntry
+	matches    []os.DirEntry
+	matchIndex int
+	pickErr    string
 }
 
 // conversionDoneMsg signals conversion completion
 type conversionDoneMsg struct {
 	outputFile string
+	outputs    []string // every file written, for banks
+	skipped    []string // bank patterns that failed, with the reason
+	warnings   []converter.Violation
+	pattern    *converter.Pattern
 	err        error
 }
 
@@ -115,12 +182,54 @@ func (m Model) Init() tea.Cmd {
 	return tea.Batch(m.spinner.Tick)
 }
 
+// Options configures the TUI
+type Options struct {
+	// Inline runs without the alternate screen, in a compact layout that
+	// fits a tmux pane; finished conversions stay in the scrollback
+	Inline bool
+	// Device is the device ID or alias to convert for; empty means the TD-3
+	Device string
+	// Convert holds the conversion options; nil means the defaults
+	Convert *converter.ConvertOptions
+	// OutputDir receives converted files; empty writes them next to the input
+	OutputDir string
+	// Port is the MIDI port of the device, shown in the status bar
+	Port string
+	// Keys holds the key bindings; nil means DefaultKeyMap
+	Keys *KeyMap
+	// Collision is the policy for outputs that another input already wrote
+	// this session (see service.CollisionPolicies); empty means ask
+	Collision string
+	// NoteNames selects how notes are shown in previews
+	NoteNames converter.NoteNaming
+	// Config is the user configuration that the settings screen saves the
+	// conversion options to, at ConfigPath; nil disables saving
+	Config     *config.Config
+	ConfigPath string
+	// Preset is the config file preset that Convert came from, if any;
+	// the settings screen switches between the presets
+	Preset string
+	// Registry is the pack registry browsed on the packs screen, and
+	// Library opens the library its packs are installed into
+	Registry *pack.Registry
+	Library  func() (*library.Library, error)
+}
+
 // New creates a new TUI model
-func New() Model {
+func New(opts Options) Model {
 	// Initialize file picker
 	fp := filepicker.New()
 	fp.AllowedTypes = []string{".mid", ".midi", ".seq", ".syx"}
 	fp.CurrentDirectory, _ = os.Getwd()
+	keys := DefaultKeyMap()
+	if opts.Keys != nil {
+		keys = *opts.Keys
+	}
+	fp.KeyMap = keys.filePickerKeys()
+	if opts.Inline {
+		fp.AutoHeight = false
+		fp.SetHeight(inlinePickerHeight)
+	}
 	
 	// Initialize spinner
 	s := spinner.New()
@@ -132,21 +241,38 @@ func New() Model {
 		menuIndex:  0,
 		filePicker: fp,
 		spinner:    s,
+		filter:     newFilterInput(),
+		opts:       opts,
+		keys:       keys,
+		written:    service.NewOutputs(),
 	}
 }
 
 // Update handles TUI updates
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// A file dragged onto the terminal converts straight away
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && isDrop(keyMsg) && !m.filtering && m.state != StateConverting {
+		if dropped, cmd, ok := m.dropFile(string(keyMsg.Runes)); ok {
+			return dropped, cmd
+		}
+	}
+	
 	// Handle file picker state first - it needs to receive all messages
 	if m.state == StateFilePicker {
+		if m.filtering {
+			return m.updateFilter(msg)
+		}
+		
 		// Check for escape/quit keys first
 		if keyMsg, ok := msg.(tea.KeyMsg); ok {
-			switch keyMsg.String() {
-			case "esc":
+			switch {
+			case key.Matches(keyMsg, m.keys.Filter):
+				return m, m.openFilter()
+			case key.Matches(keyMsg, m.keys.Back):
 				m.state = StateMenu
 				return m, nil
-			case "q", "ctrl+c":
-				return m, tea.Quit
+			case key.Matches(keyMsg, m.keys.Quit):
+				return m.quit()
 			}
 		}
 
@@ -156,9 +282,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Check if file was selected
 		if didSelect, path := m.filePicker.DidSelectFile(msg); didSelect {
-			m.selectedFile = path
-			m.state = StateConverting
-			return m, tea.Batch(m.spinner.Tick, m.performConversion())
+			return m.openFile(path)
 		}
 
 		return m, cmd
@@ -168,7 +292,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.filePicker.SetHeight(msg.Height - 10)
+		if m.opts.Inline {
+			m.filePicker.SetHeight(min(inlinePickerHeight, msg.Height-4))
+		} else {
+			m.filePicker.SetHeight(msg.Height - 10)
+		}
 		return m, nil
 
 	case tea.KeyMsg:
@@ -177,6 +305,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateMenu(msg)
 		case StateResult:
 			return m.updateResult(msg)
+		case StateBank:
+			return m.updateBank(msg)
+		case StateGenerate:
+			return m.updateGenerator(msg)
+		case StateCollision:
+			return m.updateCollision(msg)
+		case StateSettings:
+			return m.updateSettings(msg)
+		case StatePacks:
+			return m.updatePacks(msg)
+		case StateCompare:
+			return m.updateCompare(msg)
 		}
 
 	case spinner.TickMsg:
@@ -187,7 +327,53 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case conversionDoneMsg:
 		m.state = StateResult
 		m.outputFile = msg.outputFile
+		m.outputs = msg.outputs
+		m.skipped = msg.skipped
+		m.warnings = msg.warnings
+		m.pattern = msg.pattern
 		m.err = msg.err
+		m.lastWarnings = len(msg.warnings)
+		if m.opts.Inline {
+			return m, tea.Println(m.summary())
+		}
+		return m, nil
+
+	case generatorDoneMsg:
+		m.gen.status, m.gen.statusErr = msg.status, false
+		if msg.err != nil {
+			m.gen.status, m.gen.statusErr = msg.err.Error(), true
+		}
+		m.lastWarnings = msg.warnings
+		if m.opts.Inline && msg.err == nil {
+			return m, tea.Println(successStyle.Render("✓ " + msg.status))
+		}
+		return m, nil
+
+	case packsLoadedMsg:
+		m.packs.busy, m.packs.status = false, ""
+		if msg.err != nil {
+			m.packs.status, m.packs.statusErr = msg.err.Error(), true
+		}
+		m.packs.all = msg.packs
+		m.filterPacks()
+		return m, nil
+
+	case packInstalledMsg:
+		m.packs.busy = false
+		m.packs.status, m.packs.statusErr = msg.status, false
+		if msg.err != nil {
+			m.packs.status, m.packs.statusErr = msg.err.Error(), true
+		}
+		if m.opts.Inline && msg.err == nil {
+			return m, tea.Println(successStyle.Render("✓ " + msg.status))
+		}
+		return m, nil
+
+	case settingsSavedMsg:
+		m.settings.status, m.settings.statusErr = "Saved to "+msg.path, false
+		if msg.err != nil {
+			m.settings.status, m.settings.statusErr = msg.err.Error(), true
+		}
 		return m, nil
 	}
 
@@ -195,18 +381,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "up", "k":
+	switch {
+	case key.Matches(msg, m.keys.Up):
 		if m.menuIndex > 0 {
 			m.menuIndex--
 		}
-	case "down", "j":
+	case key.Matches(msg, m.keys.Down):
 		if m.menuIndex < len(menuItems)-1 {
 			m.menuIndex++
 		}
-	case "enter":
+	case key.Matches(msg, m.keys.Select):
 		if m.menuIndex == len(menuItems)-1 {
-			return m, tea.Quit
+			return m.quit()
+		}
+		if menuItems[m.menuIndex].Title == "Generate" {
+			return m.openGenerator()
+		}
+		if menuItems[m.menuIndex].Title == "Settings" {
+			return m.openSettings()
+		}
+		if menuItems[m.menuIndex].Title == "Packs" {
+			return m.openPacks()
+		}
+		if menuItems[m.menuIndex].Title == compareTitle {
+			return m.openCompare()
 		}
 		m.conversion = menuItems[m.menuIndex]
 		m.state = StateFilePicker
@@ -222,84 +420,88 @@ func (m Model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		
 		return m, m.filePicker.Init()
-	case "q", "ctrl+c":
-		return m, tea.Quit
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
 	}
 	return m, nil
 }
 
 func (m Model) updateResult(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "enter", "esc":
+	switch {
+	case key.Matches(msg, m.keys.Select), key.Matches(msg, m.keys.Back):
 		m.state = StateMenu
 		m.err = nil
+		m.warnings = nil
+		m.pattern = nil
 		m.selectedFile = ""
 		m.outputFile = ""
+		m.outputs = nil
+		m.skipped = nil
+		m.bank = nil
+		m.plan = nil
+		m.collisions = nil
 		return m, nil
-	case "q", "ctrl+c":
-		return m, tea.Quit
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
 	}
 	return m, nil
 }
 
-func (m Model) performConversion() tea.Cmd {
+// performConversion converts the selected file to path
+func (m Model) performConversion(path string) tea.Cmd {
 	return func() tea.Msg {
-		device := devices.NewTD3()
-		conv := converter.New(device)
-		
 		data, err := os.ReadFile(m.selectedFile)
 		if err != nil {
 			return conversionDoneMsg{err: err}
 		}
 		
-		var result []byte
-		var outputExt string
-		
-		switch m.conversion.FromFormat + "2" + m.conversion.ToFormat {
-		case "midi2seq":
-			result, err = conv.MIDIToSeq(data)
-			outputExt = ".seq"
-		case "seq2midi":
-			result, err = conv.SeqToMIDI(data)
-			outputExt = ".mid"
-		case "midi2syx":
-			result, err = conv.MIDIToSyx(data)
-			outputExt = ".syx"
-		case "syx2midi":
-			result, err = conv.SyxToMIDI(data)
-			outputExt = ".mid"
-		case "seq2syx":
-			result, err = conv.SeqToSyx(data)
-			outputExt = ".syx"
-		case "syx2seq":
-			result, err = conv.SyxToSeq(data)
-			outputExt = ".seq"
-		}
-		
+		to := converter.Format(m.conversion.ToFormat)
+		resp, err := service.Convert(context.Background(), service.ConvertRequest{
+			Data:     data,
+			Filename: m.selectedFile,
+			From:     converter.Format(m.conversion.FromFormat),
+			To:       []converter.Format{to},
+			Device:   m.opts.Device,
+			Options:  m.convertOptions(),
+		})
 		if err != nil {
 			return conversionDoneMsg{err: err}
 		}
 		
-		// Generate output filename
-		base := strings.TrimSuffix(m.selectedFile, filepath.Ext(m.selectedFile))
-		outputFile := base + outputExt
-		
-		err = os.WriteFile(outputFile, result, 0644)
-		if err != nil {
+		if err := m.writeOutput(path, resp.Outputs[0].Data); err != nil {
 			return conversionDoneMsg{err: err}
 		}
 		
-		return conversionDoneMsg{outputFile: outputFile}
+		return conversionDoneMsg{outputFile: path, warnings: resp.Warnings, pattern: resp.Pattern}
+	}
+}
+
+// writeOutput writes a converted file to a path from outputPath, creating
+// the output directory if one is set
+func (m Model) writeOutput(path string, data []byte) error {
+	if m.opts.OutputDir != "" {
+		if err := os.MkdirAll(m.opts.OutputDir, 0755); err != nil {
+			return err
+		}
 	}
+	return converter.WriteFileAtomic(path, data, 0644)
 }
 
 // View renders the TUI
 func (m Model) View() string {
 	var s strings.Builder
 	
+	if m.quitting && m.opts.Inline {
+		// Leave only the conversions printed above in the scrollback
+		return ""
+	}
+	
 	// Header
-	header := asciiLogo()
-	s.WriteString(header)
+	if m.opts.Inline {
+		s.WriteString(inlineHeader())
+	} else {
+		s.WriteString(asciiLogo())
+	}
 	s.WriteString("\n")
 	
 	switch m.state {
@@ -311,11 +513,25 @@ func (m Model) View() string {
 		s.WriteString(m.viewConverting())
 	case StateResult:
 		s.WriteString(m.viewResult())
+	case StateBank:
+		s.WriteString(m.viewBank())
+	case StateGenerate:
+		s.WriteString(m.viewGenerator())
+	case StateCollision:
+		s.WriteString(m.viewCollision())
+	case StateSettings:
+		s.WriteString(m.viewSettings())
+	case StatePacks:
+		s.WriteString(m.viewPacks())
+	case StateCompare:
+		s.WriteString(m.viewCompare())
 	}
 	
-	// Footer help
+	// Status bar and footer help
+	s.WriteString("\n")
+	s.WriteString(m.viewStatusBar())
 	s.WriteString("\n")
-	s.WriteString(helpStyle.Render("↑/↓: navigate • enter: select • q: quit"))
+	s.WriteString(helpStyle.Render(m.help()))
 	
 	return s.String()
 }
@@ -337,17 +553,29 @@ func (m Model) viewMenu() string {
 		s.WriteString("\n")
 	}
 	
-	return boxStyle.Render(s.String())
+	return m.box(s.String())
 }
 
 func (m Model) viewFilePicker() string {
 	var s strings.Builder
 	
-	s.WriteString(titleStyle.Render(fmt.Sprintf(" SELECT %s FILE ", strings.ToUpper(m.conversion.FromFormat))))
+	title := fmt.Sprintf(" SELECT %s FILE ", strings.ToUpper(m.conversion.FromFormat))
+	if m.comparing() {
+		title = " SELECT FIRST PATTERN "
+		if m.compare.first != "" {
+			title = fmt.Sprintf(" COMPARE %s WITH ", filepath.Base(m.compare.first))
+		}
+	}
+	s.WriteString(titleStyle.Render(title))
 	s.WriteString("\n\n")
+	if m.filtering {
+		s.WriteString(m.viewFilter())
+		s.WriteString(helpStyle.Render("type to filter or enter a path • ↑/↓: choose • enter: open • esc: close"))
+		return s.String()
+	}
 	s.WriteString(m.filePicker.View())
 	s.WriteString("\n")
-	s.WriteString(helpStyle.Render("esc: back to menu"))
+	s.WriteString(helpStyle.Render(helpText(m.keys.Open, m.keys.Parent, m.keys.Filter, m.keys.Back)))
 	
 	return s.String()
 }
@@ -360,7 +588,7 @@ func (m Model) viewConverting() string {
 	s.WriteString(fmt.Sprintf("%s Converting %s...\n", m.spinner.View(), filepath.Base(m.selectedFile)))
 	s.WriteString(statusStyle.Render(fmt.Sprintf("  %s → %s", m.conversion.FromFormat, m.conversion.ToFormat)))
 	
-	return boxStyle.Render(s.String())
+	return m.box(s.String())
 }
 
 func (m Model) viewResult() string {
@@ -370,19 +598,40 @@ func (m Model) viewResult() string {
 		s.WriteString(titleStyle.Render(" ERROR "))
 		s.WriteString("\n\n")
 		s.WriteString(errorStyle.Render(fmt.Sprintf("✗ Conversion failed: %s", m.err.Error())))
+		s.WriteString(m.viewCollisions())
 	} else {
 		s.WriteString(titleStyle.Render(" SUCCESS "))
 		s.WriteString("\n\n")
 		s.WriteString(successStyle.Render("✓ Conversion complete!"))
 		s.WriteString("\n\n")
 		s.WriteString(fmt.Sprintf("Input:  %s\n", filepath.Base(m.selectedFile)))
-		s.WriteString(fmt.Sprintf("Output: %s", filepath.Base(m.outputFile)))
+		if len(m.outputs) > 1 {
+			s.WriteString(fmt.Sprintf("Output: %d files in %s", len(m.outputs), filepath.Dir(m.outputFile)))
+			for _, out := range m.outputs {
+				s.WriteString("\n  " + filepath.Base(out))
+			}
+		} else {
+			s.WriteString(fmt.Sprintf("Output: %s", filepath.Base(m.outputFile)))
+		}
+		for _, skipped := range m.skipped {
+			s.WriteString("\n")
+			s.WriteString(errorStyle.Render("✗ skipped " + skipped))
+		}
+		s.WriteString(m.viewCollisions())
+		for _, w := range m.warnings {
+			s.WriteString("\n")
+			s.WriteString(statusStyle.UnsetPaddingTop().Render(fmt.Sprintf("⚠ %s", w)))
+		}
+		if m.pattern != nil {
+			s.WriteString("\n\n")
+			s.WriteString(render.Grid(m.pattern, render.GridOptions{Names: m.opts.NoteNames}))
+		}
 	}
 	
 	s.WriteString("\n\n")
-	s.WriteString(helpStyle.Render("Press enter to continue"))
+	s.WriteString(helpStyle.Render(fmt.Sprintf("Press %s to continue", m.keys.Select.Help().Key)))
 	
-	return boxStyle.Render(s.String())
+	return m.box(s.String())
 }
 
 func asciiLogo() string {
@@ -397,8 +646,12 @@ func asciiLogo() string {
 }
 
 // Run starts the TUI application
-func Run() error {
-	p := tea.NewProgram(New(), tea.WithAltScreen())
+func Run(opts Options) error {
+	var programOpts []tea.ProgramOption
+	if !opts.Inline {
+		programOpts = append(programOpts, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(New(opts), programOpts...)
 	_, err := p.Run()
 	return err
 }
@@ -0,0 +1,248 @@
+// Package watch watches a directory and hands new or changed files to a
+// handler once they have finished being written
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultInterval is the settle period, and the polling interval where file
+// system events are unavailable, used when none is given
+const DefaultInterval = 2 * time.Second
+
+// Handler processes a new or changed file
+type Handler func(path string) error
+
+// Stats reports a watcher's activity
+type Stats struct {
+	Dir       string    `json:"dir"`
+	Processed int       `json:"processed"`
+	Failed    int       `json:"failed"`
+	LastScan  time.Time `json:"last_scan"`
+	LastError string    `json:"last_error,omitempty"`
+	// Events is set while the directory is watched through file system
+	// events; scans then only happen when something changes
+	Events bool `json:"events"`
+}
+
+// fileState identifies a version of a file
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher watches a directory (non-recursively) for new or changed files
+type Watcher struct {
+	dir      string
+	interval time.Duration
+	handler  Handler
+
+	// ProcessExisting hands files already present at the first scan to the
+	// handler; by default they are treated as already processed
+	ProcessExisting bool
+
+	mu      sync.Mutex
+	pending map[string]fileState // state seen on the previous scan
+	done    map[string]fileState // state last handled (or ignored)
+	scanned bool
+	stats   Stats
+}
+
+// New creates a watcher for dir. A file is handled once it has been
+// unchanged for interval; a zero interval uses DefaultInterval.
+func New(dir string, interval time.Duration, handler Handler) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Watcher{
+		dir:      dir,
+		interval: interval,
+		handler:  handler,
+		pending:  make(map[string]fileState),
+		done:     make(map[string]fileState),
+		stats:    Stats{Dir: dir},
+	}
+}
+
+// Dir returns the watched directory
+func (w *Watcher) Dir() string {
+	return w.dir
+}
+
+// Stats returns a snapshot of the watcher's activity
+func (w *Watcher) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// Ignore marks the current version of a file as processed, so files written
+// by the handler itself do not trigger it again
+func (w *Watcher) Ignore(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+}
+
+// Run watches the directory until ctx is cancelled. Changes are picked up
+// through file system events, with the directory scanned once they have
+// settled for an interval; where events are unavailable, or stop arriving
+// because the directory went away, it is scanned every interval instead.
+func (w *Watcher) Run(ctx context.Context) error {
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		return w.poll(ctx)
+	}
+	defer func() { _ = events.Close() }()
+	if err := events.Add(w.dir); err != nil {
+		return w.poll(ctx)
+	}
+
+	w.setEvents(true)
+	defer w.setEvents(false)
+	// The first scan runs straight away to record the existing files
+	settle := time.NewTimer(0)
+	defer settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events.Events:
+			if !ok || (filepath.Clean(ev.Name) == filepath.Clean(w.dir) && ev.Has(fsnotify.Remove|fsnotify.Rename)) {
+				w.setEvents(false)
+				return w.poll(ctx)
+			}
+			if strings.HasPrefix(filepath.Base(ev.Name), ".") {
+				continue
+			}
+			settle.Reset(w.interval)
+		case _, ok := <-events.Errors:
+			if !ok {
+				w.setEvents(false)
+				return w.poll(ctx)
+			}
+			// Events may have been dropped; a scan catches up with them
+			settle.Reset(w.interval)
+		case <-settle.C:
+			pending, err := w.scan()
+			if err != nil {
+				w.recordError(err)
+			}
+			// Files seen changing are handled once a later scan finds
+			// them as they were
+			if pending {
+				settle.Reset(w.interval)
+			}
+		}
+	}
+}
+
+// poll scans the directory every interval until ctx is cancelled
+func (w *Watcher) poll(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Scan(); err != nil {
+			w.recordError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan performs one pass over the directory. A file is handled once it is
+// unchanged since the previous scan (i.e. no longer being written) and
+// differs from the version last handled.
+func (w *Watcher) Scan() error {
+	_, err := w.scan()
+	return err
+}
+
+// scan is Scan, also reporting whether files are waiting for a later scan
+// to find them unchanged
+func (w *Watcher) scan() (bool, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return false, err
+	}
+
+	seen := make(map[string]fileState, len(entries))
+	var ready []string
+	pending := false
+
+	w.mu.Lock()
+	first := !w.scanned
+	w.scanned = true
+	for _, e := range entries {
+		// Hidden files include the temporary files of atomic writes
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(w.dir, e.Name())
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		seen[path] = state
+
+		if first && !w.ProcessExisting {
+			w.done[path] = state
+			continue
+		}
+		if done, ok := w.done[path]; ok && done == state {
+			continue
+		}
+		if prev, ok := w.pending[path]; ok && prev == state {
+			ready = append(ready, path)
+		} else {
+			pending = true
+		}
+	}
+	w.pending = seen
+	w.stats.LastScan = time.Now()
+	w.mu.Unlock()
+
+	for _, path := range ready {
+		err := w.handler(path)
+
+		w.mu.Lock()
+		w.done[path] = seen[path]
+		if err != nil {
+			w.stats.Failed++
+			w.stats.LastError = err.Error()
+		} else {
+			w.stats.Processed++
+		}
+		w.mu.Unlock()
+	}
+	return pending, nil
+}
+
+func (w *Watcher) setEvents(on bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Events = on
+}
+
+func (w *Watcher) recordError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Failed++
+	w.stats.LastError = err.Error()
+}
@@ -0,0 +1,107 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherScan(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "old.seq")
+	if err := os.WriteFile(existing, []byte{1}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var handled []string
+	w := New(dir, 0, func(path string) error {
+		handled = append(handled, filepath.Base(path))
+		if filepath.Base(path) == "bad.seq" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	// First scan records existing files without handling them
+	if err := w.Scan(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	_ = os.WriteFile(filepath.Join(dir, "new.seq"), []byte{1, 2}, 0644)
+	_ = os.WriteFile(filepath.Join(dir, "bad.seq"), []byte{3}, 0644)
+	written := filepath.Join(dir, "out.mid")
+	_ = os.WriteFile(written, []byte{4}, 0644)
+	w.Ignore(written)
+	_ = os.WriteFile(filepath.Join(dir, ".new.seq.123.tmp"), []byte{5}, 0644)
+
+	// New files are only handled once they are stable across two scans
+	_ = w.Scan()
+	if len(handled) != 0 {
+		t.Fatalf("handled %v before files were stable", handled)
+	}
+	_ = w.Scan()
+	_ = w.Scan()
+
+	if len(handled) != 2 {
+		t.Fatalf("handled = %v, want new.seq and bad.seq once each", handled)
+	}
+	stats := w.Stats()
+	if stats.Processed != 1 || stats.Failed != 1 || stats.LastError != "boom" {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	handled := make(chan string, 4)
+	w := New(dir, 20*time.Millisecond, func(path string) error {
+		handled <- filepath.Base(path)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	waitFor(t, "the first scan", func() bool { return !w.Stats().LastScan.IsZero() })
+	if !w.Stats().Events {
+		t.Log("no file system events; polling")
+	}
+
+	_ = os.WriteFile(filepath.Join(dir, ".new.seq.123.tmp"), []byte{1}, 0644)
+	_ = os.WriteFile(filepath.Join(dir, "new.seq"), []byte{1, 2}, 0644)
+	select {
+	case name := <-handled:
+		if name != "new.seq" {
+			t.Errorf("handled %s, want new.seq", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("new.seq was not handled")
+	}
+
+	// A removed folder is polled, so its scans fail and show up in Stats
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "polling", func() bool { s := w.Stats(); return !s.Events && s.LastError != "" })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	if len(handled) != 0 {
+		t.Errorf("handled %s as well", <-handled)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}